@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"strings"
 	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/cmd/benchmark/internal/evaluation"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 )
 
 type FRAMESQuestion struct {
@@ -54,9 +58,16 @@ func main() {
 	limit := flag.Int("limit", 10, "Number of questions to test (0 = all)")
 	output := flag.String("output", "frames_results.json", "Output file for results")
 	apiURL := flag.String("api", "http://localhost:8000", "Backend API URL")
+	path := flag.String("path", pathSearch, "API path: search (stateless), chat (session per question) or chat-category (session per category)")
+	noCache := flag.Bool("no-cache", false, "Answer every question afresh instead of from the answer cache (search path)")
 	flag.Parse()
 
-	log.Printf("🧪 FRAMES Benchmark - Using API: %s", *apiURL)
+	log.Printf("🧪 FRAMES Benchmark - Using API: %s (path: %s)", *apiURL, *path)
+
+	client, err := newQueryClient(*apiURL, *path, *noCache)
+	if err != nil {
+		log.Fatalf("Invalid -path: %v", err)
+	}
 
 	questions, err := loadFRAMESDataset(*dataFile)
 	if err != nil {
@@ -78,7 +89,7 @@ func main() {
 		log.Printf("  📌 Expected: %s", q.Answer)
 		log.Printf("  🔑 Keywords: %v", q.Keywords)
 
-		result := runFRAMESQuestion(*apiURL, q, *mode)
+		result := runFRAMESQuestion(client, q, *mode)
 		results = append(results, result)
 
 		status := "✅"
@@ -86,7 +97,7 @@ func main() {
 			status = "❌"
 		}
 
-		log.Printf("  💬 Got: %s", truncate(result.ActualAnswer, 150))
+		log.Printf("  💬 Got: %s", utils.TruncateRunesWithEllipsis(result.ActualAnswer, 150))
 		log.Printf("  %s Scores: Factuality=%.2f, Depth=%.2f, Diversity=%.2f (%.2fs)",
 			status, result.FactualityScore, result.ReasoningDepth,
 			result.SourceDiversity, result.ProcessingTime.Seconds())
@@ -166,8 +177,9 @@ func createSampleFRAMESDataset() []FRAMESQuestion {
 }
 
 type SearchRequest struct {
-	Query string `json:"query"`
-	Mode  string `json:"mode"`
+	Query   string `json:"query"`
+	Mode    string `json:"mode"`
+	NoCache bool   `json:"no_cache,omitempty"`
 }
 
 type SearchResponse struct {
@@ -183,28 +195,90 @@ type Source struct {
 	Credibility float64 `json:"credibility"`
 }
 
-func runFRAMESQuestion(apiURL string, q FRAMESQuestion, mode string) FRAMESResult {
-	start := time.Now()
+// Code paths a benchmark question can be sent through (-path)
+const (
+	pathSearch       = "search"        // stateless POST /api/search
+	pathChat         = "chat"          // one chat session per question
+	pathChatCategory = "chat-category" // one chat session per category, questions share context
+)
 
-	reqBody := SearchRequest{
-		Query: q.Question,
-		Mode:  mode,
+// queryClient sends benchmark questions to the backend, either statelessly or
+// through chat sessions like the Telegram bot does
+type queryClient struct {
+	apiURL   string
+	path     string
+	sessions map[string]string // category -> chat session ID (chat-category)
+	noCache  bool              // skip the answer cache of /api/search
+}
+
+func newQueryClient(apiURL, path string, noCache bool) (*queryClient, error) {
+	switch path {
+	case pathSearch, pathChat, pathChatCategory:
+	default:
+		return nil, fmt.Errorf("unknown path %q (want search, chat or chat-category)", path)
 	}
-	jsonData, err := json.Marshal(reqBody)
+	return &queryClient{apiURL: apiURL, path: path, sessions: make(map[string]string), noCache: noCache}, nil
+}
+
+// ask posts the question and returns the raw response; chat messages answer
+// with the same SearchResponse body as /api/search
+func (c *queryClient) ask(query, mode, category string) (*http.Response, error) {
+	jsonData, err := json.Marshal(SearchRequest{Query: query, Mode: mode, NoCache: c.noCache})
 	if err != nil {
-		return FRAMESResult{
-			Question:       q.Question,
-			ExpectedAnswer: q.Answer,
-			ActualAnswer:   fmt.Sprintf("ERROR: %v", err),
-			Category:       q.Category,
-			ProcessingTime: time.Since(start),
-			Success:        false,
-			Mode:           mode,
-			HopCount:       q.HopCount,
+		return nil, err
+	}
+
+	if c.path == pathSearch {
+		return http.Post(c.apiURL+"/api/search", "application/json", bytes.NewBuffer(jsonData))
+	}
+
+	sessionID, err := c.session(mode, category)
+	if err != nil {
+		return nil, err
+	}
+	return http.Post(c.apiURL+"/api/chat/session/"+sessionID+"/message", "application/json", bytes.NewBuffer(jsonData))
+}
+
+// session returns the chat session for the next question
+func (c *queryClient) session(mode, category string) (string, error) {
+	if c.path == pathChatCategory {
+		if id, ok := c.sessions[category]; ok {
+			return id, nil
 		}
 	}
 
-	resp, err := http.Post(apiURL+"/api/search", "application/json", bytes.NewBuffer(jsonData))
+	jsonData, err := json.Marshal(map[string]string{"mode": mode})
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.Post(c.apiURL+"/api/chat/session", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("create session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("create session: HTTP %d: %s", resp.StatusCode, body)
+	}
+
+	var session struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return "", fmt.Errorf("create session: %w", err)
+	}
+
+	if c.path == pathChatCategory {
+		c.sessions[category] = session.ID
+	}
+	return session.ID, nil
+}
+
+func runFRAMESQuestion(client *queryClient, q FRAMESQuestion, mode string) FRAMESResult {
+	start := time.Now()
+
+	resp, err := client.ask(q.Question, mode, q.Category)
 	if err != nil {
 		return FRAMESResult{
 			Question:       q.Question,
@@ -274,10 +348,9 @@ func runFRAMESQuestion(apiURL string, q FRAMESQuestion, mode string) FRAMESResul
 }
 
 func evaluateFactuality(answer string, keywords []string) float64 {
-	answerLower := strings.ToLower(answer)
 	matches := 0
 	for _, keyword := range keywords {
-		if strings.Contains(answerLower, strings.ToLower(keyword)) {
+		if evaluation.ContainsKeyword(answer, keyword) {
 			matches++
 		}
 	}
@@ -401,13 +474,6 @@ func printFRAMESSummary(stats FRAMESStats) {
 	fmt.Println("\n" + strings.Repeat("=", 60))
 }
 
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen] + "..."
-}
-
 func saveFRAMESResults(results []FRAMESResult, filename string) error {
 	data, err := json.MarshalIndent(results, "", "  ")
 	if err != nil {
@@ -0,0 +1,92 @@
+package evaluation
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// englishMonths are matched as whole words ("mar" must not match "mary")
+var englishMonths = map[string]int{
+	"january": 1, "jan": 1, "february": 2, "feb": 2, "march": 3, "mar": 3,
+	"april": 4, "apr": 4, "may": 5, "june": 6, "jun": 6, "july": 7, "jul": 7,
+	"august": 8, "aug": 8, "september": 9, "sept": 9, "sep": 9,
+	"october": 10, "oct": 10, "november": 11, "nov": 11, "december": 12, "dec": 12,
+}
+
+// russianMonths are stems matched by prefix, so every case form of the month
+// name is found ("марта", "марте"); май is declined irregularly
+var russianMonths = []struct {
+	stem  string
+	month int
+}{
+	{"январ", 1}, {"феврал", 2}, {"март", 3}, {"апрел", 4}, {"май", 5}, {"мая", 5}, {"мае", 5},
+	{"июн", 6}, {"июл", 7}, {"август", 8}, {"сентябр", 9}, {"октябр", 10}, {"ноябр", 11}, {"декабр", 12},
+}
+
+var (
+	// 15 march 2020, 15 марта 2020. The month patterns have no leading \b,
+	// which is ASCII-only in Go and never matches before Cyrillic.
+	dayMonthYearPattern = regexp.MustCompile(`\b(\d{1,2})(?:st|nd|rd|th)?\s+([a-zа-я]+)\.?,?\s+(\d{4})\b`)
+	// march 15, 2020
+	monthDayYearPattern = regexp.MustCompile(`([a-zа-я]+)\.?\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`)
+	// 15.03.2020
+	numericDatePattern = regexp.MustCompile(`\b(\d{1,2})\.(\d{1,2})\.(\d{4})\b`)
+	// 2020-3-5
+	isoDatePattern = regexp.MustCompile(`\b(\d{4})-(\d{1,2})-(\d{1,2})\b`)
+	// march 2020, в марте 2020
+	monthYearPattern = regexp.MustCompile(`([a-zа-я]+)\s+(\d{4})\b`)
+)
+
+// normalizeDates writes dates in the common formats as YYYY-MM-DD and a month
+// with a year as YYYY-MM, so "15 марта 2020 года" matches "March 15, 2020"
+func normalizeDates(text string) string {
+	text = dayMonthYearPattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := dayMonthYearPattern.FindStringSubmatch(match)
+		return formatDate(match, m[3], monthNumber(m[2]), m[1])
+	})
+	text = monthDayYearPattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := monthDayYearPattern.FindStringSubmatch(match)
+		return formatDate(match, m[3], monthNumber(m[1]), m[2])
+	})
+	text = numericDatePattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := numericDatePattern.FindStringSubmatch(match)
+		month, _ := strconv.Atoi(m[2])
+		return formatDate(match, m[3], month, m[1])
+	})
+	text = isoDatePattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := isoDatePattern.FindStringSubmatch(match)
+		month, _ := strconv.Atoi(m[2])
+		return formatDate(match, m[1], month, m[3])
+	})
+	return monthYearPattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := monthYearPattern.FindStringSubmatch(match)
+		month := monthNumber(m[1])
+		if month == 0 {
+			return match
+		}
+		return fmt.Sprintf("%s-%02d", m[2], month)
+	})
+}
+
+// formatDate returns the original text if the parts are not a valid date
+func formatDate(original, year string, month int, day string) string {
+	d, err := strconv.Atoi(day)
+	if err != nil || month < 1 || month > 12 || d < 1 || d > 31 {
+		return original
+	}
+	return fmt.Sprintf("%s-%02d-%02d", year, month, d)
+}
+
+func monthNumber(word string) int {
+	if month, ok := englishMonths[word]; ok {
+		return month
+	}
+	for _, m := range russianMonths {
+		if strings.HasPrefix(word, m.stem) {
+			return m.month
+		}
+	}
+	return 0
+}
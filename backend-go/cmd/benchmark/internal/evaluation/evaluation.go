@@ -0,0 +1,166 @@
+// Package evaluation compares benchmark answers with the expected ones. It is
+// shared by the benchmark commands and handles Russian and English text:
+// stopwords, word endings, numbers and dates are normalized before matching.
+package evaluation
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Languages returned by DetectLanguage
+const (
+	English = "en"
+	Russian = "ru"
+)
+
+// minWordLength drops short words that carry no meaning; numbers are kept
+const minWordLength = 3
+
+// DetectLanguage returns Russian when a noticeable part of the letters is
+// Cyrillic, English otherwise
+func DetectLanguage(text string) string {
+	cyrillic, letters := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			letters++
+		case unicode.IsLetter(r):
+			letters++
+		}
+	}
+	if letters > 0 && float64(cyrillic)/float64(letters) > 0.3 {
+		return Russian
+	}
+	return English
+}
+
+// EvaluateAnswer reports whether the actual answer contains the expected one:
+// correct if the normalized text or >= 80% of the key words match, partial
+// if at least half of the key words match
+func EvaluateAnswer(actual, expected string) (correct, partial bool) {
+	actualNorm := Normalize(actual)
+	expectedNorm := Normalize(expected)
+	if actualNorm == "" || expectedNorm == "" {
+		return false, false
+	}
+
+	if strings.Contains(actualNorm, expectedNorm) || strings.Contains(expectedNorm, actualNorm) {
+		return true, false
+	}
+
+	ratio := MatchRatio(KeyWords(actual), KeyWords(expected))
+	switch {
+	case ratio >= 0.8:
+		return true, false
+	case ratio >= 0.5:
+		return false, true
+	}
+	return false, false
+}
+
+// MatchRatio is the share of expected key words found among the actual ones
+func MatchRatio(actualWords, expectedWords []string) float64 {
+	if len(expectedWords) == 0 {
+		return 0
+	}
+	actual := make(map[string]bool, len(actualWords))
+	for _, word := range actualWords {
+		actual[word] = true
+	}
+	matches := 0
+	for _, word := range expectedWords {
+		if actual[word] {
+			matches++
+		}
+	}
+	return float64(matches) / float64(len(expectedWords))
+}
+
+// ContainsKeyword reports whether the text mentions the keyword (a word or a
+// phrase) in any grammatical form
+func ContainsKeyword(text, keyword string) bool {
+	if strings.Contains(Normalize(text), Normalize(keyword)) {
+		return true
+	}
+	keyWords := KeyWords(keyword)
+	return len(keyWords) > 0 && MatchRatio(KeyWords(text), keyWords) == 1
+}
+
+// KeyWords returns the lemmas of the meaningful words of the text: stopwords
+// of the detected language and short words are dropped, numbers and dates are
+// normalized
+func KeyWords(text string) []string {
+	lang := DetectLanguage(text)
+	stopWords := englishStopWords
+	if lang == Russian {
+		stopWords = russianStopWords
+	}
+
+	var keyWords []string
+	for _, word := range tokenize(Normalize(text)) {
+		if isNumeric(word) {
+			keyWords = append(keyWords, word)
+			continue
+		}
+		if len([]rune(word)) < minWordLength || stopWords[word] {
+			continue
+		}
+		keyWords = append(keyWords, Lemma(word))
+	}
+	return keyWords
+}
+
+// Normalize lowercases the text, replaces ё with е, writes dates as
+// YYYY-MM-DD and numbers without thousands separators
+func Normalize(text string) string {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.ReplaceAll(text, "ё", "е")
+	text = normalizeDates(text)
+	text = normalizeNumbers(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// tokenize splits normalized text into words; dots and dashes stay inside
+// numbers and dates (3.5, 2020-03-15)
+func tokenize(text string) []string {
+	var words []string
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '-'
+	}) {
+		if word := strings.Trim(field, ".-"); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func isNumeric(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) && r != '.' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	// 1,000,000 / 1 000 000 (regular and non-breaking spaces)
+	thousandsPattern = regexp.MustCompile(`\b\d{1,3}(?:[,\x{00A0}\x{202F} ]\d{3})+\b`)
+	// 3,5 -> 3.5
+	decimalCommaPattern = regexp.MustCompile(`\b(\d+),(\d+)\b`)
+)
+
+func normalizeNumbers(text string) string {
+	text = thousandsPattern.ReplaceAllStringFunc(text, func(number string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, number)
+	})
+	return decimalCommaPattern.ReplaceAllString(text, "$1.$2")
+}
@@ -0,0 +1,76 @@
+package evaluation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minStemLength keeps short words from being cut to meaningless stems
+const minStemLength = 3
+
+// russianEndings are noun, adjective, participle and verb endings, longest
+// first, so "московского" and "московский" both become "московск"
+var russianEndings = []string{
+	"ующими", "ующего", "ующему", "ующая", "ующее", "ующий", "ующих", "ующим",
+	"иями", "ями", "ами", "ого", "его", "ому", "ему", "ыми", "ими",
+	"ией", "ий", "ый", "ой", "ая", "яя", "ое", "ее", "ые", "ие", "ых", "их", "ым", "им",
+	"ешь", "ишь", "ете", "ите", "ует", "уют", "ают", "яют", "ят", "ут", "ют", "ет", "ит",
+	"ать", "ять", "еть", "ить", "ыть", "ла", "ло", "ли", "ал", "ял", "ил", "ел",
+	"ов", "ев", "ей", "ам", "ям", "ах", "ях", "ом", "ем", "ию", "ья", "ье", "ьи",
+	"а", "я", "о", "е", "ы", "и", "у", "ю", "ь", "й",
+}
+
+// Lemma reduces a word to its stem by stripping the inflectional ending, so
+// that different forms of the word ("столицей", "столица"; "cities", "city")
+// compare equal. It is a light stemmer rather than a dictionary lemmatizer.
+func Lemma(word string) string {
+	if DetectLanguage(word) == Russian {
+		return russianStem(word)
+	}
+	return englishStem(word)
+}
+
+func russianStem(word string) string {
+	word = strings.TrimSuffix(strings.TrimSuffix(word, "ся"), "сь")
+	for _, ending := range russianEndings {
+		if stem, ok := strings.CutSuffix(word, ending); ok && len([]rune(stem)) >= minStemLength {
+			return stem
+		}
+	}
+	return word
+}
+
+func englishStem(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "ing") && len(word)-3 >= minStemLength:
+		return undouble(word[:len(word)-3])
+	case strings.HasSuffix(word, "ed") && len(word)-2 >= minStemLength:
+		return undouble(word[:len(word)-2])
+	case strings.HasSuffix(word, "es") && hasSibilantEnding(word[:len(word)-2]):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is") && len(word) > 3:
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// undouble turns "stopp" (from "stopped") into "stop"
+func undouble(stem string) string {
+	n := len(stem)
+	if n >= 2 && stem[n-1] == stem[n-2] && !unicode.IsDigit(rune(stem[n-1])) && !strings.ContainsRune("lsz", rune(stem[n-1])) {
+		return stem[:n-1]
+	}
+	return stem
+}
+
+func hasSibilantEnding(stem string) bool {
+	for _, suffix := range []string{"s", "x", "z", "ch", "sh"} {
+		if strings.HasSuffix(stem, suffix) {
+			return true
+		}
+	}
+	return false
+}
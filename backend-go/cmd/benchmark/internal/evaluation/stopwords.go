@@ -0,0 +1,43 @@
+package evaluation
+
+func wordSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+var englishStopWords = wordSet(
+	"the", "is", "at", "which", "on", "and", "or", "but", "in", "with",
+	"was", "were", "been", "being", "a", "an", "of", "to", "for", "as",
+	"are", "be", "by", "from", "that", "this", "these", "those", "it", "its",
+	"he", "she", "they", "them", "his", "her", "their", "we", "you", "i",
+	"has", "have", "had", "do", "does", "did", "not", "no", "so", "than",
+	"then", "there", "here", "what", "who", "whom", "when", "where", "why",
+	"how", "also", "about", "into", "after", "before", "over", "under",
+	"can", "could", "would", "should", "will", "may", "might", "must",
+	"such", "some", "any", "all", "more", "most", "other", "only", "very",
+	"answer", "approximately", "around",
+)
+
+var russianStopWords = wordSet(
+	"и", "в", "во", "не", "что", "он", "на", "я", "с", "со", "как", "а", "то",
+	"все", "она", "так", "его", "но", "да", "ты", "к", "у", "же", "вы", "за",
+	"бы", "по", "только", "ее", "мне", "было", "вот", "от", "меня", "еще",
+	"нет", "о", "из", "ему", "теперь", "когда", "даже", "ну", "ли", "если",
+	"уже", "или", "ни", "быть", "был", "была", "были", "него", "до", "вас",
+	"нибудь", "опять", "уж", "вам", "ведь", "там", "потом", "себя", "ничего",
+	"ей", "может", "они", "тут", "где", "есть", "надо", "ней", "для", "мы",
+	"тебя", "их", "чем", "сам", "чтобы", "без", "будто", "чего", "раз",
+	"тоже", "себе", "под", "будет", "ж", "тогда", "кто", "этот", "того",
+	"потому", "этого", "какой", "совсем", "ним", "здесь", "этом",
+	"почти", "мой", "тем", "нее", "сейчас", "куда", "зачем", "всех",
+	"никогда", "можно", "при", "наконец", "об", "другой", "хоть",
+	"после", "над", "больше", "тот", "через", "эти", "нас", "про", "всего",
+	"них", "какая", "много", "разве", "эту", "моя", "впрочем",
+	"хорошо", "свою", "этой", "перед", "иногда", "лучше", "чуть", "том",
+	"нельзя", "такой", "им", "более", "всегда", "конечно", "всю", "между",
+	"это", "является", "являлся", "являлась", "также", "около", "примерно",
+	"года", "году", "год", "ответ",
+)
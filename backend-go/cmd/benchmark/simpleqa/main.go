@@ -11,6 +11,9 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/cmd/benchmark/internal/evaluation"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 )
 
 // ============================================================================
@@ -18,13 +21,20 @@ import (
 // ============================================================================
 
 type SearchRequest struct {
-	Query string `json:"query"`
-	Mode  string `json:"mode"`
+	Query   string `json:"query"`
+	Mode    string `json:"mode"`
+	NoCache bool   `json:"no_cache,omitempty"`
 }
 
 type SearchResponse struct {
-	Answer  string   `json:"answer"`
-	Sources []Source `json:"sources"`
+	Answer       string        `json:"answer"`
+	Sources      []Source      `json:"sources"`
+	Verification *Verification `json:"verification,omitempty"`
+}
+
+// Verification is the backend's check of the answer against its sources
+type Verification struct {
+	VerifiedRatio float64 `json:"verified_ratio"`
 }
 
 type Source struct {
@@ -34,6 +44,90 @@ type Source struct {
 	Score   float64 `json:"score,omitempty"`
 }
 
+// ============================================================================
+// API Client
+// ============================================================================
+
+// Code paths a benchmark question can be sent through (-path)
+const (
+	pathSearch       = "search"        // stateless POST /api/search
+	pathChat         = "chat"          // one chat session per question
+	pathChatCategory = "chat-category" // one chat session per category, questions share context
+)
+
+// queryClient sends benchmark questions to the backend, either statelessly or
+// through chat sessions like the Telegram bot does
+type queryClient struct {
+	apiURL   string
+	path     string
+	sessions map[string]string // category -> chat session ID (chat-category)
+	noCache  bool              // skip the answer cache of /api/search
+}
+
+func newQueryClient(apiURL, path string, noCache bool) (*queryClient, error) {
+	switch path {
+	case pathSearch, pathChat, pathChatCategory:
+	default:
+		return nil, fmt.Errorf("unknown path %q (want search, chat or chat-category)", path)
+	}
+	return &queryClient{apiURL: apiURL, path: path, sessions: make(map[string]string), noCache: noCache}, nil
+}
+
+// ask posts the question and returns the raw response; chat messages answer
+// with the same SearchResponse body as /api/search
+func (c *queryClient) ask(query, mode, category string) (*http.Response, error) {
+	jsonData, err := json.Marshal(SearchRequest{Query: query, Mode: mode, NoCache: c.noCache})
+	if err != nil {
+		return nil, err
+	}
+
+	if c.path == pathSearch {
+		return http.Post(c.apiURL+"/api/search", "application/json", bytes.NewBuffer(jsonData))
+	}
+
+	sessionID, err := c.session(mode, category)
+	if err != nil {
+		return nil, err
+	}
+	return http.Post(c.apiURL+"/api/chat/session/"+sessionID+"/message", "application/json", bytes.NewBuffer(jsonData))
+}
+
+// session returns the chat session for the next question
+func (c *queryClient) session(mode, category string) (string, error) {
+	if c.path == pathChatCategory {
+		if id, ok := c.sessions[category]; ok {
+			return id, nil
+		}
+	}
+
+	jsonData, err := json.Marshal(map[string]string{"mode": mode})
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.Post(c.apiURL+"/api/chat/session", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("create session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("create session: HTTP %d: %s", resp.StatusCode, body)
+	}
+
+	var session struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return "", fmt.Errorf("create session: %w", err)
+	}
+
+	if c.path == pathChatCategory {
+		c.sessions[category] = session.ID
+	}
+	return session.ID, nil
+}
+
 // ============================================================================
 // SimpleQA Dataset Types (Hugging Face format)
 // ============================================================================
@@ -89,6 +183,7 @@ type BenchmarkResult struct {
 	SourceCount       int           `json:"source_count"`
 	SourceQuality     float64       `json:"source_quality"`
 	FactualityScore   float64       `json:"factuality_score"`
+	VerifiedRatio     *float64      `json:"verified_ratio,omitempty"`
 	Error             string        `json:"error,omitempty"`
 }
 
@@ -112,6 +207,8 @@ type Stats struct {
 	AvgTime            float64
 	AvgSourceCount     float64
 	AvgFactualityScore float64
+	AvgVerifiedRatio   float64 // over the answers the backend verified
+	VerifiedCount      int
 	TotalTime          time.Duration
 	ByCategory         map[string]CategoryStats
 	ByAnswerType       map[string]CategoryStats
@@ -127,17 +224,23 @@ func main() {
 	offset := flag.Int("offset", 0, "Starting offset in dataset")
 	output := flag.String("output", "", "Output file (auto-generated if empty)")
 	apiURL := flag.String("api", "http://localhost:8000", "Backend API URL")
+	path := flag.String("path", pathSearch, "API path: search (stateless), chat (session per question) or chat-category (session per category)")
+	noCache := flag.Bool("no-cache", false, "Answer every question afresh instead of from the answer cache (search path)")
 	hfToken := flag.String("hf-token", "", "Hugging Face API token (optional)")
 	useLocal := flag.Bool("local", false, "Use local dataset file")
 	localFile := flag.String("file", "simpleqa_dataset.json", "Local dataset file")
 	flag.Parse()
 
 	log.Printf("🧪 SimpleQA Benchmark - Research Assistant")
-	log.Printf("   Mode: %s | API: %s", *mode, *apiURL)
+	log.Printf("   Mode: %s | API: %s | Path: %s", *mode, *apiURL, *path)
+
+	client, err := newQueryClient(*apiURL, *path, *noCache)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
 
 	// Load questions
 	var questions []BenchmarkQuestion
-	var err error
 
 	if *useLocal {
 		questions, err = loadLocalDataset(*localFile)
@@ -153,7 +256,7 @@ func main() {
 
 	// Run benchmark
 	startTime := time.Now()
-	results := runBenchmark(*apiURL, questions, *mode)
+	results := runBenchmark(client, questions, *mode)
 	totalTime := time.Since(startTime)
 
 	// Calculate statistics
@@ -166,6 +269,10 @@ func main() {
 	if *output == "" {
 		*output = fmt.Sprintf("simpleqa_benchmark_%s_%s.json",
 			*mode, time.Now().Format("20060102_150405"))
+		if *path != pathSearch {
+			*output = fmt.Sprintf("simpleqa_benchmark_%s_%s_%s.json",
+				*mode, *path, time.Now().Format("20060102_150405"))
+		}
 	}
 	if err := saveResults(results, stats, *output); err != nil {
 		log.Printf("⚠️  Warning: Failed to save results: %v", err)
@@ -332,15 +439,15 @@ func createSampleDataset() []BenchmarkQuestion {
 // Benchmark Execution
 // ============================================================================
 
-func runBenchmark(apiURL string, questions []BenchmarkQuestion, mode string) []BenchmarkResult {
+func runBenchmark(client *queryClient, questions []BenchmarkQuestion, mode string) []BenchmarkResult {
 	results := make([]BenchmarkResult, 0, len(questions))
 
 	for i, q := range questions {
-		log.Printf("\n[%d/%d] ❓ %s", i+1, len(questions), truncate(q.Question, 100))
-		log.Printf("  📌 Expected: %s", truncate(q.Answer, 80))
+		log.Printf("\n[%d/%d] ❓ %s", i+1, len(questions), utils.TruncateRunesWithEllipsis(q.Question, 100))
+		log.Printf("  📌 Expected: %s", utils.TruncateRunesWithEllipsis(q.Answer, 80))
 		log.Printf("  🏷️  Category: %s | Type: %s", q.Category, q.AnswerType)
 
-		result := runQuestion(apiURL, q, mode)
+		result := runQuestion(client, q, mode)
 		results = append(results, result)
 
 		status := "✅"
@@ -350,7 +457,7 @@ func runBenchmark(apiURL string, questions []BenchmarkQuestion, mode string) []B
 			status = "❌"
 		}
 
-		log.Printf("  💬 Got: %s", truncate(result.ActualAnswer, 80))
+		log.Printf("  💬 Got: %s", utils.TruncateRunesWithEllipsis(result.ActualAnswer, 80))
 		log.Printf("  %s %s | ⏱️  %.2fs | 📚 %d sources | ✓ %.2f",
 			status,
 			formatResult(result),
@@ -362,21 +469,10 @@ func runBenchmark(apiURL string, questions []BenchmarkQuestion, mode string) []B
 	return results
 }
 
-func runQuestion(apiURL string, q BenchmarkQuestion, mode string) BenchmarkResult {
+func runQuestion(client *queryClient, q BenchmarkQuestion, mode string) BenchmarkResult {
 	start := time.Now()
 
-	reqBody := SearchRequest{
-		Query: q.Question,
-		Mode:  mode,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return createErrorResult(q, mode, err, time.Since(start))
-	}
-
-	resp, err := http.Post(apiURL+"/api/search", "application/json",
-		bytes.NewBuffer(jsonData))
+	resp, err := client.ask(q.Question, mode, q.Category)
 	if err != nil {
 		return createErrorResult(q, mode, err, time.Since(start))
 	}
@@ -399,6 +495,10 @@ func runQuestion(apiURL string, q BenchmarkQuestion, mode string) BenchmarkResul
 	correct, partial := evaluateAnswer(searchResp.Answer, q.Answer)
 	sourceQuality := evaluateSourceQuality(searchResp.Sources, q.URLs)
 	factualityScore := evaluateFactuality(searchResp.Answer, q.Answer)
+	var verifiedRatio *float64
+	if searchResp.Verification != nil {
+		verifiedRatio = &searchResp.Verification.VerifiedRatio
+	}
 
 	return BenchmarkResult{
 		ID:               q.ID,
@@ -416,6 +516,7 @@ func runQuestion(apiURL string, q BenchmarkQuestion, mode string) BenchmarkResul
 		SourceCount:      len(searchResp.Sources),
 		SourceQuality:    sourceQuality,
 		FactualityScore:  factualityScore,
+		VerifiedRatio:    verifiedRatio,
 	}
 }
 
@@ -440,71 +541,7 @@ func createErrorResult(q BenchmarkQuestion, mode string, err error, duration tim
 // ============================================================================
 
 func evaluateAnswer(actual, expected string) (correct, partial bool) {
-	if actual == "" {
-		return false, false
-	}
-
-	actualLower := strings.ToLower(strings.TrimSpace(actual))
-	expectedLower := strings.ToLower(strings.TrimSpace(expected))
-
-	// Exact substring match
-	if strings.Contains(actualLower, expectedLower) {
-		return true, false
-	}
-
-	// Check if expected is contained in actual
-	if strings.Contains(expectedLower, actualLower) {
-		return true, false
-	}
-
-	// Extract key terms (words longer than 3 chars)
-	expectedWords := extractKeyWords(expectedLower)
-	actualWords := extractKeyWords(actualLower)
-
-	// Count matches
-	matchCount := 0
-	for _, expWord := range expectedWords {
-		for _, actWord := range actualWords {
-			if expWord == actWord {
-				matchCount++
-				break
-			}
-		}
-	}
-
-	// Partial match if >50% of key words match
-	if len(expectedWords) > 0 {
-		matchRatio := float64(matchCount) / float64(len(expectedWords))
-		if matchRatio >= 0.8 {
-			return true, false
-		}
-		if matchRatio >= 0.5 {
-			return false, true
-		}
-	}
-
-	return false, false
-}
-
-func extractKeyWords(text string) []string {
-	words := strings.Fields(text)
-	keyWords := make([]string, 0)
-
-	stopWords := map[string]bool{
-		"the": true, "is": true, "at": true, "which": true, "on": true,
-		"and": true, "or": true, "but": true, "in": true, "with": true,
-		"was": true, "were": true, "been": true, "being": true, "a": true,
-		"an": true, "of": true, "to": true, "for": true, "as": true,
-	}
-
-	for _, word := range words {
-		cleaned := strings.Trim(word, ".,!?;:\"'()[]{}«»")
-		if len(cleaned) > 3 && !stopWords[cleaned] {
-			keyWords = append(keyWords, cleaned)
-		}
-	}
-
-	return keyWords
+	return evaluation.EvaluateAnswer(actual, expected)
 }
 
 func evaluateSourceQuality(sources []Source, expectedURLs []string) float64 {
@@ -567,25 +604,7 @@ func evaluateFactuality(actual, expected string) float64 {
 	if actual == "" {
 		return 0.0
 	}
-
-	actualWords := extractKeyWords(strings.ToLower(actual))
-	expectedWords := extractKeyWords(strings.ToLower(expected))
-
-	if len(expectedWords) == 0 {
-		return 0.0
-	}
-
-	matches := 0
-	for _, expWord := range expectedWords {
-		for _, actWord := range actualWords {
-			if expWord == actWord {
-				matches++
-				break
-			}
-		}
-	}
-
-	return float64(matches) / float64(len(expectedWords))
+	return evaluation.MatchRatio(evaluation.KeyWords(actual), evaluation.KeyWords(expected))
 }
 
 // ============================================================================
@@ -601,7 +620,7 @@ func calculateStats(results []BenchmarkResult, totalTime time.Duration) Stats {
 	}
 
 	var totalProcessingTime time.Duration
-	var totalSources, totalFactuality float64
+	var totalSources, totalFactuality, totalVerified float64
 
 	for _, r := range results {
 		if r.Correct {
@@ -615,6 +634,10 @@ func calculateStats(results []BenchmarkResult, totalTime time.Duration) Stats {
 		totalProcessingTime += r.ProcessingTime
 		totalSources += float64(r.SourceCount)
 		totalFactuality += r.FactualityScore
+		if r.VerifiedRatio != nil {
+			totalVerified += *r.VerifiedRatio
+			stats.VerifiedCount++
+		}
 
 		// By category
 		updateCategoryStats(stats.ByCategory, r.Category, r)
@@ -632,6 +655,9 @@ func calculateStats(results []BenchmarkResult, totalTime time.Duration) Stats {
 		stats.AvgSourceCount = totalSources / float64(stats.TotalQuestions)
 		stats.AvgFactualityScore = totalFactuality / float64(stats.TotalQuestions)
 	}
+	if stats.VerifiedCount > 0 {
+		stats.AvgVerifiedRatio = totalVerified / float64(stats.VerifiedCount)
+	}
 
 	// Finalize category stats
 	finalizeStatsMap(stats.ByCategory)
@@ -690,6 +716,10 @@ func printDetailedSummary(stats Stats, mode string) {
 	fmt.Printf("\n📚 Quality Metrics:\n")
 	fmt.Printf("  📖 Avg Sources: %.1f per question\n", stats.AvgSourceCount)
 	fmt.Printf("  ✓ Avg Factuality Score: %.2f\n", stats.AvgFactualityScore)
+	if stats.VerifiedCount > 0 {
+		fmt.Printf("  🔎 Avg Verified Ratio: %.2f (%d answers verified)\n",
+			stats.AvgVerifiedRatio, stats.VerifiedCount)
+	}
 
 	fmt.Printf("\n⏱️  Performance:\n")
 	fmt.Printf("  Average Time: %.2fs per question\n", stats.AvgTime)
@@ -735,13 +765,6 @@ func formatResult(r BenchmarkResult) string {
 	return "INCORRECT"
 }
 
-func truncate(s string, length int) string {
-	if len(s) <= length {
-		return s
-	}
-	return s[:length] + "..."
-}
-
 func saveResults(results []BenchmarkResult, stats Stats, filename string) error {
 	output := struct {
 		Timestamp string            `json:"timestamp"`
@@ -37,6 +37,13 @@ func main() {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Initialize Redis (optional - used for quotas)
+	redisClient, err := database.InitRedis(cfg.RedisURL)
+	if err != nil {
+		log.Printf("⚠️  Redis unavailable, quotas disabled: %v", err)
+		redisClient = nil
+	}
+
 	// Set Gin mode
 	if cfg.Debug {
 		gin.SetMode(gin.DebugMode)
@@ -46,13 +53,17 @@ func main() {
 
 	// Create router
 	router := gin.Default()
+	// Only trusted proxies may set the client IP, which rate limits fall back on
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// CORS configuration
 	corsConfig := cors.Config{
 		AllowOrigins:     cfg.CORSOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Accept", "X-API-Key", "X-Request-ID", "If-None-Match"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After", "X-Request-ID", "ETag", "X-Cache"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -62,7 +73,7 @@ func main() {
 	log.Printf("🌐 CORS enabled for origins: %v", cfg.CORSOrigins)
 
 	// Setup routes
-	api.SetupRoutes(router, db, cfg)
+	api.SetupRoutes(router, db, redisClient, cfg)
 
 	// Create server
 	srv := &http.Server{
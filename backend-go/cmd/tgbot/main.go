@@ -3,12 +3,18 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
@@ -22,10 +28,34 @@ type SearchRequest struct {
 }
 
 type SearchResponse struct {
-	Answer    string   `json:"answer"`
-	Sources   []Source `json:"sources"`
-	SessionID string   `json:"session_id,omitempty"`
-	Mode      string   `json:"mode,omitempty"`
+	Answer              string          `json:"answer"`
+	Sources             []Source        `json:"sources"`
+	SessionID           string          `json:"session_id,omitempty"`
+	Mode                string          `json:"mode,omitempty"`
+	Rendered            *RenderedAnswer `json:"rendered,omitempty"`
+	ImprovedAnswerJobID string          `json:"improved_answer_job_id,omitempty"`
+}
+
+// RenderedAnswer is the answer already formatted by the backend (MarkdownV2)
+type RenderedAnswer struct {
+	Format string `json:"format"`
+	Text   string `json:"text"`
+	Footer string `json:"footer,omitempty"` // plain text, already part of Text
+}
+
+// plainAnswer is the answer without markup, for when Telegram rejects the
+// rendered MarkdownV2; it keeps the backend's footer
+func plainAnswer(title string, resp *SearchResponse) string {
+	text := title + "\n" + resp.Answer
+	if resp.Rendered != nil && resp.Rendered.Footer != "" {
+		text += "\n\n" + resp.Rendered.Footer
+	}
+	return text
+}
+
+type JobResponse struct {
+	Status string          `json:"status"`
+	Result *SearchResponse `json:"result,omitempty"`
 }
 
 type Source struct {
@@ -43,6 +73,21 @@ type UserSession struct {
 
 var userSessions = make(map[int64]*UserSession)
 
+// Backend environments the admins can switch a chat to with /env
+var (
+	defaultAPIURL string            // API_URL
+	apiEnvs       map[string]string // name -> URL, from API_ENVIRONMENTS
+	apiEnvNames   []string          // config order
+	botAdmins     map[int64]bool    // BOT_ADMIN_IDS
+
+	chatEnvMu sync.RWMutex
+	chatEnvs  = make(map[int64]string) // chat ID -> environment name
+)
+
+// userKeySecret derives the per-user API keys the backend keeps export
+// integrations under (the bot token, so users cannot forge each other's keys)
+var userKeySecret string
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -56,6 +101,7 @@ func main() {
 		log.Fatal("TELEGRAM_BOT_TOKEN environment variable is required")
 	}
 	log.Println(botToken)
+	userKeySecret = botToken
 
 	apiURL := os.Getenv("API_URL")
 	if apiURL == "" {
@@ -67,8 +113,15 @@ func main() {
 		log.Fatalf("Failed to create bot: %v", err)
 	}
 
+	defaultAPIURL = apiURL
+	apiEnvs, apiEnvNames = parseEnvironments(os.Getenv("API_ENVIRONMENTS"))
+	botAdmins = parseAdminIDs(os.Getenv("BOT_ADMIN_IDS"))
+
 	log.Printf("✅ Bot authorized as @%s", bot.Self.UserName)
 	log.Printf("🔗 Using API: %s", apiURL)
+	if len(apiEnvNames) > 0 {
+		log.Printf("🔀 Switchable environments: %s", strings.Join(apiEnvNames, ", "))
+	}
 
 	// Set up menu buttons
 	setupMenuButtons(bot)
@@ -110,13 +163,16 @@ func main() {
 			case "🆕 Новая сессия":
 				handleNewSessionButton(bot, chatID, userID)
 				continue
+			case "📤 Экспорт":
+				handleExportButton(bot, chatID, userID)
+				continue
 			case "❓ Помощь":
 				handleHelpButton(bot, chatID)
 				continue
 			}
 
 			// Handle regular messages (search queries)
-			go handleQuery(bot, chatID, userID, text, apiURL)
+			go handleQuery(bot, chatID, userID, text, apiURLForChat(chatID))
 		}
 
 		// Handle callback queries (button clicks)
@@ -149,6 +205,7 @@ func handleCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, userID int64) {
 				tgbotapi.NewKeyboardButton("🆕 Новая сессия"),
 			),
 			tgbotapi.NewKeyboardButtonRow(
+				tgbotapi.NewKeyboardButton("📤 Экспорт"),
 				tgbotapi.NewKeyboardButton("❓ Помощь"),
 			),
 		)
@@ -199,6 +256,7 @@ func handleCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, userID int64) {
 				tgbotapi.NewKeyboardButton("🆕 Новая сессия"),
 			),
 			tgbotapi.NewKeyboardButtonRow(
+				tgbotapi.NewKeyboardButton("📤 Экспорт"),
 				tgbotapi.NewKeyboardButton("❓ Помощь"),
 			),
 		)
@@ -235,6 +293,7 @@ func handleCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, userID int64) {
 				tgbotapi.NewKeyboardButton("🆕 Новая сессия"),
 			),
 			tgbotapi.NewKeyboardButtonRow(
+				tgbotapi.NewKeyboardButton("📤 Экспорт"),
 				tgbotapi.NewKeyboardButton("❓ Помощь"),
 			),
 		)
@@ -245,12 +304,120 @@ func handleCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, userID int64) {
 		reply.ReplyMarkup = keyboard
 		bot.Send(reply)
 
+	case "export":
+		handleExportButton(bot, chatID, userID)
+
+	case "env":
+		handleEnvCommand(bot, msg, userID)
+
 	default:
 		reply := tgbotapi.NewMessage(chatID, "❌ Неизвестная команда. Используй /help")
 		bot.Send(reply)
 	}
 }
 
+// handleEnvCommand lists the backend environments (/env) or points the chat at
+// one of them (/env staging, /env reset). Admins only.
+func handleEnvCommand(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, userID int64) {
+	chatID := msg.Chat.ID
+
+	if !botAdmins[userID] {
+		bot.Send(tgbotapi.NewMessage(chatID, "⛔ Команда доступна только администраторам"))
+		return
+	}
+
+	name := strings.TrimSpace(msg.CommandArguments())
+	switch {
+	case name == "":
+		current := chatEnvironment(chatID)
+		var b strings.Builder
+		b.WriteString("🔀 Окружения API:\n")
+		fmt.Fprintf(&b, "%s default — %s\n", envMarker(current == ""), defaultAPIURL)
+		for _, env := range apiEnvNames {
+			fmt.Fprintf(&b, "%s %s — %s\n", envMarker(current == env), env, apiEnvs[env])
+		}
+		b.WriteString("\n/env <имя> — переключить чат, /env reset — вернуть default")
+		bot.Send(tgbotapi.NewMessage(chatID, b.String()))
+		return
+
+	case name == "reset" || name == "default":
+		chatEnvMu.Lock()
+		delete(chatEnvs, chatID)
+		chatEnvMu.Unlock()
+
+	case apiEnvs[name] == "":
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Неизвестное окружение: %s. Список: /env", name)))
+		return
+
+	default:
+		chatEnvMu.Lock()
+		chatEnvs[chatID] = name
+		chatEnvMu.Unlock()
+	}
+
+	// The chat session lives on the previous backend
+	if session, ok := userSessions[userID]; ok {
+		session.SessionID = ""
+	}
+
+	apiURL := apiURLForChat(chatID)
+	log.Printf("🔀 Chat %d switched to %s by user %d", chatID, apiURL, userID)
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Чат использует API: %s\nНачата новая сессия.", apiURL)))
+}
+
+func envMarker(current bool) string {
+	if current {
+		return "▶️"
+	}
+	return "▫️"
+}
+
+// chatEnvironment is the environment the chat was switched to, "" for default
+func chatEnvironment(chatID int64) string {
+	chatEnvMu.RLock()
+	defer chatEnvMu.RUnlock()
+	return chatEnvs[chatID]
+}
+
+// apiURLForChat returns the backend URL the chat's queries go to
+func apiURLForChat(chatID int64) string {
+	if url, ok := apiEnvs[chatEnvironment(chatID)]; ok {
+		return url
+	}
+	return defaultAPIURL
+}
+
+// parseEnvironments parses API_ENVIRONMENTS, e.g.
+// "staging=https://staging.example.com,production=https://api.example.com"
+func parseEnvironments(value string) (map[string]string, []string) {
+	envs := make(map[string]string)
+	var names []string
+	for _, item := range strings.Split(value, ",") {
+		name, url, ok := strings.Cut(strings.TrimSpace(item), "=")
+		name, url = strings.TrimSpace(name), strings.TrimRight(strings.TrimSpace(url), "/")
+		if !ok || name == "" || url == "" {
+			continue
+		}
+		if _, exists := envs[name]; !exists {
+			names = append(names, name)
+		}
+		envs[name] = url
+	}
+	return envs, names
+}
+
+// parseAdminIDs parses BOT_ADMIN_IDS, a comma separated list of Telegram user IDs
+func parseAdminIDs(value string) map[int64]bool {
+	admins := make(map[int64]bool)
+	for _, item := range strings.Split(value, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(item), 10, 64)
+		if err == nil {
+			admins[id] = true
+		}
+	}
+	return admins
+}
+
 func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 	userID := callback.From.ID
 	chatID := callback.Message.Chat.ID
@@ -279,6 +446,96 @@ func handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 		// Answer callback to remove loading state
 		bot.Request(tgbotapi.NewCallback(callback.ID, "Режим изменен"))
 	}
+
+	if strings.HasPrefix(data, "export_") {
+		provider := strings.TrimPrefix(data, "export_")
+		bot.Request(tgbotapi.NewCallback(callback.ID, "Экспортирую..."))
+		go exportSession(bot, chatID, userID, provider, apiURLForChat(chatID))
+	}
+}
+
+func handleExportButton(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
+	if session, ok := userSessions[userID]; !ok || session.SessionID == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, "Пока нечего экспортировать: задайте вопрос"))
+		return
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📝 Notion", "export_notion"),
+			tgbotapi.NewInlineKeyboardButtonData("📄 Google Docs", "export_google_docs"),
+		),
+	)
+	reply := tgbotapi.NewMessage(chatID, "Куда экспортировать текущую сессию?")
+	reply.ReplyMarkup = keyboard
+	bot.Send(reply)
+}
+
+// exportSession pushes the user's chat session to Notion or Google Docs. If
+// the account is not connected yet, the user gets the OAuth link instead.
+func exportSession(bot *tgbotapi.BotAPI, chatID int64, userID int64, provider, apiURL string) {
+	session, ok := userSessions[userID]
+	if !ok || session.SessionID == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, "Пока нечего экспортировать: задайте вопрос"))
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]string{"provider": provider})
+	if err != nil {
+		return
+	}
+
+	url := fmt.Sprintf("%s/api/chat/session/%s/export", apiURL, session.SessionID)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", userAPIKey(userID))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("❌ Export failed: %v", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "❌ Не удалось экспортировать сессию"))
+		return
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		URL     string `json:"url"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Details struct {
+			AuthURL string `json:"auth_url"`
+		} `json:"details"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		bot.Send(tgbotapi.NewMessage(chatID, "✅ Сессия экспортирована: "+result.URL))
+
+	case result.Code == "integration_not_connected" && result.Details.AuthURL != "":
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonURL("🔗 Подключить", result.Details.AuthURL),
+			),
+		)
+		reply := tgbotapi.NewMessage(chatID, "Сначала подключите аккаунт, затем снова нажмите «📤 Экспорт»")
+		reply.ReplyMarkup = keyboard
+		bot.Send(reply)
+
+	default:
+		log.Printf("❌ Export returned status %d: %s", resp.StatusCode, result.Message)
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Экспорт не удался: %s", result.Message)))
+	}
+}
+
+// userAPIKey is the API key the backend identifies the Telegram user by
+func userAPIKey(userID int64) string {
+	mac := hmac.New(sha256.New, []byte(userKeySecret))
+	mac.Write([]byte("tg:" + strconv.FormatInt(userID, 10)))
+	return "tg-" + hex.EncodeToString(mac.Sum(nil))
 }
 
 func handleQuery(bot *tgbotapi.BotAPI, chatID int64, userID int64, query string, apiURL string) {
@@ -324,12 +581,12 @@ func handleQuery(bot *tgbotapi.BotAPI, chatID int64, userID int64, query string,
 
 	log.Printf("✅ Got response: %d sources", len(response.Sources))
 
-	// Format and send response
-	responseText := formatResponse(response)
-	log.Printf("📝 Formatted response (%d chars)", len(responseText))
-
-	msg := tgbotapi.NewMessage(chatID, responseText)
-	msg.ParseMode = "Markdown"
+	// Send the backend-rendered answer (Telegram MarkdownV2)
+	msg := tgbotapi.NewMessage(chatID, response.Answer)
+	if response.Rendered != nil {
+		msg.Text = response.Rendered.Text
+		msg.ParseMode = tgbotapi.ModeMarkdownV2
+	}
 	msg.DisableWebPagePreview = true
 
 	sentMsg, err := bot.Send(msg)
@@ -337,10 +594,63 @@ func handleQuery(bot *tgbotapi.BotAPI, chatID int64, userID int64, query string,
 		log.Printf("❌ Failed to send message: %v", err)
 		// Try without markdown
 		msg.ParseMode = ""
-		msg.Text = fmt.Sprintf("💬 Ответ:\n%s", response.Answer)
+		msg.Text = plainAnswer("💬 Ответ:", response)
 		bot.Send(msg)
 	} else {
 		log.Printf("✅ Message sent successfully: %d", sentMsg.MessageID)
+
+		// Race mode: Pro is still working, edit the message when it finishes
+		if response.ImprovedAnswerJobID != "" {
+			go waitForImprovedAnswer(bot, chatID, sentMsg.MessageID, apiURL, response.ImprovedAnswerJobID)
+		}
+	}
+}
+
+// waitForImprovedAnswer polls the background Pro job and edits the sent answer
+func waitForImprovedAnswer(bot *tgbotapi.BotAPI, chatID int64, messageID int, apiURL, jobID string) {
+	deadline := time.Now().Add(90 * time.Second)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(3 * time.Second)
+
+		resp, err := http.Get(fmt.Sprintf("%s/api/jobs/%s?channel=telegram", apiURL, jobID))
+		if err != nil {
+			log.Printf("⚠️  Failed to poll job %s: %v", jobID, err)
+			continue
+		}
+
+		var job JobResponse
+		err = json.NewDecoder(resp.Body).Decode(&job)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			log.Printf("⚠️  Job %s unavailable (status %d)", jobID, resp.StatusCode)
+			return
+		}
+
+		switch job.Status {
+		case "running":
+			continue
+		case "done":
+			if job.Result == nil {
+				return
+			}
+			edit := tgbotapi.NewEditMessageText(chatID, messageID, job.Result.Answer)
+			if job.Result.Rendered != nil {
+				edit.Text = "✨ *Ответ улучшен режимом Pro*\n\n" + job.Result.Rendered.Text
+				edit.ParseMode = tgbotapi.ModeMarkdownV2
+			}
+			edit.DisableWebPagePreview = true
+			if _, err := bot.Send(edit); err != nil {
+				log.Printf("❌ Failed to edit message with improved answer: %v", err)
+				edit.ParseMode = ""
+				edit.Text = plainAnswer("✨ Улучшенный ответ:", job.Result)
+				bot.Send(edit)
+			}
+			return
+		default:
+			log.Printf("⚠️  Improved answer job %s failed", jobID)
+			return
+		}
 	}
 }
 
@@ -374,9 +684,12 @@ func createChatSession(apiURL, mode string) (string, error) {
 
 // Send message to existing chat session
 func sendChatMessage(apiURL, sessionID, query, mode string) (*SearchResponse, error) {
-	reqBody := map[string]string{
-		"query": query,
-		"mode":  mode,
+	reqBody := map[string]interface{}{
+		"query":   query,
+		"mode":    mode,
+		"race":    mode == "auto", // fast Simple answer, improved by Pro later
+		"channel": "telegram",
+		"format":  "plain", // MarkdownV2 is produced by the telegram renderer
 	}
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -406,69 +719,6 @@ func sendChatMessage(apiURL, sessionID, query, mode string) (*SearchResponse, er
 	return &searchResp, nil
 }
 
-func formatResponse(resp *SearchResponse) string {
-	var builder strings.Builder
-
-	// Answer (no markdown escaping for regular text with Cyrillic)
-	builder.WriteString("💬 *Ответ:*\n")
-	builder.WriteString(resp.Answer)
-	builder.WriteString("\n\n")
-
-	// Sources
-	if len(resp.Sources) > 0 {
-		builder.WriteString("📚 *Источники:*\n")
-		for i, source := range resp.Sources {
-			if i >= 5 { // Limit to 5 sources to avoid message length issues
-				builder.WriteString(fmt.Sprintf("\n...и ещё %d источников", len(resp.Sources)-i))
-				break
-			}
-			builder.WriteString(fmt.Sprintf("%d. %s\n%s\n\n",
-				i+1,
-				truncate(source.Title, 80),
-				source.URL))
-		}
-	}
-
-	// Mode indicator
-	if resp.Mode != "" {
-		builder.WriteString(fmt.Sprintf("\n🔧 Режим: *%s*", resp.Mode))
-	}
-
-	return builder.String()
-}
-
-func escapeMarkdown(text string) string {
-	// Escape special Markdown characters for Telegram MarkdownV2
-	replacer := strings.NewReplacer(
-		"_", "\\_",
-		"*", "\\*",
-		"[", "\\[",
-		"]", "\\]",
-		"(", "\\(",
-		")", "\\)",
-		"~", "\\~",
-		"`", "\\`",
-		">", "\\>",
-		"#", "\\#",
-		"+", "\\+",
-		"-", "\\-",
-		"=", "\\=",
-		"|", "\\|",
-		"{", "\\{",
-		"}", "\\}",
-		".", "\\.",
-		"!", "\\!",
-	)
-	return replacer.Replace(text)
-}
-
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen-3] + "..."
-}
-
 func handleModeButton(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
@@ -502,6 +752,7 @@ func handleNewSessionButton(bot *tgbotapi.BotAPI, chatID int64, userID int64) {
 			tgbotapi.NewKeyboardButton("🆕 Новая сессия"),
 		),
 		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("📤 Экспорт"),
 			tgbotapi.NewKeyboardButton("❓ Помощь"),
 		),
 	)
@@ -539,6 +790,7 @@ func handleHelpButton(bot *tgbotapi.BotAPI, chatID int64) {
 			tgbotapi.NewKeyboardButton("🆕 Новая сессия"),
 		),
 		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("📤 Экспорт"),
 			tgbotapi.NewKeyboardButton("❓ Помощь"),
 		),
 	)
@@ -556,6 +808,7 @@ func setupMenuButtons(bot *tgbotapi.BotAPI) {
 		{Command: "start", Description: "🏠 Начать работу"},
 		{Command: "mode", Description: "🔧 Выбрать режим"},
 		{Command: "newsession", Description: "🆕 Новая сессия"},
+		{Command: "export", Description: "📤 Экспорт в Notion / Google Docs"},
 		{Command: "help", Description: "❓ Помощь"},
 	}
 
@@ -3,25 +3,29 @@ package agents
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
+	"time"
 
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/scrapers"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 )
 
 type AcademicAgent struct {
 	academicScraper *scrapers.AcademicScraper
 	llmClient       *tools.LLMClient
 	reranker        *tools.BM25Reranker
+	evidence        *EvidencePolicy
 }
 
-func NewAcademicAgent(llmClient *tools.LLMClient) *AcademicAgent {
+func NewAcademicAgent(llmClient *tools.LLMClient, evidence *EvidencePolicy) *AcademicAgent {
 	return &AcademicAgent{
 		academicScraper: scrapers.NewAcademicScraper(),
 		llmClient:       llmClient,
 		reranker:        tools.NewBM25Reranker(),
+		evidence:        evidence,
 	}
 }
 
@@ -34,42 +38,42 @@ func (a *AcademicAgent) ProcessWithContext(
 	query string,
 	conversationHistory []models.Message,
 ) (*models.SearchResponse, error) {
-	log.Printf("Pro Academic mode processing: %s", query)
+	logging.Printf(ctx, "Pro Academic mode processing: %s", query)
 
-	reasoningSteps := []string{"🎓 Запущен режим Academic - поиск научных источников"}
+	reasoningSteps := appendStep(ctx, nil, "🎓 Запущен режим Academic - поиск научных источников")
 
 	searchQuery := query
 	if len(conversationHistory) > 0 {
-		reasoningSteps = append(reasoningSteps, "Адаптирую запрос с учетом контекста...")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "Адаптирую запрос с учетом контекста...")
 		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
 		if err == nil && enhanced != "" {
 			searchQuery = enhanced
 		}
 	}
 
-	reasoningSteps = append(reasoningSteps, "Ищу научные статьи в arXiv и Google Scholar...")
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Ищу научные статьи в arXiv и Google Scholar...")
 
 	allResults := make([]models.TavilyResult, 0)
 
 	// arXiv
 	arxivResults, err := a.academicScraper.SearchArxiv(ctx, searchQuery, 5)
 	if err != nil {
-		log.Printf("arXiv search failed: %v", err)
+		logging.Printf(ctx, "arXiv search failed: %v", err)
 	} else {
 		allResults = append(allResults, arxivResults...)
-		reasoningSteps = append(reasoningSteps, fmt.Sprintf("✓ arXiv: %d статей", len(arxivResults)))
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ arXiv: %d статей", len(arxivResults)))
 	}
 
 	// Google Scholar
 	scholarResults, err := a.academicScraper.SearchGoogleScholar(ctx, searchQuery, 5)
 	if err != nil {
-		log.Printf("Scholar search failed: %v", err)
+		logging.Printf(ctx, "Scholar search failed: %v", err)
 	} else {
 		allResults = append(allResults, scholarResults...)
-		reasoningSteps = append(reasoningSteps, fmt.Sprintf("✓ Google Scholar: %d статей", len(scholarResults)))
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Google Scholar: %d статей", len(scholarResults)))
 	}
 
-	if len(allResults) == 0 {
+	if len(allResults) == 0 && !hasDocuments(ctx) {
 		return &models.SearchResponse{
 			Query:     query,
 			Mode:      "pro-academic",
@@ -79,20 +83,28 @@ func (a *AcademicAgent) ProcessWithContext(
 		}, nil
 	}
 
-	reasoningSteps = append(reasoningSteps, fmt.Sprintf("Собрано %d научных источников", len(allResults)))
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("Собрано %d научных источников", len(allResults)))
 
 	// Rerank
+	rerankStart := time.Now()
 	allResults = a.reranker.Rerank(searchQuery, allResults)
+	tools.TrackTime(ctx, tools.TimingRerank, rerankStart)
 
 	if len(allResults) > 10 {
 		allResults = allResults[:10]
 	}
+	allResults = withDocuments(ctx, searchQuery, allResults)
 
-	reasoningSteps = append(reasoningSteps, "Анализирую научные результаты...")
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Анализирую научные результаты...")
+
+	evidence := a.evidence.check(ctx, allResults)
+	if step := evidence.reasoning("ru"); step != "" {
+		reasoningSteps = appendStep(ctx, reasoningSteps, step)
+	}
 
 	// Build LLM prompt
-	var promptBuilder strings.Builder
-	promptBuilder.WriteString(`Ты научный ассистент. Проанализируй академические источники.
+	var system strings.Builder
+	system.WriteString(`Ты научный ассистент. Проанализируй академические источники.
 
 Твоя задача:
 1. Дать научно обоснованный ответ
@@ -101,10 +113,16 @@ func (a *AcademicAgent) ProcessWithContext(
 4. Отметить ключевые выводы
 
 `)
+	system.WriteString(citationInstruction("ru"))
+	system.WriteString(evidence.instruction("ru"))
+	system.WriteString(formatInstruction(ctx, "ru"))
+	system.WriteString(languageInstruction(ctx, "ru"))
+	system.WriteString(personaInstruction(ctx, "ru"))
 
+	var promptBuilder strings.Builder
 	if len(conversationHistory) > 0 {
-		promptBuilder.WriteString("\nКонтекст диалога:\n")
-		for _, msg := range conversationHistory[max(0, len(conversationHistory)-4):] {
+		promptBuilder.WriteString("Контекст диалога:\n")
+		for _, msg := range recentTurns(conversationHistory, 4) {
 			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
 		}
 		promptBuilder.WriteString("\n")
@@ -117,16 +135,18 @@ func (a *AcademicAgent) ProcessWithContext(
 		if i >= 8 {
 			break
 		}
-		content := result.Content
-		if len(content) > 600 {
-			content = content[:600]
-		}
+		content := utils.TruncateRunes(result.Content, 600)
 		promptBuilder.WriteString(fmt.Sprintf("Источник %d: %s\n%s\n\n", i+1, result.Title, content))
 	}
 
-	promptBuilder.WriteString("\nНаучный анализ:")
+	promptBuilder.WriteString("Научный анализ:")
 
-	answer, err := a.llmClient.Complete(ctx, promptBuilder.String(), 0.6, 1200)
+	synthesisStart := time.Now()
+	answer, err := a.llmClient.ForTask(tools.TaskSynthesis).CompletePrompt(ctx, tools.Prompt{
+		System: strings.TrimSpace(system.String()),
+		User:   promptBuilder.String(),
+	}, 0.6, 1200)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
 	if err != nil {
 		return nil, fmt.Errorf("LLM completion failed: %w", err)
 	}
@@ -137,15 +157,14 @@ func (a *AcademicAgent) ProcessWithContext(
 		if i >= 8 {
 			break
 		}
-		snippet := result.Content
-		if len(snippet) > 200 {
-			snippet = snippet[:200] + "..."
-		}
+		snippet := utils.TruncateRunesWithEllipsis(result.Content, 200)
 		sources = append(sources, models.Source{
 			Title:       result.Title,
 			URL:         result.URL,
 			Snippet:     snippet,
 			Credibility: result.Score,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
 		})
 	}
 
@@ -164,13 +183,12 @@ func (a *AcademicAgent) enhanceQueryWithContext(
 	query string,
 	conversationHistory []models.Message,
 ) (string, error) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
+
 	var contextPrompt strings.Builder
 	contextPrompt.WriteString("Предыдущая беседа:\n")
-	for _, msg := range conversationHistory[max(0, len(conversationHistory)-4):] {
-		role := "Пользователь"
-		if msg.Role == "assistant" {
-			role = "Ассистент"
-		}
+	for _, msg := range recentTurns(conversationHistory, 4) {
+		role := turnRole(msg)
 		contextPrompt.WriteString(fmt.Sprintf("%s: %s\n", role, msg.Content))
 	}
 
@@ -180,5 +198,5 @@ func (a *AcademicAgent) enhanceQueryWithContext(
 
 Перефразируй текущий вопрос для поиска научных статей (более формально). Улучшенный запрос:`, contextPrompt.String(), query)
 
-	return a.llmClient.Complete(ctx, enhancePrompt, 0.3, 150)
+	return a.llmClient.ForTask(tools.TaskQueryEnhance).Complete(ctx, enhancePrompt, 0.3, 150)
 }
\ No newline at end of file
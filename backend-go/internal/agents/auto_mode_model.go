@@ -0,0 +1,124 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// AutoModeWeights are logistic regression coefficients, loadable from a JSON file
+type AutoModeWeights struct {
+	Bias              float64 `json:"bias"`
+	HistoryMessages   float64 `json:"history_messages"`
+	QueryWords        float64 `json:"query_words"`
+	ComplexIndicators float64 `json:"complex_indicators"`
+	SimpleIndicators  float64 `json:"simple_indicators"`
+	MultiHop          float64 `json:"multi_hop"`
+}
+
+// defaultAutoModeWeights reproduce the old "pro after 2 messages" rule:
+// 3+ messages of history push the probability above the default 0.6 threshold
+var defaultAutoModeWeights = AutoModeWeights{
+	Bias:            -3.5,
+	HistoryMessages: 1.5,
+}
+
+// AutoModeModel predicts whether a query should be routed to Pro mode
+type AutoModeModel struct {
+	weights         AutoModeWeights
+	proThreshold    float64
+	simpleThreshold float64
+}
+
+func NewAutoModeModel(modelPath string, proThreshold, simpleThreshold float64) *AutoModeModel {
+	weights := defaultAutoModeWeights
+
+	if modelPath != "" {
+		loaded, err := loadAutoModeWeights(modelPath)
+		if err != nil {
+			log.Printf("⚠️  Failed to load auto mode model, using defaults: %v", err)
+		} else {
+			weights = *loaded
+			log.Printf("🧠 Auto mode model loaded from %s", modelPath)
+		}
+	}
+
+	return &AutoModeModel{
+		weights:         weights,
+		proThreshold:    proThreshold,
+		simpleThreshold: simpleThreshold,
+	}
+}
+
+func loadAutoModeWeights(path string) (*AutoModeWeights, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read model file: %w", err)
+	}
+
+	var weights AutoModeWeights
+	if err := json.Unmarshal(data, &weights); err != nil {
+		return nil, fmt.Errorf("parse model file: %w", err)
+	}
+
+	return &weights, nil
+}
+
+// ExtractFeatures builds model features from the query and conversation size
+func (m *AutoModeModel) ExtractFeatures(query string, historySize int, multiHop bool) models.AutoModeFeatures {
+	queryLower := strings.ToLower(query)
+
+	features := models.AutoModeFeatures{
+		HistoryMessages: float64(historySize),
+		QueryWords:      float64(len(strings.Fields(query))),
+	}
+
+	for _, indicator := range complexIndicators {
+		if strings.Contains(queryLower, indicator) {
+			features.ComplexIndicators++
+		}
+	}
+	for _, indicator := range simpleIndicators {
+		if strings.Contains(queryLower, indicator) {
+			features.SimpleIndicators++
+		}
+	}
+	if multiHop {
+		features.MultiHop = 1
+	}
+
+	return features
+}
+
+// ProProbability returns the logistic model probability that Pro is the better mode
+func (m *AutoModeModel) ProProbability(f models.AutoModeFeatures) float64 {
+	w := m.weights
+	z := w.Bias +
+		w.HistoryMessages*f.HistoryMessages +
+		w.QueryWords*f.QueryWords +
+		w.ComplexIndicators*f.ComplexIndicators +
+		w.SimpleIndicators*f.SimpleIndicators +
+		w.MultiHop*f.MultiHop
+
+	return 1.0 / (1.0 + math.Exp(-z))
+}
+
+// Decide returns "pro" or "simple" when the model is confident, or "" to defer
+// to the ModeSelector heuristics
+func (m *AutoModeModel) Decide(f models.AutoModeFeatures) (string, float64) {
+	p := m.ProProbability(f)
+
+	if m.proThreshold > 0 && p >= m.proThreshold {
+		return "pro", p
+	}
+	if m.simpleThreshold > 0 && p <= m.simpleThreshold {
+		return "simple", p
+	}
+
+	return "", p
+}
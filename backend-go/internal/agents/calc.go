@@ -0,0 +1,68 @@
+package agents
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+// calcPattern matches the expressions the LLM leaves to the calculator
+// instead of computing them: [[calc: 2024 - 1969]], [[calc: 26.2 mi to km]]
+var calcPattern = regexp.MustCompile(`\[\[\s*(?i:calc|calculate|вычисли)\s*:\s*([^\[\]]+?)\s*\]\]`)
+
+// calcInstruction asks the LLM to write arithmetic, percentages and unit
+// conversions as calculator expressions, which evaluateCalculations replaces
+// with exact values; LLMs are unreliable at arithmetic
+func calcInstruction(lang string) string {
+	if lang == "ru" {
+		return "Вычисления: не считай в уме. Если ответ требует арифметики, процентов, разницы дат в годах или перевода единиц, напиши выражение в двойных квадратных скобках, оно будет заменено точным результатом: [[calc: 2024 - 1969]], [[calc: 2340 * 15 / 100]], [[calc: 26.2 mi to km]]. Числа бери из источников и пиши без разделителей тысяч.\n"
+	}
+	return "Calculations: do not compute in your head. If the answer needs arithmetic, percentages, differences of years or unit conversions, write the expression in double square brackets and it will be replaced with the exact result: [[calc: 2024 - 1969]], [[calc: 2340 * 15 / 100]], [[calc: 26.2 mi to km]]. Take the numbers from the sources and write them without thousands separators.\n"
+}
+
+// evaluateCalculations replaces the calculator expressions of answer with
+// their values, formatted for lang. An expression that can't be evaluated is
+// left as written, without the brackets.
+func evaluateCalculations(ctx context.Context, answer, lang string) string {
+	if !strings.Contains(answer, "[[") {
+		return answer
+	}
+	return calcPattern.ReplaceAllStringFunc(answer, func(marker string) string {
+		expression := calcPattern.FindStringSubmatch(marker)[1]
+		value, err := calculate(expression, lang)
+		if err != nil {
+			logging.Printf(ctx, "⚠️  Calculation %q failed: %v", expression, err)
+			return expression
+		}
+		logging.Printf(ctx, "🧮 Calculated %s = %s", expression, value)
+		return value
+	})
+}
+
+// calculate evaluates an arithmetic expression or a unit conversion
+// ("5 km to mi") and formats the result for lang
+func calculate(expression, lang string) (string, error) {
+	if q := parseUnitConversion(strings.ToLower(expression)); q != nil {
+		value, err := convertAmount(q)
+		if err != nil {
+			return "", err
+		}
+		to := unitBySymbol(q.to)
+		symbol := to.symbol
+		if lang == "ru" {
+			symbol = to.symbolRu
+		}
+		return formatNumber(value, lang) + " " + symbol, nil
+	}
+
+	// Calculate's functions take one argument, so a comma can only be the
+	// decimal comma of a Russian number
+	value, err := tools.Calculate(strings.ReplaceAll(expression, ",", "."))
+	if err != nil {
+		return "", err
+	}
+	return formatNumber(value, lang), nil
+}
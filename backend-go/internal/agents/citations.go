@@ -0,0 +1,103 @@
+package agents
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// citationPattern matches the markers the LLM writes: [1], [1, 3], [Источник 2].
+// One or two digits only, so years like [2024] are left alone.
+var citationPattern = regexp.MustCompile(`(?i)\[\s*(?:(?:источник|source)\s*)?(\d{1,2}(?:\s*[,;]\s*\d{1,2})*)\s*\]`)
+
+// citationInstruction asks the LLM to tie statements to the numbered sources
+// of the prompt, which are numbered in the order of SearchResponse.Sources
+func citationInstruction(lang string) string {
+	if lang == "ru" {
+		return "\nСсылки: после каждого утверждения из источников ставь номер источника в квадратных скобках, например [1] или [2][3]. Используй только номера из списка источников выше.\n\n"
+	}
+	return "\nCitations: after every statement taken from the sources put the source number in square brackets, e.g. [1] or [2][3]. Only use numbers from the source list above.\n\n"
+}
+
+// linkCitations normalizes the citation markers of the answer to [n], drops
+// the ones pointing past the sources and fills result.Citations in order of
+// first appearance
+func linkCitations(result *models.SearchResponse) {
+	seen := make(map[int]bool)
+	var citations []models.Citation
+
+	var b strings.Builder
+	last := 0
+	for _, m := range citationPattern.FindAllStringSubmatchIndex(result.Answer, -1) {
+		// [1](https://...) is a markdown link, not a citation
+		if m[1] < len(result.Answer) && result.Answer[m[1]] == '(' {
+			continue
+		}
+
+		var markers strings.Builder
+		for _, part := range strings.FieldsFunc(result.Answer[m[2]:m[3]], func(r rune) bool {
+			return r == ',' || r == ';' || r == ' '
+		}) {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 1 || n > len(result.Sources) {
+				continue
+			}
+			marker := "[" + strconv.Itoa(n) + "]"
+			markers.WriteString(marker)
+			if !seen[n] {
+				seen[n] = true
+				citations = append(citations, models.Citation{Marker: marker, SourceIndex: n - 1})
+			}
+		}
+
+		text := result.Answer[last:m[0]]
+		if markers.Len() == 0 {
+			// Drop the space before a removed marker
+			text = strings.TrimRight(text, " ")
+		}
+		b.WriteString(text)
+		b.WriteString(markers.String())
+		last = m[1]
+	}
+	b.WriteString(result.Answer[last:])
+
+	result.Answer = b.String()
+	result.Citations = citations
+}
+
+// renumberCitations rewrites the citation markers of answer through renumber,
+// e.g. when the sources of several answers are merged into one list. Markers
+// renumber rejects are dropped.
+func renumberCitations(answer string, renumber func(n int) (int, bool)) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range citationPattern.FindAllStringSubmatchIndex(answer, -1) {
+		if m[1] < len(answer) && answer[m[1]] == '(' {
+			continue
+		}
+
+		var markers strings.Builder
+		for _, part := range strings.FieldsFunc(answer[m[2]:m[3]], func(r rune) bool {
+			return r == ',' || r == ';' || r == ' '
+		}) {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				continue
+			}
+			if renumbered, ok := renumber(n); ok {
+				markers.WriteString("[" + strconv.Itoa(renumbered) + "]")
+			}
+		}
+		text := answer[last:m[0]]
+		if markers.Len() == 0 {
+			text = strings.TrimRight(text, " ")
+		}
+		b.WriteString(text)
+		b.WriteString(markers.String())
+		last = m[1]
+	}
+	b.WriteString(answer[last:])
+	return b.String()
+}
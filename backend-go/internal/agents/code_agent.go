@@ -0,0 +1,244 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/scrapers"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+type CodeAgent struct {
+	codeScraper *scrapers.CodeScraper
+	llmClient   *tools.LLMClient
+	reranker    *tools.BM25Reranker
+	evidence    *EvidencePolicy
+}
+
+func NewCodeAgent(llmClient *tools.LLMClient, evidence *EvidencePolicy, githubToken, stackExchangeKey string) *CodeAgent {
+	return &CodeAgent{
+		codeScraper: scrapers.NewCodeScraper(githubToken, stackExchangeKey),
+		llmClient:   llmClient,
+		reranker:    tools.NewBM25Reranker(),
+		evidence:    evidence,
+	}
+}
+
+func (a *CodeAgent) Process(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return a.ProcessWithContext(ctx, query, nil)
+}
+
+func (a *CodeAgent) ProcessWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	logging.Printf(ctx, "Pro Code mode processing: %s", query)
+
+	reasoningSteps := appendStep(ctx, nil, "💻 Запущен режим Code - поиск по GitHub и StackOverflow")
+
+	// GitHub and StackOverflow search best with short English keywords
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Составляю поисковый запрос для GitHub и StackOverflow...")
+	searchQuery := query
+	keywords, err := a.searchKeywords(ctx, query, conversationHistory)
+	if err != nil {
+		logging.Printf(ctx, "Code search keywords failed: %v", err)
+	} else if keywords != "" {
+		searchQuery = keywords
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("Запрос: %s", searchQuery))
+	}
+
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Ищу ответы на StackOverflow, issues, коммиты и репозитории на GitHub...")
+
+	allResults := make([]models.TavilyResult, 0)
+
+	// StackOverflow
+	soResults, err := a.codeScraper.SearchStackOverflow(ctx, searchQuery, "stackoverflow", 5)
+	if err != nil {
+		logging.Printf(ctx, "StackOverflow search failed: %v", err)
+	} else {
+		allResults = append(allResults, soResults...)
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ StackOverflow: %d ответов", len(soResults)))
+	}
+
+	// Russian StackOverflow, searched with the original question
+	if detectLanguage(query) == "ru" {
+		ruResults, err := a.codeScraper.SearchStackOverflow(ctx, query, "ru.stackoverflow", 3)
+		if err != nil {
+			logging.Printf(ctx, "ru.StackOverflow search failed: %v", err)
+		} else {
+			allResults = append(allResults, ruResults...)
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ StackOverflow на русском: %d ответов", len(ruResults)))
+		}
+	}
+
+	// GitHub issues and pull requests
+	issueResults, err := a.codeScraper.SearchGitHubIssues(ctx, searchQuery, 5)
+	if err != nil {
+		logging.Printf(ctx, "GitHub issue search failed: %v", err)
+	} else {
+		allResults = append(allResults, issueResults...)
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ GitHub issues: %d", len(issueResults)))
+	}
+
+	// GitHub commits
+	commitResults, err := a.codeScraper.SearchGitHubCommits(ctx, searchQuery, 3)
+	if err != nil {
+		logging.Printf(ctx, "GitHub commit search failed: %v", err)
+	} else {
+		allResults = append(allResults, commitResults...)
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ GitHub коммиты: %d", len(commitResults)))
+	}
+
+	// GitHub repositories
+	repoResults, err := a.codeScraper.SearchGitHubRepos(ctx, searchQuery, 3)
+	if err != nil {
+		logging.Printf(ctx, "GitHub repository search failed: %v", err)
+	} else {
+		allResults = append(allResults, repoResults...)
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ GitHub репозитории: %d", len(repoResults)))
+	}
+
+	if len(allResults) == 0 && !hasDocuments(ctx) {
+		return &models.SearchResponse{
+			Query:     query,
+			Mode:      "pro-code",
+			Answer:    "Не удалось найти ответы на GitHub и StackOverflow по вашему запросу.",
+			Sources:   []models.Source{},
+			Reasoning: strings.Join(reasoningSteps, "\n"),
+		}, nil
+	}
+
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("Собрано %d технических источников", len(allResults)))
+
+	// Rerank
+	rerankStart := time.Now()
+	allResults = a.reranker.Rerank(searchQuery, allResults)
+	tools.TrackTime(ctx, tools.TimingRerank, rerankStart)
+
+	if len(allResults) > 10 {
+		allResults = allResults[:10]
+	}
+	allResults = withDocuments(ctx, searchQuery, allResults)
+
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Анализирую ответы и код...")
+
+	evidence := a.evidence.check(ctx, allResults)
+	if step := evidence.reasoning("ru"); step != "" {
+		reasoningSteps = appendStep(ctx, reasoningSteps, step)
+	}
+
+	// Build LLM prompt
+	var system strings.Builder
+	system.WriteString(`Ты опытный разработчик. Ответь на технический вопрос по ответам StackOverflow и материалам GitHub.
+
+Твоя задача:
+1. Дать рабочее решение с примером кода
+2. Опираться на принятые и высоко оцененные ответы, а также на исправления из issues и коммитов
+3. Ссылаться на конкретный ответ или коммит, из которого взято решение
+4. Указать версии библиотек и языков, если решение от них зависит, и отметить устаревшие подходы
+
+`)
+	if answerFormatFromContext(ctx) == AnswerFormatMarkdown {
+		system.WriteString("Код оформляй блоками ```<язык> ... ``` с указанием языка.\n\n")
+	}
+	system.WriteString(citationInstruction("ru"))
+	system.WriteString(evidence.instruction("ru"))
+	system.WriteString(formatInstruction(ctx, "ru"))
+	system.WriteString(languageInstruction(ctx, "ru"))
+	system.WriteString(personaInstruction(ctx, "ru"))
+
+	var promptBuilder strings.Builder
+	if len(conversationHistory) > 0 {
+		promptBuilder.WriteString("Контекст диалога:\n")
+		for _, msg := range recentTurns(conversationHistory, 4) {
+			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+		}
+		promptBuilder.WriteString("\n")
+	}
+
+	promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\n", query))
+	promptBuilder.WriteString("Технические источники:\n\n")
+
+	for i, result := range allResults {
+		if i >= 8 {
+			break
+		}
+		content := utils.TruncateRunes(result.Content, 1200)
+		promptBuilder.WriteString(fmt.Sprintf("Источник %d: %s\n%s\n\n", i+1, result.Title, content))
+	}
+
+	promptBuilder.WriteString("Решение:")
+
+	synthesisStart := time.Now()
+	answer, err := a.llmClient.ForTask(tools.TaskSynthesis).CompletePrompt(ctx, tools.Prompt{
+		System: strings.TrimSpace(system.String()),
+		User:   promptBuilder.String(),
+	}, 0.3, 1500)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
+	if err != nil {
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+
+	// Format sources
+	sources := make([]models.Source, 0)
+	for i, result := range allResults {
+		if i >= 8 {
+			break
+		}
+		snippet := utils.TruncateRunesWithEllipsis(result.Content, 200)
+		sources = append(sources, models.Source{
+			Title:       result.Title,
+			URL:         result.URL,
+			Snippet:     snippet,
+			Credibility: result.Score,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
+		})
+	}
+
+	return &models.SearchResponse{
+		Query:       query,
+		Mode:        "pro-code",
+		Answer:      answer,
+		Sources:     sources,
+		Reasoning:   strings.Join(reasoningSteps, "\n"),
+		ContextUsed: len(conversationHistory) > 0,
+	}, nil
+}
+
+// searchKeywords rewrites the question (with the conversation, if any) into
+// a short English keyword query, the form GitHub and StackOverflow search
+// handles best
+func (a *CodeAgent) searchKeywords(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (string, error) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
+
+	var contextPrompt strings.Builder
+	if len(conversationHistory) > 0 {
+		contextPrompt.WriteString("Previous conversation:\n")
+		for _, msg := range recentTurns(conversationHistory, 4) {
+			contextPrompt.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+		}
+		contextPrompt.WriteString("\n")
+	}
+
+	keywordsPrompt := fmt.Sprintf(`%sQuestion: %s
+
+Write a search query for StackOverflow and GitHub: 2-5 English keywords with the language, library and error message or API name. Reply with the query only.
+Query:`, contextPrompt.String(), query)
+
+	keywords, err := a.llmClient.ForTask(tools.TaskExtraction).Complete(ctx, keywordsPrompt, 0.2, 40)
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(strings.TrimSpace(keywords), `"'`), nil
+}
@@ -0,0 +1,126 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+const (
+	// conflictSources is how many of the top sources are compared
+	conflictSources = 5
+	maxConflicts    = 5
+)
+
+// detectConflicts asks the LLM for facts on which the top sources contradict
+// each other. Different wording or details mentioned by one source only are
+// not conflicts. Source indexes refer to results.
+func (a *ProAgent) detectConflicts(
+	ctx context.Context,
+	query string,
+	results []models.TavilyResult,
+	lang string,
+) ([]models.SourceConflict, error) {
+	if len(results) > conflictSources {
+		results = results[:conflictSources]
+	}
+	if len(results) < 2 {
+		return nil, nil
+	}
+
+	var promptBuilder strings.Builder
+	if lang == "ru" {
+		promptBuilder.WriteString(`Найди факты, по которым источники прямо противоречат друг другу: разные числа, даты, имена, исходы событий.
+Разные формулировки одного факта и детали, которые упоминает только один источник, противоречиями не считаются.
+
+`)
+		promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\n", query))
+	} else {
+		promptBuilder.WriteString(`Find facts on which the sources directly contradict each other: different numbers, dates, names, outcomes of events.
+Different wording of the same fact and details mentioned by one source only are not contradictions.
+
+`)
+		promptBuilder.WriteString(fmt.Sprintf("Question: %s\n\n", query))
+	}
+
+	for i, result := range results {
+		content := result.Content
+		if result.RawContent != "" {
+			content = result.RawContent
+		}
+		content = a.llmClient.TruncateTokens(utils.SanitizeUTF8(content), sourceTokens)
+		if lang == "ru" {
+			promptBuilder.WriteString(fmt.Sprintf("Источник %d (%s):\n%s\n\n", i+1, result.Title, content))
+		} else {
+			promptBuilder.WriteString(fmt.Sprintf("Source %d (%s):\n%s\n\n", i+1, result.Title, content))
+		}
+	}
+
+	if lang == "ru" {
+		promptBuilder.WriteString(`Верни ТОЛЬКО JSON-объект; пустой список, если противоречий нет:
+{"conflicts": [{"claim": "о чём расходятся источники", "source_a": 1, "statement_a": "что утверждает источник 1", "source_b": 3, "statement_b": "что утверждает источник 3"}]}
+`)
+	} else {
+		promptBuilder.WriteString(`Return ONLY a JSON object; an empty list if there are no contradictions:
+{"conflicts": [{"claim": "what the sources disagree on", "source_a": 1, "statement_a": "what source 1 says", "source_b": 3, "statement_b": "what source 3 says"}]}
+`)
+	}
+	promptBuilder.WriteString(languageInstruction(ctx, lang))
+
+	response, err := a.llmClient.ForTask(tools.TaskExtraction).ChatCompletionJSON(ctx, []map[string]string{
+		{"role": "user", "content": promptBuilder.String()},
+	}, 0.1, 800)
+	if err != nil {
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+	return parseConflicts(response, len(results))
+}
+
+// parseConflicts reads the LLM conflicts between sourceCount sources. Source
+// numbers are 1-based in the prompt and 0-based in the conflicts; conflicts
+// with an unknown or repeated source are dropped.
+func parseConflicts(response string, sourceCount int) ([]models.SourceConflict, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON object in conflicts response")
+	}
+
+	var raw struct {
+		Conflicts []struct {
+			Claim      string `json:"claim"`
+			SourceA    int    `json:"source_a"`
+			StatementA string `json:"statement_a"`
+			SourceB    int    `json:"source_b"`
+			StatementB string `json:"statement_b"`
+		} `json:"conflicts"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("invalid conflicts JSON: %w", err)
+	}
+
+	var conflicts []models.SourceConflict
+	for _, r := range raw.Conflicts {
+		a, b := r.SourceA-1, r.SourceB-1
+		claim := strings.TrimSpace(r.Claim)
+		if claim == "" || a == b || a < 0 || b < 0 || a >= sourceCount || b >= sourceCount {
+			continue
+		}
+		conflicts = append(conflicts, models.SourceConflict{
+			Claim:      claim,
+			SourceA:    a,
+			StatementA: strings.TrimSpace(r.StatementA),
+			SourceB:    b,
+			StatementB: strings.TrimSpace(r.StatementB),
+		})
+		if len(conflicts) == maxConflicts {
+			break
+		}
+	}
+	return conflicts, nil
+}
@@ -0,0 +1,72 @@
+package agents
+
+import (
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+// sourceTokens caps the text of a source in the prompts of side calls
+// (conflicts, claim verdicts)
+const sourceTokens = 300
+
+// fitPrompt renders the prompt with the contents of its sources, trimmed when
+// the prompt (system prompt and examples included) and a reply of maxTokens
+// don't fit the model's context window.
+// The longest contents are cut first, down to a common length. A prompt that
+// doesn't fit even without source text is returned as is: the LLM call
+// rejects it with tools.ErrContextOverflow.
+func fitPrompt(
+	llmClient *tools.LLMClient,
+	contents []string,
+	maxTokens int,
+	render func(contents []string) tools.Prompt,
+) tools.Prompt {
+	prompt := render(contents)
+	excess := llmClient.PromptTokens(prompt) - llmClient.PromptBudget(maxTokens)
+	if excess <= 0 {
+		return prompt
+	}
+	// Plus a token for the ellipsis of each cut content
+	return render(trimContents(llmClient, contents, excess+len(contents)))
+}
+
+// trimContents cuts the contents by excess tokens in total, cutting each to
+// at most the same number of tokens
+func trimContents(llmClient *tools.LLMClient, contents []string, excess int) []string {
+	counts := make([]int, len(contents))
+	longest := 0
+	for i, content := range contents {
+		counts[i] = llmClient.CountTokens(content)
+		longest = max(longest, counts[i])
+	}
+
+	// The highest common length that cuts enough
+	cut := func(length int) int {
+		total := 0
+		for _, count := range counts {
+			total += max(0, count-length)
+		}
+		return total
+	}
+	low, high := 0, longest
+	for low < high {
+		mid := (low + high + 1) / 2
+		if cut(mid) >= excess {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+
+	trimmed := make([]string, len(contents))
+	for i, content := range contents {
+		switch {
+		case counts[i] <= low:
+			trimmed[i] = content
+		case low == 0:
+			trimmed[i] = ""
+		default:
+			trimmed[i] = llmClient.TruncateTokens(content, low) + "…"
+		}
+	}
+	return trimmed
+}
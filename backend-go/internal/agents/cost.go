@@ -0,0 +1,178 @@
+package agents
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// defaultEstimates are the expected latency and LLM tokens of a query in each
+// mode before any query was observed
+var defaultEstimates = map[string]models.ModeEstimate{
+	"simple":        {LatencyMs: 2500, PromptTokens: 1500, CompletionTokens: 300},
+	"pro":           {LatencyMs: 14000, PromptTokens: 11500, CompletionTokens: 2200},
+	"deep":          {LatencyMs: 50000, PromptTokens: 20000, CompletionTokens: 3000},
+	"pro-social":    {LatencyMs: 9000, PromptTokens: 3500, CompletionTokens: 800},
+	"pro-academic":  {LatencyMs: 9000, PromptTokens: 3500, CompletionTokens: 800},
+	"pro-finance":   {LatencyMs: 9000, PromptTokens: 3500, CompletionTokens: 800},
+	"pro-news":      {LatencyMs: 9000, PromptTokens: 3500, CompletionTokens: 800},
+	"pro-code":      {LatencyMs: 9000, PromptTokens: 4500, CompletionTokens: 1000},
+	"pro-video":     {LatencyMs: 12000, PromptTokens: 5000, CompletionTokens: 900},
+	"pro-factcheck": {LatencyMs: 10000, PromptTokens: 5000, CompletionTokens: 900},
+	"pro-tools":     {LatencyMs: 25000, PromptTokens: 12000, CompletionTokens: 1500},
+	"pro-mixed":     {LatencyMs: 18000, PromptTokens: 14000, CompletionTokens: 3500},
+	"images":        {LatencyMs: 4000, PromptTokens: 1500, CompletionTokens: 300},
+	"instant":       {LatencyMs: 600},
+}
+
+// DefaultEstimate returns the built-in estimate of a mode
+func DefaultEstimate(mode string) models.ModeEstimate {
+	estimate := defaultEstimates[mode]
+	estimate.Mode = mode
+	return estimate
+}
+
+// costSmoothing is the weight of the latest query in the running averages
+const costSmoothing = 0.2
+
+// ModeCosts keeps, in memory, running averages of the latency and LLM tokens
+// of the queries each mode answered, starting from the built-in estimates.
+// Auto mode consults them to stay within the token budget of a session.
+type ModeCosts struct {
+	mu       sync.Mutex
+	observed map[string]*observedCost
+}
+
+type observedCost struct {
+	samples          int64
+	latencyMs        float64
+	promptTokens     float64
+	completionTokens float64
+}
+
+func NewModeCosts() *ModeCosts {
+	return &ModeCosts{observed: make(map[string]*observedCost)}
+}
+
+// Record adds an answered query of mode to its averages
+func (c *ModeCosts) Record(mode string, latency time.Duration, promptTokens, completionTokens int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cost, ok := c.observed[mode]
+	if !ok {
+		start := defaultEstimates[mode]
+		cost = &observedCost{
+			latencyMs:        float64(start.LatencyMs),
+			promptTokens:     float64(start.PromptTokens),
+			completionTokens: float64(start.CompletionTokens),
+		}
+		c.observed[mode] = cost
+	}
+	cost.samples++
+	cost.latencyMs += costSmoothing * (float64(latency.Milliseconds()) - cost.latencyMs)
+	cost.promptTokens += costSmoothing * (float64(promptTokens) - cost.promptTokens)
+	cost.completionTokens += costSmoothing * (float64(completionTokens) - cost.completionTokens)
+}
+
+// Estimate returns the expected latency and tokens of a query in mode
+func (c *ModeCosts) Estimate(mode string) models.ModeEstimate {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cost, ok := c.observed[mode]
+	if !ok {
+		return DefaultEstimate(mode)
+	}
+	return models.ModeEstimate{
+		Mode:             mode,
+		LatencyMs:        int64(math.Round(cost.latencyMs)),
+		PromptTokens:     int64(math.Round(cost.promptTokens)),
+		CompletionTokens: int64(math.Round(cost.completionTokens)),
+		Samples:          cost.samples,
+	}
+}
+
+type sessionSpendKey struct{}
+
+// WithSessionSpend records the LLM tokens the chat session of ctx has spent
+// so far, which auto mode keeps within SESSION_TOKEN_BUDGET
+func WithSessionSpend(ctx context.Context, tokens int64) context.Context {
+	return context.WithValue(ctx, sessionSpendKey{}, tokens)
+}
+
+// sessionSpendFromContext returns the tokens spent by the session and whether
+// the request belongs to one
+func sessionSpendFromContext(ctx context.Context) (int64, bool) {
+	tokens, ok := ctx.Value(sessionSpendKey{}).(int64)
+	return tokens, ok
+}
+
+// budgetFallbacks are the cheaper modes that still answer a query of a mode,
+// most capable first and cheapest last
+func budgetFallbacks(mode string) []string {
+	switch mode {
+	case "simple", "instant", "images":
+		return nil
+	case "deep":
+		return []string{"pro", "simple"}
+	default:
+		return []string{"simple"}
+	}
+}
+
+// EstimateCost returns the cost in USD of the tokens of estimate at the
+// prices of the primary model
+func (r *RouterAgent) EstimateCost(estimate models.ModeEstimate) float64 {
+	cost := r.llmClient.EstimateCost(estimate.PromptTokens, estimate.CompletionTokens)
+	return math.Round(cost*1e6) / 1e6
+}
+
+// routingCost estimates a query in mode for the auto routing explanation
+func (r *RouterAgent) routingCost(mode string) *models.RoutingCost {
+	estimate := r.costs.Estimate(mode)
+	return &models.RoutingCost{
+		LatencyMs: estimate.LatencyMs,
+		Tokens:    estimate.PromptTokens + estimate.CompletionTokens,
+		CostUSD:   r.EstimateCost(estimate),
+	}
+}
+
+// withinBudget returns mode, or the most capable of its fallbacks expected to
+// fit in the remaining token budget of the session (the cheapest one when
+// none fits), and the routing cost of the result. Requests outside a chat
+// session and unlimited budgets keep mode.
+func (r *RouterAgent) withinBudget(ctx context.Context, mode string) (string, *models.RoutingCost) {
+	cost := r.routingCost(mode)
+	spent, inSession := sessionSpendFromContext(ctx)
+	if !inSession || r.cfg.SessionTokenBudget <= 0 {
+		return mode, cost
+	}
+	cost.SessionTokens = spent
+	cost.SessionBudget = r.cfg.SessionTokenBudget
+
+	remaining := r.cfg.SessionTokenBudget - spent
+	if cost.Tokens <= remaining {
+		return mode, cost
+	}
+
+	fallbacks := budgetFallbacks(mode)
+	if len(fallbacks) == 0 {
+		return mode, cost
+	}
+	selected := fallbacks[len(fallbacks)-1]
+	for _, fallback := range fallbacks {
+		if r.routingCost(fallback).Tokens <= remaining {
+			selected = fallback
+			break
+		}
+	}
+	selectedCost := r.routingCost(selected)
+	selectedCost.SessionTokens = spent
+	selectedCost.SessionBudget = r.cfg.SessionTokenBudget
+	selectedCost.DowngradedFrom = mode
+	return selected, selectedCost
+}
@@ -0,0 +1,170 @@
+package agents
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// Temporal phrases of queries. Years are four digits from 1900 to 2099; a
+// bare year ("2018 world cup") is a topic rather than a period and is not
+// matched on its own.
+var (
+	yearSpanPattern   = regexp.MustCompile(`(?:between|from|с|со|между)?\s*((?:19|20)\d\d)\s*(?:-|–|—|to|and|по|до|и)\s*((?:19|20)\d\d)(?:\s*(?:годами|годах|годов|годы|года|гг?\.?))?`)
+	sinceYearPattern  = regexp.MustCompile(`(?:since|after|с|со|после)\s+((?:19|20)\d\d)(?:\s*(?:года|г\.?))?`)
+	beforeYearPattern = regexp.MustCompile(`(?:before|until|до)\s+((?:19|20)\d\d)(?:\s*(?:года|г\.?))?`)
+	inYearPattern     = regexp.MustCompile(`(?:in|during|в|во|за)\s+((?:19|20)\d\d)(?:\s*(?:году|год|г\.?))?(?:$|[^\d])`)
+	lastNPattern      = regexp.MustCompile(`(?:last|past|за последние|за последних|за)\s+(\d{1,3})\s+(day|week|month|year|дн|день|недел|месяц|год|лет)[a-zа-яё]*`)
+)
+
+// relativePeriods are windows ending now or calendar periods relative to
+// now, checked in order; the first phrase found wins
+var relativePeriods = []struct {
+	phrases []string
+	period  func(now time.Time) (from, to time.Time)
+}{
+	{[]string{"yesterday", "вчера"}, func(now time.Time) (time.Time, time.Time) {
+		day := startOfDay(now).AddDate(0, 0, -1)
+		return day, day.Add(24*time.Hour - time.Second)
+	}},
+	{[]string{"today", "сегодня"}, func(now time.Time) (time.Time, time.Time) {
+		return startOfDay(now), time.Time{}
+	}},
+	{[]string{"last year", "previous year", "в прошлом году", "прошлого года"}, func(now time.Time) (time.Time, time.Time) {
+		year := time.Date(now.Year()-1, 1, 1, 0, 0, 0, 0, time.UTC)
+		return year, year.AddDate(1, 0, 0).Add(-time.Second)
+	}},
+	{[]string{"this year", "в этом году", "этого года", "с начала года"}, func(now time.Time) (time.Time, time.Time) {
+		return time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}
+	}},
+	{[]string{"past year", "last 12 months", "за последний год", "за год"}, func(now time.Time) (time.Time, time.Time) {
+		return now.AddDate(-1, 0, 0), time.Time{}
+	}},
+	{[]string{"this month", "в этом месяце", "этого месяца"}, func(now time.Time) (time.Time, time.Time) {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), time.Time{}
+	}},
+	{[]string{"last month", "past month", "за последний месяц", "за месяц", "в прошлом месяце"}, func(now time.Time) (time.Time, time.Time) {
+		return now.AddDate(0, -1, 0), time.Time{}
+	}},
+	{[]string{"this week", "last week", "past week", "на этой неделе", "на прошлой неделе", "за последнюю неделю", "за неделю"}, func(now time.Time) (time.Time, time.Time) {
+		return now.AddDate(0, 0, -7), time.Time{}
+	}},
+}
+
+// detectDateRange reads the period a query asks about: a year ("in 2019",
+// "в 2019 году"), a span of years, an open bound ("since 2020", "до 2010
+// года") or a window relative to now ("за последнюю неделю", "last 3
+// months"). Nil when the query names no period.
+func detectDateRange(query string, now time.Time) *models.DateRange {
+	q := strings.ToLower(query)
+	now = now.UTC()
+
+	if m := yearSpanPattern.FindStringSubmatchIndex(q); m != nil {
+		from, _ := strconv.Atoi(q[m[2]:m[3]])
+		to, _ := strconv.Atoi(q[m[4]:m[5]])
+		if from <= to {
+			return yearsRange(from, to, periodLabel(q[m[0]:m[1]]))
+		}
+	}
+	if m := sinceYearPattern.FindStringSubmatch(q); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		return &models.DateRange{From: yearStart(year).Unix(), Label: periodLabel(m[0])}
+	}
+	if m := beforeYearPattern.FindStringSubmatch(q); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		return &models.DateRange{To: yearStart(year).Unix() - 1, Label: periodLabel(m[0])}
+	}
+	if m := inYearPattern.FindStringSubmatch(q); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		return yearsRange(year, year, periodLabel(m[0]))
+	}
+	if m := lastNPattern.FindStringSubmatch(q); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n > 0 {
+			var from time.Time
+			switch m[2] {
+			case "day", "дн", "день":
+				from = now.AddDate(0, 0, -n)
+			case "week", "недел":
+				from = now.AddDate(0, 0, -7*n)
+			case "month", "месяц":
+				from = now.AddDate(0, -n, 0)
+			default:
+				from = now.AddDate(-n, 0, 0)
+			}
+			return &models.DateRange{From: from.Unix(), Label: periodLabel(m[0])}
+		}
+	}
+
+	for _, period := range relativePeriods {
+		for _, phrase := range period.phrases {
+			if strings.Contains(q, phrase) {
+				from, to := period.period(now)
+				r := &models.DateRange{From: from.Unix(), Label: phrase}
+				if !to.IsZero() {
+					r.To = to.Unix()
+				}
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// queryDateRange is the period a query asks about, read from the query or,
+// failing that, from the period the query extractor found
+func queryDateRange(ctx context.Context, query string, constraints *models.QueryConstraints) *models.DateRange {
+	now := time.Now()
+	r := detectDateRange(query, now)
+	if r == nil && constraints != nil {
+		r = parsePeriod(constraints.Period, now)
+	}
+	if r != nil {
+		logging.Printf(ctx, "📅 Date range %q: %d - %d", r.Label, r.From, r.To)
+	}
+	return r
+}
+
+// parsePeriod reads the period the query extractor found ("2008",
+// "2015-2020"), which may lack the prepositions detectDateRange needs
+func parsePeriod(period string, now time.Time) *models.DateRange {
+	period = strings.TrimSpace(period)
+	if period == "" {
+		return nil
+	}
+	if year, err := strconv.Atoi(period); err == nil && year >= 1900 && year <= 2099 {
+		return yearsRange(year, year, period)
+	}
+	return detectDateRange(period, now)
+}
+
+// periodLabel trims the spaces and punctuation a pattern matched around a
+// period phrase
+func periodLabel(match string) string {
+	return strings.TrimFunc(match, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// yearsRange spans whole years from and to
+func yearsRange(from, to int, label string) *models.DateRange {
+	return &models.DateRange{
+		From:  yearStart(from).Unix(),
+		To:    yearStart(to+1).Unix() - 1,
+		Label: label,
+	}
+}
+
+func yearStart(year int) time.Time {
+	return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
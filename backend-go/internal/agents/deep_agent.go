@@ -0,0 +1,393 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+const (
+	// The final answer gets this much of the time and token budgets; the
+	// research loop stops early enough to leave it
+	deepSynthesisTime   = 20 * time.Second
+	deepSynthesisTokens = 4000
+	// deepAnswerTokens bounds the answer itself
+	deepAnswerTokens = 1800
+
+	// deepFollowUps bounds the new sub-questions added per round and
+	// deepMaxSubQuestions all of them
+	deepFollowUps       = 3
+	deepMaxSubQuestions = 10
+
+	// deepSources is how many sources the final answer is written from
+	deepSources = 12
+)
+
+// DeepAgent researches iteratively: it searches the open sub-questions, reads
+// what was found, decides which sub-questions are resolved and what is still
+// missing, and searches again until nothing is missing or a time, token or
+// round budget runs out. Search and ranking are Pro's.
+type DeepAgent struct {
+	pro       *ProAgent
+	llmClient *tools.LLMClient
+	evidence  *EvidencePolicy
+	timeout   time.Duration
+	maxTokens int64
+	maxRounds int
+}
+
+func NewDeepAgent(
+	pro *ProAgent,
+	llmClient *tools.LLMClient,
+	evidence *EvidencePolicy,
+	timeout time.Duration,
+	maxTokens, maxRounds int,
+) *DeepAgent {
+	return &DeepAgent{
+		pro:       pro,
+		llmClient: llmClient,
+		evidence:  evidence,
+		timeout:   timeout,
+		maxTokens: int64(maxTokens),
+		maxRounds: maxRounds,
+	}
+}
+
+func (a *DeepAgent) Process(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return a.ProcessWithContext(ctx, query, nil)
+}
+
+func (a *DeepAgent) ProcessWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	budgetCtx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	result, err := a.process(budgetCtx, query, conversationHistory)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(budgetCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: deep mode ran out of its %s budget: %w", tools.ErrBudgetExceeded, a.timeout, err)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *DeepAgent) process(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	start := time.Now()
+	lang := answerLanguage(ctx, query)
+	ctx, meter := tools.WithTokenMeter(ctx)
+	logging.Printf(ctx, "Deep mode processing: %s (budget %s, %d tokens, %d rounds)",
+		query, a.timeout, a.maxTokens, a.maxRounds)
+
+	reasoningSteps := appendStep(ctx, nil, "🧭 Запущен режим Deep - итеративное исследование")
+
+	searchQuery := query
+	if len(conversationHistory) > 0 {
+		enhanced, err := a.pro.enhanceQueryWithContext(ctx, query, conversationHistory, lang)
+		if err != nil {
+			logging.Printf(ctx, "⚠️  LLM failed to enhance query, using original: %v", err)
+		} else if enhanced != "" {
+			searchQuery = enhanced
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✨ Улучшенный запрос: \"%s\"", searchQuery))
+		}
+	}
+
+	trace := &models.ResearchTrace{}
+	subQueries, _ := a.pro.generateSubQueries(ctx, searchQuery, lang)
+	for _, q := range subQueries {
+		trace.SubQuestions = append(trace.SubQuestions, models.SubQuestion{Question: q, Round: 1})
+	}
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("📋 План: %d %s", len(trace.SubQuestions), subQuestionsRu(len(trace.SubQuestions))))
+
+	var results []models.TavilyResult
+	seen := make(map[string]bool)
+	for round := 1; ; round++ {
+		open := openSubQuestions(trace, round)
+		switch {
+		case len(open) == 0 && allResolved(trace):
+			trace.StoppedBy = "resolved"
+		case len(open) == 0:
+			trace.StoppedBy = "exhausted"
+		case round > a.maxRounds:
+			trace.StoppedBy = "rounds"
+		case time.Since(start) > a.timeout-deepSynthesisTime:
+			trace.StoppedBy = "time"
+		case meter.TotalTokens() > a.maxTokens-deepSynthesisTokens:
+			trace.StoppedBy = "tokens"
+		}
+		if trace.StoppedBy != "" {
+			break
+		}
+
+		trace.Rounds = round
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("🔁 Раунд %d: ищу %d подвопроса", round, len(open)))
+		found := 0
+		for _, result := range a.pro.parallelSubQuerySearch(ctx, open, lang, &reasoningSteps) {
+			key := strings.TrimSuffix(result.URL, "/")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			results = append(results, result)
+			found++
+		}
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("📚 Новых источников: %d, всего: %d", found, len(results)))
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// Read what was found and decide what is still missing
+		resolved, missing, err := a.assess(ctx, searchQuery, trace, results)
+		if err != nil {
+			logging.Printf(ctx, "⚠️  Deep research assessment failed: %v", err)
+			trace.StoppedBy = "exhausted"
+			break
+		}
+		for _, n := range resolved {
+			if n >= 1 && n <= len(trace.SubQuestions) {
+				trace.SubQuestions[n-1].Resolved = true
+			}
+		}
+		added := addSubQuestions(trace, missing, round+1)
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("🧩 Решено подвопросов: %d из %d, новых: %d",
+			countResolved(trace), len(trace.SubQuestions), added))
+	}
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("⏹ Исследование завершено (%s) после %d раундов", trace.StoppedBy, trace.Rounds))
+	logging.Printf(ctx, "🧭 Deep research stopped by %s after %d rounds, %d sources, %d tokens",
+		trace.StoppedBy, trace.Rounds, len(results), meter.TotalTokens())
+
+	if len(results) == 0 && !hasDocuments(ctx) {
+		trace.Tokens = meter.TotalTokens()
+		return &models.SearchResponse{
+			Query:       query,
+			Mode:        "deep",
+			Answer:      "Не удалось найти релевантную информацию по вашему запросу.",
+			Sources:     []models.Source{},
+			Reasoning:   strings.Join(reasoningSteps, "\n"),
+			ContextUsed: len(conversationHistory) > 0,
+			Research:    trace,
+		}, nil
+	}
+
+	rerankStart := time.Now()
+	ranked := a.pro.reranker.Rerank(searchQuery, results)
+	tools.TrackTime(ctx, tools.TimingRerank, rerankStart)
+	credibilityStart := time.Now()
+	ranked = a.pro.credibilityScorer.RankSources(ranked)
+	tools.TrackTime(ctx, tools.TimingCredibility, credibilityStart)
+	top := withDocuments(ctx, searchQuery, a.pro.selectDiverseSources(ranked, deepSources))
+
+	evidence := a.evidence.check(ctx, top)
+	if step := evidence.reasoning("ru"); step != "" {
+		reasoningSteps = appendStep(ctx, reasoningSteps, step)
+	}
+	reasoningSteps = appendStep(ctx, reasoningSteps, "💡 Формирую итоговый ответ...")
+
+	contents := make([]string, len(top))
+	for i, result := range top {
+		content := result.Content
+		if result.RawContent != "" {
+			content = result.RawContent
+		}
+		contents[i] = utils.TruncateRunesWithEllipsis(utils.SanitizeUTF8(content), 700)
+	}
+	llm := a.llmClient.ForTask(tools.TaskSynthesis)
+	prompt := fitPrompt(llm, contents, deepAnswerTokens, func(contents []string) tools.Prompt {
+		var system strings.Builder
+		system.WriteString(`Ты исследовательский ассистент. По итогам многоэтапного поиска дай полный, структурированный ответ на вопрос.
+
+Твоя задача:
+1. Ответить на каждый решённый подвопрос и связать ответы в общий вывод
+2. Прямо указать, что осталось невыясненным по открытым подвопросам
+3. Отметить противоречия между источниками
+
+`)
+		system.WriteString(citationInstruction("ru"))
+		system.WriteString(calcInstruction("ru"))
+		system.WriteString(evidence.instruction("ru"))
+		system.WriteString(formatInstruction(ctx, "ru"))
+		system.WriteString(languageInstruction(ctx, "ru"))
+		system.WriteString(personaInstruction(ctx, "ru"))
+
+		var promptBuilder strings.Builder
+		if len(conversationHistory) > 0 {
+			promptBuilder.WriteString("Контекст диалога:\n")
+			for _, msg := range recentTurns(conversationHistory, 4) {
+				promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+			}
+			promptBuilder.WriteString("\n")
+		}
+		promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\n", query))
+		promptBuilder.WriteString("Подвопросы исследования:\n")
+		promptBuilder.WriteString(subQuestionList(trace))
+		promptBuilder.WriteString("\nИсточники:\n\n")
+		for i, result := range top {
+			promptBuilder.WriteString(fmt.Sprintf("Источник %d (%s):\n%s\n\n", i+1, result.Title, contents[i]))
+		}
+		promptBuilder.WriteString("Ответ:")
+		return tools.Prompt{System: strings.TrimSpace(system.String()), User: promptBuilder.String()}
+	})
+
+	synthesisStart := time.Now()
+	answer, err := llm.CompletePrompt(ctx, prompt, 0.5, deepAnswerTokens)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
+	degraded := errors.Is(err, tools.ErrLLMCircuitOpen)
+	if degraded {
+		answer, err = searchOnlyAnswer(ctx, top, "ru"), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+	trace.Tokens = meter.TotalTokens()
+
+	sources := make([]models.Source, 0, len(top))
+	for _, result := range top {
+		sources = append(sources, models.Source{
+			Title:       utils.SanitizeUTF8(result.Title),
+			URL:         result.URL,
+			Snippet:     utils.TruncateRunesWithEllipsis(utils.SanitizeUTF8(result.Snippet), 200),
+			Credibility: result.Credibility,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
+		})
+	}
+
+	return &models.SearchResponse{
+		Query:       query,
+		Mode:        "deep",
+		Answer:      answer,
+		Sources:     sources,
+		Reasoning:   strings.Join(reasoningSteps, "\n"),
+		ContextUsed: len(conversationHistory) > 0,
+		Research:    trace,
+		Degraded:    degraded,
+	}, nil
+}
+
+// assess reads the best sources found so far and returns the numbers of the
+// sub-questions they answer and follow-up searches for what is missing
+func (a *DeepAgent) assess(
+	ctx context.Context,
+	query string,
+	trace *models.ResearchTrace,
+	results []models.TavilyResult,
+) ([]int, []string, error) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
+
+	ranked := a.pro.reranker.Rerank(query, append([]models.TavilyResult(nil), results...))
+	if len(ranked) > 10 {
+		ranked = ranked[:10]
+	}
+
+	var promptBuilder strings.Builder
+	promptBuilder.WriteString(fmt.Sprintf(`Идёт исследование вопроса: %s
+
+Подвопросы:
+%s
+Найденные источники:
+
+`, query, subQuestionList(trace)))
+	for i, result := range ranked {
+		promptBuilder.WriteString(fmt.Sprintf("%d. %s: %s\n\n", i+1, result.Title,
+			utils.TruncateRunes(utils.SanitizeUTF8(result.Content), 500)))
+	}
+	promptBuilder.WriteString(fmt.Sprintf(`Определи, на какие подвопросы источники уже отвечают, и чего не хватает для полного ответа на вопрос.
+Верни ТОЛЬКО JSON: {"resolved": [номера подвопросов], "missing": ["поисковый запрос", ...]}
+В "missing" не больше %d новых поисковых запросов, не повторяющих подвопросы; пустой список, если информации достаточно.
+JSON:`, deepFollowUps))
+
+	response, err := a.llmClient.ForTask(tools.TaskPlanning).Complete(ctx, promptBuilder.String(), 0.2, 300)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return nil, nil, fmt.Errorf("no JSON object in assessment response")
+	}
+	var assessment struct {
+		Resolved []int    `json:"resolved"`
+		Missing  []string `json:"missing"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &assessment); err != nil {
+		return nil, nil, fmt.Errorf("invalid assessment JSON: %w", err)
+	}
+	if len(assessment.Missing) > deepFollowUps {
+		assessment.Missing = assessment.Missing[:deepFollowUps]
+	}
+	return assessment.Resolved, assessment.Missing, nil
+}
+
+// openSubQuestions are the unresolved sub-questions to search in round
+func openSubQuestions(trace *models.ResearchTrace, round int) []string {
+	var open []string
+	for _, q := range trace.SubQuestions {
+		if q.Round == round && !q.Resolved {
+			open = append(open, q.Question)
+		}
+	}
+	return open
+}
+
+// addSubQuestions adds the new follow-up questions for round, skipping
+// repeats, up to deepMaxSubQuestions in total
+func addSubQuestions(trace *models.ResearchTrace, questions []string, round int) int {
+	known := make(map[string]bool, len(trace.SubQuestions))
+	for _, q := range trace.SubQuestions {
+		known[strings.ToLower(q.Question)] = true
+	}
+	added := 0
+	for _, q := range questions {
+		q = strings.TrimSpace(q)
+		if q == "" || known[strings.ToLower(q)] || len(trace.SubQuestions) >= deepMaxSubQuestions {
+			continue
+		}
+		known[strings.ToLower(q)] = true
+		trace.SubQuestions = append(trace.SubQuestions, models.SubQuestion{Question: q, Round: round})
+		added++
+	}
+	return added
+}
+
+func countResolved(trace *models.ResearchTrace) int {
+	n := 0
+	for _, q := range trace.SubQuestions {
+		if q.Resolved {
+			n++
+		}
+	}
+	return n
+}
+
+func allResolved(trace *models.ResearchTrace) bool {
+	return countResolved(trace) == len(trace.SubQuestions)
+}
+
+// subQuestionList numbers the sub-questions with their status for prompts
+func subQuestionList(trace *models.ResearchTrace) string {
+	var b strings.Builder
+	for i, q := range trace.SubQuestions {
+		status := "открыт"
+		if q.Resolved {
+			status = "решён"
+		}
+		b.WriteString(fmt.Sprintf("%d. %s [%s]\n", i+1, q.Question, status))
+	}
+	return b.String()
+}
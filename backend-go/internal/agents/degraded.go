@@ -0,0 +1,36 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+// searchOnlySources caps the sources listed by a search-only answer
+const searchOnlySources = 5
+
+// searchOnlyAnswer stands in for the answer while the circuit of every LLM
+// provider is open: the best sources with their snippets, numbered like the
+// sources of the response so that the citations still resolve
+func searchOnlyAnswer(ctx context.Context, results []models.TavilyResult, lang string) string {
+	logging.Printf(ctx, "🔌 LLM providers unavailable, answering with the sources only")
+
+	var answer strings.Builder
+	if lang == "ru" {
+		answer.WriteString("Языковая модель сейчас недоступна, поэтому вместо ответа приведены найденные источники.\n\n")
+	} else {
+		answer.WriteString("The language model is unavailable right now, so here are the sources found instead of an answer.\n\n")
+	}
+	for i, result := range results {
+		if i == searchOnlySources {
+			break
+		}
+		snippet := utils.TruncateRunesWithEllipsis(utils.SanitizeUTF8(result.Snippet), 300)
+		answer.WriteString(fmt.Sprintf("%d. %s: %s [%d]\n", i+1, utils.SanitizeUTF8(result.Title), snippet, i+1))
+	}
+	return strings.TrimSpace(answer.String())
+}
@@ -0,0 +1,54 @@
+package agents
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+// maxDocumentPassages is how many passages of the uploaded documents are
+// given to the LLM, ahead of the web sources
+const maxDocumentPassages = 3
+
+type documentsKey struct{}
+
+// WithDocuments makes passages of uploaded documents (SearchRequest.DocumentIDs)
+// available to the agents handling ctx
+func WithDocuments(ctx context.Context, passages []models.TavilyResult) context.Context {
+	if len(passages) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, documentsKey{}, passages)
+}
+
+func documentsFromContext(ctx context.Context) []models.TavilyResult {
+	passages, _ := ctx.Value(documentsKey{}).([]models.TavilyResult)
+	return passages
+}
+
+// hasDocuments reports whether the agent may answer from uploaded documents
+// even if the web search found nothing
+func hasDocuments(ctx context.Context) bool {
+	return len(documentsFromContext(ctx)) > 0
+}
+
+// withDocuments puts the document passages most relevant to query before
+// the web results. Uploaded material is the user's own and is fully trusted.
+func withDocuments(ctx context.Context, query string, results []models.TavilyResult) []models.TavilyResult {
+	passages := documentsFromContext(ctx)
+	if len(passages) == 0 {
+		return results
+	}
+
+	ranked := tools.NewBM25Reranker().Rerank(query, slices.Clone(passages))
+	if len(ranked) > maxDocumentPassages {
+		ranked = ranked[:maxDocumentPassages]
+	}
+	for i := range ranked {
+		ranked[i].Score = 1
+		ranked[i].Credibility = 1
+	}
+	return append(ranked, results...)
+}
@@ -0,0 +1,138 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+const (
+	// entitySources is how many of the top sources entities are read from
+	entitySources = 5
+	maxEntities   = 15
+	// maxEntityRelations is the number of relations kept per entity
+	maxEntityRelations = 5
+)
+
+// entityTypes are the types the LLM may assign; anything else becomes "other"
+var entityTypes = []string{"person", "organization", "place", "work", "event", "other"}
+
+// extractEntities asks the LLM for the entities the top sources mention about
+// the query and the relations between them
+func (a *ProAgent) extractEntities(
+	ctx context.Context,
+	query string,
+	results []models.TavilyResult,
+	lang string,
+) ([]models.Entity, error) {
+	if len(results) > entitySources {
+		results = results[:entitySources]
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	var promptBuilder strings.Builder
+	if lang == "ru" {
+		promptBuilder.WriteString("Выпиши главные сущности, которые источники упоминают в связи с вопросом: людей, организации, места, произведения, события, и связи между ними.\n\n")
+		promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\n", query))
+	} else {
+		promptBuilder.WriteString("List the main entities the sources mention about the question: people, organizations, places, works, events, and the relations between them.\n\n")
+		promptBuilder.WriteString(fmt.Sprintf("Question: %s\n\n", query))
+	}
+
+	for i, result := range results {
+		content := utils.TruncateRunes(sourceText(result), 600)
+		if lang == "ru" {
+			promptBuilder.WriteString(fmt.Sprintf("Источник %d (%s):\n%s\n\n", i+1, result.Title, content))
+		} else {
+			promptBuilder.WriteString(fmt.Sprintf("Source %d (%s):\n%s\n\n", i+1, result.Title, content))
+		}
+	}
+
+	if lang == "ru" {
+		promptBuilder.WriteString(`Верни ТОЛЬКО JSON-объект. type: person, organization, place, work, event или other; target - имя другой сущности из списка или значение (дата, число):
+{"entities": [{"name": "Кристофер Нолан", "type": "person", "relations": [{"relation": "снял", "target": "Начало"}, {"relation": "родился", "target": "30 июля 1970"}]}]}
+`)
+	} else {
+		promptBuilder.WriteString(`Return ONLY a JSON object. type is person, organization, place, work, event or other; target is the name of another listed entity or a value (date, number):
+{"entities": [{"name": "Christopher Nolan", "type": "person", "relations": [{"relation": "directed", "target": "Inception"}, {"relation": "born", "target": "30 July 1970"}]}]}
+`)
+	}
+
+	response, err := a.llmClient.ForTask(tools.TaskExtraction).ChatCompletionJSON(ctx, []map[string]string{
+		{"role": "user", "content": promptBuilder.String()},
+	}, 0.1, 800)
+	if err != nil {
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+	return parseEntities(response)
+}
+
+// parseEntities reads the LLM entities, merging repeated names and dropping
+// entities without a name and relations without a target
+func parseEntities(response string) ([]models.Entity, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON object in entities response")
+	}
+
+	var raw struct {
+		Entities []models.Entity `json:"entities"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("invalid entities JSON: %w", err)
+	}
+
+	var entities []models.Entity
+	index := make(map[string]int)
+	for _, e := range raw.Entities {
+		name := strings.TrimSpace(e.Name)
+		if name == "" {
+			continue
+		}
+		kind := strings.ToLower(strings.TrimSpace(e.Type))
+		if !slices.Contains(entityTypes, kind) {
+			kind = "other"
+		}
+
+		key := strings.ToLower(name)
+		i, ok := index[key]
+		if !ok {
+			if len(entities) == maxEntities {
+				continue
+			}
+			i = len(entities)
+			index[key] = i
+			entities = append(entities, models.Entity{Name: name, Type: kind})
+		}
+		entities[i].Relations = mergeRelations(entities[i].Relations, e.Relations)
+	}
+	return entities, nil
+}
+
+// mergeRelations appends the relations of added missing from relations, up to
+// maxEntityRelations
+func mergeRelations(relations, added []models.EntityRelation) []models.EntityRelation {
+	for _, r := range added {
+		r.Relation = strings.TrimSpace(r.Relation)
+		r.Target = strings.TrimSpace(r.Target)
+		if r.Relation == "" || r.Target == "" || len(relations) == maxEntityRelations {
+			continue
+		}
+		known := slices.ContainsFunc(relations, func(k models.EntityRelation) bool {
+			return strings.EqualFold(k.Relation, r.Relation) && strings.EqualFold(k.Target, r.Target)
+		})
+		if !known {
+			relations = append(relations, r)
+		}
+	}
+	return relations
+}
@@ -0,0 +1,178 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// entityGraphTTL is how long the graph of an idle session is kept
+const entityGraphTTL = 24 * time.Hour
+
+// recentEntities is how many entities of the graph follow-up questions see
+const recentEntities = 8
+
+type sessionKey struct{}
+
+// WithSession marks ctx as a turn of the chat session, so the entities found
+// while answering join the session's graph
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	if sessionID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKey{}, sessionID)
+}
+
+// sessionFromContext returns the chat session of ctx, if any
+func sessionFromContext(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionKey{}).(string)
+	return sessionID
+}
+
+// EntityGraphs keeps, in memory, a graph per chat session of the entities
+// its answers mentioned and their relations. Follow-up questions use it to
+// resolve pronouns ("when was he born?").
+type EntityGraphs struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionGraph
+}
+
+type sessionGraph struct {
+	turn      int
+	entities  map[string]*graphEntity // by lower-case name
+	updatedAt time.Time
+}
+
+type graphEntity struct {
+	entity   models.Entity
+	lastTurn int // turn that last mentioned the entity
+}
+
+// NewEntityGraphs creates the store; idle sessions are dropped after entityGraphTTL
+func NewEntityGraphs() *EntityGraphs {
+	g := &EntityGraphs{sessions: make(map[string]*sessionGraph)}
+	go g.cleanup()
+	return g
+}
+
+// Add merges the entities of one answer into the session's graph
+func (g *EntityGraphs) Add(sessionID string, entities []models.Entity) {
+	if sessionID == "" || len(entities) == 0 {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	graph, ok := g.sessions[sessionID]
+	if !ok {
+		graph = &sessionGraph{entities: make(map[string]*graphEntity)}
+		g.sessions[sessionID] = graph
+	}
+	graph.turn++
+	graph.updatedAt = time.Now()
+
+	for _, e := range entities {
+		key := strings.ToLower(e.Name)
+		known, ok := graph.entities[key]
+		if !ok {
+			known = &graphEntity{entity: models.Entity{Name: e.Name, Type: e.Type}}
+			graph.entities[key] = known
+		}
+		known.entity.Relations = mergeRelations(known.entity.Relations, e.Relations)
+		known.lastTurn = graph.turn
+	}
+}
+
+// Recent returns up to limit entities of the session, the most recently
+// mentioned first
+func (g *EntityGraphs) Recent(sessionID string, limit int) []models.Entity {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	graph, ok := g.sessions[sessionID]
+	if !ok {
+		return nil
+	}
+	known := make([]*graphEntity, 0, len(graph.entities))
+	for _, e := range graph.entities {
+		known = append(known, e)
+	}
+	slices.SortFunc(known, func(a, b *graphEntity) int {
+		if a.lastTurn != b.lastTurn {
+			return b.lastTurn - a.lastTurn
+		}
+		return strings.Compare(a.entity.Name, b.entity.Name)
+	})
+
+	if len(known) > limit {
+		known = known[:limit]
+	}
+	entities := make([]models.Entity, len(known))
+	for i, e := range known {
+		entities[i] = e.entity
+		entities[i].Relations = slices.Clone(e.entity.Relations)
+	}
+	return entities
+}
+
+// Forget drops the graph of a deleted session
+func (g *EntityGraphs) Forget(sessionID string) {
+	g.mu.Lock()
+	delete(g.sessions, sessionID)
+	g.mu.Unlock()
+}
+
+// cleanup drops the graphs of sessions idle for longer than entityGraphTTL
+func (g *EntityGraphs) cleanup() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		g.mu.Lock()
+		for id, graph := range g.sessions {
+			if time.Since(graph.updatedAt) > entityGraphTTL {
+				delete(g.sessions, id)
+			}
+		}
+		g.mu.Unlock()
+	}
+}
+
+// entitiesContext lists the session's recent entities for the prompt that
+// rephrases a follow-up question (written in lang); "" without entities
+func entitiesContext(entities []models.Entity, lang string) string {
+	if len(entities) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	if lang == "ru" {
+		b.WriteString("\nСущности из предыдущих ответов (последние упомянутые первыми):\n")
+	} else {
+		b.WriteString("\nEntities from previous answers (most recently mentioned first):\n")
+	}
+	for _, e := range entities {
+		b.WriteString(fmt.Sprintf("- %s (%s)", e.Name, e.Type))
+		for i, r := range e.Relations {
+			if i == 0 {
+				b.WriteString(": ")
+			} else {
+				b.WriteString("; ")
+			}
+			b.WriteString(r.Relation + " " + r.Target)
+		}
+		b.WriteString("\n")
+	}
+	if lang == "ru" {
+		b.WriteString("Замени местоимения и отсылки (он, она, эта компания) на имя сущности, о которой идёт речь.\n")
+	} else {
+		b.WriteString("Replace pronouns and references (he, she, the company) with the name of the entity they point to.\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+// evidenceTopSources is how many of the ranked sources are assessed
+const evidenceTopSources = 8
+
+// EvidencePolicy decides whether the found sources are strong enough for a
+// confident answer. Below the threshold the agent has to state uncertainty
+// or decline instead of synthesizing an answer. A zero threshold disables it.
+type EvidencePolicy struct {
+	threshold float64
+}
+
+func NewEvidencePolicy(threshold float64) *EvidencePolicy {
+	return &EvidencePolicy{threshold: threshold}
+}
+
+// evidenceCheck is the policy decision for one set of sources
+type evidenceCheck struct {
+	tools.EvidenceAssessment
+	threshold float64
+	weak      bool
+}
+
+// check assesses the sources an answer is about to be written from, and
+// records them for the verification of the answer
+func (p *EvidencePolicy) check(ctx context.Context, results []models.TavilyResult) evidenceCheck {
+	recordSources(ctx, results)
+	assessment := tools.AssessEvidence(results, evidenceTopSources)
+	return evidenceCheck{
+		EvidenceAssessment: assessment,
+		threshold:          p.threshold,
+		weak:               p.threshold > 0 && assessment.Score < p.threshold,
+	}
+}
+
+// reasoning describes the decision for the Reasoning field
+func (c evidenceCheck) reasoning(lang string) string {
+	if c.threshold <= 0 {
+		return ""
+	}
+	if lang == "ru" {
+		if c.weak {
+			return fmt.Sprintf("⚠️ Недостаточно доказательств: %.2f < порога %.2f (достоверность %.2f, согласие источников %.2f) - ответ с явной оговоркой о неуверенности",
+				c.Score, c.threshold, c.Credibility, c.Agreement)
+		}
+		return fmt.Sprintf("📏 Доказательная база: %.2f ≥ порога %.2f (достоверность %.2f, согласие источников %.2f)",
+			c.Score, c.threshold, c.Credibility, c.Agreement)
+	}
+	if c.weak {
+		return fmt.Sprintf("⚠️ Insufficient evidence: %.2f < threshold %.2f (credibility %.2f, source agreement %.2f) - answering with explicit uncertainty",
+			c.Score, c.threshold, c.Credibility, c.Agreement)
+	}
+	return fmt.Sprintf("📏 Evidence: %.2f ≥ threshold %.2f (credibility %.2f, source agreement %.2f)",
+		c.Score, c.threshold, c.Credibility, c.Agreement)
+}
+
+// instruction is appended to the synthesis prompt when the evidence is weak
+func (c evidenceCheck) instruction(lang string) string {
+	if !c.weak {
+		return ""
+	}
+	if lang == "ru" {
+		return `
+⚠️ Найденные источники недостаточно надёжны или не подтверждают друг друга.
+Не формулируй уверенный ответ. Прямо скажи, что достоверной информации найти не удалось,
+перечисли только то, что источники действительно утверждают, с оговорками, или откажись отвечать.
+Ничего не додумывай.
+
+`
+	}
+	return `
+⚠️ The found sources are weak or do not corroborate each other.
+Do not give a confident answer. State explicitly that reliable information was not found,
+report only what the sources actually claim, with caveats, or decline to answer.
+Do not fill gaps with assumptions.
+
+`
+}
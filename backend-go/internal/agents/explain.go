@@ -0,0 +1,236 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+// Explain runs the planning stages of a query: mode selection, constraint
+// extraction, query enhancement and sub-query generation. Nothing is searched
+// and no answer is synthesized; the LLM is only called by the planning stages
+// themselves (mode selector, extractor, enhancement, sub-queries).
+func (r *RouterAgent) Explain(
+	ctx context.Context,
+	query, mode string,
+	conversationHistory []models.Message,
+) (*models.ExplainResponse, error) {
+	if mode == "" {
+		mode = "auto"
+	}
+	query, conversationHistory, screening, err := r.screen(ctx, query, conversationHistory)
+	if err != nil {
+		return nil, err
+	}
+	selectedMode, autoRouting := r.route(ctx, query, mode, conversationHistory)
+	agent, ok := r.modeAgent(selectedMode)
+	if !ok {
+		return nil, fmt.Errorf("unknown mode: %s", selectedMode)
+	}
+
+	constraints := r.extractConstraints(ctx, query, selectedMode)
+	if constraints != nil {
+		ctx = withConstraints(ctx, constraints)
+	}
+	ctx = tools.WithDateRange(ctx, queryDateRange(ctx, query, constraints))
+
+	plan := agent.plan(ctx, query, conversationHistory)
+	logging.Printf(ctx, "🧪 Explain: %s → %s, search query %q, providers %v",
+		mode, selectedMode, plan.SearchQuery, plan.Providers)
+
+	return &models.ExplainResponse{
+		Query:         query,
+		RequestedMode: mode,
+		SelectedMode:  selectedMode,
+		AutoRouting:   autoRouting,
+		Constraints:   constraints,
+		Plan:          plan,
+		Guardrails:    screening,
+	}, nil
+}
+
+// newPlan starts a plan for the query; documents are consulted by every agent
+func newPlan(ctx context.Context, query string, providers ...string) models.QueryPlan {
+	if hasDocuments(ctx) {
+		providers = append(providers, "documents")
+	}
+	return models.QueryPlan{
+		Language:    answerLanguage(ctx, query),
+		SearchQuery: query,
+		Providers:   providers,
+		DateRange:   tools.DateRangeFromContext(ctx),
+	}
+}
+
+// enhancedPlan uses the enhanced query when the enhancement succeeded
+func enhancedPlan(ctx context.Context, plan models.QueryPlan, enhanced string, err error) models.QueryPlan {
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Explain: query enhancement failed: %v", err)
+		return plan
+	}
+	if enhanced != "" {
+		plan.SearchQuery = enhanced
+		plan.Enhanced = true
+	}
+	return plan
+}
+
+func (a *SimpleAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	plan := newPlan(ctx, query, a.searchClient.Providers()...)
+	if len(conversationHistory) > 0 {
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
+		plan = enhancedPlan(ctx, plan, enhanced, err)
+	}
+	return plan
+}
+
+func (a *ProAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	plan := newPlan(ctx, query, a.searchClient.Providers()...)
+	if len(conversationHistory) > 0 {
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory, plan.Language)
+		plan = enhancedPlan(ctx, plan, enhanced, err)
+	}
+	if a.detectMultiHop(query) {
+		plan.MultiHop = true
+		plan.SubQueries, plan.Hops = a.generateSubQueries(ctx, plan.SearchQuery, plan.Language)
+	}
+	return plan
+}
+
+// plan shows the first research round; later rounds depend on what it finds
+func (a *DeepAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	plan := newPlan(ctx, query, a.pro.searchClient.Providers()...)
+	if len(conversationHistory) > 0 {
+		enhanced, err := a.pro.enhanceQueryWithContext(ctx, query, conversationHistory, plan.Language)
+		plan = enhancedPlan(ctx, plan, enhanced, err)
+	}
+	plan.MultiHop = true
+	plan.SubQueries, plan.Hops = a.pro.generateSubQueries(ctx, plan.SearchQuery, plan.Language)
+	return plan
+}
+
+func (a *SocialAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	plan := newPlan(ctx, query, "reddit", "habr", "twitter")
+	if len(conversationHistory) > 0 {
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
+		plan = enhancedPlan(ctx, plan, enhanced, err)
+	}
+	return plan
+}
+
+func (a *VideoAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	plan := newPlan(ctx, query, "youtube")
+	if len(conversationHistory) > 0 {
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
+		plan = enhancedPlan(ctx, plan, enhanced, err)
+	}
+	return plan
+}
+
+func (a *AcademicAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	plan := newPlan(ctx, query, "arxiv", "google_scholar")
+	if len(conversationHistory) > 0 {
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
+		plan = enhancedPlan(ctx, plan, enhanced, err)
+	}
+	return plan
+}
+
+func (a *FinanceAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	plan := newPlan(ctx, query, "yahoo_finance", "investing.com", "marketwatch")
+	if len(conversationHistory) > 0 {
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
+		plan = enhancedPlan(ctx, plan, enhanced, err)
+	}
+	return plan
+}
+
+func (a *NewsAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	providers := []string{"google_news"}
+	if len(a.newsScraper.Feeds()) > 0 {
+		providers = append(providers, "rss")
+	}
+	for _, provider := range a.searchClient.Providers() {
+		providers = append(providers, provider+":news")
+	}
+	plan := newPlan(ctx, query, providers...)
+	if len(conversationHistory) > 0 {
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
+		plan = enhancedPlan(ctx, plan, enhanced, err)
+	}
+	return plan
+}
+
+func (a *CodeAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	providers := []string{"stackoverflow"}
+	if detectLanguage(query) == "ru" {
+		providers = append(providers, "ru.stackoverflow")
+	}
+	providers = append(providers, "github_issues", "github_commits", "github_repos")
+	plan := newPlan(ctx, query, providers...)
+	keywords, err := a.searchKeywords(ctx, query, conversationHistory)
+	return enhancedPlan(ctx, plan, keywords, err)
+}
+
+// plan lists the extracted claims as sub-queries, each searched separately
+func (a *FactCheckAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	plan := newPlan(ctx, query, a.searchClient.Providers()...)
+	claims, err := a.extractClaims(ctx, query, conversationHistory)
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Explain: claim extraction failed: %v", err)
+		claims = []string{query}
+	}
+	plan.SubQueries = claims
+	return plan
+}
+
+// plan merges the plans of the domain agents the query is split into
+func (a *MixedAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	var plan models.QueryPlan
+	for i, domain := range queryDomains(query) {
+		domainPlan := a.agents[domain.agent].plan(ctx, query, conversationHistory)
+		if i == 0 {
+			plan = domainPlan
+			continue
+		}
+		for _, provider := range domainPlan.Providers {
+			if !slices.Contains(plan.Providers, provider) {
+				plan.Providers = append(plan.Providers, provider)
+			}
+		}
+	}
+	return plan
+}
+
+// plan lists every provider the model may call; which ones it calls, and in
+// what order, is only decided while answering
+func (a *ToolsAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	providers := append(a.searchClient.Providers(), "page_fetch", "calculator", "arxiv", "yahoo_finance", "open_meteo", "cbr")
+	return newPlan(ctx, query, providers...)
+}
+
+// plan shows the image subject; the description is searched like simple mode
+func (a *ImagesAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	plan := a.simpleAgent.plan(ctx, query, conversationHistory)
+	plan.SearchQuery = imageSubject(plan.SearchQuery)
+	plan.Providers = append([]string{"searxng_images", "brave_images"}, plan.Providers...)
+	return plan
+}
+
+// plan names the data provider of an instant question; instant answers don't
+// consult uploaded documents
+func (a *InstantAgent) plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan {
+	plan := models.QueryPlan{
+		Language:    answerLanguage(ctx, query),
+		SearchQuery: query,
+		Providers:   []string{},
+	}
+	if parsed := parseInstantQuery(query); parsed != nil {
+		plan.Providers = []string{instantProviders[parsed.kind]}
+	}
+	return plan
+}
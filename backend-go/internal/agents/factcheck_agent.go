@@ -0,0 +1,389 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+const (
+	// maxFactCheckClaims bounds the claims checked per query
+	maxFactCheckClaims = 5
+	// sourcesPerClaim is the evidence kept for each claim after reranking
+	sourcesPerClaim = 4
+)
+
+// Claim verdicts
+const (
+	verdictSupported    = "supported"
+	verdictRefuted      = "refuted"
+	verdictInsufficient = "insufficient"
+)
+
+// FactCheckAgent splits a statement into checkable claims, searches evidence
+// for each claim and gives a verdict per claim with the sources it rests on
+type FactCheckAgent struct {
+	searchClient *tools.SearchClient
+	llmClient    *tools.LLMClient
+	reranker     *tools.BM25Reranker
+	evidence     *EvidencePolicy
+}
+
+func NewFactCheckAgent(searchClient *tools.SearchClient, llmClient *tools.LLMClient, evidence *EvidencePolicy) *FactCheckAgent {
+	return &FactCheckAgent{
+		searchClient: searchClient,
+		llmClient:    llmClient,
+		reranker:     tools.NewBM25Reranker(),
+		evidence:     evidence,
+	}
+}
+
+func (a *FactCheckAgent) Process(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return a.ProcessWithContext(ctx, query, nil)
+}
+
+func (a *FactCheckAgent) ProcessWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	logging.Printf(ctx, "Fact-check mode processing: %s", query)
+	lang := answerLanguage(ctx, query)
+
+	reasoningSteps := appendStep(ctx, nil, "🔎 Запущен режим Fact-check - проверка утверждений по источникам")
+
+	// Step 1: split the statement into claims
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Выделяю проверяемые утверждения...")
+	claims, err := a.extractClaims(ctx, query, conversationHistory)
+	if err != nil {
+		logging.Printf(ctx, "Claim extraction failed, checking the query as one claim: %v", err)
+		claims = []string{query}
+	}
+	for i, claim := range claims {
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("  %d. %s", i+1, claim))
+	}
+
+	// Step 2: evidence per claim
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Ищу подтверждения и опровержения каждого утверждения...")
+	perClaim := a.searchClaims(ctx, claims)
+
+	// Sources are shared between claims; claimSources index them per claim
+	var allResults []models.TavilyResult
+	claimSources := make([][]int, len(claims))
+	indexByURL := make(map[string]int)
+	for i, results := range perClaim {
+		for _, result := range results {
+			key := strings.TrimSuffix(result.URL, "/")
+			index, ok := indexByURL[key]
+			if !ok {
+				index = len(allResults)
+				indexByURL[key] = index
+				allResults = append(allResults, result)
+			}
+			claimSources[i] = append(claimSources[i], index)
+		}
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("  ✓ Утверждение %d: %d источников", i+1, len(results)))
+	}
+
+	if len(allResults) == 0 {
+		return &models.SearchResponse{
+			Query:       query,
+			Mode:        "pro-factcheck",
+			Answer:      "Не удалось найти источники для проверки утверждений.",
+			Sources:     []models.Source{},
+			Reasoning:   strings.Join(reasoningSteps, "\n"),
+			ContextUsed: len(conversationHistory) > 0,
+		}, nil
+	}
+
+	evidence := a.evidence.check(ctx, allResults)
+	if step := evidence.reasoning("ru"); step != "" {
+		reasoningSteps = appendStep(ctx, reasoningSteps, step)
+	}
+
+	// Step 3: verdicts
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Сопоставляю утверждения с источниками...")
+	verdicts, err := a.judgeClaims(ctx, claims, claimSources, allResults)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, v := range verdicts {
+		counts[v.Verdict]++
+	}
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("Итог: подтверждено %d, опровергнуто %d, недостаточно данных %d",
+		counts[verdictSupported], counts[verdictRefuted], counts[verdictInsufficient]))
+
+	sources := make([]models.Source, 0, len(allResults))
+	for _, result := range allResults {
+		sources = append(sources, models.Source{
+			Title:       utils.SanitizeUTF8(result.Title),
+			URL:         result.URL,
+			Snippet:     utils.TruncateRunesWithEllipsis(utils.SanitizeUTF8(result.Content), 200),
+			Credibility: result.Score,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
+		})
+	}
+
+	return &models.SearchResponse{
+		Query:       query,
+		Mode:        "pro-factcheck",
+		Answer:      factCheckAnswer(verdicts, lang),
+		Sources:     sources,
+		Reasoning:   strings.Join(reasoningSteps, "\n"),
+		ContextUsed: len(conversationHistory) > 0,
+		FactCheck:   verdicts,
+	}, nil
+}
+
+// extractClaims splits the query, a question or a pasted statement, into
+// self-contained factual claims
+func (a *FactCheckAgent) extractClaims(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) ([]string, error) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
+
+	var promptBuilder strings.Builder
+	promptBuilder.WriteString(`Выдели из текста пользователя проверяемые фактические утверждения.
+
+Правила:
+- Каждое утверждение самодостаточно: без местоимений, с именами, датами и числами из текста
+- Вопрос "правда ли, что ..." превращай в утверждение
+- Мнения, оценки и прогнозы не включай
+- Не больше ` + fmt.Sprint(maxFactCheckClaims) + ` утверждений, на языке текста
+
+`)
+	if len(conversationHistory) > 0 {
+		promptBuilder.WriteString("Контекст диалога (текст может ссылаться на него):\n")
+		for _, msg := range recentTurns(conversationHistory, 4) {
+			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, utils.TruncateRunes(msg.Content, 1500)))
+		}
+		promptBuilder.WriteString("\n")
+	}
+	promptBuilder.WriteString(fmt.Sprintf("Текст: %s\n\nВерни ТОЛЬКО JSON-массив строк.\nJSON:", query))
+
+	response, err := a.llmClient.ForTask(tools.TaskExtraction).Complete(ctx, promptBuilder.String(), 0.1, 400)
+	if err != nil {
+		return nil, err
+	}
+	return parseClaims(response)
+}
+
+// parseClaims extracts the JSON array of claims from an LLM response
+func parseClaims(response string) ([]string, error) {
+	start := strings.Index(response, "[")
+	end := strings.LastIndex(response, "]")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON array in claims response")
+	}
+
+	var raw []string
+	if err := json.Unmarshal([]byte(response[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("invalid claims JSON: %w", err)
+	}
+	claims := make([]string, 0, len(raw))
+	for _, claim := range raw {
+		if claim = strings.TrimSpace(claim); claim != "" {
+			claims = append(claims, claim)
+		}
+	}
+	if len(claims) == 0 {
+		return nil, errors.New("no claims extracted")
+	}
+	if len(claims) > maxFactCheckClaims {
+		claims = claims[:maxFactCheckClaims]
+	}
+	return claims, nil
+}
+
+// searchClaims searches evidence for every claim in parallel and keeps the
+// best sourcesPerClaim results of each
+func (a *FactCheckAgent) searchClaims(ctx context.Context, claims []string) [][]models.TavilyResult {
+	perClaim := make([][]models.TavilyResult, len(claims))
+	opts := searchOptions(constraintsFromContext(ctx))
+
+	var wg sync.WaitGroup
+	for i, claim := range claims {
+		wg.Add(1)
+		go func(i int, claim string) {
+			defer wg.Done()
+
+			claimCtx, cancel := context.WithTimeout(ctx, 12*time.Second)
+			defer cancel()
+
+			var results []models.TavilyResult
+			res, err := a.searchClient.SearchWithOptions(claimCtx, claim, 6, true, opts)
+			switch {
+			case err == nil:
+				results = res.Results
+			case !errors.Is(err, tools.ErrNoResults):
+				logging.Printf(ctx, "Claim search failed for '%s': %v", claim, err)
+			}
+
+			rerankStart := time.Now()
+			results = a.reranker.Rerank(claim, withDocuments(ctx, claim, results))
+			tools.TrackTime(ctx, tools.TimingRerank, rerankStart)
+			if len(results) > sourcesPerClaim {
+				results = results[:sourcesPerClaim]
+			}
+			perClaim[i] = results
+		}(i, claim)
+	}
+	wg.Wait()
+	return perClaim
+}
+
+// judgeClaims asks the LLM for a verdict on each claim from that claim's
+// sources. Claims the LLM skipped, or judged without a usable source, are
+// insufficient.
+func (a *FactCheckAgent) judgeClaims(
+	ctx context.Context,
+	claims []string,
+	claimSources [][]int,
+	results []models.TavilyResult,
+) ([]models.ClaimVerdict, error) {
+	llm := a.llmClient.ForTask(tools.TaskVerification)
+	contents := make([]string, len(results))
+	for i, result := range results {
+		contents[i] = llm.TruncateTokens(utils.SanitizeUTF8(result.Content), sourceTokens)
+	}
+	prompt := fitPrompt(llm, contents, 1200, func(contents []string) tools.Prompt {
+		var system strings.Builder
+		system.WriteString(`Ты фактчекер. Оцени каждое утверждение только по перечисленным для него источникам.
+
+Вердикты:
+- supported - источники прямо подтверждают утверждение
+- refuted - источники прямо противоречат утверждению
+- insufficient - источники не позволяют сделать вывод или противоречат друг другу
+
+Верни ТОЛЬКО JSON-массив, по объекту на утверждение:
+[{"claim": 1, "verdict": "supported|refuted|insufficient", "explanation": "1-2 предложения с номерами источников в квадратных скобках, например [2]", "sources": [2, 3]}]
+Пояснения пиши на языке утверждений.
+`)
+		system.WriteString(languageInstruction(ctx, "ru"))
+		system.WriteString(personaInstruction(ctx, "ru"))
+
+		var promptBuilder strings.Builder
+		promptBuilder.WriteString("Источники:\n\n")
+		for i, result := range results {
+			promptBuilder.WriteString(fmt.Sprintf("Источник %d (%s):\n%s\n\n", i+1, result.Title, contents[i]))
+		}
+
+		promptBuilder.WriteString("Утверждения:\n")
+		for i, claim := range claims {
+			numbers := make([]string, 0, len(claimSources[i]))
+			for _, index := range claimSources[i] {
+				numbers = append(numbers, fmt.Sprint(index+1))
+			}
+			if len(numbers) == 0 {
+				numbers = append(numbers, "нет")
+			}
+			promptBuilder.WriteString(fmt.Sprintf("%d. %s (источники: %s)\n", i+1, claim, strings.Join(numbers, ", ")))
+		}
+		promptBuilder.WriteString("\nJSON:")
+		return tools.Prompt{System: strings.TrimSpace(system.String()), User: promptBuilder.String()}
+	})
+
+	synthesisStart := time.Now()
+	response, err := llm.CompletePrompt(ctx, prompt, 0.1, 1200)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
+	if err != nil {
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+	return parseVerdicts(response, claims, claimSources)
+}
+
+// parseVerdicts reads the LLM verdicts into one ClaimVerdict per claim, in
+// claim order. Source numbers are 1-based in the prompt and 0-based in the
+// verdicts; numbers outside the claim's sources are dropped.
+func parseVerdicts(response string, claims []string, claimSources [][]int) ([]models.ClaimVerdict, error) {
+	start := strings.Index(response, "[")
+	end := strings.LastIndex(response, "]")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON array in verdicts response")
+	}
+
+	var raw []struct {
+		Claim       int    `json:"claim"`
+		Verdict     string `json:"verdict"`
+		Explanation string `json:"explanation"`
+		Sources     []int  `json:"sources"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("invalid verdicts JSON: %w", err)
+	}
+
+	verdicts := make([]models.ClaimVerdict, len(claims))
+	for i, claim := range claims {
+		verdicts[i] = models.ClaimVerdict{Claim: claim, Verdict: verdictInsufficient, Sources: []int{}}
+	}
+	for _, r := range raw {
+		i := r.Claim - 1
+		if i < 0 || i >= len(claims) {
+			continue
+		}
+		allowed := make(map[int]bool, len(claimSources[i]))
+		for _, index := range claimSources[i] {
+			allowed[index] = true
+		}
+		v := &verdicts[i]
+		v.Explanation = strings.TrimSpace(r.Explanation)
+		for _, n := range r.Sources {
+			if allowed[n-1] {
+				v.Sources = append(v.Sources, n-1)
+			}
+		}
+		switch verdict := strings.ToLower(strings.TrimSpace(r.Verdict)); verdict {
+		case verdictSupported, verdictRefuted:
+			if len(v.Sources) > 0 {
+				v.Verdict = verdict
+			}
+		}
+	}
+	return verdicts, nil
+}
+
+// factCheckAnswer lists the verdicts as the answer text, each explanation
+// followed by the citation markers of its sources
+func factCheckAnswer(verdicts []models.ClaimVerdict, lang string) string {
+	labels := map[string]string{
+		verdictSupported:    "✅ Подтверждается",
+		verdictRefuted:      "❌ Опровергается",
+		verdictInsufficient: "❔ Недостаточно данных",
+	}
+	if lang != "ru" {
+		labels = map[string]string{
+			verdictSupported:    "✅ Supported",
+			verdictRefuted:      "❌ Refuted",
+			verdictInsufficient: "❔ Insufficient evidence",
+		}
+	}
+
+	var b strings.Builder
+	for i, v := range verdicts {
+		b.WriteString(fmt.Sprintf("%d. **%s** - %s.", i+1, v.Claim, labels[v.Verdict]))
+		if v.Explanation != "" {
+			b.WriteString(" " + v.Explanation)
+		}
+		if !citationPattern.MatchString(v.Explanation) {
+			for _, index := range v.Sources {
+				b.WriteString(fmt.Sprintf("[%d]", index+1))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
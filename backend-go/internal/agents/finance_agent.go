@@ -3,25 +3,29 @@ package agents
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
+	"time"
 
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/scrapers"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 )
 
 type FinanceAgent struct {
 	financeScraper *scrapers.FinanceScraper
 	llmClient      *tools.LLMClient
 	reranker       *tools.BM25Reranker
+	evidence       *EvidencePolicy
 }
 
-func NewFinanceAgent(llmClient *tools.LLMClient) *FinanceAgent {
+func NewFinanceAgent(llmClient *tools.LLMClient, evidence *EvidencePolicy) *FinanceAgent {
 	return &FinanceAgent{
 		financeScraper: scrapers.NewFinanceScraper(),
 		llmClient:      llmClient,
 		reranker:       tools.NewBM25Reranker(),
+		evidence:       evidence,
 	}
 }
 
@@ -34,51 +38,58 @@ func (a *FinanceAgent) ProcessWithContext(
 	query string,
 	conversationHistory []models.Message,
 ) (*models.SearchResponse, error) {
-	log.Printf("Pro Finance mode processing: %s", query)
+	logging.Printf(ctx, "Pro Finance mode processing: %s", query)
 
-	reasoningSteps := []string{"💰 Запущен режим Finance - анализ финансовых данных"}
+	reasoningSteps := appendStep(ctx, nil, "💰 Запущен режим Finance - анализ финансовых данных")
 
 	searchQuery := query
 	if len(conversationHistory) > 0 {
-		reasoningSteps = append(reasoningSteps, "Адаптирую запрос с учетом контекста...")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "Адаптирую запрос с учетом контекста...")
 		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
 		if err == nil && enhanced != "" {
 			searchQuery = enhanced
 		}
 	}
 
-	reasoningSteps = append(reasoningSteps, "Ищу финансовые данные в Yahoo Finance, Investing.com, MarketWatch...")
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Ищу финансовые данные в Yahoo Finance, Investing.com, MarketWatch...")
 
 	allResults := make([]models.TavilyResult, 0)
 
+	// Yahoo Finance works best with ticker symbols
+	yahooQuery := searchQuery
+	if constraints := constraintsFromContext(ctx); constraints != nil && len(constraints.Tickers) > 0 {
+		yahooQuery = strings.Join(constraints.Tickers, " ")
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("Тикеры: %s", yahooQuery))
+	}
+
 	// Yahoo Finance
-	yahooResults, err := a.financeScraper.SearchYahooFinance(ctx, searchQuery, 5)
+	yahooResults, err := a.financeScraper.SearchYahooFinance(ctx, yahooQuery, 5)
 	if err != nil {
-		log.Printf("Yahoo Finance search failed: %v", err)
+		logging.Printf(ctx, "Yahoo Finance search failed: %v", err)
 	} else {
 		allResults = append(allResults, yahooResults...)
-		reasoningSteps = append(reasoningSteps, fmt.Sprintf("✓ Yahoo Finance: %d новостей", len(yahooResults)))
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Yahoo Finance: %d новостей", len(yahooResults)))
 	}
 
 	// Investing.com
 	investingResults, err := a.financeScraper.SearchInvestingCom(ctx, searchQuery, 5)
 	if err != nil {
-		log.Printf("Investing.com search failed: %v", err)
+		logging.Printf(ctx, "Investing.com search failed: %v", err)
 	} else {
 		allResults = append(allResults, investingResults...)
-		reasoningSteps = append(reasoningSteps, fmt.Sprintf("✓ Investing.com: %d результатов", len(investingResults)))
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Investing.com: %d результатов", len(investingResults)))
 	}
 
 	// MarketWatch
 	marketwatchResults, err := a.financeScraper.SearchMarketWatch(ctx, searchQuery, 5)
 	if err != nil {
-		log.Printf("MarketWatch search failed: %v", err)
+		logging.Printf(ctx, "MarketWatch search failed: %v", err)
 	} else {
 		allResults = append(allResults, marketwatchResults...)
-		reasoningSteps = append(reasoningSteps, fmt.Sprintf("✓ MarketWatch: %d статей", len(marketwatchResults)))
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ MarketWatch: %d статей", len(marketwatchResults)))
 	}
 
-	if len(allResults) == 0 {
+	if len(allResults) == 0 && !hasDocuments(ctx) {
 		return &models.SearchResponse{
 			Query:     query,
 			Mode:      "pro-finance",
@@ -88,20 +99,28 @@ func (a *FinanceAgent) ProcessWithContext(
 		}, nil
 	}
 
-	reasoningSteps = append(reasoningSteps, fmt.Sprintf("Собрано %d финансовых источников", len(allResults)))
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("Собрано %d финансовых источников", len(allResults)))
 
 	// Rerank
+	rerankStart := time.Now()
 	allResults = a.reranker.Rerank(searchQuery, allResults)
+	tools.TrackTime(ctx, tools.TimingRerank, rerankStart)
 
 	if len(allResults) > 10 {
 		allResults = allResults[:10]
 	}
+	allResults = withDocuments(ctx, searchQuery, allResults)
 
-	reasoningSteps = append(reasoningSteps, "Анализирую финансовые данные...")
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Анализирую финансовые данные...")
+
+	evidence := a.evidence.check(ctx, allResults)
+	if step := evidence.reasoning("ru"); step != "" {
+		reasoningSteps = appendStep(ctx, reasoningSteps, step)
+	}
 
 	// Build LLM prompt
-	var promptBuilder strings.Builder
-	promptBuilder.WriteString(`Ты финансовый аналитик. Проанализируй финансовые данные и новости.
+	var system strings.Builder
+	system.WriteString(`Ты финансовый аналитик. Проанализируй финансовые данные и новости.
 
 Твоя задача:
 1. Дать объективный финансовый анализ
@@ -112,10 +131,17 @@ func (a *FinanceAgent) ProcessWithContext(
 ⚠️ Важно: Это не финансовый совет. Пользователь должен провести собственное исследование.
 
 `)
+	system.WriteString(citationInstruction("ru"))
+	system.WriteString(calcInstruction("ru"))
+	system.WriteString(evidence.instruction("ru"))
+	system.WriteString(formatInstruction(ctx, "ru"))
+	system.WriteString(languageInstruction(ctx, "ru"))
+	system.WriteString(personaInstruction(ctx, "ru"))
 
+	var promptBuilder strings.Builder
 	if len(conversationHistory) > 0 {
-		promptBuilder.WriteString("\nКонтекст диалога:\n")
-		for _, msg := range conversationHistory[max(0, len(conversationHistory)-4):] {
+		promptBuilder.WriteString("Контекст диалога:\n")
+		for _, msg := range recentTurns(conversationHistory, 4) {
 			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
 		}
 		promptBuilder.WriteString("\n")
@@ -128,16 +154,18 @@ func (a *FinanceAgent) ProcessWithContext(
 		if i >= 8 {
 			break
 		}
-		content := result.Content
-		if len(content) > 500 {
-			content = content[:500]
-		}
+		content := utils.TruncateRunes(result.Content, 500)
 		promptBuilder.WriteString(fmt.Sprintf("Источник %d: %s\n%s\n\n", i+1, result.Title, content))
 	}
 
-	promptBuilder.WriteString("\nФинансовый анализ:")
+	promptBuilder.WriteString("Финансовый анализ:")
 
-	answer, err := a.llmClient.Complete(ctx, promptBuilder.String(), 0.6, 1000)
+	synthesisStart := time.Now()
+	answer, err := a.llmClient.ForTask(tools.TaskSynthesis).CompletePrompt(ctx, tools.Prompt{
+		System: strings.TrimSpace(system.String()),
+		User:   promptBuilder.String(),
+	}, 0.6, 1000)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
 	if err != nil {
 		return nil, fmt.Errorf("LLM completion failed: %w", err)
 	}
@@ -148,15 +176,14 @@ func (a *FinanceAgent) ProcessWithContext(
 		if i >= 8 {
 			break
 		}
-		snippet := result.Content
-		if len(snippet) > 200 {
-			snippet = snippet[:200] + "..."
-		}
+		snippet := utils.TruncateRunesWithEllipsis(result.Content, 200)
 		sources = append(sources, models.Source{
 			Title:       result.Title,
 			URL:         result.URL,
 			Snippet:     snippet,
 			Credibility: result.Score,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
 		})
 	}
 
@@ -175,13 +202,12 @@ func (a *FinanceAgent) enhanceQueryWithContext(
 	query string,
 	conversationHistory []models.Message,
 ) (string, error) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
+
 	var contextPrompt strings.Builder
 	contextPrompt.WriteString("Предыдущая беседа:\n")
-	for _, msg := range conversationHistory[max(0, len(conversationHistory)-4):] {
-		role := "Пользователь"
-		if msg.Role == "assistant" {
-			role = "Ассистент"
-		}
+	for _, msg := range recentTurns(conversationHistory, 4) {
+		role := turnRole(msg)
 		contextPrompt.WriteString(fmt.Sprintf("%s: %s\n", role, msg.Content))
 	}
 
@@ -191,5 +217,5 @@ func (a *FinanceAgent) enhanceQueryWithContext(
 
 Перефразируй текущий вопрос для поиска финансовой информации. Улучшенный запрос:`, contextPrompt.String(), query)
 
-	return a.llmClient.Complete(ctx, enhancePrompt, 0.3, 150)
+	return a.llmClient.ForTask(tools.TaskQueryEnhance).Complete(ctx, enhancePrompt, 0.3, 150)
 }
\ No newline at end of file
@@ -0,0 +1,60 @@
+package agents
+
+import (
+	"context"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/render"
+)
+
+// Answer formats the LLM is asked to write in (SearchRequest.Format)
+const (
+	AnswerFormatMarkdown = "markdown"
+	AnswerFormatPlain    = "plain"
+	AnswerFormatHTML     = "html"
+)
+
+type answerFormatKey struct{}
+
+// WithAnswerFormat sets the answer format for the agents handling ctx
+func WithAnswerFormat(ctx context.Context, format string) context.Context {
+	if format == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, answerFormatKey{}, format)
+}
+
+// answerFormatFromContext returns the requested answer format, markdown by default
+func answerFormatFromContext(ctx context.Context) string {
+	if format, ok := ctx.Value(answerFormatKey{}).(string); ok {
+		return format
+	}
+	return AnswerFormatMarkdown
+}
+
+// formatInstruction is appended to the synthesis prompt for non-markdown formats
+func formatInstruction(ctx context.Context, lang string) string {
+	switch answerFormatFromContext(ctx) {
+	case AnswerFormatPlain:
+		if lang == "ru" {
+			return "\nОформление: только обычный текст, без Markdown — без **, #, `, таблиц и ссылок в формате [текст](url). Абзацы разделяй пустой строкой.\n\n"
+		}
+		return "\nFormatting: plain text only, no Markdown — no **, #, `, tables or [text](url) links. Separate paragraphs with a blank line.\n\n"
+	case AnswerFormatHTML:
+		if lang == "ru" {
+			return "\nОформление: HTML-фрагмент из тегов <p>, <b>, <i>, <ul>, <ol>, <li>, <br>, <code> и <a href>. Без Markdown, без <html>, <body>, стилей и скриптов.\n\n"
+		}
+		return "\nFormatting: an HTML fragment using only <p>, <b>, <i>, <ul>, <ol>, <li>, <br>, <code> and <a href>. No Markdown, no <html>, <body>, styles or scripts.\n\n"
+	default:
+		return ""
+	}
+}
+
+// formatAnswer post-processes the answer: links its citation markers to the
+// sources and converts it into the requested format
+func formatAnswer(ctx context.Context, result *models.SearchResponse) {
+	linkCitations(result)
+	format := answerFormatFromContext(ctx)
+	result.Answer = render.FormatAnswer(result.Answer, format)
+	result.Format = format
+}
@@ -0,0 +1,59 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/guardrails"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// screen applies the guardrails to a query and its conversation before any of
+// them reaches a search or LLM provider. A blocked query returns a
+// *guardrails.BlockedError; otherwise the query and conversation come back
+// sanitized and redacted, with what was removed (nil when nothing was).
+func (r *RouterAgent) screen(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (string, []models.Message, *models.Guardrails, error) {
+	result, err := r.guard.Check(query, answerLanguage(ctx, query))
+	if err != nil {
+		var blocked *guardrails.BlockedError
+		if errors.As(err, &blocked) {
+			logging.Printf(ctx, "🛡️  Query blocked by guardrails: %s", blocked.Category)
+		}
+		return "", nil, nil, err
+	}
+
+	redacted := result.Redacted
+	var history []models.Message
+	for i, msg := range conversationHistory {
+		content, kinds := r.guard.Redact(msg.Content)
+		if len(kinds) == 0 {
+			continue
+		}
+		if history == nil {
+			history = slices.Clone(conversationHistory)
+		}
+		history[i].Content = content
+		for _, kind := range kinds {
+			if !slices.Contains(redacted, kind) {
+				redacted = append(redacted, kind)
+			}
+		}
+	}
+	if history == nil {
+		history = conversationHistory
+	}
+
+	if len(redacted) == 0 && len(result.Sanitized) == 0 {
+		return result.Query, history, nil, nil
+	}
+	logging.Printf(ctx, "🛡️  Guardrails: redacted [%s], sanitized [%s]",
+		strings.Join(redacted, ", "), strings.Join(result.Sanitized, ", "))
+	return result.Query, history, &models.Guardrails{Redacted: redacted, Sanitized: result.Sanitized}, nil
+}
@@ -0,0 +1,101 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+// hopPlaceholder marks where a chained sub-query takes the answer of an
+// earlier one: "What else did {1} direct?" waits for sub-query 1
+var hopPlaceholder = regexp.MustCompile(`\{(\d+)\}`)
+
+const (
+	// hopAnswerTimeout bounds the extraction of one hop's answer
+	hopAnswerTimeout = 8 * time.Second
+	// hopAnswerMaxRunes rejects answers too long to stand in for a placeholder
+	hopAnswerMaxRunes = 100
+)
+
+// hopDependencies returns the 0-based indexes of the sub-queries query (at
+// index i) waits for; ok is false when it refers to itself, a later sub-query
+// or one that does not exist
+func hopDependencies(query string, i int) (deps []int, ok bool) {
+	for _, m := range hopPlaceholder.FindAllStringSubmatch(query, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > i {
+			return nil, false
+		}
+		deps = append(deps, n-1)
+	}
+	return deps, true
+}
+
+// substituteHops replaces the placeholders of query with the answers of the
+// sub-queries they refer to
+func substituteHops(query string, answers map[int]string) string {
+	return hopPlaceholder.ReplaceAllStringFunc(query, func(m string) string {
+		n, _ := strconv.Atoi(m[1 : len(m)-1])
+		return answers[n-1]
+	})
+}
+
+// answerHop extracts the short answer (a name, title, number or date) to a
+// sub-query from its search results, for the sub-queries chained to it
+func (a *ProAgent) answerHop(ctx context.Context, question string, results []models.TavilyResult, lang string) (string, error) {
+	if len(results) == 0 {
+		return "", fmt.Errorf("no results")
+	}
+
+	var sources strings.Builder
+	for i, result := range results {
+		if i >= 5 {
+			break
+		}
+		sources.WriteString(fmt.Sprintf("%d. %s: %s\n", i+1,
+			utils.SanitizeUTF8(result.Title),
+			utils.TruncateRunesWithEllipsis(utils.SanitizeUTF8(result.Content), 500)))
+	}
+
+	var prompt, none string
+	if lang == "ru" {
+		none = "НЕТ"
+		prompt = fmt.Sprintf(`Вопрос: %s
+
+Результаты поиска:
+%s
+Ответь на вопрос по результатам одним коротким фрагментом: имя, название, число или дата, без пояснений. Если ответа в результатах нет, напиши: НЕТ`, question, sources.String())
+	} else {
+		none = "NONE"
+		prompt = fmt.Sprintf(`Question: %s
+
+Search results:
+%s
+Answer the question from the results with one short fragment: a name, title, number or date, no explanation. If the results do not contain the answer, write: NONE`, question, sources.String())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, hopAnswerTimeout)
+	defer cancel()
+	response, err := a.llmClient.ForTask(tools.TaskPlanning).Complete(ctx, prompt, 0, 30)
+	if err != nil {
+		return "", err
+	}
+
+	answer := strings.TrimSpace(response)
+	answer = strings.Trim(answer, `"'«».`)
+	answer = strings.TrimSpace(answer)
+	if answer == "" || strings.EqualFold(answer, none) {
+		return "", fmt.Errorf("no answer in the results")
+	}
+	if len([]rune(answer)) > hopAnswerMaxRunes {
+		return "", fmt.Errorf("answer too long")
+	}
+	return answer, nil
+}
@@ -0,0 +1,192 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+// maxImages is how many images an answer shows
+const maxImages = 12
+
+// imageMaxWords keeps long questions that merely mention a picture on the
+// search path
+const imageMaxWords = 12
+
+// Image requests: a visual noun asked to be shown ("покажи картину ...",
+// "photo of ...") or a question about looks ("как выглядит ...")
+var (
+	imageNouns = []string{
+		"фотографи", "фотк", "картинк", "картину", "картины", "изображени",
+		"снимк", "снимок", "портрет",
+		"photo", "picture", "image", "painting", "portrait",
+	}
+	imageShowVerbs = []string{"покажи", "покажите", "найди", "найдите", "хочу увидеть", "show", "find"}
+	imageLooks     = []string{"как выглядит", "как выглядят", "look like", "looks like"}
+	// ...but not image processing, generation or file formats
+	imageExclusions = []string{
+		"docker", "контейнер", "обработк", "сгенерир", "генерац", "нарису", "распозна",
+		"формат", "размер", "сжат", "как сделать", "как создать",
+		"processing", "generat", "draw", "recogni", "format", "resize", "compress", "how to",
+	}
+)
+
+// The request wording around the subject of an image query, which image
+// search does better without
+var (
+	imageRequestPrefix = regexp.MustCompile(`(?i)^\s*(?:пожалуйста,?\s+|please,?\s+)?(?:(?:покажи(?:те)?|найди(?:те)?|хочу увидеть|show|find)(?:\s+(?:мне|me))?|как выглядит|как выглядят|what does|what do)\s+`)
+	imageRequestSuffix = regexp.MustCompile(`(?i)(?:\s+looks?\s+like)?(?:,?\s*пожалуйста|,?\s*please)?\s*[?!.]*\s*$`)
+)
+
+// ImagesAgent answers requests to see something ("покажи картину Звёздная
+// ночь") with images from the SearXNG images vertical or Brave, and a short
+// description from a simple web search
+type ImagesAgent struct {
+	searchClient *tools.SearchClient
+	simpleAgent  *SimpleAgent
+}
+
+func NewImagesAgent(searchClient *tools.SearchClient, simpleAgent *SimpleAgent) *ImagesAgent {
+	return &ImagesAgent{
+		searchClient: searchClient,
+		simpleAgent:  simpleAgent,
+	}
+}
+
+// Matches reports whether the query asks to see images
+func (a *ImagesAgent) Matches(query string) bool {
+	queryLower := strings.ToLower(query)
+	words := strings.FieldsFunc(queryLower, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 || len(words) > imageMaxWords {
+		return false
+	}
+	for _, signal := range imageExclusions {
+		if _, ok := matchSignal(queryLower, words, signal); ok {
+			return false
+		}
+	}
+
+	for _, signal := range imageLooks {
+		if strings.Contains(queryLower, signal) {
+			return true
+		}
+	}
+	// "фото Эйфелевой башни" asks for the photo without a verb
+	if isImageNoun(words[0]) {
+		return true
+	}
+	if !slices.ContainsFunc(words, isImageNoun) {
+		return false
+	}
+	for _, verb := range imageShowVerbs {
+		if _, ok := matchSignal(queryLower, words, verb); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isImageNoun reports whether a query word names a picture; "фото" only as a
+// whole word, not "фотосинтез"
+func isImageNoun(word string) bool {
+	if word == "фото" {
+		return true
+	}
+	for _, noun := range imageNouns {
+		if strings.HasPrefix(word, noun) {
+			return true
+		}
+	}
+	return false
+}
+
+// imageSubject strips the request wording from an image query
+func imageSubject(query string) string {
+	subject := imageRequestPrefix.ReplaceAllString(query, "")
+	subject = strings.TrimSpace(imageRequestSuffix.ReplaceAllString(subject, ""))
+	if subject == "" {
+		return query
+	}
+	return subject
+}
+
+func (a *ImagesAgent) Process(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return a.ProcessWithContext(ctx, query, nil)
+}
+
+// ProcessWithContext searches images and the web concurrently; the simple
+// agent describes the subject from the web results. Follow-ups ("покажи его
+// фото") are rephrased with the conversation first.
+func (a *ImagesAgent) ProcessWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	logging.Printf(ctx, "Images mode processing: %s", query)
+
+	reasoningSteps := appendStep(ctx, nil, "🖼️ Запущен режим Images - поиск изображений")
+
+	searchQuery := query
+	if len(conversationHistory) > 0 {
+		reasoningSteps = appendStep(ctx, reasoningSteps, "Адаптирую запрос с учетом контекста...")
+		enhanced, err := a.simpleAgent.enhanceQueryWithContext(ctx, query, conversationHistory)
+		if err == nil && enhanced != "" {
+			searchQuery = strings.TrimSpace(enhanced)
+		}
+	}
+	subject := imageSubject(searchQuery)
+
+	var (
+		wg        sync.WaitGroup
+		images    []models.Image
+		imagesErr error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		images, imagesErr = a.searchClient.SearchImages(ctx, subject, maxImages)
+	}()
+
+	// The description comes from a simple web search for the same subject
+	result, err := a.simpleAgent.ProcessWithContext(ctx, subject, nil)
+	wg.Wait()
+
+	if imagesErr != nil {
+		logging.Printf(ctx, "Image search failed: %v", imagesErr)
+		reasoningSteps = appendStep(ctx, reasoningSteps, "⚠️ Изображения не найдены")
+	} else {
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Найдено изображений: %d", len(images)))
+	}
+	if err != nil {
+		if imagesErr != nil {
+			return nil, err
+		}
+		logging.Printf(ctx, "Image description failed: %v", err)
+		result = &models.SearchResponse{Sources: []models.Source{}}
+		if answerLanguage(ctx, query) == "ru" {
+			result.Answer = fmt.Sprintf("Изображения по запросу «%s».", subject)
+		} else {
+			result.Answer = fmt.Sprintf("Images for \"%s\".", subject)
+		}
+	}
+	if result.Reasoning != "" {
+		reasoningSteps = appendStep(ctx, reasoningSteps, result.Reasoning)
+	}
+
+	result.Query = query
+	result.Mode = "images"
+	result.Images = images
+	result.Reasoning = strings.Join(reasoningSteps, "\n")
+	result.ContextUsed = len(conversationHistory) > 0
+	return result, nil
+}
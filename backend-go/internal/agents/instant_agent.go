@@ -0,0 +1,659 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/scrapers"
+)
+
+// errNoInstantAnswer means the instant agent can't answer the query: it is
+// not a weather, rate or conversion question, or the data provider failed.
+// The router answers such queries with simple mode instead.
+var errNoInstantAnswer = errors.New("no instant answer")
+
+// Kinds of instant questions
+const (
+	instantWeather  = "weather"
+	instantCurrency = "currency"
+	instantUnits    = "units"
+)
+
+// instantProviders name the data provider of each kind in explain plans
+var instantProviders = map[string]string{
+	instantWeather:  "open_meteo",
+	instantCurrency: "cbr",
+	instantUnits:    "units",
+}
+
+// instantMaxWords keeps long questions that merely mention the weather or a
+// currency on the search path
+const instantMaxWords = 12
+
+// Questions about causes, trends and forecasts need research, not a number
+var (
+	weatherExclusions = []string{
+		"недел", "месяц", "климат", "почему", "влия",
+		"week", "month", "climate", "why", "affect",
+	}
+	// ...and so do prices of assets quoted in a currency ("курс биткоина в долларах")
+	currencyExclusions = []string{
+		"почему", "прогноз", "динамик", "истори", "влия",
+		"биткоин", "крипт", "акци", "нефт", "золот",
+		"why", "forecast", "predict", "history", "trend", "affect",
+		"bitcoin", "btc", "crypto", "stock", "share", "oil", "gold",
+	}
+	currencyTriggers = []string{"курс", "переве", "конверт", "rate", "exchange", "convert"}
+)
+
+// InstantAgent answers weather, exchange rate and unit conversion questions
+// from data providers, without web search or the LLM
+type InstantAgent struct {
+	data *scrapers.InstantScraper
+}
+
+func NewInstantAgent() *InstantAgent {
+	return &InstantAgent{data: scrapers.NewInstantScraper()}
+}
+
+// instantQuery is a parsed instant question
+type instantQuery struct {
+	kind     string
+	place    string  // weather
+	tomorrow bool    // weather: forecast for tomorrow instead of now
+	amount   float64 // currency and units; 0 for a plain rate question
+	from, to string  // currency codes or unit symbols
+}
+
+// Matches reports whether the query is an instant question
+func (a *InstantAgent) Matches(query string) bool {
+	return parseInstantQuery(query) != nil
+}
+
+func (a *InstantAgent) Process(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return a.ProcessWithContext(ctx, query, nil)
+}
+
+// ProcessWithContext answers from the data providers; the conversation is not
+// needed for self-contained questions and is ignored
+func (a *InstantAgent) ProcessWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	logging.Printf(ctx, "Instant mode processing: %s", query)
+
+	parsed := parseInstantQuery(query)
+	if parsed == nil {
+		return nil, errNoInstantAnswer
+	}
+	lang := answerLanguage(ctx, query)
+	reasoningSteps := appendStep(ctx, nil, "⚡ Запущен режим Instant - ответ из источника данных без поиска")
+
+	var answer string
+	var sources []models.Source
+	var err error
+	switch parsed.kind {
+	case instantWeather:
+		answer, sources, err = a.weather(ctx, parsed, lang)
+	case instantCurrency:
+		answer, sources, err = a.currency(ctx, parsed, lang)
+	default:
+		answer, err = convertUnits(parsed, lang)
+		sources = []models.Source{}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoInstantAnswer, err)
+	}
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Источник данных: %s", instantProviders[parsed.kind]))
+
+	return &models.SearchResponse{
+		Query:     query,
+		Mode:      "instant",
+		Answer:    answer,
+		Sources:   sources,
+		Reasoning: strings.Join(reasoningSteps, "\n"),
+	}, nil
+}
+
+func (a *InstantAgent) weather(ctx context.Context, q *instantQuery, lang string) (string, []models.Source, error) {
+	weather, err := a.data.Weather(ctx, q.place, detectLanguage(q.place))
+	if err != nil {
+		return "", nil, err
+	}
+
+	place := weather.Place.Name
+	if weather.Place.Country != "" {
+		place = fmt.Sprintf("%s (%s)", place, weather.Place.Country)
+	}
+
+	var answer string
+	daily := weather.Daily
+	if q.tomorrow {
+		if len(daily.TemperatureMax) < 2 || len(daily.TemperatureMin) < 2 || len(daily.WeatherCode) < 2 {
+			return "", nil, fmt.Errorf("no forecast for tomorrow")
+		}
+		precipitation := 0.0
+		if len(daily.Precipitation) > 1 {
+			precipitation = daily.Precipitation[1]
+		}
+		if lang == "ru" {
+			answer = fmt.Sprintf("%s, завтра: от %+.0f до %+.0f °C, %s. Вероятность осадков %.0f%%.",
+				place, daily.TemperatureMin[1], daily.TemperatureMax[1],
+				weatherDescription(daily.WeatherCode[1], lang), precipitation)
+		} else {
+			answer = fmt.Sprintf("%s, tomorrow: %+.0f to %+.0f °C, %s. Chance of precipitation %.0f%%.",
+				place, daily.TemperatureMin[1], daily.TemperatureMax[1],
+				weatherDescription(daily.WeatherCode[1], lang), precipitation)
+		}
+	} else {
+		current := weather.Current
+		if lang == "ru" {
+			answer = fmt.Sprintf("%s, сейчас: %+.0f °C, ощущается как %+.0f °C, %s. Ветер %.0f м/с, влажность %.0f%%.",
+				place, current.Temperature, current.ApparentTemperature,
+				weatherDescription(current.WeatherCode, lang), current.WindSpeed, current.Humidity)
+		} else {
+			answer = fmt.Sprintf("%s, now: %+.0f °C, feels like %+.0f °C, %s. Wind %.0f m/s, humidity %.0f%%.",
+				place, current.Temperature, current.ApparentTemperature,
+				weatherDescription(current.WeatherCode, lang), current.WindSpeed, current.Humidity)
+		}
+		if len(daily.TemperatureMax) > 0 && len(daily.TemperatureMin) > 0 {
+			if lang == "ru" {
+				answer += fmt.Sprintf(" Сегодня от %+.0f до %+.0f °C.", daily.TemperatureMin[0], daily.TemperatureMax[0])
+			} else {
+				answer += fmt.Sprintf(" Today %+.0f to %+.0f °C.", daily.TemperatureMin[0], daily.TemperatureMax[0])
+			}
+		}
+	}
+
+	return answer, []models.Source{{
+		Title:     "Open-Meteo: " + weather.Place.Name,
+		URL:       fmt.Sprintf("https://open-meteo.com/en/docs#latitude=%.4f&longitude=%.4f", weather.Place.Latitude, weather.Place.Longitude),
+		Snippet:   answer,
+		FetchedAt: time.Now().Unix(),
+	}}, nil
+}
+
+func (a *InstantAgent) currency(ctx context.Context, q *instantQuery, lang string) (string, []models.Source, error) {
+	rates, err := a.data.ExchangeRates(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	from, fromPrevious, ok := rates.PerUnit(q.from)
+	if !ok {
+		return "", nil, fmt.Errorf("no rate for %s", q.from)
+	}
+	to, toPrevious, ok := rates.PerUnit(q.to)
+	if !ok {
+		return "", nil, fmt.Errorf("no rate for %s", q.to)
+	}
+
+	date := rates.Date
+	if t, err := time.Parse(time.RFC3339, rates.Date); err == nil {
+		if lang == "ru" {
+			date = t.Format("02.01.2006")
+		} else {
+			date = t.Format("2006-01-02")
+		}
+	}
+
+	rate := from / to
+	var answer string
+	if q.amount > 0 {
+		if lang == "ru" {
+			answer = fmt.Sprintf("%s %s = %s %s по курсу ЦБ РФ на %s.",
+				formatNumber(q.amount, lang), q.from, formatNumber(q.amount*rate, lang), q.to, date)
+		} else {
+			answer = fmt.Sprintf("%s %s = %s %s at the Bank of Russia rate for %s.",
+				formatNumber(q.amount, lang), q.from, formatNumber(q.amount*rate, lang), q.to, date)
+		}
+	} else {
+		change := ""
+		if toPrevious > 0 {
+			if delta := rate - fromPrevious/toPrevious; math.Abs(delta) >= 0.00005 {
+				sign := "+"
+				if delta < 0 {
+					sign = "−"
+				}
+				if lang == "ru" {
+					change = fmt.Sprintf(" (%s%s за день)", sign, formatNumber(math.Abs(delta), lang))
+				} else {
+					change = fmt.Sprintf(" (%s%s over the day)", sign, formatNumber(math.Abs(delta), lang))
+				}
+			}
+		}
+		if lang == "ru" {
+			answer = fmt.Sprintf("Курс ЦБ РФ на %s: 1 %s = %s %s%s.", date, q.from, formatNumber(rate, lang), q.to, change)
+		} else {
+			answer = fmt.Sprintf("Bank of Russia rate for %s: 1 %s = %s %s%s.", date, q.from, formatNumber(rate, lang), q.to, change)
+		}
+	}
+
+	title := "Банк России: официальные курсы валют"
+	if lang != "ru" {
+		title = "Bank of Russia: official exchange rates"
+	}
+	return answer, []models.Source{{
+		Title:     title,
+		URL:       "https://www.cbr.ru/currency_base/daily/",
+		Snippet:   answer,
+		FetchedAt: time.Now().Unix(),
+	}}, nil
+}
+
+// parseInstantQuery recognizes unit conversions, currency questions and
+// weather questions, in that order; nil for anything else
+func parseInstantQuery(query string) *instantQuery {
+	lower := strings.ToLower(strings.TrimSpace(query))
+	lower = strings.TrimRight(lower, "?!. ")
+	words := strings.Fields(lower)
+	if len(words) == 0 || len(words) > instantMaxWords || containsAny(lower, complexIndicators) {
+		return nil
+	}
+
+	if q := parseUnitConversion(lower); q != nil {
+		return q
+	}
+	if q := parseCurrency(lower); q != nil {
+		return q
+	}
+	return parseWeather(words)
+}
+
+// Weather questions
+
+// weatherFillers are dropped around the place name
+var weatherFillers = map[string]bool{
+	"какая": true, "какой": true, "какое": true, "сейчас": true, "сегодня": true,
+	"завтра": true, "на": true, "будет": true, "за": true, "окном": true, "прогноз": true,
+	"what": true, "what's": true, "whats": true, "is": true, "the": true, "like": true,
+	"how": true, "now": true, "today": true, "tomorrow": true, "will": true, "be": true,
+	"forecast": true, "current": true,
+}
+
+// weatherPrepositions precede the place name
+var weatherPrepositions = map[string]bool{"в": true, "во": true, "in": true, "for": true, "at": true}
+
+func parseWeather(words []string) *instantQuery {
+	joined := strings.Join(words, " ")
+	if containsAny(joined, weatherExclusions) {
+		return nil
+	}
+
+	at := -1
+	var kept []string
+	tomorrow := false
+	for _, word := range words {
+		word = strings.Trim(word, ",:;")
+		switch {
+		case word == "завтра" || word == "tomorrow":
+			tomorrow = true
+		case weatherFillers[word] || word == "":
+		case at < 0 && (strings.HasPrefix(word, "погод") || word == "weather"):
+			at = len(kept)
+		default:
+			kept = append(kept, word)
+		}
+	}
+	if at < 0 {
+		return nil
+	}
+
+	// The place follows the weather word ("погода в Москве") or precedes it
+	// ("в Москве погода", "london weather")
+	place := kept[at:]
+	if len(place) == 0 {
+		place = kept[:at]
+	}
+	if len(place) > 0 && weatherPrepositions[place[0]] {
+		place = place[1:]
+	}
+	if len(place) == 0 || len(place) > 4 {
+		return nil
+	}
+	for _, word := range place {
+		if weatherPrepositions[word] || strings.ContainsFunc(word, unicode.IsDigit) {
+			return nil
+		}
+	}
+	return &instantQuery{kind: instantWeather, place: strings.Join(place, " "), tomorrow: tomorrow}
+}
+
+// Currency questions
+
+// currency maps names of a currency, in any case form, to its code. Names
+// are matched as word prefixes, codes and symbols exactly.
+type currency struct {
+	code  string
+	names []string
+	stems []string
+}
+
+var currencies = []currency{
+	{code: "RUB", names: []string{"rub", "руб", "₽"}, stems: []string{"рубл", "ruble", "rouble"}},
+	{code: "USD", names: []string{"usd", "$"}, stems: []string{"доллар", "dollar", "бакс"}},
+	{code: "EUR", names: []string{"eur", "€", "евро", "euro", "euros"}},
+	{code: "CNY", names: []string{"cny", "rmb", "yuan"}, stems: []string{"юан"}},
+	{code: "GBP", names: []string{"gbp", "£"}, stems: []string{"стерлинг", "sterling"}},
+	{code: "JPY", names: []string{"jpy", "yen"}, stems: []string{"иен", "йен"}},
+	{code: "CHF", names: []string{"chf"}, stems: []string{"франк", "franc"}},
+	{code: "KZT", names: []string{"kzt"}, stems: []string{"тенге", "tenge"}},
+	{code: "TRY", names: []string{"лира", "лиры", "лир", "лирах", "lira", "liras"}},
+	{code: "BYN", names: []string{"byn"}, stems: []string{"белорусск"}},
+	{code: "UAH", names: []string{"uah"}, stems: []string{"гривн", "hryvni"}},
+}
+
+// currencySymbols are split off the amount they are written next to ("100$")
+var currencySymbols = strings.NewReplacer("$", " $ ", "€", " € ", "£", " £ ", "₽", " ₽ ")
+
+// amountPattern finds an amount, optionally in thousands, millions or billions
+var amountPattern = regexp.MustCompile(`(^|\s)(\d+(?:[.,]\d+)?)(?:\s*(тыс|млн|млрд|миллион|миллиард|thousand|million|billion)\S*)?(\s|$)`)
+
+var amountMultipliers = map[string]float64{
+	"тыс": 1e3, "thousand": 1e3,
+	"млн": 1e6, "миллион": 1e6, "million": 1e6,
+	"млрд": 1e9, "миллиард": 1e9, "billion": 1e9,
+}
+
+func currencyCode(word string) string {
+	for _, c := range currencies {
+		for _, name := range c.names {
+			if word == name {
+				return c.code
+			}
+		}
+		for _, stem := range c.stems {
+			if strings.HasPrefix(word, stem) {
+				return c.code
+			}
+		}
+	}
+	return ""
+}
+
+func parseCurrency(lower string) *instantQuery {
+	if containsAny(lower, currencyExclusions) {
+		return nil
+	}
+	lower = currencySymbols.Replace(lower)
+
+	var codes []string
+	for _, word := range strings.FieldsFunc(lower, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(",;:()/", r)
+	}) {
+		code := currencyCode(word)
+		if code != "" && (len(codes) == 0 || codes[len(codes)-1] != code) {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 || len(codes) > 2 {
+		return nil
+	}
+
+	amount := 0.0
+	if m := amountPattern.FindStringSubmatch(lower); m != nil {
+		amount, _ = strconv.ParseFloat(strings.Replace(m[2], ",", ".", 1), 64)
+		if multiplier, ok := amountMultipliers[m[3]]; ok {
+			amount *= multiplier
+		}
+	}
+	// A rate question names the rate; a conversion gives an amount and both currencies
+	if !containsAny(lower, currencyTriggers) && (amount == 0 || len(codes) < 2) {
+		return nil
+	}
+
+	q := &instantQuery{kind: instantCurrency, amount: amount, from: codes[0], to: "RUB"}
+	if len(codes) == 2 {
+		q.to = codes[1]
+	}
+	if q.from == q.to {
+		return nil
+	}
+	return q
+}
+
+// Unit conversions
+
+// unit is a unit of measure. Units of a dimension convert through its base
+// unit (meter, kilogram, liter, m/s); temperatures have their own scales.
+type unit struct {
+	symbol    string // English symbol, also the unit's key
+	symbolRu  string
+	dimension string
+	factor    float64 // size in base units
+	names     []string
+	stems     []string
+}
+
+var units = []unit{
+	{symbol: "km", symbolRu: "км", dimension: "length", factor: 1000, names: []string{"km", "км"}, stems: []string{"километр", "kilomet"}},
+	{symbol: "m", symbolRu: "м", dimension: "length", factor: 1, names: []string{"m", "м"}, stems: []string{"метр", "meter", "metre"}},
+	{symbol: "cm", symbolRu: "см", dimension: "length", factor: 0.01, names: []string{"cm", "см"}, stems: []string{"сантиметр", "centimet"}},
+	{symbol: "mm", symbolRu: "мм", dimension: "length", factor: 0.001, names: []string{"mm", "мм"}, stems: []string{"миллиметр", "millimet"}},
+	{symbol: "mi", symbolRu: "миль", dimension: "length", factor: 1609.344, names: []string{"mi", "миля", "мили", "миль", "милях", "милю", "милей"}, stems: []string{"mile"}},
+	{symbol: "ft", symbolRu: "фут", dimension: "length", factor: 0.3048, names: []string{"ft", "foot", "feet"}, stems: []string{"фут"}},
+	{symbol: "in", symbolRu: "дюйм", dimension: "length", factor: 0.0254, stems: []string{"дюйм", "inch"}},
+	{symbol: "yd", symbolRu: "ярд", dimension: "length", factor: 0.9144, names: []string{"yd"}, stems: []string{"ярд", "yard"}},
+	{symbol: "kg", symbolRu: "кг", dimension: "mass", factor: 1, names: []string{"kg", "кг"}, stems: []string{"килограм", "kilogram"}},
+	{symbol: "g", symbolRu: "г", dimension: "mass", factor: 0.001, names: []string{"g", "г"}, stems: []string{"грамм", "gram"}},
+	{symbol: "t", symbolRu: "т", dimension: "mass", factor: 1000, names: []string{"t", "т"}, stems: []string{"тонн", "tonne", "ton"}},
+	{symbol: "lb", symbolRu: "фунт", dimension: "mass", factor: 0.45359237, names: []string{"lb", "lbs"}, stems: []string{"фунт", "pound"}},
+	{symbol: "oz", symbolRu: "унц", dimension: "mass", factor: 0.028349523125, names: []string{"oz"}, stems: []string{"унци", "ounce"}},
+	{symbol: "l", symbolRu: "л", dimension: "volume", factor: 1, names: []string{"l", "л"}, stems: []string{"литр", "liter", "litre"}},
+	{symbol: "ml", symbolRu: "мл", dimension: "volume", factor: 0.001, names: []string{"ml", "мл"}, stems: []string{"миллилитр", "millilit"}},
+	{symbol: "gal", symbolRu: "галлон", dimension: "volume", factor: 3.785411784, names: []string{"gal"}, stems: []string{"галлон", "gallon"}},
+	{symbol: "km/h", symbolRu: "км/ч", dimension: "speed", factor: 1 / 3.6, names: []string{"km/h", "км/ч", "kmh", "kph"}},
+	{symbol: "m/s", symbolRu: "м/с", dimension: "speed", factor: 1, names: []string{"m/s", "м/с"}},
+	{symbol: "mph", symbolRu: "миль/ч", dimension: "speed", factor: 0.44704, names: []string{"mph"}},
+	{symbol: "kn", symbolRu: "уз", dimension: "speed", factor: 0.514444, names: []string{"узел", "узла", "узлов", "узлах", "узлы"}, stems: []string{"knot"}},
+	{symbol: "°C", symbolRu: "°C", dimension: "temperature", names: []string{"c", "°c", "с", "°с"}, stems: []string{"цельси", "celsius"}},
+	{symbol: "°F", symbolRu: "°F", dimension: "temperature", names: []string{"f", "°f"}, stems: []string{"фаренгейт", "fahrenheit"}},
+	{symbol: "K", symbolRu: "K", dimension: "temperature", names: []string{"k"}, stems: []string{"кельвин", "kelvin"}},
+}
+
+var conversionPattern = regexp.MustCompile(`(-?\d+(?:[.,]\d+)?)\s*(.+?)\s+(?:в|во|to|in|into)\s+(.+)$`)
+
+// lookupUnit finds the unit named by a phrase like "km", "градусов
+// Цельсия" or "милях"; the longest matching stem wins, so "миллиметр"
+// is not taken for a meter
+func lookupUnit(phrase string) (unit, bool) {
+	var words []string
+	for _, word := range strings.Fields(phrase) {
+		if strings.HasPrefix(word, "градус") || strings.HasPrefix(word, "degree") || word == "по" {
+			continue
+		}
+		words = append(words, word)
+	}
+	if len(words) != 1 {
+		return unit{}, false
+	}
+	word := words[0]
+
+	best, bestLen := unit{}, 0
+	for _, u := range units {
+		for _, name := range u.names {
+			if word == name {
+				return u, true
+			}
+		}
+		for _, stem := range u.stems {
+			if strings.HasPrefix(word, stem) && len(stem) > bestLen {
+				best, bestLen = u, len(stem)
+			}
+		}
+	}
+	return best, bestLen > 0
+}
+
+func parseUnitConversion(lower string) *instantQuery {
+	m := conversionPattern.FindStringSubmatch(lower)
+	if m == nil {
+		return nil
+	}
+	from, ok := lookupUnit(m[2])
+	if !ok {
+		return nil
+	}
+	to, ok := lookupUnit(m[3])
+	if !ok || to.dimension != from.dimension || to.symbol == from.symbol {
+		return nil
+	}
+	amount, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+	if err != nil {
+		return nil
+	}
+	return &instantQuery{kind: instantUnits, amount: amount, from: from.symbol, to: to.symbol}
+}
+
+func unitBySymbol(symbol string) unit {
+	for _, u := range units {
+		if u.symbol == symbol {
+			return u
+		}
+	}
+	return unit{}
+}
+
+// toCelsius and fromCelsius convert temperatures through Celsius
+func toCelsius(value float64, symbol string) float64 {
+	switch symbol {
+	case "°F":
+		return (value - 32) * 5 / 9
+	case "K":
+		return value - 273.15
+	default:
+		return value
+	}
+}
+
+func fromCelsius(value float64, symbol string) float64 {
+	switch symbol {
+	case "°F":
+		return value*9/5 + 32
+	case "K":
+		return value + 273.15
+	default:
+		return value
+	}
+}
+
+func convertUnits(q *instantQuery, lang string) (string, error) {
+	result, err := convertAmount(q)
+	if err != nil {
+		return "", err
+	}
+
+	from, to := unitBySymbol(q.from), unitBySymbol(q.to)
+	fromSymbol, toSymbol := from.symbol, to.symbol
+	if lang == "ru" {
+		fromSymbol, toSymbol = from.symbolRu, to.symbolRu
+	}
+	return fmt.Sprintf("%s %s = %s %s", formatNumber(q.amount, lang), fromSymbol, formatNumber(result, lang), toSymbol), nil
+}
+
+// convertAmount converts the amount of a unit conversion query
+func convertAmount(q *instantQuery) (float64, error) {
+	from, to := unitBySymbol(q.from), unitBySymbol(q.to)
+	if from.dimension == "" || from.dimension != to.dimension {
+		return 0, fmt.Errorf("can't convert %s to %s", q.from, q.to)
+	}
+	if from.dimension == "temperature" {
+		return fromCelsius(toCelsius(q.amount, from.symbol), to.symbol), nil
+	}
+	return q.amount * from.factor / to.factor, nil
+}
+
+// formatNumber rounds to 2 decimals (4 below 1), groups thousands and uses
+// a decimal comma for Russian
+func formatNumber(value float64, lang string) string {
+	decimals := 2
+	if math.Abs(value) < 1 {
+		decimals = 4
+	}
+	text := strconv.FormatFloat(value, 'f', decimals, 64)
+	if strings.Contains(text, ".") {
+		text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	}
+
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, fraction, hasFraction := strings.Cut(text, ".")
+	groupSep, decimalSep := ",", "."
+	if lang == "ru" {
+		groupSep, decimalSep = " ", ","
+	}
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(groupSep)
+		}
+		grouped.WriteRune(digit)
+	}
+	if hasFraction {
+		return sign + grouped.String() + decimalSep + fraction
+	}
+	return sign + grouped.String()
+}
+
+// weatherDescription describes a WMO weather code
+func weatherDescription(code int, lang string) string {
+	type description struct{ ru, en string }
+	var d description
+	switch code {
+	case 0:
+		d = description{"ясно", "clear sky"}
+	case 1:
+		d = description{"преимущественно ясно", "mainly clear"}
+	case 2:
+		d = description{"переменная облачность", "partly cloudy"}
+	case 3:
+		d = description{"пасмурно", "overcast"}
+	case 45, 48:
+		d = description{"туман", "fog"}
+	case 51, 53, 55:
+		d = description{"морось", "drizzle"}
+	case 56, 57:
+		d = description{"ледяная морось", "freezing drizzle"}
+	case 61:
+		d = description{"небольшой дождь", "light rain"}
+	case 63:
+		d = description{"дождь", "rain"}
+	case 65:
+		d = description{"сильный дождь", "heavy rain"}
+	case 66, 67:
+		d = description{"ледяной дождь", "freezing rain"}
+	case 71:
+		d = description{"небольшой снег", "light snow"}
+	case 73:
+		d = description{"снег", "snow"}
+	case 75:
+		d = description{"сильный снег", "heavy snow"}
+	case 77:
+		d = description{"снежная крупа", "snow grains"}
+	case 80, 81, 82:
+		d = description{"ливень", "rain showers"}
+	case 85, 86:
+		d = description{"снегопад", "snow showers"}
+	case 95:
+		d = description{"гроза", "thunderstorm"}
+	case 96, 99:
+		d = description{"гроза с градом", "thunderstorm with hail"}
+	default:
+		d = description{"без осадков", "no precipitation"}
+	}
+	if lang == "ru" {
+		return d.ru
+	}
+	return d.en
+}
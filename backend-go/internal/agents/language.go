@@ -0,0 +1,47 @@
+package agents
+
+import "context"
+
+type answerLangKey struct{}
+
+// WithAnswerLanguage forces the answer language (ru or en) for the agents
+// handling ctx instead of detecting it from the query
+func WithAnswerLanguage(ctx context.Context, lang string) context.Context {
+	if lang == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, answerLangKey{}, lang)
+}
+
+// answerLanguageOverride returns the forced answer language, if any
+func answerLanguageOverride(ctx context.Context) string {
+	lang, _ := ctx.Value(answerLangKey{}).(string)
+	return lang
+}
+
+// answerLanguage returns the forced answer language or the one detected from query
+func answerLanguage(ctx context.Context, query string) string {
+	if lang := answerLanguageOverride(ctx); lang != "" {
+		return lang
+	}
+	return detectLanguage(query)
+}
+
+// languageInstruction is appended to the synthesis prompt (written in
+// promptLang) when the answer language was forced
+func languageInstruction(ctx context.Context, promptLang string) string {
+	switch answerLanguageOverride(ctx) {
+	case "en":
+		if promptLang == "ru" {
+			return "\nЯзык ответа: английский, даже если вопрос задан на русском.\n\n"
+		}
+		return "\nAnswer language: English, even if the question is in another language.\n\n"
+	case "ru":
+		if promptLang == "ru" {
+			return "\nЯзык ответа: русский, даже если вопрос задан на другом языке.\n\n"
+		}
+		return "\nAnswer language: Russian, even if the question is in English.\n\n"
+	default:
+		return ""
+	}
+}
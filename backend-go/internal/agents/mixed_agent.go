@@ -0,0 +1,201 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+// mixedTimeout bounds the domain agents and the merge
+const mixedTimeout = 45 * time.Second
+
+// mixedSections are the section titles of the merged answer by vertical
+var mixedSections = map[string]string{
+	"pro-finance":  "Инвесторы и рынок",
+	"pro-academic": "Исследователи",
+	"pro-social":   "Сообщество и пользователи",
+}
+
+// MixedAgent answers queries spanning several domains ("what do investors and
+// researchers think about X"): the Social, Academic and Finance agents run
+// concurrently and their findings and sources are merged into one answer with
+// a section per domain
+type MixedAgent struct {
+	agents    map[string]modeAgent
+	llmClient *tools.LLMClient
+}
+
+func NewMixedAgent(social *SocialAgent, academic *AcademicAgent, finance *FinanceAgent, llmClient *tools.LLMClient) *MixedAgent {
+	return &MixedAgent{
+		agents: map[string]modeAgent{
+			"pro-social":   social,
+			"pro-academic": academic,
+			"pro-finance":  finance,
+		},
+		llmClient: llmClient,
+	}
+}
+
+func (a *MixedAgent) Process(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return a.ProcessWithContext(ctx, query, nil)
+}
+
+// domainAnswer is the answer of one domain agent
+type domainAnswer struct {
+	agent   string
+	section string
+	result  *models.SearchResponse
+	err     error
+}
+
+func (a *MixedAgent) ProcessWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	budgetCtx, cancel := context.WithTimeout(ctx, mixedTimeout)
+	defer cancel()
+
+	result, err := a.process(budgetCtx, query, conversationHistory)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(budgetCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: pro-mixed mode ran out of its %s budget: %w", tools.ErrBudgetExceeded, mixedTimeout, err)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *MixedAgent) process(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	logging.Printf(ctx, "Pro Mixed mode processing: %s", query)
+
+	domains := queryDomains(query)
+	names := make([]string, len(domains))
+	for i, domain := range domains {
+		names[i] = domain.section
+	}
+	reasoningSteps := appendStep(ctx, nil, fmt.Sprintf("🧩 Запущен режим Mixed - запрос охватывает несколько областей: %s",
+		strings.Join(names, ", ")))
+
+	// The domain answers are merged as markdown; the merged answer gets the
+	// requested format
+	domainCtx := WithAnswerFormat(ctx, AnswerFormatMarkdown)
+	var wg sync.WaitGroup
+	for i := range domains {
+		wg.Add(1)
+		go func(d *domainAnswer) {
+			defer wg.Done()
+			d.result, d.err = a.agents[d.agent].ProcessWithContext(domainCtx, query, conversationHistory)
+		}(&domains[i])
+	}
+	wg.Wait()
+
+	// Merge the sources into one list; a URL found by several agents is
+	// listed once and each answer's markers are renumbered to the merged list
+	var sources []models.Source
+	index := make(map[string]int)
+	var findings strings.Builder
+	answered := 0
+	for _, d := range domains {
+		if d.err != nil {
+			logging.Printf(ctx, "⚠️  Mixed mode: %s failed: %v", d.agent, d.err)
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("⚠️ %s: нет ответа", d.section))
+			continue
+		}
+		answered++
+
+		renumbered := make(map[int]int, len(d.result.Sources))
+		for i, source := range d.result.Sources {
+			key := strings.TrimSuffix(source.URL, "/")
+			n, ok := index[key]
+			if !ok || key == "" {
+				sources = append(sources, source)
+				n = len(sources)
+				index[key] = n
+			}
+			renumbered[i+1] = n
+		}
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ %s: %d источников", d.section, len(d.result.Sources)))
+
+		answer := renumberCitations(d.result.Answer, func(n int) (int, bool) {
+			m, ok := renumbered[n]
+			return m, ok
+		})
+		findings.WriteString(fmt.Sprintf("### %s\n%s\n\n", d.section, answer))
+	}
+	if answered == 0 {
+		return nil, fmt.Errorf("all domain agents failed: %w", domains[0].err)
+	}
+	if sources == nil {
+		sources = []models.Source{}
+	}
+
+	var system strings.Builder
+	system.WriteString(`Ты исследовательский ассистент. На вопрос ответили агенты разных областей. Объедини их выводы в один ответ.
+
+Твоя задача:
+1. Коротко ответить на вопрос в начале
+2. Дать отдельный раздел для каждой области с её позицией
+3. В конце сравнить позиции: где они сходятся и где расходятся
+
+`)
+	system.WriteString("Сохрани номера источников в квадратных скобках из выводов, например [3]. Не добавляй фактов, которых нет в выводах.\n")
+	system.WriteString(formatInstruction(ctx, "ru"))
+	system.WriteString(languageInstruction(ctx, "ru"))
+	system.WriteString(personaInstruction(ctx, "ru"))
+	system.WriteString("Пиши на языке вопроса.\n")
+
+	var promptBuilder strings.Builder
+	promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\nВыводы по областям:\n\n", query))
+	promptBuilder.WriteString(findings.String())
+	promptBuilder.WriteString("Источники:\n")
+	for i, source := range sources {
+		promptBuilder.WriteString(fmt.Sprintf("%d. %s (%s)\n", i+1, source.Title, source.URL))
+	}
+	promptBuilder.WriteString("\nОтвет:")
+
+	reasoningSteps = appendStep(ctx, reasoningSteps, "💡 Объединяю выводы областей в один ответ...")
+	synthesisStart := time.Now()
+	answer, err := a.llmClient.ForTask(tools.TaskSynthesis).CompletePrompt(ctx, tools.Prompt{
+		System: strings.TrimSpace(system.String()),
+		User:   promptBuilder.String(),
+	}, 0.5, 1800)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
+	if err != nil {
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+
+	return &models.SearchResponse{
+		Query:       query,
+		Mode:        "pro-mixed",
+		Answer:      answer,
+		Sources:     sources,
+		Reasoning:   strings.Join(reasoningSteps, "\n"),
+		ContextUsed: len(conversationHistory) > 0,
+	}, nil
+}
+
+// queryDomains returns the domains the query spans, or all of them when it
+// is not detected as mixed (explicit pro-mixed mode)
+func queryDomains(query string) []domainAnswer {
+	agents := mixedVerticals
+	if mixed := detectMixed(query); mixed != nil {
+		agents = mixed.Domains
+	}
+	domains := make([]domainAnswer, len(agents))
+	for i, agent := range agents {
+		domains[i] = domainAnswer{agent: agent, section: mixedSections[agent]}
+	}
+	return domains
+}
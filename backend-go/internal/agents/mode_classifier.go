@@ -0,0 +1,264 @@
+package agents
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+// defaultModeExamples is the built-in labeled set, used when
+// MODE_EXAMPLES_PATH is not set
+//
+//go:embed mode_examples.json
+var defaultModeExamples []byte
+
+const (
+	// classifierNeighbors is how many of the nearest examples vote
+	classifierNeighbors = 5
+	// classifierRetryAfter keeps a failed embedding of the examples from
+	// being retried on every query
+	classifierRetryAfter = time.Minute
+	// maxCachedQueries bounds the query embeddings kept for the mode and
+	// domain decisions of one request
+	maxCachedQueries = 256
+)
+
+// domainAgents are the vertical agents of the example domains; "general"
+// queries stay with simple or pro
+var domainAgents = map[string]string{
+	"finance":  "pro-finance",
+	"academic": "pro-academic",
+	"social":   "pro-social",
+	"news":     "pro-news",
+}
+
+// modeExample is a query labeled with the mode that answers it best and its
+// domain
+type modeExample struct {
+	Query  string `json:"query"`
+	Mode   string `json:"mode"`             // simple or pro
+	Domain string `json:"domain,omitempty"` // finance, academic, social, news or general (default)
+}
+
+// ModeClassifier picks simple or pro and the domain of a query by its
+// nearest labeled examples in embedding space, so paraphrases of an example
+// route like it. The examples are embedded on first use.
+type ModeClassifier struct {
+	llmClient *tools.LLMClient
+	examples  []modeExample
+
+	mu       sync.Mutex
+	vectors  [][]float32 // normalized embeddings of examples, nil until embedded
+	retryAt  time.Time
+	embedErr error
+	queries  map[string][]float32 // normalized embeddings of recent queries
+}
+
+// modeClassification is the vote of the nearest examples for a query.
+// Margins are the share of the vote the winner leads by (0-1);
+// domainExamples are the neighbors that voted for the domain.
+type modeClassification struct {
+	mode           string
+	margin         float64
+	domain         string
+	domainMargin   float64
+	domainExamples []string
+}
+
+// NewModeClassifier loads the examples from path, or the built-in set when
+// path is empty
+func NewModeClassifier(llmClient *tools.LLMClient, path string) (*ModeClassifier, error) {
+	data := defaultModeExamples
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("read mode examples: %w", err)
+		}
+	}
+
+	var examples []modeExample
+	if err := json.Unmarshal(data, &examples); err != nil {
+		return nil, fmt.Errorf("parse mode examples: %w", err)
+	}
+	labels := make(map[string]int)
+	for i, example := range examples {
+		if example.Mode != "simple" && example.Mode != "pro" {
+			return nil, fmt.Errorf("mode example %d: mode must be simple or pro, got %q", i, example.Mode)
+		}
+		if example.Query == "" {
+			return nil, fmt.Errorf("mode example %d: empty query", i)
+		}
+		if example.Domain == "" {
+			examples[i].Domain = "general"
+		} else if _, ok := domainAgents[example.Domain]; !ok && example.Domain != "general" {
+			return nil, fmt.Errorf("mode example %d: unknown domain %q", i, example.Domain)
+		}
+		labels[example.Mode]++
+	}
+	if labels["simple"] == 0 || labels["pro"] == 0 {
+		return nil, fmt.Errorf("mode examples need both simple and pro queries")
+	}
+
+	return &ModeClassifier{
+		llmClient: llmClient,
+		examples:  examples,
+		queries:   make(map[string][]float32),
+	}, nil
+}
+
+// Classify embeds the query and lets its nearest examples vote on the mode
+// and on the domain, weighted by similarity
+func (c *ModeClassifier) Classify(ctx context.Context, query string) (modeClassification, error) {
+	vectors, err := c.exampleVectors(ctx)
+	if err != nil {
+		return modeClassification{}, err
+	}
+	queryVector, err := c.queryVector(ctx, query)
+	if err != nil {
+		return modeClassification{}, err
+	}
+
+	type neighbor struct {
+		example    modeExample
+		similarity float64
+	}
+	neighbors := make([]neighbor, len(vectors))
+	for i, vector := range vectors {
+		neighbors[i] = neighbor{example: c.examples[i], similarity: dot(queryVector, vector)}
+	}
+	sort.Slice(neighbors, func(i, j int) bool { return neighbors[i].similarity > neighbors[j].similarity })
+	if len(neighbors) > classifierNeighbors {
+		neighbors = neighbors[:classifierNeighbors]
+	}
+
+	modeVotes := make(map[string]float64)
+	domainVotes := make(map[string]float64)
+	var total float64
+	for _, n := range neighbors {
+		weight := math.Max(n.similarity, 0)
+		modeVotes[n.example.Mode] += weight
+		domainVotes[n.example.Domain] += weight
+		total += weight
+	}
+	result := modeClassification{mode: "simple", domain: "general"}
+	if total == 0 {
+		return result, nil
+	}
+
+	if modeVotes["pro"] > modeVotes["simple"] {
+		result.mode = "pro"
+	}
+	result.margin = math.Abs(modeVotes["pro"]-modeVotes["simple"]) / total
+
+	var best, second float64
+	for domain, vote := range domainVotes {
+		switch {
+		case vote > best:
+			second, best = best, vote
+			result.domain = domain
+		case vote > second:
+			second = vote
+		}
+	}
+	result.domainMargin = (best - second) / total
+	for _, n := range neighbors {
+		if n.example.Domain == result.domain {
+			result.domainExamples = append(result.domainExamples, n.example.Query)
+		}
+	}
+	return result, nil
+}
+
+// queryVector embeds the query, reusing the embedding when the mode and the
+// domain of one request are both asked for
+func (c *ModeClassifier) queryVector(ctx context.Context, query string) ([]float32, error) {
+	c.mu.Lock()
+	vector, ok := c.queries[query]
+	c.mu.Unlock()
+	if ok {
+		return vector, nil
+	}
+
+	embeddings, err := c.llmClient.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	vector = normalize(embeddings[0])
+
+	c.mu.Lock()
+	if len(c.queries) >= maxCachedQueries {
+		clear(c.queries)
+	}
+	c.queries[query] = vector
+	c.mu.Unlock()
+	return vector, nil
+}
+
+// exampleVectors embeds the examples once; after a failure it retries only
+// after classifierRetryAfter
+func (c *ModeClassifier) exampleVectors(ctx context.Context) ([][]float32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.vectors != nil {
+		return c.vectors, nil
+	}
+	if time.Now().Before(c.retryAt) {
+		return nil, c.embedErr
+	}
+
+	queries := make([]string, len(c.examples))
+	for i, example := range c.examples {
+		queries[i] = example.Query
+	}
+	embeddings, err := c.llmClient.Embed(ctx, queries)
+	if err != nil {
+		c.retryAt = time.Now().Add(classifierRetryAfter)
+		c.embedErr = fmt.Errorf("embed mode examples: %w", err)
+		return nil, c.embedErr
+	}
+	vectors := make([][]float32, len(embeddings))
+	for i, embedding := range embeddings {
+		vectors[i] = normalize(embedding)
+	}
+	c.vectors = vectors
+	logging.Printf(ctx, "🧭 Mode classifier: embedded %d examples", len(vectors))
+	return vectors, nil
+}
+
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	norm = math.Sqrt(norm)
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+// dot is the cosine similarity of two normalized vectors
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
@@ -2,88 +2,132 @@ package agents
 
 import (
 	"context"
-	"log"
+	"math"
 	"strings"
 
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
 )
 
-type ModeSelector struct {
-	llmClient *tools.LLMClient
+// Keywords of factual and analytical queries, counted as features by the
+// auto mode routing model
+var simpleIndicators = []string{
+	"кто", "что такое", "когда", "где", "сколько",
+	"какой", "какая", "какое", "как зовут",
+	"столица", "год", "дата", "возраст",
+	"погода", "курс", "цена",
+	"who", "what is", "when", "where", "how much",
+	"capital", "weather", "price",
 }
 
-func NewModeSelector(llmClient *tools.LLMClient) *ModeSelector {
-	return &ModeSelector{llmClient: llmClient}
+var complexIndicators = []string{
+	"сравни", "проанализируй", "объясни почему",
+	"различия между", "преимущества и недостатки",
+	"как работает", "причины", "последствия",
+	"влияние", "взаимосвязь", "теории",
+	"compare", "analyze", "explain why",
+	"differences between", "advantages and disadvantages",
+	"how does", "causes", "consequences",
 }
 
-func (m *ModeSelector) SelectMode(ctx context.Context, query string) (string, error) {
-	queryLower := strings.ToLower(query)
-
-	// Simple heuristics for quick classification
-	simpleIndicators := []string{
-		"кто", "что такое", "когда", "где", "сколько",
-		"какой", "какая", "какое", "как зовут",
-		"столица", "год", "дата", "возраст",
-		"погода", "курс", "цена",
-		"who", "what is", "when", "where", "how much",
-		"capital", "weather", "price",
-	}
+// modeSchema is the reply of the mode selection prompt
+var modeSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"mode": map[string]interface{}{"type": "string", "enum": []string{"simple", "pro"}},
+	},
+	"required": []string{"mode"},
+}
 
-	complexIndicators := []string{
-		"сравни", "проанализируй", "объясни почему",
-		"различия между", "преимущества и недостатки",
-		"как работает", "причины", "последствия",
-		"влияние", "взаимосвязь", "теории",
-		"compare", "analyze", "explain why",
-		"differences between", "advantages and disadvantages",
-		"how does", "causes", "consequences",
-	}
+// modeSelectionPrompt is the system prompt of the mode selection, and
+// modeSelectionExamples its few-shot turns
+const modeSelectionPrompt = `Ты классификатор запросов. Определи сложность запроса.
 
-	hasSimple := containsAny(queryLower, simpleIndicators)
-	hasComplex := containsAny(queryLower, complexIndicators)
+SIMPLE - для простых фактических вопросов: кто, когда, где, сколько.
+PRO - для сложных аналитических вопросов: сравнения, причины, последствия, анализ.
 
-	// Quick decision for obvious cases
-	if hasSimple && !hasComplex && len(strings.Split(query, " ")) < 10 {
-		log.Printf("Query classified as SIMPLE (heuristic): %s", query)
-		return "simple", nil
-	}
+В "mode" напиши simple или pro строчными буквами.`
 
-	if hasComplex {
-		log.Printf("Query classified as PRO (heuristic): %s", query)
-		return "pro", nil
-	}
+var modeSelectionExamples = []tools.Example{
+	{User: "Запрос: Кто президент США?", Assistant: `{"mode": "simple"}`},
+	{User: "Запрос: Сравни подходы к регулированию AI", Assistant: `{"mode": "pro"}`},
+	{User: "Запрос: Столица Франции?", Assistant: `{"mode": "simple"}`},
+	{User: "Запрос: Объясни причины экономического кризиса 2008", Assistant: `{"mode": "pro"}`},
+}
 
-	// Use LLM for borderline cases
-	prompt := `Ты классификатор запросов. Определи сложность запроса.
+// ModeSelector decides between simple and pro when the routing model is not
+// confident: the embedding classifier first, the LLM for queries the
+// classifier's examples don't clearly settle
+type ModeSelector struct {
+	llmClient  *tools.LLMClient
+	classifier *ModeClassifier // nil when the examples could not be loaded
+	minMargin  float64
+}
 
-SIMPLE - для простых фактических вопросов:
-- Кто президент США?
-- Когда основан Google?
-- Столица Франции?
-- Погода в Москве?
+func NewModeSelector(llmClient *tools.LLMClient, classifier *ModeClassifier, minMargin float64) *ModeSelector {
+	return &ModeSelector{llmClient: llmClient, classifier: classifier, minMargin: minMargin}
+}
 
-PRO - для сложных аналитических вопросов:
-- Сравни подходы к регулированию AI
-- Объясни причины экономического кризиса 2008
-- Проанализируй влияние социальных сетей на общество
+// SelectMode returns simple or pro and what decided it: "classifier" or
+// "selector" (the LLM)
+func (m *ModeSelector) SelectMode(ctx context.Context, query string) (string, string, error) {
+	if m.classifier != nil {
+		classification, err := m.classifier.Classify(ctx, query)
+		switch {
+		case err != nil:
+			logging.Printf(ctx, "⚠️  Mode classifier failed, asking the LLM: %v", err)
+		case classification.margin >= m.minMargin:
+			logging.Printf(ctx, "Query classified as %s (embeddings, margin %.2f): %s",
+				strings.ToUpper(classification.mode), classification.margin, query)
+			return classification.mode, "classifier", nil
+		default:
+			logging.Printf(ctx, "Mode classifier margin %.2f below %.2f, asking the LLM", classification.margin, m.minMargin)
+		}
+	}
 
-Запрос: ` + query + `
+	// Use LLM for borderline cases
+	prompt := tools.Prompt{
+		System:   modeSelectionPrompt,
+		Examples: modeSelectionExamples,
+		User:     "Запрос: " + query,
+	}
 
-Ответь ТОЛЬКО одним словом: SIMPLE или PRO`
+	var selection struct {
+		Mode string `json:"mode"`
+	}
+	if err := m.llmClient.ForTask(tools.TaskModeSelection).CompleteJSON(ctx, prompt, modeSchema, &selection); err != nil {
+		logging.Printf(ctx, "LLM mode selection failed: %v, defaulting to simple", err)
+		return "simple", "selector", nil
+	}
+	mode := selection.Mode
 
-	response, err := m.llmClient.Complete(ctx, prompt, 0.1, 10)
+	logging.Printf(ctx, "Query classified as %s (LLM): %s", strings.ToUpper(mode), query)
+	return mode, "selector", nil
+}
+
+// SelectDomain returns the vertical agent of the query's domain by its nearest
+// labeled examples, for queries without domain keywords. It returns nil for
+// general queries and when the classifier is unavailable.
+func (m *ModeSelector) SelectDomain(ctx context.Context, query string) *models.VerticalRouting {
+	if m.classifier == nil {
+		return nil
+	}
+	classification, err := m.classifier.Classify(ctx, query)
 	if err != nil {
-		log.Printf("LLM mode selection failed: %v, defaulting to simple", err)
-		return "simple", nil
+		logging.Printf(ctx, "⚠️  Domain classification failed: %v", err)
+		return nil
 	}
-
-	mode := "simple"
-	if strings.Contains(strings.ToUpper(response), "PRO") {
-		mode = "pro"
+	agent, ok := domainAgents[classification.domain]
+	if !ok {
+		return nil
+	}
+	return &models.VerticalRouting{
+		Agent:      agent,
+		Signals:    []string{},
+		Confidence: math.Round(classification.domainMargin*100) / 100,
+		Examples:   classification.domainExamples,
 	}
-
-	log.Printf("Query classified as %s (LLM): %s", strings.ToUpper(mode), query)
-	return mode, nil
 }
 
 func containsAny(text string, indicators []string) bool {
@@ -0,0 +1,291 @@
+package agents
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// modeAgent answers queries of one mode, with or without conversation history
+type modeAgent interface {
+	Process(ctx context.Context, query string) (*models.SearchResponse, error)
+	ProcessWithContext(
+		ctx context.Context,
+		query string,
+		conversationHistory []models.Message,
+	) (*models.SearchResponse, error)
+	// plan runs the planning stages of the agent without searching (explain mode)
+	plan(ctx context.Context, query string, conversationHistory []models.Message) models.QueryPlan
+}
+
+// Dependencies a mode can require
+const (
+	requirementLLM         = "llm"
+	requirementWebSearch   = "web_search"
+	requirementImageSearch = "image_search"
+	requirementToolCalling = "tool_calling"
+)
+
+// registeredMode binds a mode name to its agent and client-facing description
+type registeredMode struct {
+	info     models.ModeInfo
+	agent    modeAgent
+	requires []string
+}
+
+// registerModes builds the mode registry in the order modes are listed to clients
+func (r *RouterAgent) registerModes() {
+	r.modes = []registeredMode{
+		{
+			info: models.ModeInfo{
+				Name:            "simple",
+				Description:     "Fast answer from a single web search",
+				ExpectedLatency: "1-3s",
+				AcceptsContext:  true,
+			},
+			agent:    r.simpleAgent,
+			requires: []string{requirementLLM, requirementWebSearch},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "pro",
+				Description:     "Deep research: query decomposition, multi-hop search, reranking and source verification",
+				ExpectedLatency: "5-20s",
+				AcceptsContext:  true,
+			},
+			agent:    r.proAgent,
+			requires: []string{requirementLLM, requirementWebSearch},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "deep",
+				Description:     "Iterative research: searches, reads and searches again for what is missing, within a time and token budget",
+				ExpectedLatency: "30-90s",
+				AcceptsContext:  true,
+			},
+			agent:    r.deepAgent,
+			requires: []string{requirementLLM, requirementWebSearch},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "pro-social",
+				Description:     "Research over social media and forums: opinions, discussions, reviews",
+				ExpectedLatency: "5-15s",
+				AcceptsContext:  true,
+			},
+			agent:    r.socialAgent,
+			requires: []string{requirementLLM},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "pro-academic",
+				Description:     "Research over scientific papers and academic sources",
+				ExpectedLatency: "5-15s",
+				AcceptsContext:  true,
+			},
+			agent:    r.academicAgent,
+			requires: []string{requirementLLM},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "pro-finance",
+				Description:     "Research over financial news, market data and reports",
+				ExpectedLatency: "5-15s",
+				AcceptsContext:  true,
+			},
+			agent:    r.financeAgent,
+			requires: []string{requirementLLM},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "pro-news",
+				Description:     "Current events from news feeds and news search, preferring the last 48 hours",
+				ExpectedLatency: "5-15s",
+				AcceptsContext:  true,
+			},
+			agent:    r.newsAgent,
+			requires: []string{requirementLLM},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "pro-code",
+				Description:     "Programming answers with code from StackOverflow, GitHub issues, commits and repositories",
+				ExpectedLatency: "5-15s",
+				AcceptsContext:  true,
+			},
+			agent:    r.codeAgent,
+			requires: []string{requirementLLM},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "pro-video",
+				Description:     "Answers from YouTube videos: transcript excerpts as sources, each linking to its timestamp",
+				ExpectedLatency: "8-20s",
+				AcceptsContext:  true,
+			},
+			agent:    r.videoAgent,
+			requires: []string{requirementLLM},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "pro-factcheck",
+				Description:     "Fact-checking: splits a statement into claims and gives a verdict per claim with its sources",
+				ExpectedLatency: "5-15s",
+				AcceptsContext:  true,
+			},
+			agent:    r.factCheckAgent,
+			requires: []string{requirementLLM, requirementWebSearch},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "pro-mixed",
+				Description:     "Queries spanning several domains: the finance, academic and social agents run concurrently and their views are merged into one answer with a section per domain",
+				ExpectedLatency: "10-30s",
+				AcceptsContext:  true,
+			},
+			agent:    r.mixedAgent,
+			requires: []string{requirementLLM},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "pro-tools",
+				Description:     "The model decides which tools to call and in what order: web search, page fetching, calculator, arXiv, finance, weather and exchange rates",
+				ExpectedLatency: "10-60s",
+				AcceptsContext:  true,
+			},
+			agent:    r.toolsAgent,
+			requires: []string{requirementLLM, requirementToolCalling, requirementWebSearch},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "images",
+				Description:     "Images of what the query asks to see (SearXNG images or Brave) with thumbnails and attribution, and a short description",
+				ExpectedLatency: "2-5s",
+				AcceptsContext:  true,
+			},
+			agent:    r.imagesAgent,
+			requires: []string{requirementLLM, requirementImageSearch},
+		},
+		{
+			info: models.ModeInfo{
+				Name:            "instant",
+				Description:     "Weather, exchange rates and unit conversions from data providers, without search or LLM",
+				ExpectedLatency: "<1s",
+			},
+			agent: r.instantAgent,
+		},
+	}
+}
+
+// modeAgent returns the agent registered for mode
+func (r *RouterAgent) modeAgent(mode string) (modeAgent, bool) {
+	for _, m := range r.modes {
+		if m.info.Name == mode {
+			return m.agent, true
+		}
+	}
+	return nil, false
+}
+
+// QueryProfile returns the routing features of a query without conversation
+// history and the probability that auto mode answers it with Pro
+func (r *RouterAgent) QueryProfile(query string) (models.AutoModeFeatures, float64) {
+	features := r.autoModeModel.ExtractFeatures(query, 0, r.proAgent.detectMultiHop(query))
+	return features, r.autoModeModel.ProProbability(features)
+}
+
+// AutoDecision returns how auto mode's routing model classifies a query without
+// conversation history. SelectedMode is empty and DecidedBy is "selector" when
+// the model is not confident and the LLM selector would choose; instant
+// questions are "instant" and image requests "images" regardless of the model.
+func (r *RouterAgent) AutoDecision(query string) models.AutoRouting {
+	features, _ := r.QueryProfile(query)
+	mode, proProbability := r.autoModeModel.Decide(features)
+	routing := models.AutoRouting{
+		Features:       features,
+		ProProbability: proProbability,
+		SelectedMode:   mode,
+		DecidedBy:      "model",
+	}
+	switch {
+	case r.matchesInstant(context.Background(), query):
+		routing.SelectedMode = "instant"
+		routing.DecidedBy = "instant"
+	case r.matchesImages(context.Background(), query):
+		routing.SelectedMode = "images"
+		routing.DecidedBy = "images"
+	case mode == "":
+		routing.DecidedBy = "selector"
+	}
+	return routing
+}
+
+// Modes lists the registered modes, followed by auto, with the configuration
+// status of their dependencies
+func (r *RouterAgent) Modes() []models.ModeInfo {
+	modes := make([]models.ModeInfo, 0, len(r.modes)+1)
+	for _, m := range r.modes {
+		modes = append(modes, r.withRequirements(m.info, m.requires))
+	}
+	return append(modes, r.withRequirements(models.ModeInfo{
+		Name:            "auto",
+		Description:     "Picks one of the modes above per query (instant answers, then routing model and LLM selector; domain queries go to a vertical agent)",
+		ExpectedLatency: "1-20s",
+		AcceptsContext:  true,
+		Default:         true,
+	}, []string{requirementLLM, requirementWebSearch}))
+}
+
+// withRequirements fills in the requirements of the mode and whether it can run
+func (r *RouterAgent) withRequirements(info models.ModeInfo, requires []string) models.ModeInfo {
+	info.Available = true
+	info.Requirements = make([]models.ModeRequirement, 0, len(requires))
+	var missing []string
+	for _, name := range requires {
+		req := r.requirement(name)
+		info.Requirements = append(info.Requirements, req)
+		if !req.Configured && !req.Optional {
+			info.Available = false
+			missing = append(missing, strings.Join(req.Env, " or "))
+		}
+	}
+	if len(missing) > 0 {
+		info.DisabledReason = "missing " + strings.Join(missing, ", ")
+	}
+	return info
+}
+
+func (r *RouterAgent) requirement(name string) models.ModeRequirement {
+	switch name {
+	case requirementLLM:
+		return models.ModeRequirement{
+			Name:       name,
+			Env:        []string{"OPENAI_API_KEY", "QWEN_API_URL", "GIGACHAT_AUTH_KEY"},
+			Configured: r.llmClient.Configured(),
+		}
+	case requirementToolCalling:
+		// GigaChat's function calling differs from the OpenAI tools API
+		return models.ModeRequirement{
+			Name:       name,
+			Env:        []string{"OPENAI_API_KEY", "QWEN_API_URL"},
+			Configured: r.llmClient.SupportsTools(),
+		}
+	case requirementWebSearch:
+		return models.ModeRequirement{
+			Name:       name,
+			Env:        []string{"TAVILY_API_KEY", "SERPAPI_API_KEY", "SEARXNG_URL", "BRAVE_SEARCH_API_KEY"},
+			Configured: r.searchClient.ProvidersConfigured(),
+			Optional:   true,
+		}
+	case requirementImageSearch:
+		// DuckDuckGo, the fallback of web search, finds no images
+		return models.ModeRequirement{
+			Name:       name,
+			Env:        []string{"SEARXNG_URL", "BRAVE_SEARCH_API_KEY"},
+			Configured: r.searchClient.ImageProvidersConfigured(),
+		}
+	default:
+		return models.ModeRequirement{Name: name}
+	}
+}
@@ -0,0 +1,305 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/scrapers"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+// minFreshNews is how many recent (or undated) sources make older ones unnecessary
+const minFreshNews = 5
+
+type NewsAgent struct {
+	newsScraper   *scrapers.NewsScraper
+	searchClient  *tools.SearchClient
+	llmClient     *tools.LLMClient
+	reranker      *tools.BM25Reranker
+	evidence      *EvidencePolicy
+	recencyWindow time.Duration
+}
+
+func NewNewsAgent(
+	searchClient *tools.SearchClient,
+	llmClient *tools.LLMClient,
+	evidence *EvidencePolicy,
+	feeds []string,
+	recencyWindow time.Duration,
+) *NewsAgent {
+	return &NewsAgent{
+		newsScraper:   scrapers.NewNewsScraper(feeds),
+		searchClient:  searchClient,
+		llmClient:     llmClient,
+		reranker:      tools.NewBM25Reranker(),
+		evidence:      evidence,
+		recencyWindow: recencyWindow,
+	}
+}
+
+func (a *NewsAgent) Process(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return a.ProcessWithContext(ctx, query, nil)
+}
+
+func (a *NewsAgent) ProcessWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	logging.Printf(ctx, "Pro News mode processing: %s", query)
+
+	reasoningSteps := appendStep(ctx, nil, "📰 Запущен режим News - поиск свежих новостей")
+
+	searchQuery := query
+	if len(conversationHistory) > 0 {
+		reasoningSteps = appendStep(ctx, reasoningSteps, "Адаптирую запрос с учетом контекста...")
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
+		if err == nil && enhanced != "" {
+			searchQuery = enhanced
+		}
+	}
+
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Ищу новости в Google News, RSS-лентах и новостной выдаче...")
+
+	allResults := make([]models.TavilyResult, 0)
+
+	// Google News
+	googleResults, err := a.newsScraper.SearchGoogleNews(ctx, searchQuery, answerLanguage(ctx, query), 8)
+	if err != nil {
+		logging.Printf(ctx, "Google News search failed: %v", err)
+	} else {
+		allResults = append(allResults, googleResults...)
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Google News: %d статей", len(googleResults)))
+	}
+
+	// RSS feeds
+	if len(a.newsScraper.Feeds()) > 0 {
+		feedResults, err := a.newsScraper.SearchFeeds(ctx, searchQuery, 8)
+		if err != nil {
+			logging.Printf(ctx, "RSS feeds search failed: %v", err)
+		} else {
+			allResults = append(allResults, feedResults...)
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ RSS-ленты: %d материалов", len(feedResults)))
+		}
+	}
+
+	// News vertical of the web search
+	webResults, err := a.searchClient.SearchWithOptions(ctx, searchQuery, 8, true, a.searchOptions(ctx))
+	if err != nil {
+		logging.Printf(ctx, "News web search failed: %v", err)
+	} else {
+		allResults = append(allResults, webResults.Results...)
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Новостной поиск: %d результатов", len(webResults.Results)))
+	}
+
+	if len(allResults) == 0 && !hasDocuments(ctx) {
+		return &models.SearchResponse{
+			Query:     query,
+			Mode:      "pro-news",
+			Answer:    "Не удалось найти свежие новости по вашему запросу.",
+			Sources:   []models.Source{},
+			Reasoning: strings.Join(reasoningSteps, "\n"),
+		}, nil
+	}
+
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("Собрано %d новостных источников", len(allResults)))
+
+	// Rerank, then move recent news to the top
+	rerankStart := time.Now()
+	allResults = a.reranker.Rerank(searchQuery, dedupeByURL(allResults))
+	tools.TrackTime(ctx, tools.TimingRerank, rerankStart)
+	if dateRange := tools.DateRangeFromContext(ctx); dateRange != nil {
+		// The sources were already limited to the period the query asked about
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("📅 Период запроса: %s", dateRange.Label))
+	} else {
+		var fresh int
+		allResults, fresh = a.preferRecent(allResults, time.Now())
+		reasoningSteps = appendStep(ctx, reasoningSteps,
+			fmt.Sprintf("За последние %d ч опубликовано %d источников", int(a.recencyWindow.Hours()), fresh))
+	}
+
+	if len(allResults) > 10 {
+		allResults = allResults[:10]
+	}
+	allResults = withDocuments(ctx, searchQuery, allResults)
+
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Анализирую новости...")
+
+	evidence := a.evidence.check(ctx, allResults)
+	if step := evidence.reasoning("ru"); step != "" {
+		reasoningSteps = appendStep(ctx, reasoningSteps, step)
+	}
+
+	// Build LLM prompt
+	var system strings.Builder
+	system.WriteString(fmt.Sprintf(`Ты новостной аналитик. Сегодня %s (UTC).
+
+Твоя задача:
+1. Рассказать, что произошло, начиная с самых свежих событий
+2. Указывать даты событий и публикаций
+3. Отделять подтвержденные факты от предварительных сообщений
+4. Отметить, если источники расходятся или новости устарели
+
+`, time.Now().UTC().Format("2006-01-02 15:04")))
+	system.WriteString(citationInstruction("ru"))
+	system.WriteString(evidence.instruction("ru"))
+	system.WriteString(formatInstruction(ctx, "ru"))
+	system.WriteString(languageInstruction(ctx, "ru"))
+	system.WriteString(personaInstruction(ctx, "ru"))
+
+	var promptBuilder strings.Builder
+	if len(conversationHistory) > 0 {
+		promptBuilder.WriteString("Контекст диалога:\n")
+		for _, msg := range recentTurns(conversationHistory, 4) {
+			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+		}
+		promptBuilder.WriteString("\n")
+	}
+
+	promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\n", query))
+	promptBuilder.WriteString("Новостные источники:\n\n")
+
+	for i, result := range allResults {
+		if i >= 8 {
+			break
+		}
+		published := "дата неизвестна"
+		if result.PublishedAt > 0 {
+			published = time.Unix(result.PublishedAt, 0).UTC().Format("2006-01-02 15:04")
+		}
+		content := utils.TruncateRunes(result.Content, 600)
+		promptBuilder.WriteString(fmt.Sprintf("Источник %d (%s): %s\n%s\n\n", i+1, published, result.Title, content))
+	}
+
+	promptBuilder.WriteString("Сводка новостей:")
+
+	synthesisStart := time.Now()
+	answer, err := a.llmClient.ForTask(tools.TaskSynthesis).CompletePrompt(ctx, tools.Prompt{
+		System: strings.TrimSpace(system.String()),
+		User:   promptBuilder.String(),
+	}, 0.5, 1200)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
+	if err != nil {
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+
+	// Format sources
+	sources := make([]models.Source, 0)
+	for i, result := range allResults {
+		if i >= 8 {
+			break
+		}
+		snippet := utils.TruncateRunesWithEllipsis(result.Content, 200)
+		sources = append(sources, models.Source{
+			Title:       result.Title,
+			URL:         result.URL,
+			Snippet:     snippet,
+			Credibility: result.Score,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
+		})
+	}
+
+	return &models.SearchResponse{
+		Query:       query,
+		Mode:        "pro-news",
+		Answer:      answer,
+		Sources:     sources,
+		Reasoning:   strings.Join(reasoningSteps, "\n"),
+		ContextUsed: len(conversationHistory) > 0,
+	}, nil
+}
+
+// searchOptions restricts the web search to the news category and to the
+// recency window, unless the query asked for another time range or period
+func (a *NewsAgent) searchOptions(ctx context.Context) tools.SearchOptions {
+	opts := searchOptions(constraintsFromContext(ctx))
+	opts.Categories = []string{"news"}
+	if opts.TimeRange == "" && tools.DateRangeFromContext(ctx) == nil {
+		opts.TimeRange = "week"
+		if a.recencyWindow <= 24*time.Hour {
+			opts.TimeRange = "day"
+		}
+	}
+	return opts
+}
+
+// preferRecent orders results published within the recency window first,
+// then undated ones, then older ones, keeping the relevance order within each
+// group. Older results are dropped when there are enough recent ones. Returns
+// the number of results inside the window.
+func (a *NewsAgent) preferRecent(results []models.TavilyResult, now time.Time) ([]models.TavilyResult, int) {
+	cutoff := now.Add(-a.recencyWindow).Unix()
+	group := func(r models.TavilyResult) int {
+		switch {
+		case r.PublishedAt >= cutoff:
+			return 0
+		case r.PublishedAt == 0:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return group(results[i]) < group(results[j])
+	})
+
+	var fresh, current int
+	for _, r := range results {
+		switch group(r) {
+		case 0:
+			fresh++
+			current++
+		case 1:
+			current++
+		}
+	}
+	if current >= minFreshNews {
+		results = results[:current]
+	}
+	return results, fresh
+}
+
+// dedupeByURL drops results already found by another source
+func dedupeByURL(results []models.TavilyResult) []models.TavilyResult {
+	seen := make(map[string]bool, len(results))
+	unique := results[:0]
+	for _, r := range results {
+		key := strings.TrimSuffix(r.URL, "/")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, r)
+	}
+	return unique
+}
+
+func (a *NewsAgent) enhanceQueryWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (string, error) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
+
+	var contextPrompt strings.Builder
+	contextPrompt.WriteString("Предыдущая беседа:\n")
+	for _, msg := range recentTurns(conversationHistory, 4) {
+		role := turnRole(msg)
+		contextPrompt.WriteString(fmt.Sprintf("%s: %s\n", role, msg.Content))
+	}
+
+	enhancePrompt := fmt.Sprintf(`%s
+
+Текущий вопрос: %s
+
+Перефразируй текущий вопрос в короткий запрос для поиска новостей (ключевые имена, события, места). Улучшенный запрос:`, contextPrompt.String(), query)
+
+	return a.llmClient.ForTask(tools.TaskQueryEnhance).Complete(ctx, enhancePrompt, 0.3, 150)
+}
@@ -0,0 +1,38 @@
+package agents
+
+import (
+	"context"
+	"strings"
+)
+
+type personaKey struct{}
+
+// WithPersona sets the instructions of a chat session (tone, depth, domain
+// expertise) for the agents handling ctx
+func WithPersona(ctx context.Context, systemPrompt string) context.Context {
+	systemPrompt = strings.TrimSpace(systemPrompt)
+	if systemPrompt == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, personaKey{}, systemPrompt)
+}
+
+// personaFromContext returns the session instructions, if any
+func personaFromContext(ctx context.Context) string {
+	systemPrompt, _ := ctx.Value(personaKey{}).(string)
+	return systemPrompt
+}
+
+// personaInstruction is appended to the synthesis prompt (written in lang)
+// when the session has instructions. They shape the answer, not the rules
+// about sources and citations given before them.
+func personaInstruction(ctx context.Context, lang string) string {
+	systemPrompt := personaFromContext(ctx)
+	if systemPrompt == "" {
+		return ""
+	}
+	if lang == "ru" {
+		return "\nИнструкции пользователя для этой беседы (тон, подробность, роль); следуй им, если они не противоречат правилам об источниках и ссылках выше:\n" + systemPrompt + "\n\n"
+	}
+	return "\nThe user's instructions for this conversation (tone, depth, role); follow them unless they contradict the rules about sources and citations above:\n" + systemPrompt + "\n\n"
+}
@@ -0,0 +1,129 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+const (
+	// pivotTranslateTimeout bounds the query translation; the search goes on
+	// in the query language alone when it is exceeded
+	pivotTranslateTimeout = 5 * time.Second
+	// pivotResults is how many results the translated query adds
+	pivotResults = 8
+)
+
+// queryPivot is the query translated into the other language and what it found
+type queryPivot struct {
+	lang    string
+	query   string
+	results []models.TavilyResult
+}
+
+// pivotLanguage is the language a query is also searched in
+func pivotLanguage(query string) string {
+	if detectLanguage(query) == "ru" {
+		return "en"
+	}
+	return "ru"
+}
+
+// pivotSearch translates query into the other language and searches it. The
+// results are marked as pivot results; nil when the translator is disabled or
+// the translation failed.
+func pivotSearch(
+	ctx context.Context,
+	searchClient *tools.SearchClient,
+	translator *tools.SnippetTranslator,
+	query string,
+) *queryPivot {
+	if translator == nil {
+		return nil
+	}
+
+	lang := pivotLanguage(query)
+	translateCtx, cancel := context.WithTimeout(ctx, pivotTranslateTimeout)
+	translated, err := translator.Translate(translateCtx, []string{query}, lang)
+	cancel()
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Query translation skipped: %v", err)
+		return nil
+	}
+	pivotQuery := strings.Trim(strings.TrimSpace(translated[0]), `"'`)
+	if pivotQuery == "" || strings.EqualFold(pivotQuery, query) {
+		return nil
+	}
+
+	pivot := &queryPivot{lang: lang, query: pivotQuery}
+	searchResults, err := searchClient.SearchWithOptions(ctx, pivotQuery, pivotResults, true, searchOptions(constraintsFromContext(ctx)))
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Search in %s failed: %v", lang, err)
+		return pivot
+	}
+	for _, result := range searchResults.Results {
+		result.Pivot = true
+		pivot.results = append(pivot.results, result)
+	}
+	logging.Printf(ctx, "🌐 Query searched in %s: %q (%d results)", lang, pivotQuery, len(pivot.results))
+	return pivot
+}
+
+// mergePivotResults appends the pivot results whose URL is not among results
+func mergePivotResults(results []models.TavilyResult, pivot *queryPivot) []models.TavilyResult {
+	if pivot == nil {
+		return results
+	}
+	seen := make(map[string]bool, len(results))
+	for _, result := range results {
+		seen[result.URL] = true
+	}
+	for _, result := range pivot.results {
+		if !seen[result.URL] {
+			seen[result.URL] = true
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// pivotLanguageNames names ru and en in the prompt and reasoning languages
+var pivotLanguageNames = map[string]map[string]string{
+	"ru": {"ru": "русском", "en": "английском"},
+	"en": {"ru": "Russian", "en": "English"},
+}
+
+// pivotInstruction is appended to the synthesis prompt (written in promptLang)
+// when some of the sources were found in the other language
+func pivotInstruction(ctx context.Context, query string, results []models.TavilyResult, promptLang string) string {
+	used := false
+	for _, result := range results {
+		if result.Pivot {
+			used = true
+			break
+		}
+	}
+	if !used {
+		return ""
+	}
+
+	answerLang := pivotLanguageNames[promptLang][answerLanguage(ctx, query)]
+	if promptLang == "ru" {
+		return fmt.Sprintf("\nЧасть источников на другом языке — используй их наравне с остальными, но отвечай на %s языке.\n", answerLang)
+	}
+	return fmt.Sprintf("\nSome sources are in another language - use them like the others, but answer in %s.\n", answerLang)
+}
+
+// sourceLanguage tags a source with the language of its snippet, or of its
+// title when the snippet is empty
+func sourceLanguage(source models.Source) string {
+	if strings.TrimSpace(source.Snippet) != "" {
+		return detectLanguage(source.Snippet)
+	}
+	return detectLanguage(source.Title)
+}
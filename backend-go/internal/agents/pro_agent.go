@@ -2,6 +2,7 @@ package agents
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
@@ -9,29 +10,91 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 )
 
+const (
+	// minSubQueries and maxSubQueries bound the sub-questions of a multi-hop
+	// query, whatever the LLM estimates
+	minSubQueries = 2
+	maxSubQueries = 6
+	// defaultSubQueries is used when the LLM gives no hop estimate
+	defaultSubQueries = 3
+	// proAnswerTokens bounds the answer of pro mode
+	proAnswerTokens = 1200
+)
+
+// subQuestionSchema is the reply of the sub-question prompt: the estimated
+// hops and the sub-questions
+var subQuestionSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"hops": map[string]interface{}{"type": "integer", "minimum": 1},
+		"sub_questions": map[string]interface{}{
+			"type":     "array",
+			"minItems": 1,
+			"items":    map[string]interface{}{"type": "string", "minLength": 5},
+		},
+	},
+	"required": []string{"hops", "sub_questions"},
+}
+
 type ProAgent struct {
 	searchClient      *tools.SearchClient
 	llmClient         *tools.LLMClient
 	reranker          *tools.BM25Reranker
 	credibilityScorer *tools.CredibilityScorer
+	evidence          *EvidencePolicy
+	translator        *tools.SnippetTranslator // nil when cross-language search is disabled
+	summarize         bool                     // summarize each source before the synthesis
+	entityGraphs      *EntityGraphs            // nil when entity extraction is disabled
+	maxSubQueries     int                      // cap on the LLM's hop estimate
 	timeout           time.Duration
 }
 
-func NewProAgent(searchClient *tools.SearchClient, llmClient *tools.LLMClient) *ProAgent {
+func NewProAgent(searchClient *tools.SearchClient, llmClient *tools.LLMClient, evidence *EvidencePolicy) *ProAgent {
 	return &ProAgent{
 		searchClient:      searchClient,
 		llmClient:         llmClient,
 		reranker:          tools.NewBM25Reranker(),
 		credibilityScorer: tools.NewCredibilityScorer(),
+		evidence:          evidence,
+		maxSubQueries:     maxSubQueries,
 		timeout:           20 * time.Second, // Global timeout
 	}
 }
 
+// WithMaxSubQueries caps the sub-questions of a multi-hop query, each of
+// which costs a search, at n (2-6)
+func (a *ProAgent) WithMaxSubQueries(n int) *ProAgent {
+	a.maxSubQueries = min(max(n, minSubQueries), maxSubQueries)
+	return a
+}
+
+// WithQueryTranslator makes the agent search queries in the other language
+// (ru <-> en) as well, translating them with translator
+func (a *ProAgent) WithQueryTranslator(translator *tools.SnippetTranslator) *ProAgent {
+	a.translator = translator
+	return a
+}
+
+// WithSourceSummaries makes the agent summarize every top source with regard
+// to the query before the synthesis instead of truncating its text
+func (a *ProAgent) WithSourceSummaries() *ProAgent {
+	a.summarize = true
+	return a
+}
+
+// WithEntityGraphs makes the agent extract the entities of its top sources
+// into the session graphs, and resolve follow-up questions against them
+func (a *ProAgent) WithEntityGraphs(graphs *EntityGraphs) *ProAgent {
+	a.entityGraphs = graphs
+	return a
+}
+
 func (a *ProAgent) Process(ctx context.Context, query string) (*models.SearchResponse, error) {
 	return a.ProcessWithContext(ctx, query, nil)
 }
@@ -42,11 +105,23 @@ func (a *ProAgent) ProcessWithContext(
 	conversationHistory []models.Message,
 ) (*models.SearchResponse, error) {
 	// Apply global timeout
-	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	budgetCtx, cancel := context.WithTimeout(ctx, a.timeout)
 	defer cancel()
 
-	queryLang := detectLanguage(query)
-	log.Printf("Pro mode processing: %s (lang: %s, with context: %v)",
+	result, err := a.process(budgetCtx, query, conversationHistory)
+	if err != nil {
+		return nil, a.budgetError(ctx, budgetCtx, err)
+	}
+	return result, nil
+}
+
+func (a *ProAgent) process(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	queryLang := answerLanguage(ctx, query)
+	logging.Printf(ctx, "Pro mode processing: %s (lang: %s, with context: %v)",
 		query, queryLang, len(conversationHistory) > 0)
 
 	reasoningSteps := []string{}
@@ -55,85 +130,39 @@ func (a *ProAgent) ProcessWithContext(
 	// Step 1: Enhance query with context
 	if len(conversationHistory) > 0 {
 		if queryLang == "ru" {
-			reasoningSteps = append(reasoningSteps, "🔍 Анализирую контекст предыдущего диалога...")
-		} else {
-			reasoningSteps = append(reasoningSteps, "🔍 Analyzing previous conversation context...")
-		}
-
-		var contextPrompt strings.Builder
-		if queryLang == "ru" {
-			contextPrompt.WriteString("Предыдущая беседа:\n")
-		} else {
-			contextPrompt.WriteString("Previous conversation:\n")
-		}
-
-		start := len(conversationHistory) - 6
-		if start < 0 {
-			start = 0
-		}
-		for _, msg := range conversationHistory[start:] {
-			role := msg.Role
-			if queryLang == "ru" {
-				if msg.Role == "user" {
-					role = "Пользователь"
-				} else {
-					role = "Ассистент"
-				}
-			}
-			contextPrompt.WriteString(fmt.Sprintf("\n%s: %s\n", role, msg.Content))
-		}
-
-		var enhancePrompt string
-		if queryLang == "ru" {
-			enhancePrompt = fmt.Sprintf(`%s
-
-Текущий вопрос: %s
-
-Перефразируй текущий вопрос так, чтобы он был самодостаточным и включал важную информацию из контекста. Улучшенный поисковый запрос:`, contextPrompt.String(), query)
+			reasoningSteps = appendStep(ctx, reasoningSteps, "🔍 Анализирую контекст предыдущего диалога...")
 		} else {
-			enhancePrompt = fmt.Sprintf(`%s
-
-Current question: %s
-
-Rephrase the current question to be self-contained and include important information from context. Enhanced search query:`, contextPrompt.String(), query)
+			reasoningSteps = appendStep(ctx, reasoningSteps, "🔍 Analyzing previous conversation context...")
 		}
 
-		enhanced, err := a.llmClient.Complete(ctx, enhancePrompt, 0.3, 200)
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory, queryLang)
 		if err != nil {
-			log.Printf("⚠️  LLM failed to enhance query, using original: %v", err)
+			logging.Printf(ctx, "⚠️  LLM failed to enhance query, using original: %v", err)
 			if queryLang == "ru" {
-				reasoningSteps = append(reasoningSteps, "⚠️ Использую оригинальный запрос (LLM недоступен)")
+				reasoningSteps = appendStep(ctx, reasoningSteps, "⚠️ Использую оригинальный запрос (LLM недоступен)")
 			} else {
-				reasoningSteps = append(reasoningSteps, "⚠️ Using original query (LLM unavailable)")
+				reasoningSteps = appendStep(ctx, reasoningSteps, "⚠️ Using original query (LLM unavailable)")
 			}
 		} else if enhanced != "" {
-			searchQuery = strings.TrimSpace(enhanced)
-			searchQuery = strings.Trim(searchQuery, `"'`)
-			searchQuery = strings.TrimSpace(searchQuery)
-
-			if searchQuery == "" {
-				searchQuery = query
-				log.Printf("⚠️  Enhanced query was empty after cleanup")
-			}
-
+			searchQuery = enhanced
 			if queryLang == "ru" {
-				reasoningSteps = append(reasoningSteps, fmt.Sprintf("✨ Улучшенный запрос: \"%s\"", searchQuery))
+				reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✨ Улучшенный запрос: \"%s\"", searchQuery))
 			} else {
-				reasoningSteps = append(reasoningSteps, fmt.Sprintf("✨ Enhanced query: \"%s\"", searchQuery))
+				reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✨ Enhanced query: \"%s\"", searchQuery))
 			}
 		} else {
-			log.Printf("⚠️  LLM returned empty enhanced query")
+			logging.Printf(ctx, "⚠️  LLM returned empty enhanced query")
 			if queryLang == "ru" {
-				reasoningSteps = append(reasoningSteps, "⚠️ Использую оригинальный запрос")
+				reasoningSteps = appendStep(ctx, reasoningSteps, "⚠️ Использую оригинальный запрос")
 			} else {
-				reasoningSteps = append(reasoningSteps, "⚠️ Using original query")
+				reasoningSteps = appendStep(ctx, reasoningSteps, "⚠️ Using original query")
 			}
 		}
 	} else {
 		if queryLang == "ru" {
-			reasoningSteps = append(reasoningSteps, "📝 Обрабатываю первый запрос без контекста")
+			reasoningSteps = appendStep(ctx, reasoningSteps, "📝 Обрабатываю первый запрос без контекста")
 		} else {
-			reasoningSteps = append(reasoningSteps, "📝 Processing first query without context")
+			reasoningSteps = appendStep(ctx, reasoningSteps, "📝 Processing first query without context")
 		}
 	}
 
@@ -141,19 +170,26 @@ Rephrase the current question to be self-contained and include important informa
 	needsMultiHop := a.detectMultiHop(query)
 
 	var allResults []models.TavilyResult
+	var pivot *queryPivot
 
 	if needsMultiHop {
 		if queryLang == "ru" {
-			reasoningSteps = append(reasoningSteps, "🔬 Обнаружен сложный вопрос - применяю multi-hop reasoning")
+			reasoningSteps = appendStep(ctx, reasoningSteps, "🔬 Обнаружен сложный вопрос - применяю multi-hop reasoning")
 		} else {
-			reasoningSteps = append(reasoningSteps, "🔬 Complex question detected - applying multi-hop reasoning")
+			reasoningSteps = appendStep(ctx, reasoningSteps, "🔬 Complex question detected - applying multi-hop reasoning")
 		}
 
-		subQueries := a.generateSubQueries(ctx, searchQuery, queryLang)
+		subQueries, hops := a.generateSubQueries(ctx, searchQuery, queryLang)
 		if queryLang == "ru" {
-			reasoningSteps = append(reasoningSteps, fmt.Sprintf("📋 Разбил на %d подвопроса", len(subQueries)))
+			if hops > 0 {
+				reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("🧮 Число шагов по оценке LLM: %d", hops))
+			}
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("📋 Разбил на %d %s", len(subQueries), subQuestionsRu(len(subQueries))))
 		} else {
-			reasoningSteps = append(reasoningSteps, fmt.Sprintf("📋 Split into %d sub-questions", len(subQueries)))
+			if hops > 0 {
+				reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("🧮 Estimate: %d facts to find", hops))
+			}
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("📋 Split into %d sub-questions", len(subQueries)))
 		}
 
 		// Try parallel search
@@ -161,59 +197,83 @@ Rephrase the current question to be self-contained and include important informa
 
 		// FALLBACK: If insufficient results from multi-hop
 		if len(allResults) < 3 {
-			log.Printf("🔄 Multi-hop insufficient results (%d), falling back to direct search", len(allResults))
+			logging.Printf(ctx, "🔄 Multi-hop insufficient results (%d), falling back to direct search", len(allResults))
 
 			if queryLang == "ru" {
-				reasoningSteps = append(reasoningSteps,
+				reasoningSteps = appendStep(ctx, reasoningSteps,
 					fmt.Sprintf("🔄 Недостаточно результатов (%d), выполняю прямой поиск", len(allResults)))
 			} else {
-				reasoningSteps = append(reasoningSteps,
+				reasoningSteps = appendStep(ctx, reasoningSteps,
 					fmt.Sprintf("🔄 Insufficient results (%d), performing direct search", len(allResults)))
 			}
 
-			directResults, err := a.searchClient.Search(ctx, searchQuery, 15, true)
+			directResults, err := a.searchClient.SearchWithOptions(ctx, searchQuery, 15, true, searchOptions(constraintsFromContext(ctx)))
 			if err != nil {
-				log.Printf("❌ Fallback search also failed: %v", err)
+				logging.Printf(ctx, "❌ Fallback search also failed: %v", err)
 				// Return what we have from multi-hop
 			} else {
 				// Merge results, prioritizing multi-hop
 				allResults = append(allResults, directResults.Results...)
-				log.Printf("✅ Fallback search added %d results", len(directResults.Results))
+				logging.Printf(ctx, "✅ Fallback search added %d results", len(directResults.Results))
 			}
 		}
 
 		if queryLang == "ru" {
-			reasoningSteps = append(reasoningSteps,
+			reasoningSteps = appendStep(ctx, reasoningSteps,
 				fmt.Sprintf("📚 Собрано %d источников", len(allResults)))
 		} else {
-			reasoningSteps = append(reasoningSteps,
+			reasoningSteps = appendStep(ctx, reasoningSteps,
 				fmt.Sprintf("📚 Collected %d sources", len(allResults)))
 		}
 	} else {
 		// Regular search
-		log.Printf("🔎 Executing search with query: %s", searchQuery)
+		logging.Printf(ctx, "🔎 Executing search with query: %s", searchQuery)
 		if queryLang == "ru" {
-			reasoningSteps = append(reasoningSteps, fmt.Sprintf("🔎 Ищу информацию по запросу: \"%s\"", searchQuery))
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("🔎 Ищу информацию по запросу: \"%s\"", searchQuery))
 		} else {
-			reasoningSteps = append(reasoningSteps, fmt.Sprintf("🔎 Searching for: \"%s\"", searchQuery))
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("🔎 Searching for: \"%s\"", searchQuery))
 		}
 
-		searchResults, err := a.searchClient.Search(ctx, searchQuery, 15, true)
-		if err != nil {
-			log.Printf("❌ Search failed: %v", err)
+		// The query is searched in the other language at the same time
+		pivotDone := make(chan *queryPivot, 1)
+		go func() {
+			pivotDone <- pivotSearch(ctx, a.searchClient, a.translator, searchQuery)
+		}()
+
+		searchResults, err := a.searchClient.SearchWithOptions(ctx, searchQuery, 15, true, searchOptions(constraintsFromContext(ctx)))
+		pivot = <-pivotDone
+		if err != nil && !errors.Is(err, tools.ErrNoResults) && (pivot == nil || len(pivot.results) == 0) {
+			logging.Printf(ctx, "❌ Search failed: %v", err)
 			return nil, fmt.Errorf("search failed: %w", err)
 		}
 
-		allResults = searchResults.Results
-		log.Printf("✅ Search returned %d results", len(allResults))
+		if err == nil {
+			allResults = searchResults.Results
+		} else if !errors.Is(err, tools.ErrNoResults) {
+			logging.Printf(ctx, "⚠️  Search failed, using the results in %s: %v", pivot.lang, err)
+		}
+		logging.Printf(ctx, "✅ Search returned %d results", len(allResults))
 		if queryLang == "ru" {
-			reasoningSteps = append(reasoningSteps, fmt.Sprintf("✅ Найдено %d источников", len(allResults)))
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✅ Найдено %d источников", len(allResults)))
 		} else {
-			reasoningSteps = append(reasoningSteps, fmt.Sprintf("✅ Found %d sources", len(allResults)))
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✅ Found %d sources", len(allResults)))
+		}
+
+		if pivot != nil {
+			before := len(allResults)
+			allResults = mergePivotResults(allResults, pivot)
+			language := pivotLanguageNames[queryLang][pivot.lang]
+			if queryLang == "ru" {
+				reasoningSteps = appendStep(ctx, reasoningSteps,
+					fmt.Sprintf("🌐 Поиск на %s языке: \"%s\" — ещё %d источников", language, pivot.query, len(allResults)-before))
+			} else {
+				reasoningSteps = appendStep(ctx, reasoningSteps,
+					fmt.Sprintf("🌐 Searched in %s: \"%s\" - %d more sources", language, pivot.query, len(allResults)-before))
+			}
 		}
 	}
 
-	if len(allResults) == 0 {
+	if len(allResults) == 0 && !hasDocuments(ctx) {
 		var answer string
 		if queryLang == "ru" {
 			answer = "Не удалось найти релевантную информацию по вашему запросу."
@@ -232,75 +292,96 @@ Rephrase the current question to be self-contained and include important informa
 
 	// Step 3: Semantic Reranking с BM25
 	if queryLang == "ru" {
-		reasoningSteps = append(reasoningSteps, "🎯 Применяю семантическую переоценку результатов (BM25)")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "🎯 Применяю семантическую переоценку результатов (BM25)")
 	} else {
-		reasoningSteps = append(reasoningSteps, "🎯 Applying semantic re-ranking (BM25)")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "🎯 Applying semantic re-ranking (BM25)")
 	}
-	allResults = a.reranker.Rerank(searchQuery, allResults)
+	rerankStart := time.Now()
+	rerankQuery := searchQuery
+	if pivot != nil {
+		// Both languages' terms, so pivot results are not ranked last
+		rerankQuery += " " + pivot.query
+	}
+	allResults = a.reranker.Rerank(rerankQuery, allResults)
+	tools.TrackTime(ctx, tools.TimingRerank, rerankStart)
 
 	// Step 4: Credibility Scoring
 	if queryLang == "ru" {
-		reasoningSteps = append(reasoningSteps, "⭐ Оцениваю достоверность источников")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "⭐ Оцениваю достоверность источников")
 	} else {
-		reasoningSteps = append(reasoningSteps, "⭐ Evaluating source credibility")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "⭐ Evaluating source credibility")
 	}
+	credibilityStart := time.Now()
 	allResults = a.credibilityScorer.RankSources(allResults)
+	tools.TrackTime(ctx, tools.TimingCredibility, credibilityStart)
 
 	// Step 5: Ensure Domain Diversity
 	if queryLang == "ru" {
-		reasoningSteps = append(reasoningSteps, "🌐 Обеспечиваю разнообразие источников")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "🌐 Обеспечиваю разнообразие источников")
 	} else {
-		reasoningSteps = append(reasoningSteps, "🌐 Ensuring source diversity")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "🌐 Ensuring source diversity")
 	}
 	topResults := a.selectDiverseSources(allResults, 10)
+	topResults = withDocuments(ctx, searchQuery, topResults)
 
 	// Step 6: Cross-verification
 	if queryLang == "ru" {
-		reasoningSteps = append(reasoningSteps, "🔍 Проверяю консистентность информации между источниками")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "🔍 Проверяю консистентность информации между источниками")
 	} else {
-		reasoningSteps = append(reasoningSteps, "🔍 Cross-verifying information across sources")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "🔍 Cross-verifying information across sources")
 	}
 	verification := a.crossVerify(topResults, queryLang)
 	if verification != "" {
-		reasoningSteps = append(reasoningSteps, verification)
+		reasoningSteps = appendStep(ctx, reasoningSteps, verification)
+	}
+
+	// Evidence threshold: weak evidence must not produce a confident answer
+	evidence := a.evidence.check(ctx, topResults)
+	if step := evidence.reasoning(queryLang); step != "" {
+		reasoningSteps = appendStep(ctx, reasoningSteps, step)
 	}
 
 	// Step 7: Format sources for LLM (top 8 for context window)
-	var sourcesContext strings.Builder
 	displaySources := topResults
 	if len(displaySources) > 8 {
 		displaySources = displaySources[:8]
 	}
 
-	for i, result := range displaySources {
-		content := result.Content
-		if result.RawContent != "" {
-			content = result.RawContent
-		}
-		
-		// Sanitize and truncate safely
-		content = utils.SanitizeUTF8(content)
-		if len(content) > 800 {
-			content = utils.TruncateUTF8WithEllipsis(content, 800)
-		}
-
+	if a.summarize {
 		if queryLang == "ru" {
-			sourcesContext.WriteString(fmt.Sprintf(
-				"Источник %d [Достоверность: %.2f] (%s):\n%s\n\n",
-				i+1, result.Credibility, result.Title, content,
-			))
+			reasoningSteps = appendStep(ctx, reasoningSteps,
+				fmt.Sprintf("🧾 Выделяю главное из каждого источника (%d параллельных запросов)", len(displaySources)))
 		} else {
-			sourcesContext.WriteString(fmt.Sprintf(
-				"Source %d [Credibility: %.2f] (%s):\n%s\n\n",
-				i+1, result.Credibility, result.Title, content,
-			))
+			reasoningSteps = appendStep(ctx, reasoningSteps,
+				fmt.Sprintf("🧾 Summarizing each source (%d parallel requests)", len(displaySources)))
 		}
 	}
+	contents, summarized := a.sourceContexts(ctx, query, displaySources, queryLang)
+	if summarized > 0 {
+		logging.Printf(ctx, "🧾 Summarized %d of %d sources", summarized, len(displaySources))
+	}
 
-	// Step 8: Build LLM prompt
-	var promptBuilder strings.Builder
-	if queryLang == "ru" {
-		promptBuilder.WriteString(`Ты исследовательский ассистент в режиме Pro с глубоким анализом.
+	// Step 8: Build LLM prompt, with the sources cut to fit the context window
+	llm := a.llmClient.ForTask(tools.TaskSynthesis)
+	prompt := fitPrompt(llm, contents, proAnswerTokens, func(contents []string) tools.Prompt {
+		var promptSources strings.Builder
+		for i, result := range displaySources {
+			if queryLang == "ru" {
+				promptSources.WriteString(fmt.Sprintf(
+					"Источник %d [Достоверность: %.2f] (%s):\n%s\n\n",
+					i+1, result.Credibility, result.Title, contents[i],
+				))
+			} else {
+				promptSources.WriteString(fmt.Sprintf(
+					"Source %d [Credibility: %.2f] (%s):\n%s\n\n",
+					i+1, result.Credibility, result.Title, contents[i],
+				))
+			}
+		}
+
+		var system strings.Builder
+		if queryLang == "ru" {
+			system.WriteString(`Ты исследовательский ассистент в режиме Pro с глубоким анализом.
 
 Твоя задача:
 1. Дать подробный, хорошо обоснованный ответ
@@ -309,8 +390,8 @@ Rephrase the current question to be self-contained and include important informa
 4. Делать выводы на основе перекрестной проверки
 
 `)
-	} else {
-		promptBuilder.WriteString(`You are a Pro research assistant with deep analysis capabilities.
+		} else {
+			system.WriteString(`You are a Pro research assistant with deep analysis capabilities.
 
 Your task:
 1. Provide a detailed, well-reasoned answer
@@ -319,48 +400,114 @@ Your task:
 4. Draw conclusions based on cross-verification
 
 `)
-	}
+		}
+		system.WriteString(citationInstruction(queryLang))
+		system.WriteString(calcInstruction(queryLang))
+		system.WriteString(evidence.instruction(queryLang))
+		system.WriteString(formatInstruction(ctx, queryLang))
+		system.WriteString(languageInstruction(ctx, queryLang))
+		system.WriteString(personaInstruction(ctx, queryLang))
+		system.WriteString(pivotInstruction(ctx, query, displaySources, queryLang))
+
+		var promptBuilder strings.Builder
+		if len(conversationHistory) > 0 {
+			if queryLang == "ru" {
+				promptBuilder.WriteString("Контекст диалога:\n")
+			} else {
+				promptBuilder.WriteString("Conversation context:\n")
+			}
+			for _, msg := range recentTurns(conversationHistory, 4) {
+				promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+			}
+			promptBuilder.WriteString("\n")
+		}
 
-	if len(conversationHistory) > 0 {
 		if queryLang == "ru" {
-			promptBuilder.WriteString("\nКонтекст диалога:\n")
+			promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\n", query))
+			promptBuilder.WriteString("Найденная информация (отсортирована по релевантности и достоверности):\n")
+			promptBuilder.WriteString(promptSources.String())
+			promptBuilder.WriteString("Подробный ответ с анализом:")
 		} else {
-			promptBuilder.WriteString("\nConversation context:\n")
-		}
-		start := len(conversationHistory) - 4
-		if start < 0 {
-			start = 0
+			promptBuilder.WriteString(fmt.Sprintf("Question: %s\n\n", query))
+			promptBuilder.WriteString("Found information (sorted by relevance and credibility):\n")
+			promptBuilder.WriteString(promptSources.String())
+			promptBuilder.WriteString("Detailed answer with analysis:")
 		}
-		for _, msg := range conversationHistory[start:] {
-			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
-		}
-		promptBuilder.WriteString("\n")
-	}
+		return tools.Prompt{System: strings.TrimSpace(system.String()), User: promptBuilder.String()}
+	})
 
 	if queryLang == "ru" {
-		promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\n", query))
-		promptBuilder.WriteString("Найденная информация (отсортирована по релевантности и достоверности):\n")
-		promptBuilder.WriteString(sourcesContext.String())
-		promptBuilder.WriteString("\nПодробный ответ с анализом:")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "💡 Формирую финальный ответ с учётом всех данных...")
 	} else {
-		promptBuilder.WriteString(fmt.Sprintf("Question: %s\n\n", query))
-		promptBuilder.WriteString("Found information (sorted by relevance and credibility):\n")
-		promptBuilder.WriteString(sourcesContext.String())
-		promptBuilder.WriteString("\nDetailed answer with analysis:")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "💡 Generating final answer based on all data...")
 	}
 
-	if queryLang == "ru" {
-		reasoningSteps = append(reasoningSteps, "💡 Формирую финальный ответ с учётом всех данных...")
+	// Contradictions between the top sources are looked for while the answer
+	// is written
+	type conflictsResult struct {
+		conflicts []models.SourceConflict
+		err       error
+	}
+	conflictsDone := make(chan conflictsResult, 1)
+	go func() {
+		conflicts, err := a.detectConflicts(ctx, query, displaySources, queryLang)
+		conflictsDone <- conflictsResult{conflicts, err}
+	}()
+
+	// So are the entities they mention
+	type entitiesResult struct {
+		entities []models.Entity
+		err      error
+	}
+	entitiesDone := make(chan entitiesResult, 1)
+	if a.entityGraphs != nil {
+		go func() {
+			entities, err := a.extractEntities(ctx, query, displaySources, queryLang)
+			entitiesDone <- entitiesResult{entities, err}
+		}()
 	} else {
-		reasoningSteps = append(reasoningSteps, "💡 Generating final answer based on all data...")
+		entitiesDone <- entitiesResult{}
 	}
 
 	// Step 9: Generate answer
-	answer, err := a.llmClient.Complete(ctx, promptBuilder.String(), 0.7, 1200)
+	synthesisStart := time.Now()
+	answer, err := completeAnswer(ctx, llm, "pro", prompt, 0.7, proAnswerTokens)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
+	degraded := errors.Is(err, tools.ErrLLMCircuitOpen)
+	if degraded {
+		answer, err = searchOnlyAnswer(ctx, displaySources, queryLang), nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("LLM completion failed: %w", err)
 	}
 
+	detected := <-conflictsDone
+	if detected.err != nil {
+		logging.Printf(ctx, "⚠️  Conflict detection failed: %v", detected.err)
+	} else if len(detected.conflicts) > 0 {
+		if queryLang == "ru" {
+			reasoningSteps = appendStep(ctx, reasoningSteps,
+				fmt.Sprintf("⚖️ Источники расходятся в %d фактах", len(detected.conflicts)))
+		} else {
+			reasoningSteps = appendStep(ctx, reasoningSteps,
+				fmt.Sprintf("⚖️ Sources disagree on %d facts", len(detected.conflicts)))
+		}
+	}
+
+	extracted := <-entitiesDone
+	if extracted.err != nil {
+		logging.Printf(ctx, "⚠️  Entity extraction failed: %v", extracted.err)
+	} else if len(extracted.entities) > 0 {
+		a.entityGraphs.Add(sessionFromContext(ctx), extracted.entities)
+		if queryLang == "ru" {
+			reasoningSteps = appendStep(ctx, reasoningSteps,
+				fmt.Sprintf("🕸️ Выделено сущностей: %d", len(extracted.entities)))
+		} else {
+			reasoningSteps = appendStep(ctx, reasoningSteps,
+				fmt.Sprintf("🕸️ Entities extracted: %d", len(extracted.entities)))
+		}
+	}
+
 	// Step 10: Format sources with UTF-8 safety
 	sources := make([]models.Source, 0)
 	for i, result := range displaySources {
@@ -369,15 +516,15 @@ Your task:
 		}
 		
 		snippet := utils.SanitizeUTF8(result.Snippet)
-		if len(snippet) > 200 {
-			snippet = utils.TruncateUTF8WithEllipsis(snippet, 200)
-		}
+		snippet = utils.TruncateRunesWithEllipsis(snippet, 200)
 		
 		sources = append(sources, models.Source{
 			Title:       utils.SanitizeUTF8(result.Title),
 			URL:         result.URL,
 			Snippet:     snippet,
 			Credibility: result.Credibility,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
 		})
 	}
 
@@ -388,97 +535,185 @@ Your task:
 		Sources:     sources,
 		Reasoning:   strings.Join(reasoningSteps, "\n"),
 		ContextUsed: len(conversationHistory) > 0,
+		Conflicts:   detected.conflicts,
+		Entities:    extracted.entities,
+		Degraded:    degraded,
 	}, nil
 }
 
-// parallelSubQuerySearch performs parallel searches for sub-queries
+// Retrieve runs search, BM25 reranking, credibility scoring and domain
+// diversification without any LLM calls and returns the ranked results
+func (a *ProAgent) Retrieve(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	budgetCtx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	searchResults, err := a.searchClient.SearchWithOptions(budgetCtx, query, 15, true, searchOptions(constraintsFromContext(ctx)))
+	if err != nil {
+		return nil, a.budgetError(ctx, budgetCtx, fmt.Errorf("search failed: %w", err))
+	}
+
+	rerankStart := time.Now()
+	results := a.reranker.Rerank(query, searchResults.Results)
+	tools.TrackTime(ctx, tools.TimingRerank, rerankStart)
+	credibilityStart := time.Now()
+	results = a.credibilityScorer.RankSources(results)
+	tools.TrackTime(ctx, tools.TimingCredibility, credibilityStart)
+	return a.selectDiverseSources(results, limit), nil
+}
+
+// budgetError reports err as ErrBudgetExceeded when the agent's own timeout,
+// rather than the caller's context, cut the request short
+func (a *ProAgent) budgetError(parent, budgetCtx context.Context, err error) error {
+	if parent.Err() == nil && errors.Is(budgetCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: pro mode ran out of its %s budget: %w", tools.ErrBudgetExceeded, a.timeout, err)
+	}
+	return err
+}
+
+// subQueryResult is what the search of one sub-query returned
+type subQueryResult struct {
+	results []models.TavilyResult
+	query   string
+	err     error
+}
+
+// parallelSubQuerySearch performs parallel searches for sub-queries. A chained
+// sub-query ("What else did {1} direct?") waits for the sub-queries it refers
+// to, and is searched with their answers in place of the placeholders; it is
+// skipped when an answer could not be found.
 func (a *ProAgent) parallelSubQuerySearch(
 	ctx context.Context,
 	subQueries []string,
 	queryLang string,
 	reasoningSteps *[]string,
 ) []models.TavilyResult {
-	type searchResult struct {
-		results []models.TavilyResult
-		query   string
-		err     error
+	deps := make([][]int, len(subQueries))
+	referenced := make(map[int]bool)
+	pending := make(map[int]bool, len(subQueries))
+	done := make(map[int]bool, len(subQueries)) // searched or skipped
+	for i, q := range subQueries {
+		d, ok := hopDependencies(q, i)
+		if !ok {
+			done[i] = true
+			logging.Printf(ctx, "⚠️  Sub-query refers to an unknown hop, skipped: %s", q)
+			continue
+		}
+		deps[i] = d
+		for _, j := range d {
+			referenced[j] = true
+		}
+		pending[i] = true
 	}
 
-	// Try parallel search with extended timeout
-	resultsChan := make(chan searchResult, len(subQueries))
-	var wg sync.WaitGroup
+	allResults := make([]models.TavilyResult, 0)
+	answers := make(map[int]string)
+	successCount := 0
+	failCount := len(subQueries) - len(pending)
+
+	// Each wave searches, in parallel, the sub-queries whose hops are answered
+	for len(pending) > 0 {
+		var wave []int
+		var queries []string
+		for i := range subQueries {
+			if !pending[i] {
+				continue
+			}
+			ready, answered := true, true
+			for _, j := range deps[i] {
+				if !done[j] {
+					ready = false
+				} else if answers[j] == "" {
+					answered = false
+				}
+			}
+			switch {
+			case ready && answered:
+				q := substituteHops(subQueries[i], answers)
+				if q != subQueries[i] {
+					if queryLang == "ru" {
+						*reasoningSteps = appendStep(ctx, *reasoningSteps,
+							fmt.Sprintf("  🔗 Связанный подвопрос: %s", truncateQuery(q, 80)))
+					} else {
+						*reasoningSteps = appendStep(ctx, *reasoningSteps,
+							fmt.Sprintf("  🔗 Chained sub-question: %s", truncateQuery(q, 80)))
+					}
+				}
+				wave = append(wave, i)
+				queries = append(queries, q)
+				delete(pending, i)
+			case ready:
+				failCount++
+				delete(pending, i)
+				done[i] = true
+				if queryLang == "ru" {
+					*reasoningSteps = appendStep(ctx, *reasoningSteps,
+						fmt.Sprintf("  ⚠️ Подвопрос пропущен (нет ответа на предыдущий шаг): %s",
+							truncateQuery(subQueries[i], 60)))
+				} else {
+					*reasoningSteps = appendStep(ctx, *reasoningSteps,
+						fmt.Sprintf("  ⚠️ Sub-query skipped (previous hop unanswered): %s",
+							truncateQuery(subQueries[i], 60)))
+				}
+			}
+		}
 
-	for _, subQuery := range subQueries {
-		wg.Add(1)
-		go func(q string) {
-			defer wg.Done()
+		for k, sr := range a.searchSubQueries(ctx, queries) {
+			i := wave[k]
+			done[i] = true
+			if sr.err != nil {
+				failCount++
+				if queryLang == "ru" {
+					*reasoningSteps = appendStep(ctx, *reasoningSteps,
+						fmt.Sprintf("  ⚠️ Подзапрос пропущен (timeout): %s",
+							truncateQuery(sr.query, 60)))
+				} else {
+					*reasoningSteps = appendStep(ctx, *reasoningSteps,
+						fmt.Sprintf("  ⚠️ Sub-query skipped (timeout): %s",
+							truncateQuery(sr.query, 60)))
+				}
+				continue
+			}
 
-			// Increased per-query timeout to handle slow responses
-			queryCtx, cancel := context.WithTimeout(ctx, 12*time.Second)
-			defer cancel()
+			successCount++
+			if queryLang == "ru" {
+				*reasoningSteps = appendStep(ctx, *reasoningSteps,
+					fmt.Sprintf("  ✓ %s (%d результатов)",
+						truncateQuery(sr.query, 60), len(sr.results)))
+			} else {
+				*reasoningSteps = appendStep(ctx, *reasoningSteps,
+					fmt.Sprintf("  ✓ %s (%d results)",
+						truncateQuery(sr.query, 60), len(sr.results)))
+			}
+			allResults = append(allResults, sr.results...)
 
-			res, err := a.searchClient.Search(queryCtx, q, 5, true)
+			if !referenced[i] {
+				continue
+			}
+			answer, err := a.answerHop(ctx, sr.query, sr.results, queryLang)
 			if err != nil {
-				log.Printf("Sub-query search failed for '%s': %v", q, err)
-				resultsChan <- searchResult{nil, q, err}
-				return
+				logging.Printf(ctx, "⚠️  No answer to hop %d (%s): %v", i+1, sr.query, err)
+				continue
 			}
-
-			resultsChan <- searchResult{res.Results, q, nil}
-		}(subQuery)
-	}
-
-	// Close channel when all goroutines finish
-	go func() {
-		wg.Wait()
-		close(resultsChan)
-	}()
-
-	// Collect results
-	allResults := make([]models.TavilyResult, 0)
-	successCount := 0
-	failCount := 0
-
-	for sr := range resultsChan {
-		if sr.err != nil {
-			failCount++
+			answers[i] = answer
 			if queryLang == "ru" {
-				*reasoningSteps = append(*reasoningSteps,
-					fmt.Sprintf("  ⚠️ Подзапрос пропущен (timeout): %s",
-						truncateQuery(sr.query, 60)))
+				*reasoningSteps = appendStep(ctx, *reasoningSteps, fmt.Sprintf("  💬 Ответ шага %d: %s", i+1, answer))
 			} else {
-				*reasoningSteps = append(*reasoningSteps,
-					fmt.Sprintf("  ⚠️ Sub-query skipped (timeout): %s",
-						truncateQuery(sr.query, 60)))
+				*reasoningSteps = appendStep(ctx, *reasoningSteps, fmt.Sprintf("  💬 Answer to step %d: %s", i+1, answer))
 			}
-			continue
 		}
-
-		successCount++
-		if queryLang == "ru" {
-			*reasoningSteps = append(*reasoningSteps,
-				fmt.Sprintf("  ✓ %s (%d результатов)",
-					truncateQuery(sr.query, 60), len(sr.results)))
-		} else {
-			*reasoningSteps = append(*reasoningSteps,
-				fmt.Sprintf("  ✓ %s (%d results)",
-					truncateQuery(sr.query, 60), len(sr.results)))
-		}
-
-		allResults = append(allResults, sr.results...)
 	}
 
 	// FALLBACK: If most sub-queries failed or not enough results
 	if failCount >= len(subQueries)/2 || len(allResults) < 3 {
-		log.Printf("⚠️ Multi-hop fallback: %d/%d sub-queries failed, switching to direct search",
+		logging.Printf(ctx, "⚠️ Multi-hop fallback: %d/%d sub-queries failed, switching to direct search",
 			failCount, len(subQueries))
 
 		if queryLang == "ru" {
-			*reasoningSteps = append(*reasoningSteps,
+			*reasoningSteps = appendStep(ctx, *reasoningSteps,
 				fmt.Sprintf("⚠️ Переключаюсь на прямой поиск (подзапросы: успех %d, фейл %d)",
 					successCount, failCount))
 		} else {
-			*reasoningSteps = append(*reasoningSteps,
+			*reasoningSteps = appendStep(ctx, *reasoningSteps,
 				fmt.Sprintf("⚠️ Switching to direct search (sub-queries: success %d, failed %d)",
 					successCount, failCount))
 		}
@@ -489,13 +724,49 @@ func (a *ProAgent) parallelSubQuerySearch(
 	return allResults
 }
 
+// searchSubQueries searches queries in parallel; the results are in the order
+// of queries
+func (a *ProAgent) searchSubQueries(ctx context.Context, queries []string) []subQueryResult {
+	results := make([]subQueryResult, len(queries))
+	var wg sync.WaitGroup
+
+	for i, subQuery := range queries {
+		wg.Add(1)
+		go func(i int, q string) {
+			defer wg.Done()
+
+			// Increased per-query timeout to handle slow responses
+			queryCtx, cancel := context.WithTimeout(ctx, 12*time.Second)
+			defer cancel()
+
+			res, err := a.searchClient.SearchWithOptions(queryCtx, q, 5, true, searchOptions(constraintsFromContext(ctx)))
+			if err != nil {
+				logging.Printf(ctx, "Sub-query search failed for '%s': %v", q, err)
+				results[i] = subQueryResult{nil, q, err}
+				return
+			}
+
+			results[i] = subQueryResult{res.Results, q, nil}
+		}(i, subQuery)
+	}
+
+	wg.Wait()
+	return results
+}
+
 // Helper function to truncate long queries
 func truncateQuery(query string, maxLen int) string {
-	return utils.TruncateUTF8(query, maxLen)
+	return utils.TruncateRunes(query, maxLen)
 }
 
-// selectDiverseSources ensures domain diversity in results
+// selectDiverseSources ensures domain diversity in results; syndicated
+// copies of one article count once, so the slots go to different evidence
 func (a *ProAgent) selectDiverseSources(results []models.TavilyResult, maxResults int) []models.TavilyResult {
+	results, copies := tools.DropNearDuplicates(results)
+	if copies > 0 {
+		log.Printf("🧹 Dropped %d near-duplicate sources (syndicated copies)", copies)
+	}
+
 	selected := make([]models.TavilyResult, 0, maxResults)
 	domainCounts := make(map[string]int)
 	maxPerDomain := 2 // Maximum 2 results from same domain
@@ -588,65 +859,137 @@ func (a *ProAgent) detectMultiHop(query string) bool {
 	return false
 }
 
-// generateSubQueries splits complex query into sub-questions
-func (a *ProAgent) generateSubQueries(ctx context.Context, query string, lang string) []string {
-	var prompt string
-	if lang == "ru" {
-		prompt = fmt.Sprintf(`Разбей сложный вопрос на 2-3 простых подвопроса для поиска информации.
+// enhanceQueryWithContext rephrases the query into a self-contained search
+// query using the last messages of the conversation; "" if the LLM returned
+// nothing usable
+func (a *ProAgent) enhanceQueryWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+	queryLang string,
+) (string, error) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
 
-Вопрос: %s
+	var contextPrompt strings.Builder
+	if queryLang == "ru" {
+		contextPrompt.WriteString("Предыдущая беседа:\n")
+	} else {
+		contextPrompt.WriteString("Previous conversation:\n")
+	}
+
+	for _, msg := range recentTurns(conversationHistory, 6) {
+		role := msg.Role
+		if queryLang == "ru" {
+			role = turnRole(msg)
+		}
+		contextPrompt.WriteString(fmt.Sprintf("\n%s: %s\n", role, msg.Content))
+	}
 
-Подвопросы (каждый с новой строки, без нумерации):`, query)
+	if a.entityGraphs != nil {
+		contextPrompt.WriteString(entitiesContext(a.entityGraphs.Recent(sessionFromContext(ctx), recentEntities), queryLang))
+	}
+
+	var enhancePrompt string
+	if queryLang == "ru" {
+		enhancePrompt = fmt.Sprintf(`%s
+
+Текущий вопрос: %s
+
+Перефразируй текущий вопрос так, чтобы он был самодостаточным и включал важную информацию из контекста. Улучшенный поисковый запрос:`, contextPrompt.String(), query)
 	} else {
-		prompt = fmt.Sprintf(`Break down this complex question into 2-3 simple sub-questions for information search.
+		enhancePrompt = fmt.Sprintf(`%s
 
-Question: %s
+Current question: %s
 
-Sub-questions (one per line, no numbering):`, query)
+Rephrase the current question to be self-contained and include important information from context. Enhanced search query:`, contextPrompt.String(), query)
 	}
 
-	response, err := a.llmClient.Complete(ctx, prompt, 0.3, 300)
+	enhanced, err := a.llmClient.ForTask(tools.TaskQueryEnhance).Complete(ctx, enhancePrompt, 0.3, 200)
 	if err != nil {
-		log.Printf("Failed to generate sub-queries: %v", err)
-		return []string{query}
+		return "", err
 	}
+	enhanced = strings.TrimSpace(enhanced)
+	enhanced = strings.Trim(enhanced, `"'`)
+	return strings.TrimSpace(enhanced), nil
+}
 
-	lines := strings.Split(response, "\n")
-	subQueries := make([]string, 0)
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		// Remove various prefixes
-		line = strings.TrimPrefix(line, "- ")
-		line = strings.TrimPrefix(line, "• ")
-		line = strings.TrimPrefix(line, "* ")
+// generateSubQueries splits complex query into sub-questions; a sub-question
+// that needs the answer of an earlier one refers to it as {N}. The LLM first
+// estimates how many facts (hops) the question needs, and as many
+// sub-questions are kept, between minSubQueries and the agent's cap. hops is
+// the estimate, 0 when the LLM gave none.
+func (a *ProAgent) generateSubQueries(ctx context.Context, query string, lang string) ([]string, int) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
 
-		// Remove numbering
-		for i := 1; i <= 9; i++ {
-			line = strings.TrimPrefix(line, fmt.Sprintf("%d. ", i))
-			line = strings.TrimPrefix(line, fmt.Sprintf("%d) ", i))
+	var prompt tools.Prompt
+	if lang == "ru" {
+		prompt = tools.Prompt{
+			System: fmt.Sprintf(`Оцени, сколько отдельных фактов нужно найти, чтобы ответить на сложный вопрос (от %d до %d), и разбей его на столько же простых подвопросов для поиска информации. Вопросу с несколькими условиями (дата, место, число, связь между людьми) нужно по подвопросу на каждое условие.
+Если подвопрос можно задать, только зная ответ на предыдущий, напиши вместо этого ответа {N}, где N - номер предыдущего подвопроса по порядку.
+
+В "hops" напиши число фактов, в "sub_questions" - подвопросы по порядку.`, minSubQueries, a.maxSubQueries),
+			Examples: []tools.Example{{
+				User:      "Вопрос: Какие ещё фильмы снял режиссёр фильма Начало?",
+				Assistant: `{"hops": 2, "sub_questions": ["Кто снял фильм Начало?", "Какие ещё фильмы снял {1}?"]}`,
+			}},
+			User: "Вопрос: " + query,
+		}
+	} else {
+		prompt = tools.Prompt{
+			System: fmt.Sprintf(`Estimate how many separate facts must be found to answer a complex question (%d to %d), and break it down into as many simple sub-questions for information search. A question with several constraints (a date, a place, a number, a relation between people) needs a sub-question per constraint.
+If a sub-question can only be asked once the answer to an earlier one is known, write {N} in place of that answer, where N is the position of the earlier sub-question.
+
+Put the number of facts in "hops" and the sub-questions, in order, in "sub_questions".`, minSubQueries, a.maxSubQueries),
+			Examples: []tools.Example{{
+				User:      "Question: What other films did the director of Inception make?",
+				Assistant: `{"hops": 2, "sub_questions": ["Who directed Inception?", "What other films did {1} direct?"]}`,
+			}},
+			User: "Question: " + query,
 		}
+	}
 
-		line = strings.TrimSpace(line)
+	var plan struct {
+		Hops         int      `json:"hops"`
+		SubQuestions []string `json:"sub_questions"`
+	}
+	if err := a.llmClient.ForTask(tools.TaskPlanning).CompleteJSON(ctx, prompt, subQuestionSchema, &plan); err != nil {
+		logging.Printf(ctx, "Failed to generate sub-queries: %v", err)
+		return []string{query}, 0
+	}
 
-		// Only add substantial queries
-		if len(line) > 10 &&
-			!strings.Contains(strings.ToLower(line), "sub-question") &&
-			!strings.Contains(strings.ToLower(line), "подвопрос") {
-			subQueries = append(subQueries, line)
+	subQueries := make([]string, 0, len(plan.SubQuestions))
+	for _, subQuery := range plan.SubQuestions {
+		if subQuery = strings.TrimSpace(subQuery); subQuery != "" {
+			subQueries = append(subQueries, subQuery)
 		}
 	}
-
+	hops := plan.Hops
 	if len(subQueries) == 0 {
-		return []string{query}
+		return []string{query}, hops
 	}
 
-	// Limit to 3 sub-queries
-	if len(subQueries) > 3 {
-		subQueries = subQueries[:3]
+	limit := min(defaultSubQueries, a.maxSubQueries)
+	if hops > 0 {
+		limit = min(max(hops, minSubQueries), a.maxSubQueries)
+	}
+	if len(subQueries) > limit {
+		subQueries = subQueries[:limit]
 	}
+	logging.Printf(ctx, "📋 Sub-queries: %d (estimated hops: %d, cap %d)", len(subQueries), hops, a.maxSubQueries)
 
-	return subQueries
+	return subQueries, hops
+}
+
+// subQuestionsRu is "подвопрос" declined for n sub-questions
+func subQuestionsRu(n int) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return "подвопрос"
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return "подвопроса"
+	default:
+		return "подвопросов"
+	}
 }
 
 // crossVerify checks consistency between sources
@@ -732,4 +1075,13 @@ func extractDomain(urlStr string) string {
 	hostname = strings.TrimPrefix(hostname, "www.")
 
 	return hostname
-}
\ No newline at end of file
+}
+
+// fetchedAt returns when a result was retrieved, defaulting to now for
+// scrapers that do not stamp their results
+func fetchedAt(result models.TavilyResult) int64 {
+	if result.FetchedAt > 0 {
+		return result.FetchedAt
+	}
+	return time.Now().Unix()
+}
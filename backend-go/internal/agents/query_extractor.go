@@ -0,0 +1,103 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+type constraintsKey struct{}
+
+// withConstraints attaches extracted query constraints to the request context
+func withConstraints(ctx context.Context, c *models.QueryConstraints) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, constraintsKey{}, c)
+}
+
+// constraintsFromContext returns the constraints extracted by the router, if any
+func constraintsFromContext(ctx context.Context) *models.QueryConstraints {
+	c, _ := ctx.Value(constraintsKey{}).(*models.QueryConstraints)
+	return c
+}
+
+// QueryExtractor turns a free-form query into structured constraints
+type QueryExtractor struct {
+	llmClient *tools.LLMClient
+}
+
+func NewQueryExtractor(llmClient *tools.LLMClient) *QueryExtractor {
+	return &QueryExtractor{llmClient: llmClient}
+}
+
+func (e *QueryExtractor) Extract(ctx context.Context, query string) (*models.QueryConstraints, error) {
+	prompt := fmt.Sprintf(`Extract structured search parameters from the user query.
+
+Return ONLY a JSON object with these fields (omit or leave empty if not present):
+{
+  "entities": ["named entities: people, companies, products, places"],
+  "time_range": "day | week | month | year (only if the query asks about recent events)",
+  "period": "explicit period mentioned, e.g. 2008 or 2015-2020",
+  "location": "geographic location the query is about",
+  "comparison_targets": ["items being compared, if it is a comparison"],
+  "tickers": ["stock ticker symbols for companies or assets, e.g. AAPL, SBER.ME, BTC-USD"],
+  "sites": ["domains the user explicitly asked to search, e.g. habr.com"]
+}
+
+Query: %s
+
+JSON:`, query)
+
+	response, err := e.llmClient.ForTask(tools.TaskExtraction).Complete(ctx, prompt, 0.1, 300)
+	if err != nil {
+		return nil, fmt.Errorf("constraint extraction failed: %w", err)
+	}
+
+	constraints, err := parseConstraints(response)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Printf(ctx, "🧩 Extracted constraints: entities=%v time_range=%q location=%q tickers=%v sites=%v",
+		constraints.Entities, constraints.TimeRange, constraints.Location, constraints.Tickers, constraints.Sites)
+	return constraints, nil
+}
+
+// parseConstraints extracts the JSON object from an LLM response
+func parseConstraints(response string) (*models.QueryConstraints, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON object in extraction response")
+	}
+
+	var constraints models.QueryConstraints
+	if err := json.Unmarshal([]byte(response[start:end+1]), &constraints); err != nil {
+		return nil, fmt.Errorf("invalid extraction JSON: %w", err)
+	}
+
+	switch constraints.TimeRange {
+	case "day", "week", "month", "year":
+	default:
+		constraints.TimeRange = ""
+	}
+
+	return &constraints, nil
+}
+
+// searchOptions maps constraints onto web search filters
+func searchOptions(c *models.QueryConstraints) tools.SearchOptions {
+	if c == nil {
+		return tools.SearchOptions{}
+	}
+	return tools.SearchOptions{
+		TimeRange: c.TimeRange,
+		Sites:     c.Sites,
+	}
+}
@@ -2,10 +2,16 @@ package agents
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/guardrails"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/jobs"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
 )
@@ -19,23 +25,109 @@ type RouterAgent struct {
 	socialAgent    *SocialAgent
 	academicAgent  *AcademicAgent
 	financeAgent   *FinanceAgent
+	newsAgent      *NewsAgent
+	codeAgent      *CodeAgent
+	videoAgent     *VideoAgent
+	factCheckAgent *FactCheckAgent
+	deepAgent      *DeepAgent
+	toolsAgent     *ToolsAgent
+	mixedAgent     *MixedAgent
+	instantAgent   *InstantAgent
+	imagesAgent    *ImagesAgent
+	costs          *ModeCosts
 	modeSelector   *ModeSelector
+	autoModeModel  *AutoModeModel
+	queryExtractor *QueryExtractor
+	guard          *guardrails.Guard
+	jobs           *jobs.Store
+	previews       *tools.PreviewFetcher    // nil when source previews are disabled
+	translator     *tools.SnippetTranslator // nil when snippet translation is disabled
+	modes          []registeredMode
 }
 
-func NewRouterAgent(cfg *config.Config) *RouterAgent {
-	searchClient := tools.NewSearchClient()
+func NewRouterAgent(
+	cfg *config.Config,
+	jobStore *jobs.Store,
+	pages *tools.PageFetcher,
+	previews *tools.PreviewFetcher,
+	translator *tools.SnippetTranslator,
+	searchCache *tools.SearchCache,
+) *RouterAgent {
+	searchClient := tools.NewSearchClient().WithTavily(cfg.TavilyAPIKey, cfg.TavilySearchDepth).WithSerpAPI(cfg.SerpAPIKey).
+		WithCache(searchCache)
+	if cfg.FetchPageContent {
+		searchClient.WithPageFetcher(pages)
+	}
 	llmClient := tools.NewLLMClient(cfg)
+	evidence := NewEvidencePolicy(cfg.EvidenceThreshold)
+	newsRecency := time.Duration(cfg.NewsRecencyHours) * time.Hour
+	proAgent := NewProAgent(searchClient, llmClient, evidence).WithMaxSubQueries(cfg.ProMaxSubQueries)
+	if cfg.CrossLanguageSearch {
+		proAgent.WithQueryTranslator(translator)
+	}
+	if cfg.ProSourceSummaries {
+		proAgent.WithSourceSummaries()
+	}
+	if cfg.EntityGraphEnabled {
+		proAgent.WithEntityGraphs(NewEntityGraphs())
+	}
+
+	modeClassifier, err := NewModeClassifier(llmClient, cfg.ModeExamplesPath)
+	if err != nil {
+		log.Printf("⚠️  Mode classifier disabled, the LLM selects modes: %v", err)
+	}
+	guard, err := guardrails.Load(cfg.GuardrailsPolicyPath, cfg.GuardrailsEnabled, cfg.PIIRedactionEnabled)
+	if err != nil {
+		log.Printf("⚠️  Guardrails policy %s rejected, using the built-in one: %v", cfg.GuardrailsPolicyPath, err)
+		guard, _ = guardrails.Load("", cfg.GuardrailsEnabled, cfg.PIIRedactionEnabled)
+	}
+
+	r := &RouterAgent{
+		cfg:            cfg,
+		searchClient:   searchClient,
+		llmClient:      llmClient,
+		simpleAgent:    NewSimpleAgent(searchClient, llmClient, evidence),
+		proAgent:       proAgent,
+		socialAgent:    NewSocialAgent(llmClient, evidence),
+		academicAgent:  NewAcademicAgent(llmClient, evidence),
+		financeAgent:   NewFinanceAgent(llmClient, evidence),
+		newsAgent:      NewNewsAgent(searchClient, llmClient, evidence, cfg.NewsRSSFeeds, newsRecency),
+		codeAgent:      NewCodeAgent(llmClient, evidence, cfg.GitHubToken, cfg.StackExchangeKey),
+		videoAgent:     NewVideoAgent(llmClient, evidence, cfg.YouTubeAPIKey),
+		instantAgent:   NewInstantAgent(),
+		factCheckAgent: NewFactCheckAgent(searchClient, llmClient, evidence),
+		toolsAgent:     NewToolsAgent(searchClient, llmClient, pages, evidence),
+		deepAgent: NewDeepAgent(
+			proAgent,
+			llmClient,
+			evidence,
+			time.Duration(cfg.DeepResearchTimeoutSeconds)*time.Second,
+			cfg.DeepResearchMaxTokens,
+			cfg.DeepResearchMaxRounds,
+		),
+		costs:        NewModeCosts(),
+		modeSelector: NewModeSelector(llmClient, modeClassifier, cfg.ModeClassifierMinMargin),
+		autoModeModel: NewAutoModeModel(
+			cfg.AutoModeModelPath,
+			cfg.AutoModeProThreshold,
+			cfg.AutoModeSimpleThreshold,
+		),
+		queryExtractor: NewQueryExtractor(llmClient),
+		guard:          guard,
+		jobs:           jobStore,
+		previews:       previews,
+		translator:     translator,
+	}
+	r.mixedAgent = NewMixedAgent(r.socialAgent, r.academicAgent, r.financeAgent, llmClient)
+	r.imagesAgent = NewImagesAgent(searchClient, r.simpleAgent)
+	r.registerModes()
+	return r
+}
 
-	return &RouterAgent{
-		cfg:           cfg,
-		searchClient:  searchClient,
-		llmClient:     llmClient,
-		simpleAgent:   NewSimpleAgent(searchClient, llmClient),
-		proAgent:      NewProAgent(searchClient, llmClient),
-		socialAgent:   NewSocialAgent(llmClient),
-		academicAgent: NewAcademicAgent(llmClient),
-		financeAgent:  NewFinanceAgent(llmClient),
-		modeSelector:  NewModeSelector(llmClient),
+// ForgetSession drops what the agents remember of a deleted chat session
+func (r *RouterAgent) ForgetSession(sessionID string) {
+	if r.proAgent.entityGraphs != nil {
+		r.proAgent.entityGraphs.Forget(sessionID)
 	}
 }
 
@@ -43,86 +135,515 @@ func (r *RouterAgent) ProcessQuery(ctx context.Context, query, mode string) (*mo
 	return r.ProcessQueryWithContext(ctx, query, mode, nil)
 }
 
+// ProcessQueryWithContext answers a query in mode, auto routing it when mode
+// is auto or empty. The query and conversation pass the guardrails first.
 func (r *RouterAgent) ProcessQueryWithContext(
 	ctx context.Context,
 	query, mode string,
 	conversationHistory []models.Message,
 ) (*models.SearchResponse, error) {
-	// Select mode if auto
-	selectedMode := mode
-	
-	if mode == "auto" || mode == "" {
-		// AUTO MODE LOGIC: Switch to Pro if context exists
-		if len(conversationHistory) > 2 {
-			// После 2+ сообщений всегда используем Pro
-			selectedMode = "pro"
-			log.Printf("🔄 Auto mode: Switching to PRO (context size: %d messages)", len(conversationHistory))
-		} else {
-			// Первые запросы - выбираем простой/сложный
-			var err error
-			selectedMode, err = r.modeSelector.SelectMode(ctx, query)
-			if err != nil {
-				log.Printf("Mode selection failed, defaulting to simple: %v", err)
-				selectedMode = "simple"
-			}
-			log.Printf("🤖 Auto mode selected: %s for query: %s", selectedMode, query)
-		}
+	query, conversationHistory, screening, err := r.screen(ctx, query, conversationHistory)
+	if err != nil {
+		return nil, err
 	}
+	result, err := r.answer(ctx, query, mode, conversationHistory)
+	if err != nil {
+		return nil, err
+	}
+	result.Guardrails = screening
+	return result, nil
+}
+
+// answer routes and answers a screened query
+func (r *RouterAgent) answer(
+	ctx context.Context,
+	query, mode string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	routingStart := time.Now()
+	ctx, meter := tools.WithTokenMeter(ctx)
+	ctx = withSourceRecorder(ctx)
+	selectedMode, autoRouting := r.route(ctx, query, mode, conversationHistory)
+
+	// Extract structured constraints for Pro modes (used for provider-specific queries)
+	constraints := r.extractConstraints(ctx, query, selectedMode)
+	if constraints != nil {
+		ctx = withConstraints(ctx, constraints)
+	}
+	// The period the query asks about filters every search of the request
+	dateRange := queryDateRange(ctx, query, constraints)
+	ctx = tools.WithDateRange(ctx, dateRange)
+	tools.TrackTime(ctx, tools.TimingRouting, routingStart)
 
 	// Process based on selected mode
 	var result *models.SearchResponse
 	var err error
 
-	switch selectedMode {
-	case "pro":
-		if len(conversationHistory) > 0 {
-			result, err = r.proAgent.ProcessWithContext(ctx, query, conversationHistory)
-		} else {
-			result, err = r.proAgent.Process(ctx, query)
-		}
-		
-	case "pro-social":
-		if len(conversationHistory) > 0 {
-			result, err = r.socialAgent.ProcessWithContext(ctx, query, conversationHistory)
-		} else {
-			result, err = r.socialAgent.Process(ctx, query)
-		}
-		
-	case "pro-academic":
-		if len(conversationHistory) > 0 {
-			result, err = r.academicAgent.ProcessWithContext(ctx, query, conversationHistory)
-		} else {
-			result, err = r.academicAgent.Process(ctx, query)
-		}
-		
-	case "pro-finance":
-		if len(conversationHistory) > 0 {
-			result, err = r.financeAgent.ProcessWithContext(ctx, query, conversationHistory)
-		} else {
-			result, err = r.financeAgent.Process(ctx, query)
-		}
-		
-	case "simple":
-		if len(conversationHistory) > 0 {
-			result, err = r.simpleAgent.ProcessWithContext(ctx, query, conversationHistory)
-		} else {
-			result, err = r.simpleAgent.Process(ctx, query)
-		}
-		
-	default:
+	agent, ok := r.modeAgent(selectedMode)
+	if !ok {
 		return nil, fmt.Errorf("unknown mode: %s", selectedMode)
 	}
+	if len(conversationHistory) > 0 {
+		result, err = agent.ProcessWithContext(ctx, query, conversationHistory)
+	} else {
+		result, err = agent.Process(ctx, query)
+	}
+
+	// The data provider could not answer - take the search path
+	if errors.Is(err, errNoInstantAnswer) {
+		logging.Printf(ctx, "⚡ Instant answer unavailable, falling back to simple: %v", err)
+		selectedMode = "simple"
+		if autoRouting != nil {
+			autoRouting.SelectedMode = selectedMode
+		}
+		result, err = r.simpleAgent.ProcessWithContext(ctx, query, conversationHistory)
+	}
 
 	if err != nil {
 		return nil, err
 	}
+	r.costs.Record(selectedMode, time.Since(routingStart), meter.PromptTokens(), meter.CompletionTokens())
+
+	// A weak Simple answer to an auto mode query is answered again by Pro
+	if autoRouting != nil && selectedMode == "simple" {
+		if escalated := r.escalate(ctx, query, conversationHistory, result); escalated != nil {
+			selectedMode = "pro"
+			autoRouting.SelectedMode = selectedMode
+			autoRouting.EscalatedFrom = "simple"
+			result = escalated
+		}
+	}
+
+	result.Constraints = constraints
+	result.DateRange = dateRange
+	r.finishAnswer(ctx, query, result)
 
 	// Preserve original mode if it was auto
 	if mode == "auto" || mode == "" {
 		result.Mode = "auto → " + selectedMode
+		result.AutoRouting = autoRouting
 	} else {
 		result.Mode = selectedMode
 	}
 	
 	return result, nil
-}
\ No newline at end of file
+}
+
+// route resolves auto mode to a concrete mode: instant answers for weather,
+// rate and conversion questions, images for requests to see something, then
+// the routing model, the LLM selector when the model is not confident, then a
+// vertical agent for domain queries (pro-mixed for Pro queries spanning
+// finance, academia and social media). Queries without domain keywords take
+// the domain of their nearest labeled examples (see SelectDomain).
+// Current-events, programming, video and fact-checking queries go to the
+// news, code, video and fact-check agents from simple mode too (see
+// simpleVerticals). With AUTO_COST_AWARE a Pro decision the query alone does
+// not support (only the length of the conversation does) is left to the
+// selector, and a chat session close to SESSION_TOKEN_BUDGET gets the most
+// capable mode it can still afford (see withinBudget). Explicit modes are
+// returned as is with nil routing.
+func (r *RouterAgent) route(
+	ctx context.Context,
+	query, mode string,
+	conversationHistory []models.Message,
+) (string, *models.AutoRouting) {
+	selectedMode := mode
+	var autoRouting *models.AutoRouting
+
+	if (mode == "auto" || mode == "") && r.matchesInstant(ctx, query) {
+		logging.Printf(ctx, "⚡ Auto mode: instant answer for query: %s", query)
+		return "instant", &models.AutoRouting{SelectedMode: "instant", DecidedBy: "instant"}
+	}
+
+	if (mode == "auto" || mode == "") && r.matchesImages(ctx, query) {
+		logging.Printf(ctx, "🖼️  Auto mode: image request: %s", query)
+		return "images", &models.AutoRouting{SelectedMode: "images", DecidedBy: "images"}
+	}
+
+	if mode == "auto" || mode == "" {
+		// AUTO MODE LOGIC: consult the routing model first
+		features := r.autoModeModel.ExtractFeatures(
+			query,
+			len(conversationHistory),
+			r.proAgent.detectMultiHop(query),
+		)
+		modelMode, proProbability := r.autoModeModel.Decide(features)
+		autoRouting = &models.AutoRouting{
+			Features:       features,
+			ProProbability: proProbability,
+		}
+		// Pro costs several Simple answers: a long conversation alone is no
+		// reason to pay for it
+		if modelMode == "pro" && r.cfg.AutoCostAware && len(conversationHistory) > 0 {
+			queryOnly := features
+			queryOnly.HistoryMessages = 0
+			if queryMode, _ := r.autoModeModel.Decide(queryOnly); queryMode != "pro" {
+				logging.Printf(ctx, "💰 Auto mode: Pro only for the conversation length, asking the selector")
+				modelMode = ""
+			}
+		}
+
+		if modelMode != "" {
+			selectedMode = modelMode
+			autoRouting.DecidedBy = "model"
+			logging.Printf(ctx, "🔄 Auto mode: model selected %s (p_pro=%.2f, context size: %d messages)",
+				strings.ToUpper(selectedMode), proProbability, len(conversationHistory))
+		} else {
+			// Model is not confident - fall back to the mode selector
+			selected, decidedBy, err := r.modeSelector.SelectMode(ctx, query)
+			if err != nil {
+				logging.Printf(ctx, "Mode selection failed, defaulting to simple: %v", err)
+				selected, decidedBy = "simple", "selector"
+			}
+			selectedMode = selected
+			autoRouting.DecidedBy = decidedBy
+			logging.Printf(ctx, "🤖 Auto mode selected: %s for query: %s (p_pro=%.2f)", selectedMode, query, proProbability)
+		}
+		autoRouting.SelectedMode = selectedMode
+
+		// Research queries spanning several domains go to pro-mixed, queries
+		// with one clear domain to the vertical agent
+		mixed := detectMixed(query)
+		if selectedMode == "pro" && r.cfg.AutoVerticalThreshold > 0 &&
+			mixed != nil && mixed.Confidence >= r.cfg.AutoVerticalThreshold {
+			autoRouting.Vertical = mixed
+			selectedMode = mixed.Agent
+			logging.Printf(ctx, "🧭 Auto mode: mixed intent %s (signals: %s, confidence %.2f)",
+				strings.Join(mixed.Domains, " + "), strings.Join(mixed.Signals, ", "), mixed.Confidence)
+		} else if (selectedMode == "pro" || selectedMode == "simple") && r.cfg.AutoVerticalThreshold > 0 {
+			vertical := detectVertical(query)
+			if vertical == nil {
+				// No keywords: paraphrases still route by the domain of
+				// similar labeled queries
+				vertical = r.modeSelector.SelectDomain(ctx, query)
+			}
+			if vertical != nil && vertical.Confidence >= r.cfg.AutoVerticalThreshold &&
+				(selectedMode == "pro" || simpleVerticals[vertical.Agent]) {
+				autoRouting.Vertical = vertical
+				selectedMode = vertical.Agent
+				if len(vertical.Examples) > 0 {
+					logging.Printf(ctx, "🧭 Auto mode: vertical %s (like: %s, confidence %.2f)",
+						vertical.Agent, strings.Join(vertical.Examples, "; "), vertical.Confidence)
+				} else {
+					logging.Printf(ctx, "🧭 Auto mode: vertical %s (signals: %s, confidence %.2f)",
+						vertical.Agent, strings.Join(vertical.Signals, ", "), vertical.Confidence)
+				}
+			}
+		}
+
+		affordable, cost := r.withinBudget(ctx, selectedMode)
+		if affordable != selectedMode {
+			logging.Printf(ctx, "💰 Auto mode: %s exceeds the session budget (%d of %d tokens spent), using %s",
+				selectedMode, cost.SessionTokens, cost.SessionBudget, affordable)
+			selectedMode = affordable
+		}
+		autoRouting.Cost = cost
+	}
+
+	return selectedMode, autoRouting
+}
+
+// matchesInstant reports whether the instant agent should answer an auto
+// mode query. Queries over uploaded documents always go to search.
+func (r *RouterAgent) matchesInstant(ctx context.Context, query string) bool {
+	return r.cfg.InstantAnswersEnabled && !hasDocuments(ctx) && r.instantAgent.Matches(query)
+}
+
+// matchesImages reports whether the images agent should answer an auto mode
+// query. Queries over uploaded documents always go to search.
+func (r *RouterAgent) matchesImages(ctx context.Context, query string) bool {
+	return r.cfg.ImageAnswersEnabled && !hasDocuments(ctx) && r.imagesAgent.Matches(query)
+}
+
+// extractConstraints extracts structured query constraints for Pro modes;
+// nil for simple, instant and images modes, when extraction is disabled or fails
+func (r *RouterAgent) extractConstraints(ctx context.Context, query, selectedMode string) *models.QueryConstraints {
+	if selectedMode == "simple" || selectedMode == "instant" || selectedMode == "images" || !r.cfg.QueryExtractionEnabled {
+		return nil
+	}
+	constraints, err := r.queryExtractor.Extract(ctx, query)
+	if err != nil {
+		logging.Printf(ctx, "⚠️  %v", err)
+		return nil
+	}
+	return constraints
+}
+
+// Retrieve returns ranked sources for the query without answer synthesis.
+// Explicit filters (time range, sites) are applied to the web search.
+func (r *RouterAgent) Retrieve(
+	ctx context.Context,
+	query string,
+	limit int,
+	filters *models.QueryConstraints,
+) ([]models.TavilyResult, models.Evidence, error) {
+	query, _, _, err := r.screen(ctx, query, nil)
+	if err != nil {
+		return nil, models.Evidence{}, err
+	}
+	results, err := r.proAgent.Retrieve(withConstraints(ctx, filters), query, limit)
+	if err != nil {
+		return nil, models.Evidence{}, err
+	}
+
+	assessment := tools.AssessEvidence(results, evidenceTopSources)
+	return results, models.Evidence{
+		Score:       assessment.Score,
+		Credibility: assessment.Credibility,
+		Agreement:   assessment.Agreement,
+	}, nil
+}
+
+// ProcessQueryRace answers an auto mode query with Simple right away and runs
+// Pro in a background job. The returned response carries the job ID; onImproved
+// is called with the Pro answer once it is ready.
+func (r *RouterAgent) ProcessQueryRace(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+	onImproved func(*models.SearchResponse),
+) (*models.SearchResponse, error) {
+	query, conversationHistory, screening, err := r.screen(ctx, query, conversationHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pro has nothing to improve on an instant answer or images, and a
+	// session that can't afford Pro is routed within its budget
+	if r.matchesInstant(ctx, query) || r.matchesImages(ctx, query) {
+		return r.answerScreened(ctx, query, conversationHistory, screening)
+	}
+	if affordable, _ := r.withinBudget(ctx, "pro"); affordable != "pro" {
+		return r.answerScreened(ctx, query, conversationHistory, screening)
+	}
+
+	logging.Printf(ctx, "🏁 Race mode: Simple now, Pro in background for query: %s", query)
+
+	requestID := logging.RequestID(ctx)
+	format := answerFormatFromContext(ctx)
+	lang := answerLanguageOverride(ctx)
+	recordStep := stepRecorderFromContext(ctx)
+	documents := documentsFromContext(ctx)
+	schema := outputSchemaFromContext(ctx)
+	sessionID := sessionFromContext(ctx)
+	persona := personaFromContext(ctx)
+	dateRange := queryDateRange(ctx, query, nil)
+	ctx = tools.WithDateRange(ctx, dateRange)
+	job := r.jobs.Submit("pro-race", 60*time.Second, func(jobCtx context.Context) (*models.SearchResponse, error) {
+		jobCtx = WithAnswerFormat(logging.WithRequestID(jobCtx, requestID), format)
+		jobCtx = WithStepRecorder(WithAnswerLanguage(jobCtx, lang), recordStep)
+		jobCtx = WithOutputSchema(WithDocuments(jobCtx, documents), schema)
+		jobCtx = tools.WithDateRange(WithSession(jobCtx, sessionID), dateRange)
+		jobCtx = withSourceRecorder(WithPersona(jobCtx, persona))
+		jobCtx, meter := tools.WithTokenMeter(jobCtx)
+		start := time.Now()
+		result, err := r.proAgent.ProcessWithContext(jobCtx, query, conversationHistory)
+		if err != nil {
+			return nil, err
+		}
+		r.costs.Record("pro", time.Since(start), meter.PromptTokens(), meter.CompletionTokens())
+		result.DateRange = dateRange
+		r.finishAnswer(jobCtx, query, result)
+		result.Mode = "auto → pro"
+		result.Guardrails = screening
+		if onImproved != nil {
+			onImproved(result)
+		}
+		return result, nil
+	})
+
+	simpleCtx, meter := tools.WithTokenMeter(withSourceRecorder(ctx))
+	start := time.Now()
+	result, err := r.simpleAgent.ProcessWithContext(simpleCtx, query, conversationHistory)
+	if err != nil {
+		// Simple failed - wait for Pro instead of failing the request
+		logging.Printf(ctx, "⚠️  Race mode: Simple failed, waiting for Pro: %v", err)
+		finished, waitErr := r.jobs.Wait(ctx, job.ID)
+		if waitErr != nil {
+			return nil, waitErr
+		}
+		if finished.Status != jobs.StatusDone {
+			return nil, fmt.Errorf("both simple and pro failed: %s", finished.Error)
+		}
+		return finished.Result, nil
+	}
+
+	r.costs.Record("simple", time.Since(start), meter.PromptTokens(), meter.CompletionTokens())
+
+	result.DateRange = dateRange
+	r.finishAnswer(simpleCtx, query, result)
+	result.Mode = "auto → simple"
+	result.ImprovedAnswerJobID = job.ID
+	result.Guardrails = screening
+	return result, nil
+}
+
+// answerScreened answers an already screened auto mode query
+func (r *RouterAgent) answerScreened(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+	screening *models.Guardrails,
+) (*models.SearchResponse, error) {
+	result, err := r.answer(ctx, query, "auto", conversationHistory)
+	if err != nil {
+		return nil, err
+	}
+	result.Guardrails = screening
+	return result, nil
+}
+
+// escalate answers an auto mode query again with Pro when its Simple answer
+// is weak: an evidence score below AUTO_ESCALATION_MIN_CONFIDENCE or fewer
+// than AUTO_ESCALATION_MIN_SOURCES sources. It returns the Pro answer with the
+// escalation noted in its reasoning, or nil to keep the Simple answer: when
+// escalation is disabled, the answer is strong enough, the session budget
+// can't cover Pro, or Pro fails or finds no sources.
+func (r *RouterAgent) escalate(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+	simple *models.SearchResponse,
+) *models.SearchResponse {
+	if !r.cfg.AutoEscalation {
+		return nil
+	}
+	var confidence float64
+	if simple.Evidence != nil {
+		confidence = simple.Evidence.Score
+	}
+	if confidence >= r.cfg.AutoEscalationMinConfidence && len(simple.Sources) >= r.cfg.AutoEscalationMinSources {
+		return nil
+	}
+	if affordable, _ := r.withinBudget(ctx, "pro"); affordable != "pro" {
+		logging.Printf(ctx, "⬆️  Weak simple answer, but Pro exceeds the session budget")
+		return nil
+	}
+
+	var note string
+	if answerLanguage(ctx, query) == "ru" {
+		note = fmt.Sprintf("⬆️ Ответ Simple недостаточно уверенный (доказательность %.2f, порог %.2f; источников %d, минимум %d) - переключаюсь на Pro",
+			confidence, r.cfg.AutoEscalationMinConfidence, len(simple.Sources), r.cfg.AutoEscalationMinSources)
+	} else {
+		note = fmt.Sprintf("⬆️ Simple answer not confident enough (evidence %.2f, threshold %.2f; %d sources, minimum %d) - escalating to Pro",
+			confidence, r.cfg.AutoEscalationMinConfidence, len(simple.Sources), r.cfg.AutoEscalationMinSources)
+	}
+	logging.Printf(ctx, "%s", note)
+	appendStep(ctx, nil, note)
+
+	proCtx, meter := tools.WithTokenMeter(ctx)
+	start := time.Now()
+	pro, err := r.proAgent.ProcessWithContext(proCtx, query, conversationHistory)
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Escalation to Pro failed, keeping the simple answer: %v", err)
+		return nil
+	}
+	r.costs.Record("pro", time.Since(start), meter.PromptTokens(), meter.CompletionTokens())
+	if len(pro.Sources) == 0 && len(simple.Sources) > 0 {
+		logging.Printf(ctx, "⚠️  Pro found no sources, keeping the simple answer")
+		return nil
+	}
+
+	pro.Reasoning = strings.TrimSpace(note + "\n" + pro.Reasoning)
+	return pro
+}
+
+// finishAnswer evaluates the calculator expressions of the answer (see
+// calcInstruction), formats it and enriches its sources. Instant answers
+// cite their data provider only and skip the enrichment to stay fast.
+func (r *RouterAgent) finishAnswer(ctx context.Context, query string, result *models.SearchResponse) {
+	defer tools.TrackTime(ctx, tools.TimingPostprocess, time.Now())
+
+	result.Answer = evaluateCalculations(ctx, result.Answer, answerLanguage(ctx, query))
+	if result.Mode != "instant" {
+		r.verifyAnswer(ctx, query, result)
+	}
+	formatAnswer(ctx, result)
+	if result.Mode == "instant" {
+		return
+	}
+	r.attachStructured(ctx, query, result)
+	r.attachPreviews(ctx, result)
+	tagSourceLanguages(result)
+	r.attachTranslations(ctx, query, result)
+}
+
+// tagSourceLanguages sets the language of every source that has none
+func tagSourceLanguages(result *models.SearchResponse) {
+	for i := range result.Sources {
+		if result.Sources[i].Language == "" {
+			result.Sources[i].Language = sourceLanguage(result.Sources[i])
+		}
+	}
+}
+
+// attachPreviews adds source cards to the cited sources, or to all sources if
+// the answer has no citation markers. Uploaded documents get no card.
+func (r *RouterAgent) attachPreviews(ctx context.Context, result *models.SearchResponse) {
+	if r.previews == nil || len(result.Sources) == 0 {
+		return
+	}
+
+	documentsPrefix := r.cfg.PublicURL + "/api/documents/"
+	cited := make(map[int]bool)
+	var indexes []int
+	for _, citation := range result.Citations {
+		cited[citation.SourceIndex] = true
+	}
+	for i, source := range result.Sources {
+		if strings.HasPrefix(source.URL, documentsPrefix) {
+			continue
+		}
+		if len(cited) == 0 || cited[i] {
+			indexes = append(indexes, i)
+		}
+	}
+	if len(indexes) > 0 {
+		r.previews.Enrich(ctx, result.Sources, indexes)
+	}
+}
+
+// snippetTranslationBudget bounds the time translations may add to an answer
+const snippetTranslationBudget = 8 * time.Second
+
+// attachTranslations adds a translation to source snippets written in another
+// language than the answer, so citations can be checked by readers who don't
+// know the source language. A failed translation leaves the sources as they are.
+func (r *RouterAgent) attachTranslations(ctx context.Context, query string, result *models.SearchResponse) {
+	if r.translator == nil || len(result.Sources) == 0 {
+		return
+	}
+
+	target := answerLanguage(ctx, query)
+	var indexes []int
+	var snippets []string
+	for i, source := range result.Sources {
+		if strings.TrimSpace(source.Snippet) == "" || source.Language == target {
+			continue
+		}
+		indexes = append(indexes, i)
+		snippets = append(snippets, source.Snippet)
+	}
+	if len(indexes) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, snippetTranslationBudget)
+	defer cancel()
+	translated, err := r.translator.Translate(ctx, snippets, target)
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Snippet translation skipped: %v", err)
+		return
+	}
+	for j, i := range indexes {
+		if translated[j] == "" {
+			continue
+		}
+		result.Sources[i].Translation = &models.SourceTranslation{
+			Language: target,
+			Snippet:  translated[j],
+			Provider: r.translator.Provider(),
+		}
+	}
+	logging.Printf(ctx, "🌐 Translated %d source snippets into %s", len(indexes), target)
+}
@@ -0,0 +1,114 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+// Output schemas of structured answers (SearchRequest.OutputSchema)
+const (
+	OutputSchemaPerson     = "person"
+	OutputSchemaDate       = "date"
+	OutputSchemaNumber     = "number"
+	OutputSchemaList       = "list"
+	OutputSchemaComparison = "comparison"
+)
+
+// outputSchemas are the JSON objects the LLM fills in, by schema name
+var outputSchemas = map[string]string{
+	OutputSchemaPerson: `{"name": string, "description": string, "born": date|null, "died": date|null,
+ "nationality": string|null, "occupations": [string], "known_for": [string]}`,
+	OutputSchemaDate: `{"event": string, "date": date, "end_date": date|null,
+ "precision": "day"|"month"|"year", "place": string|null}`,
+	OutputSchemaNumber: `{"quantity": string, "value": number, "unit": string|null, "as_of": date|null}`,
+	OutputSchemaList:   `{"topic": string, "items": [{"name": string, "description": string}]}`,
+	OutputSchemaComparison: `{"items": [string], "criteria": [{"criterion": string, "values": [string]}],
+ "summary": string}`,
+}
+
+// structuredAnswerTimeout bounds the extra LLM call of a structured answer
+const structuredAnswerTimeout = 20 * time.Second
+
+type outputSchemaKey struct{}
+
+// WithOutputSchema asks the agents handling ctx for a structured answer in
+// the given schema next to the prose answer
+func WithOutputSchema(ctx context.Context, schema string) context.Context {
+	if schema == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, outputSchemaKey{}, schema)
+}
+
+func outputSchemaFromContext(ctx context.Context) string {
+	schema, _ := ctx.Value(outputSchemaKey{}).(string)
+	return schema
+}
+
+// attachStructured fills the requested output schema from the answer in JSON
+// mode. Only facts of the answer are used; a failed extraction leaves the
+// prose answer without a structured payload.
+func (r *RouterAgent) attachStructured(ctx context.Context, query string, result *models.SearchResponse) {
+	schema := outputSchemaFromContext(ctx)
+	shape, ok := outputSchemas[schema]
+	if !ok || strings.TrimSpace(result.Answer) == "" {
+		return
+	}
+
+	language := "English"
+	if answerLanguage(ctx, query) == "ru" {
+		language = "Russian"
+	}
+	prompt := fmt.Sprintf(`Extract a structured answer to the question from the answer text.
+Return only a JSON object of this shape:
+%s
+
+Use only facts stated in the answer text; use null (or an empty array) for anything it does not state.
+Dates are ISO 8601 strings shortened to the known precision (1961-04-12, 2024-05, 1961).
+Write the other string values in %s.
+
+Question: %s
+
+Answer text:
+%s`, shape, language, query, result.Answer)
+
+	ctx, cancel := context.WithTimeout(ctx, structuredAnswerTimeout)
+	defer cancel()
+	response, err := r.llmClient.ForTask(tools.TaskExtraction).ChatCompletionJSON(ctx, []map[string]string{
+		{"role": "user", "content": prompt},
+	}, 0.1, 800)
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Structured answer (%s) failed: %v", schema, err)
+		return
+	}
+	data, err := parseStructured(response)
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Structured answer (%s) failed: %v", schema, err)
+		return
+	}
+	result.Structured = &models.StructuredAnswer{Schema: schema, Data: data}
+}
+
+// parseStructured extracts the JSON object from an LLM response
+func parseStructured(response string) (json.RawMessage, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON object in structured answer")
+	}
+
+	// Compacted rather than decoded, to keep the field order of the schema
+	var data bytes.Buffer
+	if err := json.Compact(&data, []byte(response[start:end+1])); err != nil {
+		return nil, fmt.Errorf("invalid structured answer JSON: %w", err)
+	}
+	return data.Bytes(), nil
+}
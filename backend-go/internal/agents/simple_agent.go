@@ -2,24 +2,31 @@ package agents
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"strings"
+	"time"
 
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 )
 
+// simpleAnswerTokens bounds the answer of simple mode
+const simpleAnswerTokens = 500
+
 type SimpleAgent struct {
 	searchClient *tools.SearchClient
 	llmClient    *tools.LLMClient
+	evidence     *EvidencePolicy
 }
 
-func NewSimpleAgent(searchClient *tools.SearchClient, llmClient *tools.LLMClient) *SimpleAgent {
+func NewSimpleAgent(searchClient *tools.SearchClient, llmClient *tools.LLMClient, evidence *EvidencePolicy) *SimpleAgent {
 	return &SimpleAgent{
 		searchClient: searchClient,
 		llmClient:    llmClient,
+		evidence:     evidence,
 	}
 }
 
@@ -32,46 +39,32 @@ func (a *SimpleAgent) ProcessWithContext(
 	query string,
 	conversationHistory []models.Message,
 ) (*models.SearchResponse, error) {
-	log.Printf("Simple mode processing: %s (with context: %v)", query, len(conversationHistory) > 0)
+	logging.Printf(ctx, "Simple mode processing: %s (with context: %v)", query, len(conversationHistory) > 0)
 
 	searchQuery := query
 
 	// Step 1: Enhance query with context if available
 	if len(conversationHistory) > 0 {
-		var contextPrompt strings.Builder
-		contextPrompt.WriteString("Предыдущая беседа:\n")
-		start := len(conversationHistory) - 4
-		if start < 0 {
-			start = 0
-		}
-		for _, msg := range conversationHistory[start:] {
-			role := "Пользователь"
-			if msg.Role == "assistant" {
-				role = "Ассистент"
-			}
-			contextPrompt.WriteString(fmt.Sprintf("\n%s: %s\n", role, msg.Content))
-		}
-
-		enhancePrompt := fmt.Sprintf(`%s
-
-Текущий вопрос: %s
-
-Перефразируй текущий вопрос так, чтобы он был самодостаточным и включал важную информацию из контекста. Улучшенный поисковый запрос:`, contextPrompt.String(), query)
-
-		enhanced, err := a.llmClient.Complete(ctx, enhancePrompt, 0.3, 150)
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
 		if err == nil && enhanced != "" {
 			searchQuery = enhanced
-			log.Printf("Enhanced query: %s", searchQuery)
+			logging.Printf(ctx, "Enhanced query: %s", searchQuery)
 		}
 	}
 
 	// Step 2: Search for information
 	searchResults, err := a.searchClient.Search(ctx, searchQuery, 5, false)
-	if err != nil {
+	if err != nil && !errors.Is(err, tools.ErrNoResults) {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
 
-	if len(searchResults.Results) == 0 {
+	var results []models.TavilyResult
+	if err == nil {
+		results = searchResults.Results
+	}
+	results = withDocuments(ctx, searchQuery, results)
+
+	if len(results) == 0 {
 		return &models.SearchResponse{
 			Query:       query,
 			Mode:        "simple",
@@ -81,54 +74,69 @@ func (a *SimpleAgent) ProcessWithContext(
 		}, nil
 	}
 
-	// Step 3: Format search results for LLM
-	var sourcesContext strings.Builder
-	sourcesContext.WriteString("Найденная информация:\n\n")
-	for i, result := range searchResults.Results {
-		content := utils.SanitizeUTF8(result.Content)
-		sourcesContext.WriteString(fmt.Sprintf("Источник %d (%s):\n%s\n\n",
-			i+1, result.Title, content))
-	}
+	evidence := a.evidence.check(ctx, results)
 
-	// Step 4: Build LLM prompt
-	var promptBuilder strings.Builder
-	promptBuilder.WriteString("Ты поисковый ассистент. Дай краткий и точный ответ на вопрос пользователя на основе найденной информации.\n\n")
-
-	if len(conversationHistory) > 0 {
-		promptBuilder.WriteString("Контекст диалога:\n")
-		start := len(conversationHistory) - 4
-		if start < 0 {
-			start = 0
+	// Step 3: Build LLM prompt, with the search results cut to fit the context
+	// window
+	contents := make([]string, len(results))
+	for i, result := range results {
+		contents[i] = utils.SanitizeUTF8(result.Content)
+	}
+	llm := a.llmClient.ForTask(tools.TaskSynthesis)
+	prompt := fitPrompt(llm, contents, simpleAnswerTokens, func(contents []string) tools.Prompt {
+		var system strings.Builder
+		system.WriteString("Ты поисковый ассистент. Дай краткий и точный ответ на вопрос пользователя на основе найденной информации.\n\n")
+		system.WriteString(citationInstruction("ru"))
+		system.WriteString(calcInstruction("ru"))
+		system.WriteString(evidence.instruction("ru"))
+		system.WriteString(formatInstruction(ctx, "ru"))
+		system.WriteString(languageInstruction(ctx, "ru"))
+		system.WriteString(personaInstruction(ctx, "ru"))
+
+		var promptBuilder strings.Builder
+		if len(conversationHistory) > 0 {
+			promptBuilder.WriteString("Контекст диалога:\n")
+			for _, msg := range recentTurns(conversationHistory, 4) {
+				promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+			}
+			promptBuilder.WriteString("\n")
 		}
-		for _, msg := range conversationHistory[start:] {
-			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+
+		promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\n", query))
+		promptBuilder.WriteString("Найденная информация:\n\n")
+		for i, result := range results {
+			promptBuilder.WriteString(fmt.Sprintf("Источник %d (%s):\n%s\n\n",
+				i+1, result.Title, contents[i]))
 		}
-		promptBuilder.WriteString("\n")
+		promptBuilder.WriteString("Ответ:")
+		return tools.Prompt{System: strings.TrimSpace(system.String()), User: promptBuilder.String()}
+	})
+
+	// Step 4: Generate answer using LLM
+	synthesisStart := time.Now()
+	answer, err := completeAnswer(ctx, llm, "simple", prompt, 0.7, simpleAnswerTokens)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
+	degraded := errors.Is(err, tools.ErrLLMCircuitOpen)
+	if degraded {
+		answer, err = searchOnlyAnswer(ctx, results, "ru"), nil
 	}
-
-	promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\n", query))
-	promptBuilder.WriteString(sourcesContext.String())
-	promptBuilder.WriteString("Ответ:")
-
-	// Step 5: Generate answer using LLM
-	answer, err := a.llmClient.Complete(ctx, promptBuilder.String(), 0.7, 500)
 	if err != nil {
 		return nil, fmt.Errorf("LLM completion failed: %w", err)
 	}
 
-	// Step 6: Format sources with UTF-8 safety
-	sources := make([]models.Source, 0, len(searchResults.Results))
-	for _, result := range searchResults.Results {
+	// Step 5: Format sources with UTF-8 safety
+	sources := make([]models.Source, 0, len(results))
+	for _, result := range results {
 		snippet := utils.SanitizeUTF8(result.Snippet)
-		if len(snippet) > 200 {
-			snippet = utils.TruncateUTF8WithEllipsis(snippet, 200)
-		}
+		snippet = utils.TruncateRunesWithEllipsis(snippet, 200)
 		
 		sources = append(sources, models.Source{
 			Title:       utils.SanitizeUTF8(result.Title),
 			URL:         result.URL,
 			Snippet:     snippet,
 			Credibility: result.Score,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
 		})
 	}
 
@@ -137,6 +145,36 @@ func (a *SimpleAgent) ProcessWithContext(
 		Mode:        "simple",
 		Answer:      answer,
 		Sources:     sources,
+		Reasoning:   evidence.reasoning("ru"),
 		ContextUsed: len(conversationHistory) > 0,
+		Degraded:    degraded,
+		Evidence: &models.Evidence{
+			Score:       evidence.Score,
+			Credibility: evidence.Credibility,
+			Agreement:   evidence.Agreement,
+		},
 	}, nil
-}
\ No newline at end of file
+}
+
+func (a *SimpleAgent) enhanceQueryWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (string, error) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
+
+	var contextPrompt strings.Builder
+	contextPrompt.WriteString("Предыдущая беседа:\n")
+	for _, msg := range recentTurns(conversationHistory, 4) {
+		role := turnRole(msg)
+		contextPrompt.WriteString(fmt.Sprintf("\n%s: %s\n", role, msg.Content))
+	}
+
+	enhancePrompt := fmt.Sprintf(`%s
+
+Текущий вопрос: %s
+
+Перефразируй текущий вопрос так, чтобы он был самодостаточным и включал важную информацию из контекста. Улучшенный поисковый запрос:`, contextPrompt.String(), query)
+
+	return a.llmClient.ForTask(tools.TaskQueryEnhance).Complete(ctx, enhancePrompt, 0.3, 150)
+}
@@ -3,25 +3,29 @@ package agents
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
+	"time"
 
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/scrapers"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 )
 
 type SocialAgent struct {
 	socialScraper *scrapers.SocialScraper
 	llmClient     *tools.LLMClient
 	reranker      *tools.BM25Reranker
+	evidence      *EvidencePolicy
 }
 
-func NewSocialAgent(llmClient *tools.LLMClient) *SocialAgent {
+func NewSocialAgent(llmClient *tools.LLMClient, evidence *EvidencePolicy) *SocialAgent {
 	return &SocialAgent{
 		socialScraper: scrapers.NewSocialScraper(),
 		llmClient:     llmClient,
 		reranker:      tools.NewBM25Reranker(),
+		evidence:      evidence,
 	}
 }
 
@@ -34,13 +38,13 @@ func (a *SocialAgent) ProcessWithContext(
 	query string,
 	conversationHistory []models.Message,
 ) (*models.SearchResponse, error) {
-	log.Printf("Pro Social mode processing: %s", query)
+	logging.Printf(ctx, "Pro Social mode processing: %s", query)
 
-	reasoningSteps := []string{"🗣️ Запущен режим Social - анализ мнений и дискуссий"}
+	reasoningSteps := appendStep(ctx, nil, "🗣️ Запущен режим Social - анализ мнений и дискуссий")
 
 	searchQuery := query
 	if len(conversationHistory) > 0 {
-		reasoningSteps = append(reasoningSteps, "Адаптирую запрос с учетом контекста...")
+		reasoningSteps = appendStep(ctx, reasoningSteps, "Адаптирую запрос с учетом контекста...")
 		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
 		if err == nil && enhanced != "" {
 			searchQuery = enhanced
@@ -48,38 +52,38 @@ func (a *SocialAgent) ProcessWithContext(
 	}
 
 	// Параллельный поиск в социальных сетях
-	reasoningSteps = append(reasoningSteps, "Ищу мнения в Reddit, Habr, Twitter...")
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Ищу мнения в Reddit, Habr, Twitter...")
 
 	allResults := make([]models.TavilyResult, 0)
 
 	// Reddit
 	redditResults, err := a.socialScraper.SearchReddit(ctx, searchQuery, 5)
 	if err != nil {
-		log.Printf("Reddit search failed: %v", err)
+		logging.Printf(ctx, "Reddit search failed: %v", err)
 	} else {
 		allResults = append(allResults, redditResults...)
-		reasoningSteps = append(reasoningSteps, fmt.Sprintf("✓ Reddit: %d обсуждений", len(redditResults)))
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Reddit: %d обсуждений", len(redditResults)))
 	}
 
 	// Habr
 	habrResults, err := a.socialScraper.SearchHabr(ctx, searchQuery, 5)
 	if err != nil {
-		log.Printf("Habr search failed: %v", err)
+		logging.Printf(ctx, "Habr search failed: %v", err)
 	} else {
 		allResults = append(allResults, habrResults...)
-		reasoningSteps = append(reasoningSteps, fmt.Sprintf("✓ Habr: %d статей", len(habrResults)))
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Habr: %d статей", len(habrResults)))
 	}
 
 	// Twitter
 	twitterResults, err := a.socialScraper.SearchTwitter(ctx, searchQuery, 5)
 	if err != nil {
-		log.Printf("Twitter search failed: %v", err)
+		logging.Printf(ctx, "Twitter search failed: %v", err)
 	} else {
 		allResults = append(allResults, twitterResults...)
-		reasoningSteps = append(reasoningSteps, fmt.Sprintf("✓ Twitter: %d твитов", len(twitterResults)))
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Twitter: %d твитов", len(twitterResults)))
 	}
 
-	if len(allResults) == 0 {
+	if len(allResults) == 0 && !hasDocuments(ctx) {
 		return &models.SearchResponse{
 			Query:     query,
 			Mode:      "pro-social",
@@ -89,22 +93,30 @@ func (a *SocialAgent) ProcessWithContext(
 		}, nil
 	}
 
-	reasoningSteps = append(reasoningSteps, fmt.Sprintf("Собрано %d источников, применяю reranking...", len(allResults)))
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("Собрано %d источников, применяю reranking...", len(allResults)))
 
 	// Rerank
+	rerankStart := time.Now()
 	allResults = a.reranker.Rerank(searchQuery, allResults)
+	tools.TrackTime(ctx, tools.TimingRerank, rerankStart)
 
 	// Take top 10
 	if len(allResults) > 10 {
 		allResults = allResults[:10]
 	}
+	allResults = withDocuments(ctx, searchQuery, allResults)
 
 	// Analyze sentiment
-	reasoningSteps = append(reasoningSteps, "Анализирую тональность и общее мнение...")
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Анализирую тональность и общее мнение...")
+
+	evidence := a.evidence.check(ctx, allResults)
+	if step := evidence.reasoning("ru"); step != "" {
+		reasoningSteps = appendStep(ctx, reasoningSteps, step)
+	}
 
 	// Build LLM prompt
-	var promptBuilder strings.Builder
-	promptBuilder.WriteString(`Ты аналитик социальных медиа. Проанализируй мнения из разных источников.
+	var system strings.Builder
+	system.WriteString(`Ты аналитик социальных медиа. Проанализируй мнения из разных источников.
 
 Твоя задача:
 1. Обобщить основные мнения и точки зрения
@@ -113,10 +125,16 @@ func (a *SocialAgent) ProcessWithContext(
 4. Отметить наиболее популярные аргументы
 
 `)
+	system.WriteString(citationInstruction("ru"))
+	system.WriteString(evidence.instruction("ru"))
+	system.WriteString(formatInstruction(ctx, "ru"))
+	system.WriteString(languageInstruction(ctx, "ru"))
+	system.WriteString(personaInstruction(ctx, "ru"))
 
+	var promptBuilder strings.Builder
 	if len(conversationHistory) > 0 {
-		promptBuilder.WriteString("\nКонтекст диалога:\n")
-		for _, msg := range conversationHistory[max(0, len(conversationHistory)-4):] {
+		promptBuilder.WriteString("Контекст диалога:\n")
+		for _, msg := range recentTurns(conversationHistory, 4) {
 			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
 		}
 		promptBuilder.WriteString("\n")
@@ -129,18 +147,20 @@ func (a *SocialAgent) ProcessWithContext(
 		if i >= 8 {
 			break
 		}
-		content := result.Content
-		if len(content) > 500 {
-			content = content[:500]
-		}
+		content := utils.TruncateRunes(result.Content, 500)
 		promptBuilder.WriteString(fmt.Sprintf("Источник %d (%s):\n%s\n\n", i+1, result.Title, content))
 	}
 
-	promptBuilder.WriteString("\nАнализ мнений:")
+	promptBuilder.WriteString("Анализ мнений:")
 
-	reasoningSteps = append(reasoningSteps, "Формирую итоговый анализ...")
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Формирую итоговый анализ...")
 
-	answer, err := a.llmClient.Complete(ctx, promptBuilder.String(), 0.7, 1000)
+	synthesisStart := time.Now()
+	answer, err := a.llmClient.ForTask(tools.TaskSynthesis).CompletePrompt(ctx, tools.Prompt{
+		System: strings.TrimSpace(system.String()),
+		User:   promptBuilder.String(),
+	}, 0.7, 1000)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
 	if err != nil {
 		return nil, fmt.Errorf("LLM completion failed: %w", err)
 	}
@@ -151,15 +171,14 @@ func (a *SocialAgent) ProcessWithContext(
 		if i >= 8 {
 			break
 		}
-		snippet := result.Content
-		if len(snippet) > 200 {
-			snippet = snippet[:200] + "..."
-		}
+		snippet := utils.TruncateRunesWithEllipsis(result.Content, 200)
 		sources = append(sources, models.Source{
 			Title:       result.Title,
 			URL:         result.URL,
 			Snippet:     snippet,
 			Credibility: result.Score,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
 		})
 	}
 
@@ -178,13 +197,12 @@ func (a *SocialAgent) enhanceQueryWithContext(
 	query string,
 	conversationHistory []models.Message,
 ) (string, error) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
+
 	var contextPrompt strings.Builder
 	contextPrompt.WriteString("Предыдущая беседа:\n")
-	for _, msg := range conversationHistory[max(0, len(conversationHistory)-4):] {
-		role := "Пользователь"
-		if msg.Role == "assistant" {
-			role = "Ассистент"
-		}
+	for _, msg := range recentTurns(conversationHistory, 4) {
+		role := turnRole(msg)
 		contextPrompt.WriteString(fmt.Sprintf("%s: %s\n", role, msg.Content))
 	}
 
@@ -194,7 +212,7 @@ func (a *SocialAgent) enhanceQueryWithContext(
 
 Перефразируй текущий вопрос так, чтобы он был самодостаточным для поиска в социальных сетях. Улучшенный запрос:`, contextPrompt.String(), query)
 
-	return a.llmClient.Complete(ctx, enhancePrompt, 0.3, 150)
+	return a.llmClient.ForTask(tools.TaskQueryEnhance).Complete(ctx, enhancePrompt, 0.3, 150)
 }
 
 func max(a, b int) int {
@@ -0,0 +1,58 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+)
+
+// TokenRecorder receives the text of an answer in chunks as the LLM writes
+// it, with the mode of the agent writing it. The text is the raw answer: the
+// final one may differ after post-processing (citations, verification,
+// format). It may be called from several goroutines.
+type TokenRecorder func(mode, text string)
+
+type tokenRecorderKey struct{}
+
+// WithTokenRecorder makes the Simple and Pro agents handling ctx stream the
+// answers they write to rec
+func WithTokenRecorder(ctx context.Context, rec TokenRecorder) context.Context {
+	return context.WithValue(ctx, tokenRecorderKey{}, rec)
+}
+
+func tokenRecorderFromContext(ctx context.Context) TokenRecorder {
+	rec, _ := ctx.Value(tokenRecorderKey{}).(TokenRecorder)
+	return rec
+}
+
+// completeAnswer writes an answer from prompt, streaming it to the token
+// recorder of ctx when there is one
+func completeAnswer(
+	ctx context.Context,
+	llmClient *tools.LLMClient,
+	mode string,
+	prompt tools.Prompt,
+	temperature float32,
+	maxTokens int,
+) (string, error) {
+	rec := tokenRecorderFromContext(ctx)
+	if rec == nil {
+		return llmClient.CompletePrompt(ctx, prompt, temperature, maxTokens)
+	}
+
+	chunks, err := llmClient.CompleteStream(ctx, prompt, temperature, maxTokens)
+	if err != nil {
+		return "", err
+	}
+	var answer strings.Builder
+	for text := range chunks {
+		answer.WriteString(text)
+		rec(mode, text)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("answer stream: %w", err)
+	}
+	return answer.String(), nil
+}
@@ -0,0 +1,110 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+const (
+	// sourceSummaryTimeout bounds each per-source summary; a source whose
+	// summary is late goes into the synthesis prompt truncated instead
+	sourceSummaryTimeout = 8 * time.Second
+	// sourceSummaryTokens keeps a summary to a few sentences
+	sourceSummaryTokens = 120
+	// summaryInputRunes is how much of a source the summarizer reads
+	summaryInputRunes = 4000
+	// sourceContextRunes is how much of a raw source goes into the synthesis
+	// prompt without a summary
+	sourceContextRunes = 800
+)
+
+// sourceText is the text of a result: the fetched page when there is one,
+// the search snippet otherwise
+func sourceText(result models.TavilyResult) string {
+	if result.RawContent != "" {
+		return utils.SanitizeUTF8(result.RawContent)
+	}
+	return utils.SanitizeUTF8(result.Content)
+}
+
+// sourceContexts returns what the synthesis prompt shows of each result. With
+// summaries enabled every result is summarized with regard to the query in
+// parallel (map), and the synthesis prompt reads the summaries (reduce); a
+// result whose summary failed keeps its first sourceContextRunes runes.
+func (a *ProAgent) sourceContexts(ctx context.Context, query string, results []models.TavilyResult, lang string) ([]string, int) {
+	contexts := make([]string, len(results))
+	for i, result := range results {
+		contexts[i] = utils.TruncateRunesWithEllipsis(sourceText(result), sourceContextRunes)
+	}
+	if !a.summarize || len(results) == 0 {
+		return contexts, 0
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	summarized := 0
+	for i, result := range results {
+		wg.Add(1)
+		go func(i int, result models.TavilyResult) {
+			defer wg.Done()
+			summary, err := a.summarizeSource(ctx, query, result, lang)
+			if err != nil {
+				logging.Printf(ctx, "⚠️  Summary of %s failed, using its text: %v", result.URL, err)
+				return
+			}
+			mu.Lock()
+			contexts[i] = summary
+			summarized++
+			mu.Unlock()
+		}(i, result)
+	}
+	wg.Wait()
+	return contexts, summarized
+}
+
+// summarizeSource condenses one source to the facts that bear on the query
+func (a *ProAgent) summarizeSource(ctx context.Context, query string, result models.TavilyResult, lang string) (string, error) {
+	text := utils.TruncateRunesWithEllipsis(sourceText(result), summaryInputRunes)
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("empty source")
+	}
+
+	var prompt string
+	if lang == "ru" {
+		prompt = fmt.Sprintf(`Вопрос: %s
+
+Источник (%s):
+%s
+
+Перескажи в 2-3 предложениях только то, что в источнике относится к вопросу: факты, числа, даты, имена. Ничего не добавляй от себя. Если источник не отвечает на вопрос, скажи это одним предложением.`,
+			query, utils.SanitizeUTF8(result.Title), text)
+	} else {
+		prompt = fmt.Sprintf(`Question: %s
+
+Source (%s):
+%s
+
+In 2-3 sentences, restate only what the source says that bears on the question: facts, numbers, dates, names. Add nothing of your own. If the source does not answer the question, say so in one sentence.`,
+			query, utils.SanitizeUTF8(result.Title), text)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sourceSummaryTimeout)
+	defer cancel()
+	summary, err := a.llmClient.ForTask(tools.TaskSummary).Complete(ctx, prompt, 0.1, sourceSummaryTokens)
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
@@ -0,0 +1,90 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+const (
+	// summaryRole marks the summary of the earlier turns of a conversation,
+	// the first message of its history
+	summaryRole = "system"
+	// maxSummaryChars bounds the summary kept on a session
+	maxSummaryChars = 2000
+	// summarizeTimeout bounds the summarization LLM call
+	summarizeTimeout = 20 * time.Second
+)
+
+// SummaryMessage is the history message carrying the summary of the earlier
+// turns of a session; it goes before the recent turns
+func SummaryMessage(summary string) models.Message {
+	return models.Message{Role: summaryRole, Content: summary}
+}
+
+// recentTurns returns the last n turns of a conversation, preceded by its
+// summary when the history starts with one
+func recentTurns(conversationHistory []models.Message, n int) []models.Message {
+	if len(conversationHistory) <= n {
+		return conversationHistory
+	}
+	recent := conversationHistory[len(conversationHistory)-n:]
+	if conversationHistory[0].Role != summaryRole {
+		return recent
+	}
+	return append([]models.Message{conversationHistory[0]}, recent...)
+}
+
+// turnRole is the speaker of a history message in Russian prompts
+func turnRole(msg models.Message) string {
+	switch msg.Role {
+	case "assistant":
+		return "Ассистент"
+	case summaryRole:
+		return "Сводка ранее"
+	default:
+		return "Пользователь"
+	}
+}
+
+// SummarizeConversation folds turns into the summary of a conversation
+// (empty for the first fold) and returns the new summary, in the language
+// of the conversation. It keeps what later questions may refer to: topics,
+// entities, numbers, conclusions and the user's stated preferences. The
+// turns are redacted like any history before they reach the LLM.
+func (r *RouterAgent) SummarizeConversation(ctx context.Context, summary string, turns []models.Message) (string, error) {
+	var prompt strings.Builder
+	prompt.WriteString(`Обнови краткую память диалога пользователя с поисковым ассистентом.
+Сохрани то, на что могут ссылаться следующие вопросы: темы, названия и имена, числа и даты, выводы ответов, предпочтения и условия пользователя. Опусти ссылки на источники, форматирование и повторы.
+Пиши сжато, до 10 пунктов, на языке диалога.
+
+`)
+	if summary != "" {
+		prompt.WriteString("Текущая память:\n")
+		prompt.WriteString(summary)
+		prompt.WriteString("\n\n")
+	}
+	prompt.WriteString("Новые реплики:\n")
+	for _, msg := range turns {
+		content, _ := r.guard.Redact(msg.Content)
+		prompt.WriteString(fmt.Sprintf("%s: %s\n", turnRole(msg), utils.TruncateRunes(content, 2000)))
+	}
+	prompt.WriteString("\nВерни ТОЛЬКО обновлённую память.")
+
+	summarizeCtx, cancel := context.WithTimeout(ctx, summarizeTimeout)
+	defer cancel()
+	updated, err := r.llmClient.ForTask(tools.TaskSummary).Complete(summarizeCtx, prompt.String(), 0.2, 600)
+	if err != nil {
+		return "", fmt.Errorf("summarize conversation: %w", err)
+	}
+	updated = strings.TrimSpace(updated)
+	if updated == "" {
+		return "", fmt.Errorf("summarize conversation: empty summary")
+	}
+	return utils.TruncateRunesWithEllipsis(updated, maxSummaryChars), nil
+}
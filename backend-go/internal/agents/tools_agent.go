@@ -0,0 +1,484 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/scrapers"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+const (
+	toolsTimeout = 60 * time.Second
+
+	// toolsMaxTurns bounds the model turns that may call tools and
+	// toolsMaxCalls the tool calls of all turns; then the model has to answer
+	toolsMaxTurns = 6
+	toolsMaxCalls = 10
+
+	// toolResultContent bounds the text of one source in a tool result
+	toolResultContent = 600
+	// toolPageContent bounds the text of a fetched page in a tool result
+	toolPageContent = 3000
+)
+
+// ToolsAgent lets the model run the research itself: search, scrapers, a
+// calculator and page fetching are OpenAI tools, and the model decides which
+// to call and in what order instead of following Pro's fixed pipeline. Every
+// source a tool returns gets a number the answer cites.
+type ToolsAgent struct {
+	searchClient      *tools.SearchClient
+	llmClient         *tools.LLMClient
+	pages             *tools.PageFetcher
+	academic          *scrapers.AcademicScraper
+	finance           *scrapers.FinanceScraper
+	instant           *scrapers.InstantScraper
+	credibilityScorer *tools.CredibilityScorer
+	evidence          *EvidencePolicy
+}
+
+func NewToolsAgent(
+	searchClient *tools.SearchClient,
+	llmClient *tools.LLMClient,
+	pages *tools.PageFetcher,
+	evidence *EvidencePolicy,
+) *ToolsAgent {
+	return &ToolsAgent{
+		searchClient:      searchClient,
+		llmClient:         llmClient,
+		pages:             pages,
+		academic:          scrapers.NewAcademicScraper(),
+		finance:           scrapers.NewFinanceScraper(),
+		instant:           scrapers.NewInstantScraper(),
+		credibilityScorer: tools.NewCredibilityScorer(),
+		evidence:          evidence,
+	}
+}
+
+// toolSpecs are the tools offered to the model
+func (a *ToolsAgent) toolSpecs() []tools.ToolSpec {
+	query := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{"type": "string", "description": description},
+			},
+			"required": []string{"query"},
+		}
+	}
+	return []tools.ToolSpec{
+		{
+			Name:        "web_search",
+			Description: "Поиск в интернете. Возвращает до 5 пронумерованных источников с фрагментами текста.",
+			Parameters:  query("Поисковый запрос"),
+		},
+		{
+			Name:        "fetch_url",
+			Description: "Загружает страницу по URL и возвращает её текст, например чтобы прочитать найденный источник целиком.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"url": map[string]interface{}{"type": "string", "description": "Адрес страницы (http или https)"},
+				},
+				"required": []string{"url"},
+			},
+		},
+		{
+			Name:        "calculator",
+			Description: "Вычисляет арифметическое выражение: + - * / % ^, скобки, pi, e, sqrt, abs, ln, log, exp, round. Используй для любых расчётов вместо вычислений в уме.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"expression": map[string]interface{}{"type": "string", "description": "Выражение, например (1200 - 950) / 950 * 100"},
+				},
+				"required": []string{"expression"},
+			},
+		},
+		{
+			Name:        "arxiv_search",
+			Description: "Поиск научных статей в arXiv. Запрос лучше писать по-английски.",
+			Parameters:  query("Поисковый запрос"),
+		},
+		{
+			Name:        "finance_search",
+			Description: "Финансовые новости и котировки из Yahoo Finance по тикеру или компании.",
+			Parameters:  query("Тикер или название компании"),
+		},
+		{
+			Name:        "weather",
+			Description: "Текущая погода и прогноз на завтра для населённого пункта (Open-Meteo).",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"place": map[string]interface{}{"type": "string", "description": "Название города"},
+				},
+				"required": []string{"place"},
+			},
+		},
+		{
+			Name:        "exchange_rate",
+			Description: "Официальный курс ЦБ РФ на сегодня: сколько стоит 1 единица одной валюты в другой.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"from": map[string]interface{}{"type": "string", "description": "Код валюты ISO 4217, например USD"},
+					"to":   map[string]interface{}{"type": "string", "description": "Код валюты ISO 4217, например RUB"},
+				},
+				"required": []string{"from", "to"},
+			},
+		},
+	}
+}
+
+func (a *ToolsAgent) Process(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return a.ProcessWithContext(ctx, query, nil)
+}
+
+func (a *ToolsAgent) ProcessWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	budgetCtx, cancel := context.WithTimeout(ctx, toolsTimeout)
+	defer cancel()
+
+	result, err := a.process(budgetCtx, query, conversationHistory)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(budgetCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: pro-tools mode ran out of its %s budget: %w", tools.ErrBudgetExceeded, toolsTimeout, err)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// toolsRun is the state of one query: the numbered sources found by the tools
+type toolsRun struct {
+	results []models.TavilyResult
+	seen    map[string]int // URL -> index into results
+}
+
+func (a *ToolsAgent) process(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	logging.Printf(ctx, "Pro Tools mode processing: %s", query)
+	reasoningSteps := appendStep(ctx, nil, "🛠️ Запущен режим Tools - модель сама выбирает инструменты")
+
+	run := &toolsRun{seen: make(map[string]int)}
+	var user strings.Builder
+	if len(conversationHistory) > 0 {
+		user.WriteString("Контекст диалога:\n")
+		for _, msg := range recentTurns(conversationHistory, 4) {
+			user.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+		}
+		user.WriteString("\n")
+	}
+	user.WriteString(fmt.Sprintf("Вопрос: %s\n", query))
+	if documents := withDocuments(ctx, query, nil); len(documents) > 0 {
+		user.WriteString("\nЗагруженные пользователем документы:\n\n")
+		user.WriteString(run.add(documents))
+	}
+
+	chat := a.llmClient.ForTask(tools.TaskSynthesis).NewToolChat(toolsSystemPrompt(ctx), user.String(), a.toolSpecs())
+	answer, calls, err := chat.Run(ctx, 0.3, 1500, toolsMaxTurns, toolsMaxCalls, func(ctx context.Context, call tools.ToolCall) (string, error) {
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("🔧 %s %s", call.Name, toolArgumentsSummary(call.Arguments)))
+		output, err := a.runTool(ctx, run, call)
+		if err != nil {
+			logging.Printf(ctx, "⚠️  Tool %s failed: %v", call.Name, err)
+			reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("⚠️ %s не сработал: %v", call.Name, err))
+		}
+		return output, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("📚 Вызовов инструментов: %d, источников: %d", len(calls), len(run.results)))
+
+	// Weak evidence gets one more turn to restate the answer with caveats
+	if len(run.results) > 0 {
+		evidence := a.evidence.check(ctx, run.results)
+		if step := evidence.reasoning("ru"); step != "" {
+			reasoningSteps = appendStep(ctx, reasoningSteps, step)
+		}
+		if instruction := evidence.instruction("ru"); instruction != "" {
+			chat.AddUserMessage(instruction + "Перепиши ответ с учётом этого.")
+			text, _, err := chat.Next(ctx, 0.3, 1500, false)
+			if err != nil {
+				return nil, fmt.Errorf("LLM completion failed: %w", err)
+			}
+			answer = text
+		}
+	}
+	if strings.TrimSpace(answer) == "" {
+		return nil, fmt.Errorf("model returned no answer")
+	}
+
+	sources := make([]models.Source, 0, len(run.results))
+	for _, result := range run.results {
+		sources = append(sources, models.Source{
+			Title:       utils.SanitizeUTF8(result.Title),
+			URL:         result.URL,
+			Snippet:     utils.TruncateRunesWithEllipsis(utils.SanitizeUTF8(result.Snippet), 200),
+			Credibility: result.Credibility,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
+		})
+	}
+	logging.Printf(ctx, "🛠️ Tools mode answered after %d tool calls with %d sources", len(calls), len(sources))
+
+	return &models.SearchResponse{
+		Query:       query,
+		Mode:        "pro-tools",
+		Answer:      answer,
+		Sources:     sources,
+		Reasoning:   strings.Join(reasoningSteps, "\n"),
+		ContextUsed: len(conversationHistory) > 0,
+	}, nil
+}
+
+// toolsSystemPrompt explains the tools strategy and the answer rules
+func toolsSystemPrompt(ctx context.Context) string {
+	var b strings.Builder
+	b.WriteString(`Ты исследовательский ассистент с инструментами. Сам реши, какие инструменты вызвать и в каком порядке, чтобы точно ответить на вопрос:
+- начинай с web_search; для научных тем используй arxiv_search, для котировок и компаний - finance_search
+- если фрагмента недостаточно, прочитай источник целиком через fetch_url
+- любые расчёты делай через calculator
+- можно вызывать несколько инструментов сразу, если они не зависят друг от друга
+- не повторяй одинаковые запросы; когда информации достаточно, переходи к ответу
+
+Источники в результатах инструментов пронумерованы: [1], [2] и т.д. Номера сквозные для всего диалога.
+Отвечай на языке вопроса, подробно и по существу. Укажи, если источники противоречат друг другу или информации недостаточно.
+`)
+	b.WriteString(citationInstruction("ru"))
+	b.WriteString(formatInstruction(ctx, "ru"))
+	b.WriteString(languageInstruction(ctx, "ru"))
+	b.WriteString(personaInstruction(ctx, "ru"))
+	return b.String()
+}
+
+// runTool executes one tool call and returns its result for the model
+func (a *ToolsAgent) runTool(ctx context.Context, run *toolsRun, call tools.ToolCall) (string, error) {
+	var args struct {
+		Query      string `json:"query"`
+		URL        string `json:"url"`
+		Expression string `json:"expression"`
+		Place      string `json:"place"`
+		From       string `json:"from"`
+		To         string `json:"to"`
+	}
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	switch call.Name {
+	case "web_search":
+		response, err := a.searchClient.SearchWithOptions(ctx, args.Query, 5, false, searchOptions(constraintsFromContext(ctx)))
+		if err != nil {
+			return "", err
+		}
+		return run.add(a.score(response.Results)), nil
+	case "arxiv_search":
+		results, err := a.academic.SearchArxiv(ctx, args.Query, 3)
+		if err != nil {
+			return "", err
+		}
+		return run.add(a.score(results)), nil
+	case "finance_search":
+		results, err := a.finance.SearchYahooFinance(ctx, args.Query, 5)
+		if err != nil {
+			return "", err
+		}
+		return run.add(a.score(results)), nil
+	case "fetch_url":
+		return a.fetchURL(ctx, run, args.URL)
+	case "calculator":
+		value, err := tools.Calculate(args.Expression)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s = %s", args.Expression, strconv.FormatFloat(value, 'g', 12, 64)), nil
+	case "weather":
+		return a.weather(ctx, run, args.Place)
+	case "exchange_rate":
+		return a.exchangeRate(ctx, run, strings.ToUpper(args.From), strings.ToUpper(args.To))
+	default:
+		return "", fmt.Errorf("unknown tool %s", call.Name)
+	}
+}
+
+// score fills in the credibility of results without reordering them
+func (a *ToolsAgent) score(results []models.TavilyResult) []models.TavilyResult {
+	for i := range results {
+		results[i].Credibility = a.credibilityScorer.ScoreSource(results[i])
+	}
+	return results
+}
+
+// add numbers new results after the sources found so far and lists them for
+// the model; a URL found again keeps its number
+func (r *toolsRun) add(results []models.TavilyResult) string {
+	if len(results) == 0 {
+		return "Ничего не найдено."
+	}
+	var b strings.Builder
+	for _, result := range results {
+		key := strings.TrimSuffix(result.URL, "/")
+		index, ok := r.seen[key]
+		if !ok || key == "" {
+			index = len(r.results)
+			r.seen[key] = index
+			r.results = append(r.results, result)
+		}
+		content := result.Content
+		if result.RawContent != "" {
+			content = result.RawContent
+		}
+		content = utils.TruncateRunesWithEllipsis(utils.SanitizeUTF8(content), toolResultContent)
+		b.WriteString(fmt.Sprintf("[%d] %s (%s)\n%s\n\n", index+1, result.Title, result.URL, content))
+	}
+	return b.String()
+}
+
+// fetchURL reads a public page; the page becomes a source, or fills in the
+// full text of the source it already is
+func (a *ToolsAgent) fetchURL(ctx context.Context, run *toolsRun, rawURL string) (string, error) {
+	if a.pages == nil {
+		return "", fmt.Errorf("page fetching is not available")
+	}
+	if err := checkHTTPURL(rawURL); err != nil {
+		return "", err
+	}
+	page, err := a.pages.Fetch(ctx, rawURL)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(page.Text) == "" {
+		return "", fmt.Errorf("no readable text on the page")
+	}
+
+	key := strings.TrimSuffix(rawURL, "/")
+	index, ok := run.seen[key]
+	if ok {
+		run.results[index].RawContent = page.Text
+	} else {
+		title := page.Title
+		if title == "" {
+			title = page.Metadata.Title
+		}
+		if title == "" {
+			title = page.URL
+		}
+		result := models.TavilyResult{
+			Title:      title,
+			URL:        rawURL,
+			Snippet:    utils.TruncateRunes(page.Text, 300),
+			Content:    page.Text,
+			RawContent: page.Text,
+			FetchedAt:  page.FetchedAt,
+		}
+		result.Credibility = a.credibilityScorer.ScoreSource(result)
+		index = len(run.results)
+		run.seen[key] = index
+		run.results = append(run.results, result)
+	}
+	text := utils.TruncateRunesWithEllipsis(utils.SanitizeUTF8(page.Text), toolPageContent)
+	return fmt.Sprintf("[%d] %s (%s)\n%s", index+1, run.results[index].Title, rawURL, text), nil
+}
+
+// checkHTTPURL rejects URLs of the model that are not absolute http(s) URLs;
+// the page fetcher itself refuses to connect to non-public addresses
+func checkHTTPURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Hostname() == "" {
+		return fmt.Errorf("only http and https URLs can be fetched")
+	}
+	return nil
+}
+
+func (a *ToolsAgent) weather(ctx context.Context, run *toolsRun, place string) (string, error) {
+	weather, err := a.instant.Weather(ctx, place, detectLanguage(place))
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	current := weather.Current
+	b.WriteString(fmt.Sprintf("%s, %s: сейчас %+.0f °C (ощущается как %+.0f °C), %s, ветер %.0f м/с, влажность %.0f%%.",
+		weather.Place.Name, weather.Place.Country, current.Temperature, current.ApparentTemperature,
+		weatherDescription(current.WeatherCode, "ru"), current.WindSpeed, current.Humidity))
+	daily := weather.Daily
+	for i, day := range daily.Time {
+		if i >= len(daily.TemperatureMin) || i >= len(daily.TemperatureMax) || i >= len(daily.WeatherCode) {
+			break
+		}
+		b.WriteString(fmt.Sprintf(" %s: от %+.0f до %+.0f °C, %s.", day,
+			daily.TemperatureMin[i], daily.TemperatureMax[i], weatherDescription(daily.WeatherCode[i], "ru")))
+	}
+	return run.add([]models.TavilyResult{{
+		Title:       "Open-Meteo: " + weather.Place.Name,
+		URL:         fmt.Sprintf("https://open-meteo.com/en/docs#latitude=%.4f&longitude=%.4f", weather.Place.Latitude, weather.Place.Longitude),
+		Content:     b.String(),
+		Snippet:     b.String(),
+		Credibility: 1,
+		FetchedAt:   time.Now().Unix(),
+	}}), nil
+}
+
+func (a *ToolsAgent) exchangeRate(ctx context.Context, run *toolsRun, from, to string) (string, error) {
+	rates, err := a.instant.ExchangeRates(ctx)
+	if err != nil {
+		return "", err
+	}
+	fromRate, _, ok := rates.PerUnit(from)
+	if !ok {
+		return "", fmt.Errorf("no rate for %s", from)
+	}
+	toRate, _, ok := rates.PerUnit(to)
+	if !ok {
+		return "", fmt.Errorf("no rate for %s", to)
+	}
+
+	content := fmt.Sprintf("Курс ЦБ РФ на %s: 1 %s = %s %s.", rates.Date, from,
+		strconv.FormatFloat(fromRate/toRate, 'f', 4, 64), to)
+	return run.add([]models.TavilyResult{{
+		Title:       "Банк России: официальные курсы валют",
+		URL:         "https://www.cbr.ru/currency_base/daily/",
+		Content:     content,
+		Snippet:     content,
+		Credibility: 1,
+		FetchedAt:   time.Now().Unix(),
+	}}), nil
+}
+
+// toolArgumentsSummary shortens the JSON arguments of a call for reasoning steps
+func toolArgumentsSummary(arguments string) string {
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return ""
+	}
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]string, 0, len(args))
+	for _, name := range names {
+		values = append(values, fmt.Sprint(args[name]))
+	}
+	return utils.TruncateRunesWithEllipsis(strings.Join(values, ", "), 120)
+}
@@ -0,0 +1,40 @@
+package agents
+
+import "context"
+
+// StepRecorder receives reasoning steps as soon as an agent produces them.
+// It may be called from several goroutines (race mode).
+type StepRecorder func(step string)
+
+type stepRecorderKey struct{}
+
+// WithStepRecorder makes the agents handling ctx report each reasoning step to
+// rec, after the recorders ctx already has (stored trace, live stream)
+func WithStepRecorder(ctx context.Context, rec StepRecorder) context.Context {
+	if rec == nil {
+		return ctx
+	}
+	if outer := stepRecorderFromContext(ctx); outer != nil {
+		inner := rec
+		rec = func(step string) {
+			outer(step)
+			inner(step)
+		}
+	}
+	return context.WithValue(ctx, stepRecorderKey{}, rec)
+}
+
+func stepRecorderFromContext(ctx context.Context) StepRecorder {
+	rec, _ := ctx.Value(stepRecorderKey{}).(StepRecorder)
+	return rec
+}
+
+// appendStep reports steps to the context's recorder and appends them to steps
+func appendStep(ctx context.Context, steps []string, step ...string) []string {
+	if rec := stepRecorderFromContext(ctx); rec != nil {
+		for _, s := range step {
+			rec(s)
+		}
+	}
+	return append(steps, step...)
+}
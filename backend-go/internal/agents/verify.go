@@ -0,0 +1,395 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+// Answer verification (ANSWER_VERIFICATION)
+const (
+	VerificationOff   = "off"
+	VerificationFlag  = "flag"  // mark unsupported sentences
+	VerificationStrip = "strip" // remove unsupported sentences
+)
+
+const (
+	// verifyTimeout bounds the verification LLM call
+	verifyTimeout = 20 * time.Second
+	// maxVerifySources and verifySourceChars bound the source text the
+	// sentences are checked against
+	maxVerifySources  = 8
+	verifySourceChars = 1500
+	// maxVerifySentences keeps the verdicts of long answers within one call;
+	// later sentences are left as they are
+	maxVerifySentences = 40
+)
+
+var (
+	// listItemPattern matches the marker of a list item or quote
+	listItemPattern = regexp.MustCompile(`^\s*(?:[-*•+>]|\d{1,2}[.)])\s+`)
+	// emptyListItemPattern matches a list item whose sentences were all removed
+	emptyListItemPattern = regexp.MustCompile(`^\s*(?:[-*•+>]|\d{1,2}[.)])\s*$`)
+	// trailingCitationsPattern matches citation markers written after a full stop
+	trailingCitationsPattern = regexp.MustCompile(`^(?:\s*\[\d{1,2}(?:\s*[,;]\s*\d{1,2})*\])+`)
+)
+
+// sentenceAbbreviations end with a full stop without ending the sentence
+var sentenceAbbreviations = map[string]bool{
+	"mr": true, "mrs": true, "dr": true, "st": true, "vs": true, "etc": true, "no": true,
+	"им": true, "ул": true, "гг": true, "др": true, "см": true, "стр": true, "млн": true, "млрд": true, "тыс": true,
+}
+
+type sourceRecorderKey struct{}
+
+// sourceRecorder collects the sources the agents of one request wrote their
+// answers from
+type sourceRecorder struct {
+	mu      sync.Mutex
+	results []models.TavilyResult
+	seen    map[string]bool
+}
+
+// withSourceRecorder starts collecting the sources of the answer to ctx's
+// request for its verification
+func withSourceRecorder(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sourceRecorderKey{}, &sourceRecorder{seen: make(map[string]bool)})
+}
+
+// recordSources adds sources an answer is written from; without a recorder
+// in ctx it is a no-op
+func recordSources(ctx context.Context, results []models.TavilyResult) {
+	rec, ok := ctx.Value(sourceRecorderKey{}).(*sourceRecorder)
+	if !ok {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for _, result := range results {
+		if rec.seen[result.URL] {
+			continue
+		}
+		rec.seen[result.URL] = true
+		rec.results = append(rec.results, result)
+	}
+}
+
+func recordedSources(ctx context.Context) []models.TavilyResult {
+	rec, ok := ctx.Value(sourceRecorderKey{}).(*sourceRecorder)
+	if !ok {
+		return nil
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.results
+}
+
+// answerSentence is a sentence of the answer as byte offsets
+type answerSentence struct {
+	start, end int
+}
+
+// verifyAnswer checks each sentence of the answer against the sources it was
+// written from in one LLM call, then flags or strips the sentences with a
+// factual claim the sources do not support. Answers without recorded sources
+// are left alone; a failed check leaves the answer unverified.
+func (r *RouterAgent) verifyAnswer(ctx context.Context, query string, result *models.SearchResponse) {
+	action := r.cfg.AnswerVerification
+	if action != VerificationFlag && action != VerificationStrip {
+		return
+	}
+	sources := recordedSources(ctx)
+	sentences := answerSentences(result.Answer)
+	if len(sources) == 0 || len(sentences) == 0 {
+		return
+	}
+	if len(sources) > maxVerifySources {
+		sources = sources[:maxVerifySources]
+	}
+	if len(sentences) > maxVerifySentences {
+		sentences = sentences[:maxVerifySentences]
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(`Check every numbered sentence of an answer against the sources it was written from.
+Verdicts:
+- "supported": the sources state it, or it follows directly from what they state
+- "unsupported": a factual claim the sources do not state or contradict
+- "no_claim": no factual claim (an introduction, a transition, advice, a note that information is missing)
+
+`)
+	prompt.WriteString(fmt.Sprintf("Question: %s\n\n", query))
+	for i, source := range sources {
+		content := source.Content
+		if source.RawContent != "" {
+			content = source.RawContent
+		}
+		content = utils.TruncateRunes(utils.SanitizeUTF8(content), verifySourceChars)
+		prompt.WriteString(fmt.Sprintf("Source %d (%s):\n%s\n\n", i+1, source.Title, content))
+	}
+	prompt.WriteString("Sentences:\n")
+	for i, sentence := range sentences {
+		prompt.WriteString(fmt.Sprintf("%d. %s\n", i+1, result.Answer[sentence.start:sentence.end]))
+	}
+	prompt.WriteString(`
+Return ONLY a JSON object with a verdict for every sentence:
+{"verdicts": [{"n": 1, "verdict": "supported"}]}
+`)
+
+	verifyCtx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+	verifyStart := time.Now()
+	response, err := r.llmClient.ForTask(tools.TaskVerification).ChatCompletionJSON(verifyCtx, []map[string]string{
+		{"role": "user", "content": prompt.String()},
+	}, 0.1, 1000)
+	tools.TrackTime(ctx, tools.TimingVerification, verifyStart)
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Answer verification failed: %v", err)
+		return
+	}
+	verdicts, err := parseSentenceVerdicts(response, len(sentences))
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Answer verification failed: %v", err)
+		return
+	}
+
+	verification := &models.AnswerVerification{Action: action}
+	var unsupported []answerSentence
+	supported := 0
+	for i, verdict := range verdicts {
+		switch verdict {
+		case "supported":
+			supported++
+		case "unsupported":
+			unsupported = append(unsupported, sentences[i])
+			verification.Unsupported = append(verification.Unsupported, result.Answer[sentences[i].start:sentences[i].end])
+		}
+	}
+	verification.Checked = supported + len(unsupported)
+	verification.VerifiedRatio = 1
+	if verification.Checked > 0 {
+		verification.VerifiedRatio = math.Round(float64(supported)/float64(verification.Checked)*100) / 100
+	}
+	// Stripping every claim would leave no answer: flag them instead
+	if action == VerificationStrip && supported == 0 {
+		verification.Action = VerificationFlag
+	}
+
+	lang := answerLanguage(ctx, query)
+	if len(unsupported) > 0 {
+		if verification.Action == VerificationStrip {
+			result.Answer = stripSentences(result.Answer, unsupported)
+		} else {
+			result.Answer = flagSentences(result.Answer, unsupported, lang)
+		}
+	}
+	result.Verification = verification
+
+	var step string
+	if lang == "ru" {
+		step = fmt.Sprintf("🔎 Проверка ответа по источникам: подтверждено %d из %d утверждений", supported, verification.Checked)
+		if len(unsupported) > 0 && verification.Action == VerificationStrip {
+			step += ", неподтверждённые удалены"
+		} else if len(unsupported) > 0 {
+			step += ", неподтверждённые отмечены"
+		}
+	} else {
+		step = fmt.Sprintf("🔎 Answer checked against the sources: %d of %d claims supported", supported, verification.Checked)
+		if len(unsupported) > 0 && verification.Action == VerificationStrip {
+			step += ", unsupported ones removed"
+		} else if len(unsupported) > 0 {
+			step += ", unsupported ones flagged"
+		}
+	}
+	appendStep(ctx, nil, step)
+	result.Reasoning = strings.TrimSpace(result.Reasoning + "\n" + step)
+	logging.Printf(ctx, "%s", step)
+}
+
+// parseSentenceVerdicts reads the verdict of each of n sentences; sentences the LLM
+// skipped count as no_claim
+func parseSentenceVerdicts(response string, n int) ([]string, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON object in verification")
+	}
+	var parsed struct {
+		Verdicts []struct {
+			N       int    `json:"n"`
+			Verdict string `json:"verdict"`
+		} `json:"verdicts"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid verification JSON: %w", err)
+	}
+
+	verdicts := make([]string, n)
+	for i := range verdicts {
+		verdicts[i] = "no_claim"
+	}
+	for _, v := range parsed.Verdicts {
+		if v.N < 1 || v.N > n {
+			continue
+		}
+		verdicts[v.N-1] = strings.ToLower(strings.TrimSpace(v.Verdict))
+	}
+	return verdicts, nil
+}
+
+// answerSentences splits the prose of a markdown answer into sentences.
+// Headings, tables and code blocks are not prose; list markers are not part of
+// their sentences, citation markers are.
+func answerSentences(answer string) []answerSentence {
+	var sentences []answerSentence
+	inCode := false
+	offset := 0
+	for _, line := range strings.SplitAfter(answer, "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || trimmed == "" || strings.HasPrefix(trimmed, "#") ||
+			strings.HasPrefix(trimmed, "|") || strings.HasPrefix(trimmed, "---") {
+			continue
+		}
+
+		start := lineStart
+		if m := listItemPattern.FindStringIndex(line); m != nil {
+			start += m[1]
+		}
+		end := lineStart + len(strings.TrimRight(line, "\r\n"))
+		for _, s := range splitSentences(answer[start:end]) {
+			sentences = append(sentences, answerSentence{start: start + s.start, end: start + s.end})
+		}
+	}
+	return sentences
+}
+
+// splitSentences splits a line of text at full stops, question and
+// exclamation marks followed by a space and a sentence start
+func splitSentences(text string) []answerSentence {
+	var sentences []answerSentence
+	add := func(start, end int) {
+		for start < end && unicode.IsSpace(rune(text[start])) {
+			start++
+		}
+		for end > start && unicode.IsSpace(rune(text[end-1])) {
+			end--
+		}
+		if start < end {
+			sentences = append(sentences, answerSentence{start: start, end: end})
+		}
+	}
+
+	begin := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if r != '.' && r != '!' && r != '?' && r != '…' {
+			continue
+		}
+		stop := i - size
+
+		// Closing quotes, brackets and repeated marks belong to the sentence
+		end := i
+		for end < len(text) {
+			next, nextSize := utf8.DecodeRuneInString(text[end:])
+			if !strings.ContainsRune(".!?…)»\"'*_", next) {
+				break
+			}
+			end += nextSize
+		}
+		if m := trailingCitationsPattern.FindStringIndex(text[end:]); m != nil {
+			end += m[1]
+		}
+		i = end
+
+		if end < len(text) {
+			next, _ := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsSpace(next) {
+				continue // 3.5, example.com
+			}
+			rest := strings.TrimLeft(text[end:], " \t")
+			if first, _ := utf8.DecodeRuneInString(rest); unicode.IsLower(first) {
+				continue
+			}
+		}
+		if r == '.' && isAbbreviation(text[begin:stop]) {
+			continue
+		}
+		add(begin, end)
+		begin = end
+	}
+	add(begin, len(text))
+	return sentences
+}
+
+// isAbbreviation reports whether the word before a full stop is an initial or
+// an abbreviation (т.е., e.g., г., Dr.)
+func isAbbreviation(before string) bool {
+	word := before[strings.LastIndexAny(before, " (\t")+1:]
+	if word == "" {
+		return false
+	}
+	return utf8.RuneCountInString(word) == 1 || strings.Contains(word, ".") ||
+		sentenceAbbreviations[strings.ToLower(word)]
+}
+
+// flagSentences marks the sentences as not confirmed by the sources
+func flagSentences(answer string, sentences []answerSentence, lang string) string {
+	marker := " [unverified]"
+	if lang == "ru" {
+		marker = " [не подтверждено]"
+	}
+	var b strings.Builder
+	last := 0
+	for _, s := range sentences {
+		b.WriteString(answer[last:s.end])
+		b.WriteString(marker)
+		last = s.end
+	}
+	b.WriteString(answer[last:])
+	return b.String()
+}
+
+// stripSentences removes the sentences, with the space after them, and list
+// items left empty
+func stripSentences(answer string, sentences []answerSentence) string {
+	var b strings.Builder
+	last := 0
+	for _, s := range sentences {
+		b.WriteString(answer[last:s.start])
+		last = s.end
+		for last < len(answer) && (answer[last] == ' ' || answer[last] == '\t') {
+			last++
+		}
+	}
+	b.WriteString(answer[last:])
+
+	lines := strings.Split(b.String(), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if emptyListItemPattern.MatchString(line) {
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, " \t"))
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
@@ -0,0 +1,153 @@
+package agents
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// verticalSignals point a research query at a vertical agent. Single words
+// match as word prefixes (акци -> акции, акций), phrases as substrings; "видео"
+// only appears in phrases, its prefix would also match "видеокарта".
+var verticalSignals = map[string][]string{
+	"pro-finance": {
+		"акци", "облигаци", "ключевая ставка", "ключевую ставку", "инфляци", "биржа",
+		"биржи", "дивиденд", "котировк", "курс доллара", "курс рубля", "ipo", "инвестор",
+		"stock", "shares", "bond", "dividend", "earnings", "interest rate",
+		"inflation", "market cap", "etf", "bitcoin", "nasdaq", "s&p 500", "investor",
+	},
+	"pro-academic": {
+		"исследовани", "исследовател", "научн", "учён", "учены", "публикаци", "диссертаци",
+		"рецензируем", "метаанализ", "arxiv", "scholar", "study", "studies", "research paper",
+		"researcher", "scientist", "peer-reviewed", "meta-analysis", "journal", "scientific",
+		"literature review",
+	},
+	"pro-social": {
+		"отзыв", "мнени", "обсуждени", "что думают", "опыт использования", "форум",
+		"reddit", "habr", "хабр", "reviews", "opinions", "what do people think",
+		"discussion", "forum", "experiences with", "twitter",
+	},
+	"pro-news": {
+		"новост", "сегодня", "последние", "свежие", "вчера", "на этой неделе", "только что",
+		"news", "today", "latest", "breaking", "yesterday", "this week", "headlines",
+	},
+	"pro-code": {
+		"питон", "голанг", "горутин", "компиляци", "компилятор", "стектрейс", "регулярк",
+		"golang", "goroutine", "python", "javascript", "typescript", "kotlin", "rust",
+		"docker", "kubernetes", "npm", "pip", "regex", "sql", "api", "github",
+		"stackoverflow", "exception", "stack trace", "traceback", "segfault", "compile",
+		"runtime error", "null pointer", "nullpointer",
+	},
+	"pro-video": {
+		"видеоурок", "видеоролик", "видеообзор", "видеозапис", "видео про", "видео о ",
+		"видео об", "видео по", "ролик", "ютуб", "туториал", "youtube", "tutorial",
+		"walkthrough", "screencast", "video about", "videos about", "video on",
+		"video explain",
+	},
+	"pro-factcheck": {
+		"правда ли", "правда, что", "верно ли", "миф", "фейк", "фактчек", "факт-чек",
+		"проверь факт", "is it true", "fact check", "fact-check", "debunk", "hoax", "myth",
+	},
+}
+
+// verticalOrder makes ties and iteration deterministic
+var verticalOrder = []string{"pro-finance", "pro-academic", "pro-social", "pro-news", "pro-code", "pro-video", "pro-factcheck"}
+
+// mixedVerticals are the verticals whose views pro-mixed merges, in the
+// order of its answer sections
+var mixedVerticals = []string{"pro-finance", "pro-academic", "pro-social"}
+
+// simpleVerticals also take over queries auto mode sent to simple: general web
+// search neither prefers recent news, finds code answers, reads videos nor
+// gives verdicts
+var simpleVerticals = map[string]bool{"pro-news": true, "pro-code": true, "pro-video": true, "pro-factcheck": true}
+
+// detectVertical returns the vertical agent whose signals the query matches
+// most, or nil when none match or two verticals tie. Confidence grows with the
+// number of matched signals: 0.5 for one, 0.75 for two, 0.875 for three.
+func detectVertical(query string) *models.VerticalRouting {
+	matches := verticalMatches(query)
+
+	var best *models.VerticalRouting
+	tie := false
+	for _, agent := range verticalOrder {
+		matched := matches[agent]
+		if len(matched) == 0 {
+			continue
+		}
+
+		switch {
+		case best == nil || len(matched) > len(best.Signals):
+			best = &models.VerticalRouting{Agent: agent, Signals: matched}
+			tie = false
+		case len(matched) == len(best.Signals):
+			tie = true
+		}
+	}
+
+	if best == nil || tie {
+		return nil
+	}
+	best.Confidence = 1 - math.Pow(0.5, float64(len(best.Signals)))
+	return best
+}
+
+// detectMixed routes a query matching the signals of two or more of
+// mixedVerticals to pro-mixed, nil otherwise. Confidence is that of the
+// domain with the fewest signals.
+func detectMixed(query string) *models.VerticalRouting {
+	matches := verticalMatches(query)
+	routing := &models.VerticalRouting{Agent: "pro-mixed"}
+	weakest := 0
+	for _, agent := range mixedVerticals {
+		matched := matches[agent]
+		if len(matched) == 0 {
+			continue
+		}
+		routing.Domains = append(routing.Domains, agent)
+		routing.Signals = append(routing.Signals, matched...)
+		if weakest == 0 || len(matched) < weakest {
+			weakest = len(matched)
+		}
+	}
+
+	if len(routing.Domains) < 2 {
+		return nil
+	}
+	routing.Confidence = 1 - math.Pow(0.5, float64(weakest))
+	return routing
+}
+
+// verticalMatches returns the query words matching the signals of each
+// vertical agent
+func verticalMatches(query string) map[string][]string {
+	queryLower := strings.ToLower(query)
+	words := strings.FieldsFunc(queryLower, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	matches := make(map[string][]string)
+	for _, agent := range verticalOrder {
+		for _, signal := range verticalSignals[agent] {
+			if match, ok := matchSignal(queryLower, words, signal); ok {
+				matches[agent] = append(matches[agent], match)
+			}
+		}
+	}
+	return matches
+}
+
+// matchSignal returns the query word (or phrase) matching signal
+func matchSignal(queryLower string, words []string, signal string) (string, bool) {
+	if strings.ContainsAny(signal, " &-") {
+		return signal, strings.Contains(queryLower, signal)
+	}
+	for _, word := range words {
+		if strings.HasPrefix(word, signal) {
+			return word, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,267 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/scrapers"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+const (
+	// maxVideos is how many videos are searched for transcripts
+	maxVideos = 5
+	// transcriptWindow is the length of one transcript excerpt
+	transcriptWindow = 60 * time.Second
+	// maxExcerptsPerVideo keeps one long video from filling the sources
+	maxExcerptsPerVideo = 3
+	// maxVideoSources is how many excerpts the answer is written from
+	maxVideoSources = 8
+)
+
+// VideoAgent answers from YouTube videos: the transcript excerpts most
+// relevant to the question are its sources, each linking to the moment of the
+// video it was taken from
+type VideoAgent struct {
+	videoScraper *scrapers.VideoScraper
+	llmClient    *tools.LLMClient
+	reranker     *tools.BM25Reranker
+	evidence     *EvidencePolicy
+}
+
+func NewVideoAgent(llmClient *tools.LLMClient, evidence *EvidencePolicy, youtubeAPIKey string) *VideoAgent {
+	return &VideoAgent{
+		videoScraper: scrapers.NewVideoScraper(youtubeAPIKey),
+		llmClient:    llmClient,
+		reranker:     tools.NewBM25Reranker(),
+		evidence:     evidence,
+	}
+}
+
+func (a *VideoAgent) Process(ctx context.Context, query string) (*models.SearchResponse, error) {
+	return a.ProcessWithContext(ctx, query, nil)
+}
+
+func (a *VideoAgent) ProcessWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (*models.SearchResponse, error) {
+	logging.Printf(ctx, "Pro Video mode processing: %s", query)
+
+	reasoningSteps := appendStep(ctx, nil, "🎬 Запущен режим Video - поиск по видео и их субтитрам")
+
+	searchQuery := query
+	if len(conversationHistory) > 0 {
+		reasoningSteps = appendStep(ctx, reasoningSteps, "Адаптирую запрос с учетом контекста...")
+		enhanced, err := a.enhanceQueryWithContext(ctx, query, conversationHistory)
+		if err == nil && enhanced != "" {
+			searchQuery = strings.TrimSpace(enhanced)
+		}
+	}
+	lang := detectLanguage(query)
+
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Ищу видео на YouTube...")
+	videos, err := a.videoScraper.SearchYouTube(ctx, searchQuery, lang, maxVideos)
+	if err != nil {
+		logging.Printf(ctx, "YouTube search failed: %v", err)
+	} else {
+		reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ YouTube: %d видео", len(videos)))
+	}
+
+	if len(videos) == 0 && !hasDocuments(ctx) {
+		return &models.SearchResponse{
+			Query:     query,
+			Mode:      "pro-video",
+			Answer:    "Не удалось найти видео по вашему запросу.",
+			Sources:   []models.Source{},
+			Reasoning: strings.Join(reasoningSteps, "\n"),
+		}, nil
+	}
+
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Загружаю субтитры...")
+	allResults, transcripts := a.excerpts(ctx, searchQuery, lang, videos)
+	reasoningSteps = appendStep(ctx, reasoningSteps, fmt.Sprintf("✓ Субтитры: %d из %d видео, выбрано %d фрагментов", transcripts, len(videos), len(allResults)))
+	allResults = withDocuments(ctx, searchQuery, allResults)
+
+	evidence := a.evidence.check(ctx, allResults)
+	if step := evidence.reasoning("ru"); step != "" {
+		reasoningSteps = appendStep(ctx, reasoningSteps, step)
+	}
+
+	// Build LLM prompt
+	var system strings.Builder
+	system.WriteString(`Ты исследователь, который отвечает по видеоматериалам. Ниже фрагменты субтитров видео с YouTube, у каждого указано время начала.
+
+Твоя задача:
+1. Ответить на вопрос по тому, что говорится в видео
+2. Ссылаться на фрагменты, из которых взяты утверждения, чтобы читатель мог перейти к нужному моменту
+3. Если видео расходятся, указать это
+4. Не выдумывать того, чего нет во фрагментах: субтитры могут быть автоматическими и содержать ошибки распознавания
+
+`)
+	system.WriteString(citationInstruction("ru"))
+	system.WriteString(evidence.instruction("ru"))
+	system.WriteString(formatInstruction(ctx, "ru"))
+	system.WriteString(languageInstruction(ctx, "ru"))
+	system.WriteString(personaInstruction(ctx, "ru"))
+
+	var promptBuilder strings.Builder
+	if len(conversationHistory) > 0 {
+		promptBuilder.WriteString("Контекст диалога:\n")
+		for _, msg := range recentTurns(conversationHistory, 4) {
+			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+		}
+		promptBuilder.WriteString("\n")
+	}
+
+	promptBuilder.WriteString(fmt.Sprintf("Вопрос: %s\n\n", query))
+	promptBuilder.WriteString("Фрагменты видео:\n\n")
+
+	for i, result := range allResults {
+		content := utils.TruncateRunes(result.Content, 1000)
+		promptBuilder.WriteString(fmt.Sprintf("Источник %d (%s):\n%s\n\n", i+1, result.Title, content))
+	}
+
+	promptBuilder.WriteString("Ответ:")
+
+	reasoningSteps = appendStep(ctx, reasoningSteps, "Формирую ответ по фрагментам видео...")
+
+	synthesisStart := time.Now()
+	answer, err := a.llmClient.ForTask(tools.TaskSynthesis).CompletePrompt(ctx, tools.Prompt{
+		System: strings.TrimSpace(system.String()),
+		User:   promptBuilder.String(),
+	}, 0.4, 1200)
+	tools.TrackTime(ctx, tools.TimingSynthesis, synthesisStart)
+	if err != nil {
+		return nil, fmt.Errorf("LLM completion failed: %w", err)
+	}
+
+	// Format sources
+	sources := make([]models.Source, 0, len(allResults))
+	for _, result := range allResults {
+		sources = append(sources, models.Source{
+			Title:       result.Title,
+			URL:         result.URL,
+			Snippet:     utils.TruncateRunesWithEllipsis(result.Content, 200),
+			Credibility: result.Score,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   fetchedAt(result),
+		})
+	}
+
+	return &models.SearchResponse{
+		Query:       query,
+		Mode:        "pro-video",
+		Answer:      answer,
+		Sources:     sources,
+		Reasoning:   strings.Join(reasoningSteps, "\n"),
+		ContextUsed: len(conversationHistory) > 0,
+	}, nil
+}
+
+// excerpts loads the transcripts of videos concurrently and returns their
+// windows most relevant to the query, at most maxExcerptsPerVideo per video,
+// and how many videos had a transcript. A video without one is represented
+// by its title and description.
+func (a *VideoAgent) excerpts(ctx context.Context, query, lang string, videos []scrapers.Video) ([]models.TavilyResult, int) {
+	transcripts := make([][]scrapers.TranscriptSegment, len(videos))
+	var wg sync.WaitGroup
+	for i, video := range videos {
+		wg.Add(1)
+		go func(i int, video scrapers.Video) {
+			defer wg.Done()
+			segments, err := a.videoScraper.Transcript(ctx, video.ID, lang)
+			if err != nil {
+				logging.Printf(ctx, "Transcript of %s failed: %v", video.ID, err)
+				return
+			}
+			transcripts[i] = segments
+		}(i, video)
+	}
+	wg.Wait()
+
+	withTranscript := 0
+	candidates := make([]models.TavilyResult, 0)
+	for i, video := range videos {
+		if transcripts[i] == nil {
+			if video.Description == "" {
+				continue
+			}
+			candidates = append(candidates, models.TavilyResult{
+				Title:       video.Title,
+				URL:         scrapers.WatchURL(video.ID, 0),
+				Content:     video.Description,
+				PublishedAt: video.PublishedAt,
+			})
+			continue
+		}
+		withTranscript++
+		for _, window := range scrapers.TranscriptWindows(transcripts[i], transcriptWindow) {
+			candidates = append(candidates, models.TavilyResult{
+				Title:       fmt.Sprintf("%s [%s]", video.Title, formatTimestamp(window.Start)),
+				URL:         scrapers.WatchURL(video.ID, window.Start),
+				Content:     window.Text,
+				PublishedAt: video.PublishedAt,
+			})
+		}
+	}
+
+	rerankStart := time.Now()
+	candidates = a.reranker.Rerank(query, candidates)
+	tools.TrackTime(ctx, tools.TimingRerank, rerankStart)
+
+	perVideo := make(map[string]int)
+	results := make([]models.TavilyResult, 0, maxVideoSources)
+	for _, candidate := range candidates {
+		if len(results) >= maxVideoSources {
+			break
+		}
+		// Excerpt URLs differ in the timestamp only
+		videoURL, _, _ := strings.Cut(candidate.URL, "&t=")
+		if perVideo[videoURL] >= maxExcerptsPerVideo {
+			continue
+		}
+		perVideo[videoURL]++
+		results = append(results, candidate)
+	}
+	return results, withTranscript
+}
+
+// formatTimestamp writes a video position as m:ss or h:mm:ss
+func formatTimestamp(d time.Duration) string {
+	seconds := int(d.Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+func (a *VideoAgent) enhanceQueryWithContext(
+	ctx context.Context,
+	query string,
+	conversationHistory []models.Message,
+) (string, error) {
+	defer tools.TrackTime(ctx, tools.TimingQueryEnhance, time.Now())
+
+	var contextPrompt strings.Builder
+	contextPrompt.WriteString("Предыдущая беседа:\n")
+	for _, msg := range recentTurns(conversationHistory, 4) {
+		role := turnRole(msg)
+		contextPrompt.WriteString(fmt.Sprintf("%s: %s\n", role, msg.Content))
+	}
+
+	enhancePrompt := fmt.Sprintf(`%s
+
+Текущий вопрос: %s
+
+Перефразируй текущий вопрос так, чтобы он был самодостаточным для поиска видео на YouTube. Улучшенный запрос:`, contextPrompt.String(), query)
+
+	return a.llmClient.ForTask(tools.TaskQueryEnhance).Complete(ctx, enhancePrompt, 0.3, 150)
+}
@@ -0,0 +1,344 @@
+package api
+
+import (
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/handlers"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/openapi"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/graphql"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/jobs"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// searchStreamEvents documents the Server-Sent Events of /api/search/stream:
+// every event is named after one of these fields and carries it as data
+type searchStreamEvents struct {
+	Step   models.StepEvent      `json:"step"`
+	Token  models.TokenEvent     `json:"token"`
+	Answer models.SearchResponse `json:"answer"`
+	Error  models.ErrorResponse  `json:"error"`
+}
+
+// buildSpec describes the API routes for /api/openapi.json.
+// Keep it in sync with SetupRoutes when adding or changing endpoints.
+func buildSpec() map[string]interface{} {
+	sessionID := openapi.Param{Name: "session_id", In: "path", Description: "Chat session ID"}
+	provider := openapi.Param{Name: "provider", In: "path", Description: "notion or google_docs"}
+	documentID := openapi.Param{Name: "document_id", In: "path", Description: "Document ID"}
+	jobID := openapi.Param{Name: "job_id", In: "path", Description: "Background job ID"}
+
+	spec := openapi.NewSpec("Research Pro Mode API", "1.0.0")
+	spec.Add(
+		openapi.Operation{
+			Method:  "GET",
+			Path:    "/api/health",
+			Summary: "Health check with dependency probes (database, redis, searxng, llm)",
+			Tag:     "system",
+			Params: []openapi.Param{
+				{Name: "verbose", In: "query", Description: "Set to 1 to include per-dependency status and latency"},
+			},
+			Response: map[string]interface{}{},
+		},
+		openapi.Operation{
+			Method:   "GET",
+			Path:     "/api/modes",
+			Summary:  "Available search modes with descriptions, expected latency and configuration status",
+			Tag:      "search",
+			Response: models.ModesResponse{},
+		},
+		openapi.Operation{
+			Method:   "POST",
+			Path:     "/api/estimate",
+			Summary:  "Predicted latency, LLM tokens and cost of a query per mode, from recent usage and query complexity",
+			Tag:      "search",
+			Request:  models.EstimateRequest{},
+			Response: models.EstimateResponse{},
+		},
+		openapi.Operation{
+			Method:   "POST",
+			Path:     "/api/search",
+			Summary:  "Stateless search in auto, simple or pro mode; with callback_url returns 202 and a job_id, with explain=true the query plan (ExplainResponse) instead of an answer",
+			Tag:      "search",
+			Request:  models.SearchRequest{},
+			Response: models.SearchResponse{},
+		},
+		openapi.Operation{
+			Method:      "POST",
+			Path:        "/api/search/stream",
+			Summary:     "Search like /api/search (without explain and callback_url), streamed as Server-Sent Events: step and token events while the query is answered, then one answer (SearchResponse) or error event",
+			Tag:         "search",
+			Request:     models.SearchRequest{},
+			Response:    searchStreamEvents{},
+			ContentType: "text/event-stream",
+		},
+		openapi.Operation{
+			Method:   "POST",
+			Path:     "/api/search/compare",
+			Summary:  "Run the query through simple and pro concurrently and return both answers with timing and the auto routing decision",
+			Tag:      "search",
+			Request:  models.CompareRequest{},
+			Response: models.CompareResponse{},
+		},
+		openapi.Operation{
+			Method:   "POST",
+			Path:     "/api/retrieve",
+			Summary:  "Ranked sources with relevance and credibility scores, without LLM synthesis",
+			Tag:      "search",
+			Request:  models.RetrieveRequest{},
+			Response: models.RetrieveResponse{},
+		},
+		openapi.Operation{
+			Method:  "DELETE",
+			Path:    "/api/search/:request_id",
+			Summary: "Cancel a running search or chat query; the query returns 499",
+			Tag:     "search",
+			Params: []openapi.Param{
+				{Name: "request_id", In: "path", Description: "request_id sent with the query (also returned in X-Request-ID)"},
+			},
+			Response: map[string]interface{}{},
+		},
+		openapi.Operation{
+			Method:  "GET",
+			Path:    "/api/jobs/:job_id",
+			Summary: "Get background job status and result",
+			Tag:     "jobs",
+			Params: []openapi.Param{
+				jobID,
+				{Name: "channel", In: "query", Description: "Render the result for a channel: telegram, web, api"},
+			},
+			Response: jobs.Job{},
+		},
+		openapi.Operation{
+			Method:      "GET",
+			Path:        "/api/jobs/:job_id/stream",
+			Summary:     "Stream job status as Server-Sent Events (status, improved, failed)",
+			Tag:         "jobs",
+			Params:      []openapi.Param{jobID},
+			Response:    jobs.Job{},
+			ContentType: "text/event-stream",
+		},
+		openapi.Operation{
+			Method:  "GET",
+			Path:    "/api/history",
+			Summary: "Search history of the caller (identified by API key, required), newest first",
+			Tag:     "search",
+			Params: []openapi.Param{
+				{Name: "page", In: "query", Description: "Page number (default 1)"},
+				{Name: "page_size", In: "query", Description: "Items per page (default 20, max 100)"},
+				{Name: "mode", In: "query", Description: "Filter by resolved mode, e.g. pro"},
+			},
+			Response: handlers.HistoryResponse{},
+		},
+		openapi.Operation{
+			Method:  "POST",
+			Path:    "/api/hooks/:name",
+			Summary: "Trigger a configured research hook with a signed event (any JSON object); the report goes to the hook's channel",
+			Tag:     "hooks",
+			Params: []openapi.Param{
+				{Name: "name", In: "path", Description: "Hook name from INBOUND_HOOKS_PATH"},
+				{Name: "X-Webhook-Timestamp", In: "header", Required: true, Description: "Unix seconds"},
+				{Name: "X-Webhook-Signature", In: "header", Required: true, Description: "sha256=<hex HMAC-SHA256 of \"timestamp.body\" with the hook secret>"},
+			},
+			Request:  map[string]interface{}{},
+			Response: models.JobAcceptedResponse{},
+		},
+		openapi.Operation{
+			Method:   "POST",
+			Path:     "/api/feedback",
+			Summary:  "Rate an assistant answer",
+			Tag:      "feedback",
+			Request:  models.FeedbackRequest{},
+			Response: database.Feedback{},
+		},
+		openapi.Operation{
+			Method:  "GET",
+			Path:    "/api/admin/stats",
+			Summary: "Usage analytics per day and mode: queries, latency, error rate, LLM tokens (admin API key)",
+			Tag:     "admin",
+			Params: []openapi.Param{
+				{Name: "days", In: "query", Description: "Days to report, including today (default 30, max 365)"},
+			},
+			Response: models.AdminStatsResponse{},
+		},
+		openapi.Operation{
+			Method:  "GET",
+			Path:    "/api/admin/requests/:request_id/trace",
+			Summary: "Reasoning steps of a request, stored as they happen, also for failed or timed out requests (admin API key)",
+			Tag:     "admin",
+			Params: []openapi.Param{
+				{Name: "request_id", In: "path", Description: "Request ID (X-Request-ID of the query)"},
+			},
+			Response: handlers.TraceResponse{},
+		},
+		openapi.Operation{
+			Method:   "POST",
+			Path:     "/api/chat/session",
+			Summary:  "Create chat session",
+			Tag:      "chat",
+			Request:  models.CreateSessionRequest{},
+			Response: database.ChatSession{},
+		},
+		openapi.Operation{
+			Method:  "GET",
+			Path:    "/api/chat/session/:session_id",
+			Summary: "Get chat session with a page of messages (newest page first) and sources",
+			Tag:     "chat",
+			Params: []openapi.Param{
+				sessionID,
+				{Name: "page", In: "query", Description: "Page number, 1 is the most recent messages (default 1)"},
+				{Name: "page_size", In: "query", Description: "Messages per page (default 50, max 200)"},
+				{Name: "limit", In: "query", Description: "Alias of page_size: ?limit=N loads only the last N messages"},
+				{Name: "offset", In: "query", Description: "Skip this many of the newest messages (instead of page)"},
+				{Name: "after_timestamp", In: "query", Description: "Only messages newer than this unix timestamp"},
+				{Name: "agent", In: "query", Description: "Only messages answered by this agent, e.g. pro-finance"},
+			},
+			Response: handlers.SessionResponse{},
+		},
+		openapi.Operation{
+			Method:  "GET",
+			Path:    "/api/chat/session/:session_id/messages/count",
+			Summary: "Count the session's messages without loading them",
+			Tag:     "chat",
+			Params: []openapi.Param{
+				sessionID,
+				{Name: "after_timestamp", In: "query", Description: "Only messages newer than this unix timestamp"},
+				{Name: "agent", In: "query", Description: "Only messages answered by this agent"},
+			},
+			Response: handlers.MessageCountResponse{},
+		},
+		openapi.Operation{
+			Method:   "POST",
+			Path:     "/api/chat/session/:session_id/message",
+			Summary:  "Send message in chat session (uses conversation context)",
+			Tag:      "chat",
+			Params:   []openapi.Param{sessionID},
+			Request:  models.SendMessageRequest{},
+			Response: models.SearchResponse{},
+		},
+		openapi.Operation{
+			Method:  "PUT",
+			Path:    "/api/chat/session/:session_id/message/:message_id",
+			Summary: "Edit a user message: later messages are removed and the answer is regenerated",
+			Tag:     "chat",
+			Params: []openapi.Param{
+				sessionID,
+				{Name: "message_id", In: "path", Required: true, Description: "ID of the user message"},
+			},
+			Request:  models.SendMessageRequest{},
+			Response: models.SearchResponse{},
+		},
+		openapi.Operation{
+			Method:   "DELETE",
+			Path:     "/api/chat/session/:session_id",
+			Summary:  "Delete chat session",
+			Tag:      "chat",
+			Params:   []openapi.Param{sessionID},
+			Response: map[string]string{},
+		},
+		openapi.Operation{
+			Method:   "POST",
+			Path:     "/api/chat/session/:session_id/share",
+			Summary:  "Create a signed read-only share link to the session (SHARE_SECRET)",
+			Tag:      "chat",
+			Params:   []openapi.Param{sessionID},
+			Request:  models.ShareRequest{},
+			Response: models.ShareResponse{},
+		},
+		openapi.Operation{
+			Method:  "GET",
+			Path:    "/api/shared/:token",
+			Summary: "Read-only view of a shared session",
+			Tag:     "chat",
+			Params: []openapi.Param{
+				{Name: "token", In: "path", Description: "Share token from the share link"},
+			},
+			Response: handlers.SharedSessionResponse{},
+		},
+		openapi.Operation{
+			Method:   "POST",
+			Path:     "/api/chat/session/:session_id/export",
+			Summary:  "Export session to the caller's Notion or Google Docs (409 with auth_url if not connected)",
+			Tag:      "integrations",
+			Params:   []openapi.Param{sessionID},
+			Request:  models.ExportRequest{},
+			Response: models.ExportResponse{},
+		},
+		openapi.Operation{
+			Method:             "POST",
+			Path:               "/api/documents",
+			Summary:            "Upload a PDF, DOCX or text document to ground answers in (SearchRequest.document_ids)",
+			Tag:                "documents",
+			Request:            handlers.DocumentUploadForm{},
+			RequestContentType: "multipart/form-data",
+			Response:           database.Document{},
+		},
+		openapi.Operation{
+			Method:   "GET",
+			Path:     "/api/documents",
+			Summary:  "Documents uploaded by the caller, newest first",
+			Tag:      "documents",
+			Response: handlers.DocumentsResponse{},
+		},
+		openapi.Operation{
+			Method:   "GET",
+			Path:     "/api/documents/:document_id",
+			Summary:  "Get a document of the caller",
+			Tag:      "documents",
+			Params:   []openapi.Param{documentID},
+			Response: database.Document{},
+		},
+		openapi.Operation{
+			Method:   "DELETE",
+			Path:     "/api/documents/:document_id",
+			Summary:  "Delete a document of the caller",
+			Tag:      "documents",
+			Params:   []openapi.Param{documentID},
+			Response: map[string]string{},
+		},
+		openapi.Operation{
+			Method:   "GET",
+			Path:     "/api/integrations",
+			Summary:  "Configured export integrations and whether the caller connected them",
+			Tag:      "integrations",
+			Response: models.IntegrationsResponse{},
+		},
+		openapi.Operation{
+			Method:   "GET",
+			Path:     "/api/integrations/:provider/connect",
+			Summary:  "OAuth consent URL to connect the caller's account",
+			Tag:      "integrations",
+			Params:   []openapi.Param{provider},
+			Response: models.ConnectResponse{},
+		},
+		openapi.Operation{
+			Method:  "GET",
+			Path:    "/api/integrations/:provider/callback",
+			Summary: "OAuth redirect target, stores the token (opened by the browser)",
+			Tag:     "integrations",
+			Params: []openapi.Param{
+				provider,
+				{Name: "code", In: "query", Description: "Authorization code"},
+				{Name: "state", In: "query", Description: "State from the consent URL"},
+			},
+			Response:    "",
+			ContentType: "text/plain",
+		},
+		openapi.Operation{
+			Method:   "DELETE",
+			Path:     "/api/integrations/:provider",
+			Summary:  "Forget the caller's token of a provider",
+			Tag:      "integrations",
+			Params:   []openapi.Param{provider},
+			Response: map[string]string{},
+		},
+		openapi.Operation{
+			Method:   "POST",
+			Path:     "/graphql",
+			Summary:  "GraphQL queries over sessions, messages and sources (see README for the schema)",
+			Tag:      "chat",
+			Request:  graphql.Request{},
+			Response: graphql.Result{},
+		},
+	)
+
+	return spec.Document()
+}
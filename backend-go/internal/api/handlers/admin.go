@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+	usageDayFormat   = "2006-01-02"
+)
+
+// usageAggregates selects the models.UsageStats columns of a group of usage rows
+const usageAggregates = `COUNT(*) AS queries,
+	COALESCE(SUM(CASE WHEN status = 'error' THEN 1 ELSE 0 END), 0) AS errors,
+	COALESCE(AVG(CASE WHEN status = 'ok' THEN latency_ms END), 0) AS avg_latency_ms,
+	COALESCE(SUM(CASE WHEN cached THEN 1 ELSE 0 END), 0) AS cache_hits,
+	COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens,
+	COALESCE(SUM(completion_tokens), 0) AS completion_tokens,
+	COALESCE(SUM(cost_usd), 0) AS cost_usd`
+
+type AdminHandler struct {
+	db          *gorm.DB
+	searchCache *tools.SearchCache
+}
+
+func NewAdminHandler(db *gorm.DB, searchCache *tools.SearchCache) *AdminHandler {
+	return &AdminHandler{db: db, searchCache: searchCache}
+}
+
+// Stats reports query volume, latency, error rate and LLM token spend of the
+// last ?days days (UTC), in total, per day and per requested mode, and the
+// hit rate of the search cache
+func (h *AdminHandler) Stats(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultStatsDays)))
+	if days < 1 {
+		days = defaultStatsDays
+	}
+	if days > maxStatsDays {
+		days = maxStatsDays
+	}
+
+	now := time.Now().UTC()
+	from := now.AddDate(0, 0, -(days - 1)).Format(usageDayFormat)
+	period := func() *gorm.DB {
+		return h.db.Model(&database.Usage{}).Where("day >= ?", from)
+	}
+
+	var totals models.UsageStats
+	if err := period().Select(usageAggregates).Scan(&totals).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to aggregate usage")
+		return
+	}
+
+	perDay := make([]models.DayUsage, 0, days)
+	if err := period().Select("day, " + usageAggregates).
+		Group("day").
+		Order("day").
+		Scan(&perDay).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to aggregate usage")
+		return
+	}
+
+	perMode := make([]models.ModeUsage, 0)
+	if err := period().Select("mode, " + usageAggregates).
+		Group("mode").
+		Order("queries DESC").
+		Scan(&perMode).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to aggregate usage")
+		return
+	}
+
+	finishUsageStats(&totals)
+	for i := range perDay {
+		finishUsageStats(&perDay[i].UsageStats)
+	}
+	for i := range perMode {
+		finishUsageStats(&perMode[i].UsageStats)
+	}
+
+	c.JSON(http.StatusOK, models.AdminStatsResponse{
+		From:        from,
+		To:          now.Format(usageDayFormat),
+		Totals:      totals,
+		PerDay:      perDay,
+		PerMode:     perMode,
+		SearchCache: h.searchCache.Stats(),
+	})
+}
+
+// finishUsageStats fills the fields derived from the aggregated columns
+// TraceResponse is the reasoning trace of one request
+type TraceResponse struct {
+	RequestID string                   `json:"request_id"`
+	Steps     []database.ReasoningStep `json:"steps"`
+}
+
+// Trace returns the reasoning steps stored for a request, including requests
+// that failed or timed out before producing an answer
+func (h *AdminHandler) Trace(c *gin.Context) {
+	requestID := c.Param("request_id")
+
+	steps := make([]database.ReasoningStep, 0)
+	if err := h.db.Where("request_id = ?", requestID).Order("seq").Find(&steps).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to load trace")
+		return
+	}
+	if len(steps) == 0 {
+		middleware.AbortWithError(c, http.StatusNotFound, "trace_not_found", "No reasoning steps recorded for this request")
+		return
+	}
+
+	c.JSON(http.StatusOK, TraceResponse{RequestID: requestID, Steps: steps})
+}
+
+func finishUsageStats(stats *models.UsageStats) {
+	stats.TotalTokens = stats.PromptTokens + stats.CompletionTokens
+	if stats.Queries > 0 {
+		stats.ErrorRate = float64(stats.Errors) / float64(stats.Queries)
+	}
+}
+
+// recordUsage stores a finished or failed query for the admin stats. ctx is
+// the query context, used to tell cancelled queries from errors.
+func recordUsage(
+	ctx context.Context,
+	db *gorm.DB,
+	endpoint, requestedMode string,
+	result *models.SearchResponse,
+	queryErr error,
+	latency time.Duration,
+	meter *tools.TokenMeter,
+) {
+	if requestedMode == "" {
+		requestedMode = "auto"
+	}
+
+	now := time.Now()
+	usage := database.Usage{
+		Endpoint:         endpoint,
+		Mode:             requestedMode,
+		Status:           "ok",
+		LatencyMs:        latency.Milliseconds(),
+		PromptTokens:     meter.PromptTokens(),
+		CompletionTokens: meter.CompletionTokens(),
+		CostUSD:          meter.Cost(),
+		Day:              now.UTC().Format(usageDayFormat),
+		CreatedAt:        now.Unix(),
+	}
+
+	switch {
+	case queryErr == nil:
+		if result != nil {
+			usage.Agent = agentOf(result)
+			usage.Cached = result.Cached
+		}
+	case errors.Is(ctx.Err(), context.Canceled):
+		usage.Status = "cancelled"
+	default:
+		usage.Status = "error"
+	}
+
+	if err := db.Create(&usage).Error; err != nil {
+		log.Printf("⚠️  Failed to record usage: %v", err)
+	}
+}
@@ -1,53 +1,122 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/agents"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/cache"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/jobs"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/render"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
+const (
+	defaultSessionPageSize = 50
+	maxSessionPageSize     = 200
+)
+
+// SessionResponse is a chat session with one page of its messages
+type SessionResponse struct {
+	database.ChatSession
+	Page          int   `json:"page"`
+	PageSize      int   `json:"page_size"`
+	Offset        int   `json:"offset"`
+	TotalMessages int64 `json:"total_messages"`
+}
+
+// MessageCountResponse is the number of messages matching the filters
+type MessageCountResponse struct {
+	SessionID string `json:"session_id"`
+	Count     int64  `json:"count"`
+}
+
 type ChatHandler struct {
-	db     *gorm.DB
-	cfg    *config.Config
-	router *agents.RouterAgent
+	db        *gorm.DB
+	cfg       *config.Config
+	router    *agents.RouterAgent
+	requests  *jobs.Registry
+	sessions  *sessionLocks
+	responses *cache.ResponseCache // nil when response caching is disabled
+	traces    *TraceWriter
+	footer    *render.Footer // nil without a configured footer
 }
 
-func NewChatHandler(db *gorm.DB, cfg *config.Config) *ChatHandler {
+// NewChatHandler creates the chat handler; with sharedRedis set, session
+// locks are shared by all replicas
+func NewChatHandler(
+	db *gorm.DB,
+	cfg *config.Config,
+	router *agents.RouterAgent,
+	requests *jobs.Registry,
+	sharedRedis *redis.Client,
+	responses *cache.ResponseCache,
+	traces *TraceWriter,
+	footer *render.Footer,
+) *ChatHandler {
 	return &ChatHandler{
-		db:     db,
-		cfg:    cfg,
-		router: agents.NewRouterAgent(cfg),
+		db:        db,
+		cfg:       cfg,
+		router:    router,
+		requests:  requests,
+		sessions:  newSessionLocks(sharedRedis),
+		responses: responses,
+		traces:    traces,
+		footer:    footer,
 	}
 }
 
-func (h *ChatHandler) CreateSession(c *gin.Context) {
-	var req struct {
-		Mode string `json:"mode" binding:"required"`
+// SessionScope is the response cache scope of the session in the URL
+func (h *ChatHandler) SessionScope(c *gin.Context) string {
+	return sessionScope(c.Param("session_id"))
+}
+
+func sessionScope(sessionID string) string {
+	return "session:" + sessionID
+}
+
+// invalidateSession drops the cached responses of the session after a write
+func (h *ChatHandler) invalidateSession(sessionID string) {
+	if h.responses != nil {
+		h.responses.Invalidate(context.Background(), sessionScope(sessionID))
 	}
+}
+
+func (h *ChatHandler) CreateSession(c *gin.Context) {
+	var req models.CreateSessionRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
 		return
 	}
 
 	session := database.ChatSession{
-		ID:        uuid.New().String(),
-		Mode:      req.Mode,
-		CreatedAt: time.Now().Unix(),
-		UpdatedAt: time.Now().Unix(),
-		Messages:  []database.Message{},
+		ID:           uuid.New().String(),
+		Mode:         req.Mode,
+		CreatedAt:    time.Now().Unix(),
+		UpdatedAt:    time.Now().Unix(),
+		Messages:     []database.Message{},
+		SystemPrompt: strings.TrimSpace(req.SystemPrompt),
 	}
 
 	if err := h.db.Create(&session).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to create session")
 		return
 	}
 
@@ -58,35 +127,166 @@ func (h *ChatHandler) GetSession(c *gin.Context) {
 	sessionID := c.Param("session_id")
 
 	var session database.ChatSession
-	if err := h.db.Preload("Messages.Sources").First(&session, "id = ?", sessionID).Error; err != nil {
+	if err := h.db.First(&session, "id = ?", sessionID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			middleware.AbortWithError(c, http.StatusNotFound, "session_not_found", "Session not found")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get session"})
+			middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to get session")
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, session)
+	filter, ok := h.messageFilter(c, sessionID)
+	if !ok {
+		return
+	}
+
+	// limit/offset are the same window as page_size/page, counted from the
+	// newest message: ?limit=10 loads only the last 10 messages
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("limit", c.DefaultQuery("page_size", strconv.Itoa(defaultSessionPageSize))))
+	if pageSize < 1 {
+		pageSize = defaultSessionPageSize
+	}
+	if pageSize > maxSessionPageSize {
+		pageSize = maxSessionPageSize
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * pageSize
+	if value := c.Query("offset"); value != "" {
+		offset, _ = strconv.Atoi(value)
+		if offset < 0 {
+			offset = 0
+		}
+		page = offset/pageSize + 1
+	}
+
+	var total int64
+	if err := h.db.Model(&database.Message{}).Where(filter).Count(&total).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to get session")
+		return
+	}
+
+	// Page 1 holds the most recent messages; each page is returned in chronological order
+	messages := make([]database.Message, 0)
+	if err := h.db.Preload("Sources").
+		Where(filter).
+		Order("seq DESC, timestamp DESC").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&messages).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to get session")
+		return
+	}
+	slices.Reverse(messages)
+	session.Messages = messages
+
+	c.JSON(http.StatusOK, SessionResponse{
+		ChatSession:   session,
+		Page:          page,
+		PageSize:      pageSize,
+		Offset:        offset,
+		TotalMessages: total,
+	})
 }
 
-func (h *ChatHandler) SendMessage(c *gin.Context) {
+// CountMessages returns the number of messages of the session without loading
+// them, e.g. to poll for new answers with ?after_timestamp
+func (h *ChatHandler) CountMessages(c *gin.Context) {
 	sessionID := c.Param("session_id")
 
-	var req struct {
-		Query string `json:"query" binding:"required"`
-		Mode  string `json:"mode"`
+	var session database.ChatSession
+	if err := h.db.Select("id").First(&session, "id = ?", sessionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			middleware.AbortWithError(c, http.StatusNotFound, "session_not_found", "Session not found")
+		} else {
+			middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to count messages")
+		}
+		return
+	}
+
+	filter, ok := h.messageFilter(c, sessionID)
+	if !ok {
+		return
+	}
+
+	var count int64
+	if err := h.db.Model(&database.Message{}).Where(filter).Count(&count).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to count messages")
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageCountResponse{SessionID: sessionID, Count: count})
+}
+
+// messageFilter selects the session's messages matching the optional query
+// filters: ?agent=pro-finance (routing audit) and ?after_timestamp=<unix>
+func (h *ChatHandler) messageFilter(c *gin.Context, sessionID string) (*gorm.DB, bool) {
+	filter := h.db.Where("session_id = ?", sessionID)
+	if agent := c.Query("agent"); agent != "" {
+		filter = filter.Where("agent = ?", agent)
+	}
+	if value := c.Query("after_timestamp"); value != "" {
+		after, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "after_timestamp must be unix seconds")
+			return nil, false
+		}
+		filter = filter.Where("timestamp > ?", after)
 	}
+	return filter, true
+}
+
+func (h *ChatHandler) SendMessage(c *gin.Context) {
+	sessionID := c.Param("session_id")
+
+	var req models.SendMessageRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	requestID, ctx, done, ok := startRequest(c, h.requests, req.RequestID)
+	if !ok {
+		return
+	}
+	defer done()
+
+	// One message per session at a time, so concurrent messages don't answer
+	// on stale history or interleave their turns
+	release, err := h.sessions.acquire(ctx, sessionID)
+	if err != nil {
+		writeQueryError(c, ctx, err)
 		return
 	}
+	defer release()
 
-	// Get session with history
 	var session database.ChatSession
-	if err := h.db.Preload("Messages").First(&session, "id = ?", sessionID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+	if err := h.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "session_not_found", "Session not found")
+		return
+	}
+
+	if sessionChanged(c, session, req.AfterSeq) {
+		return
+	}
+
+	// Load the recent history before the new message is stored
+	conversationHistory, err := h.loadHistory(ctx, &session, 0)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to load history")
+		return
+	}
+
+	if req.Explain {
+		mode := session.Mode
+		if req.Mode != "" {
+			mode = req.Mode
+		}
+		writeExplain(c, agents.WithAnswerFormat(ctx, req.Format), h.router, requestID, req.Query, mode, conversationHistory)
 		return
 	}
 
@@ -98,47 +298,178 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 		Content:   req.Query,
 		Timestamp: time.Now().Unix(),
 	}
-	if err := h.db.Create(&userMsg).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save message"})
+	if err := h.createMessage(&userMsg); err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to save message")
 		return
 	}
 
-	// Convert history for agent processing
-	conversationHistory := make([]models.Message, 0)
-	for _, msg := range session.Messages {
-		conversationHistory = append(conversationHistory, models.Message{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
+	h.answerMessage(c, ctx, requestID, session, req, conversationHistory)
+}
+
+// EditMessage replaces the content of a user message, drops everything after
+// it (the old reply and any later turns) and answers the corrected message
+func (h *ChatHandler) EditMessage(c *gin.Context) {
+	sessionID := c.Param("session_id")
+	messageID := c.Param("message_id")
+
+	var req models.SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	requestID, ctx, done, ok := startRequest(c, h.requests, req.RequestID)
+	if !ok {
+		return
+	}
+	defer done()
+
+	release, err := h.sessions.acquire(ctx, sessionID)
+	if err != nil {
+		writeQueryError(c, ctx, err)
+		return
+	}
+	defer release()
+
+	var session database.ChatSession
+	if err := h.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "session_not_found", "Session not found")
+		return
 	}
 
+	if sessionChanged(c, session, req.AfterSeq) {
+		return
+	}
+
+	var userMsg database.Message
+	if err := h.db.First(&userMsg, "id = ? AND session_id = ?", messageID, sessionID).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "message_not_found", "Message not found")
+		return
+	}
+	if userMsg.Role != "user" {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "Only user messages can be edited")
+		return
+	}
+
+	conversationHistory, err := h.loadHistory(ctx, &session, userMsg.Seq)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to load history")
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var later []string
+		if err := tx.Model(&database.Message{}).
+			Where("session_id = ? AND seq > ?", sessionID, userMsg.Seq).
+			Pluck("id", &later).Error; err != nil {
+			return err
+		}
+		// A summary that covers the edited message is stale
+		if err := tx.Model(&database.ChatSession{}).
+			Where("id = ? AND summary_seq >= ?", sessionID, userMsg.Seq).
+			Updates(map[string]interface{}{"summary": "", "summary_seq": 0}).Error; err != nil {
+			return err
+		}
+		if len(later) > 0 {
+			if err := tx.Where("message_id IN ?", later).Delete(&database.Source{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", later).Delete(&database.Message{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&userMsg).Updates(map[string]interface{}{
+			"content":   req.Query,
+			"timestamp": time.Now().Unix(),
+		}).Error
+	})
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to update message")
+		return
+	}
+	h.invalidateSession(sessionID)
+
+	h.answerMessage(c, ctx, requestID, session, req, conversationHistory)
+}
+
+// sessionChanged rejects the request with 409 when the client has not seen the
+// latest message of the session (after_seq)
+func sessionChanged(c *gin.Context, session database.ChatSession, afterSeq *int64) bool {
+	if afterSeq == nil || *afterSeq == session.LastSeq {
+		return false
+	}
+	middleware.AbortWithErrorDetails(c, http.StatusConflict, "session_changed",
+		"Session has new messages, reload it and retry",
+		map[string]interface{}{"last_seq": session.LastSeq})
+	return true
+}
+
+// answerMessage runs the agent on a stored user message, saves the assistant
+// reply and writes the response. conversationHistory precedes the message.
+func (h *ChatHandler) answerMessage(
+	c *gin.Context,
+	ctx context.Context,
+	requestID string,
+	session database.ChatSession,
+	req models.SendMessageRequest,
+	conversationHistory []models.Message,
+) {
 	// Process with agent (using mode from session or request)
 	mode := session.Mode
 	if req.Mode != "" {
 		mode = req.Mode
 	}
 
+	assistantMsgID := uuid.New().String()
+	// Receives the seq of the stored answer
+	assistantSaved := make(chan int64, 1)
+
+	ctx = agents.WithSession(agents.WithAnswerFormat(ctx, req.Format), session.ID)
+	ctx = agents.WithSessionSpend(agents.WithPersona(ctx, session.SystemPrompt), session.TokensUsed)
+	ctx, meter := tools.WithTokenMeter(h.traces.Trace(ctx, requestID))
+	ctx, timings := tools.WithTimings(ctx)
 	startTime := time.Now()
-	result, err := h.router.ProcessQueryWithContext(
-		c.Request.Context(),
-		req.Query,
-		mode,
-		conversationHistory,
-	)
+	var result *models.SearchResponse
+	var err error
+	if useRace(h.cfg, mode, req.Race) {
+		result, err = h.router.ProcessQueryRace(
+			ctx,
+			req.Query,
+			conversationHistory,
+			func(improved *models.SearchResponse) {
+				h.replaceAnswer(session.ID, assistantMsgID, assistantSaved, improved)
+			},
+		)
+	} else {
+		result, err = h.router.ProcessQueryWithContext(
+			ctx,
+			req.Query,
+			mode,
+			conversationHistory,
+		)
+	}
+	recordUsage(ctx, h.db, "chat", mode, result, err, time.Since(startTime), meter)
 	if err != nil {
 		log.Printf("❌ Error processing query: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeQueryError(c, ctx, err)
 		return
 	}
 
 	// Save assistant message
 	assistantMsg := database.Message{
-		ID:        uuid.New().String(),
-		SessionID: sessionID,
+		ID:        assistantMsgID,
+		SessionID: session.ID,
 		Role:      "assistant",
 		Content:   result.Answer,
 		Timestamp: time.Now().Unix(),
 		Reasoning: result.Reasoning,
+
+		RequestedMode: mode,
+		Mode:          result.Mode,
+		Agent:         agentOf(result),
+	}
+	if result.AutoRouting != nil {
+		assistantMsg.DecidedBy = result.AutoRouting.DecidedBy
 	}
 
 	// Save sources
@@ -148,40 +479,272 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 			URL:         src.URL,
 			Snippet:     src.Snippet,
 			Credibility: src.Credibility,
+			PublishedAt: src.PublishedAt,
+			FetchedAt:   src.FetchedAt,
 		})
 	}
 
-	if err := h.db.Create(&assistantMsg).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save response"})
+	if err := h.createMessage(&assistantMsg); err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to save response")
 		return
 	}
+	assistantSaved <- assistantMsg.Seq
 
-	// Update session timestamp
-	h.db.Model(&session).Update("updated_at", time.Now().Unix())
+	recordRoutingOutcome(h.db, session.ID, assistantMsg.ID, result, time.Since(startTime))
+	recordHistory(h.db, middleware.UserKey(c), session.ID, mode, result, time.Since(startTime))
+
+	// Update session timestamp, spent tokens and their cost
+	h.db.Model(&session).Updates(map[string]interface{}{
+		"updated_at":  time.Now().Unix(),
+		"tokens_used": gorm.Expr("tokens_used + ?", meter.TotalTokens()),
+		"cost_usd":    gorm.Expr("cost_usd + ?", meter.Cost()),
+	})
+	h.invalidateSession(session.ID)
 
 	// Return response
-	result.SessionID = sessionID
+	result.SessionID = session.ID
+	result.RequestID = requestID
+	result.Seq = assistantMsg.Seq
 	result.ProcessingTime = time.Since(startTime).Seconds()
+	result.Timings = timings.Breakdown(time.Since(startTime))
+	result.LLMProviders = meter.Providers()
+	result.Usage = meter.Usage()
 	result.Timestamp = time.Now().Unix()
 	result.ContextUsed = len(conversationHistory) > 0
+	renderForChannel(result, req.Channel, h.footer)
 
 	c.JSON(http.StatusOK, result)
 }
 
+// agentOf returns the agent that produced the answer, e.g. "pro" for "auto → pro"
+func agentOf(result *models.SearchResponse) string {
+	return strings.TrimPrefix(result.Mode, "auto → ")
+}
+
+// createMessage stores a message with the next sequence number of its session
+func (h *ChatHandler) createMessage(msg *database.Message) error {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&database.ChatSession{}).
+			Where("id = ?", msg.SessionID).
+			UpdateColumn("last_seq", gorm.Expr("last_seq + 1")).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&database.ChatSession{}).
+			Where("id = ?", msg.SessionID).
+			Select("last_seq").
+			Row().
+			Scan(&msg.Seq); err != nil {
+			return err
+		}
+		return tx.Create(msg).Error
+	})
+	if err == nil {
+		h.invalidateSession(msg.SessionID)
+	}
+	return err
+}
+
+// loadHistory returns the history of the session (before beforeSeq, if set)
+// in chronological order: the session summary, when there is one, then the
+// most recent messages after it, limited by ChatHistoryMaxMessages and the
+// ChatHistoryMaxChars budget. The newest message is truncated rather than
+// dropped if it alone exceeds the budget.
+func (h *ChatHandler) loadHistory(ctx context.Context, session *database.ChatSession, beforeSeq int64) ([]models.Message, error) {
+	sessionID := session.ID
+	h.summarize(ctx, session, beforeSeq)
+
+	summary, summarySeq := session.Summary, session.SummarySeq
+	if beforeSeq > 0 && summarySeq >= beforeSeq {
+		summary, summarySeq = "", 0 // covers the message being edited
+	}
+
+	query := h.db.Select("role", "content", "timestamp").Where("session_id = ? AND seq > ?", sessionID, summarySeq)
+	if beforeSeq > 0 {
+		query = query.Where("seq < ?", beforeSeq)
+	}
+
+	var messages []database.Message
+	if err := query.
+		Order("seq DESC, timestamp DESC").
+		Limit(h.cfg.ChatHistoryMaxMessages).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	history := make([]models.Message, 0, len(messages))
+	budget := h.cfg.ChatHistoryMaxChars
+	for _, msg := range messages {
+		content := msg.Content
+		if utf8.RuneCountInString(content) > budget {
+			if len(history) > 0 {
+				break
+			}
+			content = utils.TruncateRunesWithEllipsis(content, budget)
+		}
+		budget -= utf8.RuneCountInString(content)
+		history = append(history, models.Message{Role: msg.Role, Content: content})
+	}
+
+	if len(history) < len(messages) {
+		log.Printf("✂️  Session %s: history truncated to %d of %d recent messages", sessionID, len(history), len(messages))
+	}
+
+	// Back to chronological order
+	slices.Reverse(history)
+	if summary != "" {
+		history = append([]models.Message{agents.SummaryMessage(summary)}, history...)
+	}
+	return history, nil
+}
+
+// summarize folds the older messages of a long session into its summary once
+// more than ChatSummaryAfter messages follow it, keeping the most recent
+// ChatSummaryKeepRecent out. Messages beyond the ChatHistoryMaxMessages
+// before those are dropped, as the truncation would. A failed summarization
+// leaves the session to the truncation of loadHistory.
+func (h *ChatHandler) summarize(ctx context.Context, session *database.ChatSession, beforeSeq int64) {
+	if h.cfg.ChatSummaryAfter <= 0 || (beforeSeq > 0 && session.SummarySeq >= beforeSeq) {
+		return
+	}
+
+	pendingMessages := func() *gorm.DB {
+		query := h.db.Model(&database.Message{}).Where("session_id = ? AND seq > ?", session.ID, session.SummarySeq)
+		if beforeSeq > 0 {
+			query = query.Where("seq < ?", beforeSeq)
+		}
+		return query
+	}
+	var pending int64
+	if err := pendingMessages().Count(&pending).Error; err != nil || pending <= int64(h.cfg.ChatSummaryAfter) {
+		return
+	}
+
+	keep := max(h.cfg.ChatSummaryKeepRecent, 0)
+	var messages []database.Message
+	if err := pendingMessages().Select("seq", "role", "content").
+		Order("seq DESC").
+		Limit(keep + h.cfg.ChatHistoryMaxMessages).
+		Find(&messages).Error; err != nil || len(messages) <= keep {
+		return
+	}
+	older := messages[keep:]
+	slices.Reverse(older)
+	turns := make([]models.Message, len(older))
+	for i, msg := range older {
+		turns[i] = models.Message{Role: msg.Role, Content: msg.Content}
+	}
+
+	summary, err := h.router.SummarizeConversation(ctx, session.Summary, turns)
+	if err != nil {
+		log.Printf("⚠️  Session %s: %v", session.ID, err)
+		return
+	}
+	summarySeq := older[len(older)-1].Seq
+	if err := h.db.Model(&database.ChatSession{}).
+		Where("id = ?", session.ID).
+		Updates(map[string]interface{}{"summary": summary, "summary_seq": summarySeq}).Error; err != nil {
+		log.Printf("⚠️  Session %s: failed to store summary: %v", session.ID, err)
+		return
+	}
+	session.Summary, session.SummarySeq = summary, summarySeq
+	h.invalidateSession(session.ID)
+	log.Printf("🗜️  Session %s: %d messages summarized up to seq %d", session.ID, len(turns), summarySeq)
+}
+
+// errAnswerReplaced means the answer to improve is no longer in its session
+var errAnswerReplaced = errors.New("answer was replaced")
+
+// replaceAnswer swaps a stored answer for the improved Pro answer in race
+// mode. It takes the session's turn like a new message, and leaves the
+// session alone if the answer was deleted or replaced meanwhile (the question
+// edited, the session deleted).
+func (h *ChatHandler) replaceAnswer(sessionID, messageID string, saved <-chan int64, improved *models.SearchResponse) {
+	var seq int64
+	select {
+	case seq = <-saved:
+	case <-time.After(30 * time.Second):
+		log.Printf("⚠️  Message %s was never saved, dropping improved answer", messageID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	release, err := h.sessions.acquire(ctx, sessionID)
+	if err != nil {
+		log.Printf("⚠️  Session %s stayed busy, dropping improved answer for message %s: %v", sessionID, messageID, err)
+		return
+	}
+	defer release()
+
+	sources := make([]database.Source, 0, len(improved.Sources))
+	for _, src := range improved.Sources {
+		sources = append(sources, database.Source{
+			MessageID:   messageID,
+			Title:       src.Title,
+			URL:         src.URL,
+			Snippet:     src.Snippet,
+			Credibility: src.Credibility,
+			PublishedAt: src.PublishedAt,
+			FetchedAt:   src.FetchedAt,
+		})
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&database.Message{}).
+			Where("id = ? AND session_id = ? AND seq = ?", messageID, sessionID, seq).
+			Updates(map[string]interface{}{
+				"content":   improved.Answer,
+				"reasoning": improved.Reasoning,
+				"mode":      improved.Mode,
+				"agent":     agentOf(improved),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAnswerReplaced
+		}
+		if err := tx.Where("message_id = ?", messageID).Delete(&database.Source{}).Error; err != nil {
+			return err
+		}
+		if len(sources) > 0 {
+			return tx.Create(&sources).Error
+		}
+		return nil
+	})
+	if errors.Is(err, errAnswerReplaced) {
+		log.Printf("⚠️  Message %s is gone, dropping improved answer", messageID)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to store improved answer for message %s: %v", messageID, err)
+		return
+	}
+	h.invalidateSession(sessionID)
+}
+
 func (h *ChatHandler) DeleteSession(c *gin.Context) {
 	sessionID := c.Param("session_id")
 
 	// Delete messages first (cascade)
 	if err := h.db.Where("session_id = ?", sessionID).Delete(&database.Message{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete messages"})
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to delete messages")
+		return
+	}
+
+	// Revoke share links
+	if err := h.db.Where("session_id = ?", sessionID).Delete(&database.SessionShare{}).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to delete share links")
 		return
 	}
 
 	// Delete session
 	if err := h.db.Delete(&database.ChatSession{}, "id = ?", sessionID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete session"})
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to delete session")
 		return
 	}
+	h.invalidateSession(sessionID)
+	h.router.ForgetSession(sessionID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Session deleted"})
 }
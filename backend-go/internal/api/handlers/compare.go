@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/agents"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/gin-gonic/gin"
+)
+
+// Compare runs the query through simple and pro concurrently and returns both
+// answers with their timing, next to the auto mode routing decision. Answers
+// are neither cached nor stored in the history.
+func (h *SearchHandler) Compare(c *gin.Context) {
+	var req models.CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	passages, ok := documentPassages(c, h.db, h.cfg.PublicURL, req.DocumentIDs)
+	if !ok {
+		return
+	}
+
+	requestID, ctx, done, ok := startRequest(c, h.requests, req.RequestID)
+	if !ok {
+		return
+	}
+	defer done()
+
+	ctx = agents.WithAnswerLanguage(agents.WithAnswerFormat(ctx, req.Format), req.AnswerLang)
+	ctx = agents.WithDocuments(h.traces.Trace(ctx, requestID), passages)
+	logging.Printf(ctx, "⚖️  Comparing simple and pro for query: %s", req.Query)
+
+	var simple, pro models.CompareRun
+	var simpleErr, proErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		simple, simpleErr = h.compareRun(ctx, requestID, req.Query, "simple")
+	}()
+	go func() {
+		defer wg.Done()
+		pro, proErr = h.compareRun(ctx, requestID, req.Query, "pro")
+	}()
+	wg.Wait()
+
+	if simpleErr != nil && proErr != nil {
+		writeQueryError(c, ctx, simpleErr)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CompareResponse{
+		Query:     req.Query,
+		RequestID: requestID,
+		Routing:   h.router.AutoDecision(req.Query),
+		Simple:    simple,
+		Pro:       pro,
+	})
+}
+
+// compareRun answers the query in one mode; a failure is reported in the run
+// and also returned
+func (h *SearchHandler) compareRun(ctx context.Context, requestID, query, mode string) (models.CompareRun, error) {
+	ctx, meter := tools.WithTokenMeter(ctx)
+	ctx, timings := tools.WithTimings(ctx)
+	startTime := time.Now()
+	result, err := h.router.ProcessQuery(ctx, query, mode)
+	recordUsage(ctx, h.db, "compare", mode, result, err, time.Since(startTime), meter)
+
+	run := models.CompareRun{ProcessingTime: time.Since(startTime).Seconds()}
+	if err != nil {
+		logging.Printf(ctx, "❌ Compare: %s failed: %v", mode, err)
+		_, code, message := queryErrorStatus(err)
+		run.Error = &models.ErrorResponse{Code: code, Message: message, RequestID: requestID}
+		return run, err
+	}
+
+	result.RequestID = requestID
+	result.ProcessingTime = run.ProcessingTime
+	result.Timings = timings.Breakdown(time.Since(startTime))
+	result.LLMProviders = meter.Providers()
+	result.Usage = meter.Usage()
+	result.Timestamp = time.Now().Unix()
+	run.Response = result
+	return run, nil
+}
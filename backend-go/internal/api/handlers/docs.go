@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DocsHandler struct {
+	spec map[string]interface{}
+}
+
+func NewDocsHandler(spec map[string]interface{}) *DocsHandler {
+	return &DocsHandler{spec: spec}
+}
+
+func (h *DocsHandler) OpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.spec)
+}
+
+// SwaggerUI serves Swagger UI (from CDN) pointed at /api/openapi.json
+func (h *DocsHandler) SwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>Research Pro Mode API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/api/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>`
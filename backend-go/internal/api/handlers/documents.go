@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/documents"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// documentSnippetLength is the passage preview shown in the source list
+const documentSnippetLength = 200
+
+// DocumentUploadForm is the multipart form of POST /api/documents
+type DocumentUploadForm struct {
+	File []byte `json:"file" binding:"required"` // .pdf, .docx, .txt or .md
+}
+
+type DocumentsResponse struct {
+	Documents []database.Document `json:"documents"`
+}
+
+// DocumentsHandler stores documents clients upload to ground answers in
+// (SearchRequest.DocumentIDs). Documents belong to the client that uploaded
+// them (API key).
+type DocumentsHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewDocumentsHandler(db *gorm.DB, cfg *config.Config) *DocumentsHandler {
+	return &DocumentsHandler{db: db, cfg: cfg}
+}
+
+// Upload extracts the text of a PDF, DOCX or text file (multipart field
+// "file") and stores it in chunks
+func (h *DocumentsHandler) Upload(c *gin.Context) {
+	maxSize := int64(h.cfg.MaxDocumentSizeMB) << 20
+	// Leave room for the multipart headers
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+64<<10)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.abortTooLarge(c)
+			return
+		}
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "Upload the document as the multipart field \"file\"")
+		return
+	}
+	defer file.Close()
+	if header.Size > maxSize {
+		h.abortTooLarge(c)
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "Failed to read the document")
+		return
+	}
+
+	filename := filepath.Base(header.Filename)
+	text, err := documents.Extract(filename, data, maxSize)
+	switch {
+	case errors.Is(err, documents.ErrTooLarge):
+		h.abortTooLarge(c)
+		return
+	case errors.Is(err, documents.ErrUnsupported):
+		middleware.AbortWithError(c, http.StatusBadRequest, "unsupported_document", err.Error())
+		return
+	case err != nil:
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_document", err.Error())
+		return
+	}
+
+	chunks := documents.Chunk(text)
+	doc := database.Document{
+		ID:         uuid.New().String(),
+		UserKey:    middleware.UserKey(c),
+		Filename:   filename,
+		Size:       int64(len(data)),
+		Characters: utf8.RuneCountInString(text),
+		ChunkCount: len(chunks),
+		CreatedAt:  time.Now().Unix(),
+	}
+	for i, chunk := range chunks {
+		doc.Chunks = append(doc.Chunks, database.DocumentChunk{DocumentID: doc.ID, Seq: i, Content: chunk})
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Chunks").Create(&doc).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(doc.Chunks, 100).Error
+	}); err != nil {
+		logging.Printf(c.Request.Context(), "❌ Failed to store document %s: %v", filename, err)
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to store document")
+		return
+	}
+
+	logging.Printf(c.Request.Context(), "📄 Document %s uploaded: %d characters, %d chunks", filename, doc.Characters, doc.ChunkCount)
+	c.JSON(http.StatusOK, doc)
+}
+
+// List returns the caller's documents, newest first
+func (h *DocumentsHandler) List(c *gin.Context) {
+	docs := make([]database.Document, 0)
+	if err := h.db.Where("user_key = ?", middleware.UserKey(c)).
+		Order("created_at DESC").
+		Find(&docs).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to list documents")
+		return
+	}
+	c.JSON(http.StatusOK, DocumentsResponse{Documents: docs})
+}
+
+// Get returns a document of the caller
+func (h *DocumentsHandler) Get(c *gin.Context) {
+	var doc database.Document
+	if err := h.db.First(&doc, "id = ? AND user_key = ?", c.Param("document_id"), middleware.UserKey(c)).Error; err != nil {
+		abortDocumentLookup(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, doc)
+}
+
+// Delete removes a document of the caller with its chunks
+func (h *DocumentsHandler) Delete(c *gin.Context) {
+	var doc database.Document
+	if err := h.db.First(&doc, "id = ? AND user_key = ?", c.Param("document_id"), middleware.UserKey(c)).Error; err != nil {
+		abortDocumentLookup(c, err)
+		return
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", doc.ID).Delete(&database.DocumentChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&doc).Error
+	}); err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to delete document")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Document deleted"})
+}
+
+func (h *DocumentsHandler) abortTooLarge(c *gin.Context) {
+	middleware.AbortWithError(c, http.StatusRequestEntityTooLarge, "document_too_large",
+		fmt.Sprintf("Document is larger than %d MB", h.cfg.MaxDocumentSizeMB))
+}
+
+func abortDocumentLookup(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		middleware.AbortWithError(c, http.StatusNotFound, "document_not_found", "Document not found")
+	} else {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to get document")
+	}
+}
+
+// documentPassages loads the chunks of the caller's documents as sources for
+// the agents (agents.WithDocuments). It answers 401 without an API key and
+// 404 if a document is missing or belongs to another client.
+func documentPassages(c *gin.Context, db *gorm.DB, publicURL string, ids []string) ([]models.TavilyResult, bool) {
+	if len(ids) == 0 {
+		return nil, true
+	}
+	userKey := middleware.UserKey(c)
+	if userKey == "" {
+		middleware.AbortWithError(c, http.StatusUnauthorized, "unauthorized", "API key required for document_ids")
+		return nil, false
+	}
+
+	var docs []database.Document
+	if err := db.Preload("Chunks", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("seq")
+	}).Where("id IN ? AND user_key = ?", ids, userKey).Find(&docs).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to load documents")
+		return nil, false
+	}
+
+	found := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		found[doc.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			middleware.AbortWithError(c, http.StatusNotFound, "document_not_found", "Document not found: "+id)
+			return nil, false
+		}
+	}
+
+	var passages []models.TavilyResult
+	for _, doc := range docs {
+		for _, chunk := range doc.Chunks {
+			passages = append(passages, models.TavilyResult{
+				Title:   documents.Title(doc.Filename, chunk.Seq, doc.ChunkCount),
+				URL:     fmt.Sprintf("%s/api/documents/%s#chunk-%d", publicURL, doc.ID, chunk.Seq+1),
+				Content: chunk.Content,
+				Snippet: utils.TruncateRunesWithEllipsis(chunk.Content, documentSnippetLength),
+			})
+		}
+	}
+	return passages, true
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/agents"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	estimateWindowDays = 30
+	// minEstimateSamples is the usage history a mode needs before it replaces
+	// the built-in defaults
+	minEstimateSamples = 5
+)
+
+// agentUsage is the average of successful, uncached queries of one agent
+type agentUsage struct {
+	Agent               string
+	Samples             int64
+	AvgLatencyMs        float64
+	AvgPromptTokens     float64
+	AvgCompletionTokens float64
+}
+
+// Estimate predicts latency, LLM tokens and cost of a query in each mode from
+// the recent usage of the agents, scaled by the complexity of the query
+func (h *SearchHandler) Estimate(c *gin.Context) {
+	var req models.EstimateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	modes := h.router.Modes()
+	if req.Mode != "" && !hasMode(modes, req.Mode) {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "Unknown mode: "+req.Mode)
+		return
+	}
+
+	usage, err := h.agentUsage()
+	if err != nil {
+		logging.Printf(c.Request.Context(), "❌ Failed to load usage for estimate: %v", err)
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to estimate the query")
+		return
+	}
+
+	features, proProbability := h.router.QueryProfile(req.Query)
+	complexity := 1 + 0.5*features.MultiHop + 0.1*math.Min(features.ComplexIndicators, 3)
+
+	byMode := make(map[string]models.ModeEstimate, len(modes))
+	for _, mode := range modes {
+		if mode.Name == "auto" {
+			continue
+		}
+		estimate := baseEstimate(mode.Name, usage)
+		if mode.Name != "simple" && mode.Name != "instant" && mode.Name != "images" {
+			estimate = scaleEstimate(estimate, complexity)
+		}
+		byMode[mode.Name] = estimate
+	}
+	if decidedBy := h.router.AutoDecision(req.Query).DecidedBy; decidedBy == "instant" || decidedBy == "images" {
+		auto := byMode[decidedBy]
+		auto.Mode = "auto"
+		byMode["auto"] = auto
+	} else {
+		byMode["auto"] = mixEstimates("auto", byMode["simple"], byMode["pro"], proProbability)
+	}
+
+	estimates := make([]models.ModeEstimate, 0, len(modes))
+	for _, mode := range modes {
+		if req.Mode != "" && mode.Name != req.Mode {
+			continue
+		}
+		estimate := byMode[mode.Name]
+		estimate.CostUSD = h.estimateCost(estimate)
+		estimates = append(estimates, estimate)
+	}
+
+	c.JSON(http.StatusOK, models.EstimateResponse{
+		Query:          req.Query,
+		Complexity:     complexity,
+		ProProbability: proProbability,
+		Estimates:      estimates,
+	})
+}
+
+// agentUsage averages the successful, uncached queries of the last
+// estimateWindowDays days per agent
+func (h *SearchHandler) agentUsage() (map[string]agentUsage, error) {
+	from := time.Now().UTC().AddDate(0, 0, -(estimateWindowDays - 1)).Format(usageDayFormat)
+
+	var rows []agentUsage
+	err := h.db.Model(&database.Usage{}).
+		Select(`agent, COUNT(*) AS samples,
+			AVG(latency_ms) AS avg_latency_ms,
+			AVG(prompt_tokens) AS avg_prompt_tokens,
+			AVG(completion_tokens) AS avg_completion_tokens`).
+		Where("day >= ? AND status = ? AND cached = ?", from, "ok", false).
+		Group("agent").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]agentUsage, len(rows))
+	for _, row := range rows {
+		usage[row.Agent] = row
+	}
+	return usage, nil
+}
+
+// baseEstimate is the average query of the mode's agent, or the default when
+// the agent has too little history
+func baseEstimate(mode string, usage map[string]agentUsage) models.ModeEstimate {
+	if u, ok := usage[mode]; ok && u.Samples >= minEstimateSamples {
+		return models.ModeEstimate{
+			Mode:             mode,
+			LatencyMs:        int64(u.AvgLatencyMs),
+			PromptTokens:     int64(u.AvgPromptTokens),
+			CompletionTokens: int64(u.AvgCompletionTokens),
+			Samples:          u.Samples,
+		}
+	}
+
+	return agents.DefaultEstimate(mode)
+}
+
+func scaleEstimate(e models.ModeEstimate, factor float64) models.ModeEstimate {
+	e.LatencyMs = int64(float64(e.LatencyMs) * factor)
+	e.PromptTokens = int64(float64(e.PromptTokens) * factor)
+	e.CompletionTokens = int64(float64(e.CompletionTokens) * factor)
+	return e
+}
+
+// mixEstimates weights the Simple and Pro estimates by the chance of Pro
+func mixEstimates(mode string, simple, pro models.ModeEstimate, proProbability float64) models.ModeEstimate {
+	mix := func(a, b int64) int64 {
+		return int64(float64(a)*(1-proProbability) + float64(b)*proProbability)
+	}
+	return models.ModeEstimate{
+		Mode:             mode,
+		LatencyMs:        mix(simple.LatencyMs, pro.LatencyMs),
+		PromptTokens:     mix(simple.PromptTokens, pro.PromptTokens),
+		CompletionTokens: mix(simple.CompletionTokens, pro.CompletionTokens),
+		Samples:          simple.Samples + pro.Samples,
+	}
+}
+
+func (h *SearchHandler) estimateCost(e models.ModeEstimate) float64 {
+	return h.router.EstimateCost(e)
+}
+
+func hasMode(modes []models.ModeInfo, name string) bool {
+	for _, mode := range modes {
+		if mode.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/agents"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// writeExplain answers an explain request (explain=true): it runs only the
+// planning stages of the query and responds with the plan. Nothing is stored,
+// cached or counted as usage.
+func writeExplain(
+	c *gin.Context,
+	ctx context.Context,
+	router *agents.RouterAgent,
+	requestID, query, mode string,
+	conversationHistory []models.Message,
+) {
+	startTime := time.Now()
+	explained, err := router.Explain(ctx, query, mode, conversationHistory)
+	if err != nil {
+		writeQueryError(c, ctx, err)
+		return
+	}
+
+	explained.RequestID = requestID
+	explained.ProcessingTime = time.Since(startTime).Seconds()
+	c.JSON(http.StatusOK, explained)
+}
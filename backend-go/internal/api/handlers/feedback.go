@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type FeedbackHandler struct {
+	db *gorm.DB
+}
+
+func NewFeedbackHandler(db *gorm.DB) *FeedbackHandler {
+	return &FeedbackHandler{db: db}
+}
+
+func (h *FeedbackHandler) SubmitFeedback(c *gin.Context) {
+	var req models.FeedbackRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	// Only assistant answers can be rated
+	var message database.Message
+	if err := h.db.Preload("Sources").First(&message, "id = ?", req.MessageID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			middleware.AbortWithError(c, http.StatusNotFound, "message_not_found", "Message not found")
+		} else {
+			middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to get message")
+		}
+		return
+	}
+
+	if message.Role != "assistant" {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "Only assistant messages can be rated")
+		return
+	}
+
+	feedback := database.Feedback{
+		MessageID: message.ID,
+		SessionID: message.SessionID,
+		Rating:    req.Rating,
+		Comment:   req.Comment,
+		CreatedAt: time.Now().Unix(),
+		Sources:   message.Sources,
+	}
+
+	if err := h.db.Omit("Sources.*").Create(&feedback).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to save feedback")
+		return
+	}
+
+	// Attach the rating to the auto mode routing outcome, if any
+	if err := h.db.Model(&database.RoutingOutcome{}).
+		Where("message_id = ?", message.ID).
+		Update("rating", req.Rating).Error; err != nil {
+		log.Printf("⚠️  Failed to update routing outcome rating: %v", err)
+	}
+
+	c.JSON(http.StatusOK, feedback)
+}
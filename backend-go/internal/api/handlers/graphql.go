@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/graphql"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type GraphQLHandler struct {
+	db     *gorm.DB
+	schema *graphql.Schema
+}
+
+func NewGraphQLHandler(db *gorm.DB) *GraphQLHandler {
+	h := &GraphQLHandler{db: db}
+	h.schema = h.buildSchema()
+	return h
+}
+
+// Query executes a GraphQL query (POST body or GET ?query=)
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req graphql.Request
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if req.Query == "" {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "query is required")
+		return
+	}
+
+	result := h.schema.Execute(c.Request.Context(), req)
+	if result.Data == nil {
+		c.JSON(http.StatusBadRequest, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// buildSchema exposes sessions, messages and sources; sources are only
+// loaded when they are selected. As in the REST API, the session id is the
+// key to a session, so sessions can't be listed.
+func (h *GraphQLHandler) buildSchema() *graphql.Schema {
+	schema := graphql.NewSchema("Query")
+
+	schema.AddType("Query", map[string]*graphql.Field{
+		"session": {
+			Type: "ChatSession",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				var session database.ChatSession
+				err := h.db.WithContext(p.Context).First(&session, "id = ?", graphql.StringArg(p.Args, "id", "")).Error
+				if err == gorm.ErrRecordNotFound {
+					return nil, nil
+				}
+				return &session, err
+			},
+		},
+		"message": {
+			Type: "Message",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				query := h.db.WithContext(p.Context)
+				if p.Selection.Selected("sources") {
+					query = query.Preload("Sources")
+				}
+				var message database.Message
+				err := query.First(&message, "id = ?", graphql.StringArg(p.Args, "id", "")).Error
+				if err == gorm.ErrRecordNotFound {
+					return nil, nil
+				}
+				return &message, err
+			},
+		},
+	})
+
+	schema.AddType("ChatSession", map[string]*graphql.Field{
+		"id":         {Type: graphql.String},
+		"mode":       {Type: graphql.String},
+		"created_at": {Type: graphql.Int},
+		"updated_at": {Type: graphql.Int},
+		"last_seq":   {Type: graphql.Int},
+		"message_count": {
+			Type: graphql.Int,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				var count int64
+				err := h.db.WithContext(p.Context).Model(&database.Message{}).
+					Where("session_id = ?", p.Source.(*database.ChatSession).ID).
+					Count(&count).Error
+				return count, err
+			},
+		},
+		// Same paging as GET /api/chat/session/:session_id: offset 0 is the
+		// most recent messages, returned in chronological order
+		"messages": {
+			Type: graphql.ListOf("Message"),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				query := h.db.WithContext(p.Context)
+				if p.Selection.Selected("sources") {
+					query = query.Preload("Sources")
+				}
+				query = query.Where("session_id = ?", p.Source.(*database.ChatSession).ID)
+				if agent := graphql.StringArg(p.Args, "agent", ""); agent != "" {
+					query = query.Where("agent = ?", agent)
+				}
+				messages := make([]database.Message, 0)
+				err := query.
+					Order("seq DESC, timestamp DESC").
+					Offset(graphql.IntArg(p.Args, "offset", 0)).
+					Limit(clampPageSize(graphql.IntArg(p.Args, "limit", defaultSessionPageSize), maxSessionPageSize)).
+					Find(&messages).Error
+				slices.Reverse(messages)
+				return messages, err
+			},
+		},
+	})
+
+	schema.AddType("Message", map[string]*graphql.Field{
+		"id":         {Type: graphql.String},
+		"session_id": {Type: graphql.String},
+		"seq":        {Type: graphql.Int},
+		"role":       {Type: graphql.String},
+		"content":    {Type: graphql.String},
+		"timestamp":  {Type: graphql.Int},
+		"reasoning":  {Type: graphql.String},
+		"sources":    {Type: graphql.ListOf("Source")},
+
+		"requested_mode": {Type: graphql.String},
+		"mode":           {Type: graphql.String},
+		"agent":          {Type: graphql.String},
+		"decided_by":     {Type: graphql.String},
+	})
+
+	schema.AddType("Source", map[string]*graphql.Field{
+		"title":        {Type: graphql.String},
+		"url":          {Type: graphql.String},
+		"snippet":      {Type: graphql.String},
+		"credibility":  {Type: graphql.Float},
+		"published_at": {Type: graphql.Int},
+		"fetched_at":   {Type: graphql.Int},
+	})
+
+	return schema
+}
+
+func clampPageSize(size, max int) int {
+	if size < 1 {
+		return 1
+	}
+	if size > max {
+		return max
+	}
+	return size
+}
@@ -1,20 +1,158 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
 )
 
-type HealthHandler struct{}
+// healthCacheTTL is how long probe results are reused: the endpoint is
+// public, and the LLM probe is a call to the provider
+const healthCacheTTL = 10 * time.Second
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+type HealthHandler struct {
+	db           *gorm.DB
+	redis        *redis.Client
+	searchClient *tools.SearchClient
+	llmClient    *tools.LLMClient
+
+	// mu is held while probing, so concurrent requests share one probe
+	mu        sync.Mutex
+	results   map[string]DependencyStatus
+	checkedAt time.Time
+}
+
+type DependencyStatus struct {
+	Status    string `json:"status"` // ok, down
+	LatencyMs int64  `json:"latency_ms"`
+	Critical  bool   `json:"critical"`
+	Error     string `json:"error,omitempty"`
 }
 
+func NewHealthHandler(db *gorm.DB, redisClient *redis.Client, cfg *config.Config) *HealthHandler {
+	return &HealthHandler{
+		db:           db,
+		redis:        redisClient,
+		searchClient: tools.NewSearchClient().WithTavily(cfg.TavilyAPIKey, cfg.TavilySearchDepth).WithSerpAPI(cfg.SerpAPIKey),
+		llmClient:    tools.NewLLMClient(cfg),
+	}
+}
+
+// Health probes all dependencies in parallel. Overall status is "ok", "degraded"
+// (a non-critical dependency is down) or "down" (the database is down, 503).
+// Per-dependency details are returned with ?verbose=1; the errors behind them
+// are only logged.
 func (h *HealthHandler) Health(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "ok",
+	results := h.probe()
+
+	overall := "ok"
+	httpStatus := http.StatusOK
+	for _, status := range results {
+		if status.Status == "ok" {
+			continue
+		}
+		if status.Critical {
+			overall = "down"
+			httpStatus = http.StatusServiceUnavailable
+			break
+		}
+		overall = "degraded"
+	}
+
+	response := gin.H{
+		"status":  overall,
 		"service": "Research Pro Mode API",
-	})
+	}
+	if verbose := c.Query("verbose"); verbose == "1" || verbose == "true" {
+		response["dependencies"] = results
+	}
+
+	c.JSON(httpStatus, response)
+}
+
+// probe returns the dependency statuses, probed at most once per
+// healthCacheTTL
+func (h *HealthHandler) probe() map[string]DependencyStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.results != nil && time.Since(h.checkedAt) < healthCacheTTL {
+		return h.results
+	}
+
+	checks := map[string]struct {
+		critical bool
+		probe    func(ctx context.Context) error
+	}{
+		"database": {true, h.pingDatabase},
+		"redis":    {false, h.pingRedis},
+		"searxng":  {false, h.searchClient.Ping},
+		"llm":      {false, h.pingLLM},
+	}
+
+	results := make(map[string]DependencyStatus, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, critical bool, probe func(ctx context.Context) error) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			err := probe(ctx)
+			status := DependencyStatus{
+				Status:    "ok",
+				LatencyMs: time.Since(start).Milliseconds(),
+				Critical:  critical,
+			}
+			if err != nil {
+				log.Printf("⚠️  Health check of %s failed: %v", name, err)
+				status.Status = "down"
+				status.Error = "unavailable"
+			}
+
+			mu.Lock()
+			results[name] = status
+			mu.Unlock()
+		}(name, check.critical, check.probe)
+	}
+	wg.Wait()
+
+	h.results, h.checkedAt = results, time.Now()
+	return results
+}
+
+func (h *HealthHandler) pingDatabase(ctx context.Context) error {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// pingLLM reports the LLM down without a call while its circuit is open
+func (h *HealthHandler) pingLLM(ctx context.Context) error {
+	if h.llmClient.CircuitOpen() {
+		return tools.ErrLLMCircuitOpen
+	}
+	return h.llmClient.Ping(ctx)
+}
+
+func (h *HealthHandler) pingRedis(ctx context.Context) error {
+	if h.redis == nil {
+		return fmt.Errorf("redis not connected")
+	}
+	return h.redis.Ping(ctx).Err()
 }
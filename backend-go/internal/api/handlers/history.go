@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultHistoryPageSize = 20
+	maxHistoryPageSize     = 100
+	historySummaryLength   = 300
+)
+
+// HistoryResponse is a page of the caller's search history
+type HistoryResponse struct {
+	Items    []database.History `json:"items"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+	Total    int64              `json:"total"`
+}
+
+type HistoryHandler struct {
+	db *gorm.DB
+}
+
+func NewHistoryHandler(db *gorm.DB) *HistoryHandler {
+	return &HistoryHandler{db: db}
+}
+
+// GetHistory returns the caller's past queries, newest first
+func (h *HistoryHandler) GetHistory(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultHistoryPageSize)))
+	if pageSize < 1 {
+		pageSize = defaultHistoryPageSize
+	}
+	if pageSize > maxHistoryPageSize {
+		pageSize = maxHistoryPageSize
+	}
+
+	query := h.db.Model(&database.History{}).Where("user_key = ?", middleware.UserKey(c))
+	if mode := c.Query("mode"); mode != "" {
+		query = query.Where("mode = ?", mode)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to count history")
+		return
+	}
+
+	items := make([]database.History, 0)
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&items).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to get history")
+		return
+	}
+
+	c.JSON(http.StatusOK, HistoryResponse{
+		Items:    items,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
+}
+
+// recordHistory stores a finished query in the history of userKey
+// (middleware.ClientID of the caller)
+func recordHistory(
+	db *gorm.DB,
+	userKey, sessionID, requestedMode string,
+	result *models.SearchResponse,
+	latency time.Duration,
+) {
+	// Callers without an API key have no history of their own
+	if userKey == "" {
+		return
+	}
+
+	entry := database.History{
+		UserKey:       userKey,
+		SessionID:     sessionID,
+		Query:         result.Query,
+		RequestedMode: requestedMode,
+		Mode:          result.Mode,
+		LatencyMs:     latency.Milliseconds(),
+		AnswerSummary: utils.TruncateRunesWithEllipsis(result.Answer, historySummaryLength),
+		SourcesCount:  len(result.Sources),
+		CreatedAt:     time.Now().Unix(),
+	}
+
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("⚠️  Failed to record history: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/agents"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/hooks"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/jobs"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/render"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/webhook"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxHookEventSize limits the body of inbound events
+const maxHookEventSize = 64 << 10
+
+// HooksHandler runs research requested by external systems (inbound hooks)
+// and delivers the report to the hook's channel
+type HooksHandler struct {
+	db       *gorm.DB
+	router   *agents.RouterAgent
+	jobs     *jobs.Store
+	hooks    map[string]*hooks.Hook
+	replays  *hooks.Replays
+	telegram *hooks.Telegram
+	traces   *TraceWriter
+	footer   *render.Footer
+}
+
+func NewHooksHandler(
+	db *gorm.DB,
+	router *agents.RouterAgent,
+	jobStore *jobs.Store,
+	configured map[string]*hooks.Hook,
+	replays *hooks.Replays,
+	telegram *hooks.Telegram,
+	traces *TraceWriter,
+	footer *render.Footer,
+) *HooksHandler {
+	return &HooksHandler{
+		db:       db,
+		router:   router,
+		jobs:     jobStore,
+		hooks:    configured,
+		replays:  replays,
+		telegram: telegram,
+		traces:   traces,
+		footer:   footer,
+	}
+}
+
+// Trigger accepts a signed event, fills the hook's query template with it and
+// runs the research as a background job
+func (h *HooksHandler) Trigger(c *gin.Context) {
+	hook, ok := h.hooks[c.Param("name")]
+	if !ok {
+		middleware.AbortWithError(c, http.StatusNotFound, "hook_not_found", "Hook not found")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxHookEventSize+1))
+	if err != nil || len(body) > maxHookEventSize {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "Event body is too large or unreadable")
+		return
+	}
+
+	timestamp, signature := c.GetHeader(webhook.HeaderTimestamp), c.GetHeader(webhook.HeaderSignature)
+	if err := hook.Verify(timestamp, signature, body); err != nil {
+		middleware.AbortWithError(c, http.StatusUnauthorized, "invalid_signature", err.Error())
+		return
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "Event must be a JSON object")
+		return
+	}
+	query, err := hook.Query(event)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "Event does not fit the hook template: "+err.Error())
+		return
+	}
+
+	if hook.Channel == hooks.ChannelTelegram && !h.telegram.Enabled() {
+		middleware.AbortWithError(c, http.StatusServiceUnavailable, "channel_unavailable", "Telegram delivery needs TELEGRAM_BOT_TOKEN")
+		return
+	}
+
+	// Recorded last, so that a rejected event can be fixed and sent again
+	if err := h.replays.Accept(c.Request.Context(), hook.Name, timestamp, signature); err != nil {
+		middleware.AbortWithError(c, http.StatusConflict, "duplicate_event", err.Error())
+		return
+	}
+
+	requestID := logging.RequestID(c.Request.Context())
+	logging.Printf(c.Request.Context(), "🪝 Hook %s triggered: %s", hook.Name, query)
+
+	jobIDs := make(chan string, 1)
+	job := h.jobs.Submit("hook-"+hook.Name, 3*time.Minute, func(ctx context.Context) (*models.SearchResponse, error) {
+		ctx = logging.WithRequestID(ctx, requestID)
+		if hook.Channel == hooks.ChannelTelegram {
+			// MarkdownV2 is produced by the telegram renderer
+			ctx = agents.WithAnswerFormat(ctx, agents.AnswerFormatPlain)
+		}
+		ctx, meter := tools.WithTokenMeter(h.traces.Trace(ctx, requestID))
+		ctx, timings := tools.WithTimings(ctx)
+		startTime := time.Now()
+		result, err := h.router.ProcessQuery(ctx, query, hook.Mode)
+		recordUsage(ctx, h.db, "hook", hook.Mode, result, err, time.Since(startTime), meter)
+		if err != nil {
+			logging.Printf(ctx, "❌ Hook %s query failed: %v", hook.Name, err)
+		} else {
+			result.ProcessingTime = time.Since(startTime).Seconds()
+			result.Timings = timings.Breakdown(time.Since(startTime))
+			result.LLMProviders = meter.Providers()
+			result.Usage = meter.Usage()
+			result.Timestamp = time.Now().Unix()
+		}
+
+		// Deliver even if the query failed; the job keeps the result for polling
+		jobID := <-jobIDs
+		deliverCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if deliverErr := h.deliver(deliverCtx, hook, jobID, requestID, query, result, err); deliverErr != nil {
+			log.Printf("❌ Report of hook %s (job %s) was not delivered: %v", hook.Name, jobID, deliverErr)
+		}
+
+		return result, err
+	})
+	jobIDs <- job.ID
+
+	c.JSON(http.StatusAccepted, models.JobAcceptedResponse{
+		JobID:  job.ID,
+		Status: string(job.Status),
+	})
+}
+
+// deliver sends the report (or the failure) to the hook's channel
+func (h *HooksHandler) deliver(
+	ctx context.Context,
+	hook *hooks.Hook,
+	jobID, requestID, query string,
+	result *models.SearchResponse,
+	queryErr error,
+) error {
+	switch hook.Channel {
+	case hooks.ChannelWebhook:
+		var payload interface{} = result
+		if queryErr != nil {
+			_, code, message := queryErrorStatus(queryErr)
+			payload = models.ErrorResponse{Code: code, Message: message, RequestID: requestID}
+		}
+		return webhook.NewSender(hook.Secret).Deliver(ctx, hook.URL, jobID, payload)
+
+	case hooks.ChannelTelegram:
+		if err := h.telegram.Send(ctx, hook.ChatID, fmt.Sprintf("🔔 %s\n❓ %s", hook.Name, query), ""); err != nil {
+			return err
+		}
+		if queryErr != nil {
+			_, _, message := queryErrorStatus(queryErr)
+			return h.telegram.Send(ctx, hook.ChatID, "❌ "+message, "")
+		}
+
+		rendered, err := render.Render(result, render.FormatMarkdownV2, h.footer)
+		if err == nil {
+			err = h.telegram.Send(ctx, hook.ChatID, rendered.Text, "MarkdownV2")
+		}
+		if err != nil {
+			// Fall back to plain text if Telegram rejects the markup
+			plain, _ := render.Render(result, render.FormatPlain, h.footer)
+			return h.telegram.Send(ctx, hook.ChatID, utils.TruncateRunesWithEllipsis(plain.Text, 4000), "")
+		}
+		return nil
+
+	default:
+		return errors.New("unknown channel " + hook.Channel)
+	}
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/integrations"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	exportTimeout     = 60 * time.Second
+	exportTitleLength = 80
+)
+
+// IntegrationsHandler connects Notion / Google Docs accounts of API clients
+// (OAuth) and exports chat sessions to them
+type IntegrationsHandler struct {
+	db        *gorm.DB
+	cfg       *config.Config
+	providers map[string]integrations.Provider
+}
+
+func NewIntegrationsHandler(db *gorm.DB, cfg *config.Config) *IntegrationsHandler {
+	providers := integrations.NewProviders(cfg)
+	if len(providers) > 0 && cfg.OAuthStateSecret == "" {
+		logging.Printf(context.Background(), "⚠️  Session export disabled: OAUTH_STATE_SECRET is not set")
+		providers = nil
+	}
+	return &IntegrationsHandler{db: db, cfg: cfg, providers: providers}
+}
+
+// List returns the configured providers and whether the caller connected them
+func (h *IntegrationsHandler) List(c *gin.Context) {
+	var tokens []database.IntegrationToken
+	if err := h.db.Where("user_key = ?", middleware.UserKey(c)).Find(&tokens).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to list integrations")
+		return
+	}
+	connected := make(map[string]int64, len(tokens))
+	for _, token := range tokens {
+		connected[token.Provider] = token.CreatedAt
+	}
+
+	statuses := make([]models.IntegrationStatus, 0, len(h.providers))
+	for _, name := range []string{integrations.Notion, integrations.GoogleDocs} {
+		if _, ok := h.providers[name]; !ok {
+			continue
+		}
+		connectedAt, ok := connected[name]
+		statuses = append(statuses, models.IntegrationStatus{Provider: name, Connected: ok, ConnectedAt: connectedAt})
+	}
+
+	c.JSON(http.StatusOK, models.IntegrationsResponse{Integrations: statuses})
+}
+
+// Connect returns the OAuth consent URL for the caller; the browser calling it
+// gets the cookie the callback requires
+func (h *IntegrationsHandler) Connect(c *gin.Context) {
+	name, provider, ok := h.provider(c)
+	if !ok {
+		return
+	}
+	authURL, err := h.authURL(c, name, provider)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to start the connection")
+		return
+	}
+	c.JSON(http.StatusOK, models.ConnectResponse{AuthURL: authURL})
+}
+
+// Callback is where the provider sends the browser after the consent screen;
+// it stores the token for the client that started the flow, if the flow was
+// started in this browser and has not been finished before
+func (h *IntegrationsHandler) Callback(c *gin.Context) {
+	name, provider, ok := h.provider(c)
+	if !ok {
+		return
+	}
+
+	if reason := c.Query("error"); reason != "" {
+		c.String(http.StatusBadRequest, "Подключение отменено: %s", reason)
+		return
+	}
+
+	nonce, err := integrations.VerifyState(h.cfg.OAuthStateSecret, name, c.Query("state"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Ссылка подключения устарела, запросите новую")
+		return
+	}
+	// Someone else's consent link must not connect this browser's account
+	// to their API key
+	cookie, _ := c.Cookie(stateCookie(name))
+	if !hmac.Equal([]byte(cookie), []byte(nonce)) {
+		c.String(http.StatusBadRequest, "Подключение начато в другом браузере, запросите новую ссылку здесь")
+		return
+	}
+	h.setStateCookie(c, name, "", -1)
+	userKey, err := h.consumeState(name, nonce)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Ссылка подключения уже использована, запросите новую")
+		return
+	}
+
+	token, err := provider.Exchange(c.Request.Context(), c.Query("code"), h.redirectURL(name))
+	if err != nil {
+		logging.Printf(c.Request.Context(), "❌ Failed to connect %s: %v", name, err)
+		c.String(http.StatusBadGateway, "Не удалось подключить %s, попробуйте еще раз", name)
+		return
+	}
+
+	record := database.IntegrationToken{
+		UserKey:      userKey,
+		Provider:     name,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    token.ExpiresAt,
+	}
+	if err := h.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_key"}, {Name: "provider"}},
+		DoUpdates: clause.AssignmentColumns([]string{"access_token", "refresh_token", "expires_at", "updated_at"}),
+	}).Create(&record).Error; err != nil {
+		logging.Printf(c.Request.Context(), "❌ Failed to store %s token: %v", name, err)
+		c.String(http.StatusInternalServerError, "Не удалось сохранить подключение")
+		return
+	}
+
+	c.String(http.StatusOK, "✅ %s подключен, вкладку можно закрыть и повторить экспорт", name)
+}
+
+// Disconnect forgets the caller's token of a provider
+func (h *IntegrationsHandler) Disconnect(c *gin.Context) {
+	name, _, ok := h.provider(c)
+	if !ok {
+		return
+	}
+	if err := h.db.Where("user_key = ? AND provider = ?", middleware.UserKey(c), name).
+		Delete(&database.IntegrationToken{}).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to disconnect")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Integration disconnected"})
+}
+
+// ExportSession creates a document with the session's questions, answers and
+// sources in the caller's Notion or Google Docs
+func (h *IntegrationsHandler) ExportSession(c *gin.Context) {
+	sessionID := c.Param("session_id")
+
+	var req models.ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	provider, ok := h.providers[req.Provider]
+	if !ok {
+		middleware.AbortWithError(c, http.StatusNotFound, "integration_not_found", "Integration is not configured: "+req.Provider)
+		return
+	}
+
+	var session database.ChatSession
+	if err := h.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "session_not_found", "Session not found")
+		return
+	}
+	var messages []database.Message
+	if err := h.db.Preload("Sources").Where("session_id = ?", sessionID).Order("seq").Find(&messages).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to get session")
+		return
+	}
+
+	var record database.IntegrationToken
+	err := h.db.First(&record, "user_key = ? AND provider = ?", middleware.UserKey(c), req.Provider).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		h.abortNotConnected(c, req.Provider, provider)
+		return
+	}
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to load integration")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), exportTimeout)
+	defer cancel()
+
+	export, err := h.export(ctx, provider, &record, sessionDocument(session, messages), req.ParentID)
+	switch {
+	case errors.Is(err, integrations.ErrUnauthorized):
+		h.db.Delete(&record)
+		h.abortNotConnected(c, req.Provider, provider)
+		return
+	case errors.Is(err, integrations.ErrNoParent):
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request",
+			"No page is shared with the integration, share one or pass parent_id")
+		return
+	case err != nil:
+		logging.Printf(ctx, "❌ Export to %s failed: %v", req.Provider, err)
+		middleware.AbortWithError(c, http.StatusBadGateway, "export_failed", "Export failed: "+err.Error())
+		return
+	}
+
+	logging.Printf(ctx, "📤 Session %s exported to %s", sessionID, req.Provider)
+	c.JSON(http.StatusOK, models.ExportResponse{
+		Provider:   req.Provider,
+		DocumentID: export.ID,
+		URL:        export.URL,
+	})
+}
+
+// export refreshes an expired token before creating the document
+func (h *IntegrationsHandler) export(
+	ctx context.Context,
+	provider integrations.Provider,
+	record *database.IntegrationToken,
+	doc integrations.Document,
+	parentID string,
+) (*integrations.Export, error) {
+	token := &integrations.Token{
+		AccessToken:  record.AccessToken,
+		RefreshToken: record.RefreshToken,
+		ExpiresAt:    record.ExpiresAt,
+	}
+	if token.Expired() {
+		refreshed, err := provider.Refresh(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		record.AccessToken = refreshed.AccessToken
+		record.RefreshToken = refreshed.RefreshToken
+		record.ExpiresAt = refreshed.ExpiresAt
+		if err := h.db.Save(record).Error; err != nil {
+			logging.Printf(ctx, "⚠️  Failed to store refreshed token: %v", err)
+		}
+		token = refreshed
+	}
+	return provider.Export(ctx, token.AccessToken, doc, parentID)
+}
+
+// abortNotConnected answers 409 with the consent URL the caller should open
+func (h *IntegrationsHandler) abortNotConnected(c *gin.Context, name string, provider integrations.Provider) {
+	authURL, err := h.authURL(c, name, provider)
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to start the connection")
+		return
+	}
+	middleware.AbortWithErrorDetails(c, http.StatusConflict, "integration_not_connected",
+		fmt.Sprintf("Connect %s first by opening auth_url", name),
+		map[string]interface{}{"auth_url": authURL})
+}
+
+func (h *IntegrationsHandler) provider(c *gin.Context) (string, integrations.Provider, bool) {
+	name := c.Param("provider")
+	provider, ok := h.providers[name]
+	if !ok {
+		middleware.AbortWithError(c, http.StatusNotFound, "integration_not_found", "Integration is not configured: "+name)
+	}
+	return name, provider, ok
+}
+
+// authURL starts an OAuth flow of the caller: the flow is stored under a
+// nonce that goes into the state and into a cookie of the calling browser
+func (h *IntegrationsHandler) authURL(c *gin.Context, name string, provider integrations.Provider) (string, error) {
+	now := time.Now()
+	record := database.OAuthState{
+		Nonce:     uuid.New().String(),
+		UserKey:   middleware.UserKey(c),
+		Provider:  name,
+		ExpiresAt: now.Add(integrations.StateTTL).Unix(),
+	}
+	if err := h.db.Where("expires_at < ?", now.Unix()).Delete(&database.OAuthState{}).Error; err != nil {
+		logging.Printf(c.Request.Context(), "⚠️  Failed to delete expired OAuth states: %v", err)
+	}
+	if err := h.db.Create(&record).Error; err != nil {
+		logging.Printf(c.Request.Context(), "❌ Failed to store OAuth state: %v", err)
+		return "", err
+	}
+
+	h.setStateCookie(c, name, record.Nonce, int(integrations.StateTTL.Seconds()))
+	state := integrations.SignState(h.cfg.OAuthStateSecret, name, record.Nonce)
+	return provider.AuthURL(h.redirectURL(name), state), nil
+}
+
+// consumeState deletes the stored flow of nonce and returns the client that
+// started it; a flow is finished once
+func (h *IntegrationsHandler) consumeState(name, nonce string) (string, error) {
+	var record database.OAuthState
+	if err := h.db.First(&record, "nonce = ? AND provider = ? AND expires_at >= ?", nonce, name, time.Now().Unix()).Error; err != nil {
+		return "", err
+	}
+	result := h.db.Where("nonce = ?", nonce).Delete(&database.OAuthState{})
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		// A concurrent callback finished the flow first
+		return "", integrations.ErrInvalidState
+	}
+	return record.UserKey, nil
+}
+
+// stateCookie is the cookie holding the nonce of the provider's OAuth flow
+func stateCookie(name string) string {
+	return "oauth_state_" + name
+}
+
+// setStateCookie sets the nonce cookie, scoped to the provider's callback;
+// Lax, so it comes along with the provider's redirect back to us
+func (h *IntegrationsHandler) setStateCookie(c *gin.Context, name, nonce string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(stateCookie(name), nonce, maxAge, "/api/integrations/"+name,
+		"", strings.HasPrefix(h.cfg.PublicURL, "https://"), true)
+}
+
+func (h *IntegrationsHandler) redirectURL(name string) string {
+	return h.cfg.PublicURL + "/api/integrations/" + name + "/callback"
+}
+
+// sessionDocument turns the session into questions with their answers; the
+// title is the first question
+func sessionDocument(session database.ChatSession, messages []database.Message) integrations.Document {
+	doc := integrations.Document{
+		Title: "Research " + time.Unix(session.CreatedAt, 0).UTC().Format("2006-01-02"),
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "user":
+			if len(doc.Sections) == 0 {
+				doc.Title = utils.TruncateRunesWithEllipsis(msg.Content, exportTitleLength)
+			}
+			doc.Sections = append(doc.Sections, integrations.Section{Heading: msg.Content})
+		case "assistant":
+			if len(doc.Sections) == 0 {
+				doc.Sections = append(doc.Sections, integrations.Section{})
+			}
+			section := &doc.Sections[len(doc.Sections)-1]
+			if section.Body != "" {
+				section.Body += "\n\n"
+			}
+			section.Body += msg.Content
+			for _, source := range msg.Sources {
+				section.Sources = append(section.Sources, integrations.Link{Title: source.Title, URL: source.URL})
+			}
+		}
+	}
+	return doc
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/jobs"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/render"
+	"github.com/gin-gonic/gin"
+)
+
+type JobsHandler struct {
+	store  *jobs.Store
+	footer *render.Footer
+}
+
+func NewJobsHandler(store *jobs.Store, footer *render.Footer) *JobsHandler {
+	return &JobsHandler{store: store, footer: footer}
+}
+
+func (h *JobsHandler) GetJob(c *gin.Context) {
+	job, ok := h.store.Get(c.Param("job_id"))
+	if !ok {
+		middleware.AbortWithError(c, http.StatusNotFound, "job_not_found", "Job not found")
+		return
+	}
+
+	if job.Result != nil && c.Query("channel") != "" {
+		// Render a copy so the stored result is not modified
+		result := *job.Result
+		renderForChannel(&result, c.Query("channel"), h.footer)
+		job.Result = &result
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// StreamJob sends the current job status as a Server-Sent Event and then an
+// "improved" (or "failed") event once the job finishes
+func (h *JobsHandler) StreamJob(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	job, ok := h.store.Get(jobID)
+	if !ok {
+		middleware.AbortWithError(c, http.StatusNotFound, "job_not_found", "Job not found")
+		return
+	}
+
+	c.SSEvent("status", job)
+	c.Writer.Flush()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+
+	c.Stream(func(w io.Writer) bool {
+		finished, err := h.store.Wait(ctx, jobID)
+		if err != nil {
+			c.SSEvent("error", models.ErrorResponse{
+				Code:      "job_wait_failed",
+				Message:   err.Error(),
+				RequestID: logging.RequestID(c.Request.Context()),
+			})
+			return false
+		}
+
+		if finished.Status == jobs.StatusDone {
+			c.SSEvent("improved", finished)
+		} else {
+			c.SSEvent("failed", finished)
+		}
+		return false
+	})
+}
+
+// useRace reports whether an auto mode request should race Simple against Pro
+func useRace(cfg *config.Config, mode string, race bool) bool {
+	if mode != "auto" && mode != "" {
+		return false
+	}
+	return race || cfg.AutoModeRace
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/render"
+)
+
+// renderForChannel attaches the channel-specific rendering, with the footer,
+// to the response
+func renderForChannel(result *models.SearchResponse, channel string, footer *render.Footer) {
+	if result == nil || channel == "" {
+		return
+	}
+
+	format := render.FormatForChannel(channel)
+	if format == "" {
+		log.Printf("⚠️  Unknown channel %q, skipping rendering", channel)
+		return
+	}
+
+	rendered, err := render.Render(result, format, footer)
+	if err != nil {
+		log.Printf("⚠️  Failed to render answer: %v", err)
+		return
+	}
+	result.Rendered = rendered
+}
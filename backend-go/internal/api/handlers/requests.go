@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/guardrails"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/jobs"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// statusClientClosedRequest is returned when a query was cancelled via
+// DELETE /api/search/:request_id (nginx convention)
+const statusClientClosedRequest = 499
+
+type RequestsHandler struct {
+	registry *jobs.Registry
+}
+
+func NewRequestsHandler(registry *jobs.Registry) *RequestsHandler {
+	return &RequestsHandler{registry: registry}
+}
+
+// Cancel stops a running search or chat query by its request id; only the
+// client that started the query may cancel it
+func (h *RequestsHandler) Cancel(c *gin.Context) {
+	requestID := c.Param("request_id")
+
+	if !h.registry.Cancel(requestID, middleware.ClientID(c)) {
+		middleware.AbortWithError(c, http.StatusNotFound, "request_not_found", "Request not found or already finished")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Request cancelled", "request_id": requestID})
+}
+
+// startRequest registers the query under the client supplied request id (or
+// the one assigned by middleware.RequestID) so the client can cancel it. On
+// failure the error response is already written.
+func startRequest(c *gin.Context, registry *jobs.Registry, requestID string) (string, context.Context, func(), bool) {
+	parent := c.Request.Context()
+	if requestID == "" {
+		requestID = logging.RequestID(parent)
+	}
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	parent = logging.WithRequestID(parent, requestID)
+
+	ctx, done, err := registry.Start(parent, requestID, middleware.ClientID(c))
+	if err != nil {
+		middleware.AbortWithError(c, http.StatusConflict, "duplicate_request", err.Error())
+		return "", nil, nil, false
+	}
+
+	c.Header("X-Request-ID", requestID)
+	return requestID, ctx, done, true
+}
+
+// writeQueryError reports an agent error, distinguishing explicit cancellation.
+// The error itself is only logged; a query blocked by the guardrails gets its
+// category in the details.
+func writeQueryError(c *gin.Context, ctx context.Context, err error) {
+	status, code, message := queryError(c, ctx, err)
+	var blocked *guardrails.BlockedError
+	if errors.As(err, &blocked) {
+		middleware.AbortWithErrorDetails(c, status, code, message,
+			map[string]interface{}{"category": blocked.Category})
+		return
+	}
+	middleware.AbortWithError(c, status, code, message)
+}
+
+// queryError is the status and error code writeQueryError responds with
+func queryError(c *gin.Context, ctx context.Context, err error) (int, string, string) {
+	if errors.Is(ctx.Err(), context.Canceled) && c.Request.Context().Err() == nil {
+		return statusClientClosedRequest, "request_cancelled", "Request cancelled"
+	}
+	logging.Printf(ctx, "❌ Query failed: %v", err)
+	return queryErrorStatus(err)
+}
+
+// queryErrorStatus maps the domain errors of tools and agents to an HTTP
+// status and error code
+func queryErrorStatus(err error) (int, string, string) {
+	var blocked *guardrails.BlockedError
+	switch {
+	case errors.As(err, &blocked):
+		return http.StatusUnprocessableEntity, "query_blocked", blocked.Message
+	case errors.Is(err, tools.ErrBudgetExceeded):
+		return http.StatusServiceUnavailable, "budget_exceeded", "The query ran out of its time or quota budget"
+	case errors.Is(err, tools.ErrLLMRateLimited):
+		return http.StatusServiceUnavailable, "llm_rate_limited", "The language model provider is rate limiting requests"
+	case errors.Is(err, tools.ErrLLMUnavailable):
+		return http.StatusServiceUnavailable, "llm_unavailable", "The language model provider is unavailable"
+	case errors.Is(err, tools.ErrLLMCircuitOpen):
+		return http.StatusServiceUnavailable, "llm_unavailable", "The language model provider is unavailable"
+	case errors.Is(err, tools.ErrLLMBusy):
+		return http.StatusServiceUnavailable, "llm_busy", "Too many requests are waiting for the language model"
+	case errors.Is(err, tools.ErrContextOverflow):
+		return http.StatusUnprocessableEntity, "context_overflow", "The query and its context don't fit the language model's context window"
+	case errors.Is(err, tools.ErrLLMTimeout):
+		return http.StatusGatewayTimeout, "llm_timeout", "The language model did not answer in time"
+	case errors.Is(err, tools.ErrSearchUnavailable):
+		return http.StatusServiceUnavailable, "search_unavailable", "Search providers are unavailable"
+	case errors.Is(err, tools.ErrNoResults):
+		return http.StatusNotFound, "no_results", "Nothing was found for the query"
+	default:
+		return http.StatusInternalServerError, "query_failed", "Failed to answer the query"
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// recordRoutingOutcome stores the auto mode decision for later model tuning
+func recordRoutingOutcome(
+	db *gorm.DB,
+	sessionID, messageID string,
+	result *models.SearchResponse,
+	latency time.Duration,
+) {
+	if result == nil || result.AutoRouting == nil {
+		return
+	}
+
+	routing := result.AutoRouting
+	outcome := database.RoutingOutcome{
+		SessionID:         sessionID,
+		MessageID:         messageID,
+		Query:             result.Query,
+		SelectedMode:      routing.SelectedMode,
+		DecidedBy:         routing.DecidedBy,
+		ProProbability:    routing.ProProbability,
+		HistoryMessages:   routing.Features.HistoryMessages,
+		QueryWords:        routing.Features.QueryWords,
+		ComplexIndicators: routing.Features.ComplexIndicators,
+		SimpleIndicators:  routing.Features.SimpleIndicators,
+		MultiHop:          routing.Features.MultiHop,
+		LatencyMs:         latency.Milliseconds(),
+		SourcesCount:      len(result.Sources),
+		CreatedAt:         time.Now().Unix(),
+	}
+
+	if err := db.Create(&outcome).Error; err != nil {
+		log.Printf("⚠️  Failed to record routing outcome: %v", err)
+	}
+}
@@ -1,49 +1,453 @@
 package handlers
 
 import (
+	"context"
+	"io"
+	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/agents"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/cache"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/jobs"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/render"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/webhook"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type SearchHandler struct {
-	db     *gorm.DB
-	cfg    *config.Config
-	router *agents.RouterAgent
+	db       *gorm.DB
+	cfg      *config.Config
+	router   *agents.RouterAgent
+	requests *jobs.Registry
+	jobs     *jobs.Store
+	webhooks *webhook.Sender
+	answers  *cache.AnswerCache // nil when caching is disabled
+	trending *cache.Trending
+	traces   *TraceWriter
+	footer   *render.Footer // nil without a configured footer
 }
 
-func NewSearchHandler(db *gorm.DB, cfg *config.Config) *SearchHandler {
+func NewSearchHandler(
+	db *gorm.DB,
+	cfg *config.Config,
+	router *agents.RouterAgent,
+	jobStore *jobs.Store,
+	requests *jobs.Registry,
+	answers *cache.AnswerCache,
+	trending *cache.Trending,
+	traces *TraceWriter,
+	footer *render.Footer,
+) *SearchHandler {
 	return &SearchHandler{
-		db:     db,
-		cfg:    cfg,
-		router: agents.NewRouterAgent(cfg),
+		db:       db,
+		cfg:      cfg,
+		router:   router,
+		requests: requests,
+		jobs:     jobStore,
+		webhooks: webhook.NewSender(cfg.WebhookSecret).PublicOnly(),
+		answers:  answers,
+		trending: trending,
+		traces:   traces,
+		footer:   footer,
 	}
 }
 
+// answerRefreshTimeout bounds the background refresh of a stale answer
+const answerRefreshTimeout = 90 * time.Second
+
+// cachedAnswer returns a cached answer for the query, if caching is enabled.
+// A stale answer is returned right away and refreshed in a background job.
+func (h *SearchHandler) cachedAnswer(ctx context.Context, mode, query string) (*models.SearchResponse, bool) {
+	if h.answers == nil {
+		return nil, false
+	}
+	result, ok := h.answers.Get(ctx, mode, query)
+	if !ok {
+		return nil, false
+	}
+	result.Cached = true
+	if h.answers.Stale(ctx, mode, query) {
+		result.Stale = true
+		result.RefreshJobID = h.refreshAnswer(ctx, mode, query)
+	}
+	return result, true
+}
+
+// refreshAnswer starts re-answering a stale cached query unless another
+// request already did, and returns the refresh job's ID. The new answer
+// replaces the cached one; clients can poll the job to pick it up.
+func (h *SearchHandler) refreshAnswer(ctx context.Context, mode, query string) string {
+	claimed, jobID := h.answers.ClaimRefresh(ctx, mode, query, answerRefreshTimeout)
+	if !claimed {
+		return jobID
+	}
+
+	requestID := logging.RequestID(ctx)
+	job := h.jobs.Submit("answer-refresh", answerRefreshTimeout, func(jobCtx context.Context) (*models.SearchResponse, error) {
+		jobCtx = logging.WithRequestID(jobCtx, requestID)
+		defer h.answers.ReleaseRefresh(context.Background(), mode, query)
+
+		result, err := h.router.ProcessQuery(jobCtx, query, mode)
+		if err != nil {
+			logging.Printf(jobCtx, "⚠️  Stale answer refresh failed for %q (%s): %v", query, mode, err)
+			return nil, err
+		}
+		h.answers.Set(jobCtx, mode, query, result)
+		logging.Printf(jobCtx, "♻️  Refreshed stale answer for %q (%s)", query, mode)
+		return result, nil
+	})
+	h.answers.SetRefreshJob(ctx, mode, query, job.ID)
+	return job.ID
+}
+
 func (h *SearchHandler) Search(c *gin.Context) {
 	var req models.SearchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
 		return
 	}
 
-	startTime := time.Now()
+	passages, ok := documentPassages(c, h.db, h.cfg.PublicURL, req.DocumentIDs)
+	if !ok {
+		return
+	}
+
+	if req.Explain {
+		h.explain(c, req, passages)
+		return
+	}
+
+	if req.CallbackURL != "" {
+		h.searchWithCallback(c, req, passages)
+		return
+	}
+
+	requestID, ctx, done, ok := startRequest(c, h.requests, req.RequestID)
+	if !ok {
+		return
+	}
+	defer done()
 
-	// Route to appropriate mode
-	result, err := h.router.ProcessQuery(c.Request.Context(), req.Query, req.Mode)
+	result, err := h.answer(ctx, middleware.UserKey(c), req, requestID, passages)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeQueryError(c, ctx, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// stepBuffer and tokenBuffer are how many reasoning steps and answer chunks a
+// slow stream client may fall behind before further ones are dropped; the
+// agents never wait for the client
+const (
+	stepBuffer  = 256
+	tokenBuffer = 4096
+)
+
+// SearchStream answers like Search, but as Server-Sent Events: a "step" event
+// for every reasoning step and a "token" event for every chunk of the answer
+// as the agents produce them, then a single "answer" (or "error") event
+func (h *SearchHandler) SearchStream(c *gin.Context) {
+	var req models.SearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if req.Explain || req.CallbackURL != "" {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", "explain and callback_url are not supported by streaming")
+		return
+	}
+
+	passages, ok := documentPassages(c, h.db, h.cfg.PublicURL, req.DocumentIDs)
+	if !ok {
+		return
+	}
+
+	requestID, ctx, done, ok := startRequest(c, h.requests, req.RequestID)
+	if !ok {
 		return
 	}
+	defer done()
+
+	// The channel is never closed: race mode keeps reporting Pro steps after
+	// the response is sent
+	steps := make(chan models.StepEvent, stepBuffer)
+	tokens := make(chan models.TokenEvent, tokenBuffer)
+	startTime := time.Now()
+	var seq atomic.Int64
+	ctx = agents.WithStepRecorder(ctx, func(step string) {
+		event := models.StepEvent{
+			Seq:       seq.Add(1),
+			Step:      step,
+			ElapsedMs: time.Since(startTime).Milliseconds(),
+		}
+		select {
+		case steps <- event:
+		default:
+		}
+	})
+	ctx = agents.WithTokenRecorder(ctx, func(mode, text string) {
+		select {
+		case tokens <- models.TokenEvent{Seq: seq.Add(1), Mode: mode, Text: text}:
+		default:
+		}
+	})
+
+	type outcome struct {
+		result *models.SearchResponse
+		err    error
+	}
+	outcomes := make(chan outcome, 1)
+	userKey := middleware.UserKey(c)
+	go func() {
+		result, err := h.answer(ctx, userKey, req, requestID, passages)
+		outcomes <- outcome{result, err}
+	}()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-steps:
+			c.SSEvent("step", event)
+			return true
+		case event := <-tokens:
+			c.SSEvent("token", event)
+			return true
+		case out := <-outcomes:
+			// Steps and chunks recorded before the answer go out first
+			for pending := true; pending; {
+				select {
+				case event := <-steps:
+					c.SSEvent("step", event)
+				case event := <-tokens:
+					c.SSEvent("token", event)
+				default:
+					pending = false
+				}
+			}
+			if out.err != nil {
+				_, code, message := queryError(c, ctx, out.err)
+				c.SSEvent("error", models.ErrorResponse{
+					Code:      code,
+					Message:   message,
+					RequestID: requestID,
+				})
+			} else {
+				c.SSEvent("answer", out.result)
+			}
+			return false
+		}
+	})
+}
+
+// answer runs a search request and records its usage and history. Cacheable
+// queries are served from the answer cache when possible, unless the request
+// asks for a fresh answer (no_cache).
+func (h *SearchHandler) answer(ctx context.Context, userKey string, req models.SearchRequest, requestID string, passages []models.TavilyResult) (*models.SearchResponse, error) {
+	ctx = agents.WithAnswerLanguage(agents.WithAnswerFormat(ctx, req.Format), req.AnswerLang)
+	ctx = agents.WithOutputSchema(agents.WithDocuments(ctx, passages), req.OutputSchema)
+	ctx, meter := tools.WithTokenMeter(h.traces.Trace(ctx, requestID))
+	ctx, timings := tools.WithTimings(ctx)
+	startTime := time.Now()
+	h.trending.Record(ctx, req.Mode, req.Query)
+
+	// Cached and warmed answers are markdown in the query's own language,
+	// without a structured payload, and use no private documents
+	cacheable := (req.Format == "" || req.Format == agents.AnswerFormatMarkdown) && req.AnswerLang == "" &&
+		req.OutputSchema == "" && len(req.DocumentIDs) == 0
+
+	var result *models.SearchResponse
+	cached := false
+	if cacheable && !req.NoCache {
+		result, cached = h.cachedAnswer(ctx, req.Mode, req.Query)
+	}
+	if !cached {
+		// Route to appropriate mode
+		var err error
+		if useRace(h.cfg, req.Mode, req.Race) {
+			result, err = h.router.ProcessQueryRace(ctx, req.Query, nil, nil)
+		} else {
+			result, err = h.router.ProcessQuery(ctx, req.Query, req.Mode)
+		}
+		if err != nil {
+			recordUsage(ctx, h.db, "search", req.Mode, nil, err, time.Since(startTime), meter)
+			return nil, err
+		}
+
+		recordRoutingOutcome(h.db, "", "", result, time.Since(startTime))
+
+		// Race answers are provisional and not cached
+		if cacheable && h.answers != nil && result.ImprovedAnswerJobID == "" {
+			h.answers.Set(ctx, req.Mode, req.Query, result)
+		}
+	}
+
+	recordHistory(h.db, userKey, "", req.Mode, result, time.Since(startTime))
+	recordUsage(ctx, h.db, "search", req.Mode, result, nil, time.Since(startTime), meter)
 
 	// Add processing time
+	result.RequestID = requestID
 	result.ProcessingTime = time.Since(startTime).Seconds()
+	result.Timings = timings.Breakdown(time.Since(startTime))
+	result.LLMProviders = meter.Providers()
+	result.Usage = meter.Usage()
 	result.Timestamp = time.Now().Unix()
+	renderForChannel(result, req.Channel, h.footer)
 
-	c.JSON(http.StatusOK, result)
+	return result, nil
+}
+
+// explain responds with the plan of the query instead of an answer
+func (h *SearchHandler) explain(c *gin.Context, req models.SearchRequest, passages []models.TavilyResult) {
+	requestID, ctx, done, ok := startRequest(c, h.requests, req.RequestID)
+	if !ok {
+		return
+	}
+	defer done()
+
+	ctx = agents.WithAnswerLanguage(agents.WithAnswerFormat(ctx, req.Format), req.AnswerLang)
+	ctx = agents.WithDocuments(ctx, passages)
+	writeExplain(c, ctx, h.router, requestID, req.Query, req.Mode, nil)
+}
+
+// Modes lists the available search modes
+func (h *SearchHandler) Modes(c *gin.Context) {
+	c.JSON(http.StatusOK, models.ModesResponse{Modes: h.router.Modes()})
+}
+
+const (
+	defaultRetrieveLimit = 10
+	maxRetrieveLimit     = 20
+	maxRetrieveContent   = 4000
+)
+
+// Retrieve returns ranked sources with relevance and credibility scores,
+// skipping LLM synthesis
+func (h *SearchHandler) Retrieve(c *gin.Context) {
+	var req models.RetrieveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultRetrieveLimit
+	}
+	if limit > maxRetrieveLimit {
+		limit = maxRetrieveLimit
+	}
+
+	var filters *models.QueryConstraints
+	if req.TimeRange != "" || len(req.Sites) > 0 {
+		filters = &models.QueryConstraints{TimeRange: req.TimeRange, Sites: req.Sites}
+	}
+
+	startTime := time.Now()
+	ctx := c.Request.Context()
+	results, evidence, err := h.router.Retrieve(ctx, req.Query, limit, filters)
+	if err != nil {
+		writeQueryError(c, ctx, err)
+		return
+	}
+
+	sources := make([]models.RankedSource, 0, len(results))
+	for i, result := range results {
+		content := result.Content
+		if result.RawContent != "" {
+			content = result.RawContent
+		}
+		sources = append(sources, models.RankedSource{
+			Rank:        i + 1,
+			Title:       utils.SanitizeUTF8(result.Title),
+			URL:         result.URL,
+			Snippet:     utils.SanitizeUTF8(result.Snippet),
+			Content:     utils.TruncateRunesWithEllipsis(utils.SanitizeUTF8(content), maxRetrieveContent),
+			Relevance:   result.Score,
+			Credibility: result.Credibility,
+			PublishedAt: result.PublishedAt,
+			FetchedAt:   result.FetchedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.RetrieveResponse{
+		Query:          req.Query,
+		Sources:        sources,
+		Evidence:       evidence,
+		ProcessingTime: time.Since(startTime).Seconds(),
+		Timestamp:      time.Now().Unix(),
+	})
+}
+
+// searchWithCallback runs the query as a background job and POSTs the
+// signed result to req.CallbackURL when it finishes
+func (h *SearchHandler) searchWithCallback(c *gin.Context, req models.SearchRequest, passages []models.TavilyResult) {
+	if !h.webhooks.Enabled() {
+		middleware.AbortWithError(c, http.StatusBadRequest, "callbacks_disabled", "Callbacks are disabled (WEBHOOK_SECRET is not set)")
+		return
+	}
+	if err := webhook.ValidatePublicURL(c.Request.Context(), req.CallbackURL); err != nil {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	userKey := middleware.UserKey(c)
+	requestID := logging.RequestID(c.Request.Context())
+	jobIDs := make(chan string, 1)
+	job := h.jobs.Submit("search-callback", 3*time.Minute, func(ctx context.Context) (*models.SearchResponse, error) {
+		ctx = agents.WithAnswerFormat(logging.WithRequestID(ctx, requestID), req.Format)
+		ctx = agents.WithDocuments(agents.WithAnswerLanguage(ctx, req.AnswerLang), passages)
+		ctx = agents.WithOutputSchema(ctx, req.OutputSchema)
+		ctx, meter := tools.WithTokenMeter(h.traces.Trace(ctx, requestID))
+		ctx, timings := tools.WithTimings(ctx)
+		startTime := time.Now()
+		result, err := h.router.ProcessQuery(ctx, req.Query, req.Mode)
+		recordUsage(ctx, h.db, "callback", req.Mode, result, err, time.Since(startTime), meter)
+
+		var payload interface{}
+		if err != nil {
+			logging.Printf(ctx, "❌ Callback query failed: %v", err)
+			_, code, message := queryErrorStatus(err)
+			payload = models.ErrorResponse{
+				Code:      code,
+				Message:   message,
+				RequestID: requestID,
+			}
+		} else {
+			recordRoutingOutcome(h.db, "", "", result, time.Since(startTime))
+			recordHistory(h.db, userKey, "", req.Mode, result, time.Since(startTime))
+
+			result.ProcessingTime = time.Since(startTime).Seconds()
+			result.Timings = timings.Breakdown(time.Since(startTime))
+			result.LLMProviders = meter.Providers()
+			result.Usage = meter.Usage()
+			result.Timestamp = time.Now().Unix()
+			renderForChannel(result, req.Channel, h.footer)
+			payload = result
+		}
+
+		// Deliver even if the query failed; the job keeps the result for polling
+		jobID := <-jobIDs
+		deliverCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if deliverErr := h.webhooks.Deliver(deliverCtx, req.CallbackURL, jobID, payload); deliverErr != nil {
+			log.Printf("❌ Callback for job %s failed: %v", jobID, deliverErr)
+		}
+
+		return result, err
+	})
+	jobIDs <- job.ID
+
+	c.JSON(http.StatusAccepted, models.JobAcceptedResponse{
+		JobID:  job.ID,
+		Status: string(job.Status),
+	})
 }
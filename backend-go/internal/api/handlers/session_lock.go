@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// sessionLockTTL bounds how long a Redis session lock outlives a replica
+	// that died while answering
+	sessionLockTTL   = 5 * time.Minute
+	sessionLockRetry = 100 * time.Millisecond
+)
+
+// releaseScript deletes the lock only if it is still held by the caller's token
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// sessionLocks serializes message processing per chat session, so a message
+// is always answered with the previous turns already stored. With Redis the
+// lock is shared by all replicas.
+type sessionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+	redis *redis.Client
+}
+
+type sessionLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+func newSessionLocks(redisClient *redis.Client) *sessionLocks {
+	return &sessionLocks{
+		locks: make(map[string]*sessionLock),
+		redis: redisClient,
+	}
+}
+
+// acquire waits for the session's turn; the returned release must be called
+// once the message is answered
+func (l *sessionLocks) acquire(ctx context.Context, sessionID string) (func(), error) {
+	if l.redis != nil {
+		return l.acquireShared(ctx, sessionID)
+	}
+	return l.acquireLocal(ctx, sessionID)
+}
+
+// acquireShared polls for the Redis lock of the session. If Redis fails the
+// session falls back to the per-process lock.
+func (l *sessionLocks) acquireShared(ctx context.Context, sessionID string) (func(), error) {
+	key := "session-lock:" + sessionID
+	token := uuid.New().String()
+
+	for {
+		ok, err := l.redis.SetNX(ctx, key, token, sessionLockTTL).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("⚠️  Session lock unavailable, using local lock: %v", err)
+			return l.acquireLocal(ctx, sessionID)
+		}
+		if ok {
+			return func() {
+				releaseCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				defer cancel()
+				if err := releaseScript.Run(releaseCtx, l.redis, []string{key}, token).Err(); err != nil {
+					log.Printf("⚠️  Failed to release session lock %s: %v", sessionID, err)
+				}
+			}, nil
+		}
+
+		select {
+		case <-time.After(sessionLockRetry):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *sessionLocks) acquireLocal(ctx context.Context, sessionID string) (func(), error) {
+	l.mu.Lock()
+	lock, ok := l.locks[sessionID]
+	if !ok {
+		lock = &sessionLock{ch: make(chan struct{}, 1)}
+		l.locks[sessionID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	select {
+	case lock.ch <- struct{}{}:
+		return func() {
+			<-lock.ch
+			l.unref(sessionID, lock)
+		}, nil
+	case <-ctx.Done():
+		l.unref(sessionID, lock)
+		return nil, ctx.Err()
+	}
+}
+
+func (l *sessionLocks) unref(sessionID string, lock *sessionLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, sessionID)
+	}
+}
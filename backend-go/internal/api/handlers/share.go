@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SharedSessionResponse is the read-only view of a shared session. It holds
+// no session or message IDs, which would allow writing to the session.
+type SharedSessionResponse struct {
+	Mode      string          `json:"mode"`
+	CreatedAt int64           `json:"created_at"`
+	Messages  []SharedMessage `json:"messages"`
+}
+
+type SharedMessage struct {
+	Role      string            `json:"role"`
+	Content   string            `json:"content"`
+	Mode      string            `json:"mode,omitempty"`
+	Timestamp int64             `json:"timestamp"`
+	Sources   []database.Source `json:"sources,omitempty"`
+}
+
+// ShareSession creates a read-only link to the session. The token is a random
+// share ID signed with SHARE_SECRET; deleting the session revokes its links.
+func (h *ChatHandler) ShareSession(c *gin.Context) {
+	if h.cfg.ShareSecret == "" {
+		middleware.AbortWithError(c, http.StatusBadRequest, "sharing_disabled", "Sharing is disabled (SHARE_SECRET is not set)")
+		return
+	}
+
+	sessionID := c.Param("session_id")
+
+	// The body is optional: no expiry by default
+	var req models.ShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		middleware.AbortWithError(c, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	var session database.ChatSession
+	if err := h.db.First(&session, "id = ?", sessionID).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "session_not_found", "Session not found")
+		return
+	}
+
+	now := time.Now()
+	share := database.SessionShare{
+		ID:        strings.ReplaceAll(uuid.New().String(), "-", ""),
+		SessionID: sessionID,
+		CreatedAt: now.Unix(),
+	}
+	if req.ExpiresInHours > 0 {
+		share.ExpiresAt = now.Add(time.Duration(req.ExpiresInHours) * time.Hour).Unix()
+	}
+	if err := h.db.Create(&share).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to share session")
+		return
+	}
+
+	token := share.ID + "." + h.shareSignature(share.ID)
+	c.JSON(http.StatusOK, models.ShareResponse{
+		Token:     token,
+		URL:       h.cfg.PublicURL + "/api/shared/" + token,
+		ExpiresAt: share.ExpiresAt,
+	})
+}
+
+// GetShared serves the conversation behind a share token, without sign-in
+func (h *ChatHandler) GetShared(c *gin.Context) {
+	shareID, signature, ok := strings.Cut(c.Param("token"), ".")
+	if !ok || h.cfg.ShareSecret == "" ||
+		!hmac.Equal([]byte(signature), []byte(h.shareSignature(shareID))) {
+		middleware.AbortWithError(c, http.StatusNotFound, "share_not_found", "Shared session not found")
+		return
+	}
+
+	var share database.SessionShare
+	if err := h.db.First(&share, "id = ?", shareID).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusNotFound, "share_not_found", "Shared session not found")
+		return
+	}
+	if share.ExpiresAt > 0 && time.Now().Unix() > share.ExpiresAt {
+		middleware.AbortWithError(c, http.StatusGone, "share_expired", "Share link has expired")
+		return
+	}
+
+	var session database.ChatSession
+	if err := h.db.First(&session, "id = ?", share.SessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			middleware.AbortWithError(c, http.StatusNotFound, "share_not_found", "Shared session not found")
+		} else {
+			middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to get session")
+		}
+		return
+	}
+
+	var messages []database.Message
+	if err := h.db.Preload("Sources").
+		Where("session_id = ?", share.SessionID).
+		Order("seq, timestamp").
+		Find(&messages).Error; err != nil {
+		middleware.AbortWithError(c, http.StatusInternalServerError, "internal_error", "Failed to get session")
+		return
+	}
+
+	shared := make([]SharedMessage, 0, len(messages))
+	for _, msg := range messages {
+		shared = append(shared, SharedMessage{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			Mode:      msg.Mode,
+			Timestamp: msg.Timestamp,
+			Sources:   msg.Sources,
+		})
+	}
+
+	c.JSON(http.StatusOK, SharedSessionResponse{
+		Mode:      session.Mode,
+		CreatedAt: session.CreatedAt,
+		Messages:  shared,
+	})
+}
+
+// SharedScope is the response cache scope of a share link: the scope of the
+// shared session, so its cached view is invalidated with the session. Unknown
+// and expired links are not cached.
+func (h *ChatHandler) SharedScope(c *gin.Context) string {
+	shareID, _, _ := strings.Cut(c.Param("token"), ".")
+	var share database.SessionShare
+	if err := h.db.Select("session_id", "expires_at").First(&share, "id = ?", shareID).Error; err != nil {
+		return ""
+	}
+	if share.ExpiresAt > 0 && time.Now().Unix() > share.ExpiresAt {
+		return ""
+	}
+	return sessionScope(share.SessionID)
+}
+
+func (h *ChatHandler) shareSignature(shareID string) string {
+	mac := hmac.New(sha256.New, []byte(h.cfg.ShareSecret))
+	mac.Write([]byte(shareID))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/agents"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/database"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/lock"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"gorm.io/gorm"
+)
+
+const (
+	// traceBuffer is how many reasoning steps may wait to be stored before
+	// further ones are dropped; the agents never wait for the database
+	traceBuffer = 4096
+	// Steps are stored in batches of traceBatchSize, or what arrived within
+	// traceFlushInterval
+	traceBatchSize     = 100
+	traceFlushInterval = time.Second
+	// traceCleanupInterval is how often steps older than the retention are
+	// deleted, on one replica
+	traceCleanupInterval = time.Hour
+)
+
+// TraceWriter stores the reasoning steps of requests in the background, in
+// batches, and deletes them after the retention period. A nil TraceWriter
+// stores nothing.
+type TraceWriter struct {
+	db    *gorm.DB
+	steps chan database.ReasoningStep
+}
+
+// NewTraceWriter starts storing reasoning steps; retention of zero keeps them
+// forever
+func NewTraceWriter(db *gorm.DB, locker *lock.Locker, retention time.Duration) *TraceWriter {
+	w := &TraceWriter{
+		db:    db,
+		steps: make(chan database.ReasoningStep, traceBuffer),
+	}
+	go w.run()
+	if retention > 0 {
+		go w.cleanup(locker, retention)
+	}
+	return w
+}
+
+// Trace makes the agents handling ctx store every reasoning step under
+// requestID as it happens (GET /api/admin/requests/:request_id/trace). Steps
+// reach the database within traceFlushInterval.
+func (w *TraceWriter) Trace(ctx context.Context, requestID string) context.Context {
+	if w == nil || requestID == "" {
+		return ctx
+	}
+
+	startTime := time.Now()
+	var seq atomic.Int64
+	return agents.WithStepRecorder(ctx, func(step string) {
+		now := time.Now()
+		record := database.ReasoningStep{
+			RequestID: requestID,
+			Seq:       seq.Add(1),
+			Step:      step,
+			ElapsedMs: now.Sub(startTime).Milliseconds(),
+			CreatedAt: now.Unix(),
+		}
+		select {
+		case w.steps <- record:
+		default:
+			logging.Printf(ctx, "⚠️  Reasoning step dropped, %d steps are waiting to be stored", traceBuffer)
+		}
+	})
+}
+
+// run stores the queued steps in batches
+func (w *TraceWriter) run() {
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]database.ReasoningStep, 0, traceBatchSize)
+	for {
+		select {
+		case step := <-w.steps:
+			batch = append(batch, step)
+			if len(batch) < traceBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		if err := w.db.Create(&batch).Error; err != nil {
+			log.Printf("⚠️  Failed to store %d reasoning steps: %v", len(batch), err)
+		}
+		batch = make([]database.ReasoningStep, 0, traceBatchSize)
+	}
+}
+
+// cleanup deletes the steps older than retention
+func (w *TraceWriter) cleanup(locker *lock.Locker, retention time.Duration) {
+	ticker := time.NewTicker(traceCleanupInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		ctx := context.Background()
+		if !locker.Claim(ctx, "trace-cleanup", traceCleanupInterval, now) {
+			continue
+		}
+
+		result := w.db.WithContext(ctx).
+			Where("created_at < ?", now.Add(-retention).Unix()).
+			Delete(&database.ReasoningStep{})
+		if result.Error != nil {
+			log.Printf("⚠️  Failed to delete old reasoning steps: %v", result.Error)
+		} else if result.RowsAffected > 0 {
+			log.Printf("🧹 Deleted %d reasoning steps older than %s", result.RowsAffected, retention)
+		}
+	}
+}
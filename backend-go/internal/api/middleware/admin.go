@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin lets through only callers presenting one of ADMIN_API_KEYS
+// (X-API-Key or Authorization: Bearer)
+func RequireAdmin(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(cfg.AdminAPIKeys) == 0 {
+			AbortWithError(c, http.StatusForbidden, "admin_disabled", "Admin API is disabled (ADMIN_API_KEYS is not set)")
+			return
+		}
+
+		key := apiKey(c)
+		if key == "" {
+			AbortWithError(c, http.StatusUnauthorized, "unauthorized", "API key required")
+			return
+		}
+
+		for _, adminKey := range cfg.AdminAPIKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) == 1 {
+				c.Next()
+				return
+			}
+		}
+
+		AbortWithError(c, http.StatusForbidden, "forbidden", "Admin role required")
+	}
+}
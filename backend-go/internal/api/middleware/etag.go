@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/cache"
+	"github.com/gin-gonic/gin"
+)
+
+// CacheResponses serves GET responses from the response cache and adds ETags,
+// answering 304 Not Modified when If-None-Match matches. scopeOf names the
+// cache scope of the request (invalidated by the handlers on writes); requests
+// with an empty scope and non-200 responses are not cached. A nil cache
+// disables the middleware.
+func CacheResponses(responses *cache.ResponseCache, scopeOf func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if responses == nil || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		scope := scopeOf(c)
+		if scope == "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		url := c.Request.URL.RequestURI()
+		version := responses.Version(ctx, scope)
+		if cached, ok := responses.Get(ctx, scope, version, url); ok {
+			c.Header("X-Cache", "HIT")
+			writeCachedResponse(c, *cached)
+			c.Abort()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.status != http.StatusOK {
+			c.Writer.WriteHeader(writer.status)
+			_, _ = c.Writer.Write(writer.body.Bytes())
+			return
+		}
+
+		response := cache.Response{
+			Status:      writer.status,
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			ETag:        etag(writer.body.Bytes()),
+			Body:        writer.body.Bytes(),
+		}
+		responses.Set(ctx, scope, version, url, response)
+		c.Header("X-Cache", "MISS")
+		writeCachedResponse(c, response)
+	}
+}
+
+// writeCachedResponse writes the response, or 304 if the client has it.
+// Clients must revalidate every time, so a poll costs at most a cache lookup.
+func writeCachedResponse(c *gin.Context, response cache.Response) {
+	c.Header("ETag", response.ETag)
+	c.Header("Cache-Control", "no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), response.ETag) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	c.Data(response.Status, response.ContentType, response.Body)
+}
+
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches implements the weak comparison of If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds the response back so its ETag can be computed first
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
@@ -0,0 +1,386 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucket is a classic token bucket refilled continuously at rate tokens/sec
+type tokenBucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64
+	last     time.Time
+}
+
+// take tries to consume one token and returns how long to wait if none is left
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := (1 - b.tokens) / b.rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// bucketScript is tokenBucket.take run atomically on a Redis hash, so all
+// replicas share one bucket per client. Returns {allowed, wait seconds}.
+var bucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or capacity
+local last = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - last) * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = (1 - tokens) / rate
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", tostring(now))
+redis.call("EXPIRE", KEYS[1], ARGV[4])
+return {allowed, tostring(wait)}`)
+
+// maxPeekBody bounds the JSON body read to find the mode of a request
+const maxPeekBody = 1 << 20
+
+type limits struct {
+	rpm   int
+	burst int
+	quota int
+}
+
+// RateLimiter limits requests per client (configured API key or IP) with a
+// token bucket per mode class and enforces daily quotas stored in Redis.
+// Buckets live in process memory unless shared state is enabled.
+type RateLimiter struct {
+	enabled       bool
+	redis         *redis.Client
+	sharedBuckets bool
+	simple        limits
+	pro           limits
+	keys          map[string]bool // hashed keys limited on their own
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func NewRateLimiter(cfg *config.Config, redisClient *redis.Client) *RateLimiter {
+	rl := &RateLimiter{
+		enabled:       cfg.RateLimitEnabled,
+		redis:         redisClient,
+		sharedBuckets: cfg.SharedStateEnabled && redisClient != nil,
+		simple: limits{
+			rpm:   cfg.RateLimitSimpleRPM,
+			burst: cfg.RateLimitSimpleBurst,
+			quota: cfg.DailyQuotaSimple,
+		},
+		pro: limits{
+			rpm:   cfg.RateLimitProRPM,
+			burst: cfg.RateLimitProBurst,
+			quota: cfg.DailyQuotaPro,
+		},
+		keys:    make(map[string]bool),
+		buckets: make(map[string]*tokenBucket),
+	}
+	for _, key := range append(cfg.RateLimitAPIKeys, cfg.AdminAPIKeys...) {
+		rl.keys[hashKey(key)] = true
+	}
+
+	if rl.enabled {
+		go rl.cleanup()
+	}
+
+	return rl
+}
+
+// Handle returns the Gin middleware; the limit class follows the "mode" field
+// of the request body, which may be up to maxPeekBody bytes
+func (rl *RateLimiter) Handle() gin.HandlerFunc {
+	return rl.handle(func(c *gin.Context) (string, bool) {
+		mode, err := peekMode(c)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			AbortWithError(c, http.StatusRequestEntityTooLarge, "request_too_large",
+				fmt.Sprintf("Request body is larger than %d bytes", maxPeekBody))
+			return "", false
+		}
+		return modeClass(mode), true
+	})
+}
+
+// HandlePro returns the Gin middleware that always applies the Pro limits, for
+// endpoints that run a Pro agent regardless of the body
+func (rl *RateLimiter) HandlePro() gin.HandlerFunc {
+	return rl.handle(func(*gin.Context) (string, bool) {
+		return "pro", true
+	})
+}
+
+// HandleSimple returns the Gin middleware that always applies the simple
+// limits, for endpoints without a mode that run no agent
+func (rl *RateLimiter) HandleSimple() gin.HandlerFunc {
+	return rl.handle(func(*gin.Context) (string, bool) {
+		return "simple", true
+	})
+}
+
+// handle limits requests by the class classOf returns; classOf returns false
+// when it has aborted the request
+func (rl *RateLimiter) handle(classOf func(*gin.Context) (string, bool)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rl.enabled {
+			c.Next()
+			return
+		}
+
+		class, ok := classOf(c)
+		if !ok {
+			return
+		}
+		client := rl.clientID(c)
+		lim := rl.simple
+		if class == "pro" {
+			lim = rl.pro
+		}
+
+		// Step 1: Short-term token bucket
+		if ok, wait := rl.allow(class+":"+client, lim); !ok {
+			abortTooManyRequests(c, wait, "rate_limited", "Rate limit exceeded")
+			return
+		}
+
+		// Step 2: Daily quota (persisted in Redis)
+		if ok, wait := rl.checkQuota(c.Request.Context(), class, client, lim.quota); !ok {
+			abortTooManyRequests(c, wait, "quota_exceeded", "Daily quota exceeded")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// clientID identifies the caller for the limits: by API key when it is one of
+// RATE_LIMIT_API_KEYS or ADMIN_API_KEYS, otherwise by IP, so that a made-up
+// key does not get a fresh bucket and quota
+func (rl *RateLimiter) clientID(c *gin.Context) string {
+	if key := UserKey(c); key != "" && rl.keys[key] {
+		return key
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func (rl *RateLimiter) allow(key string, lim limits) (bool, time.Duration) {
+	if lim.rpm <= 0 {
+		return true, 0
+	}
+
+	capacity := float64(lim.burst)
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	if rl.sharedBuckets {
+		ok, wait, err := rl.allowShared(key, capacity, float64(lim.rpm)/60.0)
+		if err == nil {
+			return ok, wait
+		}
+		log.Printf("⚠️  Shared rate limit check failed, using local bucket: %v", err)
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := rl.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens:   capacity,
+			capacity: capacity,
+			rate:     float64(lim.rpm) / 60.0,
+			last:     now,
+		}
+		rl.buckets[key] = bucket
+	}
+
+	return bucket.take(now)
+}
+
+// allowShared takes a token from the client's bucket in Redis
+func (rl *RateLimiter) allowShared(key string, capacity, rate float64) (bool, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	now := float64(time.Now().UnixNano()) / float64(time.Second)
+	// Idle buckets refill completely, so they can expire once full again
+	idle := int(math.Ceil(capacity/rate)) + 60
+
+	res, err := bucketScript.Run(ctx, rl.redis, []string{"ratelimit:" + key},
+		capacity, rate, strconv.FormatFloat(now, 'f', 6, 64), idle).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit script result: %v", res)
+	}
+
+	allowed, _ := res[0].(int64)
+	waitStr, _ := res[1].(string)
+	wait, _ := strconv.ParseFloat(waitStr, 64)
+	return allowed == 1, time.Duration(wait * float64(time.Second)), nil
+}
+
+func (rl *RateLimiter) checkQuota(ctx context.Context, class, client string, quota int) (bool, time.Duration) {
+	if rl.redis == nil || quota <= 0 {
+		return true, 0
+	}
+
+	now := time.Now().UTC()
+	key := fmt.Sprintf("quota:%s:%s:%s", class, client, now.Format("20060102"))
+	untilReset := now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+
+	pipe := rl.redis.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, untilReset+time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		// Fail open: quotas must not take the API down with Redis
+		log.Printf("⚠️  Quota check failed, allowing request: %v", err)
+		return true, 0
+	}
+
+	if incr.Val() > int64(quota) {
+		return false, untilReset
+	}
+	return true, 0
+}
+
+// cleanup drops buckets that have been idle long enough to be full again
+func (rl *RateLimiter) cleanup() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		rl.mu.Lock()
+		for key, bucket := range rl.buckets {
+			if time.Since(bucket.last) > 10*time.Minute {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.mu.Unlock()
+	}
+}
+
+func abortTooManyRequests(c *gin.Context, wait time.Duration, code, message string) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	AbortWithErrorDetails(c, http.StatusTooManyRequests, code, message,
+		map[string]interface{}{"retry_after": seconds})
+}
+
+// ClientID identifies the caller by API key when present, otherwise by IP.
+// Keys are hashed so they never end up in Redis or the database. The IP is
+// shared by everyone behind the same NAT or proxy, so per-user data is keyed
+// by UserKey; rate limits only trust configured keys (RateLimiter.clientID).
+func ClientID(c *gin.Context) string {
+	if key := UserKey(c); key != "" {
+		return key
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// UserKey identifies the owner of per-user data (history, documents,
+// integration accounts) by the hashed API key; empty without a key
+func UserKey(c *gin.Context) string {
+	key := apiKey(c)
+	if key == "" {
+		return ""
+	}
+	return hashKey(key)
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:8])
+}
+
+// RequireAPIKey rejects callers without an API key from endpoints serving
+// per-user data, which would otherwise be shared by everyone behind an IP
+func RequireAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey(c) == "" {
+			AbortWithError(c, http.StatusUnauthorized, "unauthorized", "API key required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// apiKey returns the key from X-API-Key or an Authorization bearer token
+func apiKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// peekMode reads the "mode" field from a JSON body without consuming it. A
+// body over maxPeekBody fails with *http.MaxBytesError.
+func peekMode(c *gin.Context) (string, error) {
+	if c.Request.Body == nil {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPeekBody))
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	var req struct {
+		Mode string `json:"mode"`
+	}
+	_ = json.Unmarshal(body, &req)
+	return req.Mode, nil
+}
+
+// modeClass maps a request mode onto its limit class. Deep research is limited
+// like Pro, and so is auto mode (also a missing mode), which may run Pro
+// agents: the race, escalation and the vertical Pro modes.
+func modeClass(mode string) string {
+	if strings.HasPrefix(mode, "pro") || mode == "deep" || mode == "auto" || mode == "" {
+		return "pro"
+	}
+	return "simple"
+}
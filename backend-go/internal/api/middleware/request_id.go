@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLength caps client supplied X-Request-ID values
+const maxRequestIDLength = 128
+
+// RequestID assigns every request an ID (the client's X-Request-ID if valid),
+// echoes it in the response header and carries it in the request context
+// for logs and error responses
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		c.Header("X-Request-ID", requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+		c.Next()
+	}
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// AbortWithError writes the uniform error envelope and stops the handler chain.
+// Messages must be safe for clients; log internal errors instead of returning them.
+func AbortWithError(c *gin.Context, status int, code, message string) {
+	AbortWithErrorDetails(c, status, code, message, nil)
+}
+
+// AbortWithErrorDetails is AbortWithError with extra machine readable fields
+func AbortWithErrorDetails(c *gin.Context, status int, code, message string, details map[string]interface{}) {
+	c.AbortWithStatusJSON(status, models.ErrorResponse{
+		Code:      code,
+		Message:   message,
+		RequestID: logging.RequestID(c.Request.Context()),
+		Details:   details,
+	})
+}
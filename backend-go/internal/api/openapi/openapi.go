@@ -0,0 +1,267 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// Param is a path or query parameter
+type Param struct {
+	Name        string
+	In          string // path, query
+	Description string
+	Required    bool
+}
+
+// Operation describes a single route; Request and Response are zero values of
+// the structs the handler binds and returns
+type Operation struct {
+	Method      string
+	Path        string // Gin style, e.g. /api/chat/session/:session_id
+	Summary     string
+	Tag         string
+	Params      []Param
+	Request     interface{}
+	Response    interface{}
+	ContentType string // response content type, defaults to application/json
+
+	// RequestContentType defaults to application/json; []byte fields of a
+	// multipart/form-data request are file uploads
+	RequestContentType string
+}
+
+// Spec is an OpenAPI 3 document built from Go structs via reflection
+type Spec struct {
+	title      string
+	version    string
+	operations []Operation
+	schemas    map[string]interface{}
+}
+
+func NewSpec(title, version string) *Spec {
+	return &Spec{
+		title:   title,
+		version: version,
+		schemas: make(map[string]interface{}),
+	}
+}
+
+func (s *Spec) Add(ops ...Operation) {
+	s.operations = append(s.operations, ops...)
+}
+
+// Document renders the OpenAPI document as a JSON-serializable map
+func (s *Spec) Document() map[string]interface{} {
+	paths := make(map[string]interface{})
+
+	for _, op := range s.operations {
+		path := openAPIPath(op.Path)
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		item[strings.ToLower(op.Method)] = s.operation(op)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   s.title,
+			"version": s.version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": s.schemas,
+		},
+	}
+}
+
+func (s *Spec) operation(op Operation) map[string]interface{} {
+	result := map[string]interface{}{
+		"summary": op.Summary,
+	}
+	if op.Tag != "" {
+		result["tags"] = []string{op.Tag}
+	}
+
+	if len(op.Params) > 0 {
+		params := make([]map[string]interface{}, 0, len(op.Params))
+		for _, p := range op.Params {
+			params = append(params, map[string]interface{}{
+				"name":        p.Name,
+				"in":          p.In,
+				"description": p.Description,
+				"required":    p.Required || p.In == "path",
+				"schema":      map[string]interface{}{"type": "string"},
+			})
+		}
+		result["parameters"] = params
+	}
+
+	if op.Request != nil {
+		requestType := op.RequestContentType
+		if requestType == "" {
+			requestType = "application/json"
+		}
+		result["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				requestType: map[string]interface{}{
+					"schema": s.schemaFor(reflect.TypeOf(op.Request)),
+				},
+			},
+		}
+	}
+
+	responses := map[string]interface{}{}
+	contentType := op.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	ok := map[string]interface{}{"description": "OK"}
+	if op.Response != nil {
+		ok["content"] = map[string]interface{}{
+			contentType: map[string]interface{}{
+				"schema": s.schemaFor(reflect.TypeOf(op.Response)),
+			},
+		}
+	}
+	responses["200"] = ok
+	responses["default"] = map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": s.schemaFor(reflect.TypeOf(models.ErrorResponse{})),
+			},
+		},
+	}
+	result["responses"] = responses
+
+	return result
+}
+
+// schemaFor returns an inline schema or a $ref to a named component
+func (s *Spec) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "binary"}
+		}
+		return map[string]interface{}{
+			"type":  "array",
+			"items": s.schemaFor(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": s.schemaFor(t.Elem()),
+		}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		name := schemaName(t)
+		if _, exists := s.schemas[name]; !exists {
+			// Reserve the name first so recursive types terminate
+			s.schemas[name] = map[string]interface{}{}
+			s.schemas[name] = s.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (s *Spec) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		// Embedded structs without a json name are flattened like encoding/json does
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			embedded := s.structSchema(field.Type)
+			for name, prop := range embedded["properties"].(map[string]interface{}) {
+				properties[name] = prop
+			}
+			if req, ok := embedded["required"].([]string); ok {
+				required = append(required, req...)
+			}
+			continue
+		}
+
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		properties[name] = s.schemaFor(field.Type)
+
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// schemaName prefixes the type name with its package to avoid clashes
+// (models.Source vs database.Source)
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if idx := strings.LastIndex(pkg, "/"); idx >= 0 {
+		pkg = pkg[idx+1:]
+	}
+	if pkg == "" {
+		return t.Name()
+	}
+	return pkg + "." + t.Name()
+}
+
+// openAPIPath converts Gin ":param" segments into "{param}"
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + strings.TrimPrefix(segment, ":") + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
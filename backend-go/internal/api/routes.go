@@ -1,17 +1,116 @@
 package api
 
 import (
+	"log"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/agents"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/handlers"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/api/middleware"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/cache"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/hooks"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/jobs"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/lock"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/render"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
+func SetupRoutes(router *gin.Engine, db *gorm.DB, redisClient *redis.Client, cfg *config.Config) {
+	// Request IDs for logs and error responses
+	router.Use(middleware.RequestID())
+
+	// Per-request state lives in Redis when several replicas share the load
+	var sharedRedis *redis.Client
+	if cfg.SharedStateEnabled {
+		if redisClient == nil {
+			log.Printf("⚠️  SHARED_STATE_ENABLED is set but Redis is unavailable, state stays per process")
+		}
+		sharedRedis = redisClient
+	}
+
+	// Background jobs (race mode improved answers)
+	jobStore := jobs.NewStore(sharedRedis, 30*time.Minute)
+	// In-flight queries that can be cancelled
+	requestRegistry := jobs.NewRegistry(sharedRedis)
+	// Fetched pages, cached on disk for page content and previews
+	var pageCache *cache.PageCache
+	if cfg.PageCacheMaxMB > 0 {
+		var err error
+		pageCache, err = cache.NewPageCache(cfg.PageCacheDir, int64(cfg.PageCacheMaxMB)<<20, cache.PageTTLs{
+			News:      time.Duration(cfg.PageCacheNewsHours) * time.Hour,
+			Reference: time.Duration(cfg.PageCacheReferenceHours) * time.Hour,
+			Default:   time.Duration(cfg.PageCacheDefaultHours) * time.Hour,
+		})
+		if err != nil {
+			log.Printf("⚠️  Page cache disabled: %v", err)
+		}
+	}
+	// User-Agent, per-host intervals and robots.txt of all searches and scrapers
+	tools.ConfigurePoliteness(cfg)
+	pages := tools.NewPageFetcher(pageCache)
+	// Preview cards of cited sources
+	var previews *tools.PreviewFetcher
+	if cfg.SourcePreviewsEnabled {
+		previews = tools.NewPreviewFetcher(pages, redisClient, time.Duration(cfg.SourcePreviewCacheHours)*time.Hour)
+	}
+	// Translations of snippets in another language than the answer (nil when disabled)
+	translator := tools.NewSnippetTranslator(cfg, redisClient)
+	// Search provider results, shared by the replicas through Redis
+	var searchCache *tools.SearchCache
+	if cfg.SearchCacheTTLMinutes > 0 {
+		searchCache = tools.NewSearchCache(redisClient, time.Duration(cfg.SearchCacheTTLMinutes)*time.Minute)
+	}
+	// One router for all handlers and the cache warmer
+	routerAgent := agents.NewRouterAgent(cfg, jobStore, pages, previews, translator, searchCache)
+
+	// Scheduled jobs (cache warming, trace cleanup) run on one replica
+	locker := lock.NewLocker(redisClient)
+
+	// Answer cache and trending queries for off-peak cache warming
+	var answerCache *cache.AnswerCache
+	trending := cache.NewTrending(redisClient)
+	if cfg.AnswerCacheTTLMinutes > 0 {
+		answerCache = cache.NewAnswerCache(redisClient,
+			time.Duration(cfg.AnswerCacheTTLMinutes)*time.Minute,
+			time.Duration(cfg.AnswerCacheFreshMinutes)*time.Minute)
+		if cfg.CacheWarmEnabled {
+			startCacheWarmer(cfg, answerCache, trending, routerAgent, locker)
+		}
+	}
+
+	// Cached GET responses with ETags for polling clients; state is shared
+	// between replicas like the other per-request state
+	var responses *cache.ResponseCache
+	if cfg.HTTPCacheTTLSeconds > 0 {
+		responses = cache.NewResponseCache(sharedRedis, time.Duration(cfg.HTTPCacheTTLSeconds)*time.Second)
+	}
+
+	// Reasoning steps of requests, stored in the background and deleted after
+	// TRACE_RETENTION_DAYS
+	traces := handlers.NewTraceWriter(db, locker, time.Duration(cfg.TraceRetentionDays)*24*time.Hour)
+
 	// Initialize handlers
-	searchHandler := handlers.NewSearchHandler(db, cfg)
-	chatHandler := handlers.NewChatHandler(db, cfg)
-	healthHandler := handlers.NewHealthHandler()
+	footer := loadFooter(cfg)
+	searchHandler := handlers.NewSearchHandler(db, cfg, routerAgent, jobStore, requestRegistry, answerCache, trending, traces, footer)
+	chatHandler := handlers.NewChatHandler(db, cfg, routerAgent, requestRegistry, sharedRedis, responses, traces, footer)
+	jobsHandler := handlers.NewJobsHandler(jobStore, footer)
+	docsHandler := handlers.NewDocsHandler(buildSpec())
+	healthHandler := handlers.NewHealthHandler(db, redisClient, cfg)
+	feedbackHandler := handlers.NewFeedbackHandler(db)
+	historyHandler := handlers.NewHistoryHandler(db)
+	requestsHandler := handlers.NewRequestsHandler(requestRegistry)
+	graphqlHandler := handlers.NewGraphQLHandler(db)
+	adminHandler := handlers.NewAdminHandler(db, searchCache)
+	integrationsHandler := handlers.NewIntegrationsHandler(db, cfg)
+	documentsHandler := handlers.NewDocumentsHandler(db, cfg)
+	hooksHandler := handlers.NewHooksHandler(db, routerAgent, jobStore, loadHooks(cfg), hooks.NewReplays(redisClient), hooks.NewTelegram(cfg.TelegramBotToken), traces, footer)
+
+	// Rate limiting for query endpoints
+	rateLimiter := middleware.NewRateLimiter(cfg, redisClient)
 
 	// API routes
 	api := router.Group("/api")
@@ -19,19 +118,83 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 		// Health check
 		api.GET("/health", healthHandler.Health)
 
+		// API documentation
+		api.GET("/openapi.json", docsHandler.OpenAPI)
+		api.GET("/docs", docsHandler.SwaggerUI)
+
 		// Search
-		api.POST("/search", searchHandler.Search)
+		api.GET("/modes", middleware.CacheResponses(responses, staticScope("modes")), searchHandler.Modes)
+		api.POST("/estimate", searchHandler.Estimate)
+		api.POST("/search", rateLimiter.Handle(), searchHandler.Search)
+		api.POST("/search/stream", rateLimiter.Handle(), searchHandler.SearchStream)
+		// Simple and pro side by side (A/B view, routing checks)
+		api.POST("/search/compare", rateLimiter.HandlePro(), searchHandler.Compare)
+		api.DELETE("/search/:request_id", requestsHandler.Cancel)
+
+		// Ranked sources without answer synthesis
+		api.POST("/retrieve", rateLimiter.HandleSimple(), searchHandler.Retrieve)
+
+		// Background jobs
+		api.GET("/jobs/:job_id", jobsHandler.GetJob)
+		api.GET("/jobs/:job_id/stream", jobsHandler.StreamJob)
+
+		// Search history of the caller (API key)
+		api.GET("/history", middleware.RequireAPIKey(), historyHandler.GetHistory)
+
+		// Research triggered by external systems (INBOUND_HOOKS_PATH)
+		api.POST("/hooks/:name", hooksHandler.Trigger)
+
+		// Answer feedback
+		api.POST("/feedback", feedbackHandler.SubmitFeedback)
+
+		// Operator endpoints (ADMIN_API_KEYS)
+		admin := api.Group("/admin", middleware.RequireAdmin(cfg))
+		{
+			admin.GET("/stats", adminHandler.Stats)
+			admin.GET("/requests/:request_id/trace", adminHandler.Trace)
+		}
 
 		// Chat sessions
+		sessionCache := middleware.CacheResponses(responses, chatHandler.SessionScope)
 		chat := api.Group("/chat")
 		{
 			chat.POST("/session", chatHandler.CreateSession)
-			chat.GET("/session/:session_id", chatHandler.GetSession)
-			chat.POST("/session/:session_id/message", chatHandler.SendMessage)
+			chat.GET("/session/:session_id", sessionCache, chatHandler.GetSession)
+			chat.GET("/session/:session_id/messages/count", sessionCache, chatHandler.CountMessages)
+			chat.POST("/session/:session_id/message", rateLimiter.Handle(), chatHandler.SendMessage)
+			chat.PUT("/session/:session_id/message/:message_id", rateLimiter.Handle(), chatHandler.EditMessage)
 			chat.DELETE("/session/:session_id", chatHandler.DeleteSession)
+			chat.POST("/session/:session_id/export", middleware.RequireAPIKey(), rateLimiter.Handle(), integrationsHandler.ExportSession)
+			chat.POST("/session/:session_id/share", chatHandler.ShareSession)
+		}
+
+		// Read-only view of a shared session, no API key needed
+		api.GET("/shared/:token", middleware.CacheResponses(responses, chatHandler.SharedScope), chatHandler.GetShared)
+
+		// Documents of the caller (API key) to ground answers in
+		documentsGroup := api.Group("/documents", middleware.RequireAPIKey())
+		{
+			documentsGroup.POST("", documentsHandler.Upload)
+			documentsGroup.GET("", documentsHandler.List)
+			documentsGroup.GET("/:document_id", documentsHandler.Get)
+			documentsGroup.DELETE("/:document_id", documentsHandler.Delete)
+		}
+
+		// Notion / Google Docs accounts of the caller (API key) for export; the
+		// callback comes from the browser and carries the caller in its state
+		integrationsGroup := api.Group("/integrations")
+		{
+			integrationsGroup.GET("", middleware.RequireAPIKey(), integrationsHandler.List)
+			integrationsGroup.GET("/:provider/connect", middleware.RequireAPIKey(), integrationsHandler.Connect)
+			integrationsGroup.GET("/:provider/callback", integrationsHandler.Callback)
+			integrationsGroup.DELETE("/:provider", middleware.RequireAPIKey(), integrationsHandler.Disconnect)
 		}
 	}
 
+	// GraphQL read access to sessions, messages and sources
+	router.GET("/graphql", graphqlHandler.Query)
+	router.POST("/graphql", graphqlHandler.Query)
+
 	// Root endpoint
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -41,3 +204,63 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config) {
 		})
 	})
 }
+
+// loadHooks reads the inbound hooks file; hooks stay disabled if it is invalid
+func loadHooks(cfg *config.Config) map[string]*hooks.Hook {
+	if cfg.InboundHooksPath == "" {
+		return nil
+	}
+	configured, err := hooks.Load(cfg.InboundHooksPath)
+	if err != nil {
+		log.Printf("⚠️  Inbound hooks disabled: %v", err)
+		return nil
+	}
+	log.Printf("🪝 %d inbound hooks loaded from %s", len(configured), cfg.InboundHooksPath)
+	return configured
+}
+
+// loadFooter parses the answer footer template; answers have no footer if it
+// is invalid
+func loadFooter(cfg *config.Config) *render.Footer {
+	footer, err := render.NewFooter(cfg.AnswerFooterTemplate)
+	if err != nil {
+		log.Printf("⚠️  Answer footer disabled: %v", err)
+		return nil
+	}
+	return footer
+}
+
+func startCacheWarmer(
+	cfg *config.Config,
+	answerCache *cache.AnswerCache,
+	trending *cache.Trending,
+	routerAgent *agents.RouterAgent,
+	locker *lock.Locker,
+) {
+	startHour, endHour, err := cache.ParseHours(cfg.CacheWarmHours)
+	if err != nil {
+		log.Printf("⚠️  Cache warming disabled, invalid CACHE_WARM_HOURS: %v", err)
+		return
+	}
+	if cfg.CacheWarmIntervalMinutes <= 0 {
+		log.Printf("⚠️  Cache warming disabled, CACHE_WARM_INTERVAL_MINUTES must be positive")
+		return
+	}
+
+	cache.StartWarmer(answerCache, trending, routerAgent.ProcessQuery, locker, cache.WarmerConfig{
+		Interval:  time.Duration(cfg.CacheWarmIntervalMinutes) * time.Minute,
+		TopN:      cfg.CacheWarmTopN,
+		MinCount:  int64(cfg.CacheWarmMinCount),
+		StartHour: startHour,
+		EndHour:   endHour,
+	})
+	log.Printf("🔥 Cache warming enabled for hours %s", cfg.CacheWarmHours)
+}
+
+// staticScope is the response cache scope of endpoints whose responses only
+// change on restart
+func staticScope(name string) func(*gin.Context) string {
+	return func(*gin.Context) string {
+		return name
+	}
+}
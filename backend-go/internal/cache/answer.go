@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// AnswerCache stores answers of stateless searches by mode and normalized
+// query. It uses Redis when available and an in-process map otherwise.
+//
+// Answers are fresh for freshFor and stale after that until ttl expires; stale
+// answers are still served while one background refresh replaces them
+// (stale-while-revalidate).
+type AnswerCache struct {
+	redis    *redis.Client
+	ttl      time.Duration
+	freshFor time.Duration
+
+	mu         sync.RWMutex
+	entries    map[string]memoryEntry
+	refreshing map[string]refreshClaim
+}
+
+type memoryEntry struct {
+	response  models.SearchResponse
+	expiresAt time.Time
+}
+
+// refreshClaim marks a stale answer whose refresh job is running
+type refreshClaim struct {
+	jobID     string
+	expiresAt time.Time
+}
+
+// NewAnswerCache creates the cache; answers older than freshFor are stale.
+// A freshFor of 0 or not below ttl keeps answers fresh until they expire.
+func NewAnswerCache(redisClient *redis.Client, ttl, freshFor time.Duration) *AnswerCache {
+	if freshFor <= 0 || freshFor > ttl {
+		freshFor = ttl
+	}
+	c := &AnswerCache{
+		redis:      redisClient,
+		ttl:        ttl,
+		freshFor:   freshFor,
+		entries:    make(map[string]memoryEntry),
+		refreshing: make(map[string]refreshClaim),
+	}
+	if redisClient == nil {
+		go c.cleanup()
+	}
+	return c
+}
+
+// Normalize lowercases the query and collapses whitespace so trivially
+// different spellings share a cache entry
+func Normalize(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+func normalizeMode(mode string) string {
+	if mode == "" {
+		return "auto"
+	}
+	return mode
+}
+
+func answerKey(mode, query string) string {
+	sum := sha256.Sum256([]byte(normalizeMode(mode) + "\x00" + Normalize(query)))
+	return "answer:" + hex.EncodeToString(sum[:16])
+}
+
+func refreshKey(mode, query string) string {
+	return "answer-refresh:" + strings.TrimPrefix(answerKey(mode, query), "answer:")
+}
+
+func (c *AnswerCache) Get(ctx context.Context, mode, query string) (*models.SearchResponse, bool) {
+	key := answerKey(mode, query)
+
+	if c.redis != nil {
+		data, err := c.redis.Get(ctx, key).Bytes()
+		if err != nil {
+			if err != redis.Nil {
+				log.Printf("⚠️  Answer cache read failed: %v", err)
+			}
+			return nil, false
+		}
+		var response models.SearchResponse
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, false
+		}
+		return &response, true
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	response := entry.response
+	return &response, true
+}
+
+func (c *AnswerCache) Set(ctx context.Context, mode, query string, response *models.SearchResponse) {
+	// A search-only answer is not worth keeping past the LLM outage
+	if response.Degraded {
+		return
+	}
+	key := answerKey(mode, query)
+
+	if c.redis != nil {
+		data, err := json.Marshal(response)
+		if err != nil {
+			return
+		}
+		if err := c.redis.Set(ctx, key, data, c.ttl).Err(); err != nil {
+			log.Printf("⚠️  Answer cache write failed: %v", err)
+		}
+		return
+	}
+
+	c.mu.Lock()
+	c.entries[key] = memoryEntry{response: *response, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+}
+
+// TTL returns how long the cached answer stays valid, 0 if there is none
+func (c *AnswerCache) TTL(ctx context.Context, mode, query string) time.Duration {
+	key := answerKey(mode, query)
+
+	if c.redis != nil {
+		ttl, err := c.redis.TTL(ctx, key).Result()
+		if err != nil || ttl < 0 {
+			return 0
+		}
+		return ttl
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+	if remaining := time.Until(entry.expiresAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Stale reports whether the cached answer is past its freshness window. The
+// age is derived from the remaining TTL, which both backends keep.
+func (c *AnswerCache) Stale(ctx context.Context, mode, query string) bool {
+	remaining := c.TTL(ctx, mode, query)
+	return remaining > 0 && remaining < c.ttl-c.freshFor
+}
+
+// ClaimRefresh reserves the refresh of a stale answer for timeout, so that
+// concurrent requests start one refresh job between them. When the refresh is
+// already claimed it returns false with the running job's ID, which is empty
+// until the claimer has called SetRefreshJob.
+func (c *AnswerCache) ClaimRefresh(ctx context.Context, mode, query string, timeout time.Duration) (bool, string) {
+	if c.redis != nil {
+		key := refreshKey(mode, query)
+		ok, err := c.redis.SetNX(ctx, key, "", timeout).Result()
+		if err != nil {
+			log.Printf("⚠️  Answer refresh claim failed: %v", err)
+			return false, ""
+		}
+		if ok {
+			return true, ""
+		}
+		jobID, _ := c.redis.Get(ctx, key).Result()
+		return false, jobID
+	}
+
+	key := answerKey(mode, query)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if claim, ok := c.refreshing[key]; ok && time.Now().Before(claim.expiresAt) {
+		return false, claim.jobID
+	}
+	c.refreshing[key] = refreshClaim{expiresAt: time.Now().Add(timeout)}
+	return true, ""
+}
+
+// SetRefreshJob records the job refreshing a claimed answer
+func (c *AnswerCache) SetRefreshJob(ctx context.Context, mode, query, jobID string) {
+	if c.redis != nil {
+		if err := c.redis.SetArgs(ctx, refreshKey(mode, query), jobID, redis.SetArgs{KeepTTL: true, Mode: "XX"}).Err(); err != nil && err != redis.Nil {
+			log.Printf("⚠️  Answer refresh job write failed: %v", err)
+		}
+		return
+	}
+
+	key := answerKey(mode, query)
+	c.mu.Lock()
+	if claim, ok := c.refreshing[key]; ok {
+		claim.jobID = jobID
+		c.refreshing[key] = claim
+	}
+	c.mu.Unlock()
+}
+
+// ReleaseRefresh ends the refresh of an answer, whether it succeeded or not
+func (c *AnswerCache) ReleaseRefresh(ctx context.Context, mode, query string) {
+	if c.redis != nil {
+		if err := c.redis.Del(ctx, refreshKey(mode, query)).Err(); err != nil {
+			log.Printf("⚠️  Answer refresh release failed: %v", err)
+		}
+		return
+	}
+
+	c.mu.Lock()
+	delete(c.refreshing, answerKey(mode, query))
+	c.mu.Unlock()
+}
+
+// cleanup removes expired in-memory entries
+func (c *AnswerCache) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		c.mu.Lock()
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		for key, claim := range c.refreshing {
+			if now.After(claim.expiresAt) {
+				delete(c.refreshing, key)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
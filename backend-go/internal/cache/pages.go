@@ -0,0 +1,319 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Domain classes of cached pages; each has its own TTL
+const (
+	PageClassNews      = "news"
+	PageClassReference = "reference"
+	PageClassDefault   = "default"
+)
+
+// PageTTLs sets how long fetched pages stay fresh per domain class
+type PageTTLs struct {
+	News      time.Duration
+	Reference time.Duration
+	Default   time.Duration
+}
+
+// Hosts whose pages change often (news) or hardly ever (reference); a host
+// matches itself and its subdomains
+var (
+	newsHosts = []string{
+		"reuters.com", "apnews.com", "bbc.com", "bbc.co.uk", "cnn.com", "nytimes.com",
+		"theguardian.com", "bloomberg.com", "wsj.com", "ft.com", "news.google.com",
+		"ria.ru", "tass.ru", "lenta.ru", "rbc.ru", "interfax.ru", "kommersant.ru",
+		"vedomosti.ru", "gazeta.ru", "iz.ru", "meduza.io", "fontanka.ru",
+	}
+	referenceHosts = []string{
+		"wikipedia.org", "wikimedia.org", "britannica.com", "arxiv.org", "doi.org",
+		"stackoverflow.com", "stackexchange.com", "github.com", "developer.mozilla.org",
+		"docs.python.org", "go.dev", "pkg.go.dev",
+	}
+)
+
+// PageClass returns the domain class of a page URL
+func PageClass(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return PageClassDefault
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	switch {
+	case hostMatches(host, newsHosts) || strings.HasPrefix(host, "news."):
+		return PageClassNews
+	case hostMatches(host, referenceHosts) ||
+		strings.HasSuffix(host, ".gov") || strings.HasSuffix(host, ".edu") ||
+		strings.HasPrefix(host, "docs.") || strings.HasPrefix(host, "developer."):
+		return PageClassReference
+	default:
+		return PageClassDefault
+	}
+}
+
+func hostMatches(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// PageCache is a disk-backed LRU of fetched pages, so repeated research on
+// the same topics (and benchmark reruns) don't download and extract the same
+// articles again. Entries are files named after the SHA-256 of the URL and
+// survive restarts; file modification times keep the LRU order. When the
+// cache outgrows its size limit the least recently used pages are removed.
+type PageCache struct {
+	dir      string
+	maxBytes int64
+	ttls     PageTTLs
+
+	mu    sync.Mutex
+	lru   *list.List               // front is the most recently used
+	index map[string]*list.Element // key -> *pageEntry element
+	size  int64
+}
+
+type pageEntry struct {
+	key  string
+	size int64
+}
+
+// pageFile is the on-disk entry; Data is opaque to the cache
+type pageFile struct {
+	URL       string          `json:"url"`
+	Class     string          `json:"class"`
+	StoredAt  int64           `json:"stored_at"`
+	ExpiresAt int64           `json:"expires_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewPageCache opens the cache in dir, creating it if needed, and indexes the
+// pages already stored there
+func NewPageCache(dir string, maxBytes int64, ttls PageTTLs) (*PageCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create page cache dir: %w", err)
+	}
+	c := &PageCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		ttls:     ttls,
+		lru:      list.New(),
+		index:    make(map[string]*list.Element),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load indexes the stored pages, most recently used first
+func (c *PageCache) load() error {
+	type stored struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []stored
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		key := strings.TrimSuffix(d.Name(), ".json")
+		if len(key) != sha256.Size*2 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, stored{
+			key:     key,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("scan page cache: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range files {
+		c.index[f.key] = c.lru.PushBack(&pageEntry{key: f.key, size: f.size})
+		c.size += f.size
+	}
+	c.evictLocked()
+	log.Printf("📄 Page cache: %d pages, %d KB in %s", c.lru.Len(), c.size>>10, c.dir)
+	return nil
+}
+
+func pageKey(pageURL string) string {
+	sum := sha256.Sum256([]byte(pageURL))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *PageCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// TTL returns how long a page of the URL's domain class stays fresh
+func (c *PageCache) TTL(pageURL string) time.Duration {
+	switch PageClass(pageURL) {
+	case PageClassNews:
+		return c.ttls.News
+	case PageClassReference:
+		return c.ttls.Reference
+	default:
+		return c.ttls.Default
+	}
+}
+
+// Get returns the data stored for the URL unless it expired
+func (c *PageCache) Get(pageURL string) ([]byte, bool) {
+	key := pageKey(pageURL)
+	c.mu.Lock()
+	elem, ok := c.index[key]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := c.path(key)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		c.remove(key)
+		return nil, false
+	}
+	var file pageFile
+	if err := json.Unmarshal(raw, &file); err != nil || file.URL != pageURL {
+		c.remove(key)
+		return nil, false
+	}
+	if time.Now().Unix() >= file.ExpiresAt {
+		c.remove(key)
+		return nil, false
+	}
+
+	// Keep the LRU order across restarts
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return file.Data, true
+}
+
+// Put stores data for the URL with the TTL of its domain class
+func (c *PageCache) Put(pageURL string, data []byte) {
+	ttl := c.TTL(pageURL)
+	if ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	raw, err := json.Marshal(pageFile{
+		URL:       pageURL,
+		Class:     PageClass(pageURL),
+		StoredAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		Data:      data,
+	})
+	if err != nil {
+		return
+	}
+	if int64(len(raw)) > c.maxBytes {
+		return
+	}
+
+	key := pageKey(pageURL)
+	path := c.path(key)
+	if err := writeFileAtomic(path, raw); err != nil {
+		log.Printf("⚠️  Page cache write failed: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.index[key]; ok {
+		entry := elem.Value.(*pageEntry)
+		c.size += int64(len(raw)) - entry.size
+		entry.size = int64(len(raw))
+		c.lru.MoveToFront(elem)
+	} else {
+		c.index[key] = c.lru.PushFront(&pageEntry{key: key, size: int64(len(raw))})
+		c.size += int64(len(raw))
+	}
+	c.evictLocked()
+}
+
+// writeFileAtomic writes through a temporary file so readers never see a
+// partial entry
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (c *PageCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.index[key]; ok {
+		c.removeLocked(elem)
+	}
+}
+
+// evictLocked removes least recently used pages until the cache fits its limit
+func (c *PageCache) evictLocked() {
+	for c.size > c.maxBytes {
+		elem := c.lru.Back()
+		if elem == nil {
+			return
+		}
+		c.removeLocked(elem)
+	}
+}
+
+func (c *PageCache) removeLocked(elem *list.Element) {
+	entry := elem.Value.(*pageEntry)
+	c.lru.Remove(elem)
+	delete(c.index, entry.key)
+	c.size -= entry.size
+	if err := os.Remove(c.path(entry.key)); err != nil && !os.IsNotExist(err) {
+		log.Printf("⚠️  Page cache eviction failed: %v", err)
+	}
+}
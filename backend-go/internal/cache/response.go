@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Response is a rendered response of an idempotent endpoint
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag"`
+	Body        []byte `json:"body"`
+}
+
+// ResponseCache stores rendered GET responses grouped into scopes, e.g. all
+// URLs of one chat session. Invalidating a scope bumps its version, so entries
+// stored under an older version are never served again; a response rendered
+// from data read before the invalidation is stored under the old version and
+// is dropped as well. Redis keeps the cache consistent across replicas; the
+// in-process map is for a single instance.
+type ResponseCache struct {
+	redis *redis.Client
+	ttl   time.Duration
+
+	mu       sync.RWMutex
+	entries  map[string]memoryResponse
+	versions map[string]memoryVersion
+	next     int64
+}
+
+type memoryResponse struct {
+	response  Response
+	expiresAt time.Time
+}
+
+type memoryVersion struct {
+	version   int64
+	expiresAt time.Time
+}
+
+func NewResponseCache(redisClient *redis.Client, ttl time.Duration) *ResponseCache {
+	c := &ResponseCache{
+		redis:    redisClient,
+		ttl:      ttl,
+		entries:  make(map[string]memoryResponse),
+		versions: make(map[string]memoryVersion),
+	}
+	if redisClient == nil {
+		go c.cleanup()
+	}
+	return c
+}
+
+// versionTTL outlives every entry stored under the version, so an expired
+// version can't bring old entries back
+func (c *ResponseCache) versionTTL() time.Duration {
+	return 2 * c.ttl
+}
+
+func versionKey(scope string) string {
+	return "httpcache:version:" + scope
+}
+
+func responseKey(scope string, version int64, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return "httpcache:" + scope + ":" + strconv.FormatInt(version, 10) + ":" + hex.EncodeToString(sum[:16])
+}
+
+// Version returns the current version of the scope; read it before loading
+// the data a response is rendered from
+func (c *ResponseCache) Version(ctx context.Context, scope string) int64 {
+	if c.redis != nil {
+		version, err := c.redis.Get(ctx, versionKey(scope)).Int64()
+		if err != nil && err != redis.Nil {
+			log.Printf("⚠️  Response cache version read failed: %v", err)
+			return -1
+		}
+		return version
+	}
+
+	c.mu.RLock()
+	v, ok := c.versions[scope]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(v.expiresAt) {
+		return 0
+	}
+	return v.version
+}
+
+func (c *ResponseCache) Get(ctx context.Context, scope string, version int64, url string) (*Response, bool) {
+	if version < 0 {
+		return nil, false
+	}
+	key := responseKey(scope, version, url)
+
+	if c.redis != nil {
+		data, err := c.redis.Get(ctx, key).Bytes()
+		if err != nil {
+			if err != redis.Nil {
+				log.Printf("⚠️  Response cache read failed: %v", err)
+			}
+			return nil, false
+		}
+		var response Response
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, false
+		}
+		return &response, true
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	response := entry.response
+	return &response, true
+}
+
+// Set stores the response under the version it was rendered at
+func (c *ResponseCache) Set(ctx context.Context, scope string, version int64, url string, response Response) {
+	if version < 0 {
+		return
+	}
+	key := responseKey(scope, version, url)
+
+	if c.redis != nil {
+		data, err := json.Marshal(response)
+		if err != nil {
+			return
+		}
+		if err := c.redis.Set(ctx, key, data, c.ttl).Err(); err != nil {
+			log.Printf("⚠️  Response cache write failed: %v", err)
+		}
+		return
+	}
+
+	c.mu.Lock()
+	c.entries[key] = memoryResponse{response: response, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+}
+
+// Invalidate drops all cached responses of the scope; call it after writes
+func (c *ResponseCache) Invalidate(ctx context.Context, scope string) {
+	if c.redis != nil {
+		key := versionKey(scope)
+		pipe := c.redis.TxPipeline()
+		pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, c.versionTTL())
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("⚠️  Response cache invalidation failed: %v", err)
+		}
+		return
+	}
+
+	c.mu.Lock()
+	c.next++
+	c.versions[scope] = memoryVersion{version: c.next, expiresAt: time.Now().Add(c.versionTTL())}
+	c.mu.Unlock()
+}
+
+// cleanup removes expired in-memory entries and versions
+func (c *ResponseCache) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		c.mu.Lock()
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		for scope, v := range c.versions {
+			if now.After(v.expiresAt) {
+				delete(c.versions, scope)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// trendingWindow is how long query counts are kept
+const trendingWindow = 48 * time.Hour
+
+// TrendingQuery is a frequently asked query of a mode
+type TrendingQuery struct {
+	Query string
+	Mode  string
+	Count int64
+}
+
+// Trending counts query frequency over the last two days, in Redis sorted
+// sets per day when available and in memory otherwise
+type Trending struct {
+	redis *redis.Client
+
+	mu     sync.Mutex
+	counts map[string]*trendingEntry
+}
+
+type trendingEntry struct {
+	count    int64
+	lastSeen time.Time
+}
+
+func NewTrending(redisClient *redis.Client) *Trending {
+	return &Trending{
+		redis:  redisClient,
+		counts: make(map[string]*trendingEntry),
+	}
+}
+
+func trendingMember(mode, query string) string {
+	return normalizeMode(mode) + "|" + Normalize(query)
+}
+
+func trendingKey(day time.Time) string {
+	return "trending:queries:" + day.Format("20060102")
+}
+
+func (t *Trending) Record(ctx context.Context, mode, query string) {
+	member := trendingMember(mode, query)
+
+	if t.redis != nil {
+		key := trendingKey(time.Now())
+		pipe := t.redis.Pipeline()
+		pipe.ZIncrBy(ctx, key, 1, member)
+		pipe.Expire(ctx, key, trendingWindow)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("⚠️  Failed to record trending query: %v", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.counts[member]
+	if !ok || now.Sub(entry.lastSeen) > trendingWindow {
+		entry = &trendingEntry{}
+		t.counts[member] = entry
+	}
+	entry.count++
+	entry.lastSeen = now
+}
+
+// Top returns up to n queries asked at least minCount times, most frequent first
+func (t *Trending) Top(ctx context.Context, n int, minCount int64) []TrendingQuery {
+	counts := make(map[string]int64)
+
+	if t.redis != nil {
+		now := time.Now()
+		for _, day := range []time.Time{now, now.Add(-24 * time.Hour)} {
+			members, err := t.redis.ZRevRangeWithScores(ctx, trendingKey(day), 0, int64(n*4)).Result()
+			if err != nil {
+				log.Printf("⚠️  Failed to read trending queries: %v", err)
+				continue
+			}
+			for _, z := range members {
+				if member, ok := z.Member.(string); ok {
+					counts[member] += int64(z.Score)
+				}
+			}
+		}
+	} else {
+		cutoff := time.Now().Add(-trendingWindow)
+		t.mu.Lock()
+		for member, entry := range t.counts {
+			if entry.lastSeen.Before(cutoff) {
+				delete(t.counts, member)
+				continue
+			}
+			counts[member] = entry.count
+		}
+		t.mu.Unlock()
+	}
+
+	top := make([]TrendingQuery, 0, len(counts))
+	for member, count := range counts {
+		if count < minCount {
+			continue
+		}
+		mode, query, ok := strings.Cut(member, "|")
+		if !ok {
+			continue
+		}
+		top = append(top, TrendingQuery{Query: query, Mode: mode, Count: count})
+	}
+
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].Count > top[j].Count
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/lock"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// ProcessFunc answers a query in the given mode (RouterAgent.ProcessQuery)
+type ProcessFunc func(ctx context.Context, query, mode string) (*models.SearchResponse, error)
+
+// WarmerConfig controls which trending queries are refreshed and when
+type WarmerConfig struct {
+	Interval  time.Duration
+	TopN      int
+	MinCount  int64
+	StartHour int // off-peak window in server local time, [StartHour, EndHour)
+	EndHour   int
+}
+
+// Warmer periodically re-answers trending queries during off-peak hours so
+// their cached answers are fresh when users ask them
+type Warmer struct {
+	cache    *AnswerCache
+	trending *Trending
+	process  ProcessFunc
+	locker   *lock.Locker
+	cfg      WarmerConfig
+}
+
+// StartWarmer runs the warming loop in a background goroutine. With several
+// replicas, locker makes only one of them warm per interval.
+func StartWarmer(
+	cache *AnswerCache,
+	trending *Trending,
+	process ProcessFunc,
+	locker *lock.Locker,
+	cfg WarmerConfig,
+) *Warmer {
+	w := &Warmer{
+		cache:    cache,
+		trending: trending,
+		process:  process,
+		locker:   locker,
+		cfg:      cfg,
+	}
+	go w.run()
+	return w
+}
+
+// ParseHours parses an off-peak window like "1-7" (hours 1:00 to 6:59)
+func ParseHours(value string) (int, int, error) {
+	start, end, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected START-END, got %q", value)
+	}
+	startHour, err := strconv.Atoi(strings.TrimSpace(start))
+	if err != nil || startHour < 0 || startHour > 23 {
+		return 0, 0, fmt.Errorf("invalid start hour in %q", value)
+	}
+	endHour, err := strconv.Atoi(strings.TrimSpace(end))
+	if err != nil || endHour < 0 || endHour > 24 {
+		return 0, 0, fmt.Errorf("invalid end hour in %q", value)
+	}
+	return startHour, endHour, nil
+}
+
+func (w *Warmer) offPeak(now time.Time) bool {
+	hour := now.Hour()
+	if w.cfg.StartHour <= w.cfg.EndHour {
+		return hour >= w.cfg.StartHour && hour < w.cfg.EndHour
+	}
+	// Window wraps around midnight, e.g. 22-6
+	return hour >= w.cfg.StartHour || hour < w.cfg.EndHour
+}
+
+func (w *Warmer) run() {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if !w.offPeak(now) {
+			continue
+		}
+		if !w.locker.Claim(context.Background(), "cache-warm", w.cfg.Interval, now) {
+			continue
+		}
+		w.warm()
+	}
+}
+
+// warm refreshes trending answers that would expire before the next run
+func (w *Warmer) warm() {
+	ctx := context.Background()
+	queries := w.trending.Top(ctx, w.cfg.TopN, w.cfg.MinCount)
+	if len(queries) == 0 {
+		return
+	}
+
+	warmed := 0
+	for _, q := range queries {
+		if w.cache.TTL(ctx, q.Mode, q.Query) > w.cfg.Interval {
+			continue
+		}
+
+		queryCtx, cancel := context.WithTimeout(ctx, 90*time.Second)
+		result, err := w.process(queryCtx, q.Query, q.Mode)
+		cancel()
+		if err != nil {
+			log.Printf("⚠️  Cache warming failed for %q (%s): %v", q.Query, q.Mode, err)
+			continue
+		}
+
+		w.cache.Set(ctx, q.Mode, q.Query, result)
+		warmed++
+	}
+
+	log.Printf("🔥 Cache warming: refreshed %d of %d trending queries", warmed, len(queries))
+}
@@ -23,13 +23,275 @@ type Config struct {
 	AnthropicKey string
 	QwenAPIURL   string
 	QwenModel    string
+	// Embedding model of the OpenAI-compatible provider
+	EmbeddingModel string
+	// Texts per embedding request; 0 uses the provider's default
+	EmbeddingBatchSize int
+
+	// LLM provider: openai (OpenAI or the OpenAI-compatible QWEN_API_URL),
+	// qwen or gigachat
+	LLMProvider string
+	// Providers tried in order when the primary fails, as provider[:model]
+	// entries; a provider with fallbacks left gets LLMFallbackTimeoutSeconds
+	LLMFallbacks              []string
+	LLMFallbackTimeoutSeconds int
+	// Context window of the LLM models in tokens; 0 looks it up by model
+	LLMContextWindow int
+	// Transient LLM failures (rate limits, server errors) are retried up to
+	// LLMRetryAttempts calls in all, for at most LLMRetryMaxSeconds
+	LLMRetryAttempts   int
+	LLMRetryMaxSeconds int
+	// At most LLMMaxConcurrency calls run at once per provider (0 = no
+	// limit); the others queue for up to LLMQueueTimeoutSeconds
+	LLMMaxConcurrency      int
+	LLMQueueTimeoutSeconds int
+	// A provider failing LLMBreakerFailures calls in a row is skipped for
+	// LLMBreakerCooldownSeconds, then probed (0 failures = no breaker)
+	LLMBreakerFailures        int
+	LLMBreakerCooldownSeconds int
+	// Models of the primary provider for tasks (enhance, route, plan,
+	// extract, summarize, verify, synthesis), as task=model entries
+	LLMTaskModels []string
+	// GigaChat: the authorization key (Base64 of client_id:client_secret) is
+	// exchanged for access tokens of the scope. Its certificates are issued by
+	// the Russian Trusted Root CA, whose PEM bundle GigaChatCACert points to.
+	GigaChatAuthKey            string
+	GigaChatScope              string
+	GigaChatModel              string
+	GigaChatEmbeddingModel     string
+	GigaChatAuthURL            string
+	GigaChatAPIURL             string
+	GigaChatCACert             string
+	GigaChatInsecureSkipVerify bool
 
 	// CORS
 	CORSOrigins []string
+	// Proxies whose X-Forwarded-For / X-Real-IP give the client IP; the
+	// connection address is used when empty
+	TrustedProxies []string
+
+	// Rate limiting (requests per minute, burst size and daily quota per client)
+	RateLimitEnabled     bool
+	RateLimitSimpleRPM   int
+	RateLimitSimpleBurst int
+	RateLimitProRPM      int
+	RateLimitProBurst    int
+	DailyQuotaSimple     int
+	DailyQuotaPro        int
+	// API keys limited on their own; other keys share the limits of their IP
+	RateLimitAPIKeys []string
+
+	// Auto mode routing model (JSON weights file and decision thresholds)
+	AutoModeModelPath       string
+	AutoModeProThreshold    float64
+	AutoModeSimpleThreshold float64
+	AutoModeRace            bool
+	// Labeled examples of the embedding mode classifier (JSON, the built-in
+	// set when empty) and the vote margin below which the LLM decides
+	ModeExamplesPath        string
+	ModeClassifierMinMargin float64
+	// Minimum confidence (0-1) to hand a Pro query to a vertical agent; 0 disables
+	AutoVerticalThreshold float64
+	// Cost-aware auto mode: Pro only when the query needs it, not for a long
+	// conversation alone, and within the LLM token budget of a chat session
+	// (0 = unlimited)
+	AutoCostAware      bool
+	SessionTokenBudget int64
+	// Auto mode answers again with Pro when the Simple answer's evidence score
+	// or source count is below these minimums
+	AutoEscalation              bool
+	AutoEscalationMinConfidence float64
+	AutoEscalationMinSources    int
+
+	// News agent (pro-news): extra RSS/Atom feeds next to Google News and how
+	// recent a source must be to be preferred
+	NewsRSSFeeds     []string
+	NewsRecencyHours int
+
+	// Code agent (pro-code): optional GitHub token and Stack Exchange key that
+	// raise the anonymous API rate limits
+	GitHubToken      string
+	StackExchangeKey string
+
+	// Video agent (pro-video): optional YouTube Data API key; without it videos
+	// are found on the YouTube results page
+	YouTubeAPIKey string
+
+	// Web search: with a Tavily API key Tavily is tried ahead of SearXNG, at
+	// the search depth (basic or advanced)
+	TavilyAPIKey      string
+	TavilySearchDepth string
+	// SerpAPI key: Google results, tried after Tavily and ahead of SearXNG
+	SerpAPIKey string
+	// Results of each search provider are cached by query and parameters,
+	// in Redis when available (0 TTL disables the cache)
+	SearchCacheTTLMinutes int
+	// Politeness of the searches and scrapers: the User-Agent identifying the
+	// bot, the interval between requests to a host (0 = none) and whether
+	// robots.txt of crawled sites is respected
+	CrawlerUserAgent      string
+	CrawlerHostIntervalMs int
+	CrawlerRobotsTxt      bool
+
+	// Deep research mode: time and LLM token budget of one query and the
+	// maximum number of search rounds
+	DeepResearchTimeoutSeconds int
+	DeepResearchMaxTokens      int
+	DeepResearchMaxRounds      int
+
+	// Auto mode answers weather, exchange rate and unit conversion questions
+	// from data providers (Open-Meteo, Bank of Russia) without search or LLM
+	InstantAnswersEnabled bool
+	// Auto mode sends requests to see something ("покажи картину ...") to
+	// images mode
+	ImageAnswersEnabled bool
+
+	// LLM extraction of structured query constraints (Pro modes)
+	QueryExtractionEnabled bool
+
+	// Conversation history passed to the agents: most recent messages that fit
+	// into the character budget
+	ChatHistoryMaxMessages int
+	ChatHistoryMaxChars    int
+	// Once a session has more unsummarized messages than ChatSummaryAfter, the
+	// older ones are summarized into its memory and the most recent
+	// ChatSummaryKeepRecent stay verbatim; 0 turns summarization off
+	ChatSummaryAfter      int
+	ChatSummaryKeepRecent int
+
+	// Minimum combined source credibility/agreement (0-1) for a confident
+	// answer; below it agents state uncertainty or decline. 0 disables the check.
+	EvidenceThreshold float64
+
+	// HMAC secret for signing search callbacks; callbacks are disabled without it
+	WebhookSecret string
+
+	// HMAC secret for signing session share links; sharing is disabled without it
+	ShareSecret string
+
+	// Uploaded documents (POST /api/documents)
+	MaxDocumentSizeMB int
+
+	// Preview cards (site name, favicon, image) of cited sources, cached by URL
+	SourcePreviewsEnabled   bool
+	SourcePreviewCacheHours int
+
+	// Fetching the text of top result pages for Pro modes, and the disk cache
+	// of fetched pages (LRU by size, TTL by domain class; 0 MB disables it)
+	FetchPageContent        bool
+	PageCacheDir            string
+	PageCacheMaxMB          int
+	PageCacheNewsHours      int
+	PageCacheReferenceHours int
+	PageCacheDefaultHours   int
+
+	// Translation of source snippets written in another language than the
+	// answer: llm, libretranslate, deepl or none. The API URL and key are for
+	// LibreTranslate and DeepL.
+	SnippetTranslationProvider string
+	TranslationAPIURL          string
+	TranslationAPIKey          string
+
+	// Pro searches a query in the other language too (ru <-> en), translating
+	// it with the snippet translator
+	CrossLanguageSearch bool
+
+	// Pro summarizes each top source with regard to the query (parallel LLM
+	// calls) before the synthesis instead of truncating its text
+	ProSourceSummaries bool
+
+	// Most sub-questions (2-6) Pro splits a multi-hop query into; the LLM
+	// estimates how many the query needs
+	ProMaxSubQueries int
+
+	// Pro extracts the entities of its top sources into an in-memory graph per
+	// chat session, used to resolve pronouns in follow-up questions
+	EntityGraphEnabled bool
+
+	// Check every answer sentence against the sources after synthesis (one LLM
+	// call): off, flag or strip the unsupported ones
+	AnswerVerification string
+
+	// Guardrails applied to every query before search and LLM providers see
+	// it: the block/sanitize policy (JSON file, the built-in one when empty)
+	// and the redaction of emails, phones and card numbers
+	GuardrailsEnabled    bool
+	GuardrailsPolicyPath string
+	PIIRedactionEnabled  bool
+
+	// text/template appended to channel-rendered answers (disclaimer, source
+	// count, branding); no footer when empty
+	AnswerFooterTemplate string
+
+	// Inbound hooks: JSON file of templated research requests external systems
+	// can trigger; the bot token delivers reports to Telegram chats
+	InboundHooksPath string
+	TelegramBotToken string
+
+	// Where browsers reach this API: OAuth redirects and share links
+	PublicURL string
+
+	// Session export to Notion / Google Docs; OAuthStateSecret signs the OAuth
+	// state and export is disabled without it
+	OAuthStateSecret   string
+	NotionClientID     string
+	NotionClientSecret string
+	GoogleClientID     string
+	GoogleClientSecret string
+
+	// Answer cache for stateless searches (0 TTL disables it), how long its
+	// answers are fresh before being served stale and refreshed, and off-peak
+	// warming of trending queries
+	AnswerCacheTTLMinutes    int
+	AnswerCacheFreshMinutes  int
+	CacheWarmEnabled         bool
+	CacheWarmHours           string // off-peak window in server local time, e.g. "1-7"
+	CacheWarmIntervalMinutes int
+	CacheWarmTopN            int
+	CacheWarmMinCount        int
+
+	// API keys allowed to call /api/admin endpoints; admin API is off without them
+	AdminAPIKeys []string
+
+	// Keep jobs, cancellable requests, session locks and rate limit buckets in
+	// Redis instead of process memory, for running several replicas
+	SharedStateEnabled bool
+
+	// Cache of GET session, shared session and modes responses with ETags,
+	// invalidated on writes (0 TTL disables it)
+	HTTPCacheTTLSeconds int
+
+	// Days reasoning step traces are kept (0 keeps them forever)
+	TraceRetentionDays int
+
+	// LLM prices in USD per 1K tokens, used by POST /api/estimate and the
+	// cost of LLM calls (0 = no cost)
+	LLMPromptPricePer1K     float64
+	LLMCompletionPricePer1K float64
+	// Per model prices overriding them, as model=prompt/completion
+	LLMPricing []string
 }
 
 func LoadConfig() *Config {
 	debug, _ := strconv.ParseBool(getEnv("DEBUG", "true"))
+	rateLimitEnabled, _ := strconv.ParseBool(getEnv("RATE_LIMIT_ENABLED", "true"))
+	autoModeRace, _ := strconv.ParseBool(getEnv("AUTO_MODE_RACE", "false"))
+	gigaChatInsecure, _ := strconv.ParseBool(getEnv("GIGACHAT_INSECURE_SKIP_VERIFY", "false"))
+	autoCostAware, _ := strconv.ParseBool(getEnv("AUTO_COST_AWARE", "true"))
+	autoEscalation, _ := strconv.ParseBool(getEnv("AUTO_ESCALATION", "true"))
+	queryExtractionEnabled, _ := strconv.ParseBool(getEnv("QUERY_EXTRACTION_ENABLED", "true"))
+	cacheWarmEnabled, _ := strconv.ParseBool(getEnv("CACHE_WARM_ENABLED", "true"))
+	sharedStateEnabled, _ := strconv.ParseBool(getEnv("SHARED_STATE_ENABLED", "false"))
+	sourcePreviewsEnabled, _ := strconv.ParseBool(getEnv("SOURCE_PREVIEWS_ENABLED", "true"))
+	fetchPageContent, _ := strconv.ParseBool(getEnv("FETCH_PAGE_CONTENT", "true"))
+	crawlerRobotsTxt, _ := strconv.ParseBool(getEnv("CRAWLER_ROBOTS_TXT", "true"))
+	instantAnswersEnabled, _ := strconv.ParseBool(getEnv("INSTANT_ANSWERS_ENABLED", "true"))
+	imageAnswersEnabled, _ := strconv.ParseBool(getEnv("IMAGE_ANSWERS_ENABLED", "true"))
+	crossLanguageSearch, _ := strconv.ParseBool(getEnv("CROSS_LANGUAGE_SEARCH", "true"))
+	proSourceSummaries, _ := strconv.ParseBool(getEnv("PRO_SOURCE_SUMMARIES", "true"))
+	entityGraphEnabled, _ := strconv.ParseBool(getEnv("ENTITY_GRAPH_ENABLED", "true"))
+	guardrailsEnabled, _ := strconv.ParseBool(getEnv("GUARDRAILS_ENABLED", "true"))
+	piiRedactionEnabled, _ := strconv.ParseBool(getEnv("PII_REDACTION_ENABLED", "true"))
 
 	// Parse CORS origins
 	corsOrigins := getEnv("CORS_ORIGINS", "http://localhost:3000")
@@ -52,7 +314,149 @@ func LoadConfig() *Config {
 		QwenAPIURL:   getEnv("QWEN_API_URL", ""),
 		QwenModel:    getEnv("QWEN_MODEL", "qwen-turbo"),
 
-		CORSOrigins: origins,
+		EmbeddingModel:     getEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
+		EmbeddingBatchSize: getEnvInt("EMBEDDING_BATCH_SIZE", 0),
+
+		LLMProvider:                getEnv("LLM_PROVIDER", "openai"),
+		LLMFallbacks:               getEnvList("LLM_FALLBACKS"),
+		LLMFallbackTimeoutSeconds:  getEnvInt("LLM_FALLBACK_TIMEOUT_SECONDS", 30),
+		LLMContextWindow:           getEnvInt("LLM_CONTEXT_WINDOW", 0),
+		LLMRetryAttempts:           getEnvInt("LLM_RETRY_ATTEMPTS", 3),
+		LLMRetryMaxSeconds:         getEnvInt("LLM_RETRY_MAX_SECONDS", 20),
+		LLMMaxConcurrency:          getEnvInt("LLM_MAX_CONCURRENCY", 8),
+		LLMQueueTimeoutSeconds:     getEnvInt("LLM_QUEUE_TIMEOUT_SECONDS", 30),
+		LLMTaskModels:              getEnvList("LLM_TASK_MODELS"),
+		LLMBreakerFailures:         getEnvInt("LLM_BREAKER_FAILURES", 5),
+		LLMBreakerCooldownSeconds:  getEnvInt("LLM_BREAKER_COOLDOWN_SECONDS", 30),
+		GigaChatAuthKey:            getEnv("GIGACHAT_AUTH_KEY", ""),
+		GigaChatScope:              getEnv("GIGACHAT_SCOPE", "GIGACHAT_API_PERS"),
+		GigaChatModel:              getEnv("GIGACHAT_MODEL", "GigaChat"),
+		GigaChatEmbeddingModel:     getEnv("GIGACHAT_EMBEDDING_MODEL", "Embeddings"),
+		GigaChatAuthURL:            getEnv("GIGACHAT_AUTH_URL", "https://ngw.devices.sberbank.ru:9443/api/v2/oauth"),
+		GigaChatAPIURL:             getEnv("GIGACHAT_API_URL", "https://gigachat.devices.sberbank.ru/api/v1"),
+		GigaChatCACert:             getEnv("GIGACHAT_CA_CERT", ""),
+		GigaChatInsecureSkipVerify: gigaChatInsecure,
+
+		CORSOrigins:    origins,
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+
+		RateLimitEnabled:     rateLimitEnabled,
+		RateLimitSimpleRPM:   getEnvInt("RATE_LIMIT_SIMPLE_RPM", 30),
+		RateLimitSimpleBurst: getEnvInt("RATE_LIMIT_SIMPLE_BURST", 10),
+		RateLimitProRPM:      getEnvInt("RATE_LIMIT_PRO_RPM", 10),
+		RateLimitProBurst:    getEnvInt("RATE_LIMIT_PRO_BURST", 3),
+		DailyQuotaSimple:     getEnvInt("DAILY_QUOTA_SIMPLE", 1000),
+		DailyQuotaPro:        getEnvInt("DAILY_QUOTA_PRO", 200),
+		RateLimitAPIKeys:     getEnvList("RATE_LIMIT_API_KEYS"),
+
+		AutoModeModelPath:       getEnv("AUTO_MODE_MODEL_PATH", ""),
+		AutoModeProThreshold:    getEnvFloat("AUTO_MODE_PRO_THRESHOLD", 0.6),
+		AutoModeSimpleThreshold: getEnvFloat("AUTO_MODE_SIMPLE_THRESHOLD", 0.0),
+		AutoModeRace:            autoModeRace,
+		ModeExamplesPath:        getEnv("MODE_EXAMPLES_PATH", ""),
+		ModeClassifierMinMargin: getEnvFloat("MODE_CLASSIFIER_MIN_MARGIN", 0.2),
+		AutoVerticalThreshold:   getEnvFloat("AUTO_VERTICAL_THRESHOLD", 0.5),
+		AutoCostAware:           autoCostAware,
+		SessionTokenBudget:      int64(getEnvInt("SESSION_TOKEN_BUDGET", 0)),
+
+		AutoEscalation:              autoEscalation,
+		AutoEscalationMinConfidence: getEnvFloat("AUTO_ESCALATION_MIN_CONFIDENCE", 0.35),
+		AutoEscalationMinSources:    getEnvInt("AUTO_ESCALATION_MIN_SOURCES", 3),
+
+		NewsRSSFeeds:     getEnvList("NEWS_RSS_FEEDS"),
+		NewsRecencyHours: getEnvInt("NEWS_RECENCY_HOURS", 48),
+
+		GitHubToken:      getEnv("GITHUB_TOKEN", ""),
+		StackExchangeKey: getEnv("STACKEXCHANGE_KEY", ""),
+		YouTubeAPIKey:    getEnv("YOUTUBE_API_KEY", ""),
+
+		TavilyAPIKey:      getEnv("TAVILY_API_KEY", ""),
+		TavilySearchDepth: getEnv("TAVILY_SEARCH_DEPTH", "basic"),
+		SerpAPIKey:        getEnv("SERPAPI_API_KEY", ""),
+
+		SearchCacheTTLMinutes: getEnvInt("SEARCH_CACHE_TTL_MINUTES", 30),
+		CrawlerUserAgent:      getEnv("CRAWLER_USER_AGENT", "Mozilla/5.0 (compatible; ResearchProBot/1.0)"),
+		CrawlerHostIntervalMs: getEnvInt("CRAWLER_HOST_INTERVAL_MS", 500),
+		CrawlerRobotsTxt:      crawlerRobotsTxt,
+
+		DeepResearchTimeoutSeconds: getEnvInt("DEEP_RESEARCH_TIMEOUT_SECONDS", 90),
+		DeepResearchMaxTokens:      getEnvInt("DEEP_RESEARCH_MAX_TOKENS", 40000),
+		DeepResearchMaxRounds:      getEnvInt("DEEP_RESEARCH_MAX_ROUNDS", 4),
+
+		InstantAnswersEnabled: instantAnswersEnabled,
+		ImageAnswersEnabled:   imageAnswersEnabled,
+
+		QueryExtractionEnabled: queryExtractionEnabled,
+
+		ChatHistoryMaxMessages: getEnvInt("CHAT_HISTORY_MAX_MESSAGES", 20),
+		ChatHistoryMaxChars:    getEnvInt("CHAT_HISTORY_MAX_CHARS", 12000),
+		ChatSummaryAfter:       getEnvInt("CHAT_SUMMARY_AFTER_MESSAGES", 12),
+		ChatSummaryKeepRecent:  getEnvInt("CHAT_SUMMARY_KEEP_RECENT", 6),
+
+		EvidenceThreshold: getEnvFloat("EVIDENCE_THRESHOLD", 0.45),
+
+		WebhookSecret: getEnv("WEBHOOK_SECRET", ""),
+
+		ShareSecret: getEnv("SHARE_SECRET", ""),
+
+		MaxDocumentSizeMB: getEnvInt("MAX_DOCUMENT_SIZE_MB", 10),
+
+		SourcePreviewsEnabled:   sourcePreviewsEnabled,
+		SourcePreviewCacheHours: getEnvInt("SOURCE_PREVIEW_CACHE_HOURS", 24),
+
+		FetchPageContent:        fetchPageContent,
+		PageCacheDir:            getEnv("PAGE_CACHE_DIR", "data/page-cache"),
+		PageCacheMaxMB:          getEnvInt("PAGE_CACHE_MAX_MB", 512),
+		PageCacheNewsHours:      getEnvInt("PAGE_CACHE_TTL_NEWS_HOURS", 6),
+		PageCacheReferenceHours: getEnvInt("PAGE_CACHE_TTL_REFERENCE_HOURS", 720),
+		PageCacheDefaultHours:   getEnvInt("PAGE_CACHE_TTL_DEFAULT_HOURS", 168),
+
+		SnippetTranslationProvider: getEnv("SNIPPET_TRANSLATION_PROVIDER", "llm"),
+		TranslationAPIURL:          getEnv("TRANSLATION_API_URL", ""),
+		TranslationAPIKey:          getEnv("TRANSLATION_API_KEY", ""),
+
+		CrossLanguageSearch: crossLanguageSearch,
+		ProSourceSummaries:  proSourceSummaries,
+		ProMaxSubQueries:    getEnvInt("PRO_MAX_SUB_QUERIES", 6),
+		EntityGraphEnabled:  entityGraphEnabled,
+
+		AnswerVerification: getEnv("ANSWER_VERIFICATION", "flag"),
+
+		GuardrailsEnabled:    guardrailsEnabled,
+		GuardrailsPolicyPath: getEnv("GUARDRAILS_POLICY_PATH", ""),
+		PIIRedactionEnabled:  piiRedactionEnabled,
+
+		AnswerFooterTemplate: getEnv("ANSWER_FOOTER_TEMPLATE", ""),
+
+		InboundHooksPath: getEnv("INBOUND_HOOKS_PATH", ""),
+		TelegramBotToken: getEnv("TELEGRAM_BOT_TOKEN", ""),
+
+		PublicURL:          strings.TrimRight(getEnv("PUBLIC_URL", "http://localhost:8000"), "/"),
+		OAuthStateSecret:   getEnv("OAUTH_STATE_SECRET", ""),
+		NotionClientID:     getEnv("NOTION_CLIENT_ID", ""),
+		NotionClientSecret: getEnv("NOTION_CLIENT_SECRET", ""),
+		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
+		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
+
+		AnswerCacheTTLMinutes:    getEnvInt("ANSWER_CACHE_TTL_MINUTES", 60),
+		AnswerCacheFreshMinutes:  getEnvInt("ANSWER_CACHE_FRESH_MINUTES", 15),
+		CacheWarmEnabled:         cacheWarmEnabled,
+		CacheWarmHours:           getEnv("CACHE_WARM_HOURS", "1-7"),
+		CacheWarmIntervalMinutes: getEnvInt("CACHE_WARM_INTERVAL_MINUTES", 30),
+		CacheWarmTopN:            getEnvInt("CACHE_WARM_TOP_N", 20),
+		CacheWarmMinCount:        getEnvInt("CACHE_WARM_MIN_COUNT", 3),
+
+		AdminAPIKeys: getEnvList("ADMIN_API_KEYS"),
+
+		SharedStateEnabled: sharedStateEnabled,
+
+		HTTPCacheTTLSeconds: getEnvInt("HTTP_CACHE_TTL_SECONDS", 300),
+
+		TraceRetentionDays: getEnvInt("TRACE_RETENTION_DAYS", 7),
+
+		LLMPromptPricePer1K:     getEnvFloat("LLM_PROMPT_PRICE_PER_1K", 0),
+		LLMCompletionPricePer1K: getEnvFloat("LLM_COMPLETION_PRICE_PER_1K", 0),
+		LLMPricing:              getEnvList("LLM_PRICING"),
 	}
 }
 
@@ -61,4 +465,33 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvList parses a comma separated list, skipping empty items
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 	"unicode/utf8"
@@ -17,17 +19,39 @@ type ChatSession struct {
 	Mode      string    `json:"mode"`
 	CreatedAt int64     `json:"created_at"`
 	UpdatedAt int64     `json:"updated_at"`
+	LastSeq   int64     `gorm:"not null;default:0" json:"last_seq"` // Seq of the latest message
 	Messages  []Message `gorm:"foreignKey:SessionID" json:"messages"`
+
+	// SystemPrompt is the persona of the session (tone, depth, domain
+	// expertise) added to the prompts of every answer
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	// TokensUsed sums the LLM tokens of the session's answers; auto mode keeps
+	// it within SESSION_TOKEN_BUDGET
+	TokensUsed int64 `gorm:"not null;default:0" json:"tokens_used"`
+	// CostUSD sums the cost of those tokens at the configured LLM prices
+	CostUSD float64 `gorm:"not null;default:0" json:"cost_usd"`
+	// Summary is the rolling memory of the messages up to SummarySeq, fed to
+	// the agents instead of those messages
+	Summary    string `json:"summary,omitempty"`
+	SummarySeq int64  `gorm:"not null;default:0" json:"summary_seq,omitempty"`
 }
 
 type Message struct {
 	ID        string   `gorm:"primaryKey" json:"id"`
-	SessionID string   `json:"session_id"`
-	Role      string   `json:"role"` // user, assistant, system
+	SessionID string   `gorm:"index:idx_message_session_seq,priority:1" json:"session_id"`
+	Seq       int64    `gorm:"index:idx_message_session_seq,priority:2" json:"seq"` // turn number within the session
+	Role      string   `json:"role"`                                                // user, assistant, system
 	Content   string   `json:"content"`
 	Timestamp int64    `json:"timestamp"`
 	Sources   []Source `gorm:"foreignKey:MessageID" json:"sources,omitempty"`
 	Reasoning string   `json:"reasoning,omitempty"`
+
+	// Routing audit (assistant messages): mode the user asked for, the
+	// resulting mode ("auto → pro") and the agent that produced the answer
+	RequestedMode string `json:"requested_mode,omitempty"`
+	Mode          string `json:"mode,omitempty"`
+	Agent         string `gorm:"index" json:"agent,omitempty"` // simple, pro, pro-social, pro-academic, pro-finance, pro-news, pro-code, pro-video, pro-factcheck, pro-mixed, pro-tools, deep, images, instant
+	DecidedBy     string `json:"decided_by,omitempty"`         // model, classifier, selector (auto mode only)
 }
 
 type Source struct {
@@ -37,6 +61,135 @@ type Source struct {
 	URL         string  `json:"url"`
 	Snippet     string  `json:"snippet"`
 	Credibility float64 `json:"credibility,omitempty"`
+	PublishedAt int64   `json:"published_at,omitempty"`
+	FetchedAt   int64   `json:"fetched_at,omitempty"`
+}
+
+// Feedback is a user rating of an assistant answer. It is linked to the rated
+// message and to the sources that answer was built from.
+type Feedback struct {
+	ID        uint     `gorm:"primaryKey" json:"id"`
+	MessageID string   `gorm:"index" json:"message_id"`
+	SessionID string   `gorm:"index" json:"session_id"`
+	Rating    int      `json:"rating"`
+	Comment   string   `json:"comment,omitempty"`
+	CreatedAt int64    `json:"created_at"`
+	Message   *Message `gorm:"foreignKey:MessageID" json:"-"`
+	Sources   []Source `gorm:"many2many:feedback_sources" json:"sources,omitempty"`
+}
+
+// History is a past query of a client (API key), stored at search time
+type History struct {
+	ID            uint   `gorm:"primaryKey" json:"id"`
+	UserKey       string `gorm:"index" json:"-"`
+	SessionID     string `gorm:"index" json:"session_id,omitempty"`
+	Query         string `json:"query"`
+	RequestedMode string `json:"requested_mode"`
+	Mode          string `json:"mode"`
+	LatencyMs     int64  `json:"latency_ms"`
+	AnswerSummary string `json:"answer_summary"`
+	SourcesCount  int    `json:"sources_count"`
+	CreatedAt     int64  `gorm:"index" json:"created_at"`
+}
+
+// Usage is one answered (or failed) query, aggregated by GET /api/admin/stats
+type Usage struct {
+	ID               uint    `gorm:"primaryKey" json:"id"`
+	Endpoint         string  `json:"endpoint"` // search, chat, callback
+	Mode             string  `gorm:"index" json:"mode"`
+	Agent            string  `json:"agent,omitempty"`
+	Status           string  `json:"status"` // ok, error, cancelled
+	LatencyMs        int64   `json:"latency_ms"`
+	Cached           bool    `json:"cached"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	Day              string  `gorm:"index" json:"day"` // YYYY-MM-DD, UTC
+	CreatedAt        int64   `gorm:"index" json:"created_at"`
+}
+
+// ReasoningStep is one agent reasoning step, stored as soon as it happens so
+// that a request which times out still leaves a trace of how far it got
+type ReasoningStep struct {
+	ID        uint   `gorm:"primaryKey" json:"-"`
+	RequestID string `gorm:"index:idx_reasoning_step_request,priority:1" json:"request_id"`
+	Seq       int64  `gorm:"index:idx_reasoning_step_request,priority:2" json:"seq"`
+	Step      string `json:"step"`
+	ElapsedMs int64  `json:"elapsed_ms"` // since the request started
+	CreatedAt int64  `gorm:"index" json:"created_at"`
+}
+
+// SessionShare is a read-only link to a chat session (GET /api/shared/:token)
+type SessionShare struct {
+	ID        string `gorm:"primaryKey" json:"-"`
+	SessionID string `gorm:"index" json:"session_id"`
+	ExpiresAt int64  `json:"expires_at,omitempty"` // 0 if the link does not expire
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Document is a file a client (API key) uploaded to ground answers in;
+// its text is stored as chunks
+type Document struct {
+	ID         string          `gorm:"primaryKey" json:"id"`
+	UserKey    string          `gorm:"index" json:"-"`
+	Filename   string          `json:"filename"`
+	Size       int64           `json:"size"`       // bytes of the uploaded file
+	Characters int             `json:"characters"` // of the extracted text
+	ChunkCount int             `json:"chunks"`
+	CreatedAt  int64           `json:"created_at"`
+	Chunks     []DocumentChunk `gorm:"foreignKey:DocumentID" json:"-"`
+}
+
+type DocumentChunk struct {
+	ID         uint   `gorm:"primaryKey" json:"-"`
+	DocumentID string `gorm:"index:idx_document_chunk,priority:1" json:"document_id"`
+	Seq        int    `gorm:"index:idx_document_chunk,priority:2" json:"seq"` // position in the document, from 0
+	Content    string `json:"content"`
+}
+
+// IntegrationToken is the OAuth token a client (API key) connected for
+// exporting sessions to a provider (notion, google_docs)
+type IntegrationToken struct {
+	ID           uint   `gorm:"primaryKey" json:"-"`
+	UserKey      string `gorm:"uniqueIndex:idx_integration_user_provider,priority:1" json:"-"`
+	Provider     string `gorm:"uniqueIndex:idx_integration_user_provider,priority:2" json:"provider"`
+	AccessToken  string `json:"-"`
+	RefreshToken string `json:"-"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"` // 0 if the token does not expire
+	CreatedAt    int64  `json:"created_at"`
+	UpdatedAt    int64  `json:"updated_at"`
+}
+
+// OAuthState is an OAuth flow started by a client and not finished yet. It is
+// deleted by the callback, so a state is accepted once.
+type OAuthState struct {
+	Nonce     string `gorm:"primaryKey"`
+	UserKey   string
+	Provider  string
+	ExpiresAt int64 `gorm:"index"`
+}
+
+// RoutingOutcome records an auto mode routing decision and how it turned out.
+// Rating and Correct are filled in later (user feedback, benchmarks) and are
+// used to fit the auto mode model weights.
+type RoutingOutcome struct {
+	ID                uint    `gorm:"primaryKey" json:"id"`
+	SessionID         string  `gorm:"index" json:"session_id,omitempty"`
+	MessageID         string  `gorm:"index" json:"message_id,omitempty"`
+	Query             string  `json:"query"`
+	SelectedMode      string  `json:"selected_mode"`
+	DecidedBy         string  `json:"decided_by"`
+	ProProbability    float64 `json:"pro_probability"`
+	HistoryMessages   float64 `json:"history_messages"`
+	QueryWords        float64 `json:"query_words"`
+	ComplexIndicators float64 `json:"complex_indicators"`
+	SimpleIndicators  float64 `json:"simple_indicators"`
+	MultiHop          float64 `json:"multi_hop"`
+	LatencyMs         int64   `json:"latency_ms"`
+	SourcesCount      int     `json:"sources_count"`
+	Rating            *int    `json:"rating,omitempty"`
+	Correct           *bool   `json:"correct,omitempty"`
+	CreatedAt         int64   `json:"created_at"`
 }
 
 // BeforeSave hook to sanitize UTF-8 before saving to database
@@ -47,6 +200,25 @@ func (s *Source) BeforeSave(tx *gorm.DB) error {
 	return nil
 }
 
+// BeforeSave hook for History
+func (h *History) BeforeSave(tx *gorm.DB) error {
+	h.Query = sanitizeUTF8(h.Query)
+	h.AnswerSummary = sanitizeUTF8(h.AnswerSummary)
+	return nil
+}
+
+// BeforeSave hook for Feedback
+func (f *Feedback) BeforeSave(tx *gorm.DB) error {
+	f.Comment = sanitizeUTF8(f.Comment)
+	return nil
+}
+
+// BeforeSave hook for ReasoningStep
+func (r *ReasoningStep) BeforeSave(tx *gorm.DB) error {
+	r.Step = sanitizeUTF8(r.Step)
+	return nil
+}
+
 // BeforeSave hook for Message
 func (m *Message) BeforeSave(tx *gorm.DB) error {
 	m.Content = sanitizeUTF8(m.Content)
@@ -76,9 +248,13 @@ func InitDB(databaseURL string) (*gorm.DB, error) {
 		if dbPath == databaseURL {
 			dbPath = "research_pro.db"
 		}
-		db, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
-		})
+		var pools *sqlitePools
+		pools, err = openSQLitePools(sqliteDSN(dbPath))
+		if err == nil {
+			db, err = gorm.Open(sqlite.New(sqlite.Config{Conn: pools}), &gorm.Config{
+				Logger: logger.Default.LogMode(logger.Info),
+			})
+		}
 	}
 
 	if err != nil {
@@ -88,10 +264,97 @@ func InitDB(databaseURL string) (*gorm.DB, error) {
 	return db, nil
 }
 
+// sqlitePragmas are applied to every SQLite connection: WAL lets readers run
+// alongside the writer, busy_timeout waits for the lock instead of failing with
+// "database is locked", and immediate transactions take the write lock up front
+// so two transactions never deadlock upgrading from read to write.
+const sqlitePragmas = "_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL&_txlock=immediate"
+
+// sqliteDSN appends sqlitePragmas to the path, keeping any options already set
+func sqliteDSN(path string) string {
+	if strings.Contains(path, "?") {
+		return path + "&" + sqlitePragmas
+	}
+	return path + "?" + sqlitePragmas
+}
+
+// sqlitePools is the connection pool of gorm for SQLite: statements that
+// write and transactions go through a pool of a single connection, so writers
+// queue in the process instead of racing for the file lock (SQLite allows one
+// writer at a time anyway), while reads use a pool of their own that WAL lets
+// run alongside the writer
+type sqlitePools struct {
+	read  *sql.DB
+	write *sql.DB
+}
+
+func openSQLitePools(dsn string) (*sqlitePools, error) {
+	read, err := sql.Open(sqlite.DriverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	write, err := sql.Open(sqlite.DriverName, dsn)
+	if err != nil {
+		read.Close()
+		return nil, err
+	}
+	write.SetMaxOpenConns(1)
+	write.SetMaxIdleConns(1)
+	write.SetConnMaxLifetime(0)
+	return &sqlitePools{read: read, write: write}, nil
+}
+
+// pool picks the pool of a statement: SELECTs read, anything else (INSERT ...
+// RETURNING included) writes
+func (p *sqlitePools) pool(query string) *sql.DB {
+	statement := strings.TrimSpace(query)
+	if len(statement) >= 6 && strings.EqualFold(statement[:6], "select") {
+		return p.read
+	}
+	return p.write
+}
+
+func (p *sqlitePools) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.pool(query).PrepareContext(ctx, query)
+}
+
+func (p *sqlitePools) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.write.ExecContext(ctx, query, args...)
+}
+
+func (p *sqlitePools) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.pool(query).QueryContext(ctx, query, args...)
+}
+
+func (p *sqlitePools) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.pool(query).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts transactions on the writer: with _txlock=immediate they hold
+// the write lock from the start
+func (p *sqlitePools) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.write.BeginTx(ctx, opts)
+}
+
+// GetDBConn is what gorm's DB() returns (health checks ping the reader)
+func (p *sqlitePools) GetDBConn() (*sql.DB, error) {
+	return p.read, nil
+}
+
 func AutoMigrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&ChatSession{},
 		&Message{},
 		&Source{},
+		&RoutingOutcome{},
+		&Feedback{},
+		&History{},
+		&Usage{},
+		&ReasoningStep{},
+		&IntegrationToken{},
+		&OAuthState{},
+		&SessionShare{},
+		&Document{},
+		&DocumentChunk{},
 	)
-}
\ No newline at end of file
+}
@@ -0,0 +1,250 @@
+package documents
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/ledongthuc/pdf"
+)
+
+// Chunking of the extracted text: chunks are cut at paragraph or sentence
+// boundaries and overlap so that a fact split by a boundary is not lost
+const (
+	ChunkSize    = 1500 // characters
+	ChunkOverlap = 200
+)
+
+// maxExpansion bounds what a DOCX or PDF may unpack to (word/document.xml,
+// PDF content and font streams) to this many times the upload limit, so a
+// compression bomb is rejected instead of filling memory
+const maxExpansion = 10
+
+var (
+	ErrUnsupported = errors.New("unsupported document type, expected .pdf, .txt, .md or .docx")
+	ErrEmpty       = errors.New("no text found in the document")
+	ErrTooLarge    = errors.New("document text is too large")
+	ErrInvalid     = errors.New("invalid document")
+)
+
+// Extract returns the plain text of a PDF, DOCX or text file; the type is
+// taken from the file extension. maxSize is the upload limit in bytes, a DOCX
+// or PDF that unpacks to much more fails with ErrTooLarge. Files the parsers
+// choke on fail with ErrInvalid.
+func Extract(filename string, data []byte, maxSize int64) (text string, err error) {
+	// The PDF parser panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("⚠️  Failed to parse %s: %v", filename, r)
+			text, err = "", ErrInvalid
+		}
+	}()
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt", ".md":
+		if !utf8.Valid(data) {
+			return "", errors.New("text document must be UTF-8")
+		}
+		text = string(data)
+	case ".pdf":
+		text, err = extractPDF(data, maxSize*maxExpansion)
+	case ".docx":
+		text, err = extractDOCX(data, maxSize*maxExpansion)
+	default:
+		return "", ErrUnsupported
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text = strings.TrimSpace(utils.SanitizeUTF8(text))
+	if text == "" {
+		return "", ErrEmpty
+	}
+	return text, nil
+}
+
+// extractPDF reads the text of every page. Before a page is read, its content
+// streams and new fonts are unpacked through a limit: together they may not
+// exceed limit bytes.
+func extractPDF(data []byte, limit int64) (string, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("read pdf: %w", err)
+	}
+
+	var b strings.Builder
+	fonts := make(map[string]*pdf.Font)
+	remaining := limit
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		size, err := streamSize(page.V.Key("Contents"), remaining+1)
+		if err != nil {
+			return "", fmt.Errorf("read pdf: %w", err)
+		}
+		remaining -= size
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; ok {
+				continue
+			}
+			font := page.Font(name)
+			size, err := streamSize(font.V.Key("ToUnicode"), remaining+1)
+			if err != nil {
+				return "", fmt.Errorf("read pdf: %w", err)
+			}
+			remaining -= size
+			fonts[name] = &font
+		}
+		if remaining < 0 {
+			return "", ErrTooLarge
+		}
+
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return "", fmt.Errorf("read pdf text: %w", err)
+		}
+		b.WriteString(text)
+	}
+	return b.String(), nil
+}
+
+// streamSize returns the unpacked size of a PDF stream or array of streams,
+// reading at most limit bytes
+func streamSize(v pdf.Value, limit int64) (int64, error) {
+	switch v.Kind() {
+	case pdf.Array:
+		var total int64
+		for i := 0; i < v.Len() && total < limit; i++ {
+			size, err := streamSize(v.Index(i), limit-total)
+			if err != nil {
+				return 0, err
+			}
+			total += size
+		}
+		return total, nil
+	case pdf.Stream:
+		stream := v.Reader()
+		defer stream.Close()
+		return io.Copy(io.Discard, io.LimitReader(stream, limit))
+	default:
+		return 0, nil
+	}
+}
+
+// extractDOCX reads the paragraphs of word/document.xml, at most limit bytes
+// of it
+func extractDOCX(data []byte, limit int64) (string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("read docx: %w", err)
+	}
+
+	for _, file := range archive.File {
+		if file.Name != "word/document.xml" {
+			continue
+		}
+		content, err := file.Open()
+		if err != nil {
+			return "", fmt.Errorf("read docx: %w", err)
+		}
+		defer content.Close()
+
+		// Read one byte past the limit to tell a file of exactly limit bytes
+		// from a larger one
+		limited := &io.LimitedReader{R: content, N: limit + 1}
+		var b strings.Builder
+		decoder := xml.NewDecoder(limited)
+		for {
+			token, err := decoder.Token()
+			if limited.N <= 0 {
+				return "", ErrTooLarge
+			}
+			if err == io.EOF {
+				return b.String(), nil
+			}
+			if err != nil {
+				return "", fmt.Errorf("read docx: %w", err)
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "tab":
+					b.WriteString("\t")
+				case "br":
+					b.WriteString("\n")
+				}
+			case xml.EndElement:
+				if t.Name.Local == "p" {
+					b.WriteString("\n\n")
+				}
+			case xml.CharData:
+				b.Write(t)
+			}
+		}
+	}
+	return "", fmt.Errorf("read docx: word/document.xml not found")
+}
+
+// Chunk splits text into pieces of about ChunkSize characters with
+// ChunkOverlap characters repeated between neighbours
+func Chunk(text string) []string {
+	runes := []rune(text)
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := start + ChunkSize
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			end = breakPoint(runes, start, end)
+		}
+
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+
+		next := end - ChunkOverlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+// breakPoint moves the end of a chunk back to the last paragraph, sentence or
+// word boundary in its second half
+func breakPoint(runes []rune, start, end int) int {
+	earliest := start + (end-start)/2
+	for _, boundary := range []string{"\n\n", ". ", "\n", " "} {
+		b := []rune(boundary)
+		for i := end - len(b); i >= earliest; i-- {
+			if string(runes[i:i+len(b)]) == boundary {
+				return i + len(b)
+			}
+		}
+	}
+	return end
+}
+
+// Title is a short label of a chunk for the source list
+func Title(filename string, index, total int) string {
+	if total <= 1 {
+		return filename
+	}
+	return fmt.Sprintf("%s (%d/%d)", filename, index+1, total)
+}
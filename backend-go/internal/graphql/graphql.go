@@ -0,0 +1,390 @@
+// Package graphql is a small GraphQL query executor: it parses queries with
+// nested selections, arguments, aliases and variables and resolves them
+// against a schema of object types. Queries are bounded in depth, fields and
+// resolver calls, since every alias of a resolved field is resolved again.
+// Mutations, fragments, directives and introspection are not supported.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Built-in scalar types
+const (
+	String  = "String"
+	Int     = "Int"
+	Float   = "Float"
+	Boolean = "Boolean"
+)
+
+// Default limits of a schema
+const (
+	DefaultMaxDepth    = 6
+	DefaultMaxFields   = 100
+	DefaultMaxResolves = 20
+)
+
+// ListOf returns the list type of an element type
+func ListOf(elem string) string {
+	return "[" + elem + "]"
+}
+
+type ResolveParams struct {
+	Context   context.Context
+	Source    interface{}
+	Args      map[string]interface{}
+	Selection *Selection
+}
+
+type ResolveFunc func(p ResolveParams) (interface{}, error)
+
+// Field describes a field of an object type. Without Resolve the value is read
+// from the source struct field with the same json name.
+type Field struct {
+	Type    string
+	Resolve ResolveFunc
+}
+
+// Schema is a set of object types with a root query type
+type Schema struct {
+	query string
+	types map[string]map[string]*Field
+
+	// MaxDepth bounds the nesting of selections and MaxFields the fields of a
+	// query, aliases included. MaxResolves bounds the Resolve calls of an
+	// execution (the database queries of a schema); fields past it are null
+	// with an error. Zero is unlimited.
+	MaxDepth    int
+	MaxFields   int
+	MaxResolves int
+}
+
+func NewSchema(queryType string) *Schema {
+	return &Schema{
+		query:       queryType,
+		types:       make(map[string]map[string]*Field),
+		MaxDepth:    DefaultMaxDepth,
+		MaxFields:   DefaultMaxFields,
+		MaxResolves: DefaultMaxResolves,
+	}
+}
+
+func (s *Schema) AddType(name string, fields map[string]*Field) {
+	s.types[name] = fields
+}
+
+// Request is the standard GraphQL-over-HTTP request body
+type Request struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+type Result struct {
+	Data   interface{} `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// Execute runs the query; parse and validation problems are reported as
+// errors with no data, resolver errors null out the field
+func (s *Schema) Execute(ctx context.Context, req Request) *Result {
+	operations, err := Parse(req.Query)
+	if err != nil {
+		return &Result{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	op, err := selectOperation(operations, req.OperationName)
+	if err != nil {
+		return &Result{Errors: []Error{{Message: err.Error()}}}
+	}
+	if op.Type != "query" {
+		return &Result{Errors: []Error{{Message: fmt.Sprintf("%s operations are not supported", op.Type)}}}
+	}
+	if err := s.checkLimits(op.SelectionSet); err != nil {
+		return &Result{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	variables := make(map[string]interface{}, len(op.Defaults)+len(req.Variables))
+	for name, value := range op.Defaults {
+		variables[name] = value
+	}
+	for name, value := range req.Variables {
+		variables[name] = value
+	}
+
+	e := &executor{schema: s, ctx: ctx, variables: variables}
+	data := e.object(s.query, nil, op.SelectionSet, nil)
+	return &Result{Data: data, Errors: e.errors}
+}
+
+func selectOperation(operations []*Operation, name string) (*Operation, error) {
+	if name == "" {
+		if len(operations) > 1 {
+			return nil, fmt.Errorf("operationName is required for documents with several operations")
+		}
+		return operations[0], nil
+	}
+	for _, op := range operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// checkLimits rejects queries nested deeper than MaxDepth or selecting more
+// than MaxFields fields
+func (s *Schema) checkLimits(selections []*Selection) error {
+	fields, depth := measure(selections, 1)
+	if s.MaxDepth > 0 && depth > s.MaxDepth {
+		return fmt.Errorf("query is nested %d levels deep, the limit is %d", depth, s.MaxDepth)
+	}
+	if s.MaxFields > 0 && fields > s.MaxFields {
+		return fmt.Errorf("query selects %d fields, the limit is %d", fields, s.MaxFields)
+	}
+	return nil
+}
+
+// measure counts the fields of the selections and their nested selections
+// and the deepest level they reach
+func measure(selections []*Selection, level int) (fields, depth int) {
+	depth = level
+	for _, sel := range selections {
+		fields++
+		if len(sel.SelectionSet) > 0 {
+			nestedFields, nestedDepth := measure(sel.SelectionSet, level+1)
+			fields += nestedFields
+			depth = max(depth, nestedDepth)
+		}
+	}
+	return fields, depth
+}
+
+type executor struct {
+	schema    *Schema
+	ctx       context.Context
+	variables map[string]interface{}
+	errors    []Error
+	resolves  int
+}
+
+func (e *executor) fail(path []interface{}, format string, args ...interface{}) {
+	e.errors = append(e.errors, Error{
+		Message: fmt.Sprintf(format, args...),
+		Path:    append([]interface{}{}, path...),
+	})
+}
+
+func (e *executor) object(typeName string, source interface{}, selections []*Selection, path []interface{}) *orderedMap {
+	fields := e.schema.types[typeName]
+	result := &orderedMap{}
+
+	for _, sel := range selections {
+		fieldPath := append(path[:len(path):len(path)], sel.Alias)
+
+		if sel.Name == "__typename" {
+			result.set(sel.Alias, typeName)
+			continue
+		}
+
+		field, ok := fields[sel.Name]
+		if !ok {
+			e.fail(fieldPath, "Cannot query field %q on type %q", sel.Name, typeName)
+			continue
+		}
+
+		var value interface{}
+		var err error
+		if field.Resolve != nil {
+			if e.schema.MaxResolves > 0 && e.resolves >= e.schema.MaxResolves {
+				e.fail(fieldPath, "query resolves more than %d fields", e.schema.MaxResolves)
+				result.set(sel.Alias, nil)
+				continue
+			}
+			e.resolves++
+			value, err = field.Resolve(ResolveParams{
+				Context:   e.ctx,
+				Source:    source,
+				Args:      e.arguments(sel.Arguments),
+				Selection: sel,
+			})
+		} else {
+			value = structField(source, sel.Name)
+		}
+		if err != nil {
+			e.fail(fieldPath, "%s", err.Error())
+			result.set(sel.Alias, nil)
+			continue
+		}
+
+		result.set(sel.Alias, e.complete(field.Type, value, sel, fieldPath))
+	}
+
+	return result
+}
+
+// complete shapes a resolved value according to its field type
+func (e *executor) complete(fieldType string, value interface{}, sel *Selection, path []interface{}) interface{} {
+	if isNil(value) {
+		return nil
+	}
+
+	if strings.HasPrefix(fieldType, "[") {
+		elemType := strings.TrimSuffix(strings.TrimPrefix(fieldType, "["), "]")
+		v := reflect.Indirect(reflect.ValueOf(value))
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			e.fail(path, "expected a list for %s", fieldType)
+			return nil
+		}
+		list := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			if item.Kind() == reflect.Struct && item.CanAddr() {
+				item = item.Addr()
+			}
+			list = append(list, e.complete(elemType, item.Interface(), sel, append(path[:len(path):len(path)], i)))
+		}
+		return list
+	}
+
+	if _, isObject := e.schema.types[fieldType]; isObject {
+		if len(sel.SelectionSet) == 0 {
+			e.fail(path, "Field %q of type %q must have a selection of subfields", sel.Name, fieldType)
+			return nil
+		}
+		return e.object(fieldType, value, sel.SelectionSet, path)
+	}
+
+	if len(sel.SelectionSet) > 0 {
+		e.fail(path, "Field %q must not have a selection since type %q has no subfields", sel.Name, fieldType)
+		return nil
+	}
+	return reflect.Indirect(reflect.ValueOf(value)).Interface()
+}
+
+// arguments substitutes variables in argument values
+func (e *executor) arguments(args map[string]interface{}) map[string]interface{} {
+	resolved := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		resolved[name] = e.value(value)
+	}
+	return resolved
+}
+
+func (e *executor) value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case Variable:
+		return e.variables[string(v)]
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = e.value(item)
+		}
+		return list
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			object[name] = e.value(item)
+		}
+		return object
+	default:
+		return v
+	}
+}
+
+// structField reads the field tagged with the json name from a struct
+func structField(source interface{}, name string) interface{} {
+	v := reflect.Indirect(reflect.ValueOf(source))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] == name {
+			return v.Field(i).Interface()
+		}
+	}
+	return nil
+}
+
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
+
+// StringArg returns a string argument or the default
+func StringArg(args map[string]interface{}, name, def string) string {
+	if s, ok := args[name].(string); ok {
+		return s
+	}
+	return def
+}
+
+// IntArg returns an integer argument (literal or JSON variable) or the default
+func IntArg(args map[string]interface{}, name string, def int) int {
+	switch v := args[name].(type) {
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return def
+}
+
+// orderedMap keeps response fields in selection order
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if m.values == nil {
+		m.values = make(map[string]interface{})
+	}
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,288 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	ops, err := Parse(`
+		# comment
+		query Session($id: String!, $limit: Int = 20) {
+			s: session(id: $id) {
+				id
+				messages(limit: $limit, roles: ["user", "assistant"], filter: {agent: "pro", score: 0.5}) { seq }
+			}
+		}`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(ops) != 1 {
+		t.Fatalf("got %d operations, want 1", len(ops))
+	}
+
+	op := ops[0]
+	if op.Type != "query" || op.Name != "Session" {
+		t.Errorf("operation = %s %s, want query Session", op.Type, op.Name)
+	}
+	if op.Defaults["limit"] != int64(20) {
+		t.Errorf("default limit = %#v, want 20", op.Defaults["limit"])
+	}
+	if _, ok := op.Defaults["id"]; ok {
+		t.Errorf("id has no default, got %#v", op.Defaults["id"])
+	}
+
+	session := op.SelectionSet[0]
+	if session.Alias != "s" || session.Name != "session" {
+		t.Errorf("selection = %s: %s, want s: session", session.Alias, session.Name)
+	}
+	if session.Arguments["id"] != Variable("id") {
+		t.Errorf("id argument = %#v, want Variable(id)", session.Arguments["id"])
+	}
+
+	messages := session.Find("messages")
+	if messages == nil || !messages.Selected("seq") || messages.Selected("role") {
+		t.Fatalf("messages selection = %+v", messages)
+	}
+	roles, _ := messages.Arguments["roles"].([]interface{})
+	if len(roles) != 2 || roles[0] != "user" {
+		t.Errorf("roles argument = %#v", messages.Arguments["roles"])
+	}
+	filter, _ := messages.Arguments["filter"].(map[string]interface{})
+	if filter["agent"] != "pro" || filter["score"] != 0.5 {
+		t.Errorf("filter argument = %#v", messages.Arguments["filter"])
+	}
+}
+
+func TestParseShorthandAndSeveralOperations(t *testing.T) {
+	ops, err := Parse(`{ a } query B { b }`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(ops) != 2 || ops[0].Name != "" || ops[1].Name != "B" {
+		t.Fatalf("operations = %+v", ops)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"empty document", `  `, "no operations"},
+		{"fragment spread", `{ session { ...F } }`, "fragments are not supported"},
+		{"fragment definition", `fragment F on Message { id }`, "fragments are not supported"},
+		{"directive", `{ session @skip(if: true) { id } }`, "directives are not supported"},
+		{"unterminated string", `{ session(id: "abc) { id } }`, "unterminated string"},
+		{"empty selection", `{ session { } }`, "empty selection set"},
+		{"unexpected end", `{ session { id }`, "unexpected end"},
+		{"bad character", `{ session; }`, "unexpected character"},
+		{"too deeply nested", strings.Repeat("{ a ", maxNesting+1) + strings.Repeat("}", maxNesting+1), "nested more than"},
+		{"too deeply nested value", `{ a(x: ` + strings.Repeat("[", maxNesting+1) + strings.Repeat("]", maxNesting+1) + `) }`, "nested more than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+type testItem struct {
+	ID    string   `json:"id"`
+	Tags  []string `json:"tags"`
+	Score float64  `json:"score,omitempty"`
+}
+
+// testSchema serves item(id) and items(limit) with a resolved children list;
+// resolves counts the Resolve calls
+func testSchema(resolves *int) *Schema {
+	schema := NewSchema("Query")
+	schema.AddType("Query", map[string]*Field{
+		"item": {
+			Type: "Item",
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				*resolves++
+				id := StringArg(p.Args, "id", "")
+				if id == "missing" {
+					return nil, nil
+				}
+				if id == "broken" {
+					return nil, errors.New("item is broken")
+				}
+				return &testItem{ID: id, Tags: []string{"a"}}, nil
+			},
+		},
+		"items": {
+			Type: ListOf("Item"),
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				*resolves++
+				items := make([]testItem, IntArg(p.Args, "limit", 2))
+				for i := range items {
+					items[i].ID = strings.Repeat("i", i+1)
+				}
+				return items, nil
+			},
+		},
+	})
+	schema.AddType("Item", map[string]*Field{
+		"id":    {Type: String},
+		"tags":  {Type: ListOf(String)},
+		"score": {Type: Float},
+		"children": {
+			Type: ListOf("Item"),
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				*resolves++
+				return []*testItem{{ID: p.Source.(*testItem).ID + ".1"}}, nil
+			},
+		},
+	})
+	return schema
+}
+
+func execute(t *testing.T, schema *Schema, req Request) (string, []Error) {
+	t.Helper()
+	result := schema.Execute(context.Background(), req)
+	if result.Data == nil {
+		return "", result.Errors
+	}
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return string(data), result.Errors
+}
+
+func TestExecute(t *testing.T) {
+	var resolves int
+	schema := testSchema(&resolves)
+
+	data, errs := execute(t, schema, Request{
+		Query:     `query($id: String) { first: item(id: $id) { __typename tags id children { id } } item(id: "missing") { id } }`,
+		Variables: map[string]interface{}{"id": "x"},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("errors: %+v", errs)
+	}
+	want := `{"first":{"__typename":"Item","tags":["a"],"id":"x","children":[{"id":"x.1"}]},"item":null}`
+	if data != want {
+		t.Errorf("data = %s, want %s", data, want)
+	}
+	if resolves != 3 {
+		t.Errorf("resolves = %d, want 3", resolves)
+	}
+}
+
+func TestExecuteListOfStructs(t *testing.T) {
+	var resolves int
+	data, errs := execute(t, testSchema(&resolves), Request{Query: `{ items(limit: 3) { id } }`})
+	if len(errs) > 0 {
+		t.Fatalf("errors: %+v", errs)
+	}
+	if want := `{"items":[{"id":"i"},{"id":"ii"},{"id":"iii"}]}`; data != want {
+		t.Errorf("data = %s, want %s", data, want)
+	}
+}
+
+func TestExecuteFieldErrors(t *testing.T) {
+	var resolves int
+	data, errs := execute(t, testSchema(&resolves), Request{
+		Query: `{ item(id: "broken") { id } other: item(id: "x") { nope id { deeper } children } }`,
+	})
+
+	// Unknown fields are left out, fields of the wrong shape are null
+	if want := `{"item":null,"other":{"id":null,"children":[null]}}`; data != want {
+		t.Errorf("data = %s, want %s", data, want)
+	}
+	wantErrors := []string{
+		"item is broken",
+		`Cannot query field "nope"`,
+		`Field "id" must not have a selection`,
+		`Field "children" of type "Item" must have a selection`,
+	}
+	if len(errs) != len(wantErrors) {
+		t.Fatalf("errors = %+v, want %d", errs, len(wantErrors))
+	}
+	for i, want := range wantErrors {
+		if !strings.Contains(errs[i].Message, want) {
+			t.Errorf("error %d = %q, want %q", i, errs[i].Message, want)
+		}
+	}
+	if path, _ := json.Marshal(errs[1].Path); string(path) != `["other","nope"]` {
+		t.Errorf("error path = %s", path)
+	}
+}
+
+func TestExecuteOperations(t *testing.T) {
+	var resolves int
+	schema := testSchema(&resolves)
+
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{"named operation", Request{Query: `query A { item(id: "a") { id } } query B { item(id: "b") { id } }`, OperationName: "B"}, ""},
+		{"several without name", Request{Query: `query A { item { id } } query B { item { id } }`}, "operationName is required"},
+		{"unknown name", Request{Query: `query A { item { id } }`, OperationName: "C"}, `unknown operation "C"`},
+		{"mutation", Request{Query: `mutation { item { id } }`}, "mutation operations are not supported"},
+		{"parse error", Request{Query: `{ item {`}, "unexpected end"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, errs := execute(t, schema, tt.req)
+			if tt.want == "" {
+				if len(errs) > 0 || data != `{"item":{"id":"b"}}` {
+					t.Errorf("data = %s, errors = %+v", data, errs)
+				}
+				return
+			}
+			if data != "" || len(errs) != 1 || !strings.Contains(errs[0].Message, tt.want) {
+				t.Errorf("data = %s, errors = %+v, want %q", data, errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteLimits(t *testing.T) {
+	var resolves int
+	schema := testSchema(&resolves)
+	schema.MaxDepth = 3
+	schema.MaxFields = 6
+	schema.MaxResolves = 3
+
+	// Depth 3 and 6 fields are within the limits
+	if _, errs := execute(t, schema, Request{Query: `{ item(id: "x") { id children { id } } a: item { id } }`}); len(errs) > 0 {
+		t.Errorf("errors within limits: %+v", errs)
+	}
+
+	_, errs := execute(t, schema, Request{Query: `{ item { children { children { id } } } }`})
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "nested 4 levels deep") {
+		t.Errorf("depth errors = %+v", errs)
+	}
+
+	_, errs = execute(t, schema, Request{Query: `{ a: item { id } b: item { id } c: item { id } d: item { id } }`})
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "selects 8 fields") {
+		t.Errorf("field errors = %+v", errs)
+	}
+
+	// Aliases of a resolved field past MaxResolves are not resolved
+	schema.MaxFields = 0
+	resolves = 0
+	data, errs := execute(t, schema, Request{Query: `{ a: item(id: "a") { id } b: item(id: "b") { id } c: item(id: "c") { id } d: item(id: "d") { id } }`})
+	if resolves != 3 {
+		t.Errorf("resolves = %d, want 3", resolves)
+	}
+	if want := `{"a":{"id":"a"},"b":{"id":"b"},"c":{"id":"c"},"d":null}`; data != want {
+		t.Errorf("data = %s, want %s", data, want)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "more than 3 fields") {
+		t.Errorf("resolve errors = %+v", errs)
+	}
+}
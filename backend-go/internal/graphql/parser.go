@@ -0,0 +1,439 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operation is a parsed query operation
+type Operation struct {
+	Name         string
+	Type         string // only "query" is executed
+	Defaults     map[string]interface{}
+	SelectionSet []*Selection
+}
+
+// Selection is a field in a selection set
+type Selection struct {
+	Alias        string
+	Name         string
+	Arguments    map[string]interface{} // literals, or Variable for $name
+	SelectionSet []*Selection
+}
+
+// Variable is a reference to an operation variable in an argument
+type Variable string
+
+// Selected reports whether the field name is part of the selection set
+func (s *Selection) Selected(name string) bool {
+	for _, child := range s.SelectionSet {
+		if child.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the first selection of the field name, or nil
+func (s *Selection) Find(name string) *Selection {
+	for _, child := range s.SelectionSet {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(source) {
+		ch := source[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',':
+			i++
+		case ch == '#':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case strings.ContainsRune("{}()[]:$!=@", rune(ch)):
+			tokens = append(tokens, token{kind: tokenPunct, value: string(ch), pos: i})
+			i++
+		case ch == '.':
+			if !strings.HasPrefix(source[i:], "...") {
+				return nil, fmt.Errorf("unexpected character %q at %d", ch, i)
+			}
+			tokens = append(tokens, token{kind: tokenPunct, value: "...", pos: i})
+			i += 3
+		case ch == '"':
+			start := i
+			i++
+			for i < len(source) && source[i] != '"' {
+				if source[i] == '\\' {
+					i++
+				}
+				if i < len(source) && source[i] == '\n' {
+					return nil, fmt.Errorf("unterminated string at %d", start)
+				}
+				i++
+			}
+			if i >= len(source) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			i++
+			value, err := strconv.Unquote(source[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %w", start, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value, pos: start})
+		case ch == '-' || (ch >= '0' && ch <= '9'):
+			start := i
+			kind := tokenInt
+			i++
+			for i < len(source) {
+				c := source[i]
+				if c >= '0' && c <= '9' {
+					i++
+				} else if c == '.' || c == 'e' || c == 'E' || ((c == '+' || c == '-') && (source[i-1] == 'e' || source[i-1] == 'E')) {
+					kind = tokenFloat
+					i++
+				} else {
+					break
+				}
+			}
+			tokens = append(tokens, token{kind: kind, value: source[start:i], pos: start})
+		case ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z'):
+			start := i
+			for i < len(source) && (source[i] == '_' || (source[i] >= 'a' && source[i] <= 'z') ||
+				(source[i] >= 'A' && source[i] <= 'Z') || (source[i] >= '0' && source[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenName, value: source[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", ch, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+// maxNesting bounds the nesting of selection sets, lists and objects the
+// parser recurses into, ahead of Schema.MaxDepth
+const maxNesting = 64
+
+type parser struct {
+	tokens  []token
+	pos     int
+	nesting int
+}
+
+// Parse parses a GraphQL document into its operations. Fragments and
+// directives are not supported.
+func Parse(source string) ([]*Operation, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	var operations []*Operation
+	for p.peek().kind != tokenEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, op)
+	}
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("document contains no operations")
+	}
+	return operations, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) is(value string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.value == value
+}
+
+func (p *parser) expect(value string) error {
+	t := p.next()
+	if t.kind != tokenPunct || t.value != value {
+		return p.unexpected(t, fmt.Sprintf("%q", value))
+	}
+	return nil
+}
+
+func (p *parser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != tokenName {
+		return "", p.unexpected(t, "name")
+	}
+	return t.value, nil
+}
+
+func (p *parser) unexpected(t token, expected string) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of document, expected %s", expected)
+	}
+	return fmt.Errorf("unexpected %q at %d, expected %s", t.value, t.pos, expected)
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := &Operation{Type: "query", Defaults: map[string]interface{}{}}
+
+	if p.peek().kind == tokenName {
+		op.Type = p.next().value
+		if op.Type == "fragment" {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+		if p.peek().kind == tokenName {
+			op.Name = p.next().value
+		}
+		if p.is("(") {
+			if err := p.parseVariableDefinitions(op); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.SelectionSet = selections
+	return op, nil
+}
+
+func (p *parser) parseVariableDefinitions(op *Operation) error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	for !p.is(")") {
+		if err := p.expect("$"); err != nil {
+			return err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if p.is("=") {
+			p.next()
+			value, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+			op.Defaults[name] = value
+		}
+	}
+	return p.expect(")")
+}
+
+func (p *parser) skipType() error {
+	if p.is("[") {
+		p.next()
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+	if p.is("!") {
+		p.next()
+	}
+	return nil
+}
+
+// nest enters a nested selection set, list or object; the returned func
+// leaves it
+func (p *parser) nest() (func(), error) {
+	if p.nesting >= maxNesting {
+		return nil, fmt.Errorf("document is nested more than %d levels deep", maxNesting)
+	}
+	p.nesting++
+	return func() { p.nesting-- }, nil
+}
+
+func (p *parser) parseSelectionSet() ([]*Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	leave, err := p.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	var selections []*Selection
+	for !p.is("}") {
+		if p.is("...") {
+			return nil, fmt.Errorf("fragments are not supported")
+		}
+		if p.is("@") {
+			return nil, fmt.Errorf("directives are not supported")
+		}
+		selection, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	p.next()
+
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+	return selections, nil
+}
+
+func (p *parser) parseSelection() (*Selection, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+
+	selection := &Selection{Name: name, Alias: name}
+	if p.is(":") {
+		p.next()
+		if selection.Name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.is("(") {
+		p.next()
+		selection.Arguments = map[string]interface{}{}
+		for !p.is(")") {
+			argName, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			selection.Arguments[argName] = value
+		}
+		p.next()
+	}
+
+	if p.is("@") {
+		return nil, fmt.Errorf("directives are not supported")
+	}
+
+	if p.is("{") {
+		if selection.SelectionSet, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return selection, nil
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenInt:
+		return strconv.ParseInt(t.value, 10, 64)
+	case tokenFloat:
+		return strconv.ParseFloat(t.value, 64)
+	case tokenString:
+		return t.value, nil
+	case tokenName:
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		default:
+			return t.value, nil // enum value
+		}
+	case tokenPunct:
+		switch t.value {
+		case "$":
+			name, err := p.expectName()
+			return Variable(name), err
+		case "[":
+			leave, err := p.nest()
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+			list := []interface{}{}
+			for !p.is("]") {
+				value, err := p.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			p.next()
+			return list, nil
+		case "{":
+			leave, err := p.nest()
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+			object := map[string]interface{}{}
+			for !p.is("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				value, err := p.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				object[name] = value
+			}
+			p.next()
+			return object, nil
+		}
+	}
+	return nil, p.unexpected(t, "value")
+}
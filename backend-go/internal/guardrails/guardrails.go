@@ -0,0 +1,215 @@
+package guardrails
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// defaultPolicy is the built-in policy, used when GUARDRAILS_POLICY_PATH is
+// not set
+//
+//go:embed policy.json
+var defaultPolicy []byte
+
+// Category actions
+const (
+	ActionBlock    = "block"    // refuse the query
+	ActionSanitize = "sanitize" // remove the matching text and answer the rest
+)
+
+// ErrBlocked means the query matched a blocking category of the policy. The
+// returned error is a *BlockedError carrying the category and refusal.
+var ErrBlocked = errors.New("query blocked")
+
+// BlockedError is the structured refusal of a blocked query
+type BlockedError struct {
+	Category string
+	Message  string // in the language of the query
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrBlocked, e.Category)
+}
+
+func (e *BlockedError) Unwrap() error {
+	return ErrBlocked
+}
+
+// Category is a kind of unsafe query, recognized by case-insensitive regular
+// expressions
+type Category struct {
+	Name     string            `json:"name"`
+	Action   string            `json:"action"`
+	Patterns []string          `json:"patterns"`
+	Message  map[string]string `json:"message,omitempty"` // refusal by language (ru, en)
+
+	patterns []*regexp.Regexp
+}
+
+type policy struct {
+	Categories []*Category `json:"categories"`
+}
+
+// Personal data patterns. Card numbers are confirmed with the Luhn checksum;
+// phones need a country or trunk prefix so years and amounts are left alone.
+var (
+	emailPattern = regexp.MustCompile(`[\pL\d._%+-]+@[\pL\d-]+(?:\.[\pL\d-]+)+`)
+	cardPattern  = regexp.MustCompile(`\d(?:[ -]?\d){12,18}`)
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}|\b[78])[\s(-]*\d{3}[\s)-]*\d{3}[\s-]*\d{2}[\s-]*\d{2}`)
+)
+
+// Guard screens queries before they leave the service: categories of the
+// policy block or sanitize unsafe queries, and personal data (emails, phones,
+// card numbers) is replaced with placeholders
+type Guard struct {
+	categories []*Category // nil when the policy is disabled
+	redactPII  bool
+}
+
+// Result is a screened query
+type Result struct {
+	Query     string
+	Redacted  []string // kinds of personal data replaced: email, phone, card
+	Sanitized []string // categories whose text was removed
+}
+
+// Load reads the policy from path, or the built-in one when path is empty.
+// enforcePolicy false keeps only the redaction of personal data.
+func Load(path string, enforcePolicy, redactPII bool) (*Guard, error) {
+	guard := &Guard{redactPII: redactPII}
+	if !enforcePolicy {
+		return guard, nil
+	}
+
+	data := defaultPolicy
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("read guardrails policy: %w", err)
+		}
+	}
+	var p policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse guardrails policy: %w", err)
+	}
+	for _, category := range p.Categories {
+		if err := category.compile(); err != nil {
+			return nil, fmt.Errorf("guardrails category %q: %w", category.Name, err)
+		}
+	}
+	guard.categories = p.Categories
+	return guard, nil
+}
+
+func (c *Category) compile() error {
+	if c.Name == "" || len(c.Patterns) == 0 {
+		return fmt.Errorf("name and patterns are required")
+	}
+	if c.Action != ActionBlock && c.Action != ActionSanitize {
+		return fmt.Errorf("unknown action %q, expected block or sanitize", c.Action)
+	}
+	for _, pattern := range c.Patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+		c.patterns = append(c.patterns, re)
+	}
+	return nil
+}
+
+// Check screens a query in lang (ru or en). A query of a blocking category
+// returns a *BlockedError; one that is empty once sanitized is blocked too.
+func (g *Guard) Check(query, lang string) (Result, error) {
+	result := Result{Query: query}
+	for _, category := range g.categories {
+		for _, re := range category.patterns {
+			if !re.MatchString(result.Query) {
+				continue
+			}
+			if category.Action == ActionBlock {
+				return Result{}, category.blocked(lang)
+			}
+			result.Query = strings.Join(strings.Fields(re.ReplaceAllString(result.Query, " ")), " ")
+			if !slices.Contains(result.Sanitized, category.Name) {
+				result.Sanitized = append(result.Sanitized, category.Name)
+			}
+			if result.Query == "" {
+				return Result{}, category.blocked(lang)
+			}
+		}
+	}
+
+	result.Query, result.Redacted = g.Redact(result.Query)
+	return result, nil
+}
+
+func (c *Category) blocked(lang string) *BlockedError {
+	message := c.Message[lang]
+	if message == "" {
+		message = c.Message["en"]
+	}
+	if message == "" {
+		message = "This request can't be processed."
+	}
+	return &BlockedError{Category: c.Name, Message: message}
+}
+
+// Redact replaces the personal data of text with [email], [card] and [phone]
+// and returns the kinds it found
+func (g *Guard) Redact(text string) (string, []string) {
+	if !g.redactPII {
+		return text, nil
+	}
+
+	var kinds []string
+	found := func(kind string) {
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	text = emailPattern.ReplaceAllStringFunc(text, func(string) string {
+		found("email")
+		return "[email]"
+	})
+	text = cardPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if !luhnValid(match) {
+			return match
+		}
+		found("card")
+		return "[card]"
+	})
+	text = phonePattern.ReplaceAllStringFunc(text, func(string) string {
+		found("phone")
+		return "[phone]"
+	})
+	return text, kinds
+}
+
+// luhnValid checks the checksum of a card number, ignoring separators
+func luhnValid(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,230 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/webhook"
+	"github.com/go-resty/resty/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Report channels
+const (
+	ChannelWebhook  = "webhook"  // signed POST of the SearchResponse
+	ChannelTelegram = "telegram" // message to a Telegram chat
+)
+
+// maxSignatureAge rejects replayed requests
+const maxSignatureAge = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid hook signature")
+	ErrStaleSignature   = errors.New("hook signature timestamp is too old")
+	ErrReplayed         = errors.New("hook event was already accepted")
+)
+
+// Hook turns events of an external system (monitoring alert, CRM event) into
+// a research query. Hooks are configured in the INBOUND_HOOKS_PATH file.
+type Hook struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret"` // HMAC secret the caller signs requests with
+
+	// Template is a Go text/template over the event JSON, e.g.
+	// "What causes {{.error}} in {{.service}}?"
+	Template string `json:"template"`
+	Mode     string `json:"mode"` // auto by default
+
+	// Where the report goes: a webhook URL (signed with Secret) or a Telegram chat
+	Channel string `json:"channel"`
+	URL     string `json:"url,omitempty"`
+	ChatID  int64  `json:"chat_id,omitempty"`
+
+	tmpl *template.Template
+}
+
+// Load reads and validates the hooks file, a JSON array of hooks
+func Load(path string) (map[string]*Hook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read hooks file: %w", err)
+	}
+
+	var list []*Hook
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse hooks file: %w", err)
+	}
+
+	hooks := make(map[string]*Hook, len(list))
+	for _, hook := range list {
+		if err := hook.validate(); err != nil {
+			return nil, fmt.Errorf("hook %q: %w", hook.Name, err)
+		}
+		if _, exists := hooks[hook.Name]; exists {
+			return nil, fmt.Errorf("hook %q is defined twice", hook.Name)
+		}
+		hooks[hook.Name] = hook
+	}
+	return hooks, nil
+}
+
+func (h *Hook) validate() error {
+	if h.Name == "" || h.Secret == "" || h.Template == "" {
+		return fmt.Errorf("name, secret and template are required")
+	}
+	if h.Mode == "" {
+		h.Mode = "auto"
+	}
+
+	switch h.Channel {
+	case ChannelWebhook:
+		if err := webhook.ValidateURL(h.URL); err != nil {
+			return err
+		}
+	case ChannelTelegram:
+		if h.ChatID == 0 {
+			return fmt.Errorf("chat_id is required for the telegram channel")
+		}
+	default:
+		return fmt.Errorf("unknown channel %q, expected webhook or telegram", h.Channel)
+	}
+
+	tmpl, err := template.New(h.Name).Option("missingkey=error").Parse(h.Template)
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+	h.tmpl = tmpl
+	return nil
+}
+
+// Query fills the template with the event
+func (h *Hook) Query(event map[string]interface{}) (string, error) {
+	var b bytes.Buffer
+	if err := h.tmpl.Execute(&b, event); err != nil {
+		return "", err
+	}
+	query := strings.TrimSpace(b.String())
+	if query == "" {
+		return "", fmt.Errorf("template produced an empty query")
+	}
+	return query, nil
+}
+
+// Verify checks the signature of an event: the same scheme as outgoing search
+// callbacks, hex HMAC-SHA256 of "timestamp.body" with the hook secret
+func (h *Hook) Verify(timestamp, signature string, body []byte) error {
+	expected := "sha256=" + webhook.Sign(h.Secret, timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(sent, 0)); age > maxSignatureAge || age < -maxSignatureAge {
+		return ErrStaleSignature
+	}
+	return nil
+}
+
+// Replays remembers the accepted events of hooks while their signature is
+// valid, so a captured request can't start the research again. Events are
+// kept in Redis when it is available (shared by replicas), otherwise in
+// process memory.
+type Replays struct {
+	redis *redis.Client
+
+	mu   sync.Mutex
+	seen map[string]time.Time // key -> expiry
+}
+
+func NewReplays(redisClient *redis.Client) *Replays {
+	return &Replays{redis: redisClient, seen: make(map[string]time.Time)}
+}
+
+// Accept records the event of hook signed at timestamp and returns
+// ErrReplayed if it was accepted before
+func (r *Replays) Accept(ctx context.Context, hook, timestamp, signature string) error {
+	// Events are valid maxSignatureAge either side of their timestamp
+	ttl := 2 * maxSignatureAge
+	key := "hook-event:" + hook + ":" + timestamp + ":" + signature
+
+	if r.redis != nil {
+		ok, err := r.redis.SetNX(ctx, key, 1, ttl).Result()
+		if err == nil {
+			if !ok {
+				return ErrReplayed
+			}
+			return nil
+		}
+		log.Printf("⚠️  Failed to record hook event in Redis, using memory: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for k, expiry := range r.seen {
+		if now.After(expiry) {
+			delete(r.seen, k)
+		}
+	}
+	if _, ok := r.seen[key]; ok {
+		return ErrReplayed
+	}
+	r.seen[key] = now.Add(ttl)
+	return nil
+}
+
+// Telegram sends reports to chats through the Bot API
+type Telegram struct {
+	token  string
+	client *resty.Client
+}
+
+func NewTelegram(token string) *Telegram {
+	client := resty.New()
+	client.SetTimeout(15 * time.Second)
+	return &Telegram{token: token, client: client}
+}
+
+// Enabled reports whether a bot token is configured
+func (t *Telegram) Enabled() bool {
+	return t.token != ""
+}
+
+// Send posts text to the chat; parseMode is "MarkdownV2" or "" for plain text
+func (t *Telegram) Send(ctx context.Context, chatID int64, text, parseMode string) error {
+	body := map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"disable_web_page_preview": true,
+	}
+	if parseMode != "" {
+		body["parse_mode"] = parseMode
+	}
+
+	resp, err := t.client.R().
+		SetContext(ctx).
+		SetBody(body).
+		Post("https://api.telegram.org/bot" + t.token + "/sendMessage")
+	if err != nil {
+		// Transport errors carry the URL, and with it the bot token
+		return fmt.Errorf("telegram request failed: %s", strings.ReplaceAll(err.Error(), t.token, "<token>"))
+	}
+	if resp.IsError() {
+		return fmt.Errorf("telegram returned status %d: %s", resp.StatusCode(), resp.String())
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package integrations
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	googleDocsAPI  = "https://docs.googleapis.com/v1/documents"
+	googleDriveAPI = "https://www.googleapis.com/drive/v3/files"
+	// drive.file only grants access to the documents created by the app
+	googleScope = "https://www.googleapis.com/auth/drive.file"
+)
+
+type googleProvider struct {
+	clientID     string
+	clientSecret string
+	client       *resty.Client
+}
+
+func newGoogleProvider(clientID, clientSecret string) *googleProvider {
+	return &googleProvider{clientID: clientID, clientSecret: clientSecret, client: newClient()}
+}
+
+func (p *googleProvider) AuthURL(redirectURL, state string) string {
+	params := url.Values{
+		"client_id":     {p.clientID},
+		"redirect_uri":  {redirectURL},
+		"response_type": {"code"},
+		"scope":         {googleScope},
+		"access_type":   {"offline"}, // issue a refresh token
+		"prompt":        {"consent"},
+		"state":         {state},
+	}
+	return googleAuthURL + "?" + params.Encode()
+}
+
+func (p *googleProvider) Exchange(ctx context.Context, code, redirectURL string) (*Token, error) {
+	return p.token(ctx, map[string]string{
+		"grant_type":   "authorization_code",
+		"code":         code,
+		"redirect_uri": redirectURL,
+	})
+}
+
+func (p *googleProvider) Refresh(ctx context.Context, token *Token) (*Token, error) {
+	refreshed, err := p.token(ctx, map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": token.RefreshToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Google only returns a refresh token on the first exchange
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	return refreshed, nil
+}
+
+func (p *googleProvider) token(ctx context.Context, form map[string]string) (*Token, error) {
+	form["client_id"] = p.clientID
+	form["client_secret"] = p.clientSecret
+
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	resp, err := p.client.R().
+		SetContext(ctx).
+		SetFormData(form).
+		SetResult(&result).
+		Post(googleTokenURL)
+	// A revoked refresh token is reported as 400 invalid_grant
+	if err == nil && resp.StatusCode() == 400 && strings.Contains(resp.String(), "invalid_grant") {
+		return nil, ErrUnauthorized
+	}
+	if err := checkResponse(GoogleDocs, resp, err); err != nil {
+		return nil, err
+	}
+
+	token := &Token{AccessToken: result.AccessToken, RefreshToken: result.RefreshToken}
+	if result.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Unix() + result.ExpiresIn
+	}
+	return token, nil
+}
+
+// Export creates a Google Doc in the parentID folder (Drive root if empty) and
+// fills it with the document text
+func (p *googleProvider) Export(ctx context.Context, accessToken string, doc Document, parentID string) (*Export, error) {
+	file := map[string]interface{}{
+		"name":     doc.Title,
+		"mimeType": "application/vnd.google-apps.document",
+	}
+	if parentID != "" {
+		file["parents"] = []string{parentID}
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	resp, err := p.client.R().
+		SetContext(ctx).
+		SetAuthToken(accessToken).
+		SetBody(file).
+		SetResult(&created).
+		Post(googleDriveAPI)
+	if err := checkResponse(GoogleDocs, resp, err); err != nil {
+		return nil, err
+	}
+
+	if requests := googleRequests(doc); len(requests) > 0 {
+		resp, err = p.client.R().
+			SetContext(ctx).
+			SetAuthToken(accessToken).
+			SetBody(map[string]interface{}{"requests": requests}).
+			Post(googleDocsAPI + "/" + created.ID + ":batchUpdate")
+		if err := checkResponse(GoogleDocs, resp, err); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Export{
+		ID:  created.ID,
+		URL: "https://docs.google.com/document/d/" + created.ID + "/edit",
+	}, nil
+}
+
+// googleRequests inserts the whole text at once, then styles question headings
+// and links the sources. Docs indexes are UTF-16 code units starting at 1.
+func googleRequests(doc Document) []map[string]interface{} {
+	var text strings.Builder
+	var styles []map[string]interface{}
+	index := 1
+
+	write := func(s string) (start, end int) {
+		text.WriteString(s)
+		start = index
+		index += len(utf16.Encode([]rune(s)))
+		return start, index
+	}
+	styleRange := func(start, end int) map[string]int {
+		return map[string]int{"startIndex": start, "endIndex": end}
+	}
+
+	for _, section := range doc.Sections {
+		start, end := write(section.Heading + "\n")
+		styles = append(styles, map[string]interface{}{
+			"updateParagraphStyle": map[string]interface{}{
+				"range":          styleRange(start, end),
+				"paragraphStyle": map[string]string{"namedStyleType": "HEADING_2"},
+				"fields":         "namedStyleType",
+			},
+		})
+
+		if body := strings.TrimSpace(section.Body); body != "" {
+			write(body + "\n")
+		}
+
+		for _, source := range section.Sources {
+			title := source.Title
+			if title == "" {
+				title = source.URL
+			}
+			write("• ")
+			start, end := write(title)
+			write("\n")
+			if strings.HasPrefix(source.URL, "http") {
+				styles = append(styles, map[string]interface{}{
+					"updateTextStyle": map[string]interface{}{
+						"range":     styleRange(start, end),
+						"textStyle": map[string]interface{}{"link": map[string]string{"url": source.URL}},
+						"fields":    "link",
+					},
+				})
+			}
+		}
+		write("\n")
+	}
+
+	if text.Len() == 0 {
+		return nil
+	}
+
+	requests := []map[string]interface{}{{
+		"insertText": map[string]interface{}{
+			"location": map[string]int{"index": 1},
+			"text":     text.String(),
+		},
+	}}
+	return append(requests, styles...)
+}
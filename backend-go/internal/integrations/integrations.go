@@ -0,0 +1,156 @@
+package integrations
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/go-resty/resty/v2"
+)
+
+// Provider names
+const (
+	Notion     = "notion"
+	GoogleDocs = "google_docs"
+)
+
+// StateTTL is how long a user has to finish the OAuth consent screen
+const StateTTL = 15 * time.Minute
+
+var (
+	// ErrUnauthorized means the provider rejected the stored token (revoked
+	// or expired); the user has to connect again
+	ErrUnauthorized = errors.New("integration token rejected")
+	// ErrNoParent means there is nowhere to create the document, e.g. no
+	// Notion page was shared with the integration
+	ErrNoParent = errors.New("no parent page for the document")
+	// ErrInvalidState means the OAuth callback does not belong to a flow we started
+	ErrInvalidState = errors.New("invalid or expired oauth state")
+)
+
+// Document is a chat session prepared for export
+type Document struct {
+	Title    string
+	Sections []Section
+}
+
+// Section is one question with its answer and sources
+type Section struct {
+	Heading string
+	Body    string
+	Sources []Link
+}
+
+type Link struct {
+	Title string
+	URL   string
+}
+
+// Token is the OAuth token of a connected account
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    int64 // unix seconds, 0 if the token does not expire
+}
+
+// Expired reports whether the token should be refreshed before use
+func (t *Token) Expired() bool {
+	return t.ExpiresAt > 0 && time.Now().Add(time.Minute).Unix() >= t.ExpiresAt
+}
+
+// Export is a document created by a provider
+type Export struct {
+	ID  string
+	URL string
+}
+
+// Provider pushes documents to an external service on behalf of a user
+type Provider interface {
+	// AuthURL is the consent screen the user is sent to
+	AuthURL(redirectURL, state string) string
+	// Exchange trades the authorization code from the callback for a token
+	Exchange(ctx context.Context, code, redirectURL string) (*Token, error)
+	// Refresh renews an expired token
+	Refresh(ctx context.Context, token *Token) (*Token, error)
+	// Export creates the document under parentID (provider default if empty)
+	Export(ctx context.Context, accessToken string, doc Document, parentID string) (*Export, error)
+}
+
+// NewProviders returns the providers whose OAuth clients are configured
+func NewProviders(cfg *config.Config) map[string]Provider {
+	providers := make(map[string]Provider)
+	if cfg.NotionClientID != "" && cfg.NotionClientSecret != "" {
+		providers[Notion] = newNotionProvider(cfg.NotionClientID, cfg.NotionClientSecret)
+	}
+	if cfg.GoogleClientID != "" && cfg.GoogleClientSecret != "" {
+		providers[GoogleDocs] = newGoogleProvider(cfg.GoogleClientID, cfg.GoogleClientSecret)
+	}
+	return providers
+}
+
+func newClient() *resty.Client {
+	client := resty.New()
+	client.SetTimeout(30 * time.Second)
+	return client
+}
+
+// checkResponse turns transport errors and non-2xx responses into errors
+func checkResponse(provider string, resp *resty.Response, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	if resp.StatusCode() == 401 {
+		return fmt.Errorf("%w by %s", ErrUnauthorized, provider)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("%s returned status %d: %s",
+			provider, resp.StatusCode(), utils.TruncateRunesWithEllipsis(resp.String(), 200))
+	}
+	return nil
+}
+
+// SignState makes the OAuth state of the flow identified by nonce. The
+// client that started the flow is stored server side under the nonce, and
+// the browser that finishes it must hold the nonce in a cookie.
+func SignState(secret, provider, nonce string) string {
+	expires := strconv.FormatInt(time.Now().Add(StateTTL).Unix(), 10)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(nonce))
+	return encoded + "." + expires + "." + stateSignature(secret, provider, encoded, expires)
+}
+
+// VerifyState returns the nonce of a state made by SignState
+func VerifyState(secret, provider, state string) (string, error) {
+	parts := strings.Split(state, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidState
+	}
+	encoded, expires, signature := parts[0], parts[1], parts[2]
+
+	if !hmac.Equal([]byte(signature), []byte(stateSignature(secret, provider, encoded, expires))) {
+		return "", ErrInvalidState
+	}
+	if exp, err := strconv.ParseInt(expires, 10, 64); err != nil || time.Now().Unix() > exp {
+		return "", ErrInvalidState
+	}
+
+	nonce, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidState
+	}
+	return string(nonce), nil
+}
+
+func stateSignature(secret, provider, nonce, expires string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(provider + "." + nonce + "." + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,188 @@
+package integrations
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	notionAPI     = "https://api.notion.com/v1"
+	notionVersion = "2022-06-28"
+	// Notion limits a rich text object to 2000 characters and a request to
+	// 100 child blocks
+	notionTextLimit  = 2000
+	notionBlockBatch = 100
+)
+
+type notionProvider struct {
+	clientID     string
+	clientSecret string
+	client       *resty.Client
+}
+
+func newNotionProvider(clientID, clientSecret string) *notionProvider {
+	return &notionProvider{clientID: clientID, clientSecret: clientSecret, client: newClient()}
+}
+
+func (p *notionProvider) AuthURL(redirectURL, state string) string {
+	params := url.Values{
+		"client_id":     {p.clientID},
+		"response_type": {"code"},
+		"owner":         {"user"},
+		"redirect_uri":  {redirectURL},
+		"state":         {state},
+	}
+	return notionAPI + "/oauth/authorize?" + params.Encode()
+}
+
+func (p *notionProvider) Exchange(ctx context.Context, code, redirectURL string) (*Token, error) {
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+	resp, err := p.client.R().
+		SetContext(ctx).
+		SetBasicAuth(p.clientID, p.clientSecret).
+		SetBody(map[string]string{
+			"grant_type":   "authorization_code",
+			"code":         code,
+			"redirect_uri": redirectURL,
+		}).
+		SetResult(&result).
+		Post(notionAPI + "/oauth/token")
+	if err := checkResponse(Notion, resp, err); err != nil {
+		return nil, err
+	}
+	return &Token{AccessToken: result.AccessToken}, nil
+}
+
+// Refresh is a no-op: Notion access tokens do not expire
+func (p *notionProvider) Refresh(ctx context.Context, token *Token) (*Token, error) {
+	return token, nil
+}
+
+// Export creates a page under parentID, or under the first page the user
+// shared with the integration
+func (p *notionProvider) Export(ctx context.Context, accessToken string, doc Document, parentID string) (*Export, error) {
+	if parentID == "" {
+		var err error
+		if parentID, err = p.firstSharedPage(ctx, accessToken); err != nil {
+			return nil, err
+		}
+	}
+
+	blocks := notionBlocks(doc)
+	first := min(len(blocks), notionBlockBatch)
+
+	var page struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	resp, err := p.request(ctx, accessToken).
+		SetBody(map[string]interface{}{
+			"parent": map[string]string{"page_id": parentID},
+			"properties": map[string]interface{}{
+				"title": map[string]interface{}{"title": notionText(doc.Title)},
+			},
+			"children": blocks[:first],
+		}).
+		SetResult(&page).
+		Post(notionAPI + "/pages")
+	if err := checkResponse(Notion, resp, err); err != nil {
+		return nil, err
+	}
+
+	// The rest of the blocks are appended in batches
+	for start := first; start < len(blocks); start += notionBlockBatch {
+		end := min(start+notionBlockBatch, len(blocks))
+		resp, err := p.request(ctx, accessToken).
+			SetBody(map[string]interface{}{"children": blocks[start:end]}).
+			Patch(notionAPI + "/blocks/" + page.ID + "/children")
+		if err := checkResponse(Notion, resp, err); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Export{ID: page.ID, URL: page.URL}, nil
+}
+
+func (p *notionProvider) firstSharedPage(ctx context.Context, accessToken string) (string, error) {
+	var result struct {
+		Results []struct {
+			ID string `json:"id"`
+		} `json:"results"`
+	}
+	resp, err := p.request(ctx, accessToken).
+		SetBody(map[string]interface{}{
+			"filter":    map[string]string{"property": "object", "value": "page"},
+			"page_size": 1,
+		}).
+		SetResult(&result).
+		Post(notionAPI + "/search")
+	if err := checkResponse(Notion, resp, err); err != nil {
+		return "", err
+	}
+	if len(result.Results) == 0 {
+		return "", ErrNoParent
+	}
+	return result.Results[0].ID, nil
+}
+
+func (p *notionProvider) request(ctx context.Context, accessToken string) *resty.Request {
+	return p.client.R().
+		SetContext(ctx).
+		SetAuthToken(accessToken).
+		SetHeader("Notion-Version", notionVersion)
+}
+
+// notionBlocks renders the document: a heading per question, a paragraph per
+// answer paragraph and a bulleted link per source
+func notionBlocks(doc Document) []map[string]interface{} {
+	var blocks []map[string]interface{}
+	for _, section := range doc.Sections {
+		blocks = append(blocks, notionBlock("heading_2", notionText(section.Heading)))
+		for _, paragraph := range strings.Split(section.Body, "\n\n") {
+			if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+				blocks = append(blocks, notionBlock("paragraph", notionText(paragraph)))
+			}
+		}
+		for _, source := range section.Sources {
+			title := source.Title
+			if title == "" {
+				title = source.URL
+			}
+			text := notionText(title)
+			if strings.HasPrefix(source.URL, "http") {
+				for _, item := range text {
+					item["text"].(map[string]interface{})["link"] = map[string]string{"url": source.URL}
+				}
+			}
+			blocks = append(blocks, notionBlock("bulleted_list_item", text))
+		}
+	}
+	return blocks
+}
+
+func notionBlock(kind string, text []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"object": "block",
+		"type":   kind,
+		kind:     map[string]interface{}{"rich_text": text},
+	}
+}
+
+// notionText splits s into rich text objects within notionTextLimit
+func notionText(s string) []map[string]interface{} {
+	var text []map[string]interface{}
+	runes := []rune(s)
+	for start := 0; start < len(runes); start += notionTextLimit {
+		end := min(start+notionTextLimit, len(runes))
+		text = append(text, map[string]interface{}{
+			"type": "text",
+			"text": map[string]interface{}{"content": string(runes[start:end])},
+		})
+	}
+	return text
+}
@@ -0,0 +1,141 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrDuplicateRequest = errors.New("request with this id is already running")
+
+const (
+	// cancelChannel carries request ids to cancel on whichever replica runs them
+	cancelChannel = "requests:cancel"
+	// requestTTL bounds how long a request id stays claimed in Redis if its
+	// replica dies without releasing it
+	requestTTL = 10 * time.Minute
+)
+
+// Registry tracks in-flight queries so they can be cancelled by request id,
+// by the client that started them. With Redis, request ids are claimed across
+// replicas and cancellations are broadcast, so DELETE may hit any replica.
+type Registry struct {
+	mu     sync.Mutex
+	active map[string]activeRequest
+	redis  *redis.Client
+}
+
+type activeRequest struct {
+	cancel context.CancelFunc
+	owner  string
+}
+
+func NewRegistry(redisClient *redis.Client) *Registry {
+	r := &Registry{
+		active: make(map[string]activeRequest),
+		redis:  redisClient,
+	}
+	if redisClient != nil {
+		go r.listen()
+	}
+	return r
+}
+
+func requestKey(requestID string) string {
+	return "request:" + requestID
+}
+
+// Start derives a cancellable context for the request of owner (the client
+// that may cancel it); done must be called when the request finishes to
+// release it
+func (r *Registry) Start(parent context.Context, requestID, owner string) (ctx context.Context, done func(), err error) {
+	if r.redis != nil {
+		claimed, err := r.redis.SetNX(parent, requestKey(requestID), owner, requestTTL).Result()
+		if err != nil {
+			// Cancellation from other replicas is lost, the query itself still runs
+			log.Printf("⚠️  Failed to claim request %s: %v", requestID, err)
+		} else if !claimed {
+			return nil, nil, ErrDuplicateRequest
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.active[requestID]; exists {
+		return nil, nil, ErrDuplicateRequest
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	r.active[requestID] = activeRequest{cancel: cancel, owner: owner}
+
+	done = func() {
+		r.mu.Lock()
+		delete(r.active, requestID)
+		r.mu.Unlock()
+		cancel()
+
+		if r.redis != nil {
+			releaseCtx, cancelRelease := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancelRelease()
+			r.redis.Del(releaseCtx, requestKey(requestID))
+		}
+	}
+	return ctx, done, nil
+}
+
+// Cancel cancels a running request of owner; returns false if it is not
+// running or another client started it
+func (r *Registry) Cancel(requestID, owner string) bool {
+	if running, cancelled := r.cancelLocal(requestID, owner); running {
+		return cancelled
+	}
+	if r.redis == nil {
+		return false
+	}
+
+	// Running on another replica: broadcast the cancellation
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	runningOwner, err := r.redis.Get(ctx, requestKey(requestID)).Result()
+	if err != nil || runningOwner != owner {
+		return false
+	}
+	if err := r.redis.Publish(ctx, cancelChannel, requestID).Err(); err != nil {
+		log.Printf("⚠️  Failed to broadcast cancellation of %s: %v", requestID, err)
+		return false
+	}
+	return true
+}
+
+// cancelLocal cancels the request if it runs on this replica and belongs to
+// owner; an empty owner is a cancellation already checked by another replica
+func (r *Registry) cancelLocal(requestID, owner string) (running, cancelled bool) {
+	r.mu.Lock()
+	request, ok := r.active[requestID]
+	r.mu.Unlock()
+
+	if !ok {
+		return false, false
+	}
+	if owner != "" && request.owner != owner {
+		return true, false
+	}
+	request.cancel()
+	return true, true
+}
+
+// listen cancels requests of this replica broadcast by the others
+func (r *Registry) listen() {
+	sub := r.redis.Subscribe(context.Background(), cancelChannel)
+	defer sub.Close()
+
+	for msg := range sub.Channel() {
+		r.cancelLocal(msg.Payload, "")
+	}
+}
@@ -0,0 +1,223 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type Status string
+
+const (
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// Job is a background query whose result is picked up later by the client
+type Job struct {
+	ID        string                 `json:"id"`
+	Kind      string                 `json:"kind"`
+	Status    Status                 `json:"status"`
+	Result    *models.SearchResponse `json:"result,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	CreatedAt int64                  `json:"created_at"`
+	UpdatedAt int64                  `json:"updated_at"`
+
+	done chan struct{}
+}
+
+type JobFunc func(ctx context.Context) (*models.SearchResponse, error)
+
+// remotePollInterval is how often Wait checks Redis for a job of another replica
+const remotePollInterval = time.Second
+
+// Store runs jobs in background goroutines and keeps their results for ttl.
+// With Redis, job snapshots are mirrored there so any replica can serve them.
+type Store struct {
+	mu    sync.RWMutex
+	jobs  map[string]*Job
+	ttl   time.Duration
+	redis *redis.Client
+}
+
+func NewStore(redisClient *redis.Client, ttl time.Duration) *Store {
+	s := &Store{
+		jobs:  make(map[string]*Job),
+		ttl:   ttl,
+		redis: redisClient,
+	}
+	go s.cleanup()
+	return s
+}
+
+func jobKey(id string) string {
+	return "job:" + id
+}
+
+// publish mirrors a job snapshot to Redis
+func (s *Store) publish(job Job) {
+	if s.redis == nil {
+		return
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.redis.Set(ctx, jobKey(job.ID), data, s.ttl).Err(); err != nil {
+		log.Printf("⚠️  Failed to publish job %s: %v", job.ID, err)
+	}
+}
+
+// getRemote reads a job snapshot published by another replica
+func (s *Store) getRemote(ctx context.Context, id string) (Job, bool) {
+	if s.redis == nil {
+		return Job{}, false
+	}
+
+	data, err := s.redis.Get(ctx, jobKey(id)).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("⚠️  Failed to read job %s: %v", id, err)
+		}
+		return Job{}, false
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return Job{}, false
+	}
+	return job, true
+}
+
+// Submit starts fn detached from the caller's request context
+func (s *Store) Submit(kind string, timeout time.Duration, fn JobFunc) *Job {
+	now := time.Now().Unix()
+	job := &Job{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Status:    StatusRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+		done:      make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	snapshot := *job
+	s.mu.Unlock()
+	s.publish(snapshot)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		result, err := fn(ctx)
+
+		s.mu.Lock()
+		if err != nil {
+			log.Printf("❌ Job %s (%s) failed: %v", job.ID, kind, err)
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		} else {
+			log.Printf("✅ Job %s (%s) finished", job.ID, kind)
+			job.Status = StatusDone
+			job.Result = result
+		}
+		job.UpdatedAt = time.Now().Unix()
+		snapshot := *job
+		s.mu.Unlock()
+
+		s.publish(snapshot)
+		close(job.done)
+	}()
+
+	return job
+}
+
+// Get returns a snapshot of the job
+func (s *Store) Get(id string) (Job, bool) {
+	s.mu.RLock()
+	job, ok := s.jobs[id]
+	var snapshot Job
+	if ok {
+		snapshot = *job
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		return s.getRemote(ctx, id)
+	}
+	return snapshot, true
+}
+
+// Wait blocks until the job finishes or ctx is done
+func (s *Store) Wait(ctx context.Context, id string) (Job, error) {
+	s.mu.RLock()
+	job, ok := s.jobs[id]
+	s.mu.RUnlock()
+	if !ok {
+		return s.waitRemote(ctx, id)
+	}
+
+	select {
+	case <-job.done:
+		snapshot, _ := s.Get(id)
+		return snapshot, nil
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+}
+
+// waitRemote polls Redis until a job running on another replica finishes
+func (s *Store) waitRemote(ctx context.Context, id string) (Job, error) {
+	ticker := time.NewTicker(remotePollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, ok := s.getRemote(ctx, id)
+		if !ok {
+			if err := ctx.Err(); err != nil {
+				return Job{}, err
+			}
+			return Job{}, fmt.Errorf("job not found: %s", id)
+		}
+		if job.Status != StatusRunning {
+			return job, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return Job{}, ctx.Err()
+		}
+	}
+}
+
+// cleanup removes finished jobs older than ttl
+func (s *Store) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-s.ttl).Unix()
+		s.mu.Lock()
+		for id, job := range s.jobs {
+			if job.Status != StatusRunning && job.UpdatedAt < cutoff {
+				delete(s.jobs, id)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,62 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Locker makes a scheduled job run on one replica per interval. The first
+// replica to claim an interval slot in Redis runs the job; the claim expires
+// with the slot, so it is never released early and late tickers of other
+// replicas skip the same slot. Without Redis every claim succeeds (single
+// replica).
+//
+// Janitors that clean per-process memory (job store, in-memory caches, rate
+// limiter buckets) must keep running on every replica and are not locked.
+type Locker struct {
+	redis *redis.Client
+	owner string
+}
+
+func NewLocker(redisClient *redis.Client) *Locker {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &Locker{
+		redis: redisClient,
+		owner: host + ":" + strconv.Itoa(os.Getpid()),
+	}
+}
+
+// Claim reports whether this replica should run job name for the interval
+// slot containing now. Redis errors skip the run rather than risk duplicates.
+func (l *Locker) Claim(ctx context.Context, name string, interval time.Duration, now time.Time) bool {
+	if l == nil || l.redis == nil {
+		return true
+	}
+
+	slot := now.Truncate(interval)
+	key := fmt.Sprintf("lock:%s:%d", name, slot.Unix())
+	ttl := time.Until(slot.Add(interval))
+	if ttl <= 0 {
+		ttl = interval
+	}
+
+	ok, err := l.redis.SetNX(ctx, key, l.owner, ttl).Result()
+	if err != nil {
+		log.Printf("⚠️  Failed to claim %s slot, skipping run: %v", name, err)
+		return false
+	}
+	if !ok {
+		holder, _ := l.redis.Get(ctx, key).Result()
+		log.Printf("🔒 %s slot %s already claimed by %s", name, slot.Format(time.RFC3339), holder)
+	}
+	return ok
+}
@@ -0,0 +1,28 @@
+package logging
+
+import (
+	"context"
+	"log"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID attaches the request ID to ctx for Printf and downstream calls
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, or ""
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Printf logs like log.Printf, prefixed with the request ID of ctx if any
+func Printf(ctx context.Context, format string, args ...interface{}) {
+	if requestID := RequestID(ctx); requestID != "" {
+		log.Printf("[%s] "+format, append([]interface{}{requestID}, args...)...)
+		return
+	}
+	log.Printf(format, args...)
+}
@@ -1,20 +1,543 @@
 package models
 
+import "encoding/json"
+
 type SearchRequest struct {
 	Query string `json:"query" binding:"required"`
 	Mode  string `json:"mode"` // auto, simple, pro
+	Race  bool   `json:"race"` // auto mode: answer with Simple now, Pro later
+
+	// Channel selects a rendered answer: telegram, web, api
+	Channel string `json:"channel,omitempty"`
+
+	// Format of the answer text: markdown (default), plain or html
+	Format string `json:"format,omitempty" binding:"omitempty,oneof=markdown plain html"`
+
+	// AnswerLang forces the answer language (ru, en) instead of detecting it
+	// from the query
+	AnswerLang string `json:"answer_lang,omitempty" binding:"omitempty,oneof=ru en"`
+
+	// RequestID lets the client cancel the query via DELETE /api/search/:request_id;
+	// generated by the server if empty
+	RequestID string `json:"request_id,omitempty"`
+
+	// CallbackURL makes the search asynchronous: the request returns 202 with
+	// a job ID and the signed SearchResponse is POSTed here when it finishes
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	// DocumentIDs of uploaded documents (POST /api/documents) whose passages
+	// the agents use as sources next to the web results
+	DocumentIDs []string `json:"document_ids,omitempty" binding:"omitempty,max=10"`
+
+	// Explain runs only the planning stages (mode selection, query enhancement,
+	// sub-queries, providers) and returns an ExplainResponse instead of an answer
+	Explain bool `json:"explain,omitempty"`
+
+	// OutputSchema adds a machine-readable answer (SearchResponse.Structured)
+	OutputSchema string `json:"output_schema,omitempty" binding:"omitempty,oneof=person date number list comparison"`
+
+	// NoCache answers the query afresh instead of from the answer cache; the
+	// new answer still replaces the cached one
+	NoCache bool `json:"no_cache,omitempty"`
+}
+
+// ExplainResponse is the plan of a query without searching or synthesis
+// (SearchRequest.Explain)
+type ExplainResponse struct {
+	Query          string            `json:"query"`
+	RequestedMode  string            `json:"requested_mode"`
+	SelectedMode   string            `json:"selected_mode"`
+	AutoRouting    *AutoRouting      `json:"auto_routing,omitempty"`
+	Constraints    *QueryConstraints `json:"constraints,omitempty"`
+	Plan           QueryPlan         `json:"plan"`
+	Guardrails     *Guardrails       `json:"guardrails,omitempty"`
+	RequestID      string            `json:"request_id,omitempty"`
+	ProcessingTime float64           `json:"processing_time"`
+}
+
+// QueryPlan is what the selected agent would search for
+type QueryPlan struct {
+	Language    string   `json:"language"`     // answer language
+	SearchQuery string   `json:"search_query"` // after enhancement with the conversation
+	Enhanced    bool     `json:"enhanced"`
+	MultiHop    bool     `json:"multi_hop,omitempty"`
+	SubQueries  []string `json:"sub_queries,omitempty"`
+	Hops        int      `json:"hops,omitempty"` // the LLM's estimate of the facts to find
+	// Providers in the order they are queried; web search providers after the
+	// first are fallbacks. "documents" means uploaded document passages.
+	Providers []string `json:"providers"`
+	// DateRange filters the search results by publication date
+	DateRange *DateRange `json:"date_range,omitempty"`
+}
+
+// ModeInfo describes a search mode for clients (GET /api/modes)
+type ModeInfo struct {
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	ExpectedLatency string `json:"expected_latency"`
+	AcceptsContext  bool   `json:"accepts_context"` // uses chat history when sent to a session
+	Default         bool   `json:"default"`
+
+	// Available is false when a required dependency is not configured;
+	// DisabledReason then says what is missing
+	Available      bool              `json:"available"`
+	DisabledReason string            `json:"disabled_reason,omitempty"`
+	Requirements   []ModeRequirement `json:"requirements"`
+}
+
+// ModeRequirement is a dependency of a mode and whether it is configured
+type ModeRequirement struct {
+	Name       string   `json:"name"` // llm, web_search
+	Env        []string `json:"env"`  // any of these variables satisfies it
+	Configured bool     `json:"configured"`
+	// Optional dependencies only improve results, e.g. web search falls back
+	// to DuckDuckGo without SearXNG or Brave
+	Optional bool `json:"optional,omitempty"`
+}
+
+type ModesResponse struct {
+	Modes []ModeInfo `json:"modes"`
+}
+
+type EstimateRequest struct {
+	Query string `json:"query" binding:"required"`
+	Mode  string `json:"mode,omitempty"` // estimate only this mode; all modes if empty
+}
+
+// ModeEstimate is the predicted cost of answering a query in one mode
+type ModeEstimate struct {
+	Mode             string  `json:"mode"`
+	LatencyMs        int64   `json:"latency_ms"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"` // 0 unless LLM prices are configured
+	Samples          int64   `json:"samples"`  // past queries behind the estimate, 0 = built-in defaults
+}
+
+type EstimateResponse struct {
+	Query          string         `json:"query"`
+	Complexity     float64        `json:"complexity"`      // multiplier applied to the research modes
+	ProProbability float64        `json:"pro_probability"` // chance auto mode picks Pro
+	Estimates      []ModeEstimate `json:"estimates"`
+}
+
+// CompareRequest runs one query through simple and pro (POST /api/search/compare)
+type CompareRequest struct {
+	Query       string   `json:"query" binding:"required"`
+	Format      string   `json:"format,omitempty" binding:"omitempty,oneof=markdown plain html"`
+	AnswerLang  string   `json:"answer_lang,omitempty" binding:"omitempty,oneof=ru en"`
+	RequestID   string   `json:"request_id,omitempty"`
+	DocumentIDs []string `json:"document_ids,omitempty" binding:"omitempty,max=10"`
+}
+
+// CompareRun is the outcome of the query in one mode: the response or the error
+type CompareRun struct {
+	Response       *SearchResponse `json:"response,omitempty"`
+	Error          *ErrorResponse  `json:"error,omitempty"`
+	ProcessingTime float64         `json:"processing_time"` // seconds
+}
+
+type CompareResponse struct {
+	Query     string `json:"query"`
+	RequestID string `json:"request_id"`
+	// Routing is what auto mode's routing model makes of the query;
+	// selected_mode is empty when the LLM selector would decide
+	Routing AutoRouting `json:"routing"`
+	Simple  CompareRun  `json:"simple"`
+	Pro     CompareRun  `json:"pro"`
+}
+
+type CreateSessionRequest struct {
+	Mode string `json:"mode" binding:"required"`
+
+	// SystemPrompt configures the answers of the session: tone, depth or
+	// domain expertise ("answer as a tax lawyer, briefly")
+	SystemPrompt string `json:"system_prompt,omitempty" binding:"max=2000"`
+}
+
+type SendMessageRequest struct {
+	Query     string `json:"query" binding:"required"`
+	Mode      string `json:"mode"`
+	Race      bool   `json:"race"`
+	Channel   string `json:"channel,omitempty"`
+	Format    string `json:"format,omitempty" binding:"omitempty,oneof=markdown plain html"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// AfterSeq is the last message seq the client has seen; the message is
+	// rejected with 409 if the session has moved on since
+	AfterSeq *int64 `json:"after_seq,omitempty"`
+	// Explain returns the plan of the message (with the session history)
+	// instead of answering it; nothing is stored. Ignored by edits.
+	Explain bool `json:"explain,omitempty"`
+}
+
+type ShareRequest struct {
+	ExpiresInHours int `json:"expires_in_hours,omitempty" binding:"omitempty,min=1,max=8760"` // no expiry if empty
+}
+
+// ShareResponse is a read-only link to a session for people without access
+type ShareResponse struct {
+	Token     string `json:"token"`
+	URL       string `json:"url"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+}
+
+// ExportRequest pushes a chat session to a connected integration
+type ExportRequest struct {
+	Provider string `json:"provider" binding:"required,oneof=notion google_docs"`
+	// ParentID is the Notion page or Google Drive folder to create the document
+	// in; default: the first page shared with the Notion integration, Drive root
+	ParentID string `json:"parent_id,omitempty"`
+}
+
+type ExportResponse struct {
+	Provider   string `json:"provider"`
+	DocumentID string `json:"document_id"`
+	URL        string `json:"url"`
+}
+
+// IntegrationStatus tells whether the caller has connected a provider
+type IntegrationStatus struct {
+	Provider    string `json:"provider"` // notion, google_docs
+	Connected   bool   `json:"connected"`
+	ConnectedAt int64  `json:"connected_at,omitempty"`
+}
+
+type IntegrationsResponse struct {
+	Integrations []IntegrationStatus `json:"integrations"`
+}
+
+// ConnectResponse is the OAuth consent URL to open in a browser
+type ConnectResponse struct {
+	AuthURL string `json:"auth_url"`
+}
+
+type FeedbackRequest struct {
+	MessageID string `json:"message_id" binding:"required"`
+	Rating    int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment   string `json:"comment"`
+}
+
+// ErrorResponse is the error envelope of all endpoints
+type ErrorResponse struct {
+	Code      string                 `json:"code"` // machine readable, e.g. session_not_found
+	Message   string                 `json:"message"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// JobAcceptedResponse is returned for queries that continue in the background
+type JobAcceptedResponse struct {
+	JobID  string `json:"job_id"`
+	Status string `json:"status"`
+}
+
+type RetrieveRequest struct {
+	Query     string   `json:"query" binding:"required"`
+	Limit     int      `json:"limit"`                // default 10, max 20
+	TimeRange string   `json:"time_range,omitempty"` // day, week, month, year
+	Sites     []string `json:"sites,omitempty"`      // restrict to domains, e.g. habr.com
+}
+
+// RetrieveResponse lists ranked sources without a synthesized answer
+type RetrieveResponse struct {
+	Query          string         `json:"query"`
+	Sources        []RankedSource `json:"sources"`
+	Evidence       Evidence       `json:"evidence"`
+	ProcessingTime float64        `json:"processing_time"`
+	Timestamp      int64          `json:"timestamp"`
+}
+
+type RankedSource struct {
+	Rank        int     `json:"rank"`
+	Title       string  `json:"title"`
+	URL         string  `json:"url"`
+	Snippet     string  `json:"snippet"`
+	Content     string  `json:"content"`
+	Relevance   float64 `json:"relevance"` // BM25 score
+	Credibility float64 `json:"credibility"`
+	PublishedAt int64   `json:"published_at,omitempty"`
+	FetchedAt   int64   `json:"fetched_at,omitempty"`
+}
+
+// Evidence summarizes source credibility and cross-source agreement (0-1)
+type Evidence struct {
+	Score       float64 `json:"score"`
+	Credibility float64 `json:"credibility"`
+	Agreement   float64 `json:"agreement"`
 }
 
 type SearchResponse struct {
 	Query          string   `json:"query"`
 	Mode           string   `json:"mode"`
 	Answer         string   `json:"answer"`
+	Format         string   `json:"format,omitempty"` // markdown, plain, html
 	Sources        []Source `json:"sources"`
 	Reasoning      string   `json:"reasoning,omitempty"`
 	ProcessingTime float64  `json:"processing_time"`
 	Timestamp      int64    `json:"timestamp"`
 	SessionID      string   `json:"session_id,omitempty"`
+	RequestID      string   `json:"request_id,omitempty"`
+	Seq            int64    `json:"seq,omitempty"` // seq of the stored assistant message (chat)
+	Cached         bool     `json:"cached,omitempty"`
+	Stale          bool     `json:"stale,omitempty"` // cached answer past its freshness window
 	ContextUsed    bool     `json:"context_used,omitempty"`
+	Degraded       bool     `json:"degraded,omitempty"` // LLM unavailable, the answer lists the sources found
+
+	// Citations maps the [n] markers of the answer to Sources, in order of
+	// first appearance
+	Citations []Citation `json:"citations,omitempty"`
+
+	// AutoRouting is set when the mode was chosen automatically
+	AutoRouting *AutoRouting `json:"auto_routing,omitempty"`
+
+	// Guardrails lists what was removed from the query before it was sent to
+	// search and LLM providers
+	Guardrails *Guardrails `json:"guardrails,omitempty"`
+
+	// Verification is the check of the answer's sentences against its sources
+	Verification *AnswerVerification `json:"verification,omitempty"`
+
+	// Constraints extracted from the query (Pro modes)
+	Constraints *QueryConstraints `json:"constraints,omitempty"`
+
+	// Rendered is the answer formatted for the requested channel
+	Rendered *RenderedAnswer `json:"rendered,omitempty"`
+
+	// ImprovedAnswerJobID points to the background Pro job in race mode
+	ImprovedAnswerJobID string `json:"improved_answer_job_id,omitempty"`
+
+	// RefreshJobID points to the background job refreshing a stale answer
+	RefreshJobID string `json:"refresh_job_id,omitempty"`
+
+	// Timings break down where the processing time went
+	Timings *Timings `json:"timings,omitempty"`
+	// LLMProviders counts the LLM calls each provider served; more than one
+	// means the primary failed and a fallback answered
+	LLMProviders map[string]int `json:"llm_providers,omitempty"`
+	// Usage is what the LLM calls of the request spent
+	Usage *LLMUsage `json:"usage,omitempty"`
+
+	// FactCheck holds the per-claim verdicts of the fact-check mode
+	FactCheck []ClaimVerdict `json:"fact_check,omitempty"`
+
+	// Research traces the rounds of the deep mode
+	Research *ResearchTrace `json:"research,omitempty"`
+
+	// Conflicts are facts on which the top sources contradict each other (Pro)
+	Conflicts []SourceConflict `json:"conflicts,omitempty"`
+
+	// Entities are the people, organizations, places and works the top
+	// sources mention, with the relations between them (Pro)
+	Entities []Entity `json:"entities,omitempty"`
+
+	// DateRange is the period detected in the query; sources published
+	// outside it were dropped
+	DateRange *DateRange `json:"date_range,omitempty"`
+
+	// Images found for image requests ("покажи картину ..."), images mode
+	Images []Image `json:"images,omitempty"`
+
+	// Structured is the answer in the requested output schema
+	Structured *StructuredAnswer `json:"structured,omitempty"`
+
+	// Evidence assesses the sources of a Simple answer; auto mode escalates
+	// weak ones to Pro
+	Evidence *Evidence `json:"evidence,omitempty"`
+}
+
+// StructuredAnswer is the answer as JSON in one of the output schemas
+type StructuredAnswer struct {
+	Schema string          `json:"schema"` // person, date, number, list, comparison
+	Data   json.RawMessage `json:"data"`
+}
+
+// StepEvent is a reasoning step streamed while the query is answered
+type StepEvent struct {
+	Seq       int64  `json:"seq"`
+	Step      string `json:"step"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+// TokenEvent is a chunk of an answer streamed as the LLM writes it. Mode is
+// the agent writing it (simple, pro): a new mode starts a new answer, e.g.
+// when auto mode escalates from Simple to Pro.
+type TokenEvent struct {
+	Seq  int64  `json:"seq"`
+	Mode string `json:"mode"`
+	Text string `json:"text"`
+}
+
+// ResearchTrace is how the deep research loop went
+type ResearchTrace struct {
+	Rounds       int           `json:"rounds"`
+	SubQuestions []SubQuestion `json:"sub_questions"`
+	// StoppedBy is why the loop ended: resolved (every sub-question answered),
+	// exhausted (nothing left to search), rounds, time or tokens (budget)
+	StoppedBy string `json:"stopped_by"`
+	Tokens    int64  `json:"tokens"` // LLM tokens of the research, answer included
+}
+
+// SubQuestion is one question the deep research searched for
+type SubQuestion struct {
+	Question string `json:"question"`
+	Round    int    `json:"round"` // round it was searched in
+	Resolved bool   `json:"resolved"`
+}
+
+// SourceConflict is a fact two sources state differently. SourceA and
+// SourceB index SearchResponse.Sources, like Citation.SourceIndex.
+type SourceConflict struct {
+	Claim      string `json:"claim"`
+	SourceA    int    `json:"source_a"`
+	StatementA string `json:"statement_a,omitempty"`
+	SourceB    int    `json:"source_b"`
+	StatementB string `json:"statement_b,omitempty"`
+}
+
+// Entity is a named thing the sources mention
+type Entity struct {
+	Name      string           `json:"name"`
+	Type      string           `json:"type"` // person, organization, place, work, event, other
+	Relations []EntityRelation `json:"relations,omitempty"`
+}
+
+// EntityRelation links an entity to another one by name, e.g. "directed"
+// "Inception"
+type EntityRelation struct {
+	Relation string `json:"relation"`
+	Target   string `json:"target"`
+}
+
+// ClaimVerdict is the verdict on one claim of a fact-checked statement
+type ClaimVerdict struct {
+	Claim       string `json:"claim"`
+	Verdict     string `json:"verdict"` // supported, refuted, insufficient
+	Explanation string `json:"explanation,omitempty"`
+	// Sources indexes SearchResponse.Sources, like Citation.SourceIndex
+	Sources []int `json:"sources"`
+}
+
+// Timings of one request in milliseconds. Phases are summed over all their
+// calls; concurrent work (parallel searches, sub-queries) overlaps, so the
+// parts can add up to more than TotalMS.
+type Timings struct {
+	RoutingMS      int64            `json:"routing_ms"`       // auto mode selection and constraint extraction
+	QueryEnhanceMS int64            `json:"query_enhance_ms"` // context rewriting and sub-query generation
+	SearchMS       map[string]int64 `json:"search_ms"`        // per provider
+	PageFetchMS    int64            `json:"page_fetch_ms"`    // text of the top result pages
+	RerankMS       int64            `json:"rerank_ms"`
+	CredibilityMS  int64            `json:"credibility_ms"`  // source credibility scoring
+	SynthesisMS    int64            `json:"synthesis_ms"`    // the LLM call writing the answer
+	VerificationMS int64            `json:"verification_ms"` // checking the answer against its sources, part of postprocess
+	LLMMS          int64            `json:"llm_ms"`          // all LLM calls, including routing and enhancement
+	PostprocessMS  int64            `json:"postprocess_ms"`  // verification, formatting, previews, translations
+	TotalMS        int64            `json:"total_ms"`
+}
+
+type RenderedAnswer struct {
+	Format string `json:"format"` // markdown_v2, html, plain
+	Text   string `json:"text"`
+	// Footer is the configured answer footer as plain text, already included
+	// in Text; for clients that fall back to rendering the answer themselves
+	Footer string `json:"footer,omitempty"`
+}
+
+// QueryConstraints are structured parameters extracted from a query
+type QueryConstraints struct {
+	Entities          []string `json:"entities,omitempty"`
+	TimeRange         string   `json:"time_range,omitempty"` // day, week, month, year
+	Period            string   `json:"period,omitempty"`     // free-form, e.g. "2008-2010"
+	Location          string   `json:"location,omitempty"`
+	ComparisonTargets []string `json:"comparison_targets,omitempty"`
+	Tickers           []string `json:"tickers,omitempty"`
+	Sites             []string `json:"sites,omitempty"`
+}
+
+// DateRange is the period a query asks about, in unix seconds; a zero bound
+// is open. Label is the query phrase it was read from, e.g. "in 2019".
+type DateRange struct {
+	From  int64  `json:"from,omitempty"`
+	To    int64  `json:"to,omitempty"`
+	Label string `json:"label"`
+}
+
+// AutoModeFeatures describes a query for the auto mode routing model
+type AutoModeFeatures struct {
+	HistoryMessages   float64 `json:"history_messages"`
+	QueryWords        float64 `json:"query_words"`
+	ComplexIndicators float64 `json:"complex_indicators"`
+	SimpleIndicators  float64 `json:"simple_indicators"`
+	MultiHop          float64 `json:"multi_hop"`
+}
+
+type AutoRouting struct {
+	Features       AutoModeFeatures `json:"features"`
+	ProProbability float64          `json:"pro_probability"`
+	SelectedMode   string           `json:"selected_mode"`
+	DecidedBy      string           `json:"decided_by"` // instant, images, model, classifier, selector
+
+	// Vertical is set when a Pro decision was handed to a domain agent
+	Vertical *VerticalRouting `json:"vertical,omitempty"`
+	// Cost is the expected cost of the selected mode
+	Cost *RoutingCost `json:"cost,omitempty"`
+	// EscalatedFrom is "simple" when a weak Simple answer was replaced by Pro
+	EscalatedFrom string `json:"escalated_from,omitempty"`
+}
+
+// LLMUsage is the tokens and cost of the LLM calls of a request
+type LLMUsage struct {
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"` // 0 unless LLM prices are configured
+}
+
+// RoutingCost is what auto mode expected a query in the selected mode to
+// cost, from the running averages of the mode's recent queries
+type RoutingCost struct {
+	LatencyMs int64   `json:"latency_ms"`
+	Tokens    int64   `json:"tokens"`
+	CostUSD   float64 `json:"cost_usd"` // 0 unless LLM prices are configured
+
+	// Chat sessions with SESSION_TOKEN_BUDGET: tokens spent before the query,
+	// the budget and the mode given up to stay within it
+	SessionTokens  int64  `json:"session_tokens,omitempty"`
+	SessionBudget  int64  `json:"session_budget,omitempty"`
+	DowngradedFrom string `json:"downgraded_from,omitempty"`
+}
+
+// VerticalRouting explains why auto mode answered with a vertical agent
+type VerticalRouting struct {
+	Agent      string   `json:"agent"`      // pro-finance, pro-academic, pro-social, pro-news, pro-code, pro-video, pro-factcheck, pro-mixed
+	Signals    []string `json:"signals"`    // query words that matched the agent's keywords
+	Confidence float64  `json:"confidence"` // 0-1, grows with the number of signals
+
+	// Domains are the verticals a pro-mixed query spans
+	Domains []string `json:"domains,omitempty"`
+	// Examples are the labeled queries nearest to a query without keywords
+	// whose domain chose the agent; confidence is then their vote margin
+	Examples []string `json:"examples,omitempty"`
+}
+
+// AnswerVerification is how much of an answer its sources support
+type AnswerVerification struct {
+	VerifiedRatio float64  `json:"verified_ratio"`        // supported share of the factual sentences (0-1)
+	Checked       int      `json:"checked"`               // factual sentences checked
+	Unsupported   []string `json:"unsupported,omitempty"` // sentences the sources do not support
+	Action        string   `json:"action"`                // flag or strip: what was done to them
+}
+
+// Guardrails is what the query guardrails changed in a query
+type Guardrails struct {
+	Redacted  []string `json:"redacted,omitempty"`  // personal data replaced: email, phone, card
+	Sanitized []string `json:"sanitized,omitempty"` // policy categories whose text was removed
+}
+
+// Citation ties an inline answer marker to the source it cites
+type Citation struct {
+	Marker      string `json:"marker"`       // "[1]" as it appears in the answer
+	SourceIndex int    `json:"source_index"` // index into sources
 }
 
 type Source struct {
@@ -22,6 +545,43 @@ type Source struct {
 	URL         string  `json:"url"`
 	Snippet     string  `json:"snippet"`
 	Credibility float64 `json:"credibility,omitempty"`
+	PublishedAt int64   `json:"published_at,omitempty"` // unix seconds, from page metadata
+	FetchedAt   int64   `json:"fetched_at,omitempty"`   // unix seconds
+	Language    string  `json:"language,omitempty"`     // ru or en, detected from the snippet
+
+	// Preview of the cited page (SOURCE_PREVIEWS_ENABLED)
+	Preview *SourcePreview `json:"preview,omitempty"`
+	// Machine translation of a snippet written in another language than the
+	// answer (SNIPPET_TRANSLATION_PROVIDER)
+	Translation *SourceTranslation `json:"translation,omitempty"`
+}
+
+// Image is an image search result with its attribution: the page it appears
+// on and the site (and author, when known) to credit
+type Image struct {
+	Title        string `json:"title"`
+	URL          string `json:"url"` // full-size image
+	ThumbnailURL string `json:"thumbnail_url"`
+	PageURL      string `json:"page_url"`
+	Source       string `json:"source"` // site credited for the image
+	Author       string `json:"author,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
+
+// SourceTranslation is a snippet translated into the answer language; the
+// original stays in Source.Snippet
+type SourceTranslation struct {
+	Language string `json:"language"` // ru or en
+	Snippet  string `json:"snippet"`
+	Provider string `json:"provider"` // llm, libretranslate or deepl
+}
+
+// SourcePreview is Open Graph style data for rendering a source card
+type SourcePreview struct {
+	SiteName string `json:"site_name"`
+	Favicon  string `json:"favicon"`
+	Image    string `json:"image,omitempty"`
 }
 
 type Message struct {
@@ -38,6 +598,7 @@ type TavilySearchRequest struct {
 type TavilySearchResponse struct {
 	Results []TavilyResult `json:"results"`
 	Query   string         `json:"query"`
+	Answer  string         `json:"answer,omitempty"` // short answer of the Tavily API, when it was used
 }
 
 type TavilyResult struct {
@@ -48,4 +609,49 @@ type TavilyResult struct {
 	RawContent  string  `json:"raw_content,omitempty"`
 	Score       float64 `json:"score"`
 	Credibility float64 `json:"credibility"` // Добавлено
-}
\ No newline at end of file
+	PublishedAt int64   `json:"published_at,omitempty"`
+	FetchedAt   int64   `json:"fetched_at,omitempty"`
+	Pivot       bool    `json:"-"` // found by the query translated into the other language
+}
+
+// UsageStats aggregates the queries of a period, a day or a mode
+type UsageStats struct {
+	Queries          int64   `json:"queries"`
+	Errors           int64   `json:"errors"`
+	ErrorRate        float64 `json:"error_rate"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"` // successful queries only
+	CacheHits        int64   `json:"cache_hits"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"` // 0 unless LLM prices are configured
+}
+
+type DayUsage struct {
+	Day string `json:"day"`
+	UsageStats
+}
+
+type ModeUsage struct {
+	Mode string `json:"mode"`
+	UsageStats
+}
+
+// AdminStatsResponse is the usage report of GET /api/admin/stats
+type AdminStatsResponse struct {
+	From        string            `json:"from"` // first day, YYYY-MM-DD (UTC)
+	To          string            `json:"to"`
+	Totals      UsageStats        `json:"totals"`
+	PerDay      []DayUsage        `json:"per_day"`
+	PerMode     []ModeUsage       `json:"per_mode"`
+	SearchCache *SearchCacheStats `json:"search_cache,omitempty"` // nil when disabled
+}
+
+// SearchCacheStats counts the provider searches answered from the search
+// cache of this server since it started
+type SearchCacheStats struct {
+	Hits        int64                       `json:"hits"`
+	Misses      int64                       `json:"misses"`
+	HitRate     float64                     `json:"hit_rate"`
+	PerProvider map[string]SearchCacheStats `json:"per_provider,omitempty"`
+}
@@ -0,0 +1,84 @@
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrNotPublic rejects connections to addresses outside the public internet:
+// loopback, private, link-local, shared (carrier-grade NAT), reserved and
+// documentation ranges
+var ErrNotPublic = errors.New("not a public address")
+
+// nonPublic are the special-purpose ranges the net.IP predicates don't cover
+var nonPublic = parseCIDRs(
+	"0.0.0.0/8",       // "this network"
+	"100.64.0.0/10",   // shared address space (carrier-grade NAT)
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"240.0.0.0/4",     // reserved, broadcast
+	"64:ff9b::/96",    // NAT64, may map to any IPv4 address
+	"64:ff9b:1::/48",  // local-use NAT64
+	"100::/64",        // discard
+	"2001:db8::/32",   // documentation
+	"2002::/16",       // 6to4, may map to any IPv4 address
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// PublicIP reports whether ip is an address of the public internet
+func PublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, ipNet := range nonPublic {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Transport returns an HTTP transport that refuses to connect to addresses
+// that are not public. The address is checked as it is dialed, after DNS
+// resolution, so redirects and hosts that change their address between a
+// check and the request (DNS rebinding) can't reach our network either.
+// Proxies are not used: the proxy would be the address dialed.
+func Transport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+				return fmt.Errorf("connect to %s: %w", host, ErrNotPublic)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
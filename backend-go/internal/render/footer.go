@@ -0,0 +1,81 @@
+package render
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+// maxFooterLength bounds the rendered footer, in runes
+const maxFooterLength = 300
+
+// FooterData is what a footer template can refer to
+type FooterData struct {
+	Sources int       // number of sources of the answer
+	Mode    string    // mode that produced the answer, e.g. "auto → pro"
+	Time    time.Time // when the answer was produced, UTC
+	Seconds float64   // processing time
+}
+
+// Footer is a text/template appended to rendered answers: a disclaimer,
+// "generated with N sources at <time>", deployment branding. The template
+// produces plain text that every renderer escapes for its format, so a
+// footer can't break or inject markup.
+type Footer struct {
+	tmpl *template.Template
+}
+
+// NewFooter parses the footer template, where a literal \n is a line break.
+// An empty template means no footer.
+func NewFooter(text string) (*Footer, error) {
+	text = strings.ReplaceAll(text, `\n`, "\n")
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("footer").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse footer template: %w", err)
+	}
+
+	// Unknown fields only fail on execution; catch them at startup
+	footer := &Footer{tmpl: tmpl}
+	if _, err := footer.execute(FooterData{Time: time.Now().UTC()}); err != nil {
+		return nil, err
+	}
+	return footer, nil
+}
+
+// Text renders the footer of the response; a nil footer renders nothing
+func (f *Footer) Text(resp *models.SearchResponse) string {
+	if f == nil {
+		return ""
+	}
+	produced := time.Now().UTC()
+	if resp.Timestamp > 0 {
+		produced = time.Unix(resp.Timestamp, 0).UTC()
+	}
+	text, err := f.execute(FooterData{
+		Sources: len(resp.Sources),
+		Mode:    resp.Mode,
+		Time:    produced,
+		Seconds: resp.ProcessingTime,
+	})
+	if err != nil {
+		log.Printf("⚠️  %v", err)
+		return ""
+	}
+	return text
+}
+
+func (f *Footer) execute(data FooterData) (string, error) {
+	var b strings.Builder
+	if err := f.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render footer: %w", err)
+	}
+	return utils.TruncateRunesWithEllipsis(strings.TrimSpace(b.String()), maxFooterLength), nil
+}
@@ -0,0 +1,55 @@
+package render
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	tagPattern       = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+	hrefPattern      = regexp.MustCompile(`(?i)href\s*=\s*["']?(https?://[^"'\s>]+)`)
+	dangerousBlocks  = regexp.MustCompile(`(?is)<(script|style|iframe|object)\b.*?</(script|style|iframe|object)\s*>`)
+	allowedHTMLTags  = map[string]bool{"p": true, "b": true, "strong": true, "i": true, "em": true, "ul": true, "ol": true, "li": true, "br": true, "code": true, "a": true}
+	markdownResidues = regexp.MustCompile(`(?m)^\s*[*-]\s+`)
+)
+
+// FormatAnswer post-processes the answer text into the format requested by
+// the client (SearchRequest.Format). The LLM is asked for that format too,
+// so this mostly cleans up what it did not follow.
+func FormatAnswer(text, format string) string {
+	switch format {
+	case FormatPlain:
+		text = plainText(text)
+		return markdownResidues.ReplaceAllString(text, "- ")
+	case FormatHTML:
+		if !tagPattern.MatchString(text) {
+			return htmlText(text)
+		}
+		return sanitizeHTML(text)
+	default:
+		return text
+	}
+}
+
+// sanitizeHTML keeps only the tags of allowedHTMLTags, without attributes
+// except http(s) links, since the answer may echo content of web pages
+func sanitizeHTML(fragment string) string {
+	fragment = dangerousBlocks.ReplaceAllString(fragment, "")
+
+	return tagPattern.ReplaceAllStringFunc(fragment, func(tag string) string {
+		m := tagPattern.FindStringSubmatch(tag)
+		closing, name, attrs := m[1], strings.ToLower(m[2]), m[3]
+		if !allowedHTMLTags[name] {
+			return ""
+		}
+		if name == "a" && closing == "" {
+			href := hrefPattern.FindStringSubmatch(attrs)
+			if href == nil {
+				return "<a>"
+			}
+			return `<a href="` + html.EscapeString(href[1]) + `" target="_blank" rel="noopener noreferrer">`
+		}
+		return "<" + closing + name + ">"
+	})
+}
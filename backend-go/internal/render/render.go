@@ -0,0 +1,307 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+// Channels and the formats they are rendered to
+const (
+	ChannelTelegram = "telegram" // Telegram MarkdownV2
+	ChannelWeb      = "web"      // HTML
+	ChannelAPI      = "api"      // plain text
+
+	FormatMarkdownV2 = "markdown_v2"
+	FormatMarkdown   = "markdown" // LLM output as is
+	FormatHTML       = "html"
+	FormatPlain      = "plain"
+)
+
+const (
+	maxTelegramSources = 5
+	maxTelegramLength  = 4000 // Telegram limit is 4096 characters
+)
+
+// Citation is a numbered source reference shared by all renderers
+type Citation struct {
+	Number int
+	Title  string
+	URL    string
+	// Site and Favicon come from the source preview, empty without one
+	Site    string
+	Favicon string
+}
+
+// Answer is the canonical answer structure every renderer works from
+type Answer struct {
+	Text      string
+	Mode      string
+	Citations []Citation
+	// Signals are the query words that made auto mode pick a vertical agent
+	Signals []string
+	// Footer is plain text appended after the sources, escaped by each renderer
+	Footer string
+}
+
+// FromResponse builds the canonical answer from an agent response
+func FromResponse(resp *models.SearchResponse) Answer {
+	answer := Answer{
+		Text: strings.TrimSpace(resp.Answer),
+		Mode: resp.Mode,
+	}
+	if resp.AutoRouting != nil && resp.AutoRouting.Vertical != nil {
+		answer.Signals = resp.AutoRouting.Vertical.Signals
+	}
+	for i, src := range resp.Sources {
+		citation := Citation{
+			Number: i + 1,
+			Title:  src.Title,
+			URL:    src.URL,
+		}
+		if src.Preview != nil {
+			citation.Site = src.Preview.SiteName
+			citation.Favicon = src.Preview.Favicon
+		}
+		answer.Citations = append(answer.Citations, citation)
+	}
+	return answer
+}
+
+// FormatForChannel maps a client channel to its output format, "" for unknown
+func FormatForChannel(channel string) string {
+	switch channel {
+	case ChannelTelegram:
+		return FormatMarkdownV2
+	case ChannelWeb:
+		return FormatHTML
+	case ChannelAPI:
+		return FormatPlain
+	default:
+		return ""
+	}
+}
+
+// Render renders the response in the given format, with the footer if one
+// is configured
+func Render(resp *models.SearchResponse, format string, footer *Footer) (*models.RenderedAnswer, error) {
+	answer := FromResponse(resp)
+	answer.Footer = footer.Text(resp)
+
+	var text string
+	switch format {
+	case FormatMarkdownV2:
+		text = Telegram(answer)
+	case FormatHTML:
+		text = HTML(answer)
+	case FormatPlain:
+		text = Plain(answer)
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+
+	return &models.RenderedAnswer{Format: format, Text: text, Footer: answer.Footer}, nil
+}
+
+var (
+	boldPattern    = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	headingPattern = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`)
+	linkPattern    = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+	inlineCode     = regexp.MustCompile("`([^`]+)`")
+	citationMarker = regexp.MustCompile(`\[(\d{1,2})\]`)
+)
+
+// Telegram renders Telegram MarkdownV2 with all reserved characters escaped.
+// Only bold text survives from the LLM markdown; headings become bold lines.
+func Telegram(a Answer) string {
+	var b strings.Builder
+
+	b.WriteString("💬 *Ответ:*\n")
+	b.WriteString(telegramText(a.Text))
+	b.WriteString("\n\n")
+
+	if len(a.Citations) > 0 {
+		b.WriteString("📚 *Источники:*\n")
+		for i, c := range a.Citations {
+			if i >= maxTelegramSources {
+				b.WriteString(escapeMarkdownV2(fmt.Sprintf("...и ещё %d источников", len(a.Citations)-i)))
+				b.WriteString("\n")
+				break
+			}
+			title := escapeMarkdownV2(utils.TruncateRunesWithEllipsis(c.Title, 80))
+			if c.Site != "" {
+				title = "*" + escapeMarkdownV2(c.Site) + "* · " + title
+			}
+			b.WriteString(fmt.Sprintf("%s %s\n%s\n\n",
+				escapeMarkdownV2(fmt.Sprintf("[%d]", c.Number)),
+				title,
+				escapeMarkdownV2(c.URL)))
+		}
+	}
+
+	if a.Mode != "" {
+		b.WriteString(fmt.Sprintf("🔧 Режим: *%s*", escapeMarkdownV2(a.Mode)))
+		if len(a.Signals) > 0 {
+			b.WriteString(escapeMarkdownV2(fmt.Sprintf("\n🧭 Выбран по словам: %s", strings.Join(a.Signals, ", "))))
+		}
+	}
+
+	// The footer is kept when the answer is cut
+	var footer string
+	if a.Footer != "" {
+		footer = "\n\n_" + escapeMarkdownV2(a.Footer) + "_"
+	}
+	limit := maxTelegramLength - len([]rune(footer))
+
+	text := b.String()
+	if len([]rune(text)) > limit {
+		// Cut on a line boundary so no escape sequence or bold span is split
+		runes := []rune(text)[:limit]
+		cut := string(runes)
+		if idx := strings.LastIndex(cut, "\n"); idx > 0 {
+			cut = cut[:idx]
+		}
+		text = cut + "\n" + escapeMarkdownV2("...")
+	}
+	return text + footer
+}
+
+func telegramText(text string) string {
+	text = headingPattern.ReplaceAllString(text, "**$1**")
+	text = linkPattern.ReplaceAllString(text, "$1 ($2)")
+	text = inlineCode.ReplaceAllString(text, "$1")
+
+	var b strings.Builder
+	last := 0
+	for _, m := range boldPattern.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(escapeMarkdownV2(text[last:m[0]]))
+		b.WriteString("*" + escapeMarkdownV2(text[m[2]:m[3]]) + "*")
+		last = m[1]
+	}
+	b.WriteString(escapeMarkdownV2(text[last:]))
+	return b.String()
+}
+
+var markdownV2Replacer = strings.NewReplacer(
+	"\\", "\\\\",
+	"_", "\\_",
+	"*", "\\*",
+	"[", "\\[",
+	"]", "\\]",
+	"(", "\\(",
+	")", "\\)",
+	"~", "\\~",
+	"`", "\\`",
+	">", "\\>",
+	"#", "\\#",
+	"+", "\\+",
+	"-", "\\-",
+	"=", "\\=",
+	"|", "\\|",
+	"{", "\\{",
+	"}", "\\}",
+	".", "\\.",
+	"!", "\\!",
+)
+
+func escapeMarkdownV2(text string) string {
+	return markdownV2Replacer.Replace(text)
+}
+
+// HTML renders the answer as an HTML fragment for the web client
+func HTML(a Answer) string {
+	var b strings.Builder
+
+	b.WriteString(`<div class="answer">`)
+	b.WriteString(htmlText(a.Text))
+	b.WriteString(`</div>`)
+
+	if len(a.Citations) > 0 {
+		b.WriteString(`<ol class="sources">`)
+		for _, c := range a.Citations {
+			b.WriteString(fmt.Sprintf(`<li id="source-%d">`, c.Number))
+			if webURL(c.Favicon) {
+				b.WriteString(fmt.Sprintf(`<img class="favicon" src="%s" alt="" width="16" height="16" loading="lazy"> `,
+					html.EscapeString(c.Favicon)))
+			}
+			if c.Site != "" {
+				b.WriteString(fmt.Sprintf(`<span class="site">%s</span> `, html.EscapeString(c.Site)))
+			}
+			if webURL(c.URL) {
+				b.WriteString(fmt.Sprintf(`<a href="%s" target="_blank" rel="noopener noreferrer">%s</a></li>`,
+					html.EscapeString(c.URL), html.EscapeString(c.Title)))
+			} else {
+				// javascript: and other schemes of untrusted sources are not linked
+				b.WriteString(fmt.Sprintf(`%s <span class="url">%s</span></li>`,
+					html.EscapeString(c.Title), html.EscapeString(c.URL)))
+			}
+		}
+		b.WriteString(`</ol>`)
+	}
+
+	if a.Footer != "" {
+		b.WriteString(`<p class="footer">`)
+		b.WriteString(strings.ReplaceAll(html.EscapeString(a.Footer), "\n", "<br>"))
+		b.WriteString(`</p>`)
+	}
+
+	return b.String()
+}
+
+// webURL reports whether u is an http(s) URL, the only ones rendered as links
+// or images, like the links of the answer text (linkPattern)
+func webURL(u string) bool {
+	lower := strings.ToLower(strings.TrimSpace(u))
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// htmlText converts LLM markdown into HTML paragraphs
+func htmlText(markdown string) string {
+	var b strings.Builder
+	for _, paragraph := range strings.Split(markdown, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		text := html.EscapeString(paragraph)
+		text = headingPattern.ReplaceAllString(text, "<strong>$1</strong>")
+		text = boldPattern.ReplaceAllString(text, "<strong>$1</strong>")
+		text = linkPattern.ReplaceAllString(text, `<a href="$2" target="_blank" rel="noopener noreferrer">$1</a>`)
+		text = citationMarker.ReplaceAllString(text, `<a href="#source-$1" class="citation">[$1]</a>`)
+		text = inlineCode.ReplaceAllString(text, "<code>$1</code>")
+		text = strings.ReplaceAll(text, "\n", "<br>")
+		b.WriteString("<p>" + text + "</p>")
+	}
+	return b.String()
+}
+
+// Plain renders the answer without any markup
+func Plain(a Answer) string {
+	var b strings.Builder
+	b.WriteString(plainText(a.Text))
+
+	if len(a.Citations) > 0 {
+		b.WriteString("\n\nSources:\n")
+		for _, c := range a.Citations {
+			b.WriteString(fmt.Sprintf("[%d] %s - %s\n", c.Number, c.Title, c.URL))
+		}
+	}
+
+	text := strings.TrimRight(b.String(), "\n")
+	if a.Footer != "" {
+		text += "\n\n" + a.Footer
+	}
+	return text
+}
+
+// plainText strips the markdown the LLM tends to emit
+func plainText(markdown string) string {
+	text := headingPattern.ReplaceAllString(markdown, "$1")
+	text = boldPattern.ReplaceAllString(text, "$1")
+	text = linkPattern.ReplaceAllString(text, "$1 ($2)")
+	return inlineCode.ReplaceAllString(text, "$1")
+}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 	"github.com/go-resty/resty/v2"
 )
 
@@ -19,7 +21,7 @@ type AcademicScraper struct {
 func NewAcademicScraper() *AcademicScraper {
 	client := resty.New()
 	client.SetTimeout(15 * time.Second)
-	return &AcademicScraper{client: client}
+	return &AcademicScraper{client: tools.Polite(client, "export.arxiv.org")}
 }
 
 // arXiv API response
@@ -38,11 +40,16 @@ type ArxivResponse struct {
 
 // Search arXiv
 func (s *AcademicScraper) SearchArxiv(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "arxiv", time.Now())
 	log.Printf("🔍 Searching arXiv for: %s", query)
 
+	searchQuery := "all:" + query
+	if r := tools.DateRangeFromContext(ctx); r != nil {
+		searchQuery = fmt.Sprintf("(%s) AND submittedDate:[%s TO %s]", searchQuery, arxivDate(r.From, "0000"), arxivDate(r.To, "2359"))
+	}
 	searchURL := fmt.Sprintf(
-		"http://export.arxiv.org/api/query?search_query=all:%s&start=0&max_results=%d&sortBy=relevance&sortOrder=descending",
-		url.QueryEscape(query), limit)
+		"http://export.arxiv.org/api/query?search_query=%s&start=0&max_results=%d&sortBy=relevance&sortOrder=descending",
+		url.QueryEscape(searchQuery), limit)
 
 	resp, err := s.client.R().
 		SetContext(ctx).
@@ -64,22 +71,42 @@ func (s *AcademicScraper) SearchArxiv(ctx context.Context, query string, limit i
 			break
 		}
 
+		var publishedAt int64
+		if t, err := time.Parse(time.RFC3339, entry.Published); err == nil {
+			publishedAt = t.Unix()
+		}
+
 		results = append(results, models.TavilyResult{
-			Title:   fmt.Sprintf("[arXiv] %s", entry.Title),
-			URL:     entry.URL,
-			Content: entry.Summary,
-			Score:   0.95 - float64(i)*0.03,
+			Title:       fmt.Sprintf("[arXiv] %s", entry.Title),
+			URL:         entry.URL,
+			Content:     entry.Summary,
+			Score:       0.95 - float64(i)*0.03,
+			PublishedAt: publishedAt,
 		})
 	}
 
+	results = tools.FilterByDateRange(ctx, results)
 	log.Printf("✅ Found %d arXiv papers", len(results))
 	return results, nil
 }
 
+// arxivDate formats a date range bound for submittedDate (YYYYMMDDhhmm); an
+// open bound becomes the start of arXiv or today
+func arxivDate(unix int64, clock string) string {
+	t := time.Now().UTC()
+	if unix > 0 {
+		t = time.Unix(unix, 0).UTC()
+	} else if clock == "0000" {
+		t = time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t.Format("20060102") + clock
+}
+
 type XMLEntry struct {
-	Title   string
-	URL     string
-	Summary string
+	Title     string
+	URL       string
+	Summary   string
+	Published string
 }
 
 func extractXMLEntries(xml string) []XMLEntry {
@@ -91,18 +118,17 @@ func extractXMLEntries(xml string) []XMLEntry {
 
 	for _, part := range parts {
 		entry := XMLEntry{
-			Title:   extractBetween(part, "<title>", "</title>"),
-			URL:     extractBetween(part, `<id>`, `</id>`),
-			Summary: extractBetween(part, "<summary>", "</summary>"),
+			Title:     extractBetween(part, "<title>", "</title>"),
+			URL:       extractBetween(part, `<id>`, `</id>`),
+			Summary:   extractBetween(part, "<summary>", "</summary>"),
+			Published: strings.TrimSpace(extractBetween(part, "<published>", "</published>")),
 		}
 
 		if entry.Title != "" && entry.URL != "" {
 			// Clean up
 			entry.Title = cleanXMLText(entry.Title)
 			entry.Summary = cleanXMLText(entry.Summary)
-			if len(entry.Summary) > 300 {
-				entry.Summary = entry.Summary[:300] + "..."
-			}
+			entry.Summary = utils.TruncateRunesWithEllipsis(entry.Summary, 300)
 			entries = append(entries, entry)
 		}
 	}
@@ -112,6 +138,7 @@ func extractXMLEntries(xml string) []XMLEntry {
 
 // Google Scholar scraping (limited)
 func (s *AcademicScraper) SearchGoogleScholar(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "google_scholar", time.Now())
 	log.Printf("🔍 Scraping Google Scholar for: %s", query)
 
 	searchURL := fmt.Sprintf("https://scholar.google.com/scholar?q=%s&hl=en",
@@ -119,7 +146,6 @@ func (s *AcademicScraper) SearchGoogleScholar(ctx context.Context, query string,
 
 	resp, err := s.client.R().
 		SetContext(ctx).
-		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36").
 		Get(searchURL)
 	if err != nil {
 		return nil, fmt.Errorf("scholar request failed: %w", err)
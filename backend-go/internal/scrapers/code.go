@@ -0,0 +1,368 @@
+package scrapers
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	// codeContentLimit keeps whole code snippets of answers and issues, in runes
+	codeContentLimit = 1500
+	// githubMaxTerms bounds GitHub queries, which match only items with every term
+	githubMaxTerms = 5
+)
+
+// CodeScraper searches GitHub (repositories, issues, commits) and Stack
+// Exchange sites through their public APIs. Both work without credentials;
+// a token or key raises the rate limits.
+type CodeScraper struct {
+	github           *resty.Client
+	stackExchange    *resty.Client
+	stackExchangeKey string
+}
+
+func NewCodeScraper(githubToken, stackExchangeKey string) *CodeScraper {
+	github := resty.New()
+	github.SetTimeout(10 * time.Second)
+	github.SetBaseURL("https://api.github.com")
+	github.SetHeader("Accept", "application/vnd.github+json")
+	github.SetHeader("X-GitHub-Api-Version", "2022-11-28")
+	if githubToken != "" {
+		github.SetAuthToken(githubToken)
+	}
+
+	stackExchange := resty.New()
+	stackExchange.SetTimeout(10 * time.Second)
+	stackExchange.SetBaseURL("https://api.stackexchange.com/2.3")
+
+	return &CodeScraper{
+		github:           tools.Polite(github, "api.github.com"),
+		stackExchange:    tools.Polite(stackExchange, "api.stackexchange.com"),
+		stackExchangeKey: stackExchangeKey,
+	}
+}
+
+type githubRepoSearch struct {
+	Items []struct {
+		FullName    string `json:"full_name"`
+		HTMLURL     string `json:"html_url"`
+		Description string `json:"description"`
+		Stars       int    `json:"stargazers_count"`
+		Language    string `json:"language"`
+		PushedAt    string `json:"pushed_at"`
+	} `json:"items"`
+}
+
+type githubIssueSearch struct {
+	Items []struct {
+		Title         string    `json:"title"`
+		HTMLURL       string    `json:"html_url"`
+		Number        int       `json:"number"`
+		State         string    `json:"state"`
+		Body          string    `json:"body"`
+		Comments      int       `json:"comments"`
+		RepositoryURL string    `json:"repository_url"`
+		UpdatedAt     string    `json:"updated_at"`
+		PullRequest   *struct{} `json:"pull_request"`
+	} `json:"items"`
+}
+
+type githubCommitSearch struct {
+	Items []struct {
+		SHA     string `json:"sha"`
+		HTMLURL string `json:"html_url"`
+		Commit  struct {
+			Message string `json:"message"`
+			Author  struct {
+				Date string `json:"date"`
+			} `json:"author"`
+		} `json:"commit"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	} `json:"items"`
+}
+
+// SearchGitHubRepos finds repositories by name, description and topics
+func (s *CodeScraper) SearchGitHubRepos(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "github_repos", time.Now())
+	log.Printf("🔍 Searching GitHub repositories for: %s", query)
+
+	var search githubRepoSearch
+	if err := s.githubSearch(ctx, "repositories", githubQuery(query), limit, &search); err != nil {
+		return nil, fmt.Errorf("github repository search failed: %w", err)
+	}
+
+	results := make([]models.TavilyResult, 0, len(search.Items))
+	for i, repo := range search.Items {
+		details := fmt.Sprintf("★ %d", repo.Stars)
+		if repo.Language != "" {
+			details += ", " + repo.Language
+		}
+		content := strings.TrimSpace(repo.Description)
+		if content == "" {
+			content = repo.FullName
+		}
+		results = append(results, models.TavilyResult{
+			Title:       fmt.Sprintf("[GitHub] %s (%s)", repo.FullName, details),
+			URL:         repo.HTMLURL,
+			Content:     content,
+			Score:       0.85 - float64(i)*0.03,
+			PublishedAt: tools.ParsePublishedDate(repo.PushedAt),
+		})
+	}
+
+	log.Printf("✅ Found %d GitHub repositories", len(results))
+	return results, nil
+}
+
+// SearchGitHubIssues finds issues and pull requests, whose discussions often
+// hold the workaround for a bug
+func (s *CodeScraper) SearchGitHubIssues(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "github_issues", time.Now())
+	log.Printf("🔍 Searching GitHub issues for: %s", query)
+
+	var search githubIssueSearch
+	if err := s.githubSearch(ctx, "issues", githubQuery(query), limit, &search); err != nil {
+		return nil, fmt.Errorf("github issue search failed: %w", err)
+	}
+
+	results := make([]models.TavilyResult, 0, len(search.Items))
+	for i, issue := range search.Items {
+		kind := "issue"
+		if issue.PullRequest != nil {
+			kind = "PR"
+		}
+		repo := strings.TrimPrefix(issue.RepositoryURL, "https://api.github.com/repos/")
+		content := fmt.Sprintf("%s#%d, %s %s, %d comments", repo, issue.Number, issue.State, kind, issue.Comments)
+		if body := strings.TrimSpace(issue.Body); body != "" {
+			content += "\n" + utils.TruncateRunesWithEllipsis(body, codeContentLimit)
+		}
+		results = append(results, models.TavilyResult{
+			Title:       fmt.Sprintf("[GitHub %s] %s", kind, issue.Title),
+			URL:         issue.HTMLURL,
+			Content:     content,
+			Score:       0.85 - float64(i)*0.03,
+			PublishedAt: tools.ParsePublishedDate(issue.UpdatedAt),
+		})
+	}
+
+	log.Printf("✅ Found %d GitHub issues", len(results))
+	return results, nil
+}
+
+// SearchGitHubCommits finds commits by message, linking to the commit itself
+func (s *CodeScraper) SearchGitHubCommits(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "github_commits", time.Now())
+	log.Printf("🔍 Searching GitHub commits for: %s", query)
+
+	var search githubCommitSearch
+	if err := s.githubSearch(ctx, "commits", githubQuery(query), limit, &search); err != nil {
+		return nil, fmt.Errorf("github commit search failed: %w", err)
+	}
+
+	results := make([]models.TavilyResult, 0, len(search.Items))
+	for i, commit := range search.Items {
+		message := strings.TrimSpace(commit.Commit.Message)
+		subject, _, _ := strings.Cut(message, "\n")
+		results = append(results, models.TavilyResult{
+			Title:       fmt.Sprintf("[GitHub commit] %s@%.7s: %s", commit.Repository.FullName, commit.SHA, subject),
+			URL:         commit.HTMLURL,
+			Content:     utils.TruncateRunesWithEllipsis(message, codeContentLimit),
+			Score:       0.8 - float64(i)*0.03,
+			PublishedAt: tools.ParsePublishedDate(commit.Commit.Author.Date),
+		})
+	}
+
+	log.Printf("✅ Found %d GitHub commits", len(results))
+	return results, nil
+}
+
+func (s *CodeScraper) githubSearch(ctx context.Context, kind, query string, limit int, result any) error {
+	if query == "" {
+		return fmt.Errorf("empty query")
+	}
+	resp, err := s.github.R().
+		SetContext(ctx).
+		SetQueryParams(map[string]string{
+			"q":        query,
+			"per_page": strconv.Itoa(limit),
+		}).
+		SetResult(result).
+		Get("/search/" + kind)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("status %d", resp.StatusCode())
+	}
+	return nil
+}
+
+type stackExchangeQuestions struct {
+	Items []struct {
+		QuestionID int    `json:"question_id"`
+		Title      string `json:"title"`
+		Link       string `json:"link"`
+		Score      int    `json:"score"`
+		IsAnswered bool   `json:"is_answered"`
+	} `json:"items"`
+}
+
+type stackExchangeAnswers struct {
+	Items []struct {
+		AnswerID     int    `json:"answer_id"`
+		QuestionID   int    `json:"question_id"`
+		Score        int    `json:"score"`
+		IsAccepted   bool   `json:"is_accepted"`
+		Body         string `json:"body"`
+		CreationDate int64  `json:"creation_date"`
+	} `json:"items"`
+}
+
+// SearchStackOverflow finds answered questions on a Stack Exchange site
+// ("stackoverflow", "ru.stackoverflow") and returns the best answer of each:
+// the accepted one, otherwise the most voted. Results link to the answer.
+func (s *CodeScraper) SearchStackOverflow(ctx context.Context, query, site string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, site, time.Now())
+	log.Printf("🔍 Searching %s for: %s", site, query)
+
+	var questions stackExchangeQuestions
+	err := s.stackExchangeGet(ctx, "/search/advanced", map[string]string{
+		"q":        query,
+		"site":     site,
+		"order":    "desc",
+		"sort":     "relevance",
+		"answers":  "1",
+		"pagesize": strconv.Itoa(limit),
+	}, &questions)
+	if err != nil {
+		return nil, fmt.Errorf("%s search failed: %w", site, err)
+	}
+	if len(questions.Items) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(questions.Items))
+	for _, q := range questions.Items {
+		ids = append(ids, strconv.Itoa(q.QuestionID))
+	}
+	var answers stackExchangeAnswers
+	err = s.stackExchangeGet(ctx, "/questions/"+strings.Join(ids, ";")+"/answers", map[string]string{
+		"site":     site,
+		"order":    "desc",
+		"sort":     "votes",
+		"filter":   "withbody",
+		"pagesize": "100",
+	}, &answers)
+	if err != nil {
+		return nil, fmt.Errorf("%s answers failed: %w", site, err)
+	}
+
+	// Answers come most voted first; an accepted answer wins over them
+	best := make(map[int]int, len(questions.Items))
+	for i, answer := range answers.Items {
+		j, ok := best[answer.QuestionID]
+		if !ok || (answer.IsAccepted && !answers.Items[j].IsAccepted) {
+			best[answer.QuestionID] = i
+		}
+	}
+
+	host := site + ".com"
+	results := make([]models.TavilyResult, 0, len(questions.Items))
+	for i, q := range questions.Items {
+		j, ok := best[q.QuestionID]
+		if !ok {
+			continue
+		}
+		answer := answers.Items[j]
+		status := fmt.Sprintf("%d votes", answer.Score)
+		if answer.IsAccepted {
+			status = "accepted, " + status
+		}
+		results = append(results, models.TavilyResult{
+			Title:       fmt.Sprintf("[StackOverflow] %s (%s)", html.UnescapeString(q.Title), status),
+			URL:         fmt.Sprintf("https://%s/a/%d", host, answer.AnswerID),
+			Content:     utils.TruncateRunesWithEllipsis(answerText(answer.Body), codeContentLimit),
+			Score:       0.95 - float64(i)*0.03,
+			PublishedAt: answer.CreationDate,
+		})
+	}
+
+	log.Printf("✅ Found %d %s answers", len(results), site)
+	return results, nil
+}
+
+func (s *CodeScraper) stackExchangeGet(ctx context.Context, path string, params map[string]string, result any) error {
+	if s.stackExchangeKey != "" {
+		params["key"] = s.stackExchangeKey
+	}
+	resp, err := s.stackExchange.R().
+		SetContext(ctx).
+		SetQueryParams(params).
+		SetResult(result).
+		Get(path)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("status %d", resp.StatusCode())
+	}
+	return nil
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// answerText converts an answer's HTML body to text, keeping code blocks as
+// fenced Markdown and inline code in backticks
+func answerText(body string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return cleanXMLText(body)
+	}
+	doc.Find("code").Each(func(_ int, s *goquery.Selection) {
+		if s.ParentsFiltered("pre").Length() == 0 {
+			s.SetText("`" + s.Text() + "`")
+		}
+	})
+	doc.Find("pre").Each(func(_ int, s *goquery.Selection) {
+		s.SetText("\n```\n" + strings.TrimRight(s.Text(), "\n") + "\n```\n")
+	})
+	doc.Find("li").PrependHtml("- ")
+	doc.Find("p, li, h1, h2, h3, h4, blockquote").AppendHtml("\n")
+
+	text := blankLines.ReplaceAllString(doc.Text(), "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// githubQuery keeps the first words of the query: GitHub search matches only
+// items containing every term, so long questions find nothing
+func githubQuery(query string) string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(query, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"'?!,;()[]{}`, r)
+	}) {
+		if utf8.RuneCountInString(word) < 2 {
+			continue
+		}
+		terms = append(terms, word)
+		if len(terms) == githubMaxTerms {
+			break
+		}
+	}
+	return strings.Join(terms, " ")
+}
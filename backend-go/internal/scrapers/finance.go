@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
 	"github.com/go-resty/resty/v2"
 )
 
@@ -20,11 +21,12 @@ type FinanceScraper struct {
 func NewFinanceScraper() *FinanceScraper {
 	client := resty.New()
 	client.SetTimeout(15 * time.Second)
-	return &FinanceScraper{client: client}
+	return &FinanceScraper{client: tools.Polite(client)}
 }
 
 // Yahoo Finance scraping
 func (s *FinanceScraper) SearchYahooFinance(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "yahoo_finance", time.Now())
 	log.Printf("🔍 Scraping Yahoo Finance for: %s", query)
 	
 	searchURL := fmt.Sprintf("https://finance.yahoo.com/search?q=%s", 
@@ -32,7 +34,6 @@ func (s *FinanceScraper) SearchYahooFinance(ctx context.Context, query string, l
 	
 	resp, err := s.client.R().
 		SetContext(ctx).
-		SetHeader("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7)").
 		Get(searchURL)
 	
 	if err != nil {
@@ -72,6 +73,7 @@ func (s *FinanceScraper) SearchYahooFinance(ctx context.Context, query string, l
 
 // Investing.com scraping
 func (s *FinanceScraper) SearchInvestingCom(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "investing.com", time.Now())
 	log.Printf("🔍 Scraping Investing.com for: %s", query)
 	
 	searchURL := fmt.Sprintf("https://www.investing.com/search/?q=%s", 
@@ -79,7 +81,6 @@ func (s *FinanceScraper) SearchInvestingCom(ctx context.Context, query string, l
 	
 	resp, err := s.client.R().
 		SetContext(ctx).
-		SetHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)").
 		Get(searchURL)
 	
 	if err != nil {
@@ -119,6 +120,7 @@ func (s *FinanceScraper) SearchInvestingCom(ctx context.Context, query string, l
 
 // MarketWatch scraping
 func (s *FinanceScraper) SearchMarketWatch(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "marketwatch", time.Now())
 	log.Printf("🔍 Scraping MarketWatch for: %s", query)
 	
 	searchURL := fmt.Sprintf("https://www.marketwatch.com/search?q=%s", 
@@ -0,0 +1,212 @@
+package scrapers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/go-resty/resty/v2"
+)
+
+// ratesCacheTTL is short enough to pick up the next day's rates in the morning
+const ratesCacheTTL = time.Hour
+
+// InstantScraper reads the data providers of instant answers: Open-Meteo for
+// weather (no key needed) and the Bank of Russia daily exchange rates
+type InstantScraper struct {
+	client *resty.Client
+
+	mu        sync.Mutex
+	places    map[string]*Place // geocoding results by name and language
+	rates     *ExchangeRates
+	ratesTime time.Time
+}
+
+func NewInstantScraper() *InstantScraper {
+	client := resty.New()
+	client.SetTimeout(3 * time.Second)
+	return &InstantScraper{
+		client: tools.Polite(client, "geocoding-api.open-meteo.com", "api.open-meteo.com", "www.cbr-xml-daily.ru"),
+		places: make(map[string]*Place),
+	}
+}
+
+// Place is a geocoded location
+type Place struct {
+	Name      string  `json:"name"`
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timezone  string  `json:"timezone"`
+}
+
+// Weather is the current weather at a place and its daily forecast, today first
+type Weather struct {
+	Place   Place
+	Current struct {
+		Time                string  `json:"time"`
+		Temperature         float64 `json:"temperature_2m"`
+		ApparentTemperature float64 `json:"apparent_temperature"`
+		Humidity            float64 `json:"relative_humidity_2m"`
+		WindSpeed           float64 `json:"wind_speed_10m"` // m/s
+		WeatherCode         int     `json:"weather_code"`
+	} `json:"current"`
+	Daily struct {
+		Time           []string  `json:"time"`
+		TemperatureMax []float64 `json:"temperature_2m_max"`
+		TemperatureMin []float64 `json:"temperature_2m_min"`
+		WeatherCode    []int     `json:"weather_code"`
+		Precipitation  []float64 `json:"precipitation_probability_max"`
+	} `json:"daily"`
+}
+
+// GeocodePlace finds a place by name in the given language (ru or en).
+// Russian names in an inflected form ("в Москве") are retried without the
+// ending, which the fuzzy geocoder matches as a prefix.
+func (s *InstantScraper) GeocodePlace(ctx context.Context, name, lang string) (*Place, error) {
+	name = strings.TrimSpace(name)
+	key := lang + ":" + strings.ToLower(name)
+	s.mu.Lock()
+	place, ok := s.places[key]
+	s.mu.Unlock()
+	if ok {
+		return place, nil
+	}
+
+	candidates := []string{name}
+	if runes := []rune(name); lang == "ru" && len(runes) > 4 {
+		candidates = append(candidates, string(runes[:len(runes)-1]))
+	}
+	for _, candidate := range candidates {
+		var result struct {
+			Results []Place `json:"results"`
+		}
+		resp, err := s.client.R().
+			SetContext(ctx).
+			SetQueryParams(map[string]string{
+				"name":     candidate,
+				"count":    "1",
+				"language": lang,
+			}).
+			SetResult(&result).
+			Get("https://geocoding-api.open-meteo.com/v1/search")
+		if err != nil {
+			return nil, fmt.Errorf("geocoding request failed: %w", err)
+		}
+		if resp.IsError() {
+			return nil, fmt.Errorf("geocoding failed: status %d", resp.StatusCode())
+		}
+		if len(result.Results) > 0 {
+			place = &result.Results[0]
+			s.mu.Lock()
+			s.places[key] = place
+			s.mu.Unlock()
+			return place, nil
+		}
+	}
+	return nil, fmt.Errorf("place not found: %s", name)
+}
+
+// Weather returns the current weather and a two-day forecast for a place
+func (s *InstantScraper) Weather(ctx context.Context, placeName, lang string) (*Weather, error) {
+	defer tools.TrackSearchTime(ctx, "open_meteo", time.Now())
+	log.Printf("🌤️  Fetching weather for: %s", placeName)
+
+	place, err := s.GeocodePlace(ctx, placeName, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	weather := &Weather{Place: *place}
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetQueryParams(map[string]string{
+			"latitude":        fmt.Sprintf("%.4f", place.Latitude),
+			"longitude":       fmt.Sprintf("%.4f", place.Longitude),
+			"current":         "temperature_2m,apparent_temperature,relative_humidity_2m,wind_speed_10m,weather_code",
+			"daily":           "temperature_2m_max,temperature_2m_min,weather_code,precipitation_probability_max",
+			"wind_speed_unit": "ms",
+			"timezone":        "auto",
+			"forecast_days":   "2",
+		}).
+		SetResult(weather).
+		Get("https://api.open-meteo.com/v1/forecast")
+	if err != nil {
+		return nil, fmt.Errorf("weather request failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("weather request failed: status %d", resp.StatusCode())
+	}
+	weather.Place = *place
+	return weather, nil
+}
+
+// Rate is the ruble price of Nominal units of a currency
+type Rate struct {
+	CharCode string  `json:"CharCode"`
+	Nominal  float64 `json:"Nominal"`
+	Name     string  `json:"Name"`
+	Value    float64 `json:"Value"`
+	Previous float64 `json:"Previous"`
+}
+
+// ExchangeRates are the official Bank of Russia rates of one day
+type ExchangeRates struct {
+	Date   string          `json:"Date"`
+	Valute map[string]Rate `json:"Valute"`
+}
+
+// PerUnit returns the ruble price of one unit of the currency and of the
+// previous day's rate; the ruble itself is 1
+func (r *ExchangeRates) PerUnit(code string) (current, previous float64, ok bool) {
+	if code == "RUB" {
+		return 1, 1, true
+	}
+	rate, ok := r.Valute[code]
+	if !ok || rate.Nominal == 0 {
+		return 0, 0, false
+	}
+	return rate.Value / rate.Nominal, rate.Previous / rate.Nominal, true
+}
+
+// ExchangeRates returns today's Bank of Russia rates, cached for an hour
+func (s *InstantScraper) ExchangeRates(ctx context.Context) (*ExchangeRates, error) {
+	s.mu.Lock()
+	if s.rates != nil && time.Since(s.ratesTime) < ratesCacheTTL {
+		rates := s.rates
+		s.mu.Unlock()
+		return rates, nil
+	}
+	s.mu.Unlock()
+
+	defer tools.TrackSearchTime(ctx, "cbr", time.Now())
+	log.Printf("💱 Fetching Bank of Russia exchange rates")
+
+	// The mirror serves the rates as JSON; its Content-Type is JavaScript
+	resp, err := s.client.R().
+		SetContext(ctx).
+		Get("https://www.cbr-xml-daily.ru/daily_json.js")
+	if err != nil {
+		return nil, fmt.Errorf("exchange rates request failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("exchange rates request failed: status %d", resp.StatusCode())
+	}
+	var rates ExchangeRates
+	if err := s.client.JSONUnmarshal(resp.Body(), &rates); err != nil {
+		return nil, fmt.Errorf("parse exchange rates: %w", err)
+	}
+	if len(rates.Valute) == 0 {
+		return nil, fmt.Errorf("no exchange rates in response")
+	}
+
+	s.mu.Lock()
+	s.rates = &rates
+	s.ratesTime = time.Now()
+	s.mu.Unlock()
+	return &rates, nil
+}
@@ -0,0 +1,306 @@
+package scrapers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/go-resty/resty/v2"
+)
+
+type NewsScraper struct {
+	client *resty.Client
+	feeds  []string
+}
+
+// NewNewsScraper reads Google News search feeds and the given RSS/Atom feeds
+func NewNewsScraper(feeds []string) *NewsScraper {
+	client := resty.New()
+	client.SetTimeout(10 * time.Second)
+	return &NewsScraper{client: tools.Polite(client), feeds: feeds}
+}
+
+// Feeds returns the configured RSS/Atom feed URLs
+func (s *NewsScraper) Feeds() []string {
+	return s.feeds
+}
+
+// newsFeed covers both RSS 2.0 (<rss><channel><item>) and Atom (<feed><entry>)
+type newsFeed struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Source      string `xml:"source"`
+}
+
+type atomEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+// newsItem is a feed item in a common shape
+type newsItem struct {
+	Title       string
+	URL         string
+	Summary     string
+	Source      string
+	PublishedAt int64
+}
+
+// SearchGoogleNews queries the Google News RSS search feed in the given
+// language (ru or en)
+func (s *NewsScraper) SearchGoogleNews(ctx context.Context, query, lang string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "google_news", time.Now())
+	log.Printf("🔍 Searching Google News for: %s", query)
+
+	locale := "hl=ru&gl=RU&ceid=RU:ru"
+	if lang == "en" {
+		locale = "hl=en-US&gl=US&ceid=US:en"
+	}
+	// Google News understands the after: and before: operators of web search
+	if r := tools.DateRangeFromContext(ctx); r != nil {
+		if r.From > 0 {
+			query += " after:" + time.Unix(r.From, 0).UTC().Format(time.DateOnly)
+		}
+		if r.To > 0 {
+			query += " before:" + time.Unix(r.To, 0).UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+		}
+	}
+	searchURL := fmt.Sprintf("https://news.google.com/rss/search?q=%s&%s", url.QueryEscape(query), locale)
+
+	items, err := s.fetchFeed(ctx, searchURL)
+	if err != nil {
+		return nil, fmt.Errorf("google news request failed: %w", err)
+	}
+
+	dateRange := tools.DateRangeFromContext(ctx)
+	results := make([]models.TavilyResult, 0, limit)
+	for i, item := range items {
+		if len(results) >= limit {
+			break
+		}
+		if tools.InDateRange(dateRange, item.PublishedAt) {
+			results = append(results, item.result(0.9-float64(i)*0.03))
+		}
+	}
+
+	log.Printf("✅ Found %d Google News articles", len(results))
+	return results, nil
+}
+
+// SearchFeeds reads the configured feeds concurrently and keeps the items
+// mentioning the query terms, best matches first
+func (s *NewsScraper) SearchFeeds(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "rss", time.Now())
+	if len(s.feeds) == 0 {
+		return nil, nil
+	}
+	log.Printf("🔍 Scanning %d RSS feeds for: %s", len(s.feeds), query)
+
+	terms := newsTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	dateRange := tools.DateRangeFromContext(ctx)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var results []models.TavilyResult
+	var failures int
+	var lastErr error
+	for _, feedURL := range s.feeds {
+		wg.Add(1)
+		go func(feedURL string) {
+			defer wg.Done()
+			items, err := s.fetchFeed(ctx, feedURL)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Printf("⚠️  RSS feed %s failed: %v", feedURL, err)
+				failures++
+				lastErr = err
+				return
+			}
+			for _, item := range items {
+				if !tools.InDateRange(dateRange, item.PublishedAt) {
+					continue
+				}
+				if match := matchTerms(item.Title+" "+item.Summary, terms); match > 0 {
+					results = append(results, item.result(0.6+0.3*match))
+				}
+			}
+		}(feedURL)
+	}
+	wg.Wait()
+
+	if failures == len(s.feeds) {
+		return nil, fmt.Errorf("rss feeds failed: %w", lastErr)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	log.Printf("✅ Found %d matching RSS items", len(results))
+	return results, nil
+}
+
+// fetchFeed downloads and parses an RSS or Atom feed
+func (s *NewsScraper) fetchFeed(ctx context.Context, feedURL string) ([]newsItem, error) {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		Get(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("status %d", resp.StatusCode())
+	}
+
+	var feed newsFeed
+	if err := xml.Unmarshal(resp.Body(), &feed); err != nil {
+		return nil, fmt.Errorf("parse feed: %w", err)
+	}
+
+	items := make([]newsItem, 0, len(feed.Channel.Items)+len(feed.Entries))
+	for _, it := range feed.Channel.Items {
+		source := it.Source
+		if source == "" {
+			source = feed.Channel.Title
+		}
+		items = append(items, newsItem{
+			Title:       cleanXMLText(it.Title),
+			URL:         strings.TrimSpace(it.Link),
+			Summary:     cleanXMLText(it.Description),
+			Source:      strings.TrimSpace(source),
+			PublishedAt: tools.ParsePublishedDate(it.PubDate),
+		})
+	}
+	for _, entry := range feed.Entries {
+		var link string
+		for _, l := range entry.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = l.Href
+				break
+			}
+		}
+		summary := entry.Summary
+		if summary == "" {
+			summary = entry.Content
+		}
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+		items = append(items, newsItem{
+			Title:       cleanXMLText(entry.Title),
+			URL:         strings.TrimSpace(link),
+			Summary:     cleanXMLText(summary),
+			Source:      strings.TrimSpace(feed.Title),
+			PublishedAt: tools.ParsePublishedDate(published),
+		})
+	}
+
+	// Drop items without a title or link
+	valid := items[:0]
+	for _, item := range items {
+		if item.Title != "" && item.URL != "" {
+			valid = append(valid, item)
+		}
+	}
+	return valid, nil
+}
+
+func (item newsItem) result(score float64) models.TavilyResult {
+	title := item.Title
+	if item.Source != "" && !strings.Contains(title, item.Source) {
+		title = fmt.Sprintf("[%s] %s", item.Source, title)
+	}
+	content := item.Summary
+	if content == "" {
+		content = item.Title
+	}
+	content = utils.TruncateRunesWithEllipsis(content, 500)
+	return models.TavilyResult{
+		Title:       title,
+		URL:         item.URL,
+		Content:     content,
+		Snippet:     content,
+		Score:       score,
+		PublishedAt: item.PublishedAt,
+		FetchedAt:   time.Now().Unix(),
+	}
+}
+
+// newsStopwords are recency words that say nothing about the topic
+var newsStopwords = map[string]bool{
+	"news": true, "latest": true, "today": true, "breaking": true, "update": true,
+	"updates": true, "about": true, "what": true, "with": true, "this": true, "week": true,
+	"новости": true, "новостей": true, "последние": true, "свежие": true, "сегодня": true,
+	"сейчас": true, "что": true, "про": true, "какие": true, "неделе": true, "этой": true,
+}
+
+// newsTerms returns the topic words of the query
+func newsTerms(query string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(word) >= 3 && !newsStopwords[word] {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// matchTerms returns the share of terms found in text, or 0 when fewer than
+// half match. Terms match as prefixes of stems so inflected forms count.
+func matchTerms(text string, terms []string) float64 {
+	text = strings.ToLower(text)
+	matched := 0
+	for _, term := range terms {
+		stem := term
+		if n := utf8.RuneCountInString(term); n > 5 {
+			stem = string([]rune(term)[:n-2])
+		}
+		if strings.Contains(text, stem) {
+			matched++
+		}
+	}
+	share := float64(matched) / float64(len(terms))
+	if matched == 0 || share < 0.5 {
+		return 0
+	}
+	return share
+}
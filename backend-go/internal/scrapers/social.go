@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 	"github.com/go-resty/resty/v2"
 )
 
@@ -20,17 +22,22 @@ type SocialScraper struct {
 func NewSocialScraper() *SocialScraper {
 	client := resty.New()
 	client.SetTimeout(15 * time.Second)
-	client.SetHeader("User-Agent", "Mozilla/5.0 (compatible; ResearchBot/1.0)")
-	return &SocialScraper{client: client}
+	return &SocialScraper{client: tools.Polite(client)}
 }
 
 // Reddit scraping (без API)
 func (s *SocialScraper) SearchReddit(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "reddit", time.Now())
 	log.Printf("🔍 Scraping Reddit for: %s", query)
 	
 	// Use old.reddit.com for easier parsing
-	searchURL := fmt.Sprintf("https://old.reddit.com/search?q=%s&sort=relevance&t=all", 
-		url.QueryEscape(query))
+	// Reddit only filters by a window ending now
+	window := tools.RecencyWindow(tools.DateRangeFromContext(ctx))
+	if window == "" {
+		window = "all"
+	}
+	searchURL := fmt.Sprintf("https://old.reddit.com/search?q=%s&sort=relevance&t=%s",
+		url.QueryEscape(query), window)
 	
 	resp, err := s.client.R().
 		SetContext(ctx).
@@ -74,6 +81,7 @@ func (s *SocialScraper) SearchReddit(ctx context.Context, query string, limit in
 
 // Habr scraping
 func (s *SocialScraper) SearchHabr(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "habr", time.Now())
 	log.Printf("🔍 Scraping Habr for: %s", query)
 	
 	searchURL := fmt.Sprintf("https://habr.com/ru/search/?q=%s&target_type=posts", 
@@ -112,9 +120,7 @@ func (s *SocialScraper) SearchHabr(ctx context.Context, query string, limit int)
 		snippet := title
 		if i < len(snippetMatches) && len(snippetMatches[i]) > 1 {
 			snippet = snippetMatches[i][1]
-			if len(snippet) > 200 {
-				snippet = snippet[:200]
-			}
+			snippet = utils.TruncateRunes(snippet, 200)
 		}
 		
 		results = append(results, models.TavilyResult{
@@ -131,6 +137,7 @@ func (s *SocialScraper) SearchHabr(ctx context.Context, query string, limit int)
 
 // X/Twitter scraping (limited without API)
 func (s *SocialScraper) SearchTwitter(ctx context.Context, query string, limit int) ([]models.TavilyResult, error) {
+	defer tools.TrackSearchTime(ctx, "twitter", time.Now())
 	log.Printf("🔍 Scraping Nitter (Twitter mirror) for: %s", query)
 	
 	// Use Nitter instance (Twitter frontend without JS)
@@ -157,12 +164,10 @@ func (s *SocialScraper) SearchTwitter(ctx context.Context, query string, limit i
 		}
 		
 		content := matches[i][1]
-		if len(content) > 200 {
-			content = content[:200]
-		}
+		content = utils.TruncateRunes(content, 200)
 		
 		results = append(results, models.TavilyResult{
-			Title:   fmt.Sprintf("Twitter discussion: %s", truncate(content, 50)),
+			Title:   fmt.Sprintf("Twitter discussion: %s", utils.TruncateRunesWithEllipsis(content, 50)),
 			URL:     searchURL,
 			Content: content,
 			Score:   0.7 - float64(i)*0.05,
@@ -172,10 +177,3 @@ func (s *SocialScraper) SearchTwitter(ctx context.Context, query string, limit i
 	log.Printf("✅ Found %d Twitter results", len(results))
 	return results, nil
 }
-
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen] + "..."
-}
\ No newline at end of file
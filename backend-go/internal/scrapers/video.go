@@ -0,0 +1,299 @@
+package scrapers
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/tools"
+	"github.com/go-resty/resty/v2"
+)
+
+// Video is a YouTube search result
+type Video struct {
+	ID          string
+	Title       string
+	Channel     string
+	Description string
+	PublishedAt int64 // unix seconds, 0 when unknown
+}
+
+// TranscriptSegment is a caption line or a window of them
+type TranscriptSegment struct {
+	Start time.Duration
+	Text  string
+}
+
+// videoRendererPattern reads video IDs and titles from the ytInitialData of a
+// YouTube results page
+var videoRendererPattern = regexp.MustCompile(`"videoRenderer":\{"videoId":"([\w-]{11})".*?"title":\{"runs":\[\{"text":"((?:[^"\\]|\\.)*)"`)
+
+// VideoScraper finds YouTube videos and reads their captions. Search uses the
+// YouTube Data API with a key and the results page otherwise; captions come
+// from the caption tracks of the watch page.
+type VideoScraper struct {
+	client *resty.Client
+	apiKey string
+}
+
+func NewVideoScraper(youtubeAPIKey string) *VideoScraper {
+	client := resty.New()
+	client.SetTimeout(10 * time.Second)
+	client.SetHeader("Accept-Language", "en-US,en;q=0.9,ru;q=0.8")
+	return &VideoScraper{client: tools.Polite(client, "www.googleapis.com"), apiKey: youtubeAPIKey}
+}
+
+// WatchURL links to a video at a moment of it
+func WatchURL(videoID string, at time.Duration) string {
+	link := "https://www.youtube.com/watch?v=" + videoID
+	if seconds := int(at.Seconds()); seconds > 0 {
+		link += fmt.Sprintf("&t=%ds", seconds)
+	}
+	return link
+}
+
+// SearchYouTube finds videos for the query, preferring those in lang (ru or en)
+func (s *VideoScraper) SearchYouTube(ctx context.Context, query, lang string, limit int) ([]Video, error) {
+	defer tools.TrackSearchTime(ctx, "youtube", time.Now())
+	log.Printf("🔍 Searching YouTube for: %s", query)
+
+	var videos []Video
+	var err error
+	if s.apiKey != "" {
+		videos, err = s.searchAPI(ctx, query, lang, limit)
+	} else {
+		videos, err = s.searchPage(ctx, query, lang, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Found %d YouTube videos", len(videos))
+	return videos, nil
+}
+
+type youtubeSearch struct {
+	Items []struct {
+		ID struct {
+			VideoID string `json:"videoId"`
+		} `json:"id"`
+		Snippet struct {
+			Title        string `json:"title"`
+			Description  string `json:"description"`
+			ChannelTitle string `json:"channelTitle"`
+			PublishedAt  string `json:"publishedAt"`
+		} `json:"snippet"`
+	} `json:"items"`
+}
+
+// searchAPI uses the search endpoint of the YouTube Data API, which also
+// filters by the period of the query
+func (s *VideoScraper) searchAPI(ctx context.Context, query, lang string, limit int) ([]Video, error) {
+	params := map[string]string{
+		"part":              "snippet",
+		"type":              "video",
+		"q":                 query,
+		"maxResults":        strconv.Itoa(limit),
+		"relevanceLanguage": lang,
+		"key":               s.apiKey,
+	}
+	if r := tools.DateRangeFromContext(ctx); r != nil {
+		if r.From > 0 {
+			params["publishedAfter"] = time.Unix(r.From, 0).UTC().Format(time.RFC3339)
+		}
+		if r.To > 0 {
+			params["publishedBefore"] = time.Unix(r.To, 0).UTC().Format(time.RFC3339)
+		}
+	}
+
+	var search youtubeSearch
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetQueryParams(params).
+		SetResult(&search).
+		Get("https://www.googleapis.com/youtube/v3/search")
+	if err != nil {
+		return nil, fmt.Errorf("youtube api request failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("youtube api returned status %d", resp.StatusCode())
+	}
+
+	videos := make([]Video, 0, len(search.Items))
+	for _, item := range search.Items {
+		if item.ID.VideoID == "" {
+			continue
+		}
+		videos = append(videos, Video{
+			ID:          item.ID.VideoID,
+			Title:       html.UnescapeString(item.Snippet.Title),
+			Channel:     html.UnescapeString(item.Snippet.ChannelTitle),
+			Description: html.UnescapeString(item.Snippet.Description),
+			PublishedAt: tools.ParsePublishedDate(item.Snippet.PublishedAt),
+		})
+	}
+	return videos, nil
+}
+
+// searchPage reads the results page, which needs no key but gives titles only
+func (s *VideoScraper) searchPage(ctx context.Context, query, lang string, limit int) ([]Video, error) {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetQueryParams(map[string]string{"search_query": query, "hl": lang}).
+		Get("https://www.youtube.com/results")
+	if err != nil {
+		return nil, fmt.Errorf("youtube request failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("youtube returned status %d", resp.StatusCode())
+	}
+
+	seen := make(map[string]bool)
+	videos := make([]Video, 0, limit)
+	for _, m := range videoRendererPattern.FindAllStringSubmatch(resp.String(), -1) {
+		if len(videos) >= limit {
+			break
+		}
+		if seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		videos = append(videos, Video{ID: m[1], Title: unquoteJSON(m[2])})
+	}
+	return videos, nil
+}
+
+type captionTrack struct {
+	BaseURL      string `json:"baseUrl"`
+	LanguageCode string `json:"languageCode"`
+	Kind         string `json:"kind"` // "asr" for automatic captions
+}
+
+type timedText struct {
+	Texts []struct {
+		Start string `xml:"start,attr"`
+		Text  string `xml:",chardata"`
+	} `xml:"text"`
+}
+
+// Transcript returns the captions of a video, in lang when the video has
+// them, else in English or its first language. Written captions are
+// preferred over automatic ones.
+func (s *VideoScraper) Transcript(ctx context.Context, videoID, lang string) ([]TranscriptSegment, error) {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetQueryParams(map[string]string{"v": videoID, "hl": lang}).
+		Get("https://www.youtube.com/watch")
+	if err != nil {
+		return nil, fmt.Errorf("youtube watch page request failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("youtube watch page returned status %d", resp.StatusCode())
+	}
+
+	page := resp.String()
+	i := strings.Index(page, `"captionTracks":`)
+	if i == -1 {
+		return nil, fmt.Errorf("video %s has no captions", videoID)
+	}
+	var tracks []captionTrack
+	if err := json.NewDecoder(strings.NewReader(page[i+len(`"captionTracks":`):])).Decode(&tracks); err != nil {
+		return nil, fmt.Errorf("invalid caption tracks: %w", err)
+	}
+	track, ok := pickCaptionTrack(tracks, lang)
+	if !ok {
+		return nil, fmt.Errorf("video %s has no captions", videoID)
+	}
+
+	resp, err = s.client.R().SetContext(ctx).Get(track.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("captions request failed: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("captions returned status %d", resp.StatusCode())
+	}
+
+	var captions timedText
+	if err := xml.Unmarshal(resp.Body(), &captions); err != nil {
+		return nil, fmt.Errorf("invalid captions: %w", err)
+	}
+	segments := make([]TranscriptSegment, 0, len(captions.Texts))
+	for _, t := range captions.Texts {
+		start, err := strconv.ParseFloat(t.Start, 64)
+		if err != nil {
+			continue
+		}
+		// Caption text is HTML escaped inside the XML escaping
+		text := strings.Join(strings.Fields(html.UnescapeString(t.Text)), " ")
+		if text == "" {
+			continue
+		}
+		segments = append(segments, TranscriptSegment{
+			Start: time.Duration(start * float64(time.Second)),
+			Text:  text,
+		})
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("video %s has empty captions", videoID)
+	}
+	return segments, nil
+}
+
+// pickCaptionTrack prefers lang, then English, then the first track; written
+// captions before automatic ones of the same language
+func pickCaptionTrack(tracks []captionTrack, lang string) (captionTrack, bool) {
+	for _, code := range []string{lang, "en"} {
+		for _, automatic := range []bool{false, true} {
+			for _, t := range tracks {
+				if t.BaseURL != "" && strings.HasPrefix(t.LanguageCode, code) && (t.Kind == "asr") == automatic {
+					return t, true
+				}
+			}
+		}
+	}
+	for _, t := range tracks {
+		if t.BaseURL != "" {
+			return t, true
+		}
+	}
+	return captionTrack{}, false
+}
+
+// TranscriptWindows joins caption lines into windows of about span, each
+// starting at its first line
+func TranscriptWindows(segments []TranscriptSegment, span time.Duration) []TranscriptSegment {
+	var windows []TranscriptSegment
+	var text strings.Builder
+	var start time.Duration
+	for i, segment := range segments {
+		if text.Len() == 0 {
+			start = segment.Start
+		} else {
+			text.WriteString(" ")
+		}
+		text.WriteString(segment.Text)
+
+		last := i == len(segments)-1
+		if last || segments[i+1].Start-start >= span {
+			windows = append(windows, TranscriptSegment{Start: start, Text: text.String()})
+			text.Reset()
+		}
+	}
+	return windows
+}
+
+// unquoteJSON decodes the escapes of a JSON string body
+func unquoteJSON(s string) string {
+	var out string
+	if err := json.Unmarshal([]byte(`"`+s+`"`), &out); err != nil {
+		return s
+	}
+	return out
+}
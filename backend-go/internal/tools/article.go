@@ -0,0 +1,161 @@
+package tools
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Readability-style article extraction: paragraphs vote for the containers
+// they are in, and the container with the most prose, least links and the
+// most article-like class wins. Pages whose layout beats the scoring fall
+// back to <article>, <main> or <body>.
+
+var (
+	// articleHints and boilerplateHints are read from the class and id of
+	// elements
+	articleHints     = regexp.MustCompile(`(?i)article|body|content|entry|main|news|post|story|text|blog`)
+	boilerplateHints = regexp.MustCompile(`(?i)comment|sidebar|related|share|social|promo|advert|\bads?\b|banner|cookie|consent|newsletter|subscribe|breadcrumb|footer|masthead|menu|popup|modal|widget|sponsor|recommend`)
+	// titleSeparators split the site name off a <title>
+	titleSeparators = regexp.MustCompile(`\s+[|–—·-]\s+`)
+)
+
+const (
+	// minArticleText is the text a scored container must have to be trusted
+	minArticleText = 500
+	// minParagraphText is the shortest paragraph that votes
+	minParagraphText = 25
+	// maxLinkDensity drops blocks that are mostly links (menus, tag lists)
+	maxLinkDensity = 0.5
+)
+
+// articleRoot returns the element holding the main text of the page
+func articleRoot(doc *goquery.Document) *goquery.Selection {
+	// Boilerplate marked by class or id, except for the containers of the page
+	doc.Find("div, section, ul, ol, table, span").Each(func(_ int, s *goquery.Selection) {
+		hints := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if boilerplateHints.MatchString(hints) && !articleHints.MatchString(hints) {
+			s.Remove()
+		}
+	})
+
+	type articleCandidate struct {
+		s     *goquery.Selection
+		score float64
+	}
+	var candidates []*articleCandidate
+	byNode := make(map[any]*articleCandidate)
+	candidate := func(s *goquery.Selection) *articleCandidate {
+		node := s.Get(0)
+		if c, ok := byNode[node]; ok {
+			return c
+		}
+		c := &articleCandidate{s: s, score: containerWeight(s)}
+		byNode[node] = c
+		candidates = append(candidates, c)
+		return c
+	}
+
+	doc.Find("p, pre, td, blockquote").Each(func(_ int, p *goquery.Selection) {
+		text := strings.Join(strings.Fields(p.Text()), " ")
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphText {
+			return
+		}
+		// Long paragraphs with commas are prose, not captions or buttons
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "،")) + min(float64(length)/100, 3)
+
+		if parent := p.Parent(); parent.Length() > 0 && !parent.Is("body, html") {
+			candidate(parent).score += score
+			if grandparent := parent.Parent(); grandparent.Length() > 0 && !grandparent.Is("body, html") {
+				candidate(grandparent).score += score / 2
+			}
+		}
+	})
+
+	var best *goquery.Selection
+	var bestScore float64
+	for _, c := range candidates {
+		score := c.score * (1 - linkDensity(c.s))
+		if best == nil || score > bestScore {
+			best, bestScore = c.s, score
+		}
+	}
+	if best != nil && utf8.RuneCountInString(strings.Join(strings.Fields(best.Text()), " ")) >= minArticleText {
+		return best
+	}
+
+	root := doc.Find("article").First()
+	if root.Length() == 0 {
+		root = doc.Find("main").First()
+	}
+	if root.Length() == 0 {
+		root = doc.Find("body")
+	}
+	return root
+}
+
+// containerWeight is the initial score of a container from its tag, class
+// and id
+func containerWeight(s *goquery.Selection) float64 {
+	var weight float64
+	switch {
+	case s.Is("article"):
+		weight = 10
+	case s.Is("main, div"):
+		weight = 5
+	case s.Is("pre, td, blockquote"):
+		weight = 3
+	case s.Is("ol, ul, dl, form, li"):
+		weight = -3
+	case s.Is("h1, h2, h3, h4, h5, h6, th"):
+		weight = -5
+	}
+	hints := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+	if articleHints.MatchString(hints) {
+		weight += 25
+	}
+	if boilerplateHints.MatchString(hints) {
+		weight -= 25
+	}
+	return weight
+}
+
+// linkDensity is the share of the text of s inside links
+func linkDensity(s *goquery.Selection) float64 {
+	length := utf8.RuneCountInString(strings.Join(strings.Fields(s.Text()), " "))
+	if length == 0 {
+		return 0
+	}
+	var links int
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		links += utf8.RuneCountInString(strings.Join(strings.Fields(a.Text()), " "))
+	})
+	return min(float64(links)/float64(length), 1)
+}
+
+// articleTitle returns the headline of the page: the Open Graph title, the
+// single <h1>, or the <title> without the site name
+func articleTitle(doc *goquery.Document) string {
+	if title := strings.TrimSpace(metaContent(doc, "og:title", "twitter:title")); title != "" {
+		return title
+	}
+	if h1 := doc.Find("h1"); h1.Length() == 1 {
+		if title := strings.Join(strings.Fields(h1.Text()), " "); title != "" {
+			return title
+		}
+	}
+	title := strings.Join(strings.Fields(doc.Find("title").First().Text()), " ")
+	// "Headline | Site" and "Headline - Site": the longest part is the headline
+	if parts := titleSeparators.Split(title, -1); len(parts) > 1 {
+		title = parts[0]
+		for _, part := range parts[1:] {
+			if utf8.RuneCountInString(part) > utf8.RuneCountInString(title) {
+				title = part
+			}
+		}
+	}
+	return title
+}
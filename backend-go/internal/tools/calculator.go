@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// calculatorFunctions are the functions Calculate knows, by name
+var calculatorFunctions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"abs":   math.Abs,
+	"ln":    math.Log,
+	"log":   math.Log10,
+	"log10": math.Log10,
+	"exp":   math.Exp,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"round": math.Round,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+}
+
+// Calculate evaluates an arithmetic expression: numbers, + - * / % ^,
+// parentheses, the constants pi and e and the functions of
+// calculatorFunctions, e.g. "sqrt(2) * (3.5 + 1e3) ^ 2"
+func Calculate(expression string) (float64, error) {
+	p := &calcParser{input: strings.ReplaceAll(expression, " ", "")}
+	value, err := p.expression()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos:], p.pos)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return value, nil
+}
+
+// calcParser is a recursive descent parser over
+//
+//	expression = term {("+" | "-") term}
+//	term       = unary {("*" | "/" | "%") unary}
+//	unary      = ("-" | "+") unary | power
+//	power      = primary ["^" unary]
+//	primary    = number | constant | function "(" expression ")" | "(" expression ")"
+type calcParser struct {
+	input string
+	pos   int
+}
+
+func (p *calcParser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *calcParser) expression() (float64, error) {
+	value, err := p.term()
+	for err == nil && (p.peek() == '+' || p.peek() == '-') {
+		op := p.peek()
+		p.pos++
+		var right float64
+		if right, err = p.term(); err == nil {
+			if op == '+' {
+				value += right
+			} else {
+				value -= right
+			}
+		}
+	}
+	return value, err
+}
+
+func (p *calcParser) term() (float64, error) {
+	value, err := p.unary()
+	for err == nil && (p.peek() == '*' || p.peek() == '/' || p.peek() == '%') {
+		op := p.peek()
+		p.pos++
+		var right float64
+		if right, err = p.unary(); err != nil {
+			break
+		}
+		switch {
+		case op == '*':
+			value *= right
+		case right == 0:
+			err = fmt.Errorf("division by zero")
+		case op == '/':
+			value /= right
+		default:
+			value = math.Mod(value, right)
+		}
+	}
+	return value, err
+}
+
+func (p *calcParser) power() (float64, error) {
+	base, err := p.primary()
+	if err != nil || p.peek() != '^' {
+		return base, err
+	}
+	p.pos++
+	exponent, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+func (p *calcParser) unary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.unary()
+		return -value, err
+	case '+':
+		p.pos++
+		return p.unary()
+	}
+	return p.power()
+}
+
+func (p *calcParser) primary() (float64, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		value, err := p.expression()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing ) at position %d", p.pos)
+		}
+		p.pos++
+		return value, nil
+	case c >= '0' && c <= '9' || c == '.':
+		return p.number()
+	case unicode.IsLetter(rune(c)):
+		start := p.pos
+		for c := p.peek(); unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)); c = p.peek() {
+			p.pos++
+		}
+		name := strings.ToLower(p.input[start:p.pos])
+		switch name {
+		case "pi":
+			return math.Pi, nil
+		case "e":
+			return math.E, nil
+		}
+		fn, ok := calculatorFunctions[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown name %q", name)
+		}
+		if p.peek() != '(' {
+			return 0, fmt.Errorf("missing ( after %s", name)
+		}
+		argument, err := p.primary()
+		if err != nil {
+			return 0, err
+		}
+		return fn(argument), nil
+	case c == 0:
+		return 0, fmt.Errorf("unexpected end of expression")
+	default:
+		return 0, fmt.Errorf("unexpected %q at position %d", c, p.pos)
+	}
+}
+
+// number reads a decimal number with an optional exponent (1.5e-3)
+func (p *calcParser) number() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	if c := p.peek(); (c == 'e' || c == 'E') && p.pos+1 < len(p.input) {
+		next := p.pos + 1
+		if p.input[next] == '+' || p.input[next] == '-' {
+			next++
+		}
+		if next < len(p.input) && p.input[next] >= '0' && p.input[next] <= '9' {
+			p.pos = next
+			for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+				p.pos++
+			}
+		}
+	}
+	value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+	}
+	return value, nil
+}
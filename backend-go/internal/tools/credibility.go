@@ -2,6 +2,7 @@ package tools
 
 import (
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,7 +35,7 @@ func (c *CredibilityScorer) ScoreSource(source models.TavilyResult) float64 {
 	score += urlScore * 0.1
 
 	// 5. Freshness (10% веса)
-	freshnessScore := c.scoreFreshness(source.URL)
+	freshnessScore := c.scoreFreshness(source)
 	score += freshnessScore * 0.1
 
 	// Нормализация в диапазон 0-1
@@ -215,12 +216,29 @@ func (c *CredibilityScorer) scoreURL(urlStr string) float64 {
 }
 
 // scoreFreshness оценивает свежесть контента (если можно определить)
-func (c *CredibilityScorer) scoreFreshness(urlStr string) float64 {
+func (c *CredibilityScorer) scoreFreshness(source models.TavilyResult) float64 {
+	// Дата публикации из метаданных страницы
+	if source.PublishedAt > 0 {
+		ageDays := time.Since(time.Unix(source.PublishedAt, 0)).Hours() / 24
+		switch {
+		case ageDays <= 30:
+			return 1.0
+		case ageDays <= 365:
+			return 0.9
+		case ageDays <= 2*365:
+			return 0.8
+		case ageDays <= 5*365:
+			return 0.6
+		default:
+			return 0.4
+		}
+	}
+
 	// Простая эвристика - проверяем наличие года в URL
 	currentYear := time.Now().Year()
 	
 	for year := currentYear; year >= currentYear-5; year-- {
-		if strings.Contains(urlStr, string(rune(year))) {
+		if strings.Contains(source.URL, strconv.Itoa(year)) {
 			yearsOld := currentYear - year
 			// Свежие источники (0-2 года) = 1.0
 			// Старые (3-5 лет) = 0.5-0.8
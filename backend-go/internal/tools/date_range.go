@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+type dateRangeKey struct{}
+
+// WithDateRange restricts the searches made with ctx to a period. Like the
+// token meter it travels in the context, so the search client and the
+// scrapers apply it without being passed anything.
+func WithDateRange(ctx context.Context, r *models.DateRange) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, dateRangeKey{}, r)
+}
+
+// DateRangeFromContext returns the period searches are restricted to, if any
+func DateRangeFromContext(ctx context.Context) *models.DateRange {
+	r, _ := ctx.Value(dateRangeKey{}).(*models.DateRange)
+	return r
+}
+
+// InDateRange reports whether a publication time (unix seconds) falls within
+// r; undated results (0) are kept
+func InDateRange(r *models.DateRange, publishedAt int64) bool {
+	if r == nil || publishedAt == 0 {
+		return true
+	}
+	if r.From > 0 && publishedAt < r.From {
+		return false
+	}
+	if r.To > 0 && publishedAt > r.To {
+		return false
+	}
+	return true
+}
+
+// FilterByDateRange drops the results published outside the period of ctx
+func FilterByDateRange(ctx context.Context, results []models.TavilyResult) []models.TavilyResult {
+	r := DateRangeFromContext(ctx)
+	if r == nil {
+		return results
+	}
+	kept := make([]models.TavilyResult, 0, len(results))
+	for _, result := range results {
+		if InDateRange(r, result.PublishedAt) {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// braveFreshness is the Brave Search freshness filter for a period,
+// "YYYY-MM-DDtoYYYY-MM-DD"; "" without a period
+func braveFreshness(r *models.DateRange) string {
+	if r == nil {
+		return ""
+	}
+	from := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	if r.From > 0 {
+		from = time.Unix(r.From, 0).UTC()
+	}
+	to := time.Now().UTC()
+	if r.To > 0 {
+		to = time.Unix(r.To, 0).UTC()
+	}
+	return from.Format(time.DateOnly) + "to" + to.Format(time.DateOnly)
+}
+
+// RecencyWindow maps a period ending now onto the coarse windows of search
+// engines: day, week, month or year. It is "" for periods in the past and
+// for periods longer than a year.
+func RecencyWindow(r *models.DateRange) string {
+	if r == nil || r.From == 0 {
+		return ""
+	}
+	now := time.Now()
+	if r.To > 0 && now.Sub(time.Unix(r.To, 0)) > 24*time.Hour {
+		return ""
+	}
+	switch age := now.Sub(time.Unix(r.From, 0)); {
+	case age <= 24*time.Hour+time.Minute:
+		return "day"
+	case age <= 7*24*time.Hour+time.Minute:
+		return "week"
+	case age <= 31*24*time.Hour+time.Minute:
+		return "month"
+	case age <= 366*24*time.Hour+time.Minute:
+		return "year"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// embedInputTokens caps an input of an embedding request: the embedding
+// models take about 8k tokens per input, longer texts are cut
+const embedInputTokens = 8000
+
+// defaultEmbedBatch is how many texts an embedding request of the provider
+// carries unless EMBEDDING_BATCH_SIZE says otherwise
+func defaultEmbedBatch(provider string) int {
+	if provider == ProviderQwen {
+		// DashScope's OpenAI-compatible mode takes at most 10 inputs
+		return 10
+	}
+	return 64
+}
+
+// Embed returns the embedding of each text with the configured embedding
+// model, in the order of texts, sending them in batches. Embeddings of
+// different models can't be compared, so only the primary provider embeds.
+func (l *LLMClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(l.backends) == 0 {
+		return nil, fmt.Errorf("LLM client not initialized")
+	}
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	defer TrackTime(ctx, TimingLLM, time.Now())
+
+	primary := l.backends[0]
+	if !primary.breaker.allow() {
+		return nil, fmt.Errorf("%w: %s", ErrLLMCircuitOpen, primary.provider)
+	}
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += primary.embedBatch {
+		end := min(start+primary.embedBatch, len(texts))
+		batch, err := primary.embed(ctx, texts[start:end])
+		if start == 0 {
+			// The first batch tells whether the provider answers
+			primary.breaker.done(ctx, err)
+		}
+		if err != nil {
+			if len(texts) > primary.embedBatch {
+				return nil, fmt.Errorf("texts %d-%d of %d: %w", start+1, end, len(texts), err)
+			}
+			return nil, err
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	return embeddings, nil
+}
+
+// embed sends one embedding request for texts
+func (b *llmBackend) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	inputs := make([]string, len(texts))
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			// The providers reject empty inputs
+			text = " "
+		}
+		inputs[i] = cutTokens(b.embeddingModel, text, embedInputTokens)
+	}
+
+	var resp openai.EmbeddingResponse
+	err := b.retry.do(ctx, b.provider, func(ctx context.Context) error {
+		release, err := b.limiter.acquire(ctx, b.provider)
+		if err != nil {
+			return err
+		}
+		defer release()
+		resp, err = b.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+			Input: inputs,
+			Model: openai.EmbeddingModel(b.embeddingModel),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding failed: %w", classifyLLMError(err))
+	}
+
+	meterUsage(ctx, b.provider, resp.Usage, b.embeddingPrice.cost(resp.Usage))
+
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Data), len(texts))
+	}
+	embeddings := make([][]float32, len(texts))
+	for _, e := range resp.Data {
+		if e.Index < 0 || e.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", e.Index)
+		}
+		embeddings[e.Index] = e.Embedding
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Domain errors returned by tools and agents. Callers should match them with
+// errors.Is; the wrapped message carries the underlying cause
+var (
+	// ErrSearchUnavailable means every search provider that was tried failed
+	ErrSearchUnavailable = errors.New("search unavailable")
+	// ErrNoResults means the providers answered but found nothing
+	ErrNoResults = errors.New("no results")
+	// ErrLLMTimeout means the LLM provider did not answer before the deadline
+	ErrLLMTimeout = errors.New("llm timeout")
+	// ErrBudgetExceeded means a time or quota budget ran out before an answer
+	ErrBudgetExceeded = errors.New("budget exceeded")
+	// ErrLLMRateLimited means the LLM provider kept rate limiting the calls
+	// until the retries ran out
+	ErrLLMRateLimited = errors.New("llm rate limited")
+	// ErrLLMUnavailable means the LLM provider kept failing with server or
+	// connection errors until the retries ran out
+	ErrLLMUnavailable = errors.New("llm unavailable")
+	// ErrLLMBusy means a call waited too long for a free slot of the
+	// provider's concurrency limit
+	ErrLLMBusy = errors.New("llm busy")
+	// ErrLLMCircuitOpen means every LLM provider failed repeatedly and is
+	// skipped until its cooldown passes
+	ErrLLMCircuitOpen = errors.New("llm circuit open")
+	// ErrContextOverflow means a prompt doesn't fit the model's context window
+	ErrContextOverflow = errors.New("context window exceeded")
+)
+
+// isUnsupportedParamError reports whether the provider rejected temperature,
+// max_tokens or response_format for the configured model
+func isUnsupportedParamError(err error) bool {
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	if apiErr.Param != nil {
+		switch *apiErr.Param {
+		case "temperature", "max_tokens", "max_completion_tokens", "response_format":
+			return true
+		}
+	}
+	code, _ := apiErr.Code.(string)
+	return code == "unsupported_parameter" || code == "unsupported_value"
+}
+
+// classifyLLMError wraps provider failures in the matching domain error
+func classifyLLMError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrLLMTimeout, err)
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		code, _ := apiErr.Code.(string)
+		if apiErr.HTTPStatusCode == http.StatusTooManyRequests && code == "insufficient_quota" {
+			return fmt.Errorf("%w: %w", ErrBudgetExceeded, err)
+		}
+		if apiErr.HTTPStatusCode == http.StatusGatewayTimeout || apiErr.HTTPStatusCode == http.StatusRequestTimeout {
+			return fmt.Errorf("%w: %w", ErrLLMTimeout, err)
+		}
+	}
+	return err
+}
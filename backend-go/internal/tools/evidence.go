@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// EvidenceAssessment summarizes how well a set of sources supports an answer
+type EvidenceAssessment struct {
+	Credibility float64 // mean credibility of the sources
+	Agreement   float64 // share of sources confirmed by a source from another domain
+	Score       float64 // combined score, 0.0 - 1.0
+}
+
+// AssessEvidence scores the top sources by credibility and cross-domain agreement
+func AssessEvidence(results []models.TavilyResult, top int) EvidenceAssessment {
+	if len(results) > top {
+		results = results[:top]
+	}
+	if len(results) == 0 {
+		return EvidenceAssessment{}
+	}
+
+	var credibility float64
+	shingles := make([]map[string]struct{}, len(results))
+	domains := make([]string, len(results))
+	for i, result := range results {
+		score := result.Credibility
+		if score == 0 {
+			score = result.Score
+		}
+		if score > 1 {
+			score = 1
+		}
+		credibility += score
+
+		shingles[i] = phraseShingles(result.Content)
+		if parsed, err := url.Parse(result.URL); err == nil {
+			domains[i] = strings.TrimPrefix(parsed.Hostname(), "www.")
+		}
+	}
+	credibility /= float64(len(results))
+
+	// A source is confirmed when it shares at least two phrases with a
+	// source from a different domain
+	confirmed := 0
+	for i := range results {
+		for j := range results {
+			if i == j || (domains[i] != "" && domains[i] == domains[j]) {
+				continue
+			}
+			if sharedPhrases(shingles[i], shingles[j]) >= 2 {
+				confirmed++
+				break
+			}
+		}
+	}
+	agreement := float64(confirmed) / float64(len(results))
+
+	return EvidenceAssessment{
+		Credibility: credibility,
+		Agreement:   agreement,
+		Score:       0.6*credibility + 0.4*agreement,
+	}
+}
+
+// phraseShingles returns the 3-word phrases of a text, skipping short ones
+func phraseShingles(text string) map[string]struct{} {
+	words := strings.Fields(strings.ToLower(text))
+	phrases := make(map[string]struct{})
+	for i := 0; i+3 <= len(words); i++ {
+		phrase := strings.Join(words[i:i+3], " ")
+		if len(phrase) > 15 {
+			phrases[phrase] = struct{}{}
+		}
+	}
+	return phrases
+}
+
+func sharedPhrases(a, b map[string]struct{}) int {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for phrase := range a {
+		if _, ok := b[phrase]; ok {
+			shared++
+		}
+	}
+	return shared
+}
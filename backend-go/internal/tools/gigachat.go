@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/google/uuid"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// gigaChatTokenMargin renews an access token this long before it expires
+	// (tokens live 30 minutes)
+	gigaChatTokenMargin = time.Minute
+	gigaChatAuthTimeout = 10 * time.Second
+)
+
+// newGigaChatClient returns an OpenAI client for the GigaChat API, whose chat
+// completion, embedding and model endpoints follow the OpenAI format. Its
+// requests are authorized with GigaChat OAuth access tokens.
+func newGigaChatClient(cfg *config.Config) (*openai.Client, error) {
+	if cfg.GigaChatAuthKey == "" {
+		return nil, fmt.Errorf("GIGACHAT_AUTH_KEY is not set")
+	}
+	tlsConfig, err := gigaChatTLSConfig(cfg.GigaChatCACert, cfg.GigaChatInsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.TLSClientConfig = tlsConfig
+
+	clientConfig := openai.DefaultConfig("")
+	clientConfig.BaseURL = strings.TrimRight(cfg.GigaChatAPIURL, "/")
+	clientConfig.HTTPClient = retryAfterClient(&gigaChatTransport{
+		base:    base,
+		auth:    &http.Client{Transport: base, Timeout: gigaChatAuthTimeout},
+		authURL: cfg.GigaChatAuthURL,
+		authKey: cfg.GigaChatAuthKey,
+		scope:   cfg.GigaChatScope,
+	})
+	return openai.NewClientWithConfig(clientConfig), nil
+}
+
+// gigaChatTLSConfig trusts the system roots plus the PEM bundle at caFile
+// (the Russian Trusted Root CA, which most systems lack)
+func gigaChatTLSConfig(caFile string, insecure bool) (*tls.Config, error) {
+	if insecure {
+		log.Printf("⚠️  GigaChat: TLS certificate verification is off (GIGACHAT_INSECURE_SKIP_VERIFY)")
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+	if caFile == "" {
+		return &tls.Config{}, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read GigaChat CA certificate: %w", err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates in %s", caFile)
+	}
+	return &tls.Config{RootCAs: roots}, nil
+}
+
+// gigaChatTransport sets the access token on GigaChat API requests. The token
+// is requested with the authorization key and shared by all requests until
+// shortly before it expires.
+type gigaChatTransport struct {
+	base    http.RoundTripper
+	auth    *http.Client
+	authURL string
+	authKey string
+	scope   string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (t *gigaChatTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.accessToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		// Revoked before it expired: the next request gets a new one
+		t.mu.Lock()
+		if t.token == token {
+			t.token = ""
+		}
+		t.mu.Unlock()
+	}
+	return resp, err
+}
+
+// accessToken returns the current access token, requesting a new one when it
+// is about to expire
+func (t *gigaChatTransport) accessToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && time.Until(t.expiresAt) > gigaChatTokenMargin {
+		return t.token, nil
+	}
+
+	form := url.Values{"scope": {t.scope}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.authURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("gigachat oauth: %w", err)
+	}
+	req.Header.Set("Authorization", "Basic "+t.authKey)
+	req.Header.Set("RqUID", uuid.New().String())
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.auth.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("gigachat oauth: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gigachat oauth: %s: %s", resp.Status, utils.TruncateRunes(string(body), 200))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresAt   int64  `json:"expires_at"` // unix milliseconds
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("gigachat oauth: unexpected response: %s", utils.TruncateRunes(string(body), 200))
+	}
+	t.token = token.AccessToken
+	t.expiresAt = time.UnixMilli(token.ExpiresAt)
+	return t.token, nil
+}
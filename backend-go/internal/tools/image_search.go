@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+// resolutionPattern reads the "1920 x 1080" resolutions of SearXNG images
+var resolutionPattern = regexp.MustCompile(`(\d+)\s*[x×]\s*(\d+)`)
+
+// SearchImages finds images for query in the SearXNG images vertical, with
+// the Brave image search as a fallback. Images without an http(s) image or
+// page URL are skipped.
+func (s *SearchClient) SearchImages(ctx context.Context, query string, maxResults int) ([]models.Image, error) {
+	logging.Printf(ctx, "🖼️  Image search for: %s", query)
+
+	var attempts, failures int
+	var lastErr error
+	var images []models.Image
+	collect := func(found []models.Image, err error) []models.Image {
+		attempts++
+		if err != nil {
+			failures++
+			lastErr = err
+		}
+		images = append(images, found...)
+		return found
+	}
+
+	searxngImages := collect(s.trySearXNGImages(ctx, query, maxResults))
+	logging.Printf(ctx, "  📊 SearXNG images: %d results", len(searxngImages))
+
+	if len(images) < maxResults/2 && s.braveAPIKey != "" {
+		braveImages := collect(s.tryBraveImages(ctx, query, maxResults-len(images)))
+		logging.Printf(ctx, "  📊 Brave images: %d results", len(braveImages))
+	}
+
+	if len(images) == 0 {
+		if failures == attempts {
+			return nil, fmt.Errorf("%w: %w", ErrSearchUnavailable, lastErr)
+		}
+		return nil, ErrNoResults
+	}
+
+	// The same picture is often found by several engines
+	seen := make(map[string]bool)
+	unique := images[:0]
+	for _, image := range images {
+		if seen[image.URL] {
+			continue
+		}
+		seen[image.URL] = true
+		unique = append(unique, image)
+	}
+	if len(unique) > maxResults {
+		unique = unique[:maxResults]
+	}
+	return unique, nil
+}
+
+// SearXNG images vertical (Primary method)
+func (s *SearchClient) trySearXNGImages(ctx context.Context, query string, maxResults int) ([]models.Image, error) {
+	defer TrackSearchTime(ctx, "searxng_images", time.Now())
+
+	type SearXNGImagesResponse struct {
+		Results []struct {
+			Title        string `json:"title"`
+			URL          string `json:"url"`
+			ImgSrc       string `json:"img_src"`
+			ThumbnailSrc string `json:"thumbnail_src"`
+			Resolution   string `json:"resolution"`
+			Source       string `json:"source"`
+			Author       string `json:"author"`
+		} `json:"results"`
+	}
+
+	var searxResp SearXNGImagesResponse
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetQueryParams(map[string]string{
+			"q":          query,
+			"format":     "json",
+			"categories": "images",
+			"safesearch": "1",
+		}).
+		SetResult(&searxResp).
+		Get(s.searxngURL + "/search")
+
+	if err != nil {
+		logging.Printf(ctx, "⚠️  SearXNG images failed: %v", err)
+		return nil, fmt.Errorf("searxng images: %w", err)
+	}
+
+	if resp.IsError() {
+		logging.Printf(ctx, "⚠️  SearXNG images error response: %d", resp.StatusCode())
+		return nil, fmt.Errorf("searxng images returned status %d", resp.StatusCode())
+	}
+
+	images := make([]models.Image, 0)
+	for _, r := range searxResp.Results {
+		if len(images) >= maxResults {
+			break
+		}
+		image, ok := newImage(r.Title, r.ImgSrc, r.ThumbnailSrc, r.URL, r.Source)
+		if !ok {
+			continue
+		}
+		image.Author = strings.TrimSpace(r.Author)
+		if m := resolutionPattern.FindStringSubmatch(r.Resolution); m != nil {
+			image.Width, _ = strconv.Atoi(m[1])
+			image.Height, _ = strconv.Atoi(m[2])
+		}
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+// Brave image search (Fallback)
+func (s *SearchClient) tryBraveImages(ctx context.Context, query string, maxResults int) ([]models.Image, error) {
+	defer TrackSearchTime(ctx, "brave_images", time.Now())
+
+	type BraveImagesResponse struct {
+		Results []struct {
+			Title     string `json:"title"`
+			URL       string `json:"url"`
+			Source    string `json:"source"`
+			Thumbnail struct {
+				Src string `json:"src"`
+			} `json:"thumbnail"`
+			Properties struct {
+				URL    string `json:"url"`
+				Width  int    `json:"width"`
+				Height int    `json:"height"`
+			} `json:"properties"`
+		} `json:"results"`
+	}
+
+	var braveResp BraveImagesResponse
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("Accept", "application/json").
+		SetHeader("Accept-Encoding", "gzip").
+		SetHeader("X-Subscription-Token", s.braveAPIKey).
+		SetQueryParams(map[string]string{
+			"q":          query,
+			"count":      fmt.Sprintf("%d", maxResults),
+			"safesearch": "strict",
+		}).
+		SetResult(&braveResp).
+		Get("https://api.search.brave.com/res/v1/images/search")
+
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Brave images failed: %v", err)
+		return nil, fmt.Errorf("brave images: %w", err)
+	}
+
+	if resp.IsError() {
+		logging.Printf(ctx, "⚠️  Brave images error: %d - %s", resp.StatusCode(), resp.String())
+		return nil, fmt.Errorf("brave images returned status %d", resp.StatusCode())
+	}
+
+	images := make([]models.Image, 0)
+	for _, r := range braveResp.Results {
+		if len(images) >= maxResults {
+			break
+		}
+		image, ok := newImage(r.Title, r.Properties.URL, r.Thumbnail.Src, r.URL, r.Source)
+		if !ok {
+			continue
+		}
+		image.Width, image.Height = r.Properties.Width, r.Properties.Height
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+// newImage builds an image result; the thumbnail defaults to the image and
+// the credited source to the host of the page
+func newImage(title, imageURL, thumbnailURL, pageURL, source string) (models.Image, bool) {
+	imageURL, thumbnailURL, pageURL = withScheme(imageURL), withScheme(thumbnailURL), withScheme(pageURL)
+	if !isWebURL(imageURL) || !isWebURL(pageURL) {
+		return models.Image{}, false
+	}
+	if !isWebURL(thumbnailURL) {
+		thumbnailURL = imageURL
+	}
+	if source = strings.TrimSpace(source); source == "" {
+		u, _ := url.Parse(pageURL)
+		source = strings.TrimPrefix(u.Hostname(), "www.")
+	}
+	return models.Image{
+		Title:        utils.TruncateRunesWithEllipsis(strings.TrimSpace(title), 200),
+		URL:          imageURL,
+		ThumbnailURL: thumbnailURL,
+		PageURL:      pageURL,
+		Source:       source,
+	}, true
+}
+
+// withScheme completes the protocol-relative URLs ("//host/path") some
+// engines return
+func withScheme(raw string) string {
+	if strings.HasPrefix(raw, "//") {
+		return "https:" + raw
+	}
+	return raw
+}
+
+func isWebURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+)
+
+// llmBreaker is the circuit breaker of a provider. After threshold failures
+// in a row it opens: calls skip the provider at once instead of each waiting
+// out its own timeout during an incident. Once cooldown has passed a single
+// call probes the provider; its success closes the circuit, its failure
+// keeps it open for another cooldown.
+type llmBreaker struct {
+	provider  string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int       // in a row
+	openedAt time.Time // of the last failure past the threshold
+	probing  bool
+}
+
+var (
+	breakersMu sync.Mutex
+	// breakers are shared by the LLM clients of the process, by provider
+	breakers = map[string]*llmBreaker{}
+)
+
+// breakerFor returns the circuit breaker of provider, nil when disabled
+func breakerFor(cfg *config.Config, provider string) *llmBreaker {
+	if cfg.LLMBreakerFailures <= 0 {
+		return nil
+	}
+
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	if breaker, ok := breakers[provider]; ok {
+		return breaker
+	}
+	breaker := &llmBreaker{
+		provider:  provider,
+		threshold: cfg.LLMBreakerFailures,
+		cooldown:  time.Duration(cfg.LLMBreakerCooldownSeconds) * time.Second,
+	}
+	breakers[provider] = breaker
+	return breaker
+}
+
+// allow reports whether a call may go to the provider: always while the
+// circuit is closed, and once the cooldown has passed as the probe. A call
+// allowed must be followed by done.
+func (b *llmBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// open reports whether calls skip the provider
+func (b *llmBreaker) open() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold
+}
+
+// done records the outcome of an allowed call. Failures of the provider
+// count towards opening the circuit; any answer from it closes the circuit.
+// A call that was cancelled, or never sent (busy, too long), counts as
+// neither.
+func (b *llmBreaker) done(ctx context.Context, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+
+	switch {
+	case ctx.Err() != nil, errors.Is(err, context.Canceled),
+		errors.Is(err, ErrLLMBusy), errors.Is(err, ErrContextOverflow):
+		// Says nothing about the provider
+	case providerFailure(err):
+		b.failures++
+		if b.failures == b.threshold {
+			log.Printf("🔌 LLM provider %s failed %d times in a row, circuit open for %s: %v",
+				b.provider, b.failures, b.cooldown, err)
+		}
+		if b.failures >= b.threshold {
+			b.openedAt = time.Now()
+		}
+	default:
+		if b.failures >= b.threshold {
+			log.Printf("🔌 LLM provider %s recovered, circuit closed", b.provider)
+		}
+		b.failures = 0
+	}
+}
+
+// providerFailure reports whether err means the provider is failing: retries
+// ran out on rate limits or server errors, or it didn't answer in time
+func providerFailure(err error) bool {
+	if errors.Is(err, ErrLLMUnavailable) || errors.Is(err, ErrLLMRateLimited) || errors.Is(err, ErrLLMTimeout) {
+		return true
+	}
+	transient, _ := transientLLMError(err)
+	return transient
+}
+
+// CircuitOpen reports whether every provider of the chain is skipped after
+// failing repeatedly, so that LLM calls fail at once with ErrLLMCircuitOpen
+func (l *LLMClient) CircuitOpen() bool {
+	for _, b := range l.backends {
+		if !b.breaker.open() {
+			return false
+		}
+	}
+	return len(l.backends) > 0
+}
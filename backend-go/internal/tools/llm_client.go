@@ -2,41 +2,217 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
 	openai "github.com/sashabaranov/go-openai"
 )
 
+// LLM providers (LLM_PROVIDER, LLM_FALLBACKS)
+const (
+	ProviderOpenAI   = "openai"
+	ProviderQwen     = "qwen"
+	ProviderGigaChat = "gigachat"
+)
+
+// llmBackend is a provider and model of the fallback chain
+type llmBackend struct {
+	provider       string
+	client         *openai.Client
+	model          string
+	embeddingModel string
+	embedBatch     int // texts per embedding request
+	contextWindow  int // tokens
+	retry          retryPolicy
+	limiter        *llmLimiter // nil without a concurrency limit
+	breaker        *llmBreaker // nil when disabled
+	price          llmPrice    // of the model
+	embeddingPrice llmPrice    // of the embedding model
+}
+
+// LLMClient sends LLM calls to the primary provider and, when it fails or
+// takes longer than LLM_FALLBACK_TIMEOUT_SECONDS, to the fallbacks in order.
+// The provider that served each call is counted by the TokenMeter of the call
+// context.
 type LLMClient struct {
-	cfg    *config.Config
-	client *openai.Client
+	cfg      *config.Config
+	backends []*llmBackend // primary first; nil without a configured provider
+	// attemptTimeout bounds a call to one provider while fallbacks remain
+	attemptTimeout time.Duration
+	// tasks are the clients of the tasks with a model of their own, base the
+	// client a task client was derived from
+	tasks map[string]*LLMClient
+	base  *LLMClient
 }
 
 func NewLLMClient(cfg *config.Config) *LLMClient {
-	var client *openai.Client
+	l := &LLMClient{
+		cfg:            cfg,
+		attemptTimeout: time.Duration(cfg.LLMFallbackTimeoutSeconds) * time.Second,
+	}
 
-	// Use OpenAI by default
-	if cfg.OpenAIKey != "" {
-		client = openai.NewClient(cfg.OpenAIKey)
-	} else if cfg.QwenAPIURL != "" {
+	var primary *llmBackend
+	var err error
+	switch {
+	case strings.EqualFold(cfg.LLMProvider, ProviderGigaChat):
+		primary, err = newLLMBackend(cfg, ProviderGigaChat, "")
+	case strings.EqualFold(cfg.LLMProvider, ProviderQwen):
+		primary, err = newLLMBackend(cfg, ProviderQwen, "")
+	case cfg.OpenAIKey != "":
+		// Use OpenAI by default
+		primary, err = newLLMBackend(cfg, ProviderOpenAI, "")
+	case cfg.QwenAPIURL != "":
 		// For Qwen or other OpenAI-compatible APIs
+		primary, err = newLLMBackend(cfg, ProviderQwen, cfg.OpenAIModel)
+	}
+	if err != nil {
+		log.Printf("⚠️  LLM provider %s is not available: %v", cfg.LLMProvider, err)
+	}
+	if primary != nil {
+		l.backends = append(l.backends, primary)
+	}
+
+	for _, entry := range cfg.LLMFallbacks {
+		provider, model, _ := strings.Cut(entry, ":")
+		backend, err := newLLMBackend(cfg, strings.ToLower(strings.TrimSpace(provider)), strings.TrimSpace(model))
+		if err != nil {
+			log.Printf("⚠️  LLM fallback %s is not available: %v", entry, err)
+			continue
+		}
+		l.backends = append(l.backends, backend)
+	}
+	if len(l.backends) > 1 {
+		names := make([]string, len(l.backends))
+		for i, b := range l.backends {
+			names[i] = b.provider + ":" + b.model
+		}
+		log.Printf("🔁 LLM providers: %s", strings.Join(names, " → "))
+	}
+	l.initTasks(cfg)
+
+	return l
+}
+
+// newLLMBackend connects to a provider; an empty model selects the
+// provider's configured one
+func newLLMBackend(cfg *config.Config, provider, model string) (*llmBackend, error) {
+	backend := &llmBackend{
+		provider:       provider,
+		model:          model,
+		embeddingModel: cfg.EmbeddingModel,
+		retry:          newRetryPolicy(cfg),
+		limiter:        limiterFor(cfg, provider),
+		breaker:        breakerFor(cfg, provider),
+	}
+	switch provider {
+	case ProviderOpenAI:
+		if cfg.OpenAIKey == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY is not set")
+		}
 		clientConfig := openai.DefaultConfig(cfg.OpenAIKey)
+		clientConfig.HTTPClient = retryAfterClient(http.DefaultTransport)
+		backend.client = openai.NewClientWithConfig(clientConfig)
+		if backend.model == "" {
+			backend.model = cfg.OpenAIModel
+		}
+	case ProviderQwen:
+		if cfg.QwenAPIURL == "" {
+			return nil, fmt.Errorf("QWEN_API_URL is not set")
+		}
+		clientConfig := openai.DefaultConfig("")
 		clientConfig.BaseURL = cfg.QwenAPIURL
-		client = openai.NewClientWithConfig(clientConfig)
+		clientConfig.HTTPClient = retryAfterClient(http.DefaultTransport)
+		backend.client = openai.NewClientWithConfig(clientConfig)
+		if backend.model == "" {
+			backend.model = cfg.QwenModel
+		}
+	case ProviderGigaChat:
+		client, err := newGigaChatClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		backend.client = client
+		backend.embeddingModel = cfg.GigaChatEmbeddingModel
+		if backend.model == "" {
+			backend.model = cfg.GigaChatModel
+		}
+	default:
+		return nil, fmt.Errorf("unknown provider %q, expected openai, qwen or gigachat", provider)
+	}
+	backend.embedBatch = cfg.EmbeddingBatchSize
+	if backend.embedBatch <= 0 {
+		backend.embedBatch = defaultEmbedBatch(provider)
+	}
+	backend.embeddingPrice = priceOf(cfg, backend.embeddingModel)
+	backend.setModel(cfg, backend.model)
+	return backend, nil
+}
+
+// setModel makes the backend serve model, with its price and context window
+func (b *llmBackend) setModel(cfg *config.Config, model string) {
+	b.model = model
+	b.price = priceOf(cfg, model)
+	b.contextWindow = cfg.LLMContextWindow
+	if b.contextWindow <= 0 {
+		b.contextWindow = contextWindow(model)
+	}
+}
+
+// Configured reports whether an LLM provider (OpenAI key, Qwen URL or
+// GigaChat key) is set
+func (l *LLMClient) Configured() bool {
+	return len(l.backends) > 0
+}
+
+// Provider returns the primary LLM provider: openai, qwen or gigachat
+func (l *LLMClient) Provider() string {
+	if len(l.backends) == 0 {
+		return ""
 	}
+	return l.backends[0].provider
+}
 
-	return &LLMClient{
-		cfg:    cfg,
-		client: client,
+// SupportsTools reports whether a provider of the chain takes OpenAI tool
+// definitions; GigaChat has a function calling API of its own
+func (l *LLMClient) SupportsTools() bool {
+	for _, b := range l.backends {
+		if b.supportsTools() {
+			return true
+		}
 	}
+	return false
+}
+
+func (b *llmBackend) supportsTools() bool {
+	return b.provider != ProviderGigaChat
+}
+
+// Ping checks that a provider of the chain is reachable and accepts the key
+func (l *LLMClient) Ping(ctx context.Context) error {
+	if len(l.backends) == 0 {
+		return fmt.Errorf("LLM client not initialized")
+	}
+
+	var errs []error
+	for _, b := range l.backends {
+		if _, err := b.client.ListModels(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: list models failed: %w", b.provider, err))
+			continue
+		}
+		return nil
+	}
+	return errors.Join(errs...)
 }
 
 // supportsCustomParams checks if model supports custom temperature and max_tokens
-func (l *LLMClient) supportsCustomParams() bool {
-	model := strings.ToLower(l.cfg.OpenAIModel)
+func supportsCustomParams(model string) bool {
+	model = strings.ToLower(model)
 	// o1 models and some newer GPT-4 variants don't support custom params
 	if strings.Contains(model, "o1") ||
 		strings.Contains(model, "o1-preview") ||
@@ -47,60 +223,54 @@ func (l *LLMClient) supportsCustomParams() bool {
 }
 
 // isGPT4Model checks if model is GPT-4 or newer
-func (l *LLMClient) isGPT4Model() bool {
-	model := strings.ToLower(l.cfg.OpenAIModel)
+func isGPT4Model(model string) bool {
+	model = strings.ToLower(model)
 	return strings.Contains(model, "gpt-4") || strings.Contains(model, "o1")
 }
 
-func (l *LLMClient) Complete(ctx context.Context, prompt string, temperature float32, maxTokens int) (string, error) {
-	if l.client == nil {
-		return "", fmt.Errorf("LLM client not initialized")
-	}
+// completion is a provider-independent chat completion request; each
+// provider of the chain gets it with its model and supported parameters
+type completion struct {
+	messages    []openai.ChatCompletionMessage
+	tools       []openai.Tool
+	toolChoice  any
+	temperature float32
+	maxTokens   int
+	json        bool // JSON mode, where the provider has it
+}
 
+// request builds the completion request for the backend's model
+func (b *llmBackend) request(c completion) openai.ChatCompletionRequest {
 	req := openai.ChatCompletionRequest{
-		Model: l.cfg.OpenAIModel,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
+		Model:      b.model,
+		Messages:   c.messages,
+		Tools:      c.tools,
+		ToolChoice: c.toolChoice,
 	}
 
 	// Only set custom parameters for models that support them
-	if l.supportsCustomParams() {
-		req.Temperature = temperature
-		if !l.isGPT4Model() {
-			req.MaxTokens = maxTokens
+	if supportsCustomParams(b.model) {
+		req.Temperature = c.temperature
+		if !isGPT4Model(b.model) {
+			req.MaxTokens = c.maxTokens
 		}
+	} else {
+		// For models that don't support custom params, use defaults
+		// (temperature=1, no max_tokens). They reject system messages too.
+		req.Messages = foldSystemMessages(c.messages)
 	}
-	// For models that don't support custom params, use defaults (temperature=1, no max_tokens)
 
-	resp, err := l.client.CreateChatCompletion(ctx, req)
-	if err != nil {
-		// Retry with default parameters if error is related to unsupported params
-		if strings.Contains(err.Error(), "temperature") ||
-			strings.Contains(err.Error(), "max_tokens") ||
-			strings.Contains(err.Error(), "max_completion_tokens") {
-			log.Printf("⚠️  Retrying with default parameters (temperature=1, no max_tokens)")
-			
-			req.Temperature = 1.0
-			req.MaxTokens = 0
-			
-			resp, err = l.client.CreateChatCompletion(ctx, req)
-			if err != nil {
-				return "", fmt.Errorf("chat completion failed: %w", err)
-			}
-		} else {
-			return "", fmt.Errorf("chat completion failed: %w", err)
+	if c.json && b.provider != ProviderGigaChat {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
 		}
 	}
+	return req
+}
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from LLM")
-	}
-
-	return resp.Choices[0].Message.Content, nil
+// Complete sends prompt as a single user message
+func (l *LLMClient) Complete(ctx context.Context, prompt string, temperature float32, maxTokens int) (string, error) {
+	return l.CompletePrompt(ctx, Prompt{User: prompt}, temperature, maxTokens)
 }
 
 func (l *LLMClient) ChatCompletion(
@@ -109,10 +279,32 @@ func (l *LLMClient) ChatCompletion(
 	temperature float32,
 	maxTokens int,
 ) (string, error) {
-	if l.client == nil {
-		return "", fmt.Errorf("LLM client not initialized")
-	}
+	return l.createCompletion(ctx, completion{
+		messages:    chatMessages(messages),
+		temperature: temperature,
+		maxTokens:   maxTokens,
+	})
+}
 
+// ChatCompletionJSON is ChatCompletion in JSON mode: the model answers with a
+// single JSON object. The prompt must still ask for JSON; providers without
+// JSON mode (GigaChat) get the plain request.
+func (l *LLMClient) ChatCompletionJSON(
+	ctx context.Context,
+	messages []map[string]string,
+	temperature float32,
+	maxTokens int,
+) (string, error) {
+	return l.createCompletion(ctx, completion{
+		messages:    chatMessages(messages),
+		temperature: temperature,
+		maxTokens:   maxTokens,
+		json:        true,
+	})
+}
+
+// chatMessages converts a conversation to completion messages
+func chatMessages(messages []map[string]string) []openai.ChatCompletionMessage {
 	var chatMessages []openai.ChatCompletionMessage
 	for _, msg := range messages {
 		role := msg["role"]
@@ -133,43 +325,108 @@ func (l *LLMClient) ChatCompletion(
 			Content: content,
 		})
 	}
+	return chatMessages
+}
 
-	req := openai.ChatCompletionRequest{
-		Model:    l.cfg.OpenAIModel,
-		Messages: chatMessages,
+// createCompletion sends c and returns the text of the reply
+func (l *LLMClient) createCompletion(ctx context.Context, c completion) (string, error) {
+	message, err := l.createMessage(ctx, c)
+	if err != nil {
+		return "", err
 	}
+	return message.Content, nil
+}
 
-	// Only set custom parameters for models that support them
-	if l.supportsCustomParams() {
-		req.Temperature = temperature
-		if !l.isGPT4Model() {
-			req.MaxTokens = maxTokens
+// createMessage sends c to the providers of the chain in order until one
+// answers. A provider gets attemptTimeout while others remain after it; a
+// cancelled or expired call context ends the chain. Providers with an open
+// circuit are skipped, and ErrLLMCircuitOpen returned when that is all of
+// them.
+func (l *LLMClient) createMessage(ctx context.Context, c completion) (openai.ChatCompletionMessage, error) {
+	if len(l.backends) == 0 {
+		return openai.ChatCompletionMessage{}, fmt.Errorf("LLM client not initialized")
+	}
+	defer TrackTime(ctx, TimingLLM, time.Now())
+
+	var backends []*llmBackend
+	for _, b := range l.backends {
+		if c.tools == nil || b.supportsTools() {
+			backends = append(backends, b)
 		}
 	}
+	if len(backends) == 0 {
+		return openai.ChatCompletionMessage{}, fmt.Errorf("tool calling is not supported by the %s provider", l.Provider())
+	}
 
-	resp, err := l.client.CreateChatCompletion(ctx, req)
-	if err != nil {
-		// Retry with default parameters if error is related to unsupported params
-		if strings.Contains(err.Error(), "temperature") ||
-			strings.Contains(err.Error(), "max_tokens") ||
-			strings.Contains(err.Error(), "max_completion_tokens") {
-			log.Printf("⚠️  Retrying with default parameters (temperature=1, no max_tokens)")
-			
-			req.Temperature = 1.0
-			req.MaxTokens = 0
-			
-			resp, err = l.client.CreateChatCompletion(ctx, req)
-			if err != nil {
-				return "", fmt.Errorf("chat completion failed: %w", err)
+	var err error
+	for i, b := range backends {
+		if !b.breaker.allow() {
+			logging.Printf(ctx, "🔌 LLM provider %s skipped, its circuit is open", b.provider)
+			continue
+		}
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if i < len(backends)-1 && l.attemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, l.attemptTimeout)
+		}
+		var message openai.ChatCompletionMessage
+		message, err = b.createMessage(attemptCtx, c)
+		cancel()
+		b.breaker.done(ctx, err)
+		if err == nil {
+			if i > 0 {
+				logging.Printf(ctx, "🔁 LLM call served by fallback %s (%s)", b.provider, b.model)
 			}
-		} else {
-			return "", fmt.Errorf("chat completion failed: %w", err)
+			return message, nil
+		}
+		if ctx.Err() != nil || i == len(backends)-1 {
+			break
 		}
+		logging.Printf(ctx, "⚠️  LLM provider %s failed, falling back to %s: %v", b.provider, backends[i+1].provider, err)
+	}
+	if err == nil {
+		err = fmt.Errorf("%w: every LLM provider is failing", ErrLLMCircuitOpen)
 	}
+	return openai.ChatCompletionMessage{}, err
+}
+
+// createMessage sends c to the provider, retrying transient failures and,
+// once, with provider defaults when the model rejects temperature, max_tokens
+// or JSON mode. A prompt too long for the model isn't sent. Each attempt
+// waits for a slot of the provider's concurrency limit.
+func (b *llmBackend) createMessage(ctx context.Context, c completion) (openai.ChatCompletionMessage, error) {
+	if err := b.checkContext(c); err != nil {
+		return openai.ChatCompletionMessage{}, err
+	}
+	req := b.request(c)
+	var resp openai.ChatCompletionResponse
+	send := func(ctx context.Context) error {
+		release, err := b.limiter.acquire(ctx, b.provider)
+		if err != nil {
+			return err
+		}
+		defer release()
+		resp, err = b.client.CreateChatCompletion(ctx, req)
+		return err
+	}
+	err := b.retry.do(ctx, b.provider, send)
+	if err != nil && isUnsupportedParamError(err) {
+		logging.Printf(ctx, "⚠️  Retrying with default parameters (temperature=1, no max_tokens)")
+
+		req.Temperature = 1.0
+		req.MaxTokens = 0
+		req.ResponseFormat = nil
+
+		err = b.retry.do(ctx, b.provider, send)
+	}
+	if err != nil {
+		return openai.ChatCompletionMessage{}, fmt.Errorf("chat completion failed: %w", classifyLLMError(err))
+	}
+
+	meterUsage(ctx, b.provider, resp.Usage, b.price.cost(resp.Usage))
 
 	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from LLM")
+		return openai.ChatCompletionMessage{}, fmt.Errorf("no response from LLM")
 	}
 
-	return resp.Choices[0].Message.Content, nil
-}
\ No newline at end of file
+	return resp.Choices[0].Message, nil
+}
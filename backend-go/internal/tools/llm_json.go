@@ -0,0 +1,208 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// jsonAttempts bounds the replies CompleteJSON asks for: a malformed or
+	// invalid one is sent back for correction
+	jsonAttempts    = 3
+	jsonTemperature = 0.1
+	jsonMaxTokens   = 1000
+)
+
+// CompleteJSON asks for a JSON object matching schema (a JSON schema, as for
+// the parameters of a ToolSpec) and decodes it into out. The provider's JSON
+// mode is used where it has one and the schema goes with the user message of
+// the prompt. A reply
+// that isn't JSON or breaks the schema is sent back with what is wrong, up to
+// jsonAttempts replies in all.
+func (l *LLMClient) CompleteJSON(ctx context.Context, p Prompt, schema map[string]interface{}, out interface{}) error {
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	p.User += "\n\nReply with ONLY a JSON object matching this JSON schema:\n" + string(schemaJSON)
+	c := completion{
+		messages:    p.messages(),
+		temperature: jsonTemperature,
+		maxTokens:   jsonMaxTokens,
+		json:        true,
+	}
+	for attempt := 1; ; attempt++ {
+		message, err := l.createMessage(ctx, c)
+		if err != nil {
+			return err
+		}
+		object, err := decodeJSONReply(message.Content, schema)
+		if err == nil {
+			return json.Unmarshal(object, out)
+		}
+		if attempt == jsonAttempts {
+			return fmt.Errorf("invalid JSON reply after %d attempts: %w", attempt, err)
+		}
+
+		logging.Printf(ctx, "⚠️  Invalid JSON reply (attempt %d of %d): %v", attempt, jsonAttempts, err)
+		c.messages = append(c.messages, message, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: fmt.Sprintf("The reply is invalid: %v. Reply again with ONLY the corrected JSON object.", err),
+		})
+	}
+}
+
+// decodeJSONReply extracts the JSON object from a reply, which models may wrap
+// in text or code fences, and checks it against schema
+func decodeJSONReply(reply string, schema map[string]interface{}) (json.RawMessage, error) {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON object in the reply")
+	}
+	object := json.RawMessage(reply[start : end+1])
+
+	var value interface{}
+	if err := json.Unmarshal(object, &value); err != nil {
+		return nil, fmt.Errorf("malformed JSON: %w", err)
+	}
+	if err := validateSchema(value, schema, "$"); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// validateSchema checks a decoded JSON value against the subset of JSON
+// schema the prompts use: type, properties, required, additionalProperties
+// (false), items, enum, minimum, maximum, minItems, maxItems and minLength
+func validateSchema(value interface{}, schema map[string]interface{}, path string) error {
+	if types := schemaStrings(schema["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool {
+		return hasJSONType(value, t)
+	}) {
+		return fmt.Errorf("%s must be %s", path, strings.Join(types, " or "))
+	}
+	if enum, ok := schema["enum"]; ok && !slices.ContainsFunc(schemaValues(enum), func(v interface{}) bool {
+		return fmt.Sprint(v) == fmt.Sprint(value)
+	}) {
+		return fmt.Errorf("%s must be one of %v", path, enum)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		for name, field := range v {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if additional, set := schema["additionalProperties"].(bool); set && !additional {
+					return fmt.Errorf("%s.%s is not allowed", path, name)
+				}
+				continue
+			}
+			if err := validateSchema(field, property, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if n, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < n {
+			return fmt.Errorf("%s must have at least %v items", path, n)
+		}
+		if n, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > n {
+			return fmt.Errorf("%s must have at most %v items", path, n)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		if n, ok := schemaNumber(schema["minLength"]); ok && float64(len([]rune(strings.TrimSpace(v)))) < n {
+			return fmt.Errorf("%s must have at least %v characters", path, n)
+		}
+	case float64:
+		if n, ok := schemaNumber(schema["minimum"]); ok && v < n {
+			return fmt.Errorf("%s must be at least %v", path, n)
+		}
+		if n, ok := schemaNumber(schema["maximum"]); ok && v > n {
+			return fmt.Errorf("%s must be at most %v", path, n)
+		}
+	}
+	return nil
+}
+
+func hasJSONType(value interface{}, t string) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	case string:
+		return t == "string"
+	case bool:
+		return t == "boolean"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case nil:
+		return t == "null"
+	}
+	return false
+}
+
+// schemaStrings reads a schema keyword holding a string or a list of them
+func schemaStrings(keyword interface{}) []string {
+	switch v := keyword.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		return strs
+	}
+	return nil
+}
+
+// schemaValues reads a schema keyword holding a list of values
+func schemaValues(keyword interface{}) []interface{} {
+	switch v := keyword.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		values := make([]interface{}, len(v))
+		for i, s := range v {
+			values[i] = s
+		}
+		return values
+	}
+	return nil
+}
+
+// schemaNumber reads a numeric schema keyword
+func schemaNumber(keyword interface{}) (float64, bool) {
+	switch v := keyword.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
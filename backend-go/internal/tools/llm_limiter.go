@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+)
+
+// llmLimiter bounds the calls in flight to a provider. Parallel sub-query
+// summaries of several users would otherwise burst into the provider's rate
+// limits; the calls past the limit queue instead.
+type llmLimiter struct {
+	slots   chan struct{}
+	timeout time.Duration // of the queue; 0 waits as long as the call context
+}
+
+var (
+	limitersMu sync.Mutex
+	// limiters are shared by the LLM clients of the process, by provider
+	limiters = map[string]*llmLimiter{}
+)
+
+// limiterFor returns the limiter of provider, nil without a limit
+func limiterFor(cfg *config.Config, provider string) *llmLimiter {
+	if cfg.LLMMaxConcurrency <= 0 {
+		return nil
+	}
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if limiter, ok := limiters[provider]; ok {
+		return limiter
+	}
+	limiter := &llmLimiter{
+		slots:   make(chan struct{}, cfg.LLMMaxConcurrency),
+		timeout: time.Duration(cfg.LLMQueueTimeoutSeconds) * time.Second,
+	}
+	limiters[provider] = limiter
+	return limiter
+}
+
+// acquire takes a slot, waiting for one while the limit is reached, and
+// returns the func releasing it. A wait past the queue timeout fails with
+// ErrLLMBusy; a call context done while waiting fails with its error.
+func (l *llmLimiter) acquire(ctx context.Context, provider string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	release := func() { <-l.slots }
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	start := time.Now()
+	var expired <-chan time.Time
+	if l.timeout > 0 {
+		timer := time.NewTimer(l.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case l.slots <- struct{}{}:
+		logging.Printf(ctx, "⏳ LLM call queued %s for a free %s slot", time.Since(start).Round(time.Millisecond), provider)
+		return release, nil
+	case <-expired:
+		return nil, fmt.Errorf("%w: %d calls to %s in flight for %s", ErrLLMBusy, cap(l.slots), provider, l.timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// retryBaseDelay is the first backoff delay, doubled on each retry up to
+	// retryMaxDelay
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 8 * time.Second
+)
+
+// retryPolicy retries the transient failures of a provider: rate limits,
+// server errors and dropped connections. It waits what the provider asks for
+// in Retry-After, or backs off exponentially with jitter, and gives up after
+// attempts calls or once maxElapsed would pass.
+type retryPolicy struct {
+	attempts   int // the first call included
+	maxElapsed time.Duration
+}
+
+func newRetryPolicy(cfg *config.Config) retryPolicy {
+	return retryPolicy{
+		attempts:   max(1, cfg.LLMRetryAttempts),
+		maxElapsed: time.Duration(cfg.LLMRetryMaxSeconds) * time.Second,
+	}
+}
+
+// do runs call until it succeeds, fails for good or the policy runs out. A
+// policy run out returns ErrLLMRateLimited or ErrLLMUnavailable wrapping the
+// last error.
+func (p retryPolicy) do(ctx context.Context, provider string, call func(ctx context.Context) error) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		hint := &retryAfterHint{}
+		err := call(context.WithValue(ctx, retryAfterKey{}, hint))
+		if err == nil {
+			return nil
+		}
+		transient, rateLimited := transientLLMError(err)
+		if !transient || ctx.Err() != nil {
+			return err
+		}
+
+		wait := hint.after
+		if wait <= 0 {
+			wait = backoff(attempt)
+		}
+		if attempt >= p.attempts || time.Since(start)+wait > p.maxElapsed || pastDeadline(ctx, wait) {
+			if rateLimited {
+				return fmt.Errorf("%w after %d attempts: %w", ErrLLMRateLimited, attempt, err)
+			}
+			return fmt.Errorf("%w after %d attempts: %w", ErrLLMUnavailable, attempt, err)
+		}
+
+		logging.Printf(ctx, "⏳ LLM provider %s failed (attempt %d of %d), retrying in %s: %v",
+			provider, attempt, p.attempts, wait.Round(time.Millisecond), err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// backoff is the delay before the retry after attempt: exponential, with the
+// upper half jittered so that concurrent calls don't retry in step
+func backoff(attempt int) time.Duration {
+	delay := min(retryMaxDelay, retryBaseDelay<<(attempt-1))
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// pastDeadline reports whether waiting wait would pass the deadline of ctx
+func pastDeadline(ctx context.Context, wait time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) < wait
+}
+
+// transientLLMError reports whether err is worth retrying, and whether it is
+// a rate limit. An exhausted quota is not: it won't pass by waiting.
+func transientLLMError(err error) (transient, rateLimited bool) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, false
+	}
+
+	status := 0
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		if code, _ := apiErr.Code.(string); code == "insufficient_quota" {
+			return false, false
+		}
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	}
+	switch status {
+	case http.StatusTooManyRequests:
+		return true, true
+	case http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, false
+	}
+
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout(),
+		errors.As(err, &opErr),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET):
+		return true, false
+	}
+	return false, false
+}
+
+// retryAfterHint receives the wait a provider asked for in its last response
+type retryAfterHint struct {
+	after time.Duration
+}
+
+type retryAfterKey struct{}
+
+// retryAfterTransport passes the Retry-After of rate limited and unavailable
+// responses to the retryAfterHint of the request context
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+// retryAfterClient returns an HTTP client for a provider API over base
+func retryAfterClient(base http.RoundTripper) *http.Client {
+	return &http.Client{Transport: &retryAfterTransport{base: base}}
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return resp, err
+	}
+	if hint, ok := req.Context().Value(retryAfterKey{}).(*retryAfterHint); ok {
+		hint.after = retryAfter(resp.Header, time.Now())
+	}
+	return resp, err
+}
+
+// retryAfter reads the wait a response asks for: retry-after-ms (OpenAI) or
+// Retry-After in seconds or as an HTTP date. It is 0 without one.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(0, date.Sub(now))
+	}
+	return 0
+}
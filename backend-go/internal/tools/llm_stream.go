@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// streamBuffer is how many chunks a stream may run ahead of its reader
+const streamBuffer = 64
+
+// CompleteStream is CompletePrompt with the reply streamed: the channel
+// receives the text in chunks as the provider generates it and is closed at the end. The
+// providers of the chain are tried in order until one starts answering within
+// LLM_FALLBACK_TIMEOUT_SECONDS; once it has, the stream stays with it, and a
+// failure after that closes the channel early (and is logged). Providers with
+// an open circuit are skipped. Providers don't
+// report the usage of streamed replies, so the meter gets the tokenizer count.
+func (l *LLMClient) CompleteStream(ctx context.Context, p Prompt, temperature float32, maxTokens int) (<-chan string, error) {
+	if len(l.backends) == 0 {
+		return nil, fmt.Errorf("LLM client not initialized")
+	}
+
+	c := completion{
+		messages:    p.messages(),
+		temperature: temperature,
+		maxTokens:   maxTokens,
+	}
+	start := time.Now()
+
+	var err error
+	for i, b := range l.backends {
+		if !b.breaker.allow() {
+			logging.Printf(ctx, "🔌 LLM provider %s skipped, its circuit is open", b.provider)
+			continue
+		}
+		var stream *openai.ChatCompletionStream
+		var first string
+		var cancel context.CancelFunc
+		stream, first, cancel, err = l.openStream(ctx, b, c, i < len(l.backends)-1)
+		b.breaker.done(ctx, err)
+		if err == nil {
+			if i > 0 {
+				logging.Printf(ctx, "🔁 LLM stream served by fallback %s (%s)", b.provider, b.model)
+			}
+			chunks := make(chan string, streamBuffer)
+			go func() {
+				defer cancel()
+				b.relayStream(ctx, stream, first, c, chunks, start)
+			}()
+			return chunks, nil
+		}
+		if ctx.Err() != nil || i == len(l.backends)-1 {
+			break
+		}
+		logging.Printf(ctx, "⚠️  LLM provider %s failed, falling back to %s: %v", b.provider, l.backends[i+1].provider, err)
+	}
+	TrackTime(ctx, TimingLLM, start)
+	if err == nil {
+		err = fmt.Errorf("%w: every LLM provider is failing", ErrLLMCircuitOpen)
+	}
+	return nil, err
+}
+
+// openStream starts the stream on b and waits for its first text. With
+// fallbacks left the first text must arrive within attemptTimeout. The
+// stream holds a slot of the provider's concurrency limit until the returned
+// cancel ends its context, once it has been read.
+func (l *LLMClient) openStream(
+	ctx context.Context,
+	b *llmBackend,
+	c completion,
+	fallbacksLeft bool,
+) (*openai.ChatCompletionStream, string, context.CancelFunc, error) {
+	if err := b.checkContext(c); err != nil {
+		return nil, "", nil, err
+	}
+	streamCtx, cancel := context.WithCancel(ctx)
+	if fallbacksLeft && l.attemptTimeout > 0 {
+		timer := time.AfterFunc(l.attemptTimeout, cancel)
+		defer timer.Stop()
+	}
+
+	req := b.request(c)
+	req.Stream = true
+	var stream *openai.ChatCompletionStream
+	var release func()
+	err := b.retry.do(streamCtx, b.provider, func(ctx context.Context) error {
+		slot, err := b.limiter.acquire(ctx, b.provider)
+		if err != nil {
+			return err
+		}
+		stream, err = b.client.CreateChatCompletionStream(ctx, req)
+		if err != nil {
+			slot()
+			return err
+		}
+		release = slot
+		return nil
+	})
+	if err != nil {
+		cancel()
+		return nil, "", nil, fmt.Errorf("chat completion stream failed: %w", classifyLLMError(err))
+	}
+	done := func() {
+		cancel()
+		release()
+	}
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			stream.Close()
+			done()
+			return nil, "", nil, fmt.Errorf("no response from LLM")
+		}
+		if err != nil {
+			stream.Close()
+			done()
+			return nil, "", nil, fmt.Errorf("chat completion stream failed: %w", classifyLLMError(err))
+		}
+		if text := streamText(resp); text != "" {
+			return stream, text, done, nil
+		}
+	}
+}
+
+// relayStream sends the text of the stream to chunks until it ends, then
+// meters the usage counted with the model's tokenizer
+func (b *llmBackend) relayStream(
+	ctx context.Context,
+	stream *openai.ChatCompletionStream,
+	first string,
+	c completion,
+	chunks chan<- string,
+	start time.Time,
+) {
+	defer close(chunks)
+	defer stream.Close()
+	defer TrackTime(ctx, TimingLLM, start)
+
+	var reply strings.Builder
+	send := func(text string) bool {
+		reply.WriteString(text)
+		select {
+		case chunks <- text:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	defer func() {
+		usage := openai.Usage{
+			PromptTokens:     b.promptTokens(c),
+			CompletionTokens: b.countTokens(reply.String()),
+		}
+		meterUsage(ctx, b.provider, usage, b.price.cost(usage))
+	}()
+
+	if !send(first) {
+		return
+	}
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				logging.Printf(ctx, "⚠️  LLM stream of %s broke off: %v", b.provider, err)
+			}
+			return
+		}
+		if text := streamText(resp); text != "" && !send(text) {
+			return
+		}
+	}
+}
+
+func streamText(resp openai.ChatCompletionStreamResponse) string {
+	if len(resp.Choices) == 0 {
+		return ""
+	}
+	return resp.Choices[0].Delta.Content
+}
+
+// estimateTokens approximates the tokens of a text of n runes (about three
+// runes per token for mixed Russian and English text) when the tokenizer is
+// not available
+func estimateTokens(runes int) int {
+	return (runes + 2) / 3
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+)
+
+// LLM tasks (LLM_TASK_MODELS): the kinds of calls that may go to a model of
+// their own, e.g. a cheap fast one for query enhancement and mode selection
+// and a strong one for the final answer
+const (
+	TaskQueryEnhance  = "enhance"   // rewriting the query for search
+	TaskModeSelection = "route"     // picking the mode of auto mode
+	TaskPlanning      = "plan"      // sub-questions, hops and follow-up searches
+	TaskExtraction    = "extract"   // structured data: constraints, entities, conflicts, schemas
+	TaskSummary       = "summarize" // source and conversation summaries
+	TaskVerification  = "verify"    // claims and answer checks against the sources
+	TaskSynthesis     = "synthesis" // the answer
+)
+
+var llmTasks = []string{
+	TaskQueryEnhance, TaskModeSelection, TaskPlanning, TaskExtraction,
+	TaskSummary, TaskVerification, TaskSynthesis,
+}
+
+// ForTask returns the client for the calls of task: the same providers, with
+// the task's model from LLM_TASK_MODELS on the primary one. Tasks without a
+// model of their own, and the fallbacks, keep the configured models.
+func (l *LLMClient) ForTask(task string) *LLMClient {
+	if client, ok := l.tasks[task]; ok {
+		return client
+	}
+	if l.base != nil {
+		return l.base
+	}
+	return l
+}
+
+// initTasks builds the clients of the tasks with a model of their own, from
+// LLM_TASK_MODELS entries (task=model)
+func (l *LLMClient) initTasks(cfg *config.Config) {
+	if len(l.backends) == 0 {
+		return
+	}
+
+	tasks := make(map[string]*LLMClient)
+	for _, entry := range cfg.LLMTaskModels {
+		task, model, ok := strings.Cut(entry, "=")
+		task, model = strings.ToLower(strings.TrimSpace(task)), strings.TrimSpace(model)
+		if !ok || model == "" || !slices.Contains(llmTasks, task) {
+			log.Printf("⚠️  Invalid LLM_TASK_MODELS entry %q, expected task=model with a task of %s",
+				entry, strings.Join(llmTasks, ", "))
+			continue
+		}
+		if model == l.backends[0].model {
+			continue
+		}
+
+		client := *l
+		client.backends = slices.Clone(l.backends)
+		client.backends[0] = l.backends[0].withModel(cfg, model)
+		client.base = l
+		tasks[task] = &client
+		log.Printf("🧩 LLM task %s: %s (%s)", task, model, l.backends[0].provider)
+	}
+	// The task clients share the map, so ForTask works on any of them too
+	for _, client := range tasks {
+		client.tasks = tasks
+	}
+	l.tasks = tasks
+}
+
+// withModel returns a copy of the backend serving model
+func (b *llmBackend) withModel(cfg *config.Config, model string) *llmBackend {
+	backend := *b
+	backend.setModel(cfg, model)
+	return &backend
+}
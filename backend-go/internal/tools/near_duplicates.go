@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+const (
+	// shingleSize is the number of consecutive words compared
+	shingleSize = 3
+	// minShingles keeps short snippets, which share phrases by chance, from
+	// being compared at all
+	minShingles = 10
+	// nearDuplicateOverlap is the share of the shorter text's shingles the
+	// other text must contain for the two to be copies of one article
+	nearDuplicateOverlap = 0.8
+)
+
+// DropNearDuplicates removes results that are syndicated copies of another
+// result: the word shingles of one are mostly contained in the other's, even
+// under a different URL. Of each group of copies the most credible is kept,
+// in the position of the first. It also returns how many were dropped.
+func DropNearDuplicates(results []models.TavilyResult) ([]models.TavilyResult, int) {
+	sets := make([]map[uint64]struct{}, len(results))
+	for i, result := range results {
+		sets[i] = shingles(result.Title + " " + result.Content)
+	}
+
+	kept := make([]int, 0, len(results)) // indexes into results
+	for i := range results {
+		copyOf := -1
+		for k, j := range kept {
+			if nearDuplicate(sets[i], sets[j]) {
+				copyOf = k
+				break
+			}
+		}
+		switch {
+		case copyOf == -1:
+			kept = append(kept, i)
+		case results[i].Credibility > results[kept[copyOf]].Credibility:
+			kept[copyOf] = i
+		}
+	}
+
+	if len(kept) == len(results) {
+		return results, 0
+	}
+	unique := make([]models.TavilyResult, len(kept))
+	for k, i := range kept {
+		unique[k] = results[i]
+	}
+	return unique, len(results) - len(kept)
+}
+
+// shingles hashes every run of shingleSize consecutive words of text
+func shingles(text string) map[uint64]struct{} {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	set := make(map[uint64]struct{})
+	for i := 0; i+shingleSize <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+shingleSize], " ")))
+		set[h.Sum64()] = struct{}{}
+	}
+	return set
+}
+
+func nearDuplicate(a, b map[uint64]struct{}) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) < minShingles {
+		return false
+	}
+	shared := 0
+	for s := range a {
+		if _, ok := b[s]; ok {
+			shared++
+		}
+	}
+	return float64(shared) >= nearDuplicateOverlap*float64(len(a))
+}
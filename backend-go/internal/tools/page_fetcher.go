@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/cache"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/netguard"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	// maxPageSize is how much of a page is downloaded
+	maxPageSize = 2 << 20
+	// maxPageText bounds the extracted text of a page, in runes
+	maxPageText = 20000
+)
+
+// PageContent is a fetched page reduced to its metadata and readable text
+type PageContent struct {
+	URL       string       `json:"url"` // final URL after redirects
+	Metadata  PageMetadata `json:"metadata"`
+	Title     string       `json:"title"` // headline of the article
+	Text      string       `json:"text"`
+	FetchedAt int64        `json:"fetched_at"` // unix seconds
+}
+
+// PageFetcher downloads HTML pages and extracts their metadata and text.
+// Extracted pages are kept in the disk page cache when one is configured.
+// The URLs come from search results, pages and the model, so only public
+// addresses are connected to, redirects included.
+type PageFetcher struct {
+	client *resty.Client
+	cache  *cache.PageCache // nil disables caching
+}
+
+func NewPageFetcher(pageCache *cache.PageCache) *PageFetcher {
+	client := resty.New()
+	client.SetTransport(netguard.Transport())
+	client.SetTimeout(10 * time.Second)
+	client.SetRedirectPolicy(resty.FlexibleRedirectPolicy(3))
+	client.SetHeader("Accept", "text/html")
+
+	return &PageFetcher{client: Polite(client), cache: pageCache}
+}
+
+// Fetch returns the cached page or downloads and extracts it
+func (f *PageFetcher) Fetch(ctx context.Context, pageURL string) (*PageContent, error) {
+	if f.cache != nil {
+		if data, ok := f.cache.Get(pageURL); ok {
+			var page PageContent
+			if err := json.Unmarshal(data, &page); err == nil {
+				return &page, nil
+			}
+		}
+	}
+
+	page, err := f.download(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.cache != nil {
+		if data, err := json.Marshal(page); err == nil {
+			f.cache.Put(pageURL, data)
+		}
+	}
+	return page, nil
+}
+
+func (f *PageFetcher) download(ctx context.Context, pageURL string) (*PageContent, error) {
+	resp, err := f.client.R().
+		SetContext(ctx).
+		SetDoNotParseResponse(true).
+		Get(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	body := resp.RawBody()
+	defer body.Close()
+	if resp.IsError() {
+		return nil, fmt.Errorf("status %d", resp.StatusCode())
+	}
+	if contentType := resp.Header().Get("Content-Type"); !strings.Contains(contentType, "html") {
+		return nil, fmt.Errorf("not an HTML page: %s", contentType)
+	}
+
+	var raw bytes.Buffer
+	if _, err := raw.ReadFrom(io.LimitReader(body, maxPageSize)); err != nil && raw.Len() == 0 {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Relative URLs in the metadata resolve against the final URL
+	finalURL := resp.RawResponse.Request.URL
+	meta := ExtractPageMetadata(doc)
+	meta.Favicon = resolveURL(finalURL, meta.Favicon)
+	meta.Image = resolveURL(finalURL, meta.Image)
+
+	// The headline is read before the text extraction strips the page chrome
+	title := articleTitle(doc)
+
+	return &PageContent{
+		URL:       finalURL.String(),
+		Metadata:  meta,
+		Title:     title,
+		Text:      extractPageText(doc),
+		FetchedAt: time.Now().Unix(),
+	}, nil
+}
+
+// extractPageText returns the readable text of the article (see
+// articleRoot): headings, paragraphs and list items without page chrome
+func extractPageText(doc *goquery.Document) string {
+	doc.Find("script, style, noscript, template, svg, nav, header, footer, aside, form, iframe").Remove()
+
+	root := articleRoot(doc)
+
+	var text strings.Builder
+	root.Find("h1, h2, h3, p, li, blockquote, pre").Each(func(_ int, s *goquery.Selection) {
+		// Text of nested blocks (a paragraph inside a list item) is taken once
+		if s.ParentsFiltered("p, li, blockquote, pre").Length() > 0 {
+			return
+		}
+		block := strings.Join(strings.Fields(s.Text()), " ")
+		if block == "" || s.Is("li") && linkDensity(s) > maxLinkDensity {
+			return
+		}
+		text.WriteString(block)
+		text.WriteString("\n")
+	})
+	if text.Len() == 0 {
+		return utils.TruncateRunes(strings.Join(strings.Fields(root.Text()), " "), maxPageText)
+	}
+	return utils.TruncateRunes(strings.TrimSpace(text.String()), maxPageText)
+}
@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// PageMetadata holds metadata extracted from a fetched HTML page
+type PageMetadata struct {
+	Title       string
+	Description string
+	PublishedAt int64 // unix seconds, 0 if unknown
+	ModifiedAt  int64 // unix seconds, 0 if unknown
+
+	// Open Graph data for source cards; URLs are as written in the page and
+	// may be relative
+	SiteName string
+	Image    string
+	Favicon  string
+}
+
+// Meta tags that carry publication/modification dates, in priority order
+var (
+	publishedMetaTags = []string{
+		"article:published_time", "og:published_time", "datePublished",
+		"pubdate", "publish-date", "date", "dc.date", "dc.date.issued",
+		"citation_publication_date", "sailthru.date",
+	}
+	modifiedMetaTags = []string{
+		"article:modified_time", "og:updated_time", "dateModified",
+		"last-modified", "dc.date.modified",
+	}
+)
+
+var dateLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.000000",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"02.01.2006",
+	time.RFC1123,
+	time.RFC1123Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"January 2, 2006",
+	"Jan 2, 2006",
+}
+
+// ParsePublishedDate parses the date formats returned by search engines and
+// page metadata; returns unix seconds or 0 if the value is not recognized
+func ParsePublishedDate(value string) int64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			// Ignore placeholder and future dates
+			if t.Year() < 1990 || t.After(time.Now().Add(48*time.Hour)) {
+				return 0
+			}
+			return t.Unix()
+		}
+	}
+
+	return 0
+}
+
+// ExtractPageMetadata reads title, description and dates from meta tags,
+// JSON-LD and <time> elements
+func ExtractPageMetadata(doc *goquery.Document) PageMetadata {
+	meta := PageMetadata{
+		Title:       strings.TrimSpace(doc.Find("title").First().Text()),
+		Description: metaContent(doc, "description", "og:description"),
+	}
+
+	meta.SiteName = strings.TrimSpace(metaContent(doc, "og:site_name", "application-name"))
+	meta.Image = strings.TrimSpace(metaContent(doc, "og:image", "og:image:url", "twitter:image"))
+	doc.Find(`link[rel="icon"], link[rel="shortcut icon"], link[rel="apple-touch-icon"]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		meta.Favicon = strings.TrimSpace(s.AttrOr("href", ""))
+		return meta.Favicon == ""
+	})
+
+	meta.PublishedAt = ParsePublishedDate(metaContent(doc, publishedMetaTags...))
+	meta.ModifiedAt = ParsePublishedDate(metaContent(doc, modifiedMetaTags...))
+
+	// JSON-LD (schema.org Article/NewsArticle)
+	if meta.PublishedAt == 0 || meta.ModifiedAt == 0 {
+		doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			var ld struct {
+				DatePublished string `json:"datePublished"`
+				DateModified  string `json:"dateModified"`
+			}
+			if err := json.Unmarshal([]byte(s.Text()), &ld); err != nil {
+				return true
+			}
+			if meta.PublishedAt == 0 {
+				meta.PublishedAt = ParsePublishedDate(ld.DatePublished)
+			}
+			if meta.ModifiedAt == 0 {
+				meta.ModifiedAt = ParsePublishedDate(ld.DateModified)
+			}
+			return meta.PublishedAt == 0
+		})
+	}
+
+	// <time datetime="..."> as a last resort
+	if meta.PublishedAt == 0 {
+		if datetime, ok := doc.Find("time[datetime]").First().Attr("datetime"); ok {
+			meta.PublishedAt = ParsePublishedDate(datetime)
+		}
+	}
+
+	return meta
+}
+
+func metaContent(doc *goquery.Document, names ...string) string {
+	for _, name := range names {
+		selector := `meta[property="` + name + `"], meta[name="` + name + `"], meta[itemprop="` + name + `"]`
+		if content, ok := doc.Find(selector).First().Attr("content"); ok && strings.TrimSpace(content) != "" {
+			return content
+		}
+	}
+	return ""
+}
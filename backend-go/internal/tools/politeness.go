@@ -0,0 +1,409 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	defaultCrawlerUserAgent = "Mozilla/5.0 (compatible; ResearchProBot/1.0)"
+	// robotsTTL is how long a robots.txt is kept before it is fetched again;
+	// robots.txt that failed with a server error is retried sooner
+	robotsTTL      = 24 * time.Hour
+	robotsRetryTTL = time.Hour
+	robotsTimeout  = 5 * time.Second
+	// maxRobotsSize bounds the robots.txt read, as in RFC 9309
+	maxRobotsSize = 500 << 10
+	// maxCrawlDelay caps the Crawl-delay honoured: answers are waited for
+	maxCrawlDelay = 10 * time.Second
+	// hostIdleTTL is how long a host without requests keeps its schedule and
+	// robots.txt; idle hosts are dropped at most once per hostIdleTTL
+	hostIdleTTL = time.Hour
+)
+
+// politeness is shared by the HTTP clients of the process, so that the
+// requests of every search and scraper to a host are spaced out together
+var politeness = &politeState{
+	userAgent: defaultCrawlerUserAgent,
+	agent:     robotsAgent(defaultCrawlerUserAgent),
+	interval:  500 * time.Millisecond,
+	robots:    true,
+	hosts:     map[string]*politeHost{},
+}
+
+type politeState struct {
+	mu        sync.Mutex
+	userAgent string
+	agent     string // the product token matched against robots.txt groups
+	interval  time.Duration
+	robots    bool
+	hosts     map[string]*politeHost
+	prunedAt  time.Time
+}
+
+// politeHost is the request schedule and robots.txt of a host
+type politeHost struct {
+	lastUsed time.Time // guarded by politeState.mu
+
+	mu   sync.Mutex
+	next time.Time // the earliest start of the next request
+
+	robotsMu      sync.Mutex
+	rules         *robotsRules // nil until fetched
+	rulesExpireAt time.Time
+}
+
+// ConfigurePoliteness applies the crawler settings: the User-Agent sent with
+// every request, the interval between requests to a host and whether
+// robots.txt is respected. Call it once at startup; the defaults hold until
+// then.
+func ConfigurePoliteness(cfg *config.Config) {
+	politeness.mu.Lock()
+	defer politeness.mu.Unlock()
+	if cfg.CrawlerUserAgent != "" {
+		politeness.userAgent = cfg.CrawlerUserAgent
+		politeness.agent = robotsAgent(cfg.CrawlerUserAgent)
+	}
+	politeness.interval = time.Duration(cfg.CrawlerHostIntervalMs) * time.Millisecond
+	politeness.robots = cfg.CrawlerRobotsTxt
+}
+
+// Polite sends the requests of client through the politeness layer of the
+// process: they identify the bot with the configured User-Agent, requests to
+// a host are spaced out by the host interval (or the longer Crawl-delay of
+// its robots.txt), and URLs the robots.txt disallows get a 403 response
+// without being requested. The hosts of apis are APIs, not crawled, so their
+// robots.txt is not consulted. Services of our own (localhost, private
+// addresses, Docker service names) are only identified.
+func Polite(client *resty.Client, apis ...string) *resty.Client {
+	base := client.GetClient().Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return client.SetTransport(&politeTransport{base: base, apis: apis})
+}
+
+type politeTransport struct {
+	base http.RoundTripper
+	apis []string
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	politeness.mu.Lock()
+	userAgent, interval, robots := politeness.userAgent, politeness.interval, politeness.robots
+	politeness.mu.Unlock()
+
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", userAgent)
+
+	host := req.URL.Hostname()
+	if internalHost(host) {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	h := politeness.host(req.URL.Host)
+	var rules *robotsRules
+	if robots && !slices.Contains(t.apis, host) {
+		rules = h.robotsRules(ctx, t.base, req.URL.Scheme, req.URL.Host, userAgent)
+		path := req.URL.EscapedPath()
+		if req.URL.RawQuery != "" {
+			path += "?" + req.URL.RawQuery
+		}
+		if !rules.allowed(path) {
+			logging.Printf(ctx, "🤖 robots.txt of %s disallows %s", req.URL.Host, path)
+			return robotsDisallowed(req), nil
+		}
+	}
+
+	if err := h.wait(ctx, max(interval, rules.delay())); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+func (p *politeState) host(host string) *politeHost {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if now.Sub(p.prunedAt) >= hostIdleTTL {
+		p.prune(now)
+	}
+	h, ok := p.hosts[host]
+	if !ok {
+		h = &politeHost{}
+		p.hosts[host] = h
+	}
+	h.lastUsed = now
+	return h
+}
+
+// prune drops the hosts without requests for hostIdleTTL; p.mu must be held.
+// A host whose request is still scheduled past the idle time is kept.
+func (p *politeState) prune(now time.Time) {
+	p.prunedAt = now
+	for name, h := range p.hosts {
+		if now.Sub(h.lastUsed) < hostIdleTTL {
+			continue
+		}
+		h.mu.Lock()
+		scheduled := h.next.After(now)
+		h.mu.Unlock()
+		if !scheduled {
+			delete(p.hosts, name)
+		}
+	}
+}
+
+// wait reserves the next request slot of the host and sleeps until it
+func (h *politeHost) wait(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+	h.mu.Lock()
+	now := time.Now()
+	start := now
+	if h.next.After(now) {
+		start = h.next
+	}
+	h.next = start.Add(interval)
+	h.mu.Unlock()
+
+	delay := start.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// robotsRules returns the robots.txt rules of the host for our agent,
+// fetching them when missing or expired. A robots.txt that can't be reached
+// allows everything until it is retried after robotsRetryTTL; the request
+// itself then reports the failure.
+func (h *politeHost) robotsRules(ctx context.Context, base http.RoundTripper, scheme, host, userAgent string) *robotsRules {
+	h.robotsMu.Lock()
+	defer h.robotsMu.Unlock()
+	if h.rules != nil && time.Now().Before(h.rulesExpireAt) {
+		return h.rules
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, robotsTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+"/robots.txt", nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := (&http.Client{Transport: base}).Do(req)
+	if err != nil {
+		logging.Printf(ctx, "⚠️  robots.txt of %s unreachable: %v", host, err)
+		h.rules = &robotsRules{}
+		h.rulesExpireAt = time.Now().Add(robotsRetryTTL)
+		return h.rules
+	}
+	defer resp.Body.Close()
+
+	politeness.mu.Lock()
+	agent := politeness.agent
+	politeness.mu.Unlock()
+
+	ttl := robotsTTL
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		h.rules = parseRobots(io.LimitReader(resp.Body, maxRobotsSize), agent)
+	case resp.StatusCode >= 500:
+		// RFC 9309: a robots.txt failing on the server disallows everything
+		h.rules = &robotsRules{disallowAll: true}
+		ttl = robotsRetryTTL
+	default:
+		// No robots.txt: everything is allowed
+		h.rules = &robotsRules{}
+	}
+	h.rulesExpireAt = time.Now().Add(ttl)
+	return h.rules
+}
+
+// robotsDisallowed is the response to a request the robots.txt disallows
+func robotsDisallowed(req *http.Request) *http.Response {
+	body := "disallowed by robots.txt"
+	return &http.Response{
+		Status:        "403 Forbidden",
+		StatusCode:    http.StatusForbidden,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// internalHost reports whether host is a service of ours rather than a site:
+// localhost, a private or loopback address, or a name without a dot (Docker
+// service names such as searxng)
+func internalHost(host string) bool {
+	if host == "localhost" || !strings.Contains(host, ".") && net.ParseIP(host) == nil {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
+
+// robotsAgent returns the product token of a User-Agent, matched against
+// the User-agent lines of robots.txt: ResearchProBot for
+// "Mozilla/5.0 (compatible; ResearchProBot/1.0)"
+func robotsAgent(userAgent string) string {
+	token := userAgent
+	if _, compatible, ok := strings.Cut(userAgent, "compatible;"); ok {
+		token = compatible
+	}
+	token = strings.TrimSpace(token)
+	if i := strings.IndexAny(token, "/;) "); i >= 0 {
+		token = token[:i]
+	}
+	return strings.ToLower(token)
+}
+
+// robotsRules are the rules of the robots.txt group that applies to us
+type robotsRules struct {
+	disallowAll bool
+	rules       []robotsRule
+	crawlDelay  time.Duration
+}
+
+type robotsRule struct {
+	pattern string
+	match   *regexp.Regexp
+	allow   bool
+}
+
+// allowed reports whether path (with its query) may be requested: the
+// longest matching rule decides, allow winning a tie; no match allows
+func (r *robotsRules) allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	if r.disallowAll {
+		return false
+	}
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !rule.match.MatchString(path) {
+			continue
+		}
+		if len(rule.pattern) > longest || len(rule.pattern) == longest && rule.allow {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// delay returns the Crawl-delay of the group, capped by maxCrawlDelay
+func (r *robotsRules) delay() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return min(r.crawlDelay, maxCrawlDelay)
+}
+
+// robotsPattern compiles a robots.txt path pattern, with * for any
+// characters and a trailing $ anchoring the end
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// parseRobots reads the group of robots.txt for agent, or the * group when
+// no group names it. Groups naming the agent more than once are merged.
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	var named, wildcard robotsRules
+	var hasNamed bool
+	var groupAgents []string
+	inRules := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			// A user-agent line after rules starts a new group
+			if inRules {
+				groupAgents, inRules = nil, false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+			continue
+		}
+
+		if key != "allow" && key != "disallow" && key != "crawl-delay" {
+			continue
+		}
+		inRules = true
+		var targets []*robotsRules
+		for _, groupAgent := range groupAgents {
+			switch {
+			case groupAgent == agent:
+				targets = append(targets, &named)
+				hasNamed = true
+			case groupAgent == "*":
+				targets = append(targets, &wildcard)
+			}
+		}
+		switch key {
+		case "allow", "disallow":
+			if value == "" || len(targets) == 0 {
+				continue
+			}
+			rule := robotsRule{pattern: value, match: robotsPattern(value), allow: key == "allow"}
+			for _, target := range targets {
+				target.rules = append(target.rules, rule)
+			}
+		case "crawl-delay":
+			seconds, err := time.ParseDuration(value + "s")
+			if err != nil {
+				continue
+			}
+			for _, target := range targets {
+				target.crawlDelay = seconds
+			}
+		}
+	}
+
+	if hasNamed {
+		return &named
+	}
+	return &wildcard
+}
@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// previewFetchBudget bounds the time spent on previews of one answer
+	previewFetchBudget = 3 * time.Second
+	// previewConcurrency limits parallel page fetches per answer
+	previewConcurrency = 5
+)
+
+// PreviewFetcher builds source cards (site name, favicon, image) from the
+// Open Graph metadata of cited pages. Previews are cached by URL in Redis when
+// available and in process memory otherwise; pages that fail to load are
+// cached with a fallback preview so they are not fetched again.
+type PreviewFetcher struct {
+	pages *PageFetcher
+	redis *redis.Client
+	ttl   time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]previewEntry
+}
+
+type previewEntry struct {
+	preview   models.SourcePreview
+	expiresAt time.Time
+}
+
+func NewPreviewFetcher(pages *PageFetcher, redisClient *redis.Client, ttl time.Duration) *PreviewFetcher {
+	f := &PreviewFetcher{
+		pages:   pages,
+		redis:   redisClient,
+		ttl:     ttl,
+		entries: make(map[string]previewEntry),
+	}
+	if redisClient == nil {
+		go f.cleanup()
+	}
+	return f
+}
+
+// Enrich sets Preview on the sources at the given indexes; nil indexes means
+// all sources. Sources without an http(s) URL are skipped. Pages that do not
+// answer within the budget get a fallback preview built from the URL.
+func (f *PreviewFetcher) Enrich(ctx context.Context, sources []models.Source, indexes []int) {
+	if indexes == nil {
+		indexes = make([]int, len(sources))
+		for i := range sources {
+			indexes[i] = i
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, previewFetchBudget)
+	defer cancel()
+
+	sem := make(chan struct{}, previewConcurrency)
+	var wg sync.WaitGroup
+	for _, i := range indexes {
+		if i < 0 || i >= len(sources) || sources[i].Preview != nil {
+			continue
+		}
+		if _, ok := fallbackPreview(sources[i].URL); !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(source *models.Source) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			preview := f.Preview(ctx, source.URL)
+			source.Preview = &preview
+		}(&sources[i])
+	}
+	wg.Wait()
+}
+
+// Preview returns the cached preview of the page or fetches it
+func (f *PreviewFetcher) Preview(ctx context.Context, pageURL string) models.SourcePreview {
+	key := previewKey(pageURL)
+	if preview, ok := f.get(ctx, key); ok {
+		return preview
+	}
+
+	preview, fetched := f.fetch(ctx, pageURL)
+	// A page cut off by the answer budget is not cached so a later answer
+	// can still get its card
+	if fetched || ctx.Err() == nil {
+		f.set(ctx, key, preview)
+	}
+	return preview
+}
+
+// fetch loads the page and reads its metadata; on failure it returns the
+// fallback preview and false
+func (f *PreviewFetcher) fetch(ctx context.Context, pageURL string) (models.SourcePreview, bool) {
+	preview, _ := fallbackPreview(pageURL)
+
+	page, err := f.pages.Fetch(ctx, pageURL)
+	if err != nil {
+		return preview, false
+	}
+	if page.Metadata.SiteName != "" {
+		preview.SiteName = page.Metadata.SiteName
+	}
+	if page.Metadata.Favicon != "" {
+		preview.Favicon = page.Metadata.Favicon
+	}
+	preview.Image = page.Metadata.Image
+	return preview, true
+}
+
+// fallbackPreview uses the host as site name and /favicon.ico as favicon;
+// false for URLs that are not http(s)
+func fallbackPreview(pageURL string) (models.SourcePreview, bool) {
+	u, err := url.Parse(pageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.SourcePreview{}, false
+	}
+	return models.SourcePreview{
+		SiteName: strings.TrimPrefix(u.Hostname(), "www."),
+		Favicon:  u.Scheme + "://" + u.Host + "/favicon.ico",
+	}, true
+}
+
+// resolveURL makes ref absolute; "" for empty or non-http(s) references
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+func previewKey(pageURL string) string {
+	sum := sha256.Sum256([]byte(pageURL))
+	return "preview:" + hex.EncodeToString(sum[:16])
+}
+
+func (f *PreviewFetcher) get(ctx context.Context, key string) (models.SourcePreview, bool) {
+	var preview models.SourcePreview
+
+	if f.redis != nil {
+		data, err := f.redis.Get(ctx, key).Bytes()
+		if err != nil {
+			if err != redis.Nil {
+				log.Printf("⚠️  Preview cache read failed: %v", err)
+			}
+			return preview, false
+		}
+		return preview, json.Unmarshal(data, &preview) == nil
+	}
+
+	f.mu.RLock()
+	entry, ok := f.entries[key]
+	f.mu.RUnlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return preview, false
+	}
+	return entry.preview, true
+}
+
+func (f *PreviewFetcher) set(ctx context.Context, key string, preview models.SourcePreview) {
+	if f.redis != nil {
+		data, err := json.Marshal(preview)
+		if err != nil {
+			return
+		}
+		// The answer context may already be done; the write must not depend on it
+		writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		if err := f.redis.Set(writeCtx, key, data, f.ttl).Err(); err != nil {
+			log.Printf("⚠️  Preview cache write failed: %v", err)
+		}
+		return
+	}
+
+	f.mu.Lock()
+	f.entries[key] = previewEntry{preview: preview, expiresAt: time.Now().Add(f.ttl)}
+	f.mu.Unlock()
+}
+
+// cleanup removes expired in-memory entries
+func (f *PreviewFetcher) cleanup() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		f.mu.Lock()
+		for key, entry := range f.entries {
+			if now.After(entry.expiresAt) {
+				delete(f.entries, key)
+			}
+		}
+		f.mu.Unlock()
+	}
+}
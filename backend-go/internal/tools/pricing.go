@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// llmPrice is what a model charges per 1K prompt and completion tokens, in USD
+type llmPrice struct {
+	prompt     float64
+	completion float64
+}
+
+// cost returns the cost of usage at the price
+func (p llmPrice) cost(usage openai.Usage) float64 {
+	return (float64(usage.PromptTokens)*p.prompt + float64(usage.CompletionTokens)*p.completion) / 1000
+}
+
+// priceOf returns the price of model from LLM_PRICING entries
+// (model=prompt/completion), or the LLM_PROMPT_PRICE_PER_1K and
+// LLM_COMPLETION_PRICE_PER_1K prices when the table doesn't list it
+func priceOf(cfg *config.Config, model string) llmPrice {
+	for _, entry := range cfg.LLMPricing {
+		name, prices, ok := strings.Cut(entry, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), model) {
+			continue
+		}
+		prompt, completion, _ := strings.Cut(prices, "/")
+		promptPrice, err1 := strconv.ParseFloat(strings.TrimSpace(prompt), 64)
+		completionPrice, err2 := strconv.ParseFloat(strings.TrimSpace(completion), 64)
+		if err1 != nil || (completion != "" && err2 != nil) {
+			log.Printf("⚠️  Invalid LLM_PRICING entry %q, expected model=prompt/completion", entry)
+			break
+		}
+		return llmPrice{prompt: promptPrice, completion: completionPrice}
+	}
+	return llmPrice{prompt: cfg.LLMPromptPricePer1K, completion: cfg.LLMCompletionPricePer1K}
+}
+
+// EstimateCost returns the cost of prompt and completion tokens at the price
+// of the primary model
+func (l *LLMClient) EstimateCost(promptTokens, completionTokens int64) float64 {
+	if len(l.backends) == 0 {
+		return 0
+	}
+	return l.backends[0].price.cost(openai.Usage{
+		PromptTokens:     int(promptTokens),
+		CompletionTokens: int(completionTokens),
+	})
+}
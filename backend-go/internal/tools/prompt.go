@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Prompt is a single-turn request with its instructions apart from the
+// material: the system prompt says how to answer, the examples show it on
+// sample questions and the user message carries the question and its
+// context. Models follow instructions sent this way more reliably than ones
+// buried in a single message.
+type Prompt struct {
+	System   string
+	Examples []Example // few-shot turns, before the user message
+	User     string
+}
+
+// Example is a few-shot turn: a sample user message and the expected reply
+type Example struct {
+	User      string
+	Assistant string
+}
+
+// messages returns the chat messages of the prompt
+func (p Prompt) messages() []openai.ChatCompletionMessage {
+	messages := make([]openai.ChatCompletionMessage, 0, 2+2*len(p.Examples))
+	if p.System != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: p.System})
+	}
+	for _, example := range p.Examples {
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: example.User},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: example.Assistant},
+		)
+	}
+	return append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: p.User})
+}
+
+// CompletePrompt is Complete with a system prompt and few-shot examples
+func (l *LLMClient) CompletePrompt(ctx context.Context, p Prompt, temperature float32, maxTokens int) (string, error) {
+	return l.createCompletion(ctx, completion{
+		messages:    p.messages(),
+		temperature: temperature,
+		maxTokens:   maxTokens,
+	})
+}
+
+// PromptTokens returns the tokens p takes in the context window of the
+// primary model, the chat format included
+func (l *LLMClient) PromptTokens(p Prompt) int {
+	c := completion{messages: p.messages()}
+	if len(l.backends) == 0 {
+		tokens := replyTokens
+		for _, msg := range c.messages {
+			tokens += messageTokens + estimateTokens(len([]rune(msg.Content)))
+		}
+		return tokens
+	}
+	return l.backends[0].promptTokens(c)
+}
+
+// foldSystemMessages turns the system messages into the start of the first
+// user message, for models that reject the system role
+func foldSystemMessages(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	var system []string
+	folded := make([]openai.ChatCompletionMessage, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == openai.ChatMessageRoleSystem {
+			system = append(system, msg.Content)
+			continue
+		}
+		if len(system) > 0 && msg.Role == openai.ChatMessageRoleUser {
+			msg.Content = strings.Join(system, "\n\n") + "\n\n" + msg.Content
+			system = nil
+		}
+		folded = append(folded, msg)
+	}
+	return folded
+}
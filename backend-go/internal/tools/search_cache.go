@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// SearchCache stores the results of each search provider by provider,
+// normalized query and search parameters, in Redis when available and in
+// process memory otherwise. The same query asked again (a benchmark re-run,
+// a popular question, Pro's sub-queries repeating) skips the provider calls.
+// Failed and empty searches are not cached.
+type SearchCache struct {
+	redis *redis.Client
+	ttl   time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]searchCacheEntry
+
+	statsMu sync.Mutex
+	stats   map[string]*models.SearchCacheStats // by provider, since start
+}
+
+type searchCacheEntry struct {
+	Results   []models.TavilyResult `json:"results"`
+	Answer    string                `json:"answer,omitempty"`
+	expiresAt time.Time
+}
+
+func NewSearchCache(redisClient *redis.Client, ttl time.Duration) *SearchCache {
+	c := &SearchCache{
+		redis:   redisClient,
+		ttl:     ttl,
+		entries: make(map[string]searchCacheEntry),
+		stats:   make(map[string]*models.SearchCacheStats),
+	}
+	if redisClient == nil {
+		go c.cleanup()
+	}
+	return c
+}
+
+// WithCache makes the searches go through cache; nil disables caching
+func (s *SearchClient) WithCache(cache *SearchCache) *SearchClient {
+	s.cache = cache
+	return s
+}
+
+func searchCacheKey(provider, query string, params []string) string {
+	query = strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	sum := sha256.Sum256([]byte(query + "\x00" + strings.Join(params, "\x00")))
+	return "search:" + provider + ":" + hex.EncodeToString(sum[:16])
+}
+
+// search returns the cached results of provider for the query and params, or
+// runs the search and caches what it finds. A nil cache always searches.
+func (c *SearchCache) search(
+	ctx context.Context,
+	provider, query string,
+	params []string,
+	search func() ([]models.TavilyResult, string, error),
+) ([]models.TavilyResult, string, error) {
+	if c == nil {
+		return search()
+	}
+
+	key := searchCacheKey(provider, query, params)
+	if entry, ok := c.get(ctx, key); ok {
+		c.record(provider, true)
+		logging.Printf(ctx, "  💾 %s: %d cached results", provider, len(entry.Results))
+		return entry.Results, entry.Answer, nil
+	}
+	c.record(provider, false)
+
+	results, answer, err := search()
+	if err == nil && len(results) > 0 {
+		c.set(ctx, key, searchCacheEntry{Results: results, Answer: answer})
+	}
+	return results, answer, err
+}
+
+func (c *SearchCache) get(ctx context.Context, key string) (searchCacheEntry, bool) {
+	var entry searchCacheEntry
+
+	if c.redis != nil {
+		data, err := c.redis.Get(ctx, key).Bytes()
+		if err != nil {
+			if err != redis.Nil {
+				log.Printf("⚠️  Search cache read failed: %v", err)
+			}
+			return entry, false
+		}
+		return entry, json.Unmarshal(data, &entry) == nil
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return entry, false
+	}
+	// The caller may change the results it gets
+	entry.Results = slices.Clone(entry.Results)
+	return entry, true
+}
+
+func (c *SearchCache) set(ctx context.Context, key string, entry searchCacheEntry) {
+	if c.redis != nil {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		// The search context may already be done; the write must not depend on it
+		writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		if err := c.redis.Set(writeCtx, key, data, c.ttl).Err(); err != nil {
+			log.Printf("⚠️  Search cache write failed: %v", err)
+		}
+		return
+	}
+
+	entry.Results = slices.Clone(entry.Results)
+	entry.expiresAt = time.Now().Add(c.ttl)
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+}
+
+func (c *SearchCache) record(provider string, hit bool) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	stats, ok := c.stats[provider]
+	if !ok {
+		stats = &models.SearchCacheStats{}
+		c.stats[provider] = stats
+	}
+	if hit {
+		stats.Hits++
+	} else {
+		stats.Misses++
+	}
+}
+
+// Stats returns the hits and misses of the cache since the process started,
+// in total and per provider
+func (c *SearchCache) Stats() *models.SearchCacheStats {
+	if c == nil {
+		return nil
+	}
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	total := &models.SearchCacheStats{PerProvider: make(map[string]models.SearchCacheStats, len(c.stats))}
+	for provider, stats := range c.stats {
+		perProvider := *stats
+		perProvider.HitRate = hitRate(perProvider.Hits, perProvider.Misses)
+		total.PerProvider[provider] = perProvider
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+	}
+	total.HitRate = hitRate(total.Hits, total.Misses)
+	return total
+}
+
+func hitRate(hits, misses int64) float64 {
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+// cleanup removes expired in-memory entries
+func (c *SearchCache) cleanup() {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		c.mu.Lock()
+		for key, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
@@ -3,26 +3,42 @@ package tools
 import (
 	"context"
 	"fmt"
-	"log"
-	"math/rand"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
 	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
 	"github.com/go-resty/resty/v2"
 )
 
 type SearchClient struct {
 	client      *resty.Client
-	userAgents  []string
-	lastReqTime time.Time
 	searxngURL  string
 	braveAPIKey string
+	// Tavily Search API, tried first when its key is set
+	tavilyAPIKey string
+	tavilyDepth  string
+	serpAPIKey   string       // SerpAPI, after Tavily
+	pages        *PageFetcher // fills RawContent when requested; nil disables
+	cache        *SearchCache // results by provider and query; nil disables
 }
 
+const (
+	// rawContentPages is how many top results get their page text
+	rawContentPages = 5
+	// rawContentBudget bounds the time page fetches add to a search
+	rawContentBudget = 4 * time.Second
+	// maxRawContent bounds the page text kept per result, in runes
+	maxRawContent = 4000
+)
+
 func NewSearchClient() *SearchClient {
 	client := resty.New()
 	client.SetTimeout(20 * time.Second)
@@ -34,30 +50,75 @@ func NewSearchClient() *SearchClient {
 		searxngURL = "http://searxng:8080" // Docker service name
 	}
 
+	// The APIs of the providers are not crawled; the DuckDuckGo HTML page is
+	apis := []string{"api.search.brave.com", "api.tavily.com", "serpapi.com", "api.duckduckgo.com"}
+	if u, err := url.Parse(searxngURL); err == nil {
+		apis = append(apis, u.Hostname())
+	}
+
 	return &SearchClient{
-		client:      client,
+		client:      Polite(client, apis...),
 		searxngURL:  searxngURL,
 		braveAPIKey: os.Getenv("BRAVE_SEARCH_API_KEY"),
-		userAgents: []string{
-			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-		},
 	}
 }
 
-func (s *SearchClient) getRandomUserAgent() string {
-	return s.userAgents[rand.Intn(len(s.userAgents))]
+// WithPageFetcher makes searches with includeRawContent fetch the text of the
+// top result pages
+func (s *SearchClient) WithPageFetcher(pages *PageFetcher) *SearchClient {
+	s.pages = pages
+	return s
+}
+
+// ProvidersConfigured reports whether Tavily, SerpAPI, SearXNG or the Brave
+// Search API is configured; without them searches rely on the DuckDuckGo
+// fallbacks
+func (s *SearchClient) ProvidersConfigured() bool {
+	return s.tavilyAPIKey != "" || s.serpAPIKey != "" || s.ImageProvidersConfigured()
 }
 
-func (s *SearchClient) rateLimit() {
-	elapsed := time.Since(s.lastReqTime)
-	minDelay := 500 * time.Millisecond
+// ImageProvidersConfigured reports whether SearXNG or the Brave Search API,
+// the providers of image search, is configured
+func (s *SearchClient) ImageProvidersConfigured() bool {
+	return os.Getenv("SEARXNG_URL") != "" || s.braveAPIKey != ""
+}
+
+// Providers lists the web search providers in the order they are tried
+func (s *SearchClient) Providers() []string {
+	var providers []string
+	if s.tavilyAPIKey != "" {
+		providers = append(providers, "tavily")
+	}
+	if s.serpAPIKey != "" {
+		providers = append(providers, "serpapi")
+	}
+	providers = append(providers, "searxng")
+	if s.braveAPIKey != "" {
+		providers = append(providers, "brave")
+	}
+	return append(providers, "duckduckgo")
+}
 
-	if elapsed < minDelay {
-		time.Sleep(minDelay - elapsed)
+// Ping checks that the SearXNG backend is up
+func (s *SearchClient) Ping(ctx context.Context) error {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		Get(s.searxngURL + "/healthz")
+	if err != nil {
+		return fmt.Errorf("searxng unreachable: %w", err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("searxng returned status %d", resp.StatusCode())
 	}
-	s.lastReqTime = time.Now()
+	return nil
+}
+
+// SearchOptions narrow a search to a time range, specific sites and/or
+// search verticals
+type SearchOptions struct {
+	TimeRange  string   // day, week, month, year (SearXNG time_range)
+	Sites      []string // restrict to these domains via site: operators
+	Categories []string // SearXNG categories, e.g. news; other providers ignore them
 }
 
 func (s *SearchClient) Search(
@@ -66,37 +127,109 @@ func (s *SearchClient) Search(
 	maxResults int,
 	includeRawContent bool,
 ) (*models.TavilySearchResponse, error) {
-	log.Printf("🔍 Multi-source search for: %s", query)
+	return s.SearchWithOptions(ctx, query, maxResults, includeRawContent, SearchOptions{})
+}
+
+func (s *SearchClient) SearchWithOptions(
+	ctx context.Context,
+	query string,
+	maxResults int,
+	includeRawContent bool,
+	opts SearchOptions,
+) (*models.TavilySearchResponse, error) {
+	query = applySiteFilters(query, opts.Sites)
+	logging.Printf(ctx, "🔍 Multi-source search for: %s", query)
+
+	// A period detected in the query narrows the engines that support it
+	dateRange := DateRangeFromContext(ctx)
+	timeRange := opts.TimeRange
+	if timeRange == "" {
+		timeRange = RecencyWindow(dateRange)
+	}
 
 	var allResults []models.TavilyResult
 
+	// Track provider failures so an outage is not reported as "nothing found"
+	var attempts, failures int
+	var lastErr error
+	collect := func(results []models.TavilyResult, err error) []models.TavilyResult {
+		attempts++
+		if err != nil {
+			failures++
+			lastErr = err
+		}
+		allResults = append(allResults, results...)
+		return results
+	}
+
+	// Strategy 0: Tavily (Primary when configured - with an answer and page content)
+	var answer string
+	if s.tavilyAPIKey != "" {
+		params := []string{strconv.Itoa(maxResults), strconv.FormatBool(includeRawContent), timeRange,
+			strings.Join(opts.Sites, ","), strings.Join(opts.Categories, ","), s.tavilyDepth}
+		tavilyResults, tavilyAnswer, err := s.cache.search(ctx, "tavily", query, params, func() ([]models.TavilyResult, string, error) {
+			return s.tryTavily(ctx, query, maxResults, includeRawContent, timeRange, opts)
+		})
+		answer = tavilyAnswer
+		collect(tavilyResults, err)
+		logging.Printf(ctx, "  📊 Tavily: %d results", len(tavilyResults))
+	}
+
+	// Strategy 0b: SerpAPI (Google results, when configured)
+	if len(allResults) < 3 && s.serpAPIKey != "" {
+		limit := maxResults - len(allResults)
+		serpResults := collect(s.cached(ctx, "serpapi", query, []string{strconv.Itoa(limit), timeRange, strings.Join(opts.Categories, ",")},
+			func() ([]models.TavilyResult, error) {
+				return s.trySerpAPI(ctx, query, limit, timeRange, opts.Categories)
+			}))
+		logging.Printf(ctx, "  📊 SerpAPI: %d results", len(serpResults))
+	}
+
 	// Strategy 1: SearXNG (Primary - aggregates multiple search engines)
-	searxngResults := s.trySearXNG(ctx, query, maxResults)
-	allResults = append(allResults, searxngResults...)
-	log.Printf("  📊 SearXNG: %d results", len(searxngResults))
+	if len(allResults) < 3 {
+		limit := maxResults - len(allResults)
+		searxngResults := collect(s.cached(ctx, "searxng", query, []string{strconv.Itoa(limit), timeRange, strings.Join(opts.Categories, ",")},
+			func() ([]models.TavilyResult, error) {
+				return s.trySearXNG(ctx, query, limit, timeRange, opts.Categories)
+			}))
+		logging.Printf(ctx, "  📊 SearXNG: %d results", len(searxngResults))
+	}
 
 	// Strategy 2: Brave Search API (Fallback)
 	if len(allResults) < 3 && s.braveAPIKey != "" {
-		s.rateLimit()
-		braveResults := s.tryBraveSearchAPI(ctx, query, maxResults-len(allResults))
-		allResults = append(allResults, braveResults...)
-		log.Printf("  📊 Brave API: %d results", len(braveResults))
+		limit, freshness := maxResults-len(allResults), braveFreshness(dateRange)
+		braveResults := collect(s.cached(ctx, "brave", query, []string{strconv.Itoa(limit), freshness},
+			func() ([]models.TavilyResult, error) {
+				return s.tryBraveSearchAPI(ctx, query, limit, freshness)
+			}))
+		logging.Printf(ctx, "  📊 Brave API: %d results", len(braveResults))
 	}
 
 	// Strategy 3: DuckDuckGo Instant Answer (Additional fallback)
 	if len(allResults) < 2 {
-		s.rateLimit()
-		instantResults := s.tryInstantAnswer(ctx, query, maxResults-len(allResults))
-		allResults = append(allResults, instantResults...)
-		log.Printf("  📊 DDG Instant: %d results", len(instantResults))
+		limit := maxResults - len(allResults)
+		instantResults := collect(s.cached(ctx, "duckduckgo_instant", query, []string{strconv.Itoa(limit)},
+			func() ([]models.TavilyResult, error) {
+				return s.tryInstantAnswer(ctx, query, limit)
+			}))
+		logging.Printf(ctx, "  📊 DDG Instant: %d results", len(instantResults))
 	}
 
 	// Strategy 4: DuckDuckGo HTML (Last resort)
 	if len(allResults) < 1 {
-		s.rateLimit()
-		htmlResults := s.tryDDGHTML(ctx, query, maxResults-len(allResults))
-		allResults = append(allResults, htmlResults...)
-		log.Printf("  📊 DDG HTML: %d results", len(htmlResults))
+		limit := maxResults - len(allResults)
+		htmlResults := collect(s.cached(ctx, "duckduckgo_html", query, []string{strconv.Itoa(limit)},
+			func() ([]models.TavilyResult, error) {
+				return s.tryDDGHTML(ctx, query, limit)
+			}))
+		logging.Printf(ctx, "  📊 DDG HTML: %d results", len(htmlResults))
+	}
+
+	if len(allResults) == 0 {
+		if failures == attempts {
+			return nil, fmt.Errorf("%w: %w", ErrSearchUnavailable, lastErr)
+		}
+		return nil, ErrNoResults
 	}
 
 	// Deduplicate and limit
@@ -106,50 +239,137 @@ func (s *SearchClient) Search(
 		allResults = allResults[:maxResults]
 	}
 
-	log.Printf("✅ Total: %d unique results", len(allResults))
+	fetchedAt := time.Now().Unix()
+	for i := range allResults {
+		allResults[i].FetchedAt = fetchedAt
+	}
+
+	if includeRawContent && s.pages != nil {
+		s.attachRawContent(ctx, allResults)
+	}
+
+	// Page metadata may have dated more results, so the period is applied last
+	if dateRange != nil {
+		before := len(allResults)
+		allResults = FilterByDateRange(ctx, allResults)
+		logging.Printf(ctx, "  📅 %s: %d of %d results kept", dateRange.Label, len(allResults), before)
+		if len(allResults) == 0 {
+			return nil, ErrNoResults
+		}
+	}
+
+	logging.Printf(ctx, "✅ Total: %d unique results", len(allResults))
 	return &models.TavilySearchResponse{
 		Results: allResults,
 		Query:   query,
+		Answer:  answer,
 	}, nil
 }
 
+// cached runs the search of a provider through the search cache; params
+// are the search parameters besides the query
+func (s *SearchClient) cached(
+	ctx context.Context,
+	provider, query string,
+	params []string,
+	search func() ([]models.TavilyResult, error),
+) ([]models.TavilyResult, error) {
+	results, _, err := s.cache.search(ctx, provider, query, params, func() ([]models.TavilyResult, string, error) {
+		results, err := search()
+		return results, "", err
+	})
+	return results, err
+}
+
+// attachRawContent sets RawContent (and a missing PublishedAt) from the pages
+// of the top results; pages that fail or miss the budget are skipped
+func (s *SearchClient) attachRawContent(ctx context.Context, results []models.TavilyResult) {
+	defer TrackTime(ctx, TimingPageFetch, time.Now())
+	ctx, cancel := context.WithTimeout(ctx, rawContentBudget)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var fetched atomic.Int32
+	for i := range results {
+		if i >= rawContentPages {
+			break
+		}
+		// Tavily sends the page content along
+		if results[i].RawContent != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(result *models.TavilyResult) {
+			defer wg.Done()
+			page, err := s.pages.Fetch(ctx, result.URL)
+			if err != nil || len(page.Text) <= len(result.Content) {
+				return
+			}
+			result.RawContent = utils.TruncateRunes(page.Text, maxRawContent)
+			if result.PublishedAt == 0 {
+				result.PublishedAt = page.Metadata.PublishedAt
+			}
+			// Engines cut long titles short; the page has the whole headline
+			if page.Title != "" && (result.Title == "" || strings.HasSuffix(result.Title, "...") || strings.HasSuffix(result.Title, "…")) {
+				result.Title = page.Title
+			}
+			fetched.Add(1)
+		}(&results[i])
+	}
+	wg.Wait()
+	logging.Printf(ctx, "  📄 Page content: %d of %d pages", fetched.Load(), min(len(results), rawContentPages))
+}
+
 // SearXNG search (Primary method)
 func (s *SearchClient) trySearXNG(
 	ctx context.Context,
 	query string,
 	maxResults int,
-) []models.TavilyResult {
+	timeRange string,
+	categories []string,
+) ([]models.TavilyResult, error) {
+	defer TrackSearchTime(ctx, "searxng", time.Now())
+
 	type SearXNGResponse struct {
 		Results []struct {
-			Title   string  `json:"title"`
-			URL     string  `json:"url"`
-			Content string  `json:"content"`
-			Engine  string  `json:"engine"`
-			Score   float64 `json:"score"`
+			Title         string  `json:"title"`
+			URL           string  `json:"url"`
+			Content       string  `json:"content"`
+			Engine        string  `json:"engine"`
+			Score         float64 `json:"score"`
+			PublishedDate string  `json:"publishedDate"`
 		} `json:"results"`
 		Query string `json:"query"`
 	}
 
+	params := map[string]string{
+		"q":        query,
+		"format":   "json",
+		"language": "en",
+	}
+	switch timeRange {
+	case "day", "week", "month", "year":
+		params["time_range"] = timeRange
+	}
+	if len(categories) > 0 {
+		params["categories"] = strings.Join(categories, ",")
+	}
+
 	var searxResp SearXNGResponse
 	resp, err := s.client.R().
 		SetContext(ctx).
-		SetQueryParams(map[string]string{
-			"q":        query,
-			"format":   "json",
-			"language": "en",
-		}).
+		SetQueryParams(params).
 		SetResult(&searxResp).
-		SetHeader("User-Agent", s.getRandomUserAgent()).
 		Get(s.searxngURL + "/search")
 
 	if err != nil {
-		log.Printf("⚠️  SearXNG failed: %v", err)
-		return nil
+		logging.Printf(ctx, "⚠️  SearXNG failed: %v", err)
+		return nil, fmt.Errorf("searxng: %w", err)
 	}
 
 	if resp.IsError() {
-		log.Printf("⚠️  SearXNG error response: %d", resp.StatusCode())
-		return nil
+		logging.Printf(ctx, "⚠️  SearXNG error response: %d", resp.StatusCode())
+		return nil, fmt.Errorf("searxng returned status %d", resp.StatusCode())
 	}
 
 	results := make([]models.TavilyResult, 0)
@@ -169,9 +389,7 @@ func (s *SearchClient) trySearXNG(
 		}
 
 		// Truncate long content
-		if len(content) > 500 {
-			content = content[:500] + "..."
-		}
+		content = utils.TruncateRunesWithEllipsis(content, 500)
 
 		score := 0.95 - float64(i)*0.03
 		if r.Score > 0 {
@@ -179,15 +397,16 @@ func (s *SearchClient) trySearXNG(
 		}
 
 		results = append(results, models.TavilyResult{
-			Title:   r.Title,
-			URL:     r.URL,
-			Content: content,
-			Snippet: content,
-			Score:   score,
+			Title:       r.Title,
+			URL:         r.URL,
+			Content:     content,
+			Snippet:     content,
+			Score:       score,
+			PublishedAt: ParsePublishedDate(r.PublishedDate),
 		})
 	}
 
-	return results
+	return results, nil
 }
 
 // Brave Search API (Fallback)
@@ -195,9 +414,12 @@ func (s *SearchClient) tryBraveSearchAPI(
 	ctx context.Context,
 	query string,
 	maxResults int,
-) []models.TavilyResult {
+	freshness string,
+) ([]models.TavilyResult, error) {
+	defer TrackSearchTime(ctx, "brave", time.Now())
+
 	if s.braveAPIKey == "" {
-		return nil
+		return nil, nil
 	}
 
 	type BraveResponse struct {
@@ -207,31 +429,37 @@ func (s *SearchClient) tryBraveSearchAPI(
 				URL         string `json:"url"`
 				Description string `json:"description"`
 				Age         string `json:"age"`
+				PageAge     string `json:"page_age"`
 			} `json:"results"`
 		} `json:"web"`
 	}
 
+	params := map[string]string{
+		"q":     query,
+		"count": fmt.Sprintf("%d", maxResults),
+	}
+	if freshness != "" {
+		params["freshness"] = freshness
+	}
+
 	var braveResp BraveResponse
 	resp, err := s.client.R().
 		SetContext(ctx).
 		SetHeader("Accept", "application/json").
 		SetHeader("Accept-Encoding", "gzip").
 		SetHeader("X-Subscription-Token", s.braveAPIKey).
-		SetQueryParams(map[string]string{
-			"q":     query,
-			"count": fmt.Sprintf("%d", maxResults),
-		}).
+		SetQueryParams(params).
 		SetResult(&braveResp).
 		Get("https://api.search.brave.com/res/v1/web/search")
 
 	if err != nil {
-		log.Printf("⚠️  Brave API failed: %v", err)
-		return nil
+		logging.Printf(ctx, "⚠️  Brave API failed: %v", err)
+		return nil, fmt.Errorf("brave: %w", err)
 	}
 
 	if resp.IsError() {
-		log.Printf("⚠️  Brave API error: %d - %s", resp.StatusCode(), resp.String())
-		return nil
+		logging.Printf(ctx, "⚠️  Brave API error: %d - %s", resp.StatusCode(), resp.String())
+		return nil, fmt.Errorf("brave returned status %d", resp.StatusCode())
 	}
 
 	results := make([]models.TavilyResult, 0)
@@ -245,20 +473,19 @@ func (s *SearchClient) tryBraveSearchAPI(
 		}
 
 		content := r.Description
-		if len(content) > 500 {
-			content = content[:500] + "..."
-		}
+		content = utils.TruncateRunesWithEllipsis(content, 500)
 
 		results = append(results, models.TavilyResult{
-			Title:   r.Title,
-			URL:     r.URL,
-			Content: content,
-			Snippet: content,
-			Score:   0.9 - float64(i)*0.04,
+			Title:       r.Title,
+			URL:         r.URL,
+			Content:     content,
+			Snippet:     content,
+			Score:       0.9 - float64(i)*0.04,
+			PublishedAt: ParsePublishedDate(r.PageAge),
 		})
 	}
 
-	return results
+	return results, nil
 }
 
 // DuckDuckGo Instant Answer (Additional fallback)
@@ -266,7 +493,9 @@ func (s *SearchClient) tryInstantAnswer(
 	ctx context.Context,
 	query string,
 	maxResults int,
-) []models.TavilyResult {
+) ([]models.TavilyResult, error) {
+	defer TrackSearchTime(ctx, "duckduckgo_instant", time.Now())
+
 	type DDGResponse struct {
 		RelatedTopics []struct {
 			FirstURL string `json:"FirstURL"`
@@ -288,8 +517,11 @@ func (s *SearchClient) tryInstantAnswer(
 		SetResult(&ddgResp).
 		Get(ddgURL)
 
-	if err != nil || resp.IsError() {
-		return nil
+	if err != nil {
+		return nil, fmt.Errorf("ddg instant: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("ddg instant returned status %d", resp.StatusCode())
 	}
 
 	results := make([]models.TavilyResult, 0)
@@ -323,7 +555,7 @@ func (s *SearchClient) tryInstantAnswer(
 		}
 		if topic.FirstURL != "" && topic.Text != "" {
 			results = append(results, models.TavilyResult{
-				Title:   truncateText(topic.Text, 100),
+				Title:   utils.TruncateRunesWithEllipsis(topic.Text, 100),
 				URL:     topic.FirstURL,
 				Content: topic.Text,
 				Snippet: topic.Text,
@@ -332,7 +564,7 @@ func (s *SearchClient) tryInstantAnswer(
 		}
 	}
 
-	return results
+	return results, nil
 }
 
 // DuckDuckGo HTML (Last resort)
@@ -340,7 +572,9 @@ func (s *SearchClient) tryDDGHTML(
 	ctx context.Context,
 	query string,
 	maxResults int,
-) []models.TavilyResult {
+) ([]models.TavilyResult, error) {
+	defer TrackSearchTime(ctx, "duckduckgo_html", time.Now())
+
 	searchURL := fmt.Sprintf(
 		"https://html.duckduckgo.com/html/?q=%s",
 		url.QueryEscape(query),
@@ -348,21 +582,23 @@ func (s *SearchClient) tryDDGHTML(
 
 	resp, err := s.client.R().
 		SetContext(ctx).
-		SetHeader("User-Agent", s.getRandomUserAgent()).
 		SetHeader("Accept", "text/html,application/xhtml+xml").
 		SetHeader("Accept-Language", "en-US,en;q=0.9").
 		SetHeader("Referer", "https://duckduckgo.com/").
 		Get(searchURL)
 
-	if err != nil || resp.IsError() {
-		return nil
+	if err != nil {
+		return nil, fmt.Errorf("ddg html: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("ddg html returned status %d", resp.StatusCode())
 	}
 
 	doc, err := goquery.NewDocumentFromReader(
 		strings.NewReader(resp.String()),
 	)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("ddg html: %w", err)
 	}
 
 	results := make([]models.TavilyResult, 0)
@@ -398,7 +634,7 @@ func (s *SearchClient) tryDDGHTML(
 		}
 	})
 
-	return results
+	return results, nil
 }
 
 func (s *SearchClient) deduplicateResults(
@@ -421,9 +657,24 @@ func (s *SearchClient) deduplicateResults(
 	return unique
 }
 
-func truncateText(text string, maxLen int) string {
-	if len(text) <= maxLen {
-		return text
+// applySiteFilters appends "(site:a OR site:b)" to the query
+func applySiteFilters(query string, sites []string) string {
+	if len(sites) == 0 {
+		return query
+	}
+
+	filters := make([]string, 0, len(sites))
+	for _, site := range sites {
+		site = strings.TrimSpace(site)
+		if site != "" {
+			filters = append(filters, "site:"+site)
+		}
+	}
+	if len(filters) == 0 {
+		return query
+	}
+	if len(filters) == 1 {
+		return query + " " + filters[0]
 	}
-	return text[:maxLen] + "..."
+	return query + " (" + strings.Join(filters, " OR ") + ")"
 }
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+const serpAPISearchURL = "https://serpapi.com/search.json"
+
+// serpAPITimeRanges maps time ranges onto Google's tbs=qdr: windows
+var serpAPITimeRanges = map[string]string{
+	"day":   "qdr:d",
+	"week":  "qdr:w",
+	"month": "qdr:m",
+	"year":  "qdr:y",
+}
+
+// WithSerpAPI makes SerpAPI (Google results) a provider, tried after Tavily
+// and ahead of SearXNG; an empty key leaves it off
+func (s *SearchClient) WithSerpAPI(apiKey string) *SearchClient {
+	s.serpAPIKey = apiKey
+	return s
+}
+
+// SerpAPI search: the organic results, plus the answer box and knowledge
+// graph as direct answers and the "people also ask" entries as sources
+func (s *SearchClient) trySerpAPI(
+	ctx context.Context,
+	query string,
+	maxResults int,
+	timeRange string,
+	categories []string,
+) ([]models.TavilyResult, error) {
+	defer TrackSearchTime(ctx, "serpapi", time.Now())
+
+	type SerpAPIResult struct {
+		Title   string `json:"title"`
+		Link    string `json:"link"`
+		Snippet string `json:"snippet"`
+		Date    string `json:"date"`
+	}
+	type SerpAPIResponse struct {
+		Error          string          `json:"error"`
+		OrganicResults []SerpAPIResult `json:"organic_results"`
+		NewsResults    []SerpAPIResult `json:"news_results"`
+		AnswerBox      struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Answer  string `json:"answer"`
+			Snippet string `json:"snippet"`
+		} `json:"answer_box"`
+		KnowledgeGraph struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Website     string `json:"website"`
+			Source      struct {
+				Link string `json:"link"`
+			} `json:"source"`
+		} `json:"knowledge_graph"`
+		RelatedQuestions []struct {
+			Question string `json:"question"`
+			Snippet  string `json:"snippet"`
+			Link     string `json:"link"`
+			Date     string `json:"date"`
+		} `json:"related_questions"`
+	}
+
+	params := map[string]string{
+		"engine":  "google",
+		"q":       query,
+		"num":     fmt.Sprintf("%d", maxResults),
+		"api_key": s.serpAPIKey,
+	}
+	if tbs, ok := serpAPITimeRanges[timeRange]; ok {
+		params["tbs"] = tbs
+	}
+	news := slices.Contains(categories, "news")
+	if news {
+		params["tbm"] = "nws"
+	}
+
+	var serpResp SerpAPIResponse
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetQueryParams(params).
+		SetResult(&serpResp).
+		Get(serpAPISearchURL)
+
+	if err != nil {
+		logging.Printf(ctx, "⚠️  SerpAPI failed: %v", err)
+		return nil, fmt.Errorf("serpapi: %w", err)
+	}
+
+	if resp.IsError() {
+		logging.Printf(ctx, "⚠️  SerpAPI error: %d - %s", resp.StatusCode(), resp.String())
+		return nil, fmt.Errorf("serpapi returned status %d", resp.StatusCode())
+	}
+	// "Google hasn't returned any results for this query" comes with 200
+	if serpResp.Error != "" && len(serpResp.OrganicResults) == 0 && len(serpResp.NewsResults) == 0 {
+		logging.Printf(ctx, "⚠️  SerpAPI: %s", serpResp.Error)
+		return nil, nil
+	}
+
+	results := make([]models.TavilyResult, 0)
+	add := func(title, link, content string, score float64, date string) {
+		if title == "" || link == "" || content == "" {
+			return
+		}
+		content = utils.TruncateRunesWithEllipsis(content, 500)
+		results = append(results, models.TavilyResult{
+			Title:       title,
+			URL:         link,
+			Content:     content,
+			Snippet:     content,
+			Score:       score,
+			PublishedAt: ParsePublishedDate(date),
+		})
+	}
+
+	// Direct answers
+	answerBox := serpResp.AnswerBox
+	answer := answerBox.Answer
+	if answer == "" {
+		answer = answerBox.Snippet
+	}
+	add(answerBox.Title, answerBox.Link, answer, 1.0, "")
+
+	graph := serpResp.KnowledgeGraph
+	graphLink := graph.Source.Link
+	if graphLink == "" {
+		graphLink = graph.Website
+	}
+	add(graph.Title, graphLink, graph.Description, 0.97, "")
+
+	organic := serpResp.OrganicResults
+	if news {
+		organic = serpResp.NewsResults
+	}
+	for i, r := range organic {
+		if i >= maxResults {
+			break
+		}
+		content := r.Snippet
+		if content == "" {
+			content = r.Title
+		}
+		add(r.Title, r.Link, content, 0.95-float64(i)*0.03, r.Date)
+	}
+
+	// People also ask: an answer from a page to a question next to the query
+	for i, r := range serpResp.RelatedQuestions {
+		add(r.Question, r.Link, r.Snippet, 0.7-float64(i)*0.03, r.Date)
+	}
+
+	return results, nil
+}
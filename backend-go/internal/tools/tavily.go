@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/logging"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/utils"
+)
+
+const tavilySearchURL = "https://api.tavily.com/search"
+
+// WithTavily makes the Tavily Search API the first provider tried, ahead of
+// SearXNG; an empty key leaves it off. depth is Tavily's search_depth, basic
+// or advanced.
+func (s *SearchClient) WithTavily(apiKey, depth string) *SearchClient {
+	s.tavilyAPIKey = apiKey
+	s.tavilyDepth = depth
+	return s
+}
+
+// Tavily Search API (Primary when configured)
+func (s *SearchClient) tryTavily(
+	ctx context.Context,
+	query string,
+	maxResults int,
+	includeRawContent bool,
+	timeRange string,
+	opts SearchOptions,
+) ([]models.TavilyResult, string, error) {
+	defer TrackSearchTime(ctx, "tavily", time.Now())
+
+	type TavilyRequest struct {
+		Query             string   `json:"query"`
+		SearchDepth       string   `json:"search_depth,omitempty"`
+		Topic             string   `json:"topic,omitempty"`
+		TimeRange         string   `json:"time_range,omitempty"`
+		MaxResults        int      `json:"max_results"`
+		IncludeAnswer     bool     `json:"include_answer"`
+		IncludeRawContent bool     `json:"include_raw_content"`
+		IncludeDomains    []string `json:"include_domains,omitempty"`
+	}
+	type TavilyResponse struct {
+		Answer  string `json:"answer"`
+		Results []struct {
+			Title         string  `json:"title"`
+			URL           string  `json:"url"`
+			Content       string  `json:"content"`
+			RawContent    string  `json:"raw_content"`
+			Score         float64 `json:"score"`
+			PublishedDate string  `json:"published_date"`
+		} `json:"results"`
+	}
+
+	request := TavilyRequest{
+		Query:             query,
+		SearchDepth:       s.tavilyDepth,
+		MaxResults:        maxResults,
+		IncludeAnswer:     true,
+		IncludeRawContent: includeRawContent,
+		IncludeDomains:    opts.Sites,
+	}
+	if slices.Contains(opts.Categories, "news") {
+		request.Topic = "news"
+	}
+	switch timeRange {
+	case "day", "week", "month", "year":
+		request.TimeRange = timeRange
+	}
+
+	var tavilyResp TavilyResponse
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetAuthToken(s.tavilyAPIKey).
+		SetHeader("Content-Type", "application/json").
+		SetBody(request).
+		SetResult(&tavilyResp).
+		Post(tavilySearchURL)
+
+	if err != nil {
+		logging.Printf(ctx, "⚠️  Tavily failed: %v", err)
+		return nil, "", fmt.Errorf("tavily: %w", err)
+	}
+
+	if resp.IsError() {
+		logging.Printf(ctx, "⚠️  Tavily error: %d - %s", resp.StatusCode(), resp.String())
+		return nil, "", fmt.Errorf("tavily returned status %d", resp.StatusCode())
+	}
+
+	results := make([]models.TavilyResult, 0)
+	for i, r := range tavilyResp.Results {
+		if i >= maxResults {
+			break
+		}
+
+		if r.Title == "" || r.URL == "" {
+			continue
+		}
+
+		content := r.Content
+		if content == "" {
+			content = r.Title
+		}
+		content = utils.TruncateRunesWithEllipsis(content, 500)
+
+		score := 0.95 - float64(i)*0.03
+		if r.Score > 0 {
+			score = r.Score
+		}
+
+		results = append(results, models.TavilyResult{
+			Title:       r.Title,
+			URL:         r.URL,
+			Content:     content,
+			Snippet:     content,
+			RawContent:  utils.TruncateRunes(r.RawContent, maxRawContent),
+			Score:       score,
+			PublishedAt: ParsePublishedDate(r.PublishedDate),
+		})
+	}
+
+	return results, tavilyResp.Answer, nil
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+)
+
+// Request phases timed by RequestTimings
+const (
+	TimingRouting      = "routing"
+	TimingQueryEnhance = "query_enhance"
+	TimingPageFetch    = "page_fetch"
+	TimingRerank       = "rerank"
+	TimingCredibility  = "credibility"
+	TimingSynthesis    = "synthesis"
+	TimingVerification = "verification"
+	TimingLLM          = "llm"
+	TimingPostprocess  = "postprocess"
+)
+
+// RequestTimings sums the time one request spends per phase and per search
+// provider. Like TokenMeter it travels in the context, so code deep in the
+// agents records into it without being passed anything; without one in the
+// context recording is a no-op.
+type RequestTimings struct {
+	mu       sync.Mutex
+	phases   map[string]time.Duration
+	searches map[string]time.Duration
+}
+
+type timingsKey struct{}
+
+// WithTimings returns a context whose phases are timed into the returned timings
+func WithTimings(ctx context.Context) (context.Context, *RequestTimings) {
+	timings := &RequestTimings{
+		phases:   make(map[string]time.Duration),
+		searches: make(map[string]time.Duration),
+	}
+	return context.WithValue(ctx, timingsKey{}, timings), timings
+}
+
+func timingsFromContext(ctx context.Context) *RequestTimings {
+	timings, _ := ctx.Value(timingsKey{}).(*RequestTimings)
+	return timings
+}
+
+// TrackTime adds the time since start to a phase; use it as
+// defer tools.TrackTime(ctx, tools.TimingRerank, time.Now())
+func TrackTime(ctx context.Context, phase string, start time.Time) {
+	if timings := timingsFromContext(ctx); timings != nil {
+		timings.mu.Lock()
+		timings.phases[phase] += time.Since(start)
+		timings.mu.Unlock()
+	}
+}
+
+// TrackSearchTime adds the time since start to a search provider
+func TrackSearchTime(ctx context.Context, provider string, start time.Time) {
+	if timings := timingsFromContext(ctx); timings != nil {
+		timings.mu.Lock()
+		timings.searches[provider] += time.Since(start)
+		timings.mu.Unlock()
+	}
+}
+
+// Breakdown returns the recorded timings in milliseconds with the given total
+func (t *RequestTimings) Breakdown(total time.Duration) *models.Timings {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	breakdown := &models.Timings{
+		RoutingMS:      t.phases[TimingRouting].Milliseconds(),
+		QueryEnhanceMS: t.phases[TimingQueryEnhance].Milliseconds(),
+		PageFetchMS:    t.phases[TimingPageFetch].Milliseconds(),
+		RerankMS:       t.phases[TimingRerank].Milliseconds(),
+		CredibilityMS:  t.phases[TimingCredibility].Milliseconds(),
+		SynthesisMS:    t.phases[TimingSynthesis].Milliseconds(),
+		VerificationMS: t.phases[TimingVerification].Milliseconds(),
+		LLMMS:          t.phases[TimingLLM].Milliseconds(),
+		PostprocessMS:  t.phases[TimingPostprocess].Milliseconds(),
+		TotalMS:        total.Milliseconds(),
+	}
+	if len(t.searches) > 0 {
+		breakdown.SearchMS = make(map[string]int64, len(t.searches))
+		for provider, d := range t.searches {
+			breakdown.SearchMS[provider] = d.Milliseconds()
+		}
+	}
+	return breakdown
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"maps"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/models"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// TokenMeter sums the LLM tokens spent on one request. LLMClient adds to the
+// meter carried in the call context, so agents need no changes to be metered.
+// A meter started inside another one counts into both, so an agent can meter
+// its own share of a request. It also counts the calls served by each LLM
+// provider and sums their cost at the configured prices.
+type TokenMeter struct {
+	prompt     atomic.Int64
+	completion atomic.Int64
+	parent     *TokenMeter
+
+	mu        sync.Mutex
+	providers map[string]int
+	cost      float64 // USD
+}
+
+type tokenMeterKey struct{}
+
+// WithTokenMeter returns a context whose LLM calls are counted by the meter
+// and by the meter already in ctx, if any
+func WithTokenMeter(ctx context.Context) (context.Context, *TokenMeter) {
+	parent, _ := ctx.Value(tokenMeterKey{}).(*TokenMeter)
+	meter := &TokenMeter{parent: parent}
+	return context.WithValue(ctx, tokenMeterKey{}, meter), meter
+}
+
+func (m *TokenMeter) PromptTokens() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.prompt.Load()
+}
+
+func (m *TokenMeter) CompletionTokens() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.completion.Load()
+}
+
+func (m *TokenMeter) TotalTokens() int64 {
+	return m.PromptTokens() + m.CompletionTokens()
+}
+
+// Cost returns the cost of the metered calls in USD, 0 unless LLM prices
+// are configured
+func (m *TokenMeter) Cost() float64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cost
+}
+
+// Usage returns the tokens and cost of the metered calls, nil when there
+// were none
+func (m *TokenMeter) Usage() *models.LLMUsage {
+	if m.TotalTokens() == 0 {
+		return nil
+	}
+	return &models.LLMUsage{
+		PromptTokens:     m.PromptTokens(),
+		CompletionTokens: m.CompletionTokens(),
+		TotalTokens:      m.TotalTokens(),
+		CostUSD:          math.Round(m.Cost()*1e6) / 1e6,
+	}
+}
+
+// Providers returns the number of LLM calls each provider served, nil when
+// there were none
+func (m *TokenMeter) Providers() map[string]int {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.providers)
+}
+
+// meterUsage adds the usage and cost of a call served by provider to the
+// meter of ctx, if any
+func meterUsage(ctx context.Context, provider string, usage openai.Usage, cost float64) {
+	meter, _ := ctx.Value(tokenMeterKey{}).(*TokenMeter)
+	for ; meter != nil; meter = meter.parent {
+		meter.prompt.Add(int64(usage.PromptTokens))
+		meter.completion.Add(int64(usage.CompletionTokens))
+
+		meter.mu.Lock()
+		if meter.providers == nil {
+			meter.providers = make(map[string]int)
+		}
+		meter.providers[provider]++
+		meter.cost += cost
+		meter.mu.Unlock()
+	}
+}
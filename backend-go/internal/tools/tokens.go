@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+const (
+	// messageTokens is what the chat format adds to each message, and
+	// replyTokens what it adds before the reply
+	messageTokens = 4
+	replyTokens   = 3
+	// contextMargin is left free in the context window for what the count
+	// doesn't see: provider-side formatting and tokenizer differences
+	contextMargin = 256
+	// defaultContextWindow is assumed for models missing in contextWindows
+	defaultContextWindow = 8192
+)
+
+// contextWindows are the context windows of the known model families, most
+// specific prefix first (LLM_CONTEXT_WINDOW overrides them)
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4o", 128000},
+	{"gpt-4.1", 128000},
+	{"gpt-4.5", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 128000},
+	{"o3", 128000},
+	{"o4", 128000},
+	{"qwen", 32768},
+	{"gigachat", 32768},
+}
+
+// contextWindow returns the context window of model in tokens
+func contextWindow(model string) int {
+	model = strings.ToLower(model)
+	for _, w := range contextWindows {
+		if strings.HasPrefix(model, w.prefix) {
+			return w.tokens
+		}
+	}
+	return defaultContextWindow
+}
+
+func init() {
+	// The BPE ranks are embedded in the binary: no download at first use
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*tiktoken.Tiktoken{}
+)
+
+// encodingFor returns the tiktoken encoding of model. Models without one
+// (Qwen, GigaChat) are counted with cl100k_base, which comes close for them.
+// It returns nil when the encoding can't be loaded.
+func encodingFor(model string) *tiktoken.Tiktoken {
+	model = strings.ToLower(model)
+	name, ok := tiktoken.MODEL_TO_ENCODING[model]
+	if !ok {
+		name = tiktoken.MODEL_CL100K_BASE
+		for prefix, encoding := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+			if strings.HasPrefix(model, prefix) {
+				name = encoding
+				break
+			}
+		}
+	}
+
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	if enc, ok := encodings[name]; ok {
+		return enc
+	}
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		log.Printf("⚠️  Tokenizer %s is not available, estimating tokens: %v", name, err)
+	}
+	encodings[name] = enc
+	return enc
+}
+
+// countTokens returns the tokens of text for the backend's model
+func (b *llmBackend) countTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	if enc := encodingFor(b.model); enc != nil {
+		return len(enc.EncodeOrdinary(text))
+	}
+	return estimateTokens(len([]rune(text)))
+}
+
+// promptTokens returns the tokens the request of c takes in the backend's
+// context window: messages, tool calls and tool definitions
+func (b *llmBackend) promptTokens(c completion) int {
+	tokens := replyTokens
+	for _, msg := range c.messages {
+		tokens += messageTokens + b.countTokens(msg.Content) + b.countTokens(msg.Name)
+		for _, call := range msg.ToolCalls {
+			tokens += b.countTokens(call.Function.Name) + b.countTokens(call.Function.Arguments)
+		}
+	}
+	if len(c.tools) > 0 {
+		definitions, _ := json.Marshal(c.tools)
+		tokens += b.countTokens(string(definitions))
+	}
+	return tokens
+}
+
+// checkContext returns ErrContextOverflow when the prompt of c and a reply of
+// c.maxTokens don't fit the backend's context window
+func (b *llmBackend) checkContext(c completion) error {
+	prompt := b.promptTokens(c)
+	if prompt+c.maxTokens+contextMargin <= b.contextWindow {
+		return nil
+	}
+	return fmt.Errorf("%w: the prompt takes %d tokens and the reply up to %d, but %s has a context window of %d",
+		ErrContextOverflow, prompt, c.maxTokens, b.model, b.contextWindow)
+}
+
+// CountTokens returns the tokens of text for the primary model
+func (l *LLMClient) CountTokens(text string) int {
+	if len(l.backends) == 0 {
+		return estimateTokens(len([]rune(text)))
+	}
+	return l.backends[0].countTokens(text)
+}
+
+// PromptBudget returns how many tokens a prompt (as counted by PromptTokens)
+// may take with the primary model when the reply may take maxTokens
+func (l *LLMClient) PromptBudget(maxTokens int) int {
+	window := defaultContextWindow
+	if len(l.backends) > 0 {
+		window = l.backends[0].contextWindow
+	}
+	return max(0, window-maxTokens-contextMargin)
+}
+
+// TruncateTokens cuts text to its first n tokens for the primary model
+func (l *LLMClient) TruncateTokens(text string, n int) string {
+	model := ""
+	if len(l.backends) > 0 {
+		model = l.backends[0].model
+	}
+	return cutTokens(model, text, n)
+}
+
+// cutTokens cuts text to its first n tokens for model
+func cutTokens(model, text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	enc := encodingFor(model)
+	if enc == nil {
+		runes := []rune(text)
+		if len(runes) > n*3 {
+			return string(runes[:n*3])
+		}
+		return text
+	}
+
+	tokens := enc.EncodeOrdinary(text)
+	if len(tokens) <= n {
+		return text
+	}
+	// A cut inside a multibyte character leaves an invalid tail
+	return strings.ToValidUTF8(enc.Decode(tokens[:n]), "")
+}
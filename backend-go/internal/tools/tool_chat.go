@@ -0,0 +1,173 @@
+package tools
+
+import (
+	"context"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ToolSpec describes a function the model may call. Parameters is the JSON
+// schema of its arguments.
+type ToolSpec struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+}
+
+// ToolCall is a function call requested by the model; Arguments is JSON
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// ToolHandler runs a tool call the model asked for and returns its result.
+// An error goes to the model as the result, so it can try otherwise.
+type ToolHandler func(ctx context.Context, call ToolCall) (string, error)
+
+// ToolResult is a tool call run in a tool loop and what it returned
+type ToolResult struct {
+	Call   ToolCall
+	Output string
+	Err    error
+}
+
+// toolLimitResult answers the calls past the limit of a tool loop
+const toolLimitResult = "Tool call limit reached: answer with what has been found so far."
+
+// ToolChat is a conversation in which the model decides which tools to call,
+// and in what order, before it answers (OpenAI function calling)
+type ToolChat struct {
+	llm      *LLMClient
+	tools    []openai.Tool
+	messages []openai.ChatCompletionMessage
+}
+
+// NewToolChat starts a conversation with a system prompt and a user message
+func (l *LLMClient) NewToolChat(system, user string, specs []ToolSpec) *ToolChat {
+	return l.newToolChat([]openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: system},
+		{Role: openai.ChatMessageRoleUser, Content: user},
+	}, specs)
+}
+
+// ChatCompletionWithTools is ChatCompletion with tools: the model may call
+// them, through handler, for up to maxTurns turns before it answers. It
+// returns the answer and the tool calls run on the way, in order.
+func (l *LLMClient) ChatCompletionWithTools(
+	ctx context.Context,
+	messages []map[string]string,
+	specs []ToolSpec,
+	handler ToolHandler,
+	temperature float32,
+	maxTokens int,
+	maxTurns int,
+) (string, []ToolResult, error) {
+	return l.newToolChat(chatMessages(messages), specs).Run(ctx, temperature, maxTokens, maxTurns, 0, handler)
+}
+
+func (l *LLMClient) newToolChat(messages []openai.ChatCompletionMessage, specs []ToolSpec) *ToolChat {
+	chat := &ToolChat{llm: l, messages: messages}
+	for _, spec := range specs {
+		chat.tools = append(chat.tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: openai.FunctionDefinition{
+				Name:        spec.Name,
+				Description: spec.Description,
+				Parameters:  spec.Parameters,
+			},
+		})
+	}
+	return chat
+}
+
+// Next sends the conversation and returns the tool calls the model asks for
+// or, when it asks for none, its answer. Every call must get a result via
+// AddResult before the next turn. With allowTools false the model has to answer.
+func (c *ToolChat) Next(ctx context.Context, temperature float32, maxTokens int, allowTools bool) (string, []ToolCall, error) {
+	req := completion{
+		messages:    c.messages,
+		tools:       c.tools,
+		temperature: temperature,
+		maxTokens:   maxTokens,
+	}
+	if !allowTools {
+		req.toolChoice = "none"
+	}
+
+	message, err := c.llm.createMessage(ctx, req)
+	if err != nil {
+		return "", nil, err
+	}
+	c.messages = append(c.messages, message)
+	if !allowTools || len(message.ToolCalls) == 0 {
+		return message.Content, nil, nil
+	}
+
+	calls := make([]ToolCall, 0, len(message.ToolCalls))
+	for _, call := range message.ToolCalls {
+		calls = append(calls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return "", calls, nil
+}
+
+// Run loops until the model answers: the tool calls of each turn are run with
+// handler and their results sent back. After maxTurns turns with tool calls,
+// or once maxCalls calls have run (0 for no limit), the model has to answer;
+// calls past maxCalls are not run. It returns the answer and the calls run,
+// in order.
+func (c *ToolChat) Run(
+	ctx context.Context,
+	temperature float32,
+	maxTokens int,
+	maxTurns int,
+	maxCalls int,
+	handler ToolHandler,
+) (string, []ToolResult, error) {
+	var results []ToolResult
+	for turn := 0; ; turn++ {
+		allowTools := turn < maxTurns && (maxCalls <= 0 || len(results) < maxCalls)
+		text, calls, err := c.Next(ctx, temperature, maxTokens, allowTools)
+		if err != nil {
+			return "", results, err
+		}
+		if len(calls) == 0 {
+			return text, results, nil
+		}
+		for _, call := range calls {
+			if maxCalls > 0 && len(results) >= maxCalls {
+				c.AddResult(call, toolLimitResult)
+				continue
+			}
+			output, err := handler(ctx, call)
+			results = append(results, ToolResult{Call: call, Output: output, Err: err})
+			if err != nil {
+				output = "Error: " + err.Error()
+			}
+			c.AddResult(call, output)
+		}
+	}
+}
+
+// AddResult answers a tool call of the last turn
+func (c *ToolChat) AddResult(call ToolCall, result string) {
+	c.messages = append(c.messages, openai.ChatCompletionMessage{
+		Role:       openai.ChatMessageRoleTool,
+		Content:    result,
+		Name:       call.Name,
+		ToolCallID: call.ID,
+	})
+}
+
+// AddUserMessage adds a message from the user, e.g. an instruction for the
+// final answer
+func (c *ToolChat) AddUserMessage(content string) {
+	c.messages = append(c.messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: content,
+	})
+}
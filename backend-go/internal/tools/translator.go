@@ -0,0 +1,281 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/config"
+	"github.com/go-resty/resty/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Snippet translation providers (SNIPPET_TRANSLATION_PROVIDER)
+const (
+	TranslationProviderLLM            = "llm"
+	TranslationProviderLibreTranslate = "libretranslate"
+	TranslationProviderDeepL          = "deepl"
+)
+
+// translationCacheTTL is long: a snippet's translation does not change
+const translationCacheTTL = 7 * 24 * time.Hour
+
+// SnippetTranslator machine-translates short texts such as source snippets
+// with the configured provider. Translations are cached by provider, target
+// language and text in Redis when available and in process memory otherwise.
+type SnippetTranslator struct {
+	provider string
+	llm      *LLMClient
+	client   *resty.Client
+	apiURL   string
+	apiKey   string
+	redis    *redis.Client
+
+	mu      sync.RWMutex
+	entries map[string]translationEntry
+}
+
+type translationEntry struct {
+	text      string
+	expiresAt time.Time
+}
+
+// NewSnippetTranslator returns nil when translation is disabled or the
+// provider is unknown
+func NewSnippetTranslator(cfg *config.Config, redisClient *redis.Client) *SnippetTranslator {
+	provider := strings.ToLower(cfg.SnippetTranslationProvider)
+	apiURL := strings.TrimRight(cfg.TranslationAPIURL, "/")
+	switch provider {
+	case TranslationProviderLLM:
+	case TranslationProviderLibreTranslate:
+		if apiURL == "" {
+			apiURL = "https://libretranslate.com"
+		}
+	case TranslationProviderDeepL:
+		if apiURL == "" {
+			apiURL = "https://api-free.deepl.com"
+		}
+	case "", "none":
+		return nil
+	default:
+		log.Printf("⚠️  Unknown SNIPPET_TRANSLATION_PROVIDER %q, snippet translation disabled", provider)
+		return nil
+	}
+
+	client := resty.New()
+	client.SetTimeout(10 * time.Second)
+
+	t := &SnippetTranslator{
+		provider: provider,
+		llm:      NewLLMClient(cfg),
+		client:   client,
+		apiURL:   apiURL,
+		apiKey:   cfg.TranslationAPIKey,
+		redis:    redisClient,
+		entries:  make(map[string]translationEntry),
+	}
+	if redisClient == nil {
+		go t.cleanup()
+	}
+	return t
+}
+
+// Provider names the translation provider
+func (t *SnippetTranslator) Provider() string {
+	return t.provider
+}
+
+// Translate translates texts into the target language (ru or en), detecting
+// the source language. The result has one translation per text; only texts
+// missing from the cache are sent to the provider, in one request.
+func (t *SnippetTranslator) Translate(ctx context.Context, texts []string, target string) ([]string, error) {
+	translated := make([]string, len(texts))
+	var missing []int
+	for i, text := range texts {
+		if cached, ok := t.get(ctx, t.key(text, target)); ok {
+			translated[i] = cached
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return translated, nil
+	}
+
+	batch := make([]string, len(missing))
+	for j, i := range missing {
+		batch[j] = texts[i]
+	}
+
+	var results []string
+	var err error
+	switch t.provider {
+	case TranslationProviderLibreTranslate:
+		results, err = t.translateLibre(ctx, batch, target)
+	case TranslationProviderDeepL:
+		results, err = t.translateDeepL(ctx, batch, target)
+	default:
+		results, err = t.translateLLM(ctx, batch, target)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s translation failed: %w", t.provider, err)
+	}
+	if len(results) != len(batch) {
+		return nil, fmt.Errorf("%s translation returned %d texts for %d", t.provider, len(results), len(batch))
+	}
+
+	for j, i := range missing {
+		translated[i] = strings.TrimSpace(results[j])
+		t.set(ctx, t.key(texts[i], target), translated[i])
+	}
+	return translated, nil
+}
+
+func (t *SnippetTranslator) translateLLM(ctx context.Context, texts []string, target string) ([]string, error) {
+	language := "English"
+	if target == "ru" {
+		language = "Russian"
+	}
+	input, err := json.Marshal(texts)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := fmt.Sprintf(`Translate each string of the JSON array into %s. Keep names, numbers and dates as they are.
+Return only a JSON array of strings with the translations in the same order.
+
+%s`, language, input)
+
+	response, err := t.llm.Complete(ctx, prompt, 0.1, 300*len(texts))
+	if err != nil {
+		return nil, err
+	}
+
+	start := strings.Index(response, "[")
+	end := strings.LastIndex(response, "]")
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON array in response")
+	}
+	var translated []string
+	if err := json.Unmarshal([]byte(response[start:end+1]), &translated); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return translated, nil
+}
+
+// translateLibre calls a LibreTranslate instance (POST /translate)
+func (t *SnippetTranslator) translateLibre(ctx context.Context, texts []string, target string) ([]string, error) {
+	var result struct {
+		TranslatedText []string `json:"translatedText"`
+	}
+	resp, err := t.client.R().
+		SetContext(ctx).
+		SetBody(map[string]any{
+			"q":       texts,
+			"source":  "auto",
+			"target":  target,
+			"format":  "text",
+			"api_key": t.apiKey,
+		}).
+		SetResult(&result).
+		Post(t.apiURL + "/translate")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("status %d", resp.StatusCode())
+	}
+	return result.TranslatedText, nil
+}
+
+// translateDeepL calls the DeepL API (POST /v2/translate)
+func (t *SnippetTranslator) translateDeepL(ctx context.Context, texts []string, target string) ([]string, error) {
+	var result struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	resp, err := t.client.R().
+		SetContext(ctx).
+		SetHeader("Authorization", "DeepL-Auth-Key "+t.apiKey).
+		SetBody(map[string]any{
+			"text":        texts,
+			"target_lang": strings.ToUpper(target),
+		}).
+		SetResult(&result).
+		Post(t.apiURL + "/v2/translate")
+	if err != nil {
+		return nil, err
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("status %d", resp.StatusCode())
+	}
+
+	translated := make([]string, len(result.Translations))
+	for i, tr := range result.Translations {
+		translated[i] = tr.Text
+	}
+	return translated, nil
+}
+
+func (t *SnippetTranslator) key(text, target string) string {
+	sum := sha256.Sum256([]byte(text))
+	return "translation:" + t.provider + ":" + target + ":" + hex.EncodeToString(sum[:16])
+}
+
+func (t *SnippetTranslator) get(ctx context.Context, key string) (string, bool) {
+	if t.redis != nil {
+		text, err := t.redis.Get(ctx, key).Result()
+		if err != nil {
+			if err != redis.Nil {
+				log.Printf("⚠️  Translation cache read failed: %v", err)
+			}
+			return "", false
+		}
+		return text, true
+	}
+
+	t.mu.RLock()
+	entry, ok := t.entries[key]
+	t.mu.RUnlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.text, true
+}
+
+func (t *SnippetTranslator) set(ctx context.Context, key, text string) {
+	if t.redis != nil {
+		if err := t.redis.Set(ctx, key, text, translationCacheTTL).Err(); err != nil {
+			log.Printf("⚠️  Translation cache write failed: %v", err)
+		}
+		return
+	}
+
+	t.mu.Lock()
+	t.entries[key] = translationEntry{text: text, expiresAt: time.Now().Add(translationCacheTTL)}
+	t.mu.Unlock()
+}
+
+// cleanup removes expired in-memory entries
+func (t *SnippetTranslator) cleanup() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		t.mu.Lock()
+		for key, entry := range t.entries {
+			if now.After(entry.expiresAt) {
+				delete(t.entries, key)
+			}
+		}
+		t.mu.Unlock()
+	}
+}
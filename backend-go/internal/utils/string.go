@@ -6,7 +6,7 @@ import (
 )
 
 // TruncateUTF8 safely truncates a string to maxLen bytes
-// without breaking UTF-8 characters
+// without breaking UTF-8 characters. For limits in characters use TruncateRunes.
 func TruncateUTF8(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -45,4 +45,33 @@ func TruncateUTF8WithEllipsis(s string, maxLen int) string {
 
 	truncated := TruncateUTF8(s, maxLen-3)
 	return truncated + "..."
-}
\ No newline at end of file
+}
+
+// TruncateRunes truncates a string to at most maxRunes characters. Use it
+// for limits meant in characters (snippets, titles, prompt budgets): a byte
+// limit keeps only half as much Cyrillic text as Latin.
+func TruncateRunes(s string, maxRunes int) string {
+	if maxRunes <= 0 {
+		return ""
+	}
+	count := 0
+	for i := range s {
+		if count == maxRunes {
+			return s[:i]
+		}
+		count++
+	}
+	return s
+}
+
+// TruncateRunesWithEllipsis truncates to maxRunes characters, the ellipsis
+// included
+func TruncateRunesWithEllipsis(s string, maxRunes int) string {
+	if utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	if maxRunes < 3 {
+		return "..."
+	}
+	return TruncateRunes(s, maxRunes-3) + "..."
+}
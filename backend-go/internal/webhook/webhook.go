@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Vova4o/bootCamp2025CaseSber/backend/internal/netguard"
+	"github.com/go-resty/resty/v2"
+)
+
+// Headers sent with every callback
+const (
+	HeaderSignature = "X-Webhook-Signature" // sha256=<hex HMAC of "timestamp.body">
+	HeaderTimestamp = "X-Webhook-Timestamp" // unix seconds
+	HeaderJobID     = "X-Job-ID"
+)
+
+// Sender posts signed JSON payloads to client callback URLs
+type Sender struct {
+	secret string
+	client *resty.Client
+}
+
+func NewSender(secret string) *Sender {
+	client := resty.New()
+	client.SetTimeout(10 * time.Second)
+	client.SetRetryCount(3)
+	client.SetRetryWaitTime(2 * time.Second)
+	client.AddRetryCondition(func(r *resty.Response, err error) bool {
+		if errors.Is(err, netguard.ErrNotPublic) {
+			return false
+		}
+		return err != nil || r.StatusCode() >= 500
+	})
+
+	return &Sender{secret: secret, client: client}
+}
+
+// PublicOnly makes the sender refuse to connect to addresses that are not
+// public, for callback URLs given by clients. The address is checked when
+// connecting, so a host resolving to a public address for ValidatePublicURL
+// and to a private one afterwards (DNS rebinding) or redirecting there is
+// refused too.
+func (s *Sender) PublicOnly() *Sender {
+	s.client.SetTransport(netguard.Transport())
+	return s
+}
+
+// Enabled reports whether a signing secret is configured
+func (s *Sender) Enabled() bool {
+	return s.secret != ""
+}
+
+// ValidateURL checks that a callback URL is an absolute http(s) URL. URLs set
+// by the operator (inbound hooks) may point into our network; those of clients
+// go through ValidatePublicURL.
+func ValidateURL(callbackURL string) error {
+	parsed, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("callback_url must be an http or https URL")
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("callback_url has no host")
+	}
+	return nil
+}
+
+// ValidatePublicURL checks the callback URL like ValidateURL and that its host
+// resolves to public addresses only, so clients can't make the server post to
+// its own network (cloud metadata, internal services)
+func ValidatePublicURL(ctx context.Context, callbackURL string) error {
+	if err := ValidateURL(callbackURL); err != nil {
+		return err
+	}
+	parsed, _ := url.Parse(callbackURL)
+	host := parsed.Hostname()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("callback_url host %s does not resolve", host)
+	}
+	for _, addr := range addrs {
+		if !netguard.PublicIP(addr.IP) {
+			return fmt.Errorf("callback_url host %s is %w", host, netguard.ErrNotPublic)
+		}
+	}
+	return nil
+}
+
+// Deliver posts the payload with its HMAC signature, retrying on network
+// errors and 5xx responses
+func (s *Sender) Deliver(ctx context.Context, callbackURL, jobID string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode callback payload: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader(HeaderTimestamp, timestamp).
+		SetHeader(HeaderSignature, "sha256="+Sign(s.secret, timestamp, body)).
+		SetHeader(HeaderJobID, jobID).
+		SetBody(body).
+		Post(callbackURL)
+	if err != nil {
+		return fmt.Errorf("callback request failed: %w", err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode())
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body"; receivers recompute
+// it with the shared secret to verify the callback
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
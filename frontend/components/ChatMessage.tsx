@@ -17,11 +17,29 @@ export default function ChatMessage({ message, mode }: ChatMessageProps) {
     if (mode.startsWith("pro-social")) return "Social";
     if (mode.startsWith("pro-academic")) return "Academic";
     if (mode.startsWith("pro-finance")) return "Finance";
+    if (mode.startsWith("pro-news")) return "News";
+    if (mode.startsWith("pro-code")) return "Code";
+    if (mode.startsWith("pro-video")) return "Video";
+    if (mode.startsWith("pro-factcheck")) return "Fact-check";
+    if (mode.startsWith("pro-mixed")) return "Mixed";
+    if (mode.startsWith("pro-tools")) return "Tools";
+    if (mode === "deep") return "Deep";
+    if (mode === "images" || mode.endsWith("→ images")) return "Images";
+    if (mode === "instant" || mode.endsWith("→ instant")) return "Instant";
     if (mode.startsWith("pro") || mode.includes("→ pro")) return "Pro";
     if (mode === "simple") return "Simple";
     return "Auto";
   };
 
+  const formatAge = (unixSeconds: number) => {
+    const days = Math.floor((Date.now() / 1000 - unixSeconds) / 86400);
+    if (days <= 0) return "сегодня";
+    if (days === 1) return "вчера";
+    if (days < 30) return `${days} дн. назад`;
+    if (days < 365) return `${Math.floor(days / 30)} мес. назад`;
+    return `${Math.floor(days / 365)} г. назад`;
+  };
+
   return (
     <div className={`flex gap-3 ${isUser ? "flex-row-reverse" : ""}`}>
       {/* Avatar */}
@@ -97,17 +115,52 @@ export default function ChatMessage({ message, mode }: ChatMessageProps) {
                   className="block p-3 bg-neutral-800/50 rounded-lg hover:bg-neutral-800 transition-colors border border-neutral-700/50 hover:border-neutral-600"
                 >
                   <div className="flex items-start gap-2">
-                    <ExternalLink
-                      size={14}
-                      className="text-neutral-500 mt-1 flex-shrink-0"
-                    />
+                    {source.preview?.favicon ? (
+                      // eslint-disable-next-line @next/next/no-img-element
+                      <img
+                        src={source.preview.favicon}
+                        alt=""
+                        width={14}
+                        height={14}
+                        loading="lazy"
+                        className="mt-1 flex-shrink-0 rounded-sm"
+                      />
+                    ) : (
+                      <ExternalLink
+                        size={14}
+                        className="text-neutral-500 mt-1 flex-shrink-0"
+                      />
+                    )}
                     <div className="flex-1 min-w-0">
-                      <div className="font-medium text-sm text-neutral-200 truncate">
-                        {source.title}
+                      {source.preview?.site_name && (
+                        <div className="text-[11px] text-neutral-500 truncate">
+                          {source.preview.site_name}
+                        </div>
+                      )}
+                      <div className="flex items-center gap-2">
+                        <div className="font-medium text-sm text-neutral-200 truncate">
+                          {source.title}
+                        </div>
+                        {source.published_at && (
+                          <span
+                            className="flex-shrink-0 text-[10px] px-1.5 py-0.5 rounded bg-neutral-700/60 text-neutral-400"
+                            title={new Date(source.published_at * 1000).toLocaleDateString("ru-RU")}
+                          >
+                            {formatAge(source.published_at)}
+                          </span>
+                        )}
                       </div>
                       <div className="text-xs text-neutral-500 mt-1 line-clamp-2">
                         {source.snippet}
                       </div>
+                      {source.translation && (
+                        <div
+                          className="text-xs text-neutral-400 mt-1 line-clamp-2 italic"
+                          title={`Машинный перевод (${source.translation.provider})`}
+                        >
+                          {source.translation.snippet}
+                        </div>
+                      )}
                     </div>
                   </div>
                 </a>
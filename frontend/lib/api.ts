@@ -33,9 +33,10 @@ export const searchAPI = {
     };
   },
 
-  createSession: async (mode: SearchMode): Promise<ChatSession> => {
+  createSession: async (mode: SearchMode, systemPrompt?: string): Promise<ChatSession> => {
     const response = await api.post<ChatSession>('/api/chat/session', {
       mode,
+      system_prompt: systemPrompt,
     });
     return response.data;
   },
@@ -2,15 +2,41 @@ export type SearchMode =
   | 'auto' 
   | 'simple' 
   | 'pro' 
+  | 'deep'
   | 'pro-social' 
   | 'pro-academic' 
-  | 'pro-finance';
+  | 'pro-finance'
+  | 'pro-news'
+  | 'pro-code'
+  | 'pro-video'
+  | 'pro-factcheck'
+  | 'pro-mixed'
+  | 'pro-tools'
+  | 'images'
+  | 'instant';
 
 export interface Source {
   title: string;
   url: string;
   snippet: string;
   credibility?: number;
+  published_at?: number; // unix seconds
+  fetched_at?: number; // unix seconds
+  language?: string; // ru or en
+  preview?: SourcePreview;
+  translation?: SourceTranslation;
+}
+
+export interface SourceTranslation {
+  language: string;
+  snippet: string;
+  provider: string;
+}
+
+export interface SourcePreview {
+  site_name: string;
+  favicon: string;
+  image?: string;
 }
 
 export interface Message {
@@ -28,14 +54,23 @@ export interface ChatSession {
   created_at: number;
   updated_at: number;
   messages: Message[];
+  system_prompt?: string;
+  tokens_used?: number;
+  cost_usd?: number; // cost of those tokens at the configured LLM prices
+  summary?: string; // rolling memory of the messages up to summary_seq
+  summary_seq?: number;
 }
 
 export interface SearchRequest {
   query: string;
   mode: SearchMode;
   session_id?: string;
+  output_schema?: OutputSchema;
+  no_cache?: boolean;
 }
 
+export type OutputSchema = 'person' | 'date' | 'number' | 'list' | 'comparison';
+
 export interface SearchResponse {
   query: string;
   mode: SearchMode;
@@ -43,7 +78,110 @@ export interface SearchResponse {
   sources: Source[];
   reasoning?: string;
   processing_time: number;
+  timings?: Timings;
+  fact_check?: ClaimVerdict[];
+  research?: ResearchTrace;
+  conflicts?: SourceConflict[];
+  entities?: Entity[];
+  date_range?: DateRange;
+  images?: ImageResult[];
+  structured?: { schema: OutputSchema; data: Record<string, unknown> };
+  evidence?: { score: number; credibility: number; agreement: number }; // simple mode
+  llm_providers?: Record<string, number>; // LLM calls served per provider
+  usage?: LLMUsage; // what the LLM calls of the request spent
+  verification?: { verified_ratio: number; checked: number; unsupported?: string[]; action: 'flag' | 'strip' };
+  guardrails?: { redacted?: ('email' | 'phone' | 'card')[]; sanitized?: string[] };
   timestamp: number;
   session_id?: string;
   context_used?: boolean;
-}
\ No newline at end of file
+  degraded?: boolean; // LLM unavailable, the answer lists the sources found
+}
+
+// Verdict on one claim of a fact-checked statement (pro-factcheck mode)
+export interface ClaimVerdict {
+  claim: string;
+  verdict: 'supported' | 'refuted' | 'insufficient';
+  explanation?: string;
+  sources: number[]; // indexes into sources
+}
+
+// Fact on which two sources contradict each other; indexes into sources
+export interface SourceConflict {
+  claim: string;
+  source_a: number;
+  statement_a?: string;
+  source_b: number;
+  statement_b?: string;
+}
+
+// Period a query asks about, in unix seconds; a missing bound is open
+export interface DateRange {
+  from?: number;
+  to?: number;
+  label: string;
+}
+
+// Image of the images mode with its attribution
+export interface ImageResult {
+  title: string;
+  url: string;
+  thumbnail_url: string;
+  page_url: string;
+  source: string;
+  author?: string;
+  width?: number;
+  height?: number;
+}
+
+// Named thing the sources mention, with relations to other entities by name
+export interface Entity {
+  name: string;
+  type: 'person' | 'organization' | 'place' | 'work' | 'event' | 'other';
+  relations?: { relation: string; target: string }[];
+}
+
+// Rounds of the deep research mode
+export interface ResearchTrace {
+  rounds: number;
+  sub_questions: { question: string; round: number; resolved: boolean }[];
+  stopped_by: 'resolved' | 'exhausted' | 'rounds' | 'time' | 'tokens';
+  tokens: number;
+}
+
+// Reasoning step of POST /api/search/stream ("step" event)
+export interface StepEvent {
+  seq: number;
+  step: string;
+  elapsed_ms: number;
+}
+
+// Answer chunk of POST /api/search/stream ("token" event); a new mode starts
+// a new answer, the "answer" event replaces the preview
+export interface TokenEvent {
+  seq: number;
+  mode: string;
+  text: string;
+}
+
+// Tokens and cost of the LLM calls of a request
+export interface LLMUsage {
+  prompt_tokens: number;
+  completion_tokens: number;
+  total_tokens: number;
+  cost_usd: number; // 0 unless LLM prices are configured
+}
+
+// Where the request spent its time, in milliseconds
+export interface Timings {
+  routing_ms: number;
+  query_enhance_ms: number;
+  search_ms?: Record<string, number>; // per provider
+  page_fetch_ms: number;
+  rerank_ms: number;
+  credibility_ms: number;
+  synthesis_ms: number; // the LLM call writing the answer, part of llm_ms
+  verification_ms: number; // part of llm_ms and postprocess_ms
+  llm_ms: number;
+  postprocess_ms: number;
+  total_ms: number;
+}